@@ -0,0 +1,192 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package anonymize rewrites IP addresses so that datasets such as logs can
+// be shared without revealing the addresses in them. Crypto-PAn keeps the
+// prefix structure of the addresses, truncation keeps the network part and
+// hashing replaces every address with an unrelated address.
+package anonymize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Methods of anonymization
+const (
+	MethodCryptoPAn = "crypto-pan"
+	MethodTruncate  = "truncate"
+	MethodHash      = "hash"
+)
+
+// Methods is the list of supported anonymization methods
+var Methods = []string{MethodCryptoPAn, MethodTruncate, MethodHash}
+
+// KeySize is the size of a Crypto-PAn key in bytes: a 16 byte AES key
+// followed by 16 bytes used to derive the padding
+const KeySize = 32
+
+// Anonymizer rewrites an address into an anonymized address of the same family
+type Anonymizer interface {
+	Anonymize(addr netip.Addr) netip.Addr
+}
+
+// CryptoPAn is the prefix-preserving anonymization of Xu et al. (Crypto-PAn):
+// two addresses sharing a prefix of n bits are anonymized into two addresses
+// sharing a prefix of n bits. The mapping is one-to-one and is determined by
+// the key, so the same key anonymizes the same address the same way.
+type CryptoPAn struct {
+	block cipher.Block
+	pad   [16]byte
+}
+
+// NewCryptoPAn is a function that returns a Crypto-PAn anonymizer for the
+// 32 byte key
+func NewCryptoPAn(key []byte) (*CryptoPAn, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key: %d bytes (Crypto-PAn needs %d)", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	c := &CryptoPAn{block: block}
+	block.Encrypt(c.pad[:], key[16:])
+	return c, nil
+}
+
+// Anonymize is a function that anonymizes an IPv4 or IPv6 address. Bit i of
+// the result is bit i of the address XOR the first bit of the AES encryption
+// of the first i bits of the address followed by the bits of the padding.
+func (c *CryptoPAn) Anonymize(addr netip.Addr) netip.Addr {
+	orig := addr.AsSlice()
+	bits := len(orig) * 8
+	result := make([]byte, len(orig))
+
+	var input, output [16]byte
+	for pos := 0; pos < bits; pos++ {
+		// The first pos bits of the address followed by the padding
+		input = c.pad
+		copy(input[:pos/8], orig[:pos/8])
+		if rem := pos % 8; rem != 0 {
+			mask := byte(0xff << (8 - rem))
+			input[pos/8] = orig[pos/8]&mask | c.pad[pos/8]&^mask
+		}
+
+		c.block.Encrypt(output[:], input[:])
+		result[pos/8] |= (output[0] >> 7) << (7 - pos%8)
+	}
+
+	for i := range result {
+		result[i] ^= orig[i]
+	}
+	anonymized, _ := netip.AddrFromSlice(result)
+	return anonymized.WithZone(addr.Zone())
+}
+
+// Truncate anonymizes an address by keeping the first bits of the address
+// (the network) and setting the rest (the host) to zero
+type Truncate struct {
+	IPv4Bits int
+	IPv6Bits int
+}
+
+// Anonymize is a function that truncates an IPv4 or IPv6 address
+func (t Truncate) Anonymize(addr netip.Addr) netip.Addr {
+	bits := t.IPv6Bits
+	if addr.Is4() {
+		bits = t.IPv4Bits
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return addr
+	}
+	return prefix.Addr().WithZone(addr.Zone())
+}
+
+// Hash anonymizes an address by replacing it with the first bits of its
+// HMAC-SHA256 with a key. The result is not related to the address, but
+// different addresses may (rarely) be anonymized into the same address.
+type Hash struct {
+	key []byte
+}
+
+// NewHash is a function that returns a hash anonymizer for the key
+func NewHash(key []byte) *Hash {
+	return &Hash{key: key}
+}
+
+// Anonymize is a function that hashes an IPv4 or IPv6 address
+func (h *Hash) Anonymize(addr netip.Addr) netip.Addr {
+	orig := addr.AsSlice()
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write(orig)
+	anonymized, _ := netip.AddrFromSlice(mac.Sum(nil)[:len(orig)])
+	return anonymized.WithZone(addr.Zone())
+}
+
+// New is a function that returns the anonymizer of a method. The key is used
+// by the crypto-pan and hash methods, the number of bits to keep of IPv4 and
+// IPv6 addresses by the truncate method.
+func New(method string, key []byte, ipv4Bits, ipv6Bits int) (Anonymizer, error) {
+	switch strings.ToLower(method) {
+	case MethodCryptoPAn:
+		return NewCryptoPAn(key)
+	case MethodTruncate:
+		if ipv4Bits < 0 || ipv4Bits > 32 {
+			return nil, fmt.Errorf("invalid IPv4 bits: %d (must be between 0 and 32)", ipv4Bits)
+		}
+		if ipv6Bits < 0 || ipv6Bits > 128 {
+			return nil, fmt.Errorf("invalid IPv6 bits: %d (must be between 0 and 128)", ipv6Bits)
+		}
+		return Truncate{IPv4Bits: ipv4Bits, IPv6Bits: ipv6Bits}, nil
+	case MethodHash:
+		return NewHash(key), nil
+	}
+	return nil, fmt.Errorf("invalid method: %s (must be one of %s)", method, strings.Join(Methods, ", "))
+}
+
+// ParseKey is a function that returns the key given as 64 hexadecimal
+// digits, or derives a key from any other string (a passphrase) with SHA-256
+func ParseKey(s string) []byte {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil && len(key) == KeySize {
+		return key
+	}
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+// NewKey is a function that returns a random key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,140 @@
+package anonymize_test
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/anonymize"
+)
+
+// referenceKey is the key of the sample trace distributed with the
+// reference implementation of Crypto-PAn
+var referenceKey = []byte{21, 34, 23, 141, 51, 164, 207, 128, 19, 10, 91, 22, 73, 144, 125, 16, 216, 152, 143, 131, 121, 121, 101, 39, 98, 87, 76, 45, 42, 132, 34, 2}
+
+// commonPrefix is a function that returns the number of leading bits two
+// addresses of the same family have in common
+func commonPrefix(a, b netip.Addr) int {
+	x, y := a.AsSlice(), b.AsSlice()
+	for i := 0; i < len(x)*8; i++ {
+		if (x[i/8]>>(7-i%8))&1 != (y[i/8]>>(7-i%8))&1 {
+			return i
+		}
+	}
+	return len(x) * 8
+}
+
+// TestCryptoPAn tests the anonymization against the reference implementation
+func TestCryptoPAn(t *testing.T) {
+	c, err := anonymize.NewCryptoPAn(referenceKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "128.11.68.132", expected: "135.242.180.132"},
+		{addr: "129.118.74.4", expected: "134.136.186.123"},
+		{addr: "130.132.252.244", expected: "133.68.164.234"},
+		{addr: "141.223.7.43", expected: "141.167.8.160"},
+		{addr: "141.233.145.108", expected: "141.129.237.235"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := c.Anonymize(netip.MustParseAddr(tc.addr)); got.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestCryptoPAnPrefixPreserving tests that the length of the common prefix
+// of two addresses is preserved, for IPv4 and IPv6
+func TestCryptoPAnPrefixPreserving(t *testing.T) {
+	c, _ := anonymize.NewCryptoPAn(referenceKey)
+
+	// Setup test cases
+	testCases := []struct {
+		a, b string
+	}{
+		{a: "10.0.0.1", b: "10.0.0.2"},
+		{a: "10.0.0.1", b: "10.0.255.1"},
+		{a: "10.0.0.1", b: "192.168.0.1"},
+		{a: "2001:db8::1", b: "2001:db8::2"},
+		{a: "2001:db8:1::1", b: "2001:db8:ffff::1"},
+		{a: "fe80::1%eth0", b: "2001:db8::1"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.a+"-"+tc.b, func(t *testing.T) {
+			a, b := netip.MustParseAddr(tc.a), netip.MustParseAddr(tc.b)
+			x, y := c.Anonymize(a), c.Anonymize(b)
+			if commonPrefix(a, b) != commonPrefix(x, y) {
+				t.Errorf("expected a common prefix of %d bits, got %d (%s, %s)", commonPrefix(a, b), commonPrefix(x, y), x, y)
+			}
+			if x.Zone() != a.Zone() {
+				t.Errorf("expected zone %q, got %q", a.Zone(), x.Zone())
+			}
+		})
+	}
+}
+
+// TestNew tests the anonymizers of the methods
+func TestNew(t *testing.T) {
+	key := anonymize.ParseKey("secret")
+
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		method        string
+		addr          string
+		expected      string
+		expectedError string
+	}{
+		{name: "TruncateIPv4", method: "truncate", addr: "192.0.2.123", expected: "192.0.2.0"},
+		{name: "TruncateIPv6", method: "truncate", addr: "2001:db8:1:2:3::4", expected: "2001:db8:1::"},
+		{name: "Hash", method: "hash", addr: "192.0.2.123", expected: "140.130.42.254"},
+		{name: "CryptoPAn", method: "CRYPTO-PAN", addr: "192.0.2.123"},
+		{name: "InvalidMethod", method: "rot13", expectedError: "invalid method"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := anonymize.New(tc.method, key, 24, 48)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := a.Anonymize(netip.MustParseAddr(tc.addr))
+			if tc.expected != "" && got.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+			if got.Is4() != netip.MustParseAddr(tc.addr).Is4() {
+				t.Errorf("expected the same address family, got %s", got)
+			}
+		})
+	}
+}
+
+// TestParseKey tests that hexadecimal keys are decoded and passphrases hashed
+func TestParseKey(t *testing.T) {
+	hexKey := strings.Repeat("ab", anonymize.KeySize)
+	if key := anonymize.ParseKey(hexKey); len(key) != anonymize.KeySize || key[0] != 0xab {
+		t.Errorf("expected the decoded key, got %x", key)
+	}
+	if key := anonymize.ParseKey("correct horse"); len(key) != anonymize.KeySize || key[0] == 0xab {
+		t.Errorf("expected a derived key, got %x", key)
+	}
+}
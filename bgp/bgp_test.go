@@ -0,0 +1,175 @@
+package bgp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/bgp"
+	"github.com/bitcanon/iptool/ip"
+)
+
+// responses are the canned responses of the data calls of the fake API
+var responses = map[string]string{
+	"routing-status": `{"status": "ok", "data": {
+		"first_seen": {"origin": "3333", "time": "2000-08-18T08:00:00"},
+		"last_seen": {"origin": "3333", "time": "2026-10-15T08:00:00"},
+		"visibility": {"v4": {"ris_peers_seeing": 320, "total_ris_peers": 330}, "v6": {"ris_peers_seeing": 0, "total_ris_peers": 340}},
+		"origins": [{"origin": 3333, "route_objects": ["RIPE"]}]}}`,
+	"looking-glass": `{"status": "ok", "data": {"rrcs": [
+		{"rrc": "RRC00", "location": "Amsterdam, Netherlands", "peers": [
+			{"peer": "192.0.2.1", "asn_origin": "3333", "as_path": "64500 3333", "prefix": "193.0.0.0/21"},
+			{"peer": "192.0.2.2", "asn_origin": "3333", "as_path": "64501 1299 3333", "prefix": "193.0.0.0/21"},
+			{"peer": "192.0.2.3", "asn_origin": "64666", "as_path": "64502 64666", "prefix": "193.0.0.0/21"}]},
+		{"rrc": "RRC21", "location": "Paris, France", "peers": [
+			{"peer": "198.51.100.1", "asn_origin": "3333", "as_path": "64503 3333", "prefix": "193.0.0.0/21"},
+			{"peer": "198.51.100.2", "asn_origin": "3333", "as_path": "64503 3333", "prefix": "193.0.0.0/16"}]}]}}`,
+}
+
+// newTestClient is a helper that returns a client of a fake RIPEstat API
+func newTestClient(t *testing.T) *bgp.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sourceapp") != bgp.SourceApp {
+			t.Errorf("expected sourceapp %q, got %q", bgp.SourceApp, r.URL.Query().Get("sourceapp"))
+		}
+		call := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/data.json")
+		if call == "rpki-validation" {
+			// AS3333 is authorized to originate the prefix, any other AS is not
+			if r.URL.Query().Get("resource") == "3333" {
+				w.Write([]byte(`{"status": "ok", "data": {"status": "valid", "validating_roas": [{"origin": "3333", "prefix": "193.0.0.0/21", "max_length": 21, "validity": "valid"}]}}`))
+			} else {
+				w.Write([]byte(`{"status": "ok", "data": {"status": "invalid_asn", "validating_roas": [{"origin": "3333", "prefix": "193.0.0.0/21", "max_length": 21, "validity": "invalid_asn"}]}}`))
+			}
+			return
+		}
+		response, ok := responses[call]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": "error", "messages": [["error", "unknown data call"]]}`))
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	client := bgp.NewClient(server.Client())
+	client.BaseURL = server.URL
+	return client
+}
+
+func TestLookup(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name               string
+		collectors         []string
+		expectedOrigins    []bgp.ASN
+		expectedPeers      []int
+		expectedRPKI       []string
+		expectedConsistent bool
+		expectedCollectors []string
+	}{
+		{name: "AllCollectors", expectedOrigins: []bgp.ASN{3333, 64666}, expectedPeers: []int{3, 1}, expectedRPKI: []string{"valid", "invalid_asn"}, expectedConsistent: false, expectedCollectors: []string{"RRC00", "RRC21"}},
+		{name: "OneCollector", collectors: []string{"rrc21"}, expectedOrigins: []bgp.ASN{3333}, expectedPeers: []int{1}, expectedRPKI: []string{"valid"}, expectedConsistent: true, expectedCollectors: []string{"RRC21"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := bgp.Lookup(context.Background(), newTestClient(t), netip.MustParsePrefix("193.0.0.0/21"), tc.collectors)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.PeersSeeing != 320 || result.PeersTotal != 330 {
+				t.Errorf("expected visibility 320/330, got %d/%d", result.PeersSeeing, result.PeersTotal)
+			}
+			var origins []bgp.ASN
+			var peers []int
+			var rpki []string
+			for _, o := range result.Origins {
+				origins, peers, rpki = append(origins, o.ASN), append(peers, o.Peers), append(rpki, o.RPKI)
+			}
+			if !reflect.DeepEqual(origins, tc.expectedOrigins) {
+				t.Errorf("expected origins %v, got %v", tc.expectedOrigins, origins)
+			}
+			if !reflect.DeepEqual(peers, tc.expectedPeers) {
+				t.Errorf("expected peers %v, got %v", tc.expectedPeers, peers)
+			}
+			if !reflect.DeepEqual(rpki, tc.expectedRPKI) {
+				t.Errorf("expected RPKI states %v, got %v", tc.expectedRPKI, rpki)
+			}
+			if result.Consistent != tc.expectedConsistent {
+				t.Errorf("expected consistent %t, got %t", tc.expectedConsistent, result.Consistent)
+			}
+			var collectors []string
+			for _, c := range result.Collectors {
+				collectors = append(collectors, c.Name)
+			}
+			if !reflect.DeepEqual(collectors, tc.expectedCollectors) {
+				t.Errorf("expected collectors %v, got %v", tc.expectedCollectors, collectors)
+			}
+		})
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	client := newTestClient(t)
+	client.BaseURL += "/unknown"
+	_, err := bgp.Lookup(context.Background(), client, netip.MustParsePrefix("193.0.0.0/21"), nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown data call") {
+		t.Errorf("expected the error message of the API, got %v", err)
+	}
+}
+
+func TestLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// The address of the fake API is used as it is, the name of the API is not resolved
+	if _, err := bgp.Lookup(context.Background(), newTestClient(t), netip.MustParsePrefix("193.0.0.0/21"), nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	client := bgp.NewClient(nil)
+	if _, err := bgp.Lookup(context.Background(), client, netip.MustParsePrefix("193.0.0.0/21"), nil); !errors.Is(err, ip.ErrLookupsDisabled) {
+		t.Errorf("expected %v, got %v", ip.ErrLookupsDisabled, err)
+	}
+}
+
+func TestParseCollectors(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "Default", input: "", expected: nil},
+		{name: "RIS", input: "RIS", expected: nil},
+		{name: "Collectors", input: "rrc00, RRC21", expected: []string{"rrc00", "rrc21"}},
+		{name: "Unknown", input: "route-views", expectErr: true},
+		{name: "Invalid", input: "rrc1", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			collectors, err := bgp.ParseCollectors(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(collectors, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, collectors)
+			}
+		})
+	}
+}
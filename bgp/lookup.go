@@ -0,0 +1,182 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Result is the state of a prefix in the global routing table
+type Result struct {
+	Prefix      string             `json:"prefix"`
+	PeersSeeing int                `json:"peers_seeing"`
+	PeersTotal  int                `json:"peers_total"`
+	FirstSeen   string             `json:"first_seen,omitempty"`
+	LastSeen    string             `json:"last_seen,omitempty"`
+	Consistent  bool               `json:"origin_consistent"`
+	Origins     []Origin           `json:"origins"`
+	Collectors  []CollectorSummary `json:"collectors"`
+}
+
+// Origin is an AS that originates the prefix, with the number of peers that
+// see the prefix originated by the AS and the RPKI validation state
+type Origin struct {
+	ASN          ASN      `json:"asn"`
+	Peers        int      `json:"peers"`
+	RouteObjects []string `json:"route_objects,omitempty"`
+	RPKI         string   `json:"rpki"`
+	ROAs         []ROA    `json:"roas,omitempty"`
+}
+
+// CollectorSummary is the number of peers of a route collector that see the
+// prefix, and the origin AS of their routes
+type CollectorSummary struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Peers    int    `json:"peers"`
+	Origins  []ASN  `json:"origins"`
+}
+
+// Visible is a function that returns true if any RIS peer sees the prefix
+func (r *Result) Visible() bool {
+	return r.PeersSeeing > 0 || len(r.Origins) > 0
+}
+
+// Lookup is a function that looks up the visibility, the origin AS and the
+// RPKI validation state of the prefix. The looking glass is limited to the
+// route collectors given (e.g. rrc00), all collectors are used if none are.
+func Lookup(ctx context.Context, client *Client, prefix netip.Prefix, collectors []string) (*Result, error) {
+	resource := prefix.Masked().String()
+	result := &Result{Prefix: resource}
+
+	// The visibility and the origins of the prefix in all of RIS
+	status, err := client.RoutingStatus(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	visibility := status.Visibility.V4
+	if prefix.Addr().Is6() {
+		visibility = status.Visibility.V6
+	}
+	result.PeersSeeing, result.PeersTotal = visibility.Seeing, visibility.Total
+	result.FirstSeen, result.LastSeen = status.FirstSeen.Time, status.LastSeen.Time
+
+	origins := make(map[ASN]*Origin)
+	origin := func(asn ASN) *Origin {
+		if origins[asn] == nil {
+			origins[asn] = &Origin{ASN: asn}
+		}
+		return origins[asn]
+	}
+	for _, o := range status.Origins {
+		origin(o.Origin).RouteObjects = o.RouteObjects
+	}
+
+	// The routes seen by the peers of the route collectors
+	lg, err := client.LookingGlass(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range lg.Collectors {
+		if len(collectors) > 0 && !containsFold(collectors, c.Name) {
+			continue
+		}
+		summary := CollectorSummary{Name: c.Name, Location: c.Location}
+		for _, p := range c.Peers {
+			// Only the routes to the prefix itself, not to less specifics
+			if p.Prefix != "" && p.Prefix != resource {
+				continue
+			}
+			summary.Peers++
+			origin(p.Origin).Peers++
+			if !slices.Contains(summary.Origins, p.Origin) {
+				summary.Origins = append(summary.Origins, p.Origin)
+			}
+		}
+		result.Collectors = append(result.Collectors, summary)
+	}
+
+	// Validate the prefix against the ROAs of every origin AS
+	for _, o := range origins {
+		if o.ASN == 0 {
+			continue
+		}
+		validation, err := client.RPKIValidation(ctx, o.ASN, resource)
+		if err != nil {
+			return nil, err
+		}
+		o.RPKI, o.ROAs = validation.Status, validation.ROAs
+	}
+
+	// The origins are sorted by the number of peers that see them
+	for _, o := range origins {
+		if o.ASN != 0 {
+			result.Origins = append(result.Origins, *o)
+		}
+	}
+	sort.Slice(result.Origins, func(i, j int) bool {
+		if result.Origins[i].Peers != result.Origins[j].Peers {
+			return result.Origins[i].Peers > result.Origins[j].Peers
+		}
+		return result.Origins[i].ASN < result.Origins[j].ASN
+	})
+
+	// A prefix originated by more than one AS (MOAS) may be hijacked or misconfigured
+	result.Consistent = len(result.Origins) <= 1
+	return result, nil
+}
+
+// ParseCollectors is a function that parses the looking glass selected by the
+// user: ris (all RIS route collectors) or a list of route collectors
+// separated by commas (e.g. rrc00,rrc21). A nil list selects all collectors.
+func ParseCollectors(lg string) ([]string, error) {
+	lg = strings.ToLower(strings.TrimSpace(lg))
+	if lg == "" || lg == "ris" {
+		return nil, nil
+	}
+
+	var collectors []string
+	for _, name := range strings.Split(lg, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) != 5 || !strings.HasPrefix(name, "rrc") || strings.Trim(name[3:], "0123456789") != "" {
+			return nil, fmt.Errorf("unknown looking glass: %s (use ris for all RIS route collectors, or collectors such as rrc00,rrc21)", name)
+		}
+		collectors = append(collectors, name)
+	}
+	return collectors, nil
+}
+
+// containsFold is a function that returns true if the list contains the
+// string, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
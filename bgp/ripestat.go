@@ -0,0 +1,219 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package bgp looks up the state of prefixes in the global routing table
+// using the RIPEstat Data API (https://stat.ripe.net/docs/data_api), which
+// is fed by the route collectors of the RIPE NCC Routing Information Service
+// (RIS): the visibility and the origin AS of a prefix, the routes seen by the
+// peers of the collectors (looking glass) and the RPKI validation state.
+package bgp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// DefaultBaseURL is the base URL of the RIPEstat Data API
+const DefaultBaseURL = "https://stat.ripe.net/data"
+
+// SourceApp identifies iptool to RIPEstat, as requested by its terms of use
+const SourceApp = "iptool"
+
+// Client is a client of the RIPEstat Data API
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient is a function that returns a client of the RIPEstat Data API
+// using the HTTP client (http.DefaultClient if nil)
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{BaseURL: DefaultBaseURL, HTTP: httpClient}
+}
+
+// ASN is an AS number. RIPEstat returns AS numbers both as JSON numbers and
+// as strings, ASN accepts both.
+type ASN uint32
+
+// UnmarshalJSON is a function that parses an AS number given as a number or
+// as a string (with or without the AS prefix)
+func (a *ASN) UnmarshalJSON(data []byte) error {
+	s := strings.TrimPrefix(strings.Trim(string(data), `"`), "AS")
+	if s == "" || s == "null" {
+		*a = 0
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid AS number: %s", data)
+	}
+	*a = ASN(n)
+	return nil
+}
+
+// String is a function that returns the AS number in the AS1234 notation
+func (a ASN) String() string {
+	return "AS" + strconv.FormatUint(uint64(a), 10)
+}
+
+// RoutingStatus is the routing status of a prefix (routing-status data call)
+type RoutingStatus struct {
+	FirstSeen struct {
+		Origin ASN    `json:"origin"`
+		Time   string `json:"time"`
+	} `json:"first_seen"`
+	LastSeen struct {
+		Origin ASN    `json:"origin"`
+		Time   string `json:"time"`
+	} `json:"last_seen"`
+	Visibility struct {
+		V4 Visibility `json:"v4"`
+		V6 Visibility `json:"v6"`
+	} `json:"visibility"`
+	Origins []struct {
+		Origin       ASN      `json:"origin"`
+		RouteObjects []string `json:"route_objects"`
+	} `json:"origins"`
+}
+
+// Visibility is the number of RIS peers that see a prefix
+type Visibility struct {
+	Seeing int `json:"ris_peers_seeing"`
+	Total  int `json:"total_ris_peers"`
+}
+
+// LookingGlass holds the routes to a prefix seen by the peers of the RIS
+// route collectors (looking-glass data call)
+type LookingGlass struct {
+	Collectors []Collector `json:"rrcs"`
+}
+
+// Collector is a RIS route collector (RRC) and the routes of its peers
+type Collector struct {
+	Name     string `json:"rrc"`
+	Location string `json:"location"`
+	Peers    []Peer `json:"peers"`
+}
+
+// Peer is a route to a prefix seen by a peer of a route collector
+type Peer struct {
+	Peer    string `json:"peer"`
+	Origin  ASN    `json:"asn_origin"`
+	ASPath  string `json:"as_path"`
+	Prefix  string `json:"prefix"`
+	NextHop string `json:"next_hop"`
+}
+
+// RPKIValidation is the RPKI validation state of a prefix announced by an
+// origin AS (rpki-validation data call)
+type RPKIValidation struct {
+	Status string `json:"status"`
+	ROAs   []ROA  `json:"validating_roas"`
+}
+
+// ROA is a route origin authorization that covers a prefix
+type ROA struct {
+	Origin    ASN    `json:"origin"`
+	Prefix    string `json:"prefix"`
+	MaxLength int    `json:"max_length"`
+	Validity  string `json:"validity"`
+}
+
+// get is a function that calls a data call of the API with the parameters
+// and decodes the data of the response into v
+func (c *Client) get(ctx context.Context, call string, params url.Values, v any) error {
+	params.Set("sourceapp", SourceApp)
+	u := strings.TrimSuffix(c.BaseURL, "/") + "/" + call + "/data.json?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	// The API is resolved by the HTTP client, unless name resolution is disabled
+	if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil && ip.LookupsDisabled() {
+		return fmt.Errorf("cannot resolve %s: %w", req.URL.Hostname(), ip.ErrLookupsDisabled)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The API returns the error messages in the messages of the response
+	var response struct {
+		Status   string          `json:"status"`
+		Messages [][]string      `json:"messages"`
+		Data     json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s: invalid response (HTTP status %d): %w", call, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || response.Status == "error" {
+		for _, message := range response.Messages {
+			if len(message) == 2 && message[0] == "error" {
+				return fmt.Errorf("%s: %s", call, message[1])
+			}
+		}
+		return fmt.Errorf("%s: HTTP status %d", call, resp.StatusCode)
+	}
+	return json.Unmarshal(response.Data, v)
+}
+
+// RoutingStatus is a function that returns the routing status of the prefix
+func (c *Client) RoutingStatus(ctx context.Context, prefix string) (*RoutingStatus, error) {
+	var status RoutingStatus
+	if err := c.get(ctx, "routing-status", url.Values{"resource": {prefix}}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// LookingGlass is a function that returns the routes to the prefix seen by
+// the peers of the RIS route collectors
+func (c *Client) LookingGlass(ctx context.Context, prefix string) (*LookingGlass, error) {
+	var lg LookingGlass
+	if err := c.get(ctx, "looking-glass", url.Values{"resource": {prefix}}, &lg); err != nil {
+		return nil, err
+	}
+	return &lg, nil
+}
+
+// RPKIValidation is a function that returns the RPKI validation state of the
+// prefix announced by the origin AS
+func (c *Client) RPKIValidation(ctx context.Context, origin ASN, prefix string) (*RPKIValidation, error) {
+	var validation RPKIValidation
+	params := url.Values{"resource": {strconv.FormatUint(uint64(origin), 10)}, "prefix": {prefix}}
+	if err := c.get(ctx, "rpki-validation", params, &validation); err != nil {
+		return nil, err
+	}
+	return &validation, nil
+}
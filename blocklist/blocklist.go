@@ -0,0 +1,167 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package blocklist
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// bogonsTXT is the embedded list of bogon prefixes
+//
+//go:embed bogons.txt
+var bogonsTXT string
+
+// BogonURLs are the URLs of the full bogon lists of Team Cymru, which also
+// contain the address space that is not allocated to a regional registry
+var BogonURLs = []string{
+	"https://www.team-cymru.org/Services/Bogons/fullbogons-ipv4.txt",
+	"https://www.team-cymru.org/Services/Bogons/fullbogons-ipv6.txt",
+}
+
+// Entry represents a prefix of a list with its description or reference
+// (e.g. the SBL number of a Spamhaus DROP entry)
+type Entry struct {
+	List        string       `json:"list"`
+	Prefix      netip.Prefix `json:"prefix"`
+	Description string       `json:"description,omitempty"`
+}
+
+// List is a list of prefixes indexed for longest-prefix matching
+type List struct {
+	Name string
+	trie *ip.Trie[Entry]
+}
+
+// dropRecord is a record of the JSON format of the Spamhaus DROP lists
+type dropRecord struct {
+	CIDR  string `json:"cidr"`
+	SBLID string `json:"sblid"`
+}
+
+// Parse is a function that parses a list of prefixes, one per line, with
+// an optional description after a ; or # (e.g. "1.10.16.0/20 ; SBL256894"
+// as in the Spamhaus DROP lists). Lines in the JSON format of the DROP
+// lists ({"cidr":"1.10.16.0/20","sblid":"SBL256894",...}) are accepted too.
+// Empty lines and comment lines are ignored.
+func Parse(name string, r io.Reader) (*List, error) {
+	list := &List{Name: name, trie: ip.NewTrie[Entry]()}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// The JSON format has one record per line and a metadata record at the end
+		if strings.HasPrefix(line, "{") {
+			var record dropRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", name, number, err)
+			}
+			if record.CIDR == "" {
+				continue
+			}
+			line = record.CIDR + " ; " + record.SBLID
+		}
+
+		field, description, _ := strings.Cut(strings.ReplaceAll(line, "#", ";"), ";")
+		prefix, err := ip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", name, number, err)
+		}
+		list.trie.Insert(prefix, Entry{List: name, Prefix: prefix, Description: strings.TrimSpace(description)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return list, nil
+}
+
+// Bogons is a function that returns the bogon list, the list downloaded
+// with the update command if it exists, otherwise the embedded list
+func Bogons() (*List, error) {
+	if path, err := BogonPath(); err == nil {
+		if f, err := os.Open(path); err == nil {
+			defer f.Close()
+			return Parse("bogons", f)
+		}
+	}
+	return Parse("bogons", strings.NewReader(bogonsTXT))
+}
+
+// BogonPath is a function that returns the path of the downloaded bogon
+// list in the cache directory of the user (e.g. ~/.cache/iptool/bogons.txt)
+func BogonPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iptool", "bogons.txt"), nil
+}
+
+// Len is a function that returns the number of prefixes in the list
+func (l *List) Len() int {
+	return l.trie.Len()
+}
+
+// Check is a function that returns the most specific entry of the list
+// that covers the whole prefix (a single address is given as a /32 or
+// /128 prefix). The second return value is false if no entry covers it.
+func (l *List) Check(prefix netip.Prefix) (Entry, bool) {
+	bits := prefix.Bits()
+	if prefix.Addr().Is4In6() {
+		bits = max(bits-96, 0)
+	}
+	for _, m := range l.trie.Matches(prefix.Addr()) {
+		if m.Prefix.Bits() <= bits {
+			return m.Values[0], true
+		}
+	}
+	return Entry{}, false
+}
+
+// Overlaps is a function that returns the entries of the list that are
+// more specific than the prefix and lie within it, i.e. the entries that
+// cover only a part of the prefix, in address order
+func (l *List) Overlaps(prefix netip.Prefix) []Entry {
+	bits := prefix.Bits()
+	if prefix.Addr().Is4In6() {
+		bits = max(bits-96, 0)
+	}
+	var entries []Entry
+	for _, m := range l.trie.Within(prefix) {
+		if m.Prefix.Bits() > bits {
+			entries = append(entries, m.Values...)
+		}
+	}
+	return entries
+}
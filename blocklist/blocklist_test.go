@@ -0,0 +1,139 @@
+package blocklist_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/blocklist"
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestBogons(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	bogons, err := blocklist.Bogons()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bogons.Len() < 20 {
+		t.Errorf("expected at least 20 bogon prefixes, got %d", bogons.Len())
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "10.1.2.3", expected: "10.0.0.0/8"},
+		{input: "10.1.0.0/16", expected: "10.0.0.0/8"},
+		{input: "::ffff:192.168.1.1", expected: "192.168.0.0/16"},
+		{input: "2001:db8::1", expected: "2001:db8::/32"},
+		{input: "::1", expected: "::1/128"},
+		{input: "239.1.1.1", expected: "224.0.0.0/4"},
+		{input: "8.8.8.8", expected: ""},
+		{input: "0.0.0.0/0", expected: ""},
+		{input: "2606:4700::1111", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			prefix, err := ip.ParsePrefix(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, ok := bogons.Check(prefix)
+			if got := map[bool]string{true: entry.Prefix.String()}[ok]; got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+			if ok && (entry.List != "bogons" || entry.Description == "") {
+				t.Errorf("unexpected entry: %+v", entry)
+			}
+		})
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	list, err := blocklist.Parse("bogons", strings.NewReader("10.0.0.0/8\n192.0.0.0/24\n192.0.2.0/24\n2001:db8::/32\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "0.0.0.0/0", expected: "10.0.0.0/8 192.0.0.0/24 192.0.2.0/24"},
+		{input: "10.0.0.0/7", expected: "10.0.0.0/8"},
+		{input: "192.0.0.0/16", expected: "192.0.0.0/24 192.0.2.0/24"},
+		{input: "::ffff:192.0.0.0/112", expected: "192.0.0.0/24 192.0.2.0/24"},
+		{input: "2001::/16", expected: "2001:db8::/32"},
+		{input: "10.0.0.0/8", expected: ""},
+		{input: "10.1.0.0/16", expected: ""},
+		{input: "8.0.0.0/8", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			prefix, err := ip.ParsePrefix(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range list.Overlaps(prefix) {
+				got = append(got, entry.Prefix.String())
+			}
+			if strings.Join(got, " ") != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	drop := `; Spamhaus DROP List 2024/01/01
+1.10.16.0/20 ; SBL256894
+2.56.192.0/22 ; SBL459831
+{"cidr":"5.42.92.0/24","sblid":"SBL650301","rir":"ripencc"}
+{"type":"metadata","timestamp":1704067200,"size":3}
+192.0.2.7  # a single address
+`
+	list, err := blocklist.Parse("drop", strings.NewReader(drop))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list.Len() != 4 {
+		t.Errorf("expected 4 prefixes, got %d", list.Len())
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		input       string
+		description string
+		ok          bool
+	}{
+		{input: "1.10.20.1", description: "SBL256894", ok: true},
+		{input: "5.42.92.0/25", description: "SBL650301", ok: true},
+		{input: "192.0.2.7", description: "a single address", ok: true},
+		{input: "5.42.92.0/23", ok: false},
+		{input: "192.0.2.8", ok: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			prefix, _ := ip.ParsePrefix(tc.input)
+			entry, ok := list.Check(prefix)
+			if ok != tc.ok || entry.Description != tc.description {
+				t.Errorf("expected %v %q, got %v %q", tc.ok, tc.description, ok, entry.Description)
+			}
+		})
+	}
+
+	// Invalid lines are reported with their line number
+	if _, err := blocklist.Parse("bad", strings.NewReader("10.0.0.0/8\nnot-a-prefix ; x\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
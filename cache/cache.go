@@ -0,0 +1,213 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Namespaces of the cached lookups
+const (
+	NamespaceDNS   = "dns"
+	NamespaceWhois = "whois"
+	NamespaceASN   = "asn"
+	NamespaceGeoIP = "geoip"
+	NamespaceOUI   = "oui"
+)
+
+// Namespaces is the list of all namespaces, e.g. for clearing the whole cache
+var Namespaces = []string{NamespaceDNS, NamespaceWhois, NamespaceASN, NamespaceGeoIP, NamespaceOUI}
+
+// disabled controls whether the default cache is used by the package level functions
+var disabled bool
+
+// Cache is a TTL based on-disk cache of lookup results. Every entry is
+// stored as a JSON file in a directory per namespace.
+type Cache struct {
+	Dir string
+}
+
+// entry is the on-disk format of a cached value
+type entry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// New is a function that returns a cache storing its entries in dir
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// Default is a function that returns the cache in the cache directory of
+// the user (e.g. ~/.cache/iptool on Linux)
+func Default() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(dir, "iptool")), nil
+}
+
+// Disable is a function that disables (or re-enables) the cache used by
+// Remember. When disabled, every lookup goes to the upstream source.
+func Disable(disable bool) {
+	disabled = disable
+}
+
+// path is a function that returns the file name of the entry with the key
+func (c *Cache) path(namespace, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, namespace, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get is a function that reads the value stored with the key in the
+// namespace into v. It returns false if there is no entry or if the entry has
+// expired.
+func (c *Cache) Get(namespace, key string, v any) (bool, error) {
+	data, err := os.ReadFile(c.path(namespace, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Treat corrupt and expired entries as missing
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key || time.Now().After(e.Expires) {
+		return false, nil
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Set is a function that stores the value v with the key in the namespace
+// for the duration ttl
+func (c *Cache) Set(namespace, key string, v any, ttl time.Duration) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry{Key: key, Expires: time.Now().Add(ttl), Value: value})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so that concurrent readers never see partial entries
+	path := c.path(namespace, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Clear is a function that removes all entries in the namespaces and
+// returns the number of entries removed. All namespaces are cleared if none
+// are given.
+func (c *Cache) Clear(namespaces ...string) (int, error) {
+	if len(namespaces) == 0 {
+		namespaces = Namespaces
+	}
+
+	removed := 0
+	for _, namespace := range namespaces {
+		files, err := filepath.Glob(filepath.Join(c.Dir, namespace, "*.json"))
+		if err != nil {
+			return removed, err
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Stats is a function that returns the number of entries and their total
+// size in bytes in the namespace
+func (c *Cache) Stats(namespace string) (entries int, size int64, err error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, namespace, "*.json"))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		entries++
+		size += info.Size()
+	}
+	return entries, size, nil
+}
+
+// Remember is a function that returns the cached result of a lookup in the
+// default cache, or calls lookup and caches its result for the duration ttl.
+// Failed lookups are not cached. The cache is bypassed if it is disabled or
+// if the cache directory is unavailable.
+func Remember[T any](namespace, key string, ttl time.Duration, lookup func() (T, error)) (T, error) {
+	if disabled {
+		return lookup()
+	}
+	c, err := Default()
+	if err != nil {
+		return lookup()
+	}
+
+	// Return the cached value if there is one
+	var value T
+	if ok, _ := c.Get(namespace, key, &value); ok {
+		return value, nil
+	}
+
+	// Look up and cache the value, failing to cache is not an error
+	value, err = lookup()
+	if err != nil {
+		return value, err
+	}
+	c.Set(namespace, key, value, ttl)
+	return value, nil
+}
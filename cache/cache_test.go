@@ -0,0 +1,102 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/cache"
+)
+
+func TestCacheGetSet(t *testing.T) {
+	c := cache.New(t.TempDir())
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		key       string
+		value     string
+		ttl       time.Duration
+		expectHit bool
+	}{
+		{name: "Fresh", key: "example.com", value: "93.184.216.34", ttl: time.Hour, expectHit: true},
+		{name: "Expired", key: "expired.example.com", value: "10.0.0.1", ttl: -time.Second, expectHit: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := c.Set(cache.NamespaceDNS, tc.key, tc.value, tc.ttl); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var value string
+			hit, err := c.Get(cache.NamespaceDNS, tc.key, &value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hit != tc.expectHit {
+				t.Fatalf("expected hit %t, got %t", tc.expectHit, hit)
+			}
+			if hit && value != tc.value {
+				t.Errorf("expected %s, got %s", tc.value, value)
+			}
+		})
+	}
+
+	// A missing key is not an error
+	var value string
+	if hit, err := c.Get(cache.NamespaceWhois, "missing", &value); hit || err != nil {
+		t.Errorf("expected miss without error, got hit %t and error %v", hit, err)
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	c := cache.New(t.TempDir())
+	c.Set(cache.NamespaceDNS, "a", 1, time.Hour)
+	c.Set(cache.NamespaceDNS, "b", 2, time.Hour)
+	c.Set(cache.NamespaceOUI, "c", 3, time.Hour)
+
+	// Clear a single namespace
+	removed, err := c.Clear(cache.NamespaceDNS)
+	if err != nil || removed != 2 {
+		t.Fatalf("expected 2 entries removed, got %d (error %v)", removed, err)
+	}
+	if entries, _, _ := c.Stats(cache.NamespaceOUI); entries != 1 {
+		t.Errorf("expected 1 entry left in %s, got %d", cache.NamespaceOUI, entries)
+	}
+
+	// Clear all namespaces
+	removed, err = c.Clear()
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 entry removed, got %d (error %v)", removed, err)
+	}
+}
+
+func TestRememberDisabled(t *testing.T) {
+	cache.Disable(true)
+	defer cache.Disable(false)
+
+	// With the cache disabled, every call must go to the lookup function
+	calls := 0
+	lookup := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Remember(cache.NamespaceDNS, "iptool-test", time.Hour, lookup); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 lookups, got %d", calls)
+	}
+
+	// Errors are passed through
+	_, err := cache.Remember(cache.NamespaceDNS, "iptool-test", time.Hour, func() (int, error) {
+		return 0, errors.New("lookup failed")
+	})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
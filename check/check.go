@@ -0,0 +1,266 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/probe"
+)
+
+// probeNamePattern matches the valid names of the probes of a check, which
+// are Starlark identifiers
+var probeNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config is the definition of a check in the configuration file, e.g.
+//
+//	checks:
+//	  web:
+//	    probes:
+//	      dns: dns://www.example.com
+//	      https: tcp://www.example.com:443
+//	    alert: dns.failed >= 1 and https.failed >= 2
+//	    hook: notify-send "web is down"
+//	    recover-hook: notify-send "web is up"
+//	    result-hook: echo "$IPTOOL_CHECK_VARS" >> web.log
+//
+// The alert condition is a Starlark expression. Instead, the Starlark script
+// of the check can define an alert(probes) function, and an
+// on_result(probes, alerting) function that runs after every round of
+// probes. The hook runs when the alert condition becomes true, the recover
+// hook when it becomes false again and the result hook after every round.
+type Config struct {
+	Probes      map[string]string `mapstructure:"probes"`
+	Alert       string            `mapstructure:"alert"`
+	Script      string            `mapstructure:"script"`
+	Hook        string            `mapstructure:"hook"`
+	RecoverHook string            `mapstructure:"recover-hook"`
+	ResultHook  string            `mapstructure:"result-hook"`
+}
+
+// Check is a composite check: a set of named probes and an alert condition
+// over the results of the probes
+type Check struct {
+	Name        string
+	Hook        string
+	RecoverHook string
+	ResultHook  string
+
+	// Output receives the output of print in the script (standard error
+	// if nil)
+	Output io.Writer
+
+	script   *script
+	probes   []*probeState
+	alerting bool
+}
+
+// probeState holds the results of a named probe of a check
+type probeState struct {
+	name   string
+	prober probe.Prober
+
+	sent     int
+	received int
+	failed   int // consecutive failures
+	lastRTT  time.Duration
+	lastErr  error
+}
+
+// Event is the outcome of a round of probes of a check
+type Event struct {
+	Check    *Check
+	Alerting bool
+	Changed  bool
+}
+
+// New is a function that returns a check from its configuration
+func New(name string, cfg Config) (*Check, error) {
+	if len(cfg.Probes) == 0 {
+		return nil, fmt.Errorf("check %s: no probes defined", name)
+	}
+	c := &Check{Name: name, Hook: cfg.Hook, RecoverHook: cfg.RecoverHook, ResultHook: cfg.ResultHook}
+
+	// Create the probes, sorted by name so that the output is deterministic
+	names := make([]string, 0, len(cfg.Probes))
+	for probeName := range cfg.Probes {
+		names = append(names, probeName)
+	}
+	sort.Strings(names)
+	for _, probeName := range names {
+		if !probeNamePattern.MatchString(probeName) {
+			return nil, fmt.Errorf("check %s: invalid probe name: %s", name, probeName)
+		}
+		prober, err := probe.New(cfg.Probes[probeName])
+		if err != nil {
+			return nil, fmt.Errorf("check %s: probe %s: %w", name, probeName, err)
+		}
+		c.probes = append(c.probes, &probeState{name: probeName, prober: prober})
+	}
+
+	// Compile the alert condition and the script
+	script, err := newScript(cfg.Alert, cfg.Script, c.probes)
+	if err != nil {
+		return nil, fmt.Errorf("check %s: %w", name, err)
+	}
+	c.script = script
+
+	return c, nil
+}
+
+// Variables is a function that returns the variables of the probes, which
+// are the fields of the probes in the alert condition (e.g. dns.failed):
+//
+//	<probe>.ok      true if the last probe succeeded
+//	<probe>.failed  number of consecutive failed probes
+//	<probe>.rtt     response time of the last probe in milliseconds (0 if failed)
+//	<probe>.loss    packet loss since the start in percent
+//	<probe>.sent    number of probes sent
+func (c *Check) Variables() map[string]any {
+	vars := make(map[string]any)
+	for _, p := range c.probes {
+		loss := 0.0
+		if p.sent > 0 {
+			loss = float64(p.sent-p.received) / float64(p.sent) * 100
+		}
+		vars[p.name+".ok"] = p.sent > 0 && p.lastErr == nil
+		vars[p.name+".failed"] = float64(p.failed)
+		vars[p.name+".rtt"] = float64(p.lastRTT) / float64(time.Millisecond)
+		vars[p.name+".loss"] = loss
+		vars[p.name+".sent"] = float64(p.sent)
+	}
+	return vars
+}
+
+// Run is a function that sends one probe of every probe of the check
+// (concurrently), updates the results and evaluates the alert condition
+func (c *Check) Run(ctx context.Context, timeout time.Duration) (Event, error) {
+	var wg sync.WaitGroup
+	for _, p := range c.probes {
+		wg.Add(1)
+		go func(p *probeState) {
+			defer wg.Done()
+			rtt, err := p.prober.Probe(ctx, timeout)
+			p.sent++
+			p.lastRTT, p.lastErr = rtt, err
+			if err != nil {
+				p.failed++
+				p.lastRTT = 0
+			} else {
+				p.received++
+				p.failed = 0
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	output := c.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	alerting, err := c.script.evalAlert(c.probes, output)
+	if err != nil {
+		return Event{}, fmt.Errorf("check %s: %w", c.Name, err)
+	}
+	if err := c.script.onResult(c.probes, alerting, output); err != nil {
+		return Event{}, fmt.Errorf("check %s: %w", c.Name, err)
+	}
+
+	event := Event{Check: c, Alerting: alerting, Changed: alerting != c.alerting}
+	c.alerting = alerting
+	return event, nil
+}
+
+// RunHooks is a function that runs the hook commands of the check for an
+// event: the result hook (every round), and the hook or the recover hook
+// when the alert condition has changed. See runHook for the environment.
+func (e Event) RunHooks(ctx context.Context) error {
+	if err := e.runHook(ctx, e.Check.ResultHook); err != nil {
+		return err
+	}
+	if !e.Changed {
+		return nil
+	}
+	if e.Alerting {
+		return e.runHook(ctx, e.Check.Hook)
+	}
+	return e.runHook(ctx, e.Check.RecoverHook)
+}
+
+// runHook is a function that runs a hook command using the shell. The name
+// of the check, the state (alert or ok) and the variables (JSON) are passed
+// in the IPTOOL_CHECK, IPTOOL_CHECK_STATE and IPTOOL_CHECK_VARS environment
+// variables.
+func (e Event) runHook(ctx context.Context, command string) error {
+	if command == "" {
+		return nil
+	}
+
+	state := "ok"
+	if e.Alerting {
+		state = "alert"
+	}
+	vars, err := json.Marshal(e.Check.Variables())
+	if err != nil {
+		return err
+	}
+
+	env := []string{
+		"IPTOOL_CHECK=" + e.Check.Name,
+		"IPTOOL_CHECK_STATE=" + state,
+		"IPTOOL_CHECK_VARS=" + string(vars),
+	}
+	if err := notify.Command(ctx, command, env); err != nil {
+		return fmt.Errorf("check %s: hook failed: %w", e.Check.Name, err)
+	}
+	return nil
+}
+
+// Status is a function that returns a short summary of the last results of
+// the probes of the check, e.g. "dns=ok (12.34 ms), https=failed (2x)"
+func (c *Check) Status() string {
+	var sb strings.Builder
+	for i, p := range c.probes {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		switch {
+		case p.sent == 0:
+			fmt.Fprintf(&sb, "%s=pending", p.name)
+		case p.lastErr != nil:
+			fmt.Fprintf(&sb, "%s=failed (%dx)", p.name, p.failed)
+		default:
+			fmt.Fprintf(&sb, "%s=ok (%.2f ms)", p.name, float64(p.lastRTT)/float64(time.Millisecond))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package check_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/check"
+	"github.com/bitcanon/iptool/probe"
+)
+
+// fakeResults holds the results of the fake probes, by address
+var fakeResults = map[string][]bool{}
+
+// fakeProber is a prober that returns the results in fakeResults in turn
+type fakeProber struct {
+	address string
+	count   int
+}
+
+func (p *fakeProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	results := fakeResults[p.address]
+	ok := results[p.count%len(results)]
+	p.count++
+	if !ok {
+		return 0, errors.New("timeout")
+	}
+	return 10 * time.Millisecond, nil
+}
+
+func (p *fakeProber) String() string {
+	return "fake://" + p.address
+}
+
+func init() {
+	probe.Register("fake", func(address string) (probe.Prober, error) {
+		return &fakeProber{address: address}, nil
+	})
+}
+
+func TestNew(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		cfg       check.Config
+		expectErr string
+	}{
+		{name: "Valid", cfg: check.Config{Probes: map[string]string{"dns": "fake://dns"}, Alert: "dns.failed >= 2"}},
+		{name: "NoProbes", cfg: check.Config{Alert: "true"}, expectErr: "no probes"},
+		{name: "NoAlert", cfg: check.Config{Probes: map[string]string{"dns": "fake://dns"}}, expectErr: "no alert"},
+		{name: "InvalidProbeName", cfg: check.Config{Probes: map[string]string{"d.ns": "fake://dns"}, Alert: "true"}, expectErr: "invalid probe name"},
+		{name: "InvalidTarget", cfg: check.Config{Probes: map[string]string{"dns": "gopher://dns"}, Alert: "true"}, expectErr: "unknown probe type"},
+		{name: "InvalidAlert", cfg: check.Config{Probes: map[string]string{"dns": "fake://dns"}, Alert: "dns.failed >="}, expectErr: "invalid alert condition"},
+		{name: "UnknownVariable", cfg: check.Config{Probes: map[string]string{"dns": "fake://dns"}, Alert: "https.failed >= 2"}, expectErr: "undefined: https"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := check.New("test", tc.cfg)
+			if tc.expectErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+				t.Errorf("expected error containing %q, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	// DNS fails from the second round on, HTTPS fails every other round
+	fakeResults["dns"] = []bool{true, false, false, false, false}
+	fakeResults["https"] = []bool{false, true}
+
+	c, err := check.New("web", check.Config{
+		Probes: map[string]string{"dns": "fake://dns", "https": "fake://https"},
+		Alert:  "dns.failed >= 1 and https.failed >= 1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Expected alert state and change after every round
+	expected := []struct {
+		alerting, changed bool
+	}{
+		{alerting: false, changed: false}, // dns ok, https failed
+		{alerting: false, changed: false}, // dns failed, https ok
+		{alerting: true, changed: true},   // dns failed, https failed
+		{alerting: false, changed: true},  // dns failed, https ok
+	}
+	for i, e := range expected {
+		event, err := c.Run(context.Background(), time.Second)
+		if err != nil {
+			t.Fatalf("round %d: unexpected error: %v", i+1, err)
+		}
+		if event.Alerting != e.alerting || event.Changed != e.changed {
+			t.Errorf("round %d: expected alerting=%v changed=%v, got alerting=%v changed=%v", i+1, e.alerting, e.changed, event.Alerting, event.Changed)
+		}
+	}
+
+	// Check the variables and the status after the last round
+	vars := c.Variables()
+	if vars["dns.failed"] != 3.0 || vars["https.ok"] != true || vars["https.loss"] != 50.0 || vars["dns.sent"] != 4.0 {
+		t.Errorf("unexpected variables: %v", vars)
+	}
+	if status := c.Status(); status != "dns=failed (3x), https=ok (10.00 ms)" {
+		t.Errorf("unexpected status: %s", status)
+	}
+}
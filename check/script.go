@@ -0,0 +1,196 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// maxSteps is the maximum number of execution steps of a single call of
+// the Starlark code of a check, which stops runaway scripts
+const maxSteps = 1_000_000
+
+// fileOptions are the Starlark dialect of the checks: no while loops, no
+// recursion and no top-level statements other than definitions
+var fileOptions = &syntax.FileOptions{}
+
+// script holds the Starlark code of a check. The alert condition is either
+// an expression over the probes, which are predeclared by name (e.g.
+// dns.failed >= 1 and https.failed >= 2), or an alert(probes) function
+// defined in the script. The script may also define an on_result(probes,
+// alerting) function, which is called after every round of probes.
+type script struct {
+	alert   string
+	globals starlark.StringDict
+}
+
+// newScript is a function that compiles the alert expression and the script
+// of a check, and evaluates the alert condition once (before any probe was
+// sent) to report unknown names and other errors early
+func newScript(alert, source string, probes []*probeState) (*script, error) {
+	s := &script{alert: alert, globals: starlark.StringDict{}}
+	if source != "" {
+		globals, err := starlark.ExecFileOptions(fileOptions, s.thread(io.Discard), "script", source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script: %w", scriptError(err))
+		}
+		s.globals = globals
+	}
+
+	// The alert condition is an expression or a function of the script
+	_, hasAlert := s.globals["alert"]
+	switch {
+	case alert == "" && !hasAlert:
+		return nil, fmt.Errorf("no alert condition defined")
+	case alert != "" && hasAlert:
+		return nil, fmt.Errorf("the alert condition is defined twice (alert and the alert function of the script)")
+	}
+	if alert != "" {
+		if _, err := syntax.ParseExpr("alert", alert, 0); err != nil {
+			return nil, fmt.Errorf("invalid alert condition: %w", scriptError(err))
+		}
+	}
+	for _, name := range []string{"alert", "on_result"} {
+		if fn, ok := s.globals[name]; ok {
+			if _, ok := fn.(starlark.Callable); !ok {
+				return nil, fmt.Errorf("invalid script: %s is not a function", name)
+			}
+		}
+	}
+	for _, p := range probes {
+		if _, ok := s.globals[p.name]; ok {
+			return nil, fmt.Errorf("invalid script: the name %s is used by a probe", p.name)
+		}
+	}
+
+	if _, err := s.evalAlert(probes, io.Discard); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// thread is a function that returns a Starlark thread that writes the
+// output of print to w
+func (s *script) thread(w io.Writer) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: "check",
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintln(w, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	return thread
+}
+
+// evalAlert is a function that evaluates the alert condition, which must
+// be a boolean, with the results of the probes
+func (s *script) evalAlert(probes []*probeState, w io.Writer) (bool, error) {
+	var result starlark.Value
+	var err error
+	if s.alert != "" {
+		env := starlark.StringDict{}
+		for name, value := range s.globals {
+			env[name] = value
+		}
+		for _, p := range probes {
+			env[p.name] = p.value()
+		}
+		var fn *starlark.Function
+		if fn, err = starlark.ExprFuncOptions(fileOptions, "alert", s.alert, env); err == nil {
+			result, err = starlark.Call(s.thread(w), fn, nil, nil)
+		}
+	} else {
+		result, err = starlark.Call(s.thread(w), s.globals["alert"], starlark.Tuple{probeDict(probes)}, nil)
+	}
+	if err != nil {
+		return false, fmt.Errorf("alert condition: %w", scriptError(err))
+	}
+
+	alerting, ok := result.(starlark.Bool)
+	if !ok {
+		return false, fmt.Errorf("alert condition: got %s, want bool", result.Type())
+	}
+	return bool(alerting), nil
+}
+
+// onResult is a function that calls the on_result function of the script,
+// if it is defined
+func (s *script) onResult(probes []*probeState, alerting bool, w io.Writer) error {
+	fn, ok := s.globals["on_result"]
+	if !ok {
+		return nil
+	}
+	if _, err := starlark.Call(s.thread(w), fn, starlark.Tuple{probeDict(probes), starlark.Bool(alerting)}, nil); err != nil {
+		return fmt.Errorf("on_result: %w", scriptError(err))
+	}
+	return nil
+}
+
+// value is a function that returns the results of a probe as a Starlark
+// struct with the same fields as the variables of the probe (see Variables)
+func (p *probeState) value() *starlarkstruct.Struct {
+	loss := 0.0
+	if p.sent > 0 {
+		loss = float64(p.sent-p.received) / float64(p.sent) * 100
+	}
+	return starlarkstruct.FromStringDict(starlark.String(p.name), starlark.StringDict{
+		"ok":     starlark.Bool(p.sent > 0 && p.lastErr == nil),
+		"failed": starlark.MakeInt(p.failed),
+		"rtt":    starlark.Float(float64(p.lastRTT) / float64(time.Millisecond)),
+		"loss":   starlark.Float(loss),
+		"sent":   starlark.MakeInt(p.sent),
+	})
+}
+
+// probeDict is a function that returns the results of the probes as a
+// frozen Starlark dict, by probe name
+func probeDict(probes []*probeState) *starlark.Dict {
+	dict := starlark.NewDict(len(probes))
+	for _, p := range probes {
+		dict.SetKey(starlark.String(p.name), p.value())
+	}
+	dict.Freeze()
+	return dict
+}
+
+// scriptError is a function that returns the message of a Starlark
+// evaluation error, prefixed with the position of the innermost call in
+// the code of the check (e.g. script:2:12)
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if !errors.As(err, &evalErr) {
+		return err
+	}
+	for i := range evalErr.CallStack {
+		if frame := evalErr.CallStack.At(i); frame.Pos.IsValid() {
+			return fmt.Errorf("%s: %s", frame.Pos, evalErr.Msg)
+		}
+	}
+	return errors.New(evalErr.Msg)
+}
@@ -0,0 +1,102 @@
+package check_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/check"
+)
+
+func TestAlert(t *testing.T) {
+	// After one round: dns failed, https succeeded in 10 ms
+	fakeResults["alert-dns"] = []bool{false}
+	fakeResults["alert-https"] = []bool{true}
+	probes := map[string]string{"dns": "fake://alert-dns", "https": "fake://alert-https"}
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		alert     string
+		script    string
+		expected  bool
+		expectErr string
+	}{
+		{name: "Field", alert: "https.ok", expected: true},
+		{name: "Constant", alert: "True", expected: true},
+		{name: "Comparison", alert: "dns.failed >= 1", expected: true},
+		{name: "ComparisonFloat", alert: "https.rtt > 9.5", expected: true},
+		{name: "And", alert: "dns.failed >= 1 and https.failed >= 2", expected: false},
+		{name: "Or", alert: "dns.failed >= 1 or https.failed >= 2", expected: true},
+		{name: "Not", alert: "not dns.ok", expected: true},
+		{name: "Loss", alert: "dns.loss == 100.0 and https.loss == 0.0", expected: true},
+		{name: "Helper", alert: "down(dns, https)", script: "def down(*probes):\n    return any([not p.ok for p in probes])\n", expected: true},
+		{name: "Function", script: "def alert(probes):\n    return len([p for p in probes.values() if not p.ok]) >= 2\n", expected: false},
+		{name: "NotABool", alert: "dns.failed", expectErr: "want bool"},
+		{name: "UnknownField", alert: "dns.lost > 1", expectErr: "no .lost attribute"},
+		{name: "UnknownProbe", alert: "tcp.ok", expectErr: "undefined: tcp"},
+		{name: "Syntax", alert: "dns.failed >=", expectErr: "invalid alert condition"},
+		{name: "NoCondition", script: "x = 1\n", expectErr: "no alert condition"},
+		{name: "Twice", alert: "True", script: "def alert(probes):\n    return True\n", expectErr: "defined twice"},
+		{name: "NotAFunction", script: "alert = True\n", expectErr: "alert is not a function"},
+		{name: "ProbeName", alert: "True", script: "dns = 1\n", expectErr: "used by a probe"},
+		{name: "While", script: "def alert(probes):\n    while True:\n        pass\n", expectErr: "invalid script"},
+		{name: "Recursion", script: "def f(n):\n    return f(n)\ndef alert(probes):\n    return f(1)\n", expectErr: "called recursively"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := check.New("test", check.Config{Probes: probes, Alert: tc.alert, Script: tc.script})
+			if err == nil {
+				var event check.Event
+				event, err = c.Run(context.Background(), time.Second)
+				if err == nil && event.Alerting != tc.expected {
+					t.Errorf("expected %v, got %v", tc.expected, event.Alerting)
+				}
+			}
+			if tc.expectErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+				t.Errorf("expected error containing %q, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestOnResult(t *testing.T) {
+	fakeResults["result-dns"] = []bool{true, false}
+
+	c, err := check.New("test", check.Config{
+		Probes: map[string]string{"dns": "fake://result-dns"},
+		Script: `
+def alert(probes):
+    return not probes["dns"].ok
+
+def on_result(probes, alerting):
+    print("dns sent=%d failed=%d alerting=%s" % (probes["dns"].sent, probes["dns"].failed, alerting))
+`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The output of print is written to the output of the check
+	var out bytes.Buffer
+	c.Output = &out
+	for i := 0; i < 2; i++ {
+		if _, err := c.Run(context.Background(), time.Second); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := "dns sent=1 failed=0 alerting=False\ndns sent=2 failed=1 alerting=True\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/extract"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// aggregateCmd represents the aggregate command
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate [file]",
+	Short: "Count the IP addresses in a log file or text by subnet",
+	Long: `Count the IP addresses in a log file or text by subnet.
+
+Every IPv4 and IPv6 address in the input (a file, or standard input when no
+file or - is given) is counted in the subnet it belongs to, and the subnets
+are printed ranked by the number of occurrences, with the number of unique
+addresses seen in the subnet. This is a quick way to find the noisy subnets
+in a log.
+
+The addresses are grouped into subnets of --by bits for IPv4 (/24 by
+default) and --by6 bits for IPv6 (/64 by default), or into the subnets given
+with --subnets (the most specific subnet that contains the address). Use
+--by /32 to count the individual addresses.
+
+Use --columns to only count the addresses in some columns (fields) of the
+lines, numbered from 1, e.g. the client address of an access log. The
+columns are separated by whitespace, or by the --delimiter.
+
+Examples:
+  iptool aggregate --input-file ips.txt --by /24 --top 20
+  iptool aggregate /var/log/nginx/access.log --columns 1 --by /16
+  iptool aggregate /var/log/auth.log -4 --top 0 --json
+  journalctl -u sshd | iptool aggregate --by /24 --by6 /48`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input := viper.GetString("aggregate.input-file")
+		if len(args) > 0 {
+			if input != "" {
+				return exitcode.New(exitcode.Usage, errors.New("invalid input: give either a file or --input-file, not both"))
+			}
+			input = args[0]
+		}
+		if input == "" {
+			input = "-"
+		}
+		return aggregateAction(os.Stdout, os.Stdin, input)
+	},
+}
+
+// aggregateJSON is the JSON representation of a subnet of the aggregate command
+type aggregateJSON struct {
+	Rank      int          `json:"rank"`
+	Prefix    netip.Prefix `json:"prefix"`
+	Type      string       `json:"type"`
+	Count     int          `json:"count"`
+	Share     float64      `json:"share"`
+	Addresses int          `json:"addresses"`
+}
+
+// parsePrefixLength is a function that parses a prefix length with or
+// without the leading slash (e.g. /24 or 24) and checks it against the
+// number of bits of the address family
+func parsePrefixLength(s string, bits int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "/"))
+	if err != nil || n < 0 || n > bits {
+		return 0, fmt.Errorf("invalid prefix length: %s (must be between /0 and /%d)", s, bits)
+	}
+	return n, nil
+}
+
+// aggregateAction is the action function for the aggregate command
+func aggregateAction(out io.Writer, stdin io.Reader, input string) error {
+	// Parse the prefix lengths and subnets to group the addresses into
+	bits4, err := parsePrefixLength(viper.GetString("aggregate.by"), 32)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	bits6, err := parsePrefixLength(viper.GetString("aggregate.by6"), 128)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	var subnets []netip.Prefix
+	for _, s := range viper.GetStringSlice("aggregate.subnets") {
+		subnet, err := ip.ParsePrefix(s)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	group := pcapGroup(subnets, bits4, bits6)
+
+	// Validate the number of rows and the columns
+	top := viper.GetInt("aggregate.top")
+	if top < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --top value: %d (must not be negative)", top))
+	}
+	columns := viper.GetIntSlice("aggregate.columns")
+	for _, c := range columns {
+		if c < 1 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid column: %d (columns are numbered from 1)", c))
+		}
+	}
+	delimiter := viper.GetString("aggregate.delimiter")
+	if delimiter == "tab" {
+		delimiter = "\t"
+	}
+
+	// Open the input file
+	reader := stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	// Count the addresses of every line in their subnet
+	family := getFamily("aggregate")
+	counts := make(map[netip.Prefix]int)
+	unique := make(map[netip.Prefix]map[netip.Addr]bool)
+	total := 0
+	count := func(addr netip.Addr) {
+		addr = addr.Unmap().WithZone("")
+		if !family.Match(addr) {
+			return
+		}
+		prefix := group(addr)
+		counts[prefix]++
+		if unique[prefix] == nil {
+			unique[prefix] = make(map[netip.Addr]bool)
+		}
+		unique[prefix][addr] = true
+		total++
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(columns) == 0 {
+			for _, addr := range extract.Find(line) {
+				count(addr)
+			}
+			continue
+		}
+		for i, span := range fieldSpans(line, delimiter) {
+			if slices.Contains(columns, i+1) {
+				for _, addr := range extract.Find(line[span[0]:span[1]]) {
+					count(addr)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Rank the subnets by the number of occurrences, then by prefix
+	prefixes := make([]netip.Prefix, 0, len(counts))
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if counts[prefixes[i]] != counts[prefixes[j]] {
+			return counts[prefixes[i]] > counts[prefixes[j]]
+		}
+		return ip.ComparePrefixes(prefixes[i], prefixes[j]) < 0
+	})
+	if top > 0 && len(prefixes) > top {
+		prefixes = prefixes[:top]
+	}
+	results := make([]aggregateJSON, len(prefixes))
+	for i, prefix := range prefixes {
+		results[i] = aggregateJSON{
+			Rank:      i + 1,
+			Prefix:    prefix,
+			Type:      ip.Classify(prefix.Addr()),
+			Count:     counts[prefix],
+			Share:     float64(counts[prefix]) * 100 / float64(total),
+			Addresses: len(unique[prefix]),
+		}
+	}
+
+	if viper.GetBool("aggregate.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		table := render.NewTable(out, getRenderOptions("aggregate", out),
+			render.Column{Title: "Rank", Align: render.AlignRight},
+			render.Column{Title: "Subnet"},
+			render.Column{Title: "Type", Truncate: true},
+			render.Column{Title: "Count", Align: render.AlignRight},
+			render.Column{Title: "Share", Align: render.AlignRight},
+			render.Column{Title: "Addresses", Align: render.AlignRight},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		rows := make([][]string, len(results))
+		for i, r := range results {
+			rows[i] = []string{strconv.Itoa(r.Rank), r.Prefix.String(), r.Type, strconv.Itoa(r.Count), fmt.Sprintf("%.1f%%", r.Share), strconv.Itoa(r.Addresses)}
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+		fmt.Fprintf(out, "\n%d address(es) in %d subnet(s)\n", total, len(counts))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(aggregateCmd)
+	addFamilyFlags(aggregateCmd, "aggregate")
+
+	// Define the flags for the input and the columns to count
+	aggregateCmd.Flags().StringP("input-file", "f", "", "read the text from a file (default is standard input)")
+	viper.BindPFlag("aggregate.input-file", aggregateCmd.Flags().Lookup("input-file"))
+	aggregateCmd.Flags().IntSliceP("columns", "c", nil, "only count the addresses in these columns, numbered from 1 (default is the whole line)")
+	viper.BindPFlag("aggregate.columns", aggregateCmd.Flags().Lookup("columns"))
+	aggregateCmd.Flags().StringP("delimiter", "d", "", "column delimiter (default is whitespace)")
+	viper.BindPFlag("aggregate.delimiter", aggregateCmd.Flags().Lookup("delimiter"))
+	aggregateCmd.RegisterFlagCompletionFunc("delimiter", completeValues(",", ";", "tab", "|"))
+
+	// Define the flags for grouping the addresses into subnets
+	aggregateCmd.Flags().StringP("by", "b", "/24", "prefix length of the subnets of IPv4 addresses")
+	viper.BindPFlag("aggregate.by", aggregateCmd.Flags().Lookup("by"))
+	aggregateCmd.RegisterFlagCompletionFunc("by", completeValues("/8", "/16", "/24", "/32"))
+	aggregateCmd.Flags().String("by6", "/64", "prefix length of the subnets of IPv6 addresses")
+	viper.BindPFlag("aggregate.by6", aggregateCmd.Flags().Lookup("by6"))
+	aggregateCmd.RegisterFlagCompletionFunc("by6", completeValues("/32", "/48", "/56", "/64", "/128"))
+	aggregateCmd.Flags().StringSlice("subnets", nil, "subnets to group the addresses into (e.g. 10.1.0.0/16,10.2.0.0/16)")
+	viper.BindPFlag("aggregate.subnets", aggregateCmd.Flags().Lookup("subnets"))
+
+	// Define the flag for the number of subnets to print
+	aggregateCmd.Flags().IntP("top", "n", 20, "number of subnets to print (0 for all)")
+	viper.BindPFlag("aggregate.top", aggregateCmd.Flags().Lookup("top"))
+
+	// Define the table layout flags (--no-header, --wide and --narrow, --columns
+	// selects the input columns)
+	addRenderFlags(aggregateCmd, "aggregate")
+
+	// Define the flag for printing the subnets in JSON format
+	aggregateCmd.Flags().Bool("json", false, "print the subnets in JSON format")
+	viper.BindPFlag("aggregate.json", aggregateCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/viper"
+)
+
+// lookupAlias is a function that returns the address (or network) of the
+// named alias defined in the "aliases" section of the configuration file,
+// for example:
+//
+//	aliases:
+//	  dc1-core: 10.0.0.0/21
+//	  web-vip: 192.0.2.10
+//
+// Alias names are case insensitive.
+func lookupAlias(name string) (string, bool) {
+	// Names with dots or colons are addresses or host names, never aliases
+	if name == "" || strings.ContainsAny(name, ".:/@{} ") {
+		return "", false
+	}
+	value := strings.TrimSpace(viper.GetString("aliases." + strings.ToLower(name)))
+	return value, value != ""
+}
+
+// resolveAlias is a function that replaces an alias with its address. The
+// alias may be followed by a port (web-vip:443) or be the host of a URL
+// (https://web-vip/health). Anything that is not an alias is returned as is.
+func resolveAlias(s string) string {
+	if value, ok := lookupAlias(s); ok {
+		return value
+	}
+
+	// The host of a URL
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return s
+		}
+		value, ok := lookupAlias(u.Hostname())
+		if !ok {
+			return s
+		}
+		if port := u.Port(); port != "" {
+			u.Host = net.JoinHostPort(value, port)
+		} else if strings.Contains(value, ":") {
+			u.Host = "[" + value + "]"
+		} else {
+			u.Host = value
+		}
+		return u.String()
+	}
+
+	// An alias followed by a port
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		if value, ok := lookupAlias(host); ok {
+			return net.JoinHostPort(value, port)
+		}
+	}
+	return s
+}
+
+// resolveAliases is a function that replaces the aliases in a list of
+// arguments with their addresses
+func resolveAliases(args []string) []string {
+	resolved := make([]string, len(args))
+	for i, arg := range args {
+		resolved[i] = resolveAlias(arg)
+	}
+	return resolved
+}
+
+// expandTargets is a function that expands the patterns and target groups
+// in a list of targets and resolves the aliases among the expanded targets
+func expandTargets(targets []string) ([]string, error) {
+	expanded, err := utils.ExpandTargets(targets)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Usage, err)
+	}
+	return resolveAliases(expanded), nil
+}
+
+// aliasNames is a function that returns the sorted names of the aliases
+func aliasNames() []string {
+	aliases := viper.GetStringMapString("aliases")
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,282 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/bitcanon/iptool/anonymize"
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/extract"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// anonymizeCmd represents the anonymize command
+var anonymizeCmd = &cobra.Command{
+	Use:   "anonymize [file]",
+	Short: "Anonymize the IP addresses in a log file or text",
+	Long: `Anonymize the IP addresses in a log file or text.
+
+Every IPv4 and IPv6 address in the input (a file, or standard input when no
+file or - is given) is rewritten, and the rest of the text is left untouched,
+so that logs and other datasets can be shared safely. The --method flag
+selects how the addresses are rewritten:
+
+  crypto-pan  prefix-preserving anonymization (Crypto-PAn): addresses in the
+              same subnet are rewritten into addresses in the same subnet,
+              and every address is always rewritten the same way (default)
+  truncate    keep the network part of the addresses (--ipv4-bits and
+              --ipv6-bits) and set the host part to zero
+  hash        replace the addresses with the HMAC-SHA256 of the address,
+              the result has no relation to the address
+
+Crypto-PAn and hash use a secret key. Use --key with 64 hexadecimal digits or
+a passphrase (or --key-file) to anonymize several files the same way, the
+datasets can then be correlated without revealing the addresses. A random key
+is used if no key is given. Keep the key secret: anyone with the key can
+reverse Crypto-PAn.
+
+Use --columns to only rewrite the addresses in some columns (fields) of the
+lines, numbered from 1. The columns are separated by whitespace, or by the
+--delimiter.
+
+Examples:
+  iptool anonymize --input-file access.log > access-anon.log
+  iptool anonymize access.log --columns 1 --key-file secret.key
+  iptool anonymize flows.csv --columns 2,3 --delimiter , --method truncate
+  tcpdump -nr capture.pcap | iptool anonymize --method hash`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input := viper.GetString("anonymize.input-file")
+		if len(args) > 0 {
+			if input != "" {
+				return exitcode.New(exitcode.Usage, errors.New("invalid input: give either a file or --input-file, not both"))
+			}
+			input = args[0]
+		}
+		if input == "" {
+			input = "-"
+		}
+		return anonymizeAction(os.Stdout, input)
+	},
+}
+
+// anonymizeKey is a function that returns the key of the anonymization from
+// the --key or --key-file flag, or a random key if neither is set
+func anonymizeKey() ([]byte, error) {
+	key, keyFile := viper.GetString("anonymize.key"), viper.GetString("anonymize.key-file")
+	switch {
+	case key != "" && keyFile != "":
+		return nil, exitcode.New(exitcode.Usage, errors.New("invalid key: use either --key or --key-file, not both"))
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid key file: %s is empty", keyFile))
+		}
+		return anonymize.ParseKey(string(data)), nil
+	case key != "":
+		return anonymize.ParseKey(key), nil
+	}
+	return anonymize.NewKey()
+}
+
+// fieldSpans is a function that returns the start and end positions of the
+// fields of a line, separated by the delimiter or by runs of whitespace if
+// the delimiter is empty
+func fieldSpans(line, delimiter string) [][2]int {
+	var spans [][2]int
+	if delimiter == "" {
+		start := -1
+		for i, r := range line {
+			switch {
+			case unicode.IsSpace(r) && start >= 0:
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			case !unicode.IsSpace(r) && start < 0:
+				start = i
+			}
+		}
+		if start >= 0 {
+			spans = append(spans, [2]int{start, len(line)})
+		}
+		return spans
+	}
+
+	start := 0
+	for {
+		i := strings.Index(line[start:], delimiter)
+		if i < 0 {
+			return append(spans, [2]int{start, len(line)})
+		}
+		spans = append(spans, [2]int{start, start + i})
+		start += i + len(delimiter)
+	}
+}
+
+// anonymizeLine is a function that rewrites the addresses of a line, or only
+// the addresses in the columns (numbered from 1) if any columns are given
+func anonymizeLine(line string, columns []int, delimiter string, fn func(netip.Addr) netip.Addr) string {
+	if len(columns) == 0 {
+		return extract.Replace(line, fn)
+	}
+
+	// Rewrite the selected fields, the delimiters are left untouched
+	spans := fieldSpans(line, delimiter)
+	var b strings.Builder
+	last := 0
+	for i, span := range spans {
+		if !slices.Contains(columns, i+1) {
+			continue
+		}
+		b.WriteString(line[last:span[0]])
+		b.WriteString(extract.Replace(line[span[0]:span[1]], fn))
+		last = span[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// anonymizeAction is the action function for the anonymize command
+func anonymizeAction(out io.Writer, input string) error {
+	// Validate the columns and the delimiter
+	columns := viper.GetIntSlice("anonymize.columns")
+	for _, c := range columns {
+		if c < 1 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid column: %d (columns are numbered from 1)", c))
+		}
+	}
+	delimiter := viper.GetString("anonymize.delimiter")
+	if delimiter == "tab" {
+		delimiter = "\t"
+	}
+
+	// Create the anonymizer of the method
+	key, err := anonymizeKey()
+	if err != nil {
+		return err
+	}
+	anonymizer, err := anonymize.New(viper.GetString("anonymize.method"), key, viper.GetInt("anonymize.ipv4-bits"), viper.GetInt("anonymize.ipv6-bits"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Remember the anonymized addresses, the same addresses appear on many
+	// lines of a log and Crypto-PAn needs an AES encryption per bit
+	cache := make(map[netip.Addr]netip.Addr)
+	fn := func(addr netip.Addr) netip.Addr {
+		if a, ok := cache[addr]; ok {
+			return a
+		}
+		a := anonymizer.Anonymize(addr)
+		if len(cache) < 1000000 {
+			cache[addr] = a
+		}
+		return a
+	}
+
+	// Open the input file before anything is written
+	var reader io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("anonymize.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Rewrite the input line by line
+	writer := bufio.NewWriter(outputStream)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintln(writer, anonymizeLine(scanner.Text(), columns, delimiter, fn)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(anonymizeCmd)
+
+	// Define the flags for the input and the columns to anonymize
+	anonymizeCmd.Flags().StringP("input-file", "f", "", "read the text from a file (default is standard input)")
+	viper.BindPFlag("anonymize.input-file", anonymizeCmd.Flags().Lookup("input-file"))
+	anonymizeCmd.Flags().IntSliceP("columns", "c", nil, "only anonymize the addresses in these columns, numbered from 1 (default is the whole line)")
+	viper.BindPFlag("anonymize.columns", anonymizeCmd.Flags().Lookup("columns"))
+	anonymizeCmd.Flags().StringP("delimiter", "d", "", "column delimiter (default is whitespace)")
+	viper.BindPFlag("anonymize.delimiter", anonymizeCmd.Flags().Lookup("delimiter"))
+	anonymizeCmd.RegisterFlagCompletionFunc("delimiter", completeValues(",", ";", "tab", "|"))
+
+	// Define the flags for the method and its key
+	anonymizeCmd.Flags().StringP("method", "m", anonymize.MethodCryptoPAn, "anonymization method ("+strings.Join(anonymize.Methods, ", ")+")")
+	viper.BindPFlag("anonymize.method", anonymizeCmd.Flags().Lookup("method"))
+	anonymizeCmd.RegisterFlagCompletionFunc("method", completeValues(anonymize.Methods...))
+	anonymizeCmd.Flags().StringP("key", "k", "", "secret key: 64 hexadecimal digits or a passphrase (default is a random key)")
+	viper.BindPFlag("anonymize.key", anonymizeCmd.Flags().Lookup("key"))
+	anonymizeCmd.Flags().String("key-file", "", "read the secret key from a file")
+	viper.BindPFlag("anonymize.key-file", anonymizeCmd.Flags().Lookup("key-file"))
+	anonymizeCmd.Flags().Int("ipv4-bits", 24, "number of bits to keep of IPv4 addresses (truncate)")
+	viper.BindPFlag("anonymize.ipv4-bits", anonymizeCmd.Flags().Lookup("ipv4-bits"))
+	anonymizeCmd.Flags().Int("ipv6-bits", 48, "number of bits to keep of IPv6 addresses (truncate)")
+	viper.BindPFlag("anonymize.ipv6-bits", anonymizeCmd.Flags().Lookup("ipv6-bits"))
+
+	// Define the flag for the output file
+	anonymizeCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("anonymize.output-file", anonymizeCmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/bgp"
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bgpLookupCmd represents the bgp lookup command
+var bgpLookupCmd = &cobra.Command{
+	Use:   "lookup <prefix>",
+	Short: "Show the visibility, origin AS and RPKI state of a prefix",
+	Long: `Show the visibility, origin AS and RPKI state of a prefix.
+
+The prefix is looked up with the RIPEstat Data API: the number of RIS peers
+that see the prefix, the AS (or ASes) that originate it, whether the origin
+is the same for all peers, and the RPKI validation state of every origin AS
+(valid, invalid_asn, invalid_length or unknown when no ROA covers it). The
+routes seen by the peers of every route collector are summarized in a table.
+
+Use --lg to limit the looking glass to some of the RIS route collectors
+(e.g. --lg rrc00,rrc21), the default ris uses all collectors. The base URL of
+the API (https://stat.ripe.net/data) can be changed with the bgp.ripestat-url
+configuration key.
+
+The command exits with exit code 5 if the prefix is originated by more than
+one AS or an origin is RPKI invalid, and with exit code 3 if the prefix is
+not visible at all, which makes it usable in monitoring scripts. Use --json
+for the full result in JSON format.
+
+Examples:
+  iptool bgp lookup 193.0.0.0/21
+  iptool bgp lookup 2001:67c:2e8::/48 --lg rrc00
+  iptool bgp lookup 193.0.0.0/21 --json`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return bgpLookupAction(os.Stdout, resolveAlias(args[0]))
+	},
+}
+
+// bgpLookupAction is the action function for the bgp lookup command
+func bgpLookupAction(out io.Writer, s string) error {
+	// The looked up resource is a prefix, the API reports on the prefix as announced
+	if !strings.Contains(s, "/") {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid prefix: %s (give the prefix as announced, e.g. 193.0.0.0/21)", s))
+	}
+	prefix, err := ip.ParsePrefix(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	collectors, err := bgp.ParseCollectors(viper.GetString("bgp.lookup.lg"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	timeout := viper.GetDuration("bgp.lookup.timeout") * time.Millisecond
+	if timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid timeout: %d (must be greater than 0)", viper.GetInt("bgp.lookup.timeout")))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop waiting for the API when the user presses Ctrl-C
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+
+	// The API can be replaced by a mirror in the bgp.ripestat-url configuration key
+	client := bgp.NewClient(&http.Client{Timeout: timeout})
+	if url := viper.GetString("bgp.ripestat-url"); url != "" {
+		client.BaseURL = url
+	}
+	result, err := bgp.Lookup(ctx, client, prefix, collectors)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("bgp.lookup.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else if err := printBGPLookup(out, result); err != nil {
+		return err
+	}
+
+	// Report the problems with the exit code for automation
+	if !result.Visible() {
+		return exitcode.New(exitcode.Unreachable, fmt.Errorf("%s is not visible in the global routing table", result.Prefix))
+	}
+	var problems []string
+	if !result.Consistent {
+		problems = append(problems, fmt.Sprintf("%d origin ASes", len(result.Origins)))
+	}
+	for _, o := range result.Origins {
+		if strings.HasPrefix(o.RPKI, "invalid") {
+			problems = append(problems, fmt.Sprintf("%s is RPKI %s", o.ASN, o.RPKI))
+		}
+	}
+	if len(problems) > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%s: %s", result.Prefix, strings.Join(problems, ", ")))
+	}
+	return nil
+}
+
+// printBGPLookup is a function that prints the result of a bgp lookup as a
+// report followed by a table of the route collectors
+func printBGPLookup(out io.Writer, result *bgp.Result) error {
+	fmt.Fprintf(out, "Prefix:             %s\n", result.Prefix)
+	if result.PeersTotal > 0 {
+		fmt.Fprintf(out, "Visibility:         %d of %d RIS peers (%.1f%%)\n", result.PeersSeeing, result.PeersTotal, float64(result.PeersSeeing)*100/float64(result.PeersTotal))
+	}
+	if result.FirstSeen != "" {
+		fmt.Fprintf(out, "First seen:         %s\n", result.FirstSeen)
+	}
+	if result.LastSeen != "" {
+		fmt.Fprintf(out, "Last seen:          %s\n", result.LastSeen)
+	}
+
+	// Print every origin AS with the number of peers and the RPKI state
+	label := "Origin AS:"
+	for _, o := range result.Origins {
+		peers := fmt.Sprintf("seen by %d peers", o.Peers)
+		if o.Peers == 1 {
+			peers = "seen by 1 peer"
+		}
+		details := []string{peers, "RPKI " + o.RPKI}
+		if len(o.RouteObjects) > 0 {
+			details = append(details, "route objects in "+strings.Join(o.RouteObjects, ", "))
+		}
+		fmt.Fprintf(out, "%-19s %s (%s)\n", label, o.ASN, strings.Join(details, ", "))
+		label = ""
+	}
+	if len(result.Origins) == 0 {
+		fmt.Fprintf(out, "Origin AS:          none (the prefix is not announced)\n")
+	}
+	if len(result.Origins) > 1 {
+		fmt.Fprintf(out, "Origin consistency: inconsistent, the prefix is originated by %d ASes (MOAS)\n", len(result.Origins))
+	} else if len(result.Origins) == 1 {
+		fmt.Fprintf(out, "Origin consistency: consistent\n")
+	}
+
+	if len(result.Collectors) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+
+	// Summarize the looking glass of every route collector
+	table := render.NewTable(out, getRenderOptions("bgp.lookup", out),
+		render.Column{Title: "Collector"},
+		render.Column{Title: "Location", Truncate: true},
+		render.Column{Title: "Peers", Align: render.AlignRight},
+		render.Column{Title: "Origins"},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	rows := make([][]string, len(result.Collectors))
+	for i, c := range result.Collectors {
+		origins := make([]string, len(c.Origins))
+		for j, asn := range c.Origins {
+			origins[j] = asn.String()
+		}
+		rows[i] = []string{c.Name, c.Location, fmt.Sprint(c.Peers), strings.Join(origins, ", ")}
+		table.Fit(rows[i]...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	bgpCmd.AddCommand(bgpLookupCmd)
+
+	// Define the flags for the looking glass and the timeout of the API requests
+	bgpLookupCmd.Flags().String("lg", "ris", "looking glass: ris (all RIS route collectors) or route collectors, e.g. rrc00,rrc21")
+	viper.BindPFlag("bgp.lookup.lg", bgpLookupCmd.Flags().Lookup("lg"))
+	bgpLookupCmd.RegisterFlagCompletionFunc("lg", completeValues("ris", "rrc00", "rrc01", "rrc03", "rrc21"))
+	bgpLookupCmd.Flags().Int("timeout", 10000, "timeout of every API request in milliseconds")
+	viper.BindPFlag("bgp.lookup.timeout", bgpLookupCmd.Flags().Lookup("timeout"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(bgpLookupCmd, "bgp.lookup")
+
+	// Define the flag for printing the result in JSON format
+	bgpLookupCmd.Flags().Bool("json", false, "print the result in JSON format")
+	viper.BindPFlag("bgp.lookup.json", bgpLookupCmd.Flags().Lookup("json"))
+}
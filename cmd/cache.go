@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/cache"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of external lookups",
+	Long: `Manage the cache of external lookups.
+
+The results of external lookups (DNS, whois, ASN, GeoIP and OUI) are cached
+on disk in the cache directory of the user, which speeds up repeated and
+bulk runs and respects the rate limits of the upstream sources. Use the
+global --no-cache flag to bypass the cache for a single run.
+
+Without a subcommand, the location and the contents of the cache are shown.
+
+Examples:
+  iptool cache
+  iptool cache clear
+  iptool cache clear dns`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cacheInfoAction(os.Stdout)
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:       "clear [namespace...]",
+	Short:     "Remove cached lookup results",
+	Long:      `Remove cached lookup results, from all namespaces or only from the namespaces given.`,
+	ValidArgs: cache.Namespaces,
+	Args:      cobra.OnlyValidArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cacheClearAction(os.Stdout, args)
+	},
+	SilenceUsage: true,
+}
+
+// cacheInfoAction prints the location of the cache and the number of entries per namespace
+func cacheInfoAction(out io.Writer) error {
+	c, err := cache.Default()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Cache directory: %s\n", c.Dir)
+	for _, namespace := range cache.Namespaces {
+		entries, size, err := c.Stats(namespace)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, " %-6s %6d entries %10s\n", namespace, entries, utils.FormatBytes(size))
+	}
+	return nil
+}
+
+// cacheClearAction removes the cached entries in the namespaces (all if none are given)
+func cacheClearAction(out io.Writer, namespaces []string) error {
+	c, err := cache.Default()
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Clear(namespaces...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed %d cached entries from %s.\n", removed, c.Dir)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
@@ -0,0 +1,280 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/bitcanon/iptool/check"
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [name...]",
+	Short: "Run the composite checks defined in the configuration file",
+	Long: `Run the composite checks defined in the configuration file.
+
+A check combines several named probes with an alert condition over their
+results, and runs hook commands when the condition changes. This allows for
+checks such as "alert only if DNS fails AND TCP 443 fails twice in a row":
+
+  checks:
+    web:
+      probes:
+        dns: dns://www.example.com
+        https: tcp://www.example.com:443
+      alert: dns.failed >= 1 and https.failed >= 2
+      hook: notify-send "web is down"
+      recover-hook: notify-send "web is up"
+      result-hook: echo "$IPTOOL_CHECK_VARS" >> web.log
+
+The alert condition is a Starlark expression (a dialect of Python, see
+https://github.com/bazelbuild/starlark). Every probe is a value with the
+fields:
+  <probe>.ok      True if the last probe succeeded
+  <probe>.failed  number of consecutive failed probes
+  <probe>.rtt     response time of the last probe in milliseconds (0 if failed)
+  <probe>.loss    packet loss since the start in percent
+  <probe>.sent    number of probes sent
+
+Longer logic goes in the Starlark script of the check, which can define
+helper functions for the alert condition, an alert(probes) function in
+place of the alert condition, and an on_result(probes, alerting) function
+that runs after every round of probes. The probes are passed as a dict by
+probe name, and the output of print is written to standard output:
+
+  checks:
+    web:
+      probes:
+        dns: dns://www.example.com
+        https: tcp://www.example.com:443
+      script: |
+        def alert(probes):
+            return probes["dns"].failed >= 1 and probes["https"].failed >= 2
+        def on_result(probes, alerting):
+            if probes["https"].rtt > 500:
+                print("https is slow: %.1f ms" % probes["https"].rtt)
+
+The hook runs when the condition becomes true, the recover hook when it
+becomes false again and the result hook after every round of probes. The
+name of the check, the state (alert or ok) and the variables in JSON format
+are passed to the hooks in the IPTOOL_CHECK, IPTOOL_CHECK_STATE and
+IPTOOL_CHECK_VARS environment variables.
+
+All checks are run unless the names of the checks are given. Use --once to
+run a single round of probes, the command then exits with a non-zero exit
+code if any alert condition is true.
+
+Examples:
+  iptool check
+  iptool check web --interval 10000
+  iptool check --once`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeCheckArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return checkAction(os.Stdout, args)
+	},
+}
+
+// loadChecks is a function that loads the checks with the given names (all
+// checks if no names are given) from the configuration file
+func loadChecks(names []string) ([]*check.Check, error) {
+	var configs map[string]check.Config
+	if err := viper.UnmarshalKey("checks", &configs); err != nil {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid checks in the configuration file: %w", err))
+	}
+	if len(configs) == 0 {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("no checks defined in the configuration file, see --help for more information"))
+	}
+
+	// Run all checks, sorted by name, if no names are given
+	if len(names) == 0 {
+		for name := range configs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var checks []*check.Check
+	for _, name := range names {
+		cfg, ok := configs[name]
+		if !ok {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("unknown check: %s", name))
+		}
+
+		// Resolve the aliases used as probe targets
+		for probeName, target := range cfg.Probes {
+			cfg.Probes[probeName] = resolveAlias(target)
+		}
+		c, err := check.New(name, cfg)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
+
+// checkAction is the action function for the check command
+func checkAction(out io.Writer, names []string) error {
+	interval := viper.GetDuration("check.interval") * time.Millisecond
+	timeout := viper.GetDuration("check.timeout") * time.Millisecond
+	if interval <= 0 || timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--interval and --timeout must be greater than zero"))
+	}
+
+	checks, err := loadChecks(names)
+	if err != nil {
+		return err
+	}
+
+	// The output of print in the scripts is printed with the results
+	for _, c := range checks {
+		c.Output = out
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop when the user presses Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The alerts are posted to the webhook in the background
+	webhook, err := getWebhookDispatcher("check")
+	if err != nil {
+		return err
+	}
+	defer webhook.Close(notify.Timeout)
+
+	once := viper.GetBool("check.once")
+	if !once {
+		fmt.Fprintf(out, "Running %d check(s) every %s, press Ctrl-C to stop.\n", len(checks), interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		alerts := 0
+		for _, c := range checks {
+			event, err := c.Run(ctx, timeout)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			state := "OK"
+			if event.Alerting {
+				state = "ALERT"
+				alerts++
+			}
+
+			// Print the state of every check with --once or --verbose, otherwise only the changes
+			if once || viper.GetBool("check.verbose") || event.Changed {
+				fmt.Fprintf(out, "[%s] %-5s %s: %s\n", utils.GetTimestamp(), state, c.Name, c.Status())
+			}
+
+			// Post the changes of the alert condition to the webhook
+			if event.Changed {
+				alert := notify.NewEvent(c.Name, "", 0, notify.StateOK, notify.StateAlert)
+				alert.Message = fmt.Sprintf("check %s recovered: %s", c.Name, c.Status())
+				if event.Alerting {
+					alert.State, alert.Previous = notify.StateAlert, notify.StateOK
+					alert.Message = fmt.Sprintf("check %s is alerting: %s", c.Name, c.Status())
+				}
+				alert.Source = "check"
+				webhook.Notify(alert)
+			}
+
+			if err := event.RunHooks(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+
+		if once {
+			if alerts > 0 {
+				return exitcode.New(exitcode.Partial, fmt.Errorf("%d of %d check(s) alerting", alerts, len(checks)))
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	// Define the flag for the interval between rounds of probes
+	checkCmd.Flags().IntP("interval", "i", 5000, "time between rounds of probes, in milliseconds")
+	viper.BindPFlag("check.interval", checkCmd.Flags().Lookup("interval"))
+
+	// Define the flag for the probe timeout
+	checkCmd.Flags().IntP("timeout", "t", 2000, "time to wait for a response, in milliseconds")
+	viper.BindPFlag("check.timeout", checkCmd.Flags().Lookup("timeout"))
+
+	// Define the flag for running a single round of probes
+	checkCmd.Flags().Bool("once", false, "run a single round of probes and exit")
+	viper.BindPFlag("check.once", checkCmd.Flags().Lookup("once"))
+
+	// Define the flag for printing the state of every check after every round
+	checkCmd.Flags().BoolP("verbose", "v", false, "print the state of every check after every round, not only the changes")
+	viper.BindPFlag("check.verbose", checkCmd.Flags().Lookup("verbose"))
+
+	// Define the flags for posting the changes of the alert conditions to a webhook
+	addWebhookFlags(checkCmd, "check")
+}
+
+// completeCheckArgs is a function that completes the names of the checks
+// defined in the configuration file
+func completeCheckArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for name := range viper.GetStringMap("checks") {
+		if !slices.Contains(args, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,336 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/blocklist"
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkBogonCmd represents the check bogon command
+var checkBogonCmd = &cobra.Command{
+	Use:   "bogon <address|prefix...>",
+	Short: "Check addresses and prefixes against bogon and block lists",
+	Long: `Check addresses and prefixes against bogon and block lists.
+
+Every address or prefix is checked against the bogon list, the address
+space that must never appear on the public internet (private, reserved,
+documentation and multicast space). The bogon list is embedded in iptool;
+use --update to download the full bogon lists of Team Cymru, which also
+contain the unallocated address space, and use them from then on.
+
+Use --blocklist to also check against block lists such as the Spamhaus DROP
+lists, given as files or http(s) URLs. A list has one prefix per line with
+an optional description after a ; or #, and the JSON format of the DROP
+lists is accepted too. A prefix is listed if an entry of a list covers the
+whole prefix, and partial if it only overlaps entries of the lists (e.g.
+0.0.0.0/0 overlaps all IPv4 bogons), which are shown in the Match column.
+
+The addresses are given as arguments, read from the file given with
+--input-file, or read from standard input with - (one or more per line).
+Use --csv for CSV output and --filter listed or --filter clean to only print
+the addresses that are (or are not, even partially) on a list, e.g. to scrub the addresses
+of a log file.
+
+Examples:
+  iptool check bogon 10.1.2.3 8.8.8.8 2001:db8::1
+  iptool check bogon --update
+  iptool check bogon 1.10.16.1 --blocklist https://www.spamhaus.org/drop/drop_v4.json
+  iptool extract access.log | iptool check bogon - --filter clean --csv
+  iptool check bogon -f addresses.txt --blocklist drop.txt --no-bogons`,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Validate the filter
+		switch filter := viper.GetString("check.bogon.filter"); filter {
+		case "", "listed", "clean":
+		default:
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --filter value: %s (must be listed or clean)", filter))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no input is provided, print a short help text
+		if len(args) == 0 && viper.GetString("check.bogon.input-file") == "" && !viper.GetBool("check.bogon.update") {
+			cmd.Help()
+			return nil
+		}
+		return checkBogonAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// openList is a function that opens a list given as a file or an http(s) URL
+func openList(source string, timeout time.Duration) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The host of the URL is resolved by the HTTP client, unless name resolution is disabled
+	if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil && ip.LookupsDisabled() {
+		return nil, fmt.Errorf("cannot resolve %s: %w", req.URL.Hostname(), ip.ErrLookupsDisabled)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", source, resp.Status)
+	}
+
+	// Read the whole list before the timeout cancels the request
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
+
+// updateBogons is a function that downloads the full bogon lists and saves
+// them as the bogon list used by the check bogon command
+func updateBogons(out io.Writer, timeout time.Duration) error {
+	var data bytes.Buffer
+	for _, url := range blocklist.BogonURLs {
+		r, err := openList(url, timeout)
+		if err != nil {
+			return err
+		}
+		io.Copy(&data, r)
+		r.Close()
+		data.WriteString("\n")
+	}
+
+	// Only save the lists if they are valid
+	list, err := blocklist.Parse("bogons", bytes.NewReader(data.Bytes()))
+	if err != nil {
+		return err
+	}
+	path, err := blocklist.BogonPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Updated the bogon list: %d prefixes (%s)\n", list.Len(), path)
+	return nil
+}
+
+// checkBogonAction is the action function for the check bogon command
+func checkBogonAction(out io.Writer, stdin io.Reader, args []string) error {
+	timeout := viper.GetDuration("check.bogon.timeout") * time.Millisecond
+	if viper.GetBool("check.bogon.update") {
+		if err := updateBogons(os.Stderr, timeout); err != nil {
+			return err
+		}
+	}
+
+	// Read the addresses and prefixes to check
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	if inputFile := viper.GetString("check.bogon.input-file"); inputFile != "" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		list, err := ip.ParsePrefixes(file)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("%s: %w", inputFile, err))
+		}
+		prefixes = append(prefixes, list...)
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	// Load the bogon list and the block lists
+	var bogons *blocklist.List
+	var lists []*blocklist.List
+	if !viper.GetBool("check.bogon.no-bogons") {
+		bogons, err = blocklist.Bogons()
+		if err != nil {
+			return err
+		}
+		lists = append(lists, bogons)
+	}
+	for _, source := range viper.GetStringSlice("check.bogon.blocklist") {
+		r, err := openList(source, timeout)
+		if err != nil {
+			return err
+		}
+		list, err := blocklist.Parse(filepath.Base(source), r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		lists = append(lists, list)
+	}
+
+	// Check every address against the lists, the first list that covers it
+	// wins. A prefix that no list covers is partially listed if entries of
+	// the lists lie within it.
+	filter := viper.GetString("check.bogon.filter")
+	csvOutput := viper.GetBool("check.bogon.csv")
+	var rows [][]string
+	for _, prefix := range prefixes {
+		input := prefix.String()
+		if prefix.IsSingleIP() {
+			input = prefix.Addr().String()
+		}
+
+		row := []string{input, "clean", "", "", ""}
+		var overlaps []blocklist.Entry
+		for _, list := range lists {
+			if entry, ok := list.Check(prefix); ok {
+				status := "listed"
+				if list == bogons {
+					status = "bogon"
+				}
+				row = []string{input, status, entry.List, entry.Prefix.String(), entry.Description}
+				overlaps = nil
+				break
+			}
+			overlaps = append(overlaps, list.Overlaps(prefix)...)
+		}
+		if len(overlaps) > 0 {
+			row = partialRow(input, overlaps, csvOutput)
+		}
+		if (filter == "listed" && row[1] == "clean") || (filter == "clean" && row[1] != "clean") {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if csvOutput {
+		w := csv.NewWriter(out)
+		w.Write([]string{"input", "status", "list", "match", "description"})
+		w.WriteAll(rows)
+		if err := w.Error(); err != nil {
+			return err
+		}
+	} else {
+		table := render.NewTable(out, getRenderOptions("check.bogon", out),
+			render.Column{Title: "Input"},
+			render.Column{Title: "Status"},
+			render.Column{Title: "List"},
+			render.Column{Title: "Match"},
+			render.Column{Title: "Description"},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		for _, row := range rows {
+			table.Fit(row...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// partialRow is a function that returns the row of a prefix that is
+// partially listed, with the lists and the entries that overlap it. The
+// table lists the first few entries only, CSV output lists all of them.
+func partialRow(input string, overlaps []blocklist.Entry, all bool) []string {
+	var names, matches []string
+	for _, entry := range overlaps {
+		if !slices.Contains(names, entry.List) {
+			names = append(names, entry.List)
+		}
+		matches = append(matches, entry.Prefix.String())
+	}
+	const maxMatches = 4
+	if !all && len(matches) > maxMatches {
+		matches = append(matches[:maxMatches], fmt.Sprintf("(+%d more)", len(matches)-maxMatches))
+	}
+
+	description := overlaps[0].Description
+	if len(overlaps) > 1 {
+		description = fmt.Sprintf("overlaps %d entries", len(overlaps))
+	}
+	return []string{input, "partial", strings.Join(names, ", "), strings.Join(matches, ", "), description}
+}
+
+func init() {
+	checkCmd.AddCommand(checkBogonCmd)
+
+	// Define the flags for the lists
+	checkBogonCmd.Flags().StringSliceP("blocklist", "b", nil, "also check against a block list file or http(s) URL (e.g. the Spamhaus DROP list)")
+	viper.BindPFlag("check.bogon.blocklist", checkBogonCmd.Flags().Lookup("blocklist"))
+	checkBogonCmd.Flags().Bool("no-bogons", false, "only check against the block lists")
+	viper.BindPFlag("check.bogon.no-bogons", checkBogonCmd.Flags().Lookup("no-bogons"))
+	checkBogonCmd.Flags().Bool("update", false, "download the full bogon lists of Team Cymru and use them from now on")
+	viper.BindPFlag("check.bogon.update", checkBogonCmd.Flags().Lookup("update"))
+	checkBogonCmd.Flags().IntP("timeout", "t", 10000, "time to wait for a list to download, in milliseconds")
+	viper.BindPFlag("check.bogon.timeout", checkBogonCmd.Flags().Lookup("timeout"))
+
+	// Define the flag for reading the addresses from a file
+	checkBogonCmd.Flags().StringP("input-file", "f", "", "read the addresses and prefixes from a file")
+	viper.BindPFlag("check.bogon.input-file", checkBogonCmd.Flags().Lookup("input-file"))
+
+	// Define the flags for the output
+	checkBogonCmd.Flags().String("filter", "", "only print the addresses that are listed or clean")
+	viper.BindPFlag("check.bogon.filter", checkBogonCmd.Flags().Lookup("filter"))
+	checkBogonCmd.RegisterFlagCompletionFunc("filter", completeValues("listed", "clean"))
+	checkBogonCmd.Flags().Bool("csv", false, "print the results in CSV format")
+	viper.BindPFlag("check.bogon.csv", checkBogonCmd.Flags().Lookup("csv"))
+	addRenderFlags(checkBogonCmd, "check.bogon")
+}
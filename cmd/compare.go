@@ -0,0 +1,246 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/probe"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <target...> --remote ssh://[user@]host[:port]",
+	Short: "Compare the reachability of targets from here and from a remote host",
+	Long: `Compare the reachability of targets from here and from a remote host.
+
+The targets are probed locally and, at the same time, from the remote host
+(vantage point), and the results are compared side by side. This answers the
+question "does this only fail from my network?" in one command.
+
+The remote probes are run with iptool probe over SSH, so iptool must be
+installed on the remote host (use --remote-command if it is not in the PATH).
+SSH runs in batch mode, so key-based authentication must be set up.
+
+Targets are given as <type>://<address>, where the type of probe is one of
+` + strings.Join(probe.Schemes(), ", ") + `. The type defaults to tcp and the TCP
+port to 443. Brace patterns and @<name> group references are expanded locally.
+
+Examples:
+  iptool compare 10.0.0.1:22 https://www.example.com --remote ssh://jumphost
+  iptool compare @dns-servers --remote ssh://admin@192.0.2.10:2222 --count 5`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeTargetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return compareAction(os.Stdout, args)
+	},
+}
+
+// compareAction is the action function for the compare command
+func compareAction(out io.Writer, args []string) error {
+	settings, err := getProbeSettings("compare")
+	if err != nil {
+		return err
+	}
+
+	// Parse the remote vantage point
+	remote := viper.GetString("compare.remote")
+	if remote == "" {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--remote is required, see --help for more information"))
+	}
+	sshArgs, err := parseSSHRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	probers, err := newProbers(args)
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop probing when the user presses Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Run the remote probes while probing locally
+	type remoteResults struct {
+		results []probe.Result
+		err     error
+	}
+	remoteDone := make(chan remoteResults, 1)
+	go func() {
+		results, err := runRemoteProbes(ctx, sshArgs, probers, settings)
+		remoteDone <- remoteResults{results: results, err: err}
+	}()
+
+	local := probe.RunAll(ctx, probers, settings.count, settings.interval, settings.timeout)
+	r := <-remoteDone
+	if r.err != nil {
+		return r.err
+	}
+
+	// Find the length of the longest target and local result (for padding)
+	targetWidth, localWidth := len("Target"), len("Local")
+	for _, result := range local {
+		targetWidth = max(targetWidth, len(result.Target))
+		localWidth = max(localWidth, len(formatProbeResult(result)))
+	}
+	remoteWidth := len("Remote")
+	for _, result := range r.results {
+		remoteWidth = max(remoteWidth, len(formatProbeResult(result)))
+	}
+
+	// Print the results side by side
+	fmtString := fmt.Sprintf("%%-%ds  %%-%ds  %%-%ds  %%s\n", targetWidth, localWidth, remoteWidth)
+	fmt.Fprintf(out, fmtString, "Target", "Local", "Remote", "Verdict")
+	for i := range local {
+		fmt.Fprintf(out, fmtString, local[i].Target, formatProbeResult(local[i]), formatProbeResult(r.results[i]), compareVerdict(local[i], r.results[i]))
+	}
+
+	return nil
+}
+
+// compareVerdict is a function that returns the verdict of the comparison
+// of the local and remote results of a target
+func compareVerdict(local, remote probe.Result) string {
+	switch {
+	case local.OK() && remote.OK():
+		return "ok from both"
+	case !local.OK() && remote.OK():
+		return "FAILS LOCALLY ONLY"
+	case local.OK() && !remote.OK():
+		return "FAILS REMOTELY ONLY"
+	default:
+		return "fails from both"
+	}
+}
+
+// parseSSHRemote is a function that parses a remote vantage point given as
+// ssh://[user@]host[:port] and returns the arguments for the ssh command
+func parseSSHRemote(remote string) ([]string, error) {
+	if !strings.Contains(remote, "://") {
+		remote = "ssh://" + remote
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Hostname() == "" {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid remote: %s", remote))
+	}
+	if u.Scheme != "ssh" {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("unsupported remote: %s (only ssh:// is supported)", remote))
+	}
+
+	// Never prompt for passwords or host keys, the output is parsed
+	args := []string{"-o", "BatchMode=yes"}
+	if port := u.Port(); port != "" {
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid port in remote: %s", remote))
+		}
+		args = append(args, "-p", port)
+	}
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+	return append(args, host), nil
+}
+
+// runRemoteProbes is a function that runs iptool probe on the remote host
+// over SSH and returns the results, in the same order as the probers
+func runRemoteProbes(ctx context.Context, sshArgs []string, probers []probe.Prober, settings probeSettings) ([]probe.Result, error) {
+	// Build the remote command line, every argument is quoted for the remote shell
+	command := []string{
+		viper.GetString("compare.remote-command"), "probe", "--json",
+		"--count", strconv.Itoa(settings.count),
+		"--interval", strconv.FormatInt(settings.interval.Milliseconds(), 10),
+		"--timeout", strconv.FormatInt(settings.timeout.Milliseconds(), 10),
+	}
+	for _, prober := range probers {
+		command = append(command, prober.String())
+	}
+	for i, arg := range command[1:] {
+		command[i+1] = shellQuote(arg)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", append(sshArgs, "--", strings.Join(command, " "))...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("remote probes failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("remote probes failed: %w", err)
+	}
+
+	var results []probe.Result
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		return nil, fmt.Errorf("invalid response from the remote host: %w", err)
+	}
+	if len(results) != len(probers) {
+		return nil, fmt.Errorf("invalid response from the remote host: expected %d results, got %d", len(probers), len(results))
+	}
+	return results, nil
+}
+
+// shellQuote is a function that quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	addProbeFlags(compareCmd, "compare")
+
+	// Define the flag for the remote vantage point
+	compareCmd.Flags().StringP("remote", "r", "", "remote vantage point (ssh://[user@]host[:port])")
+	viper.BindPFlag("compare.remote", compareCmd.Flags().Lookup("remote"))
+
+	// Define the flag for the path of iptool on the remote host
+	compareCmd.Flags().String("remote-command", "iptool", "path of iptool on the remote host")
+	viper.BindPFlag("compare.remote-command", compareCmd.Flags().Lookup("remote-command"))
+}
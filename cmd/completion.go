@@ -0,0 +1,199 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/plugin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// commonPorts are the well-known TCP ports offered when completing a port
+var commonPorts = []struct {
+	port    int
+	service string
+}{
+	{21, "ftp"}, {22, "ssh"}, {23, "telnet"}, {25, "smtp"}, {53, "dns"},
+	{80, "http"}, {88, "kerberos"}, {110, "pop3"}, {135, "msrpc"}, {139, "netbios"},
+	{143, "imap"}, {179, "bgp"}, {389, "ldap"}, {443, "https"}, {445, "smb"},
+	{465, "smtps"}, {514, "syslog"}, {587, "submission"}, {636, "ldaps"}, {853, "dns-over-tls"},
+	{993, "imaps"}, {995, "pop3s"}, {1433, "mssql"}, {1521, "oracle"}, {2049, "nfs"},
+	{3306, "mysql"}, {3389, "rdp"}, {5060, "sip"}, {5432, "postgresql"}, {5900, "vnc"},
+	{6379, "redis"}, {6443, "kubernetes"}, {8080, "http-alt"}, {8443, "https-alt"}, {9200, "elasticsearch"},
+}
+
+// completePorts is a function that returns the common ports matching the
+// prefix, with the name of the service as description
+func completePorts(toComplete string) []string {
+	var completions []string
+	for _, p := range commonPorts {
+		if port := strconv.Itoa(p.port); strings.HasPrefix(port, toComplete) {
+			completions = append(completions, port+"\t"+p.service)
+		}
+	}
+	return completions
+}
+
+// completeTargets is a function that returns the aliases, the target groups
+// (@name) and their members defined in the configuration file matching the prefix
+func completeTargets(toComplete string) []string {
+	completions := completeAliases(toComplete)
+	seen := make(map[string]bool)
+
+	groups := viper.GetStringMap("groups")
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		members := viper.GetStringSlice("groups." + name)
+		if strings.HasPrefix("@"+name, toComplete) {
+			completions = append(completions, fmt.Sprintf("@%s\tgroup of %d target(s)", name, len(members)))
+		}
+		for _, member := range members {
+			if !seen[member] && strings.HasPrefix(member, toComplete) {
+				seen[member] = true
+				completions = append(completions, member+"\tin @"+name)
+			}
+		}
+	}
+	return completions
+}
+
+// completeAliases is a function that returns the aliases defined in the
+// configuration file matching the prefix, with their addresses as description
+func completeAliases(toComplete string) []string {
+	var completions []string
+	for _, name := range aliasNames() {
+		if strings.HasPrefix(name, toComplete) {
+			value, _ := lookupAlias(name)
+			completions = append(completions, name+"\t"+value)
+		}
+	}
+	return completions
+}
+
+// completeAliasArgs is a function that completes the arguments of the
+// commands taking an address or network with the aliases
+func completeAliasArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeAliases(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTargetArgs is a function that completes a list of targets
+func completeTargetArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeTargets(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeHostPortArgs is a function that completes the arguments of the
+// commands taking "<host> [port]": the saved targets, then the common ports
+func completeHostPortArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch {
+	case len(args) == 0:
+		return completeTargets(toComplete), cobra.ShellCompDirectiveNoFileComp
+	case len(args) == 1 && !strings.Contains(strings.Trim(args[0], "[]"), ":"):
+		return completePorts(toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePortArgs is a function that completes a list of ports
+func completePortArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Complete the last port of a comma separated list
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+	var completions []string
+	for _, c := range completePorts(toComplete) {
+		completions = append(completions, prefix+c)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePrefixLengths is a function that returns a completion function for
+// the IPv4 prefix lengths from first to last, with the number of addresses
+// of a subnet as description
+func completePrefixLengths(first, last int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var completions []string
+		for bits := first; bits <= last; bits++ {
+			if length := strconv.Itoa(bits); strings.HasPrefix(length, toComplete) {
+				completions = append(completions, fmt.Sprintf("%s\t/%d, %d addresses", length, bits, uint64(1)<<(32-bits)))
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeValues is a function that returns a completion function for a
+// flag with a fixed set of values
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeList is a function that returns a completion function for a flag
+// with a comma separated list of values, e.g. --with rdns,asn
+func completeList(values func() []string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// Offer the values that are not in the list yet, after the values already given
+		prefix := ""
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			prefix = toComplete[:i+1]
+		}
+		given := make(map[string]bool)
+		for _, v := range strings.Split(prefix, ",") {
+			given[v] = true
+		}
+
+		var completions []string
+		for _, v := range values() {
+			if !given[v] {
+				completions = append(completions, prefix+v)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
+
+// completePlugins is a function that completes the names of the plugins as
+// commands of iptool
+func completePlugins(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	for _, p := range plugin.List() {
+		if strings.HasPrefix(p.Name, toComplete) {
+			completions = append(completions, p.Name+"\tplugin")
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/bitcanon/iptool/exitcode"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write the configuration file",
+	Long: `Read and write the configuration file.
+
+Every flag of every command can be given a default value in the configuration
+file (~/.iptool.yaml unless --config is given), under the name of the command
+and the flag, e.g. tcp.ping.timeout for the --timeout flag of tcp ping. The
+config command group lists these keys, shows the effective value of a key and
+where it comes from (default, file, env or flag), and sets keys in the file
+after validating the key and the type of the value.
+
+A flag on the command line overrides an IPTOOL_ environment variable (e.g.
+IPTOOL_TCP_PING_TIMEOUT), which overrides the configuration file, which
+overrides the default of the flag. Use --no-config to ignore the file.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
+
+// configKey describes a configuration key: the flag it is bound to, or a
+// key that is only set in the configuration file
+type configKey struct {
+	Key         string
+	Type        string
+	Default     string
+	Description string
+	Command     string
+	Flag        *pflag.Flag
+}
+
+// configSections are the keys that are only set in the configuration file,
+// the keys ending in a dot are sections of named entries
+var configSections = []configKey{
+	{Key: "aliases.", Type: "string", Description: "named address or network, used in place of an address (aliases.<name>)"},
+	{Key: "checks", Type: "map", Description: "composite checks run by the check command (edit the file to change them)"},
+	{Key: "enrich.dnsbl", Type: "stringSlice", Description: "DNS blocklist zones queried by enrich for the reputation column"},
+	{Key: "groups.", Type: "stringSlice", Description: "named group of targets, referenced as @<name> (groups.<name>)"},
+	{Key: "results.file", Type: "string", Description: "file the results are recorded to with --record"},
+}
+
+// configSchema is a function that returns the known configuration keys,
+// sorted by key. The keys of the flags are found by walking the commands,
+// a flag is configurable if it is bound to <command path>.<flag name>.
+func configSchema() []configKey {
+	bound := make(map[string]bool)
+	for _, key := range viper.AllKeys() {
+		bound[key] = true
+	}
+
+	var keys []configKey
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		// The keys of the root flags have no prefix
+		path := strings.Join(strings.Fields(cmd.CommandPath())[1:], ".")
+		cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			key := f.Name
+			if path != "" {
+				key = path + "." + f.Name
+			}
+			if !bound[key] {
+				return
+			}
+			keys = append(keys, configKey{
+				Key:         key,
+				Type:        f.Value.Type(),
+				Default:     f.DefValue,
+				Description: f.Usage,
+				Command:     cmd.CommandPath(),
+				Flag:        f,
+			})
+		})
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(rootCmd)
+	keys = append(keys, configSections...)
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Key < keys[j].Key
+	})
+	return keys
+}
+
+// lookupConfigKey is a function that returns the schema of a configuration
+// key, a key in a section of named entries (e.g. aliases.web) is accepted
+func lookupConfigKey(key string) (configKey, error) {
+	key = strings.ToLower(key)
+	for _, k := range configSchema() {
+		if k.Key == key {
+			return k, nil
+		}
+		if strings.HasSuffix(k.Key, ".") && strings.HasPrefix(key, k.Key) && len(key) > len(k.Key) {
+			k.Key = key
+			return k, nil
+		}
+
+		// The settings of the entries of a map (e.g. checks.web.condition)
+		if k.Type == "map" && strings.HasPrefix(key, k.Key+".") {
+			k.Key = key
+			return k, nil
+		}
+	}
+	return configKey{}, exitcode.New(exitcode.Usage, fmt.Errorf("unknown configuration key: %s (see iptool config list)", key))
+}
+
+// configSource is a function that returns where the effective value of a
+// configuration key comes from: flag, env, file or default
+func configSource(k configKey) string {
+	if k.Flag != nil && k.Flag.Changed {
+		return "flag"
+	}
+	env := "IPTOOL_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(k.Key))
+	if _, ok := os.LookupEnv(env); ok {
+		return "env"
+	}
+	if viper.InConfig(k.Key) {
+		return "file"
+	}
+	return "default"
+}
+
+// configValue is a function that returns the effective value of a
+// configuration key as a string, lists are separated by commas
+func configValue(key string) string {
+	switch value := viper.Get(key).(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(value, ",")
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		s := fmt.Sprint(value)
+		// Flag values of lists are printed as [a,b]
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			s = strings.Trim(s, "[]")
+		}
+		return s
+	}
+}
+
+// configFilePath is a function that returns the path of the configuration
+// file: the file that was read, the file given with --config, or the default
+// file in the home directory if there is none yet
+func configFilePath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".iptool.yaml"), nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the configuration file in an editor",
+	Long: `Open the configuration file in an editor.
+
+The editor is taken from the VISUAL or EDITOR environment variable (vi, or
+notepad on Windows, if neither is set). The configuration file is created if
+it does not exist yet. When the editor is closed, the file is validated and
+unknown keys and invalid values are reported.
+
+Examples:
+  iptool config edit
+  EDITOR=nano iptool config edit`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configEditAction(os.Stdout)
+	},
+}
+
+// configEditAction is the action function for the config edit command
+func configEditAction(out io.Writer) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	// Create the configuration file if it does not exist yet
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	// Find the editor, the variables may contain arguments (e.g. code --wait)
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run the editor %s: %w", editor, err)
+	}
+
+	// Validate the edited file
+	problems, err := validateConfigFile(path)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(out, "Warning: %s\n", problem)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// validateConfigFile is a function that reads a configuration file and
+// returns the unknown keys and the values that are invalid for their key
+func validateConfigFile(path string) ([]string, error) {
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.SetConfigType("yaml")
+	if err := file.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems []string
+	for _, key := range file.AllKeys() {
+		k, err := lookupConfigKey(key)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unknown key %s", key))
+			continue
+		}
+
+		// Lists and maps are not validated, only single values
+		value := file.Get(key)
+		switch value.(type) {
+		case []any, map[string]any:
+			continue
+		}
+		if _, err := utils.ParseConfigValue(k.Type, fmt.Sprint(value)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	return problems, nil
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show the effective value of a configuration key and its source",
+	Long: `Show the effective value of a configuration key and its source.
+
+The source is where the effective value comes from: the default of the flag,
+the configuration file (file), an IPTOOL_ environment variable (env) or a
+flag on the command line (flag). Use --value to only print the value, e.g. in
+scripts.
+
+Examples:
+  iptool config get tcp.ping.timeout
+  iptool config get aliases.web-vip --value`,
+	Args:              cobra.ExactArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeConfigKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configGetAction(os.Stdout, args[0])
+	},
+}
+
+// configGetAction is the action function for the config get command
+func configGetAction(out io.Writer, key string) error {
+	k, err := lookupConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("config.get.value") {
+		fmt.Fprintln(out, configValue(k.Key))
+	} else {
+		fmt.Fprintf(out, "Key:         %s\n", k.Key)
+		fmt.Fprintf(out, "Value:       %s\n", configValue(k.Key))
+		fmt.Fprintf(out, "Source:      %s\n", configSource(k))
+		fmt.Fprintf(out, "Type:        %s\n", k.Type)
+		if k.Flag != nil {
+			fmt.Fprintf(out, "Default:     %s\n", k.Default)
+			fmt.Fprintf(out, "Flag:        %s --%s\n", k.Command, k.Flag.Name)
+		}
+		fmt.Fprintf(out, "Description: %s\n", k.Description)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// completeConfigKeys is a function that completes the configuration keys
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for _, k := range configSchema() {
+		keys = append(keys, k.Key)
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+
+	// Define the flag for only printing the value
+	configGetCmd.Flags().Bool("value", false, "only print the value")
+	viper.BindPFlag("config.get.value", configGetCmd.Flags().Lookup("value"))
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:     "list [prefix]",
+	Aliases: []string{"ls"},
+	Short:   "List the configuration keys with their values and sources",
+	Long: `List the configuration keys with their values and sources.
+
+Every key that can be set in the configuration file is listed with its type,
+its effective value and where the value comes from (default, file, env or
+flag). Give a prefix (e.g. tcp.ping) to only list the keys of a command, and
+use --changed to only list the keys that are not at their default.
+
+The path of the configuration file is printed at the top.
+
+Examples:
+  iptool config list
+  iptool config list tcp.ping
+  iptool config list --changed`,
+	Args:              cobra.MaximumNArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeConfigKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prefix := ""
+		if len(args) > 0 {
+			prefix = strings.ToLower(args[0])
+		}
+		return configListAction(os.Stdout, prefix)
+	},
+}
+
+// configListAction is the action function for the config list command
+func configListAction(out io.Writer, prefix string) error {
+	// Collect the rows first, so that the columns fit the longest values
+	var rows [][]string
+	for _, k := range configSchema() {
+		if !strings.HasPrefix(k.Key, prefix) {
+			continue
+		}
+
+		// The sections of named entries are listed entry by entry
+		if strings.HasSuffix(k.Key, ".") {
+			var names []string
+			for name := range viper.GetStringMap(strings.TrimSuffix(k.Key, ".")) {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				entry := k
+				entry.Key = k.Key + name
+				rows = append(rows, []string{entry.Key, entry.Type, configValue(entry.Key), configSource(entry)})
+			}
+			continue
+		}
+
+		source := configSource(k)
+		if viper.GetBool("config.list.changed") && source == "default" {
+			continue
+		}
+		value := configValue(k.Key)
+		if k.Type == "map" {
+			value = fmt.Sprintf("(%d entries)", len(viper.GetStringMap(k.Key)))
+		}
+		rows = append(rows, []string{k.Key, k.Type, value, source})
+	}
+
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	if viper.GetBool("no-config") {
+		path += " (ignored, --no-config)"
+	} else if viper.ConfigFileUsed() == "" {
+		path += " (not found)"
+	}
+	fmt.Fprintf(out, "Configuration file: %s\n\n", path)
+
+	table := render.NewTable(out, getRenderOptions("config.list", out),
+		render.Column{Title: "Key"},
+		render.Column{Title: "Type"},
+		render.Column{Title: "Value", Truncate: true},
+		render.Column{Title: "Source"},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	for _, row := range rows {
+		table.Fit(row...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+
+	// Define the flag for only listing the keys that are not at their default
+	configListCmd.Flags().BoolP("changed", "c", false, "only list the keys that are set in the file, the environment or a flag")
+	viper.BindPFlag("config.list.changed", configListCmd.Flags().Lookup("changed"))
+	addRenderFlags(configListCmd, "config.list")
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration key in the configuration file",
+	Long: `Set a configuration key in the configuration file.
+
+The key must be a known configuration key (see iptool config list) and the
+value must be valid for the type of the key, e.g. a number for an int key or
+a duration such as 500ms for a duration key. Lists are separated by commas.
+The configuration file is created if it does not exist yet.
+
+Note that the file is rewritten by the set command, so comments in the file
+are not kept. Use iptool config edit to keep them.
+
+Examples:
+  iptool config set tcp.ping.timeout 500
+  iptool config set subnet.split.format csv
+  iptool config set aliases.web-vip 192.0.2.10
+  iptool config set groups.dns-servers 1.1.1.1,8.8.8.8`,
+	Args:              cobra.ExactArgs(2),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeConfigKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configSetAction(os.Stdout, args[0], args[1])
+	},
+}
+
+// configSetAction is the action function for the config set command
+func configSetAction(out io.Writer, key, s string) error {
+	k, err := lookupConfigKey(key)
+	if err != nil {
+		return err
+	}
+	if k.Type == "map" {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("%s cannot be set on the command line, use iptool config edit", k.Key))
+	}
+	value, err := utils.ParseConfigValue(k.Type, s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("%s: %w", k.Key, err))
+	}
+
+	// Create the configuration file if it does not exist yet
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	// Use a separate instance to only write the keys of the file, not the
+	// defaults and flags of the global instance
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.SetConfigType("yaml")
+	if err := file.ReadInConfig(); err != nil {
+		return err
+	}
+	file.Set(k.Key, value)
+	if err := file.WriteConfig(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Set %s to %s in %s\n", k.Key, s, path)
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// convert6to4Cmd represents the convert 6to4 command
+var convert6to4Cmd = &cobra.Command{
+	Use:   "6to4 <address>",
+	Short: "Convert between an IPv4 address and its 6to4 prefix",
+	Long: `Convert between an IPv4 address and its 6to4 prefix.
+
+6to4 (RFC 3056) gives every public IPv4 address the IPv6 prefix
+2002:<ipv4>::/48. Given an IPv4 address, the 6to4 prefix is printed. Given
+an address in 2002::/16, the embedded IPv4 address is printed.
+
+Examples:
+  iptool convert 6to4 192.0.2.1
+  iptool convert 6to4 2002:c000:201::1`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return convert6to4Action(os.Stdout, args[0])
+	},
+}
+
+// convert6to4Action is the action function for the convert 6to4 command
+func convert6to4Action(out io.Writer, s string) error {
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(s), "[]"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid address: %s", s))
+	}
+
+	if addr.Is4() {
+		prefix, err := ip.SixToFour(addr)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+
+		// 6to4 only works with a public IPv4 address
+		if kind := ip.Classify(addr); kind != "Global unicast" {
+			fmt.Fprintf(os.Stderr, "Warning: %s is a %s address, 6to4 requires a public IPv4 address\n", addr, strings.ToLower(kind))
+		}
+		fmt.Fprintf(out, "IPv4 address : %s\n", addr)
+		fmt.Fprintf(out, "6to4 prefix  : %s\n", prefix)
+	} else {
+		v4, err := ip.ExtractSixToFour(addr)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		prefix, _ := ip.SixToFour(v4)
+		fmt.Fprintf(out, "6to4 address : %s\n", addr)
+		fmt.Fprintf(out, "6to4 prefix  : %s\n", prefix)
+		fmt.Fprintf(out, "IPv4 address : %s\n", v4)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// init registers the command
+func init() {
+	convertCmd.AddCommand(convert6to4Cmd)
+}
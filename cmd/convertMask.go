@@ -0,0 +1,104 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// convertMaskCmd represents the convert mask command
+var convertMaskCmd = &cobra.Command{
+	Use:   "mask <mask>",
+	Short: "Convert a netmask between prefix, dotted, wildcard and hex notation",
+	Long: `Convert a netmask between prefix, dotted, wildcard and hex notation.
+
+The mask can be given in any of the following formats:
+  /24 or 24           prefix length
+  255.255.255.0       dotted-decimal netmask
+  0.0.0.255           dotted-decimal wildcard mask
+  ffffff00            hexadecimal (with or without 0x)
+
+The mask must be contiguous, unless the --allow-discontiguous flag is set.
+
+Examples:
+  iptool convert mask /24
+  iptool convert mask 255.255.240.0
+  iptool convert mask 0.0.3.255
+  iptool convert mask 0xffffff00`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		input := strings.Join(args, " ")
+
+		return convertMaskAction(os.Stdout, input)
+	},
+}
+
+// convertMaskAction is the action function for the convert mask command
+func convertMaskAction(out io.Writer, s string) error {
+	// Parse the mask in any of the supported formats
+	mask, err := ip.ParseMask(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Discontiguous masks are only accepted if the --allow-discontiguous flag is set
+	prefixLength := fmt.Sprintf("/%d", mask.PrefixLength())
+	if !mask.Contiguous() {
+		if !viper.GetBool("allow-discontiguous") {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("%w: %s (use --allow-discontiguous to convert it anyway)", ip.ErrDiscontiguousNetmask, mask.Netmask()))
+		}
+		prefixLength = "n/a (non-contiguous)"
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	fmt.Fprintf(out, "Prefix length : %s\n", prefixLength)
+	fmt.Fprintf(out, "Netmask       : %s\n", mask.Netmask())
+	fmt.Fprintf(out, "Wildcard mask : %s\n", mask.Wildcard())
+	fmt.Fprintf(out, "Hexadecimal   : %s\n", mask.Hex())
+	fmt.Fprintf(out, "Binary        : %s\n", mask.Binary())
+	fmt.Fprintf(out, "Addresses     : %d\n", mask.NetworkSize())
+
+	return nil
+}
+
+// init registers the command
+func init() {
+	convertCmd.AddCommand(convertMaskCmd)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// convertNAT64Cmd represents the convert nat64 command
+var convertNAT64Cmd = &cobra.Command{
+	Use:   "nat64 <address>",
+	Short: "Embed an IPv4 address in a NAT64 prefix or extract it",
+	Long: `Embed an IPv4 address in a NAT64 prefix or extract it.
+
+NAT64 and DNS64 (RFC 6052) represent IPv4 addresses as IPv6 addresses by
+embedding them in a NAT64 prefix, the well-known prefix 64:ff9b::/96 by
+default. Use --prefix for a network-specific prefix, which must be a /32,
+/40, /48, /56, /64 or /96; bits 64 to 71 of the address are skipped.
+
+Given an IPv4 address, the IPv6 address is printed. Given an IPv6 address,
+the embedded IPv4 address is printed.
+
+Examples:
+  iptool convert nat64 192.0.2.33
+  iptool convert nat64 64:ff9b::c000:221
+  iptool convert nat64 192.0.2.33 --prefix 2001:db8:122::/48
+  iptool convert nat64 2001:db8:122:c000:2:2100:: --prefix 2001:db8:122::/48`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return convertNAT64Action(os.Stdout, args[0])
+	},
+}
+
+// convertNAT64Action is the action function for the convert nat64 command
+func convertNAT64Action(out io.Writer, s string) error {
+	prefix, err := netip.ParsePrefix(viper.GetString("convert.nat64.prefix"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid NAT64 prefix: %s", viper.GetString("convert.nat64.prefix")))
+	}
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(s), "[]"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid address: %s", s))
+	}
+
+	var v4, v6 netip.Addr
+	if addr.Is4() {
+		v4 = addr
+		if v6, err = ip.NAT64Embed(prefix, v4); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+	} else {
+		v6 = addr
+		if v4, err = ip.NAT64Extract(prefix, v6); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+	}
+
+	fmt.Fprintf(out, "IPv4 address : %s\n", v4)
+	fmt.Fprintf(out, "NAT64 prefix : %s\n", prefix.Masked())
+	fmt.Fprintf(out, "IPv6 address : %s\n", v6)
+
+	// With a /96 prefix the IPv4 address is readable in mixed notation
+	if prefix.Bits() == 96 {
+		fmt.Fprintf(out, "Mixed        : %s\n", ip.FormatIPv6(v6).Mixed)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	convertCmd.AddCommand(convertNAT64Cmd)
+
+	// Define the flag for the NAT64 prefix
+	convertNAT64Cmd.Flags().StringP("prefix", "p", ip.NAT64WellKnownPrefix.String(), "NAT64 prefix (/32, /40, /48, /56, /64 or /96)")
+	viper.BindPFlag("convert.nat64.prefix", convertNAT64Cmd.Flags().Lookup("prefix"))
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// convertTeredoCmd represents the convert teredo command
+var convertTeredoCmd = &cobra.Command{
+	Use:   "teredo <address>",
+	Short: "Decode the parameters of a Teredo address",
+	Long: `Decode the parameters of a Teredo address.
+
+A Teredo address (RFC 4380) in 2001::/32 embeds the IPv4 address of the
+Teredo server, flags, and the public IPv4 address and UDP port of the
+client behind the NAT. The client address and port are obfuscated by
+inverting their bits. The cone flag tells that the client is behind a
+cone NAT.
+
+Examples:
+  iptool convert teredo 2001:0:4136:e378:8000:63bf:3fff:fdd2`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return convertTeredoAction(os.Stdout, args[0])
+	},
+}
+
+// convertTeredoAction is the action function for the convert teredo command
+func convertTeredoAction(out io.Writer, s string) error {
+	addr, err := ip.ParseIPv6Address(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	teredo, err := ip.ParseTeredo(addr)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	nat := "restricted NAT"
+	if teredo.Cone {
+		nat = "cone NAT"
+	}
+	fmt.Fprintf(out, "Teredo address : %s\n", addr)
+	fmt.Fprintf(out, "Server         : %s\n", teredo.Server)
+	fmt.Fprintf(out, "Client         : %s\n", teredo.Client)
+	fmt.Fprintf(out, "Client port    : %d\n", teredo.Port)
+	fmt.Fprintf(out, "Flags          : 0x%04x (%s)\n", teredo.Flags, nat)
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// init registers the command
+func init() {
+	convertCmd.AddCommand(convertTeredoCmd)
+}
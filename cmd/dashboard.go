@@ -0,0 +1,436 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/probe"
+	"github.com/bitcanon/iptool/results"
+	"github.com/bitcanon/iptool/stats"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ANSI escape sequences used to draw the dashboard
+const (
+	ansiAltScreen    = "\x1b[?1049h\x1b[?25l"
+	ansiNormalScreen = "\x1b[?25h\x1b[?1049l"
+	ansiHome         = "\x1b[H"
+	ansiClearLine    = "\x1b[K"
+	ansiClearBelow   = "\x1b[J"
+	ansiRed          = "\x1b[31m"
+	ansiGreen        = "\x1b[32m"
+	ansiYellow       = "\x1b[33m"
+	ansiBold         = "\x1b[1m"
+	ansiReset        = "\x1b[0m"
+)
+
+// dashboardCmd represents the dashboard command
+var dashboardCmd = &cobra.Command{
+	Use:     "dashboard [target...]",
+	Aliases: []string{"monitor"},
+	Short:   "Show a live dashboard of the status of many targets",
+	Long: `Show a live dashboard of the status of many targets.
+
+Every target is probed concurrently once per interval, and the dashboard
+shows the current status, the last and average response time, the packet
+loss and a sparkline of the recent response times of every target. Press
+Ctrl-C to quit.
+
+The targets are read from the file given with --targets (or --targets-file),
+where they are organized in groups, and/or given as arguments (including
+@<name> references to the groups in the configuration file). A targets file
+looks like this:
+
+  groups:
+    routers:
+      - icmp://10.0.0.{1..4}
+    core:
+      - 10.0.0.{1..4}:22
+    web:
+      - https://www.example.com
+      - tcp://[2001:db8::80]:80
+
+Targets are given as <type>://<address>, where the type of probe is one of
+` + strings.Join(probe.Schemes(), ", ") + `. The type defaults to tcp and the TCP
+port to 443. ICMP probes use unprivileged ping sockets where the system
+allows them (see net.ipv4.ping_group_range on Linux), otherwise they require
+raw socket privileges.
+
+With the global --record flag, the result of every probe is appended to the
+results store (see 'iptool history'), so that the trend of a target can be
+shown later with 'iptool history show <address> --kind monitor'.
+
+Use --webhook-url to post an alert to a chat or alerting webhook when a
+target goes down, recovers or (with --alert-rtt) responds slower than the
+threshold. See 'iptool tcp ping --help' for the webhook flags.
+
+Examples:
+  iptool dashboard --targets groups.yaml
+  iptool dashboard 1.1.1.1:53 8.8.8.8:53 --interval 500
+  iptool dashboard icmp://1.1.1.1 icmp://8.8.8.8 --history 60
+  iptool monitor --targets-file targets.yaml --record
+  iptool dashboard @core --alert-rtt 100ms --webhook-url https://hooks.example.com/iptool`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeTargetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no targets are provided, print a short help text
+		if len(args) == 0 && viper.GetString("dashboard.targets") == "" {
+			cmd.Help()
+			return nil
+		}
+		return dashboardAction(os.Stdout, args)
+	},
+}
+
+// dashboardTarget holds the probe results of a single target
+type dashboardTarget struct {
+	group  string
+	prober probe.Prober
+
+	// Packet counters and response time statistics
+	sent     int
+	received int
+	stats    stats.Stats
+
+	// Result of the last probe, a nil error means that the target responded
+	last    time.Duration
+	lastErr error
+
+	// Response times of the recent probes in milliseconds, NaN for lost probes
+	history []float64
+
+	// State of the target for alerts (up, slow or down, empty before the first probe)
+	state string
+
+	// Results of the probes that are not yet recorded in the results store
+	pending []results.Result
+}
+
+// add is a function that records the result of a probe, and keeps it for
+// the results store if record is set
+func (t *dashboardTarget) add(rtt time.Duration, err error, historySize int, record bool) {
+	t.sent++
+	t.last, t.lastErr = rtt, err
+
+	if record {
+		result := results.Result{Time: time.Now(), Kind: results.KindMonitor, Target: t.prober.String(), Success: err == nil, Detail: t.group}
+		if u, err := url.Parse(t.prober.String()); err == nil && u.Host != "" {
+			result.Address = u.Hostname()
+			result.Port, _ = strconv.Atoi(u.Port())
+		}
+		if err != nil {
+			result.Detail += ", " + shortError(err.Error())
+		} else {
+			result.RTTMs = float64(rtt) / float64(time.Millisecond)
+		}
+		t.pending = append(t.pending, result)
+	}
+
+	value := math.NaN()
+	if err == nil {
+		t.received++
+		t.stats.Add(rtt)
+		value = float64(rtt) / float64(time.Millisecond)
+	}
+
+	t.history = append(t.history, value)
+	if len(t.history) > historySize {
+		t.history = t.history[len(t.history)-historySize:]
+	}
+}
+
+// loss is a function that returns the packet loss of the target in percent
+func (t *dashboardTarget) loss() float64 {
+	if t.sent == 0 {
+		return 0
+	}
+	return float64(t.sent-t.received) / float64(t.sent) * 100
+}
+
+// dashboardAction is the action function for the dashboard command
+func dashboardAction(out io.Writer, args []string) error {
+	// Check the probe settings
+	interval := viper.GetDuration("dashboard.interval") * time.Millisecond
+	timeout := viper.GetDuration("dashboard.timeout") * time.Millisecond
+	historySize := viper.GetInt("dashboard.history")
+	if interval <= 0 || timeout <= 0 || historySize <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--interval, --timeout and --history must be greater than zero"))
+	}
+
+	// Collect the groups of targets from the arguments and the targets file
+	var groups []probe.Group
+	if len(args) > 0 {
+		groups = append(groups, probe.Group{Name: "targets", Targets: args})
+	}
+	if targetsFile := viper.GetString("dashboard.targets"); targetsFile != "" {
+		fileGroups, err := probe.LoadGroups(targetsFile)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, fileGroups...)
+	}
+
+	// Create a prober for every target
+	var targets []*dashboardTarget
+	for _, group := range groups {
+		expanded, err := expandTargets(group.Targets)
+		if err != nil {
+			return err
+		}
+		for _, target := range expanded {
+			prober, err := probe.New(target)
+			if err != nil {
+				return exitcode.New(exitcode.Usage, err)
+			}
+			targets = append(targets, &dashboardTarget{group: group.Name, prober: prober})
+		}
+	}
+	if len(targets) == 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no targets to probe"))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop when the user presses Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The alerts are posted to the webhook in the background
+	webhook, err := getWebhookDispatcher("dashboard")
+	if err != nil {
+		return err
+	}
+	defer webhook.Close(notify.Timeout)
+	alertRTT := viper.GetDuration("dashboard.alert-rtt")
+	if alertRTT < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --alert-rtt value: %s (must be positive)", alertRTT))
+	}
+
+	// The mutex protects the results from being read while they are updated
+	var mutex sync.Mutex
+
+	// With --record, the results of the probes are appended to the results
+	// store once per interval (and when quitting) instead of after every probe
+	record := viper.GetBool("record")
+	flushResults := func() {
+		var list []results.Result
+		for _, t := range targets {
+			list = append(list, t.pending...)
+			t.pending = nil
+		}
+		recordResults(list...)
+	}
+
+	// Probe every target in its own goroutine
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target *dashboardTarget) {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				rtt, err := target.prober.Probe(ctx, timeout)
+				if ctx.Err() != nil {
+					return
+				}
+				mutex.Lock()
+				target.add(rtt, err, historySize, record)
+				mutex.Unlock()
+
+				// Alert when the target goes down, gets slow or recovers, the
+				// first probe only alerts if the target is not up
+				state := notify.StateUp
+				switch {
+				case err != nil:
+					state = notify.StateDown
+				case alertRTT > 0 && rtt > alertRTT:
+					state = notify.StateSlow
+				}
+				if previous := target.state; state != previous && (previous != "" || state != notify.StateUp) {
+					event := notify.NewEvent(target.prober.String(), "", 0, state, previous)
+					event.Source = "dashboard"
+					webhook.Notify(event)
+				}
+				target.state = state
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(target)
+	}
+
+	// Draw the dashboard in the alternate screen of the terminal, so that
+	// the previous content of the terminal is restored when quitting
+	terminal := out == os.Stdout && utils.IsTerminal(os.Stdout)
+	if terminal {
+		fmt.Fprint(out, ansiAltScreen)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		mutex.Lock()
+		frame := renderDashboard(targets, interval, terminal)
+		flushResults()
+		mutex.Unlock()
+
+		if terminal {
+			// Redraw the screen in place to avoid flickering
+			frame = strings.ReplaceAll(frame, "\n", ansiClearLine+"\n")
+			fmt.Fprint(out, ansiHome+frame+ansiClearBelow)
+		} else {
+			fmt.Fprintln(out, frame)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			flushResults()
+			if terminal {
+				// Leave the last state of the dashboard on the normal screen
+				fmt.Fprint(out, ansiNormalScreen)
+				fmt.Fprint(out, renderDashboard(targets, interval, false))
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderDashboard is a function that returns a frame of the dashboard, with
+// colors if the color flag is set
+func renderDashboard(targets []*dashboardTarget, interval time.Duration, color bool) string {
+	// colorize is a helper that wraps the text in a color when colors are enabled
+	colorize := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	// Find the length of the longest target (for padding)
+	width := len("Target")
+	for _, t := range targets {
+		width = max(width, len(t.prober.String()))
+	}
+	fmtString := fmt.Sprintf("  %%-6s %%-%ds %%10s %%10s %%7s  %%s\n", width)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s  %d targets, interval %s, %s\n", colorize(ansiBold, "iptool dashboard"), len(targets), interval, utils.GetTimestamp())
+
+	group := ""
+	for i, t := range targets {
+		// Print a header for every group
+		if i == 0 || t.group != group {
+			group = t.group
+			fmt.Fprintf(&sb, "\n%s\n", colorize(ansiBold, group))
+			fmt.Fprintf(&sb, fmtString, "Status", "Target", "Last", "Average", "Loss", "History")
+		}
+
+		// Determine the status of the target from the last probe
+		status, statusColor, last, average := "...", ansiYellow, "-", "-"
+		switch {
+		case t.sent == 0:
+		case t.lastErr != nil:
+			status, statusColor = "DOWN", ansiRed
+		case t.received < t.sent:
+			status, statusColor = "UP", ansiYellow
+		default:
+			status, statusColor = "UP", ansiGreen
+		}
+		if t.sent > 0 && t.lastErr == nil {
+			last = formatMilliseconds(t.last)
+		}
+		if t.received > 0 {
+			average = formatMilliseconds(t.stats.Mean())
+		}
+
+		// Pad the status before it is colored so that the columns line up
+		status = colorize(statusColor, fmt.Sprintf("%-6s", status))
+		fmt.Fprintf(&sb, fmtString, status, t.prober.String(), last, average, fmt.Sprintf("%.1f%%", t.loss()), utils.Sparkline(t.history, '×'))
+	}
+
+	return sb.String()
+}
+
+// formatMilliseconds is a function that formats a response time in milliseconds
+func formatMilliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", float64(d)/float64(time.Millisecond))
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+
+	// Define the flag for the file with the groups of targets
+	dashboardCmd.Flags().StringP("targets", "t", "", "YAML file with groups of targets")
+	viper.BindPFlag("dashboard.targets", dashboardCmd.Flags().Lookup("targets"))
+
+	// Accept --targets-file as well, like the other commands reading files
+	dashboardCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "targets-file" {
+			name = "targets"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	// Define the flag for the interval between probes
+	dashboardCmd.Flags().IntP("interval", "i", 1000, "time between probes of a target, in milliseconds")
+	viper.BindPFlag("dashboard.interval", dashboardCmd.Flags().Lookup("interval"))
+
+	// Define the flag for the probe timeout
+	dashboardCmd.Flags().Int("timeout", 1000, "time to wait for a response, in milliseconds")
+	viper.BindPFlag("dashboard.timeout", dashboardCmd.Flags().Lookup("timeout"))
+
+	// Define the flag for the number of probes in the sparkline
+	dashboardCmd.Flags().Int("history", 30, "number of recent probes shown in the history sparkline")
+	viper.BindPFlag("dashboard.history", dashboardCmd.Flags().Lookup("history"))
+
+	// Define the flags for alerting when a target goes down, gets slow or recovers
+	dashboardCmd.Flags().Duration("alert-rtt", 0, "report a target as slow when a response takes longer than this (e.g. 200ms)")
+	viper.BindPFlag("dashboard.alert-rtt", dashboardCmd.Flags().Lookup("alert-rtt"))
+	addWebhookFlags(dashboardCmd, "dashboard")
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/discovery"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// discoverNeighborsCmd represents the discover neighbors command
+var discoverNeighborsCmd = &cobra.Command{
+	Use:   "neighbors",
+	Short: "Show the switches and routers announcing themselves with LLDP or CDP",
+	Long: `Show the switches and routers announcing themselves with LLDP or CDP.
+
+The LLDP (IEEE 802.1AB) and CDP (Cisco Discovery Protocol) frames received on
+the interface are captured for the --wait duration, and the name, port, VLAN
+and management address of every neighbor are printed. This tells you which
+switch port a server is patched to. Switches send LLDP every 30 seconds and
+CDP every 60 seconds by default, use --count to stop as soon as the expected
+number of neighbors has been found.
+
+Capturing the frames requires raw socket privileges: run iptool as root, or
+install iptool-helper (see iptool version). Capturing is supported on Linux.
+
+Examples:
+  iptool discover neighbors --interface eth0
+  iptool discover neighbors -i eth0 --wait 60s --count 1
+  iptool discover neighbors -i eth0 --json`,
+	Aliases:      []string{"neighbours"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return discoverNeighborsAction(os.Stdout)
+	},
+}
+
+// discoverNeighborsAction is the action function for the discover neighbors command
+func discoverNeighborsAction(out io.Writer) error {
+	iface := viper.GetString("discover.neighbors.interface")
+	if iface == "" {
+		return exitcode.New(exitcode.Usage, errors.New("invalid interface: no interface given (use --interface)"))
+	}
+	wait := viper.GetDuration("discover.neighbors.wait")
+	if wait <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid wait: %s (must be positive)", wait))
+	}
+	count := viper.GetInt("discover.neighbors.count")
+	if count < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid count: %d (must be 0 or positive)", count))
+	}
+
+	neighbors, err := discovery.Capture(iface, wait, count)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("discover.neighbors.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if neighbors == nil {
+			neighbors = []discovery.Neighbor{}
+		}
+		if err := encoder.Encode(neighbors); err != nil {
+			return err
+		}
+	} else if len(neighbors) > 0 {
+		table := render.NewTable(out, getRenderOptions("discover.neighbors", out),
+			render.Column{Title: "Protocol"},
+			render.Column{Title: "Switch"},
+			render.Column{Title: "Port"},
+			render.Column{Title: "VLAN", Align: render.AlignRight},
+			render.Column{Title: "Management"},
+			render.Column{Title: "Platform", Truncate: true},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		rows := make([][]string, len(neighbors))
+		for i, n := range neighbors {
+			// Show the port description too, it often names the patched server
+			port := n.Port
+			if n.PortDescription != "" && n.PortDescription != n.Port {
+				port += " (" + n.PortDescription + ")"
+			}
+			vlan := ""
+			if n.VLAN > 0 {
+				vlan = strconv.Itoa(n.VLAN)
+			}
+			rows[i] = []string{n.Protocol, n.Name(), port, vlan, n.ManagementAddress, n.Platform}
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Finding no neighbors is reported by the exit code, e.g. for scripts
+	if len(neighbors) == 0 {
+		return exitcode.New(exitcode.Unreachable, fmt.Errorf("no LLDP or CDP neighbors found on %s within %s", iface, wait))
+	}
+
+	return nil
+}
+
+func init() {
+	discoverCmd.AddCommand(discoverNeighborsCmd)
+
+	// Define the flags for the interface and the time to listen
+	discoverNeighborsCmd.Flags().StringP("interface", "i", "", "network interface to listen on (e.g. eth0)")
+	viper.BindPFlag("discover.neighbors.interface", discoverNeighborsCmd.Flags().Lookup("interface"))
+	discoverNeighborsCmd.RegisterFlagCompletionFunc("interface", completeInterfaces)
+	discoverNeighborsCmd.Flags().DurationP("wait", "w", 60*time.Second, "time to listen for announcements (at most 60s with iptool-helper)")
+	viper.BindPFlag("discover.neighbors.wait", discoverNeighborsCmd.Flags().Lookup("wait"))
+	discoverNeighborsCmd.Flags().IntP("count", "c", 0, "stop after finding this number of neighbors (0 waits the full time)")
+	viper.BindPFlag("discover.neighbors.count", discoverNeighborsCmd.Flags().Lookup("count"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(discoverNeighborsCmd, "discover.neighbors")
+
+	// Define the flag for printing the neighbors in JSON format
+	discoverNeighborsCmd.Flags().Bool("json", false, "print the neighbors in JSON format")
+	viper.BindPFlag("discover.neighbors.json", discoverNeighborsCmd.Flags().Lookup("json"))
+}
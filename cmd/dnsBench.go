@@ -0,0 +1,345 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/dns"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dnsBenchCmd represents the dns bench command
+var dnsBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the latency and failure rate of DNS resolvers",
+	Long: `Benchmark the latency and failure rate of DNS resolvers.
+
+The same set of A queries is sent to every server, one query at a time per
+server unless --concurrency is raised (the servers are benchmarked in
+parallel), and the minimum, average, 95th percentile and maximum response
+times and the failure rate are reported per server, fastest first. A query
+fails if the server does not respond within the timeout or responds with an
+error. A response that the name does not exist (NXDOMAIN) is an answer.
+
+The names are given with --names or read from a file with --names-file (one
+name per line), and are queried in turn until --queries queries have been
+sent. By default, a set of popular domains is queried, which measures the
+response times of cached answers.
+
+The servers are queried over plain UDP (falling back to TCP for truncated
+responses) unless they are given as tls://host[:port] for DNS over TLS (port
+853 by default) or as an https:// URL for DNS over HTTPS. Use --dot and --doh
+to add encrypted servers, which makes it easy to compare the plaintext and
+encrypted response times of a resolver, or to benchmark resolvers in networks
+that block plain DNS. When only --dot or --doh is given, only the encrypted
+servers are benchmarked.
+
+Use --rate to limit the number of queries sent to every server (e.g. 20/s),
+so that a benchmark does not trip the rate limits of a resolver. When Ctrl-C
+is pressed, no more queries are sent and the queries in flight are completed
+before the results are reported (press Ctrl-C again to quit immediately).
+
+Examples:
+  iptool dns bench
+  iptool dns bench --servers 1.1.1.1,8.8.8.8,9.9.9.9 --queries 100
+  iptool dns bench --servers 192.168.1.1,10.0.0.53:5353 --names-file domains.txt
+  iptool dns bench --names example.com,example.org --timeout 500 --json
+  iptool dns bench --queries 1000 --concurrency 10 --rate 100/s
+  iptool dns bench --doh https://cloudflare-dns.com/dns-query --dot 1.1.1.1:853
+  iptool dns bench --servers 1.1.1.1 --dot 1.1.1.1 --doh https://cloudflare-dns.com/dns-query`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+
+		// Determine the output file using Viper
+		outputStream, err := utils.GetOutputStream(viper.GetString("dns.bench.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer outputStream.Close()
+
+		return dnsBenchAction(outputStream)
+	},
+}
+
+// dnsBenchJSON is a server in the JSON output of the dns bench command
+type dnsBenchJSON struct {
+	Server      string         `json:"server"`
+	Queries     int            `json:"queries"`
+	Failures    int            `json:"failures"`
+	FailureRate float64        `json:"failure_rate"`
+	MinMs       float64        `json:"min_ms"`
+	AvgMs       float64        `json:"avg_ms"`
+	P95Ms       float64        `json:"p95_ms"`
+	MaxMs       float64        `json:"max_ms"`
+	Errors      map[string]int `json:"errors,omitempty"`
+}
+
+// dnsBenchAction is the action function for the dns bench command
+func dnsBenchAction(out io.Writer) error {
+	if ip.LookupsDisabled() {
+		return ip.ErrLookupsDisabled
+	}
+
+	// Read the names to query
+	names := viper.GetStringSlice("dns.bench.names")
+	if namesFile := viper.GetString("dns.bench.names-file"); namesFile != "" {
+		list, err := readBenchNames(namesFile)
+		if err != nil {
+			return err
+		}
+		names = append(names, list...)
+	}
+	if len(names) == 0 {
+		names = dns.DefaultBenchNames
+	}
+
+	queries := viper.GetInt("dns.bench.queries")
+	if queries < 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid number of queries: %d (must be at least 1)", queries))
+	}
+	timeout := viper.GetDuration("dns.bench.timeout") * time.Millisecond
+
+	// Validate the rate and concurrency before sending any queries
+	if _, err := getRateLimiter("dns.bench"); err != nil {
+		return err
+	}
+	concurrency, err := getConcurrency("dns.bench")
+	if err != nil {
+		return err
+	}
+
+	// Validate the servers before sending any queries
+	servers, err := getBenchServers()
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop the benchmark when the user presses Ctrl-C, the results so far are reported
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+
+	// Benchmark the servers in parallel, the rate is limited per server
+	results := make([]*dns.BenchResult, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		limiter, _ := getRateLimiter("dns.bench")
+		wg.Add(1)
+		go func(i int, server dns.Server) {
+			defer wg.Done()
+			results[i], _ = dns.BenchServer(ctx, server, names, queries, timeout, limiter, concurrency)
+		}(i, server)
+	}
+	wg.Wait()
+
+	// Sort the servers by average response time, servers without answers last
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Stats, results[j].Stats
+		if (a.Count() == 0) != (b.Count() == 0) {
+			return b.Count() == 0
+		}
+		return a.Mean() < b.Mean()
+	})
+
+	if viper.GetBool("dns.bench.json") {
+		list := make([]dnsBenchJSON, len(results))
+		for i, r := range results {
+			list[i] = dnsBenchJSON{
+				Server:      r.Server,
+				Queries:     r.Queries,
+				Failures:    r.Failures,
+				FailureRate: r.FailureRate(),
+				MinMs:       durationMs(r.Stats.Min()),
+				AvgMs:       durationMs(r.Stats.Mean()),
+				P95Ms:       durationMs(r.Stats.Percentile(95)),
+				MaxMs:       durationMs(r.Stats.Max()),
+				Errors:      r.Errors,
+			}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	// Find the length of the longest server (for padding)
+	width := len("Server")
+	for _, r := range results {
+		width = max(width, len(r.Server))
+	}
+
+	fmtString := fmt.Sprintf("%%-%ds  %%7s  %%7s  %%10s  %%10s  %%10s  %%10s\n", width)
+	fmt.Fprintf(out, fmtString, "Server", "Queries", "Failed", "Min", "Avg", "P95", "Max")
+	for _, r := range results {
+		failed := fmt.Sprintf("%.1f%%", r.FailureRate())
+		if r.Stats.Count() == 0 {
+			fmt.Fprintf(out, fmtString, r.Server, fmt.Sprint(r.Queries), failed, "-", "-", "-", "-")
+			continue
+		}
+		fmt.Fprintf(out, fmtString, r.Server, fmt.Sprint(r.Queries), failed,
+			formatMilliseconds(r.Stats.Min()), formatMilliseconds(r.Stats.Mean()),
+			formatMilliseconds(r.Stats.Percentile(95)), formatMilliseconds(r.Stats.Max()))
+	}
+
+	// Print the reasons of the failures
+	for _, r := range results {
+		reasons := make([]string, 0, len(r.Errors))
+		for reason := range r.Errors {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(out, "%s: %d failed query(s): %s\n", r.Server, r.Errors[reason], reason)
+		}
+	}
+	return nil
+}
+
+// getBenchServers is a function that returns the DNS servers to benchmark:
+// the servers given with --servers, followed by the DNS over TLS servers
+// given with --dot and the DNS over HTTPS servers given with --doh. When only
+// --dot or --doh is given, the default plaintext servers are left out.
+func getBenchServers() ([]dns.Server, error) {
+	dot := viper.GetStringSlice("dns.bench.dot")
+	doh := viper.GetStringSlice("dns.bench.doh")
+
+	var names []string
+	if viper.IsSet("dns.bench.servers") || len(dot)+len(doh) == 0 {
+		names = resolveAliases(viper.GetStringSlice("dns.bench.servers"))
+	}
+	for _, server := range resolveAliases(dot) {
+		if !strings.HasPrefix(server, "tls://") {
+			server = "tls://" + server
+		}
+		names = append(names, server)
+	}
+	for _, server := range doh {
+		if !strings.HasPrefix(server, "https://") {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid DNS over HTTPS server: %s (must be an https:// URL)", server))
+		}
+		names = append(names, server)
+	}
+	if len(names) == 0 {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("no DNS servers given, use --servers, --dot or --doh"))
+	}
+
+	// Parse the servers before sending any queries
+	servers := make([]dns.Server, len(names))
+	for i, name := range names {
+		server, err := dns.ParseServer(name)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		servers[i] = server
+	}
+	return servers, nil
+}
+
+// durationMs is a function that returns a duration in milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// readBenchNames is a function that reads the names to query from a file,
+// one name per line. Empty lines and lines starting with # are ignored.
+func readBenchNames(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, strings.Fields(line)[0])
+	}
+	return names, scanner.Err()
+}
+
+// init registers the command and flags
+func init() {
+	dnsCmd.AddCommand(dnsBenchCmd)
+
+	// Define the flag for the DNS servers to benchmark
+	dnsBenchCmd.Flags().StringSliceP("servers", "s", []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}, "DNS servers to benchmark (address, address:port, tls://host[:port] or https:// URL)")
+	viper.BindPFlag("dns.bench.servers", dnsBenchCmd.Flags().Lookup("servers"))
+
+	// Define the flags for the encrypted DNS servers to benchmark
+	dnsBenchCmd.Flags().StringSlice("dot", nil, "DNS over TLS servers to benchmark (host or host:port, port 853 by default)")
+	viper.BindPFlag("dns.bench.dot", dnsBenchCmd.Flags().Lookup("dot"))
+	dnsBenchCmd.Flags().StringSlice("doh", nil, "DNS over HTTPS servers to benchmark (https:// URL)")
+	viper.BindPFlag("dns.bench.doh", dnsBenchCmd.Flags().Lookup("doh"))
+
+	// Define the flag for the number of queries per server
+	dnsBenchCmd.Flags().IntP("queries", "q", 100, "number of queries to send to every server")
+	viper.BindPFlag("dns.bench.queries", dnsBenchCmd.Flags().Lookup("queries"))
+
+	// Define the flags for the names to query
+	dnsBenchCmd.Flags().StringSliceP("names", "n", nil, "names to query (default a set of popular domains)")
+	viper.BindPFlag("dns.bench.names", dnsBenchCmd.Flags().Lookup("names"))
+	dnsBenchCmd.Flags().String("names-file", "", "read the names to query from file, one name per line")
+	viper.BindPFlag("dns.bench.names-file", dnsBenchCmd.Flags().Lookup("names-file"))
+
+	// Define the flags for the rate and number of concurrent queries per server
+	addRateFlag(dnsBenchCmd, "dns.bench")
+	addConcurrencyFlag(dnsBenchCmd, "dns.bench", 1)
+
+	// Define the flag for the time to wait for a response
+	dnsBenchCmd.Flags().IntP("timeout", "t", 2000, "time to wait for a response, in milliseconds")
+	viper.BindPFlag("dns.bench.timeout", dnsBenchCmd.Flags().Lookup("timeout"))
+
+	// Enable the --json flag to print the results in JSON format
+	dnsBenchCmd.Flags().Bool("json", false, "print the results in JSON format")
+	viper.BindPFlag("dns.bench.json", dnsBenchCmd.Flags().Lookup("json"))
+
+	// Enable the --output-file flag to write the output to a file
+	dnsBenchCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("dns.bench.output-file", dnsBenchCmd.Flags().Lookup("output-file"))
+}
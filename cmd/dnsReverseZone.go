@@ -0,0 +1,179 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/dns"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maxPTRHostBits limits the PTR record skeletons to 2^16 = 65536 addresses
+const maxPTRHostBits = 16
+
+// dnsReverseZoneCmd represents the dns reverse-zone command
+var dnsReverseZoneCmd = &cobra.Command{
+	Use:   "reverse-zone <prefix>",
+	Short: "Generate the reverse DNS zones for a prefix",
+	Long: `Generate the reverse DNS zones (in-addr.arpa or ip6.arpa) for a prefix.
+
+Reverse zones are delegated on octet (IPv4) or nibble (IPv6) boundaries, so
+a prefix that is not aligned on such a boundary is covered by several zones.
+IPv4 prefixes longer than /24 are covered by a classless zone using RFC 2317
+style naming, together with the CNAME records that must be added to the
+parent zone.
+
+Use --ptr to generate PTR record skeletons for every address in the prefix.
+The PTR targets are named after the address in the domain set by --domain
+(e.g. ip-10-12-0-1.example.com).
+
+Examples:
+  iptool dns reverse-zone 10.12.0.0/16
+  iptool dns reverse-zone 10.12.0.0/15
+  iptool dns reverse-zone 192.0.2.64/26 --ptr --domain example.com
+  iptool dns reverse-zone 2001:db8:1::/48`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Exactly one argument required
+		if len(args) != 1 {
+			cmd.Help()
+			return nil
+		}
+
+		// Get the output file name from the viper configuration
+		outputFile := viper.GetString("dns.reverse-zone.output-file")
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(outputFile, false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return dnsReverseZoneAction(out, resolveAlias(args[0]))
+	},
+}
+
+// dnsReverseZoneAction is the action function for the dns reverse-zone command
+func dnsReverseZoneAction(out io.Writer, s string) error {
+	// Parse the input string as a prefix
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid prefix: %s", s))
+	}
+	prefix = prefix.Masked()
+
+	// Calculate the reverse zones covering the prefix
+	zones, err := dns.ReverseZones(prefix)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Buffer the output, large prefixes generate a lot of records
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+
+	ptr := viper.GetBool("dns.reverse-zone.ptr")
+	domain := strings.Trim(viper.GetString("dns.reverse-zone.domain"), ".")
+
+	// Make sure that the number of PTR records is reasonable
+	if ptr && prefix.Addr().BitLen()-prefix.Bits() > maxPTRHostBits {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("too many addresses in the prefix to generate PTR records (at most %d)", 1<<maxPTRHostBits))
+	}
+
+	// Without PTR records, print one zone name per line
+	if !ptr {
+		for _, zone := range zones {
+			fmt.Fprintln(writer, zone.Name)
+			if zone.Classless {
+				fmt.Fprintf(writer, "; classless zone (RFC 2317), delegate from %s using CNAME records (see --ptr)\n", zone.Parent)
+			}
+		}
+		return nil
+	}
+
+	for i, zone := range zones {
+		if i > 0 {
+			fmt.Fprintln(writer)
+		}
+
+		// Classless zones need CNAME records in the parent zone
+		if zone.Classless {
+			fmt.Fprintf(writer, "; Records in the parent zone %s\n", zone.Parent)
+			fmt.Fprintf(writer, "%s. IN NS ns1.%s.\n", zone.Name, domain)
+			for addr := zone.Prefix.Addr(); zone.Prefix.Contains(addr); addr = addr.Next() {
+				fmt.Fprintln(writer, zone.ClasslessCNAME(addr))
+			}
+			fmt.Fprintln(writer)
+		}
+
+		// Print the PTR record skeletons of the zone
+		fmt.Fprintf(writer, "; Zone %s\n", zone.Name)
+		for addr := zone.Prefix.Addr(); addr.IsValid() && zone.Prefix.Contains(addr); addr = addr.Next() {
+			// Zones may be larger than the prefix, only print records for the prefix
+			if !prefix.Contains(addr) {
+				continue
+			}
+			fmt.Fprintf(writer, "%s. IN PTR %s.%s.\n", zone.PTRName(addr), ptrHostname(addr), domain)
+		}
+	}
+
+	return nil
+}
+
+// ptrHostname is a function that returns a host name derived from the
+// address, e.g. ip-10-12-0-1 for 10.12.0.1
+func ptrHostname(addr netip.Addr) string {
+	return "ip-" + strings.NewReplacer(".", "-", ":", "-").Replace(addr.StringExpanded())
+}
+
+// init registers the command and flags
+func init() {
+	dnsCmd.AddCommand(dnsReverseZoneCmd)
+
+	// Enable the --ptr flag to generate PTR record skeletons
+	dnsReverseZoneCmd.Flags().BoolP("ptr", "p", false, "generate PTR record skeletons for every address")
+	viper.BindPFlag("dns.reverse-zone.ptr", dnsReverseZoneCmd.Flags().Lookup("ptr"))
+
+	// Define the flag for the domain of the PTR targets
+	dnsReverseZoneCmd.Flags().StringP("domain", "d", "example.com", "domain of the PTR targets")
+	viper.BindPFlag("dns.reverse-zone.domain", dnsReverseZoneCmd.Flags().Lookup("domain"))
+
+	// Enable the --output-file flag to write the output to a file
+	dnsReverseZoneCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("dns.reverse-zone.output-file", dnsReverseZoneCmd.Flags().Lookup("output-file"))
+}
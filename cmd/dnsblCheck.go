@@ -0,0 +1,256 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/dns"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dnsblCheckCmd represents the dnsbl check command
+var dnsblCheckCmd = &cobra.Command{
+	Use:   "check <ip>",
+	Short: "Check if an IP address is listed in DNS blocklists",
+	Long: `Check if an IP address is listed in DNS blocklists.
+
+The blocklists are queried in parallel. For every blocklist the table shows
+if the address is listed, the return codes of the listing (the addresses in
+127.0.0.0/8 that the blocklist answers with, described for the blocklists
+that encode the reason of a listing in the code, e.g. SBL, XBL and PBL for
+zen.spamhaus.org) and the reasons that the blocklist publishes in TXT records.
+
+Use --lists to choose the blocklists (default zen.spamhaus.org,
+bl.spamcop.net, b.barracudacentral.org and psbl.surriel.com). Some
+blocklists refuse queries through public resolvers, e.g. Spamhaus answers
+them with 127.255.255.254, which is reported as an error. Use --server to
+query a resolver of your own instead of the system resolver.
+
+The command exits with exit code 5 if the address is listed in at least one
+blocklist, and with exit code 3 if none of the blocklists could be queried.
+
+Examples:
+  iptool dnsbl check 192.0.2.1
+  iptool dnsbl check 192.0.2.1 --lists zen.spamhaus.org,bl.spamcop.net
+  iptool dnsbl check 2001:db8::1 --server 10.0.0.53 --json`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return dnsblCheckAction(os.Stdout, resolveAlias(args[0]))
+	},
+}
+
+// dnsblCheckJSON is the JSON output of the dnsbl check command
+type dnsblCheckJSON struct {
+	Address  string         `json:"address"`
+	Listed   int            `json:"listed"`
+	Listings []dnsblListing `json:"listings"`
+}
+
+// dnsblListing is a blocklist in the JSON output of the dnsbl check command
+type dnsblListing struct {
+	dns.Listing
+	Descriptions []string `json:"descriptions,omitempty"`
+	DurationMs   float64  `json:"duration_ms"`
+}
+
+// dnsblCheckAction is the action function for the dnsbl check command
+func dnsblCheckAction(out io.Writer, s string) error {
+	if ip.LookupsDisabled() {
+		return ip.ErrLookupsDisabled
+	}
+
+	// A prefix is not an address, so only accept a plain address
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(s), "[]"))
+	if err != nil || addr.Zone() != "" {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid address: %s", s))
+	}
+	addr = addr.Unmap()
+
+	// Trim the blocklists and drop the empty ones (e.g. a trailing comma)
+	var blocklists []string
+	for _, list := range viper.GetStringSlice("dnsbl.check.lists") {
+		if list = strings.TrimSpace(list); list != "" {
+			blocklists = append(blocklists, list)
+		}
+	}
+	if len(blocklists) == 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no blocklists given, use --lists"))
+	}
+
+	timeout := viper.GetDuration("dnsbl.check.timeout") * time.Millisecond
+	if timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid timeout: %d (must be greater than 0)", viper.GetInt("dnsbl.check.timeout")))
+	}
+
+	// Query the server given with --server instead of the system resolver
+	resolver := net.DefaultResolver
+	if name := viper.GetString("dnsbl.check.server"); name != "" {
+		server, err := dns.ParseServer(name)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		resolver = server.Resolver()
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop waiting for the blocklists when the user presses Ctrl-C
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+
+	listings := dns.CheckBlocklists(ctx, resolver, addr, blocklists, timeout)
+	listed, failed := 0, 0
+	for _, l := range listings {
+		if l.Listed {
+			listed++
+		}
+		if l.Error != "" {
+			failed++
+		}
+	}
+
+	if viper.GetBool("dnsbl.check.json") {
+		result := dnsblCheckJSON{Address: addr.String(), Listed: listed, Listings: make([]dnsblListing, len(listings))}
+		for i, l := range listings {
+			result.Listings[i] = dnsblListing{Listing: l, Descriptions: dnsblDescriptions(l), DurationMs: durationMs(l.Duration)}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else if err := printDNSBLCheck(out, addr.String(), listings, listed); err != nil {
+		return err
+	}
+
+	// Report listings and failures with the exit code for automation
+	if listed > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%s is listed in %d of %d blocklists", addr, listed, len(listings)))
+	}
+	if failed == len(listings) {
+		return exitcode.New(exitcode.Unreachable, fmt.Errorf("none of the blocklists could be queried"))
+	}
+	return nil
+}
+
+// dnsblDescriptions is a function that returns the descriptions of the
+// return codes of a listing, for the codes that the blocklist describes
+func dnsblDescriptions(l dns.Listing) []string {
+	var descriptions []string
+	for _, code := range l.Codes {
+		if d := dns.CodeDescription(l.Blocklist, code); d != "" {
+			descriptions = append(descriptions, d)
+		}
+	}
+	return descriptions
+}
+
+// printDNSBLCheck is a function that prints the listings of an address as a
+// table of the blocklists followed by a summary
+func printDNSBLCheck(out io.Writer, addr string, listings []dns.Listing, listed int) error {
+	table := render.NewTable(out, getRenderOptions("dnsbl.check", out),
+		render.Column{Title: "Blocklist"},
+		render.Column{Title: "Status"},
+		render.Column{Title: "Codes"},
+		render.Column{Title: "Time", Align: render.AlignRight},
+		render.Column{Title: "Reason", Truncate: true},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	rows := make([][]string, len(listings))
+	for i, l := range listings {
+		status, reason := "not listed", ""
+		switch {
+		case l.Error != "":
+			status, reason = "error", l.Error
+		case l.Listed:
+			status, reason = "listed", strings.Join(l.Reasons, "; ")
+		}
+
+		// Describe the codes that the blocklist describes, e.g. 127.0.0.4 (XBL)
+		codes := make([]string, len(l.Codes))
+		for j, code := range l.Codes {
+			codes[j] = code
+			if d := dns.CodeDescription(l.Blocklist, code); d != "" {
+				codes[j] += " (" + d + ")"
+			}
+		}
+		rows[i] = []string{l.Blocklist, status, strings.Join(codes, ", "), fmt.Sprintf("%.0f ms", durationMs(l.Duration)), reason}
+		table.Fit(rows[i]...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+
+	// Print the summary in the table format only, to keep csv and tsv parsable
+	if render.Format(strings.ToLower(viper.GetString("format"))) == render.FormatTable {
+		fmt.Fprintf(out, "\n%s is listed in %d of %d blocklists\n", addr, listed, len(listings))
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	dnsblCmd.AddCommand(dnsblCheckCmd)
+
+	// Define the flags for the blocklists and the resolver to query
+	dnsblCheckCmd.Flags().StringSliceP("lists", "l", dns.DefaultBlocklists, "DNS blocklist zones to check")
+	viper.BindPFlag("dnsbl.check.lists", dnsblCheckCmd.Flags().Lookup("lists"))
+	dnsblCheckCmd.RegisterFlagCompletionFunc("lists", completeValues(dns.DefaultBlocklists...))
+	dnsblCheckCmd.Flags().StringP("server", "s", "", "DNS server to query (address, address:port, tls://host[:port] or https:// URL), default the system resolver")
+	viper.BindPFlag("dnsbl.check.server", dnsblCheckCmd.Flags().Lookup("server"))
+	dnsblCheckCmd.Flags().IntP("timeout", "t", 5000, "time to wait for every blocklist, in milliseconds")
+	viper.BindPFlag("dnsbl.check.timeout", dnsblCheckCmd.Flags().Lookup("timeout"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(dnsblCheckCmd, "dnsbl.check")
+
+	// Define the flag for printing the result in JSON format
+	dnsblCheckCmd.Flags().Bool("json", false, "print the result in JSON format")
+	viper.BindPFlag("dnsbl.check.json", dnsblCheckCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/doctor"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the network environment of this host",
+	Long: `Diagnose the network environment of this host.
+
+The doctor runs a set of checks in parallel and prints a summarized report,
+a one-shot answer to "is my network broken":
+
+  Local name resolution  localhost and the hostname resolve
+  Default gateway        the default route exists and the gateway answers
+  Internet reachability  anycast targets answer over IPv4 and IPv6
+  MTU                    the MTU of the interface of the default route
+  NAT detection          the local and the public address are compared
+  DNS health             the system resolver resolves names, and quickly
+
+Every check passes, warns about a degraded network, fails or is skipped when
+it does not apply. The gateway and the internet targets are probed with TCP
+handshakes, a refused connection proves that a host is reachable too. The
+public address is looked up with the OpenDNS resolver (myip.opendns.com).
+
+Use --targets to replace the internet targets (address:port) and --names to
+replace the names resolved by the DNS check. The command exits with exit code
+5 if any check fails.
+
+Examples:
+  iptool doctor
+  iptool doctor --timeout 5000
+  iptool doctor --targets 192.0.2.10:443,[2001:db8::10]:443 --names intranet.example.com
+  iptool doctor --json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+		return doctorAction(os.Stdout)
+	},
+}
+
+// doctorJSON is a check in the JSON output of the doctor command
+type doctorJSON struct {
+	doctor.Result
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// doctorAction runs the checks and prints the report
+func doctorAction(out io.Writer) error {
+	timeout := viper.GetDuration("doctor.timeout") * time.Millisecond
+	if timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid timeout: %d (must be greater than 0)", viper.GetInt("doctor.timeout")))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	checks := doctor.Checks(doctor.Config{
+		Targets: resolveAliases(viper.GetStringSlice("doctor.targets")),
+		Names:   viper.GetStringSlice("doctor.names"),
+	})
+	results := doctor.Run(context.Background(), checks, timeout)
+
+	if viper.GetBool("doctor.json") {
+		list := make([]doctorJSON, len(results))
+		for i, r := range results {
+			list[i] = doctorJSON{Result: r, DurationMs: durationMs(r.Duration)}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(list); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(out, results)
+	}
+
+	if failed := doctor.Count(results, doctor.StatusFail); failed > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d check(s) failed", failed))
+	}
+	return nil
+}
+
+// printDoctorReport is a function that prints the results of the checks,
+// with the statuses in green, yellow and red when writing to a terminal
+func printDoctorReport(out io.Writer, results []doctor.Result) {
+	color := out == os.Stdout && utils.IsTerminal(os.Stdout)
+	colors := map[doctor.Status]string{
+		doctor.StatusPass: ansiGreen,
+		doctor.StatusWarn: ansiYellow,
+		doctor.StatusFail: ansiRed,
+	}
+
+	// Find the length of the longest check name (for padding)
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+
+	fmt.Fprintf(out, "Running %s doctor (%s/%s)\n", rootCmd.Name(), runtime.GOOS, runtime.GOARCH)
+	for _, r := range results {
+		status := "[" + r.Status.String() + "]"
+		if code, ok := colors[r.Status]; ok && color {
+			status = code + status + ansiReset
+		}
+		fmt.Fprintf(out, " %s %-*s  %s\n", status, width, r.Name, r.Detail)
+	}
+
+	fmt.Fprintf(out, "%d passed, %d warning(s), %d failed, %d skipped\n",
+		doctor.Count(results, doctor.StatusPass), doctor.Count(results, doctor.StatusWarn),
+		doctor.Count(results, doctor.StatusFail), doctor.Count(results, doctor.StatusSkip))
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Define the flags for the targets of the checks
+	doctorCmd.Flags().StringSlice("targets", doctor.DefaultTargets, "internet targets to connect to (address:port)")
+	viper.BindPFlag("doctor.targets", doctorCmd.Flags().Lookup("targets"))
+	doctorCmd.Flags().StringSlice("names", doctor.DefaultNames, "names to resolve in the DNS check")
+	viper.BindPFlag("doctor.names", doctorCmd.Flags().Lookup("names"))
+
+	// Define the flag for the time every check may take
+	doctorCmd.Flags().IntP("timeout", "t", 3000, "time every check may take, in milliseconds")
+	viper.BindPFlag("doctor.timeout", doctorCmd.Flags().Lookup("timeout"))
+
+	// Enable the --json flag to print the results in JSON format
+	doctorCmd.Flags().Bool("json", false, "print the results in JSON format")
+	viper.BindPFlag("doctor.json", doctorCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,527 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/enrich"
+	"github.com/bitcanon/iptool/envelope"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/progress"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// enrichCmd represents the enrich command
+var enrichCmd = &cobra.Command{
+	Use:   "enrich [address...]",
+	Short: "Enrich a list of IP addresses with DNS, ASN, geo and reputation data",
+	Long: `Enrich a list of IP addresses with DNS, ASN, geo and reputation data.
+
+The addresses are read from the command line, from the file given with
+--input or from standard input (one address per line, empty lines and
+lines starting with # are ignored), or from the JSON output of another
+command with --from-json (e.g. iptool sweep --format json), and are
+streamed through a concurrent enrichment pipeline. One row is written per input address, in the same
+order as the input. Use -4 or -6 to only enrich the addresses of one
+address family.
+
+The following sources can be selected with --with:
+  rdns  host names (PTR records) of the address
+  asn   origin AS number, AS name and announced prefix (Team Cymru)
+  geo   country and regional internet registry (Team Cymru)
+  rep   listings in DNS blocklists (enrich.dnsbl in the configuration file)
+
+The results of the lookups are cached, see iptool cache.
+
+Use --column to enrich a log file instead of a list of addresses: the input
+is a CSV file with a header line, or JSON records (one object per line or an
+array of objects), and --column is the name of the column or field with the
+address (or the number of the column, from 1, for CSV files). Every record is
+written in the format of the input with the enrichment columns appended,
+named after the column (e.g. src_ip_asn), so that a flow log can be enriched
+once for the source and once for the destination address. The records of
+the other address family are written without enrichment with -4 or -6.
+
+The classification source classifies the address (e.g. Private or Global
+unicast) without network lookups, and ptr is an alias of rdns.
+
+Use --workers to set the number of concurrent lookups and --rate to limit
+the number of addresses looked up (e.g. 50/s), so that large lists do not
+trip the rate limits of the DNS servers. When Ctrl-C is pressed, no more
+addresses are read and the lookups in flight are completed and written
+(press Ctrl-C again to quit immediately).
+
+Examples:
+  iptool enrich 1.1.1.1 8.8.8.8
+  iptool enrich --input ips.txt --with rdns,asn,geo,rep --workers 50
+  iptool enrich --input ips.txt --with rdns --rate 20/s
+  iptool enrich --input ips.txt --format json -o result.json
+  iptool enrich --input-file flows.csv --column src_ip --with ptr,asn,geo,classification
+  iptool enrich --input-file events.json --column client.ip --with asn
+  cat ips.txt | iptool enrich --with asn
+  iptool sweep --ipv6-nd fe80::/64%eth0 --format json | iptool enrich --from-json -`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return enrichAction(os.Stdout, args)
+	},
+}
+
+// enrichAction is the action function for the enrich command
+func enrichAction(out io.Writer, args []string) error {
+	// Parse the list of sources
+	sources, err := enrich.ParseSources(viper.GetStringSlice("enrich.with"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Parse the rate of the lookups
+	limiter, err := getRateLimiter("enrich")
+	if err != nil {
+		return err
+	}
+
+	// Check the output format
+	format := viper.GetString("enrich.format")
+	if format != "csv" && format != "json" {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be csv or json)", format))
+	}
+
+	// Open the input, the arguments take precedence over the input file and standard input
+	column := viper.GetString("enrich.column")
+	var input io.Reader = os.Stdin
+	if column != "" && (len(args) > 0 || viper.GetString("enrich.from-json") != "") {
+		return exitcode.New(exitcode.Usage, errors.New("invalid input: --column enriches a log file (--input or standard input), not addresses or --from-json"))
+	}
+	if len(args) > 0 {
+		input = strings.NewReader(strings.Join(resolveAliases(args), "\n"))
+	} else if fromJSON := viper.GetString("enrich.from-json"); fromJSON != "" {
+		targets, err := readJSONTargets(fromJSON, os.Stdin)
+		if err != nil {
+			return err
+		}
+		input = strings.NewReader(strings.Join(targets, "\n"))
+	} else if inputFile := viper.GetString("enrich.input"); inputFile != "" && inputFile != "-" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("enrich.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop the pipeline when the user presses Ctrl-C, the lookups in flight are completed
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+
+	// Enrich the records of a log file in the column
+	if column != "" {
+		return enrichLog(ctx, outputStream, input, column, sources, limiter)
+	}
+
+	// Feed the addresses to the pipeline
+	family := getFamily("enrich")
+	addresses := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(addresses)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			// Skip the addresses of the other family, invalid addresses are reported in the output
+			if addr, err := netip.ParseAddr(line); err == nil && !family.Match(addr) {
+				continue
+			}
+			select {
+			case addresses <- line:
+			case <-ctx.Done():
+				scanErr <- ctx.Err()
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	results := enrich.Pipeline(ctx, addresses, sources, viper.GetInt("enrich.workers"), limiter)
+
+	// Write the results as they arrive
+	bar := newProgressBar("enrich", 0, outputStream)
+	defer bar.Finish()
+	if err := writeEnrichResults(outputStream, format, sources, results, bar); err != nil {
+		return err
+	}
+
+	return <-scanErr
+}
+
+// writeEnrichResults is a function that writes the results of an enrichment
+// pipeline as they arrive, either as CSV or as one JSON object per line. The
+// results written are counted on the progress bar (may be nil).
+func writeEnrichResults(out io.Writer, format string, sources []enrich.Source, results <-chan enrich.Result, bar *progress.Bar) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		for r := range results {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+			bar.Add(1)
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Write(enrich.Columns(sources))
+	writer.Flush()
+	for r := range results {
+		writer.Write(r.Row(sources))
+		bar.Add(1)
+
+		// Flush every row so that the output can be followed in real time
+		writer.Flush()
+	}
+	return writer.Error()
+}
+
+// logRecord is a record of a log file to enrich: a CSV row or a JSON
+// object. Records of the other address family are skipped (not enriched).
+type logRecord struct {
+	row  []string
+	json json.RawMessage
+	skip bool
+}
+
+// peekByte is a function that returns the first byte of the input that is
+// not white space, without consuming it
+func peekByte(r *bufio.Reader) byte {
+	for i := 1; ; i++ {
+		b, err := r.Peek(i)
+		if err != nil || len(b) < i {
+			return 0
+		}
+		if c := b[i-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c
+		}
+	}
+}
+
+// jsonField is a function that returns the value of a field of a JSON
+// object as a string. Nested fields are separated by dots (e.g. client.ip).
+func jsonField(object json.RawMessage, name string) string {
+	var fields map[string]any
+	if err := json.Unmarshal(object, &fields); err != nil {
+		return ""
+	}
+	if v, ok := fields[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	var value any = fields
+	for _, key := range strings.Split(name, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// csvColumnIndex is a function that returns the index of the column in the
+// header of a CSV file, by name (case insensitive) or by number (from 1)
+func csvColumnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil && n >= 1 && n <= len(header) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("invalid column: %s (not in the header: %s)", column, strings.Join(header, ", "))
+}
+
+// enrichLog is a function that enriches the address in the column of every
+// record of a CSV or JSON log file, and writes the records in the format of
+// the input with the enrichment columns appended
+func enrichLog(ctx context.Context, out io.Writer, input io.Reader, column string, sources []enrich.Source, limiter *ratelimit.Limiter) error {
+	reader := bufio.NewReader(input)
+	isJSON := envelope.IsJSON(reader)
+
+	// The enrichment columns are named after the column, e.g. src_ip_asn
+	enrichColumns := func(name string) []string {
+		prefix := strings.NewReplacer(".", "_", " ", "_").Replace(strings.TrimSpace(name)) + "_"
+		var columns []string
+		for _, c := range enrich.SourceColumns(sources) {
+			columns = append(columns, prefix+c)
+		}
+		return columns
+	}
+	columns := enrichColumns(column)
+
+	// Read the header of a CSV file and find the column
+	var csvReader *csv.Reader
+	var csvWriter *csv.Writer
+	index := 0
+	if !isJSON {
+		csvReader = csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		header, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if index, err = csvColumnIndex(header, column); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		columns = enrichColumns(header[index])
+		csvWriter = csv.NewWriter(out)
+		csvWriter.Write(append(header, columns...))
+		csvWriter.Flush()
+	}
+
+	// Feed the records to the output and their addresses to the pipeline,
+	// the records are buffered while their addresses are looked up
+	workers := viper.GetInt("enrich.workers")
+	family := getFamily("enrich")
+	records := make(chan logRecord, max(workers, 1)*16)
+	addresses := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(records)
+		defer close(addresses)
+
+		// Read the next record and the address in the column
+		var decoder *json.Decoder
+		if isJSON {
+			decoder = json.NewDecoder(reader)
+			if peekByte(reader) == '[' {
+				if _, err := decoder.Token(); err != nil {
+					readErr <- err
+					return
+				}
+			}
+		}
+		next := func() (logRecord, string, error) {
+			if isJSON {
+				var object json.RawMessage
+				if !decoder.More() {
+					return logRecord{}, "", io.EOF
+				}
+				if err := decoder.Decode(&object); err != nil {
+					return logRecord{}, "", err
+				}
+				return logRecord{json: object}, jsonField(object, column), nil
+			}
+			row, err := csvReader.Read()
+			if err != nil {
+				return logRecord{}, "", err
+			}
+			value := ""
+			if index < len(row) {
+				value = strings.TrimSpace(row[index])
+			}
+			return logRecord{row: row}, value, nil
+		}
+
+		for {
+			record, value, err := next()
+			if err == io.EOF {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			// Skip the addresses of the other family, invalid addresses are reported in the output
+			if addr, err := netip.ParseAddr(value); err == nil && !family.Match(addr) {
+				record.skip = true
+			}
+			select {
+			case records <- record:
+			case <-ctx.Done():
+				readErr <- ctx.Err()
+				return
+			}
+			if record.skip {
+				continue
+			}
+			select {
+			case addresses <- value:
+			case <-ctx.Done():
+				readErr <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	results := enrich.Pipeline(ctx, addresses, sources, workers, limiter)
+
+	// Write the records in order, with the result of their address
+	bar := newProgressBar("enrich", 0, out)
+	defer bar.Finish()
+	encoder := json.NewEncoder(out)
+	for record := range records {
+		bar.Add(1)
+		var values []string
+		if !record.skip {
+			r, ok := <-results
+			if !ok {
+				// Ctrl-C was pressed, the record was not looked up
+				break
+			}
+			values = r.Values(sources)
+		} else {
+			values = make([]string, len(columns))
+		}
+
+		if !isJSON {
+			csvWriter.Write(append(record.row, values...))
+
+			// Flush every row so that the output can be followed in real time
+			csvWriter.Flush()
+			continue
+		}
+
+		// Append the enrichment fields to the JSON object, keeping its fields as is
+		object := bytes.TrimSpace(record.json)
+		if len(object) < 2 || object[0] != '{' {
+			return fmt.Errorf("invalid record: %s (must be a JSON object)", object)
+		}
+		var b bytes.Buffer
+		b.Write(object[:len(object)-1])
+		empty := len(bytes.TrimSpace(object[1:len(object)-1])) == 0
+		for i, c := range columns {
+			if values[i] == "" {
+				continue
+			}
+			if !empty {
+				b.WriteByte(',')
+			}
+			empty = false
+			name, _ := json.Marshal(c)
+			value, _ := json.Marshal(values[i])
+			b.Write(name)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteByte('}')
+		if err := encoder.Encode(json.RawMessage(b.Bytes())); err != nil {
+			return err
+		}
+	}
+	if csvWriter != nil {
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+
+	// Wait for the reader, it stops when Ctrl-C was pressed
+	if err := <-readErr; err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(enrichCmd)
+	addFamilyFlags(enrichCmd, "enrich")
+
+	// Define the flag for the input file
+	enrichCmd.Flags().StringP("input", "i", "", "file with one address per line, or a log file with --column (default standard input)")
+	viper.BindPFlag("enrich.input", enrichCmd.Flags().Lookup("input"))
+
+	// Accept --input-file as well, like the other commands reading files
+	enrichCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "input-file" {
+			name = "input"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	// Define the flag for the column with the address in a log file
+	enrichCmd.Flags().StringP("column", "c", "", "enrich a CSV or JSON log file: the column or field with the address (e.g. src_ip)")
+	viper.BindPFlag("enrich.column", enrichCmd.Flags().Lookup("column"))
+
+	// Define the flag for reading the addresses from JSON records
+	enrichCmd.Flags().String("from-json", "", "read the addresses from the JSON output of another command (- for standard input)")
+	viper.BindPFlag("enrich.from-json", enrichCmd.Flags().Lookup("from-json"))
+
+	// Define the flag for the enrichment sources
+	enrichCmd.Flags().StringSliceP("with", "w", []string{"rdns", "asn"}, "enrichment sources ("+strings.Join(enrich.SourceNames(), ", ")+")")
+	viper.BindPFlag("enrich.with", enrichCmd.Flags().Lookup("with"))
+	enrichCmd.RegisterFlagCompletionFunc("with", completeList(enrich.SourceNames))
+
+	// Define the flag for the number of concurrent workers
+	enrichCmd.Flags().IntP("workers", "n", 10, "number of concurrent lookups")
+	viper.BindPFlag("enrich.workers", enrichCmd.Flags().Lookup("workers"))
+
+	// Define the flag for the rate of the lookups
+	addRateFlag(enrichCmd, "enrich")
+
+	// Define the flag for the output format
+	enrichCmd.Flags().StringP("format", "f", "csv", "output format (csv or json)")
+	viper.BindPFlag("enrich.format", enrichCmd.Flags().Lookup("format"))
+	enrichCmd.RegisterFlagCompletionFunc("format", completeValues("csv", "json"))
+
+	// Enable the --output-file flag to write the output to a file
+	enrichCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("enrich.output-file", enrichCmd.Flags().Lookup("output-file"))
+}
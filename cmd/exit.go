@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/spf13/viper"
+)
+
+// errorJSON is the error written to standard error with --error-format json
+type errorJSON struct {
+	Error string `json:"error"`
+	Kind  string `json:"kind"`
+	Code  int    `json:"code"`
+}
+
+// writeError is a function that writes an error in the format selected with
+// --error-format: the message (text) or a JSON object with the message, the
+// kind of failure and the exit code (json)
+func writeError(w io.Writer, err error, code int) {
+	// The variable is read directly as well, the configuration is not
+	// loaded yet if the flags could not be parsed
+	format := viper.GetString("error-format")
+	if !rootCmd.PersistentFlags().Changed("error-format") {
+		if env, ok := os.LookupEnv("IPTOOL_ERROR_FORMAT"); ok {
+			format = env
+		}
+	}
+
+	if strings.EqualFold(format, "json") {
+		json.NewEncoder(w).Encode(errorJSON{Error: err.Error(), Kind: exitcode.Kind(code), Code: code})
+		return
+	}
+	fmt.Fprintln(w, "Error:", err)
+}
+
+// exitWithError is a function that writes the error to standard error and
+// exits with the exit code of the error (see the exitcode package)
+func exitWithError(err error) {
+	code := exitcode.Classify(err)
+	writeError(os.Stderr, err, code)
+	os.Exit(code)
+}
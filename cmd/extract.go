@@ -0,0 +1,327 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/enrich"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/extract"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// followInterval is how often a followed file is checked for new data
+const followInterval = 250 * time.Millisecond
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract [file]",
+	Short: "Extract the unique IP addresses from a log file or text",
+	Long: `Extract the unique IP addresses from a log file or text.
+
+Every IPv4 and IPv6 address found in the input (a file given as argument or
+with --input-file, or standard input when no file or - is given) is printed
+once, in the order it is first seen. Use --unique=false to print every
+occurrence, --sort to print the addresses in ascending order once the whole
+input has been read, and -4 (--v4) or -6 (--v6) to only print the addresses
+of one address family.
+
+Addresses that are part of a longer sequence of digits and dots, such as
+version numbers, are ignored. With --cidrs, an address followed by a prefix
+length (e.g. 10.0.0.0/8 or 2001:db8::/32) is printed as a prefix, and with
+--with-ports, an address followed by a port (e.g. 10.0.0.1:443 or
+[2001:db8::1]:443) is printed with its port. The output can be fed to the
+other commands reading lists of addresses or prefixes.
+
+With --follow, the file is followed like tail -f: the addresses of the lines
+written to the file from now on are printed in real time, and rotated or
+truncated files are reopened. Standard input is read until it is closed.
+
+Addresses are deduplicated using a window of the most recently seen
+addresses (--window), which bounds the memory used when following a log file
+for a long time. An address that has dropped out of the window is printed
+again when it is seen again.
+
+Use --with to enrich the addresses with DNS, ASN, geo and reputation data, see
+iptool enrich --help for the available sources.
+
+Examples:
+  iptool extract /var/log/auth.log
+  iptool extract /var/log/auth.log -6
+  iptool extract --input-file config.txt --cidrs --sort
+  iptool extract --input-file capture.txt --with-ports --unique=false
+  iptool extract /var/log/nginx/access.log --follow
+  iptool extract /var/log/auth.log --follow --with rdns,asn,geo
+  journalctl -f -u sshd | iptool extract --follow`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input := viper.GetString("extract.input-file")
+		if len(args) > 0 {
+			if input != "" {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("the input file cannot be given both as argument and with --input-file"))
+			}
+			input = args[0]
+		}
+		if input == "" {
+			input = "-"
+		}
+		return extractAction(os.Stdout, input)
+	},
+}
+
+// extractAction is the action function for the extract command
+func extractAction(out io.Writer, input string) error {
+	// Parse the list of enrichment sources, if any
+	var sources []enrich.Source
+	if with := viper.GetStringSlice("extract.with"); len(with) > 0 {
+		var err error
+		if sources, err = enrich.ParseSources(with); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+	}
+
+	// Check the output format
+	format := viper.GetString("extract.format")
+	if format != "csv" && format != "json" {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be csv or json)", format))
+	}
+
+	// Parse the rate of the lookups
+	limiter, err := getRateLimiter("extract")
+	if err != nil {
+		return err
+	}
+
+	// Check that the flags can be combined
+	unique := viper.GetBool("extract.unique")
+	sorted := viper.GetBool("extract.sort")
+	cidrs := viper.GetBool("extract.cidrs")
+	withPorts := viper.GetBool("extract.with-ports")
+	follow := viper.GetBool("extract.follow")
+	if sorted && follow {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--sort cannot be combined with --follow"))
+	}
+	if len(sources) > 0 && (cidrs || withPorts) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--with cannot be combined with --cidrs or --with-ports"))
+	}
+
+	// Check the size of the deduplication window
+	windowSize := viper.GetInt("extract.window")
+	if windowSize < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --window value: %d (must not be negative)", windowSize))
+	}
+
+	// Make sure that the input file exists before anything is written
+	var file *os.File
+	if input != "-" {
+		var err error
+		if file, err = os.Open(input); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("extract.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Stop reading when the user presses Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Send every address that is not in the window to the output
+	addresses := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(addresses)
+		window := extract.NewWindow[extract.Token](windowSize)
+		family := getFamily("extract")
+		var collected []extract.Token
+		send := func(token extract.Token) bool {
+			select {
+			case addresses <- token.String():
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		handleLine := func(line string) bool {
+			for _, token := range extract.FindTokens(line) {
+				// Only keep the prefix length and the port if requested
+				if !cidrs {
+					token.Bits = -1
+				}
+				if !withPorts || token.Bits >= 0 {
+					token.Port = 0
+				}
+				if !family.Match(token.Addr) || (unique && window.Seen(token)) {
+					continue
+				}
+
+				// Hold the addresses back until the end of the input when sorting
+				if sorted {
+					collected = append(collected, token)
+				} else if !send(token) {
+					return false
+				}
+			}
+			return true
+		}
+
+		// Follow the file from its current end
+		if follow && file != nil {
+			readErr <- extract.Follow(ctx, input, followInterval, func(line string) {
+				handleLine(line)
+			})
+			return
+		}
+
+		// Read the whole input, standard input is read until it is closed
+		var reader io.Reader = os.Stdin
+		if file != nil {
+			reader = file
+		}
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if !handleLine(scanner.Text()) {
+				readErr <- nil
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			readErr <- err
+			return
+		}
+
+		// Send the sorted addresses once the whole input has been read
+		slices.SortStableFunc(collected, extract.Token.Compare)
+		for _, token := range collected {
+			if !send(token) {
+				break
+			}
+		}
+		readErr <- nil
+	}()
+
+	// Print the addresses as they are found, enriched if requested
+	if len(sources) > 0 {
+		results := enrich.Pipeline(ctx, addresses, sources, viper.GetInt("extract.workers"), limiter)
+		if err := writeEnrichResults(outputStream, format, sources, results, nil); err != nil {
+			return err
+		}
+	} else {
+		for addr := range addresses {
+			if _, err := fmt.Fprintln(outputStream, addr); err != nil {
+				return err
+			}
+		}
+	}
+
+	return <-readErr
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(extractCmd)
+	addFamilyFlags(extractCmd, "extract")
+
+	// Define the flag for the input file
+	extractCmd.Flags().String("input-file", "", "file to extract the addresses from (default standard input)")
+	viper.BindPFlag("extract.input-file", extractCmd.Flags().Lookup("input-file"))
+
+	// Accept --v4 and --v6 as well as --ipv4 and --ipv6
+	extractCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		switch name {
+		case "v4":
+			name = "ipv4"
+		case "v6":
+			name = "ipv6"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	// Define the flags for deduplicating and sorting the addresses
+	extractCmd.Flags().Bool("unique", true, "print every address once (use --unique=false to print every occurrence)")
+	viper.BindPFlag("extract.unique", extractCmd.Flags().Lookup("unique"))
+
+	extractCmd.Flags().Bool("sort", false, "print the addresses in ascending order once the whole input has been read")
+	viper.BindPFlag("extract.sort", extractCmd.Flags().Lookup("sort"))
+
+	// Define the flags for keeping the prefix lengths and the ports
+	extractCmd.Flags().Bool("cidrs", false, "print addresses followed by a prefix length as prefixes (e.g. 10.0.0.0/8)")
+	viper.BindPFlag("extract.cidrs", extractCmd.Flags().Lookup("cidrs"))
+
+	extractCmd.Flags().Bool("with-ports", false, "print addresses followed by a port with the port (e.g. 10.0.0.1:443)")
+	viper.BindPFlag("extract.with-ports", extractCmd.Flags().Lookup("with-ports"))
+
+	// Define the flag for following the file as it grows
+	extractCmd.Flags().BoolP("follow", "f", false, "follow the file as it grows and print new addresses in real time")
+	viper.BindPFlag("extract.follow", extractCmd.Flags().Lookup("follow"))
+
+	// Define the flag for the size of the deduplication window
+	extractCmd.Flags().Int("window", 100000, "number of recently seen addresses to remember for deduplication (0 for unlimited)")
+	viper.BindPFlag("extract.window", extractCmd.Flags().Lookup("window"))
+
+	// Define the flag for the enrichment sources
+	extractCmd.Flags().StringSliceP("with", "w", nil, "enrich the addresses using these sources ("+strings.Join(enrich.SourceNames(), ", ")+")")
+	viper.BindPFlag("extract.with", extractCmd.Flags().Lookup("with"))
+	extractCmd.RegisterFlagCompletionFunc("with", completeList(enrich.SourceNames))
+
+	// Define the flag for the number of concurrent workers
+	extractCmd.Flags().IntP("workers", "n", 10, "number of concurrent lookups (with --with)")
+	viper.BindPFlag("extract.workers", extractCmd.Flags().Lookup("workers"))
+
+	// Define the flag for the rate of the lookups
+	addRateFlag(extractCmd, "extract")
+
+	// Define the flag for the output format
+	extractCmd.Flags().String("format", "csv", "output format of enriched addresses (csv or json)")
+	viper.BindPFlag("extract.format", extractCmd.Flags().Lookup("format"))
+	extractCmd.RegisterFlagCompletionFunc("format", completeValues("csv", "json"))
+
+	// Enable the --output-file flag to write the output to a file
+	extractCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("extract.output-file", extractCmd.Flags().Lookup("output-file"))
+}
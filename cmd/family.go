@@ -0,0 +1,45 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addFamilyFlags is a function that adds the --ipv4 (-4) and --ipv6 (-6)
+// address family filters to a command and binds them to the configuration
+// of the command, e.g. subnet.sort.ipv4
+func addFamilyFlags(cmd *cobra.Command, command string) {
+	cmd.Flags().BoolP("ipv4", "4", false, "only process IPv4 addresses and prefixes")
+	viper.BindPFlag(command+".ipv4", cmd.Flags().Lookup("ipv4"))
+
+	cmd.Flags().BoolP("ipv6", "6", false, "only process IPv6 addresses and prefixes")
+	viper.BindPFlag(command+".ipv6", cmd.Flags().Lookup("ipv6"))
+}
+
+// getFamily is a function that returns the address family filter selected
+// with the --ipv4 and --ipv6 flags of a command
+func getFamily(command string) ip.Family {
+	return ip.ParseFamily(viper.GetBool(command+".ipv4"), viper.GetBool(command+".ipv6"))
+}
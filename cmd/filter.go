@@ -0,0 +1,212 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/extract"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// filterCmd represents the filter command
+var filterCmd = &cobra.Command{
+	Use:   "filter --match <prefix,...> [file...]",
+	Short: "Print the lines with an IP address in the given subnets",
+	Long: `Print the lines with an IP address in the given subnets.
+
+The lines of the input (the files, or standard input when no file or - is
+given) are printed if an IPv4 or IPv6 address found in the line is in one of
+the prefixes given with --match (or in the file given with --match-file, one
+prefix per line), like grep for CIDRs. Use --invert to print the lines
+without an address in the prefixes instead, and --count to only print the
+number of lines.
+
+Use --columns to only look at the addresses in some columns (fields) of the
+lines, numbered from 1, e.g. the source address of a firewall log. The
+columns are separated by whitespace, or by the --delimiter.
+
+The command exits with exit code 5 if no line was printed, like grep.
+
+Examples:
+  iptool filter --match 10.0.0.0/8,192.168.0.0/16 access.log
+  tail -f /var/log/syslog | iptool filter -m 2001:db8::/32
+  iptool filter --match-file office-networks.txt --invert --columns 1 access.log
+  iptool filter -m 203.0.113.0/24 --count auth.log`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		return filterAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// filterSet is a function that returns the set of addresses of the --match
+// and --match-file flags
+func filterSet(stdin io.Reader) (*ip.Set, error) {
+	match := viper.GetStringSlice("filter.match")
+	matchFile := viper.GetString("filter.match-file")
+	if len(match) == 0 && matchFile == "" {
+		return nil, exitcode.New(exitcode.Usage, errors.New("no prefixes to match (use --match or --match-file)"))
+	}
+
+	prefixes, err := ip.ParsePrefixes(strings.NewReader(strings.Join(resolveAliases(match), "\n")))
+	if err != nil {
+		return nil, exitcode.New(exitcode.Usage, err)
+	}
+	set := ip.NewSet(prefixes)
+	if matchFile != "" {
+		fileSet, err := readSetFile(matchFile, stdin)
+		if err != nil {
+			return nil, err
+		}
+		set = set.Union(fileSet)
+	}
+	return set, nil
+}
+
+// filterAction is the action function for the filter command
+func filterAction(out io.Writer, stdin io.Reader, files []string) error {
+	set, err := filterSet(stdin)
+	if err != nil {
+		return err
+	}
+
+	// Validate the columns
+	columns := viper.GetIntSlice("filter.columns")
+	for _, c := range columns {
+		if c < 1 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid column: %d (columns are numbered from 1)", c))
+		}
+	}
+	delimiter := viper.GetString("filter.delimiter")
+	if delimiter == "tab" {
+		delimiter = "\t"
+	}
+	if viper.GetString("filter.match-file") == "-" && slices.Contains(files, "-") {
+		return exitcode.New(exitcode.Usage, errors.New("invalid input: standard input can not be read for both --match-file and the lines"))
+	}
+
+	// matches reports whether an address of the text is in the set
+	matches := func(text string) bool {
+		for _, addr := range extract.Find(text) {
+			if set.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Print the lines that match (or do not match with --invert) as they are read
+	invert, countOnly := viper.GetBool("filter.invert"), viper.GetBool("filter.count")
+	count := 0
+	for _, file := range files {
+		reader := stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			reader = f
+		}
+
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			matched := false
+			if len(columns) == 0 {
+				matched = matches(line)
+			} else {
+				for i, span := range fieldSpans(line, delimiter) {
+					if slices.Contains(columns, i+1) && matches(line[span[0]:span[1]]) {
+						matched = true
+						break
+					}
+				}
+			}
+			if matched == invert {
+				continue
+			}
+			count++
+			if !countOnly {
+				if _, err := fmt.Fprintln(out, line); err != nil {
+					return err
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if countOnly {
+		fmt.Fprintln(out, count)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// No lines printed is reported by the exit code, like grep
+	if count == 0 {
+		return exitcode.New(exitcode.Partial, errors.New("no matching lines"))
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(filterCmd)
+
+	// Define the flags for the prefixes to match
+	filterCmd.Flags().StringSliceP("match", "m", nil, "prefixes and addresses to match (e.g. 10.0.0.0/8,192.168.0.0/16)")
+	viper.BindPFlag("filter.match", filterCmd.Flags().Lookup("match"))
+	filterCmd.Flags().String("match-file", "", "file with the prefixes to match, one per line (- for standard input)")
+	viper.BindPFlag("filter.match-file", filterCmd.Flags().Lookup("match-file"))
+	filterCmd.Flags().BoolP("invert", "v", false, "print the lines without an address in the prefixes")
+	viper.BindPFlag("filter.invert", filterCmd.Flags().Lookup("invert"))
+
+	// Define the flags for the columns to look at
+	filterCmd.Flags().IntSliceP("columns", "c", nil, "only match the addresses in these columns, numbered from 1 (default is the whole line)")
+	viper.BindPFlag("filter.columns", filterCmd.Flags().Lookup("columns"))
+	filterCmd.Flags().StringP("delimiter", "d", "", "column delimiter (default is whitespace)")
+	viper.BindPFlag("filter.delimiter", filterCmd.Flags().Lookup("delimiter"))
+	filterCmd.RegisterFlagCompletionFunc("delimiter", completeValues(",", ";", "tab", "|"))
+
+	// Define the flag for only printing the number of lines
+	filterCmd.Flags().Bool("count", false, "only print the number of matching lines")
+	viper.BindPFlag("filter.count", filterCmd.Flags().Lookup("count"))
+}
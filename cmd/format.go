@@ -0,0 +1,239 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// formatForms is the list of forms accepted by the --form flag
+var formatForms = []string{"compressed", "expanded", "mixed", "reverse", "url"}
+
+// formatCmd represents the format command
+var formatCmd = &cobra.Command{
+	Use:   "format <address...|->",
+	Short: "Normalize and validate IPv6 addresses",
+	Long: `Normalize and validate IPv6 addresses.
+
+Every address is printed in the following forms:
+  compressed   canonical notation (RFC 5952), e.g. 2001:db8::1
+  expanded     all 32 hex digits, e.g. 2001:0db8:0000:0000:0000:0000:0000:0001
+  mixed        the last 32 bits as an IPv4 address, e.g. 2001:db8::0.0.0.1
+  reverse      reverse nibble notation (ip6.arpa), as used in PTR records
+  url          enclosed in brackets for URLs, e.g. [2001:db8::1]
+
+Use --form to only print one of the forms, one address per line, e.g. to
+canonicalize the addresses in a config file or a database export. Use
+--check to only report the addresses that are invalid or not in canonical
+form, with a non-zero exit code if any are found (e.g. in CI pipelines).
+
+The addresses are given as arguments, or read from standard input (one per
+line) with -.
+
+Examples:
+  iptool format 2001:0db8:0000:0000:0000:0000:0000:0001
+  iptool format fe80::1%eth0 --json
+  iptool format ::ffff:192.0.2.1 --form reverse
+  cat addresses.txt | iptool format - --form compressed
+  cat addresses.txt | iptool format - --check`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return formatAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// formatResult is an address in all forms, as printed by the format command
+type formatResult struct {
+	Input string `json:"input"`
+	*ip.IPv6Forms
+	Canonical bool   `json:"canonical"`
+	Error     string `json:"error,omitempty"`
+}
+
+// formatAction is the action function for the format command
+func formatAction(out io.Writer, stdin io.Reader, args []string) error {
+	form := strings.ToLower(viper.GetString("format.form"))
+	if form != "" && !slices.Contains(formatForms, form) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid form: %s (must be one of %s)", form, strings.Join(formatForms, ", ")))
+	}
+
+	// Collect the addresses from the arguments and standard input
+	var inputs []string
+	for _, arg := range args {
+		if arg != "-" {
+			inputs = append(inputs, arg)
+			continue
+		}
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				inputs = append(inputs, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Parse and format the addresses
+	results := make([]formatResult, 0, len(inputs))
+	invalid, nonCanonical := 0, 0
+	for _, input := range inputs {
+		result := formatResult{Input: input}
+		addr, err := ip.ParseIPv6Address(resolveAlias(input))
+		if err != nil {
+			result.Error = err.Error()
+			invalid++
+		} else {
+			forms := ip.FormatIPv6(addr)
+			result.IPv6Forms = &forms
+			result.Canonical = input == forms.Compressed
+			if !result.Canonical {
+				nonCanonical++
+			}
+		}
+		results = append(results, result)
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("format.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	check := viper.GetBool("format.check")
+	switch {
+	case viper.GetBool("format.json"):
+		encoder := json.NewEncoder(outputStream)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	case check:
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Fprintf(outputStream, "%s: %s\n", r.Input, r.Error)
+			} else if !r.Canonical {
+				fmt.Fprintf(outputStream, "%s: not canonical, use %s\n", r.Input, r.Compressed)
+			}
+		}
+	case form != "":
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Fprintf(outputStream, "%s: %s\n", r.Input, r.Error)
+				continue
+			}
+			fmt.Fprintln(outputStream, formatForm(*r.IPv6Forms, form))
+		}
+	default:
+		for i, r := range results {
+			if i > 0 {
+				fmt.Fprintln(outputStream)
+			}
+			if r.Error != "" {
+				fmt.Fprintf(outputStream, "%s: %s\n", r.Input, r.Error)
+				continue
+			}
+			canonical := "yes"
+			if !r.Canonical {
+				canonical = "no"
+			}
+			fmt.Fprintf(outputStream, "Input      : %s\n", r.Input)
+			fmt.Fprintf(outputStream, "Canonical  : %s\n", canonical)
+			fmt.Fprintf(outputStream, "Compressed : %s\n", r.Compressed)
+			fmt.Fprintf(outputStream, "Expanded   : %s\n", r.Expanded)
+			fmt.Fprintf(outputStream, "Mixed      : %s\n", r.Mixed)
+			fmt.Fprintf(outputStream, "Reverse    : %s\n", r.Reverse)
+			fmt.Fprintf(outputStream, "URL        : %s\n", r.URL)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if invalid > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d invalid IPv6 address(es)", invalid))
+	}
+	if check && nonCanonical > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d address(es) not in canonical form", nonCanonical))
+	}
+	return nil
+}
+
+// formatForm is a function that returns one of the forms of an address
+func formatForm(forms ip.IPv6Forms, form string) string {
+	switch form {
+	case "expanded":
+		return forms.Expanded
+	case "mixed":
+		return forms.Mixed
+	case "reverse":
+		return forms.Reverse
+	case "url":
+		return forms.URL
+	default:
+		return forms.Compressed
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(formatCmd)
+
+	// Define the flag for printing a single form
+	formatCmd.Flags().StringP("form", "f", "", "only print one form (compressed, expanded, mixed, reverse or url)")
+	viper.BindPFlag("format.form", formatCmd.Flags().Lookup("form"))
+	formatCmd.RegisterFlagCompletionFunc("form", completeValues(formatForms...))
+
+	// Define the flag for only reporting invalid and non-canonical addresses
+	formatCmd.Flags().BoolP("check", "c", false, "only report invalid and non-canonical addresses, exit non-zero if any")
+	viper.BindPFlag("format.check", formatCmd.Flags().Lookup("check"))
+
+	// Define the flag for printing the results in JSON format
+	formatCmd.Flags().Bool("json", false, "print the results in JSON format")
+	viper.BindPFlag("format.json", formatCmd.Flags().Lookup("json"))
+
+	// Enable the --output-file flag to write the output to a file
+	formatCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("format.output-file", formatCmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bitcanon/iptool/packet"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+)
+
+// headerCmd represents the header command
+var headerCmd = &cobra.Command{
+	Use:   "header",
+	Short: "Build packet headers and calculate checksums",
+	Long: `Build packet headers and calculate checksums.
+
+The header command group constructs packet headers from their fields and
+calculates the internet checksums (RFC 1071) of the headers, including the
+TCP and UDP pseudo-headers. The byte layout of every header is printed,
+which helps when hand-crafting packets or validating captures.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(headerCmd)
+}
+
+// parseHexBytes is a function that parses bytes given in hexadecimal, with
+// optional spaces, colons or dashes between the bytes (e.g. "45 00 00 14",
+// "45:00:00:14" or "0x45000014")
+func parseHexBytes(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	s = strings.NewReplacer(" ", "", "\t", "", "\n", "", "\r", "", ":", "", "-", "").Replace(s)
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex bytes: %s", s)
+	}
+	return b, nil
+}
+
+// formatHexBytes is a function that formats bytes as hexadecimal pairs
+// separated by spaces
+func formatHexBytes(b []byte) string {
+	var pairs []string
+	for _, c := range b {
+		pairs = append(pairs, fmt.Sprintf("%02x", c))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// writeHeaderFields is a function that prints the byte layout of a header
+// as a table with the offset, the bytes, the name and the value of every
+// field. The offsets are printed relative to the start of the packet.
+func writeHeaderFields(out io.Writer, title string, b []byte, start int, fields []packet.Field) {
+	fmt.Fprintf(out, "%s (%d bytes):\n", title, len(b))
+	table := render.NewTable(out, render.Options{},
+		render.Column{Title: "Offset", Align: render.AlignRight},
+		render.Column{Title: "Bytes"},
+		render.Column{Title: "Field"},
+		render.Column{Title: "Value"},
+	)
+	rows := make([][]string, len(fields))
+	for i, f := range fields {
+		rows[i] = []string{fmt.Sprint(start + f.Offset), formatHexBytes(b[f.Offset : f.Offset+f.Length]), f.Name, f.Value}
+		table.Fit(rows[i]...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+	fmt.Fprintln(out)
+}
+
+// writeHexDump is a function that prints bytes as a hex dump with 16 bytes
+// per line, prefixed by the offset of the line
+func writeHexDump(out io.Writer, b []byte) {
+	for i := 0; i < len(b); i += 16 {
+		line := b[i:min(i+16, len(b))]
+		left, right := line[:min(8, len(line))], line[min(8, len(line)):]
+		fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%04x  %-23s  %s", i, formatHexBytes(left), formatHexBytes(right)), " "))
+	}
+}
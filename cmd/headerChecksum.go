@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/packet"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// headerChecksumCmd represents the header checksum command
+var headerChecksumCmd = &cobra.Command{
+	Use:   "checksum <hex bytes...>",
+	Short: "Calculate the internet checksum of a sequence of bytes",
+	Long: `Calculate the internet checksum of a sequence of bytes.
+
+The internet checksum (RFC 1071) used by IPv4, ICMP, TCP and UDP is
+calculated over the given bytes, which are given in hexadecimal with optional
+spaces, colons or dashes between the bytes. Use - to read the bytes from
+standard input (e.g. copied from a capture).
+
+To calculate the checksum of a header, set its checksum field to zero. To
+validate a header copied from a capture, include its checksum field: the
+checksum of a valid header is 0x0000. Use --verify to exit with a non-zero
+exit code if it is not.
+
+Examples:
+  iptool header checksum 45000073000040004011 0000 c0a80001c0a800c7
+  iptool header checksum 45000073000040004011b861c0a80001c0a800c7 --verify
+  echo "45 00 00 73 00 00 40 00 40 11 b8 61 c0 a8 00 01 c0 a8 00 c7" | iptool header checksum -`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no bytes are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return headerChecksumAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// headerChecksumAction is the action function for the header checksum command
+func headerChecksumAction(out io.Writer, stdin io.Reader, args []string) error {
+	// Read the bytes from standard input if - is given
+	input := strings.Join(args, "")
+	if len(args) == 1 && args[0] == "-" {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		input = string(b)
+	}
+	data, err := parseHexBytes(input)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	checksum := packet.Checksum(data)
+	fmt.Fprintf(out, "Checksum: 0x%04x (%d bytes)\n", checksum, len(data))
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// A valid checksum field makes the checksum of the data zero
+	if viper.GetBool("header.checksum.verify") && checksum != 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("invalid checksum: the checksum of the data is 0x%04x (expected 0x0000)", checksum))
+	}
+	return nil
+}
+
+func init() {
+	headerCmd.AddCommand(headerChecksumCmd)
+
+	// Define the flag for validating the checksum of the data
+	headerChecksumCmd.Flags().Bool("verify", false, "exit with a non-zero exit code if the data does not contain a valid checksum")
+	viper.BindPFlag("header.checksum.verify", headerChecksumCmd.Flags().Lookup("verify"))
+}
@@ -0,0 +1,277 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/packet"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// headerIPv4Cmd represents the header ipv4 command
+var headerIPv4Cmd = &cobra.Command{
+	Use:   "ipv4",
+	Short: "Build an IPv4 packet and calculate its checksums",
+	Long: `Build an IPv4 packet and calculate its checksums.
+
+The IPv4 header is constructed from the given fields, followed by a TCP or
+UDP header (depending on --protocol) and the payload. The header checksum
+and the TCP or UDP checksum, which covers the pseudo-header of the source
+and destination addresses, are calculated. The byte layout of every header
+and a hex dump of the packet are printed. For other protocols (e.g. icmp or
+a protocol number) the payload follows the IPv4 header as is.
+
+Use --hex to only print the packet as a hex string (e.g. to feed it to a
+packet generator) or --json for the headers, fields and checksums in JSON
+format.
+
+Examples:
+  iptool header ipv4 --src 10.0.0.1 --dst 10.0.0.2
+  iptool header ipv4 --src 10.0.0.1 --dst 10.0.0.2 --ttl 64 --tcp-flags SYN,ACK --dst-port 443
+  iptool header ipv4 --src 10.0.0.1 --dst 10.0.0.53 --protocol udp --dst-port 53 --payload-hex "12 34 01 00"
+  iptool header ipv4 --src 10.0.0.1 --dst 10.0.0.2 --protocol icmp --payload-hex 0800f7ff00000000 --hex`,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// The source and destination addresses are required
+		for _, flag := range []string{"src", "dst"} {
+			if viper.GetString("header.ipv4."+flag) == "" {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("--%s is required", flag))
+			}
+		}
+
+		// --payload and --payload-hex are mutually exclusive
+		if viper.GetString("header.ipv4.payload") != "" && viper.GetString("header.ipv4.payload-hex") != "" {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("--payload and --payload-hex are mutually exclusive"))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return headerIPv4Action(os.Stdout)
+	},
+}
+
+// headerJSON is the JSON representation of a header in a packet
+type headerJSON struct {
+	Name     string         `json:"name"`
+	Offset   int            `json:"offset"`
+	Bytes    string         `json:"bytes"`
+	Checksum string         `json:"checksum,omitempty"`
+	Fields   []packet.Field `json:"fields"`
+}
+
+// packetJSON is the JSON representation of a packet built by the header
+// ipv4 command
+type packetJSON struct {
+	Length  int          `json:"length"`
+	Headers []headerJSON `json:"headers"`
+	Payload string       `json:"payload"`
+	Packet  string       `json:"packet"`
+}
+
+// headerIPv4Action is the action function for the header ipv4 command
+func headerIPv4Action(out io.Writer) error {
+	// Parse the addresses of the packet
+	src, err := netip.ParseAddr(resolveAlias(viper.GetString("header.ipv4.src")))
+	if err != nil || !src.Is4() {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid source address: %s (must be an IPv4 address)", viper.GetString("header.ipv4.src")))
+	}
+	dst, err := netip.ParseAddr(resolveAlias(viper.GetString("header.ipv4.dst")))
+	if err != nil || !dst.Is4() {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid destination address: %s (must be an IPv4 address)", viper.GetString("header.ipv4.dst")))
+	}
+
+	// Parse the protocol and the payload
+	protocol, err := packet.ParseProtocol(viper.GetString("header.ipv4.protocol"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	payload := []byte(viper.GetString("header.ipv4.payload"))
+	if s := viper.GetString("header.ipv4.payload-hex"); s != "" {
+		if payload, err = parseHexBytes(s); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+	}
+
+	// Build the transport header, the IPv4 header includes its length
+	var transport []byte
+	switch protocol {
+	case packet.ProtocolTCP:
+		flags, err := packet.ParseTCPFlags(viper.GetString("header.ipv4.tcp-flags"))
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		transport = packet.TCP{
+			SrcPort: uint16(viper.GetUint("header.ipv4.src-port")),
+			DstPort: uint16(viper.GetUint("header.ipv4.dst-port")),
+			Seq:     viper.GetUint32("header.ipv4.seq"),
+			Ack:     viper.GetUint32("header.ipv4.ack"),
+			Flags:   flags,
+			Window:  uint16(viper.GetUint("header.ipv4.window")),
+		}.Marshal(src, dst, payload)
+	case packet.ProtocolUDP:
+		transport = packet.UDP{
+			SrcPort: uint16(viper.GetUint("header.ipv4.src-port")),
+			DstPort: uint16(viper.GetUint("header.ipv4.dst-port")),
+		}.Marshal(src, dst, payload)
+	}
+
+	ipv4, err := packet.IPv4{
+		TOS:            uint8(viper.GetUint("header.ipv4.tos")),
+		ID:             uint16(viper.GetUint("header.ipv4.id")),
+		DontFragment:   viper.GetBool("header.ipv4.df"),
+		MoreFragments:  viper.GetBool("header.ipv4.mf"),
+		FragmentOffset: uint16(viper.GetUint("header.ipv4.fragment-offset")),
+		TTL:            uint8(viper.GetUint("header.ipv4.ttl")),
+		Protocol:       protocol,
+		Src:            src,
+		Dst:            dst,
+	}.Marshal(len(transport) + len(payload))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Assemble the packet
+	pkt := append(append(append([]byte{}, ipv4...), transport...), payload...)
+
+	// Collect the headers with their byte layout and checksums
+	ipv4Fields, _ := packet.IPv4Fields(ipv4)
+	headers := []headerJSON{{Name: "IPv4", Bytes: hex.EncodeToString(ipv4), Checksum: formatChecksum(ipv4[10:12]), Fields: ipv4Fields}}
+	switch protocol {
+	case packet.ProtocolTCP:
+		fields, _ := packet.TCPFields(transport)
+		headers = append(headers, headerJSON{Name: "TCP", Offset: len(ipv4), Bytes: hex.EncodeToString(transport), Checksum: formatChecksum(transport[16:18]), Fields: fields})
+	case packet.ProtocolUDP:
+		fields, _ := packet.UDPFields(transport)
+		headers = append(headers, headerJSON{Name: "UDP", Offset: len(ipv4), Bytes: hex.EncodeToString(transport), Checksum: formatChecksum(transport[6:8]), Fields: fields})
+	}
+
+	switch {
+	case viper.GetBool("header.ipv4.hex"):
+		fmt.Fprintln(out, hex.EncodeToString(pkt))
+	case viper.GetBool("header.ipv4.json"):
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(packetJSON{Length: len(pkt), Headers: headers, Payload: hex.EncodeToString(payload), Packet: hex.EncodeToString(pkt)}); err != nil {
+			return err
+		}
+	default:
+		for _, h := range headers {
+			b, _ := hex.DecodeString(h.Bytes)
+			writeHeaderFields(out, h.Name+" header", b, h.Offset, h.Fields)
+		}
+
+		// Print the pseudo-header that is covered by the TCP or UDP checksum
+		if len(transport) > 0 {
+			pseudo := packet.PseudoHeader(src, dst, protocol, len(transport)+len(payload))
+			fmt.Fprintf(out, "Pseudo-header (%d bytes, only used for the %s checksum):\n", len(pseudo), headers[1].Name)
+			writeHexDump(out, pseudo)
+			fmt.Fprintln(out)
+		}
+
+		if len(payload) > 0 {
+			fmt.Fprintf(out, "Payload (%d bytes):\n", len(payload))
+			writeHexDump(out, payload)
+			fmt.Fprintln(out)
+		}
+
+		fmt.Fprintf(out, "Packet (%d bytes):\n", len(pkt))
+		writeHexDump(out, pkt)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// formatChecksum is a function that formats the two bytes of a checksum
+// field in hexadecimal (e.g. 0xb861)
+func formatChecksum(b []byte) string {
+	return fmt.Sprintf("0x%02x%02x", b[0], b[1])
+}
+
+func init() {
+	headerCmd.AddCommand(headerIPv4Cmd)
+
+	// Define the flags for the addresses of the IPv4 header
+	headerIPv4Cmd.Flags().StringP("src", "s", "", "source address of the packet (required)")
+	viper.BindPFlag("header.ipv4.src", headerIPv4Cmd.Flags().Lookup("src"))
+	headerIPv4Cmd.RegisterFlagCompletionFunc("src", completeAliasArgs)
+	headerIPv4Cmd.Flags().StringP("dst", "d", "", "destination address of the packet (required)")
+	viper.BindPFlag("header.ipv4.dst", headerIPv4Cmd.Flags().Lookup("dst"))
+	headerIPv4Cmd.RegisterFlagCompletionFunc("dst", completeAliasArgs)
+
+	// Define the flags for the other fields of the IPv4 header
+	headerIPv4Cmd.Flags().Uint8("ttl", 64, "time to live of the packet")
+	viper.BindPFlag("header.ipv4.ttl", headerIPv4Cmd.Flags().Lookup("ttl"))
+	headerIPv4Cmd.Flags().Uint8("tos", 0, "type of service (DSCP and ECN) of the packet")
+	viper.BindPFlag("header.ipv4.tos", headerIPv4Cmd.Flags().Lookup("tos"))
+	headerIPv4Cmd.Flags().Uint16("id", 0, "identification of the packet")
+	viper.BindPFlag("header.ipv4.id", headerIPv4Cmd.Flags().Lookup("id"))
+	headerIPv4Cmd.Flags().Bool("df", false, "set the don't fragment flag")
+	viper.BindPFlag("header.ipv4.df", headerIPv4Cmd.Flags().Lookup("df"))
+	headerIPv4Cmd.Flags().Bool("mf", false, "set the more fragments flag")
+	viper.BindPFlag("header.ipv4.mf", headerIPv4Cmd.Flags().Lookup("mf"))
+	headerIPv4Cmd.Flags().Uint16("fragment-offset", 0, "fragment offset of the packet in units of 8 bytes")
+	viper.BindPFlag("header.ipv4.fragment-offset", headerIPv4Cmd.Flags().Lookup("fragment-offset"))
+	headerIPv4Cmd.Flags().StringP("protocol", "p", "tcp", "protocol of the payload (tcp, udp, icmp or a number)")
+	viper.BindPFlag("header.ipv4.protocol", headerIPv4Cmd.Flags().Lookup("protocol"))
+	headerIPv4Cmd.RegisterFlagCompletionFunc("protocol", completeValues("tcp", "udp", "icmp"))
+
+	// Define the flags for the TCP and UDP headers
+	headerIPv4Cmd.Flags().Uint16("src-port", 49152, "source port of the TCP or UDP header")
+	viper.BindPFlag("header.ipv4.src-port", headerIPv4Cmd.Flags().Lookup("src-port"))
+	headerIPv4Cmd.Flags().Uint16("dst-port", 80, "destination port of the TCP or UDP header")
+	viper.BindPFlag("header.ipv4.dst-port", headerIPv4Cmd.Flags().Lookup("dst-port"))
+	headerIPv4Cmd.Flags().Uint32("seq", 0, "sequence number of the TCP header")
+	viper.BindPFlag("header.ipv4.seq", headerIPv4Cmd.Flags().Lookup("seq"))
+	headerIPv4Cmd.Flags().Uint32("ack", 0, "acknowledgment number of the TCP header")
+	viper.BindPFlag("header.ipv4.ack", headerIPv4Cmd.Flags().Lookup("ack"))
+	headerIPv4Cmd.Flags().String("tcp-flags", "SYN", "flags of the TCP header (e.g. SYN,ACK)")
+	viper.BindPFlag("header.ipv4.tcp-flags", headerIPv4Cmd.Flags().Lookup("tcp-flags"))
+	headerIPv4Cmd.RegisterFlagCompletionFunc("tcp-flags", completeValues("SYN", "SYN,ACK", "ACK", "PSH,ACK", "FIN,ACK", "RST", "RST,ACK"))
+	headerIPv4Cmd.Flags().Uint16("window", 65535, "window size of the TCP header")
+	viper.BindPFlag("header.ipv4.window", headerIPv4Cmd.Flags().Lookup("window"))
+
+	// Define the flags for the payload of the packet
+	headerIPv4Cmd.Flags().String("payload", "", "payload of the packet as text")
+	viper.BindPFlag("header.ipv4.payload", headerIPv4Cmd.Flags().Lookup("payload"))
+	headerIPv4Cmd.Flags().String("payload-hex", "", "payload of the packet in hexadecimal (e.g. \"de ad be ef\")")
+	viper.BindPFlag("header.ipv4.payload-hex", headerIPv4Cmd.Flags().Lookup("payload-hex"))
+
+	// Define the flags for the output format
+	headerIPv4Cmd.Flags().Bool("hex", false, "only print the packet as a hex string")
+	viper.BindPFlag("header.ipv4.hex", headerIPv4Cmd.Flags().Lookup("hex"))
+	headerIPv4Cmd.Flags().Bool("json", false, "print the headers and checksums in JSON format")
+	viper.BindPFlag("header.ipv4.json", headerIPv4Cmd.Flags().Lookup("json"))
+}
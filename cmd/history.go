@@ -0,0 +1,81 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitcanon/iptool/results"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show previous measurements recorded in the results store",
+	Long: `Show previous measurements recorded in the results store.
+
+Recording is opt-in: run tcp ping, probe, sweep, inspect or dashboard
+with the global --record flag (or set record: true in the config
+file), and every result is appended to the results store with a timestamp. The store is kept in the
+configuration directory of the user by default (for example
+~/.config/iptool/results.jsonl on Linux), use the results.file key in the
+config file to use a different file.
+
+Use "history show" to view the measurements and the trend of a target.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// resultsPath is a function that returns the path of the results store,
+// either the file in the results.file key or the default path
+func resultsPath() (string, error) {
+	if path := viper.GetString("results.file"); path != "" {
+		return path, nil
+	}
+	return results.DefaultPath()
+}
+
+// recordResults is a function that appends the results to the results store
+// if recording is enabled with --record (or the record key). Recording is a
+// side effect of the measurement, so a failure is reported as a warning
+// instead of failing the command.
+func recordResults(list ...results.Result) {
+	if !viper.GetBool("record") || len(list) == 0 {
+		return
+	}
+	path, err := resultsPath()
+	if err == nil {
+		err = results.Append(path, list...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the results were not recorded: %v\n", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
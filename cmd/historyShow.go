@@ -0,0 +1,258 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/results"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyPeriods are the lengths of the periods accepted by the --by flag
+var historyPeriods = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:   "show <host>",
+	Short: "Show the recorded measurements and the trend of a target",
+	Long: `Show the recorded measurements and the trend of a target.
+
+The target is given as the name or the address that was measured. The most
+recent measurements are listed first (the last 20 by default, use --limit),
+followed by the trend: the number of measurements, the failure rate and the
+minimum, average and maximum response times per period (--by hour, day or
+week), and a sparkline of the average response times.
+
+Use --kind to only show the results of one command (tcp-ping, probe, sweep,
+inspect or monitor for the dashboard) and --since to only show the results of a recent period, e.g.
+24h, 7d or 2w.
+
+Examples:
+  iptool history show 10.0.0.1
+  iptool history show web01.example.com --kind tcp-ping --since 7d
+  iptool history show 10.0.0.1 --by hour --since 24h
+  iptool history show 10.0.0.1 --json`,
+	Args:              cobra.ExactArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return historyShowAction(os.Stdout, resolveAlias(args[0]))
+	},
+}
+
+// historyPeriodJSON is a period of the trend, as printed with --json
+type historyPeriodJSON struct {
+	Start       time.Time `json:"start"`
+	Count       int       `json:"count"`
+	Failures    int       `json:"failures"`
+	FailureRate float64   `json:"failure_rate"`
+	MinMs       float64   `json:"min_ms,omitempty"`
+	AvgMs       float64   `json:"avg_ms,omitempty"`
+	MaxMs       float64   `json:"max_ms,omitempty"`
+}
+
+// historyShowAction is the action function for the history show command
+func historyShowAction(out io.Writer, target string) error {
+	kind := strings.ToLower(viper.GetString("history.show.kind"))
+	if kind != "" && !slices.Contains(results.Kinds, kind) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid kind: %s (must be one of %s)", kind, strings.Join(results.Kinds, ", ")))
+	}
+	by := strings.ToLower(viper.GetString("history.show.by"))
+	period, ok := historyPeriods[by]
+	if !ok {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid period: %s (must be hour, day or week)", by))
+	}
+	var since time.Time
+	if s := viper.GetString("history.show.since"); s != "" {
+		d, err := utils.ParseDuration(s)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		since = time.Now().Add(-d)
+	}
+	limit := viper.GetInt("history.show.limit")
+
+	// Read the results of the target
+	path, err := resultsPath()
+	if err != nil {
+		return err
+	}
+	list, err := results.Read(path, func(r results.Result) bool {
+		return r.Matches(target) && (kind == "" || r.Kind == kind) && !r.Time.Before(since)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Summarize the results per period, in the time zone of the timestamps
+	loc := time.Local
+	if strings.EqualFold(viper.GetString("time-zone"), "utc") {
+		loc = time.UTC
+	}
+	trend := results.Trend(list, period, loc)
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if viper.GetBool("history.show.json") {
+		periods := make([]historyPeriodJSON, len(trend))
+		for i, p := range trend {
+			periods[i] = historyPeriodJSON{
+				Start:       p.Start,
+				Count:       p.Count,
+				Failures:    p.Failures,
+				FailureRate: p.FailureRate(),
+				MinMs:       durationMs(p.Stats.Min()),
+				AvgMs:       durationMs(p.Stats.Mean()),
+				MaxMs:       durationMs(p.Stats.Max()),
+			}
+		}
+		if list == nil {
+			list = []results.Result{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Target  string              `json:"target"`
+			Results []results.Result    `json:"results"`
+			Trend   []historyPeriodJSON `json:"trend"`
+		}{target, list, periods})
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintf(out, "No results of %s found in %s\n", target, path)
+		return nil
+	}
+
+	// List the most recent measurements first
+	table := render.NewTable(out, render.Options{},
+		render.Column{Title: "Time"},
+		render.Column{Title: "Kind"},
+		render.Column{Title: "Address"},
+		render.Column{Title: "Port", Align: render.AlignRight},
+		render.Column{Title: "Result"},
+		render.Column{Title: "RTT", Align: render.AlignRight},
+		render.Column{Title: "Detail"},
+	)
+	recent := slices.Clone(list)
+	slices.Reverse(recent)
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	rows := make([][]string, len(recent))
+	for i, r := range recent {
+		port, status, rtt := "", "FAIL", ""
+		if r.Port > 0 {
+			port = strconv.Itoa(r.Port)
+		}
+		if r.Success {
+			status = "OK"
+		}
+		if r.RTTMs > 0 {
+			rtt = formatMilliseconds(r.RTT())
+		}
+		rows[i] = []string{utils.FormatTimestamp(r.Time), r.Kind, r.Address, port, status, rtt, r.Detail}
+		table.Fit(rows[i]...)
+	}
+	for _, row := range rows {
+		table.Row(row...)
+	}
+	if len(recent) < len(list) {
+		fmt.Fprintf(out, "(the last %d of %d results, use --limit 0 to show all)\n", len(recent), len(list))
+	}
+
+	// Print the trend per period
+	fmt.Fprintf(out, "\nTrend per %s:\n", by)
+	layout := "2006-01-02"
+	if period < 24*time.Hour {
+		layout = "2006-01-02 15:04"
+	}
+	trendTable := render.NewTable(out, render.Options{},
+		render.Column{Title: "Period", Width: len(layout)},
+		render.Column{Title: "Results", Align: render.AlignRight},
+		render.Column{Title: "Failed", Align: render.AlignRight},
+		render.Column{Title: "Min", Width: 10, Align: render.AlignRight},
+		render.Column{Title: "Avg", Width: 10, Align: render.AlignRight},
+		render.Column{Title: "Max", Width: 10, Align: render.AlignRight},
+	)
+	averages := make([]float64, len(trend))
+	for i, p := range trend {
+		failed := fmt.Sprintf("%.1f%%", p.FailureRate())
+		if p.Stats.Count() == 0 {
+			averages[i] = math.NaN()
+			trendTable.Row(p.Start.Format(layout), strconv.Itoa(p.Count), failed, "-", "-", "-")
+			continue
+		}
+		averages[i] = durationMs(p.Stats.Mean())
+		trendTable.Row(p.Start.Format(layout), strconv.Itoa(p.Count), failed,
+			formatMilliseconds(p.Stats.Min()), formatMilliseconds(p.Stats.Mean()), formatMilliseconds(p.Stats.Max()))
+	}
+	fmt.Fprintf(out, "\nAverage RTT: %s\n", utils.Sparkline(averages, ' '))
+
+	return nil
+}
+
+func init() {
+	historyCmd.AddCommand(historyShowCmd)
+
+	// Define the flag for the kind of results to show
+	historyShowCmd.Flags().StringP("kind", "k", "", "only show the results of one command (tcp-ping, probe, sweep or inspect)")
+	viper.BindPFlag("history.show.kind", historyShowCmd.Flags().Lookup("kind"))
+	historyShowCmd.RegisterFlagCompletionFunc("kind", completeValues(results.Kinds...))
+
+	// Define the flag for the period of the results to show
+	historyShowCmd.Flags().StringP("since", "s", "", "only show the results of a recent period, e.g. 24h, 7d or 2w")
+	viper.BindPFlag("history.show.since", historyShowCmd.Flags().Lookup("since"))
+
+	// Define the flag for the length of the periods of the trend
+	historyShowCmd.Flags().String("by", "day", "length of the periods of the trend (hour, day or week)")
+	viper.BindPFlag("history.show.by", historyShowCmd.Flags().Lookup("by"))
+	historyShowCmd.RegisterFlagCompletionFunc("by", completeValues("hour", "day", "week"))
+
+	// Define the flag for the number of measurements to list
+	historyShowCmd.Flags().IntP("limit", "l", 20, "number of recent measurements to list (0 for all)")
+	viper.BindPFlag("history.show.limit", historyShowCmd.Flags().Lookup("limit"))
+
+	// Define the flag for printing the results in JSON format
+	historyShowCmd.Flags().Bool("json", false, "print the results and the trend in JSON format")
+	viper.BindPFlag("history.show.json", historyShowCmd.Flags().Lookup("json"))
+}
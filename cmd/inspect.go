@@ -22,13 +22,19 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"net"
 	"os"
 	"strings"
 
+	"github.com/bitcanon/iptool/exitcode"
 	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/mac"
+	"github.com/bitcanon/iptool/results"
+	"github.com/bitcanon/iptool/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -46,17 +52,27 @@ Examples:
   iptool inspect 10.0.0.1 255.255.255.0
   iptool inspect 0xc0800d25
   iptool inspect c0800d25/22
-  iptool inspect c0800d25 fffffe00`,
-	SilenceUsage: true,
+  iptool inspect c0800d25 fffffe00
+  iptool inspect 10.0.0.1 255.0.255.0 --allow-discontiguous
+  iptool inspect 2001:db8::/64 --derive 00:11:22:33:44:55`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If no arguments are provided, print a short help text
 		if len(args) == 0 {
 			cmd.Help()
 			return nil
 		}
-		input := strings.Join(args, " ")
+		input := strings.Join(resolveAliases(args), " ")
+		if err := inspectAction(os.Stdout, input); err != nil {
+			return err
+		}
 
-		return inspectAction(os.Stdout, input)
+		// Record the address and its type if --record is set
+		if r, err := server.Inspect(input); err == nil {
+			recordResults(results.Result{Kind: results.KindInspect, Target: r.Address, Address: r.Address, Success: true, Detail: r.Type + ", " + r.Prefix})
+		}
+		return nil
 	},
 }
 
@@ -116,12 +132,19 @@ Decimal Notation:
 func inspectAction(out io.Writer, s string) error {
 	if strings.Contains(s, ":") {
 		// If there is a colon in the input string, assume it is an IPv6 address
-		return fmt.Errorf("support for IPv6 addresses is not implemented yet")
+		return inspectIPv6Action(out, s)
 	} else {
 		// Otherwise, assume it is an IPv4 address (either in hexadecimal or dotted decimal notation)
 		ipv4, err := ip.ParseIPv4(s)
+		if errors.Is(err, ip.ErrDiscontiguousNetmask) {
+			// Discontiguous masks are only accepted with wildcard mask semantics
+			if !viper.GetBool("allow-discontiguous") {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("%w, use --allow-discontiguous to treat it as a wildcard mask", err))
+			}
+			return inspectMaskedAction(out, s)
+		}
 		if err != nil {
-			return err
+			return exitcode.New(exitcode.Usage, err)
 		}
 
 		// Create a data structure with the values to fill in the template placeholders
@@ -200,6 +223,134 @@ func inspectAction(out io.Writer, s string) error {
 	return nil
 }
 
+const maskedTemplate = `Address Details:
+ IPv4 address       : {{.HostAddress}}
+ Mask               : {{.Mask}} (discontiguous)
+ Wildcard mask      : {{.WildcardMask}}
+
+Wildcard Details:
+ Base address       : {{.BaseAddress}}
+ Matched addresses  : {{.MatchCount}}
+ ACL entry          : {{.ACLEntry}}
+`
+
+// inspectMaskedAction prints information about an IPv4 address combined with
+// a discontiguous mask, using the semantics of an ACL wildcard mask
+func inspectMaskedAction(out io.Writer, s string) error {
+	masked, err := ip.ParseIPv4Masked(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Create a data structure with the values to fill in the template placeholders
+	data := struct {
+		HostAddress  string
+		Mask         string
+		WildcardMask string
+		BaseAddress  string
+		MatchCount   string
+		ACLEntry     string
+	}{
+		HostAddress:  masked.Address(),
+		Mask:         masked.Netmask(),
+		WildcardMask: masked.Wildcard(),
+		BaseAddress:  masked.Base(),
+		MatchCount:   fmt.Sprintf("%d", masked.MatchCount()),
+		ACLEntry:     masked.ACLEntry(),
+	}
+
+	// Create a new template and parse the template text
+	tmpl := template.Must(template.New("maskedDetails").Parse(maskedTemplate))
+
+	// Execute the template with the data and write the result to an output
+	return tmpl.Execute(out, data)
+}
+
+const ipv6Template = `Address Details:
+ IPv6 address       : {{.HostAddress}}
+ Expanded address   : {{.ExpandedAddress}}
+ Address type       : {{.AddressType}}
+
+Network Details:
+ CIDR notation      : {{.NetworkDetails}} ({{.NetworkSize}} addresses)
+ Network address    : {{.NetworkAddress}}
+ Last address       : {{.LastAddress}}
+{{- if .MAC}}
+
+SLAAC Details (MAC address {{.MAC}}):
+ Interface ID       : {{.InterfaceID}} (modified EUI-64)
+ SLAAC address      : {{.SLAACAddress}}
+ Link-local address : {{.LinkLocalAddress}}
+ Privacy extensions : temporary addresses (RFC 8981) use random interface IDs
+                      anywhere in {{.NetworkDetails}} and cannot be derived from the MAC
+{{- end}}
+`
+
+// inspectIPv6Action prints information about an IPv6 address and, if the
+// --derive flag is set, the addresses derived from a MAC address using SLAAC
+func inspectIPv6Action(out io.Writer, s string) error {
+	ipv6, err := ip.ParseIPv6(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Create a data structure with the values to fill in the template placeholders
+	data := struct {
+		HostAddress      string
+		ExpandedAddress  string
+		AddressType      string
+		NetworkDetails   string
+		NetworkSize      string
+		NetworkAddress   string
+		LastAddress      string
+		MAC              string
+		InterfaceID      string
+		SLAACAddress     string
+		LinkLocalAddress string
+	}{
+		HostAddress:     ipv6.Address(),
+		ExpandedAddress: ipv6.Expanded(),
+		AddressType:     ipv6.Type(),
+		NetworkDetails:  fmt.Sprintf("%s/%d", ipv6.Network(), ipv6.PrefixLength()),
+		NetworkSize:     ipv6.NetworkSize().String(),
+		NetworkAddress:  ipv6.Network(),
+		LastAddress:     ipv6.LastAddress(),
+	}
+
+	// Derive the SLAAC addresses from the MAC address if the --derive flag is set
+	if derive := viper.GetString("inspect.derive"); derive != "" {
+		hw, err := mac.ParseMAC(derive)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		interfaceID, err := mac.EUI64(hw)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+
+		// The SLAAC address is the /64 prefix combined with the interface ID
+		slaac, err := ip.SLAACAddress(ipv6.Net, interfaceID)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+
+		// The link-local address is fe80::/64 combined with the interface ID
+		_, linkLocalPrefix, _ := net.ParseCIDR("fe80::/64")
+		linkLocal, _ := ip.SLAACAddress(linkLocalPrefix, interfaceID)
+
+		data.MAC = hw.String()
+		data.InterfaceID = ip.ExpandIPv6(append(make(net.IP, 8), interfaceID...))[20:]
+		data.SLAACAddress = slaac.String()
+		data.LinkLocalAddress = linkLocal.String()
+	}
+
+	// Create a new template and parse the template text
+	tmpl := template.Must(template.New("ipv6Details").Parse(ipv6Template))
+
+	// Execute the template with the data and write the result to an output
+	return tmpl.Execute(out, data)
+}
+
 func init() {
 	// Register the inspect command with the root command
 	rootCmd.AddCommand(inspectCmd)
@@ -207,4 +358,8 @@ func init() {
 	// Enable the --verbose flag for the inspect command
 	inspectCmd.Flags().BoolP("verbose", "v", false, "display comprehensive IP address information")
 	viper.BindPFlag("inspect.verbose", inspectCmd.Flags().Lookup("verbose"))
+
+	// Enable the --derive flag for deriving SLAAC addresses from a MAC address
+	inspectCmd.Flags().StringP("derive", "d", "", "derive the SLAAC (EUI-64) addresses of a MAC address in an IPv6 /64")
+	viper.BindPFlag("inspect.derive", inspectCmd.Flags().Lookup("derive"))
 }
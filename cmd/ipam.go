@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamCmd represents the ipam command
+var ipamCmd = &cobra.Command{
+	Use:   "ipam",
+	Short: "Manage the IP address plan in a local IPAM store",
+	Long: `Manage the IP address plan in a local IPAM store.
+
+The IPAM store is a YAML file with the prefixes of the address plan and
+their metadata (name, VLAN and description). Prefixes may be nested, so that
+a /24 allocated from a /16 is part of the hierarchy of the /16. The store is
+kept in the configuration directory of the user by default (for example
+~/.config/iptool/ipam.yaml on Linux), use --file or the ipam.file key in the
+config file to use a different store, e.g. one kept in version control.
+
+Every change of the store is recorded in an audit log next to the store,
+use "ipam history" to review how a prefix evolved.
+
+Use "ipam push" and "ipam pull" to synchronize the store with NetBox.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// ipamPath is a function that returns the path of the IPAM store, either
+// the file selected with --file (or ipam.file) or the default path
+func ipamPath() (string, error) {
+	if path := viper.GetString("ipam.file"); path != "" {
+		return path, nil
+	}
+	return ipam.DefaultPath()
+}
+
+// loadIPAM is a function that loads the IPAM store and returns it together
+// with its path
+func loadIPAM() (*ipam.Store, string, error) {
+	path, err := ipamPath()
+	if err != nil {
+		return nil, "", err
+	}
+	store, err := ipam.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return store, path, nil
+}
+
+func init() {
+	rootCmd.AddCommand(ipamCmd)
+
+	// Define the flag for selecting the IPAM store file
+	ipamCmd.PersistentFlags().String("file", "", "IPAM store file (default is ipam.yaml in the user config directory)")
+	viper.BindPFlag("ipam.file", ipamCmd.PersistentFlags().Lookup("file"))
+}
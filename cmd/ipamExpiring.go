@@ -0,0 +1,150 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamExpiringCmd represents the ipam expiring command
+var ipamExpiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List the prefixes that are about to expire",
+	Long: `List the prefixes in the IPAM store that are about to expire.
+
+The prefixes with an expiry date within the duration given with --within
+(30 days by default) are listed by expiry date, together with the prefixes
+that have already expired and should be reclaimed. Use --fail to exit with
+a non-zero exit code if any prefix has expired, e.g. in a scheduled job.
+
+Examples:
+  iptool ipam expiring
+  iptool ipam expiring --within 7d
+  iptool ipam expiring --within 0 --fail`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+		return ipamExpiringAction(os.Stdout, time.Now())
+	},
+}
+
+// ipamExpiringAction is the action function for the ipam expiring command
+func ipamExpiringAction(out io.Writer, now time.Time) error {
+	within, err := utils.ParseDuration(viper.GetString("ipam.expiring.within"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	store, _, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+
+	// Find the expiring and expired entries
+	entries := store.Expiring(now, within)
+	expired := 0
+	for _, e := range entries {
+		if t, _ := e.Expiry(now.Location()); !t.After(now) {
+			expired++
+		}
+	}
+
+	if viper.GetBool("ipam.expiring.json") {
+		if entries == nil {
+			entries = []ipam.Entry{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			return err
+		}
+	} else if len(entries) == 0 {
+		fmt.Fprintln(out, "No prefixes expiring within", viper.GetString("ipam.expiring.within"))
+	} else {
+		// Find the length of the longest prefix and name (for padding)
+		prefixWidth, nameWidth := len("Prefix"), len("Name")
+		for _, e := range entries {
+			prefixWidth = max(prefixWidth, len(e.Prefix))
+			nameWidth = max(nameWidth, len(e.Name))
+		}
+
+		fmtString := fmt.Sprintf("%%-%ds  %%-%ds  %%-10s  %%-10s  %%s\n", prefixWidth, nameWidth)
+		fmt.Fprintf(out, fmtString, "Prefix", "Name", "State", "Expires", "Remaining")
+		for _, e := range entries {
+			t, _ := e.Expiry(now.Location())
+			fmt.Fprintf(out, fmtString, e.Prefix, e.Name, e.Status(), e.Expires, formatRemaining(t.Sub(now)))
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if expired > 0 && viper.GetBool("ipam.expiring.fail") {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d prefix(es) expired", expired))
+	}
+	return nil
+}
+
+// formatRemaining is a function that formats the time remaining until an
+// expiry date in days (or hours on the last day)
+func formatRemaining(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "expired"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d hour(s)", int(d.Hours())+1)
+	default:
+		return fmt.Sprintf("%d day(s)", int(d.Hours()/24))
+	}
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamExpiringCmd)
+
+	// Define the flag for the time window
+	ipamExpiringCmd.Flags().StringP("within", "w", "30d", "list the prefixes expiring within the duration (e.g. 7d, 2w)")
+	viper.BindPFlag("ipam.expiring.within", ipamExpiringCmd.Flags().Lookup("within"))
+	ipamExpiringCmd.RegisterFlagCompletionFunc("within", completeValues("7d", "14d", "30d", "90d"))
+
+	// Define the flag for printing the entries in JSON format
+	ipamExpiringCmd.Flags().Bool("json", false, "print the entries in JSON format")
+	viper.BindPFlag("ipam.expiring.json", ipamExpiringCmd.Flags().Lookup("json"))
+
+	// Define the flag for failing when prefixes have expired
+	ipamExpiringCmd.Flags().Bool("fail", false, "exit with a non-zero exit code if any prefix has expired")
+	viper.BindPFlag("ipam.expiring.fail", ipamExpiringCmd.Flags().Lookup("fail"))
+}
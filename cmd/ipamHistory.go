@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamHistoryCmd represents the ipam history command
+var ipamHistoryCmd = &cobra.Command{
+	Use:   "history [prefix]",
+	Short: "Show the history of changes in the IPAM store",
+	Long: `Show the history of changes in the IPAM store.
+
+Every change of the IPAM store (imports, reservations, allocations,
+deprecations and releases) is appended to an audit log next to the store
+(ipam.log for ipam.yaml), recording when the change was made, by whom, on
+which host and with which command. The user is taken from the IPTOOL_USER
+environment variable, falling back to SUDO_USER, USER and USERNAME.
+
+Without arguments, all changes are shown. With a prefix, only the changes of
+that prefix are shown, or also those of the prefixes nested in it with
+--nested.
+
+Examples:
+  iptool ipam history
+  iptool ipam history 10.0.3.0/24
+  iptool ipam history 10.0.0.0/16 --nested
+  iptool ipam history 10.0.3.0/24 --json`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ipamHistoryAction(os.Stdout, args)
+	},
+}
+
+// ipamHistoryAction is the action function for the ipam history command
+func ipamHistoryAction(out io.Writer, args []string) error {
+	path, err := ipamPath()
+	if err != nil {
+		return err
+	}
+	changes, err := ipam.ReadLog(ipam.LogPath(path))
+	if err != nil {
+		return err
+	}
+
+	// Keep the changes of the prefix (and the nested prefixes) only
+	if len(args) > 0 {
+		prefix, err := parseIPAMPrefix(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		parent := netip.MustParsePrefix(prefix)
+		nested := viper.GetBool("ipam.history.nested")
+
+		var filtered []ipam.Change
+		for _, c := range changes {
+			p, err := netip.ParsePrefix(c.Prefix)
+			if err != nil {
+				continue
+			}
+			if p == parent || (nested && p.Bits() > parent.Bits() && parent.Contains(p.Addr())) {
+				filtered = append(filtered, c)
+			}
+		}
+		changes = filtered
+	}
+
+	if viper.GetBool("ipam.history.json") {
+		if changes == nil {
+			changes = []ipam.Change{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes found in", ipam.LogPath(path))
+		return nil
+	}
+
+	// Format the timestamps and find the longest values (for padding)
+	times := make([]string, len(changes))
+	timeWidth, userWidth, prefixWidth := len("Time"), len("User"), len("Prefix")
+	for i, c := range changes {
+		times[i] = utils.FormatTimestamp(c.Time)
+		timeWidth = max(timeWidth, len(times[i]))
+		userWidth = max(userWidth, len(c.User))
+		prefixWidth = max(prefixWidth, len(c.Prefix))
+	}
+
+	fmtString := fmt.Sprintf("%%-%ds  %%-%ds  %%-6s  %%-%ds  %%s\n", timeWidth, userWidth, prefixWidth)
+	fmt.Fprintf(out, fmtString, "Time", "User", "Action", "Prefix", "Change")
+	for i, c := range changes {
+		fmt.Fprintf(out, fmtString, times[i], c.User, c.Action, c.Prefix, c.Summary())
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamHistoryCmd)
+
+	// Define the flag for including the changes of nested prefixes
+	ipamHistoryCmd.Flags().BoolP("nested", "n", false, "also show the changes of the prefixes nested in the prefix")
+	viper.BindPFlag("ipam.history.nested", ipamHistoryCmd.Flags().Lookup("nested"))
+
+	// Define the flag for printing the changes in JSON format
+	ipamHistoryCmd.Flags().Bool("json", false, "print the changes in JSON format")
+	viper.BindPFlag("ipam.history.json", ipamHistoryCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"unicode/utf8"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamImportCmd represents the ipam import command
+var ipamImportCmd = &cobra.Command{
+	Use:   "import <file|-> --map <mapping>",
+	Short: "Import an address plan from a spreadsheet (CSV)",
+	Long: `Import an address plan from a spreadsheet (CSV) into the IPAM store.
+
+The --map flag maps the fields of the IPAM entries (prefix, name, vlan,
+description, state and expires) to the columns of the spreadsheet, either by column number
+(starting at 1) or by header name. Only the prefix is required. The first
+row is treated as a header if a field is mapped by header name, or if its
+prefix column does not contain a prefix.
+
+The entries are normalized while importing: netmasks are converted to
+prefix lengths (10.0.0.0 255.255.255.0 becomes 10.0.0.0/24), host bits are
+cleared, single addresses become /32 (or /128) prefixes and VLANs written as
+"VLAN 10" become 10. The delimiter is detected from the first line (comma,
+semicolon, tab or pipe) unless it is given with --delimiter.
+
+Prefixes that are already in the store with the same data are left as they
+are, so a spreadsheet can be imported again as it is migrated. A prefix that
+is in the store with different data is reported as a conflict, unless
+--update is set. Nested prefixes are allowed, use --flat to reject prefixes
+overlapping other prefixes for address plans without a hierarchy.
+
+The import is all or nothing: if any row is invalid, nothing is imported.
+Use --skip-invalid to import the valid rows only and --dry-run to check a
+spreadsheet without changing the store.
+
+Examples:
+  iptool ipam import plan.csv --map prefix=2,name=1,vlan=3
+  iptool ipam import plan.csv --map prefix=Network,name=Site,vlan=VLAN --dry-run
+  iptool ipam import plan.csv --map prefix=1,description=4 --delimiter ';'
+  cat plan.csv | iptool ipam import - --map prefix=1 --skip-invalid`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		if len(args) > 1 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v (only one file can be imported at a time)", args[1:]))
+		}
+
+		// Read from standard input or open the file
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+
+		return ipamImportAction(os.Stdout, in)
+	},
+}
+
+// ipamImportAction is the action function for the ipam import command
+func ipamImportAction(out io.Writer, in io.Reader) error {
+	// The mapping of the fields to the columns is required
+	spec := viper.GetString("ipam.import.map")
+	if spec == "" {
+		return exitcode.New(exitcode.Usage, errors.New("no column mapping specified (use --map prefix=<column>[,name=<column>,...])"))
+	}
+	mapping, err := ipam.ParseMapping(spec)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	delimiter, err := parseDelimiter(viper.GetString("ipam.import.delimiter"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	rows, err := ipam.ReadCSV(in, ipam.ImportOptions{Mapping: mapping, Delimiter: delimiter})
+	if err != nil {
+		return err
+	}
+
+	store, path, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+
+	// Add the rows to the store, reporting invalid rows and conflicts
+	update := viper.GetBool("ipam.import.update")
+	flat := viper.GetBool("ipam.import.flat")
+	var added, updated, unchanged, invalid int
+	for _, row := range rows {
+		for _, note := range row.Notes {
+			fmt.Fprintf(out, "line %d: %s\n", row.Line, note)
+		}
+		if row.Err != nil {
+			fmt.Fprintf(out, "line %d: error: %v\n", row.Line, row.Err)
+			invalid++
+			continue
+		}
+
+		// Reject overlapping prefixes in flat address plans
+		if flat {
+			if overlaps := store.Overlaps(netip.MustParsePrefix(row.Entry.Prefix)); len(overlaps) > 0 {
+				fmt.Fprintf(out, "line %d: error: %s overlaps %s\n", row.Line, row.Entry, overlaps[0])
+				invalid++
+				continue
+			}
+		}
+
+		exists := store.Find(row.Entry.Prefix) >= 0
+		changed, err := store.Add(row.Entry, update)
+		switch {
+		case err != nil:
+			fmt.Fprintf(out, "line %d: error: %v (use --update to replace it)\n", row.Line, err)
+			invalid++
+		case !changed:
+			unchanged++
+		case exists:
+			updated++
+		default:
+			added++
+		}
+	}
+
+	// Import all rows or nothing, unless the invalid rows are skipped
+	if invalid > 0 && !viper.GetBool("ipam.import.skip-invalid") {
+		return fmt.Errorf("%d invalid row(s), nothing imported (use --skip-invalid to import the valid rows)", invalid)
+	}
+
+	summary := fmt.Sprintf("%d added, %d updated, %d unchanged, %d skipped", added, updated, unchanged, invalid)
+	if viper.GetBool("ipam.import.dry-run") {
+		fmt.Fprintf(out, "Dry run, nothing imported into %s (%s)\n", path, summary)
+	} else {
+		if err := store.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Imported %d row(s) into %s (%s)\n", len(rows)-invalid, path, summary)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// parseDelimiter is a function that parses the delimiter of a CSV file. An
+// empty string means that the delimiter is detected, "tab" (or \t) is the
+// tab character.
+func parseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == '"' || r == '\r' || r == '\n' {
+		return 0, exitcode.New(exitcode.Usage, fmt.Errorf("invalid delimiter: %q (must be a single character)", s))
+	}
+	return r, nil
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamImportCmd)
+
+	// Define the flag for mapping the fields to the columns of the spreadsheet
+	ipamImportCmd.Flags().StringP("map", "m", "", "map fields to columns, e.g. prefix=2,name=1,vlan=3 (numbers or header names)")
+	viper.BindPFlag("ipam.import.map", ipamImportCmd.Flags().Lookup("map"))
+
+	// Define the flag for the field delimiter
+	ipamImportCmd.Flags().StringP("delimiter", "d", "", "field delimiter (default is detected from the first line)")
+	viper.BindPFlag("ipam.import.delimiter", ipamImportCmd.Flags().Lookup("delimiter"))
+	ipamImportCmd.RegisterFlagCompletionFunc("delimiter", completeValues(",", ";", "tab", "|"))
+
+	// Define the flag for replacing entries that are already in the store
+	ipamImportCmd.Flags().BoolP("update", "u", false, "replace prefixes already in the store with different data")
+	viper.BindPFlag("ipam.import.update", ipamImportCmd.Flags().Lookup("update"))
+
+	// Define the flag for rejecting nested prefixes
+	ipamImportCmd.Flags().Bool("flat", false, "reject prefixes overlapping other prefixes (no nesting)")
+	viper.BindPFlag("ipam.import.flat", ipamImportCmd.Flags().Lookup("flat"))
+
+	// Define the flag for importing the valid rows only
+	ipamImportCmd.Flags().Bool("skip-invalid", false, "import the valid rows and skip the invalid ones")
+	viper.BindPFlag("ipam.import.skip-invalid", ipamImportCmd.Flags().Lookup("skip-invalid"))
+
+	// Define the flag for checking the spreadsheet without changing the store
+	ipamImportCmd.Flags().BoolP("dry-run", "n", false, "check the spreadsheet without changing the store")
+	viper.BindPFlag("ipam.import.dry-run", ipamImportCmd.Flags().Lookup("dry-run"))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamListCmd represents the ipam list command
+var ipamListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the prefixes in the IPAM store",
+	Long: `List the prefixes in the IPAM store.
+
+The prefixes are listed in order, IPv4 before IPv6, with every prefix before
+the prefixes it contains, together with their reservation state (reserved,
+allocated or deprecated) and expiry date. Use -4 or -6 to only list the
+prefixes of one address family and --state to only list the prefixes in a
+state.
+
+Examples:
+  iptool ipam list
+  iptool ipam list -4
+  iptool ipam list --state reserved
+  iptool ipam list --json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+		return ipamListAction(os.Stdout)
+	},
+}
+
+// ipamListAction is the action function for the ipam list command
+func ipamListAction(out io.Writer) error {
+	store, _, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+	store.Sort()
+
+	// Keep the entries of the selected address family only
+	family := getFamily("ipam.list")
+	entries := []ipam.Entry{}
+	for _, e := range store.Entries {
+		if prefix, err := netip.ParsePrefix(e.Prefix); err != nil || family.Match(prefix.Addr()) {
+			entries = append(entries, e)
+		}
+	}
+
+	// Keep the entries in the selected state only
+	if state := viper.GetString("ipam.list.state"); state != "" {
+		if _, err := ipam.ParseState(state); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		filtered := []ipam.Entry{}
+		for _, e := range entries {
+			if strings.EqualFold(e.Status(), state) {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("ipam.list.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Print the entries in JSON format
+	if viper.GetBool("ipam.list.json") {
+		encoder := json.NewEncoder(outputStream)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(outputStream, "No prefixes in the IPAM store")
+		return nil
+	}
+
+	// Find the length of the longest prefix and name (for padding)
+	prefixWidth, nameWidth := len("Prefix"), len("Name")
+	for _, e := range entries {
+		prefixWidth = max(prefixWidth, len(e.Prefix))
+		nameWidth = max(nameWidth, len(e.Name))
+	}
+
+	fmtString := fmt.Sprintf("%%-%ds  %%-%ds  %%-4s  %%-10s  %%-10s  %%s\n", prefixWidth, nameWidth)
+	fmt.Fprintf(outputStream, fmtString, "Prefix", "Name", "VLAN", "State", "Expires", "Description")
+	for _, e := range entries {
+		vlan, expires := "-", "-"
+		if e.VLAN > 0 {
+			vlan = strconv.Itoa(e.VLAN)
+		}
+		if e.Expires != "" {
+			expires = e.Expires
+		}
+		fmt.Fprintf(outputStream, fmtString, e.Prefix, e.Name, vlan, e.Status(), expires, e.Description)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamListCmd)
+	addFamilyFlags(ipamListCmd, "ipam.list")
+
+	// Define the flag for listing the prefixes in one state only
+	ipamListCmd.Flags().String("state", "", "only list the prefixes in the state (reserved, allocated or deprecated)")
+	viper.BindPFlag("ipam.list.state", ipamListCmd.Flags().Lookup("state"))
+	ipamListCmd.RegisterFlagCompletionFunc("state", completeValues(ipam.States...))
+
+	// Define the flag for printing the entries in JSON format
+	ipamListCmd.Flags().Bool("json", false, "print the entries in JSON format")
+	viper.BindPFlag("ipam.list.json", ipamListCmd.Flags().Lookup("json"))
+
+	// Enable the --output-file flag to write the output to a file
+	ipamListCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("ipam.list.output-file", ipamListCmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamMergeCmd represents the ipam merge command
+var ipamMergeCmd = &cobra.Command{
+	Use:   "merge <base> <ours> <theirs>",
+	Short: "Merge two versions of an IPAM store file (git merge driver)",
+	Long: `Merge two versions of an IPAM store file (git merge driver).
+
+The changes made in ours and theirs since the common version base are
+merged per prefix and the result is written to ours. A prefix changed in
+only one of the versions gets that change, a prefix changed differently in
+both versions is a conflict: our version of it is kept and the command
+exits with a non-zero exit code.
+
+iptool uses the same merge when the store is saved while someone else
+changed it, and locks the store while saving, so that engineers sharing the
+store on a network drive do not overwrite each other's changes. To use the
+merge for a store kept in a git repository, register the merge driver and
+assign it to the store in .gitattributes:
+
+  git config merge.ipam.name "iptool IPAM store merge"
+  git config merge.ipam.driver "iptool ipam merge %O %A %B"
+  echo "ipam.yaml merge=ipam" >> .gitattributes
+
+Examples:
+  iptool ipam merge base.yaml ours.yaml theirs.yaml`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 3 {
+			cmd.Help()
+			return nil
+		}
+		return ipamMergeAction(os.Stdout, args[0], args[1], args[2])
+	},
+}
+
+// ipamMergeAction is the action function for the ipam merge command
+func ipamMergeAction(out io.Writer, base, ours, theirs string) error {
+	conflicts, err := ipam.MergeFiles(base, ours, theirs)
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if len(conflicts) > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%w: %s (our version was kept)", ipam.ErrMergeConflict, strings.Join(conflicts, ", ")))
+	}
+	fmt.Fprintf(out, "Merged %s into %s\n", theirs, ours)
+	return nil
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamMergeCmd)
+}
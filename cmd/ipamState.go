@@ -0,0 +1,295 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamReserveCmd represents the ipam reserve command
+var ipamReserveCmd = &cobra.Command{
+	Use:   "reserve <prefix>",
+	Short: "Reserve a prefix in the IPAM store",
+	Long: `Reserve a prefix in the IPAM store.
+
+A reserved prefix is set aside for a future allocation, optionally until an
+expiry date given with --expires as a date (2024-12-31) or as a duration
+from now (30d, 2w). Reserving a prefix that is already reserved updates the
+reservation, e.g. to extend it. Use "ipam expiring" to find reservations
+that are about to expire.
+
+Examples:
+  iptool ipam reserve 10.0.3.0/24 --name lab-east --expires 30d
+  iptool ipam reserve 10.0.3.0/24 --expires 2024-12-31`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return ipamStateAction(os.Stdout, "ipam.reserve", strings.Join(args, " "), ipam.StateReserved)
+	},
+}
+
+// ipamAllocateCmd represents the ipam allocate command
+var ipamAllocateCmd = &cobra.Command{
+	Use:   "allocate <prefix>",
+	Short: "Allocate a prefix in the IPAM store",
+	Long: `Allocate a prefix in the IPAM store.
+
+The prefix is either a new prefix or a reserved prefix that is taken into
+use. The expiry date of a reservation is cleared, unless a new one is given
+with --expires (for temporary allocations, e.g. in lab environments).
+
+Examples:
+  iptool ipam allocate 10.0.3.0/24
+  iptool ipam allocate 10.0.4.0/24 --name servers --vlan 40
+  iptool ipam allocate 10.0.5.0/24 --name test-lab --expires 14d`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return ipamStateAction(os.Stdout, "ipam.allocate", strings.Join(args, " "), ipam.StateAllocated)
+	},
+}
+
+// ipamDeprecateCmd represents the ipam deprecate command
+var ipamDeprecateCmd = &cobra.Command{
+	Use:   "deprecate <prefix>",
+	Short: "Mark a prefix in the IPAM store as deprecated",
+	Long: `Mark a prefix in the IPAM store as deprecated.
+
+A deprecated prefix is being phased out and should be reclaimed, optionally
+by the expiry date given with --expires. Use "ipam release" to remove the
+prefix from the store when it is no longer in use.
+
+Examples:
+  iptool ipam deprecate 10.0.3.0/24
+  iptool ipam deprecate 10.0.3.0/24 --expires 90d`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return ipamStateAction(os.Stdout, "ipam.deprecate", strings.Join(args, " "), ipam.StateDeprecated)
+	},
+}
+
+// ipamReleaseCmd represents the ipam release command
+var ipamReleaseCmd = &cobra.Command{
+	Use:   "release <prefix>",
+	Short: "Release a prefix, removing it from the IPAM store",
+	Long: `Release a prefix, removing it from the IPAM store.
+
+The prefix is removed from the store whatever its state, which makes the
+address space available again. The prefixes nested in it are kept.
+
+Examples:
+  iptool ipam release 10.0.3.0/24`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return ipamReleaseAction(os.Stdout, strings.Join(args, " "))
+	},
+}
+
+// parseIPAMPrefix is a function that parses the prefix argument of the ipam
+// commands, which may be an alias or written with a netmask
+func parseIPAMPrefix(s string) (string, error) {
+	prefix, _, err := ipam.NormalizePrefix(resolveAlias(s))
+	if err != nil {
+		return "", exitcode.New(exitcode.Usage, err)
+	}
+	return prefix.String(), nil
+}
+
+// ipamStateAction is the action function for the ipam reserve, allocate and
+// deprecate commands, which move a prefix to the state
+func ipamStateAction(out io.Writer, command, arg, state string) error {
+	prefix, err := parseIPAMPrefix(arg)
+	if err != nil {
+		return err
+	}
+	store, path, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+
+	// Find the prefix in the store, only deprecation requires it to exist
+	entry := ipam.Entry{Prefix: prefix}
+	i := store.Find(prefix)
+	if i >= 0 {
+		entry = store.Entries[i]
+	} else if state == ipam.StateDeprecated {
+		return fmt.Errorf("prefix not found in the IPAM store: %s", prefix)
+	}
+
+	// Check that the state transition is valid
+	current := entry.Status()
+	switch {
+	case i < 0:
+	case state == ipam.StateReserved && current != ipam.StateReserved:
+		return fmt.Errorf("%s is already %s", entry, current)
+	case state == ipam.StateAllocated && current != ipam.StateReserved:
+		return fmt.Errorf("%s is already %s", entry, current)
+	case state == ipam.StateAllocated:
+		// The expiry date of the reservation does not apply to the allocation
+		entry.Expires = ""
+	}
+	entry.State, _ = ipam.ParseState(state)
+
+	// Apply the fields given as flags
+	if err := applyIPAMEntryFlags(&entry, command); err != nil {
+		return err
+	}
+
+	if i >= 0 {
+		store.Entries[i] = entry
+	} else {
+		store.Entries = append(store.Entries, entry)
+	}
+	if err := store.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s is %s", entry, state)
+	if entry.Expires != "" {
+		fmt.Fprintf(out, " until %s", entry.Expires)
+	}
+	fmt.Fprintln(out)
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// ipamReleaseAction is the action function for the ipam release command
+func ipamReleaseAction(out io.Writer, arg string) error {
+	prefix, err := parseIPAMPrefix(arg)
+	if err != nil {
+		return err
+	}
+	store, path, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+
+	i := store.Find(prefix)
+	if i < 0 {
+		return fmt.Errorf("prefix not found in the IPAM store: %s", prefix)
+	}
+	entry := store.Entries[i]
+	store.Remove(prefix)
+	if err := store.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s is released\n", entry)
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// addIPAMEntryFlags is a function that adds the flags for the fields of an
+// entry to a command. Deprecation only takes an expiry date.
+func addIPAMEntryFlags(cmd *cobra.Command, command string, fields bool) {
+	if fields {
+		cmd.Flags().String("name", "", "name of the prefix")
+		viper.BindPFlag(command+".name", cmd.Flags().Lookup("name"))
+
+		cmd.Flags().Int("vlan", 0, "VLAN ID of the prefix (0 for none)")
+		viper.BindPFlag(command+".vlan", cmd.Flags().Lookup("vlan"))
+
+		cmd.Flags().String("description", "", "description of the prefix")
+		viper.BindPFlag(command+".description", cmd.Flags().Lookup("description"))
+	}
+
+	cmd.Flags().String("expires", "", "expiry date (e.g. 2024-12-31) or duration from now (e.g. 30d)")
+	viper.BindPFlag(command+".expires", cmd.Flags().Lookup("expires"))
+	cmd.RegisterFlagCompletionFunc("expires", completeValues("7d", "14d", "30d", "90d"))
+}
+
+// applyIPAMEntryFlags is a function that sets the fields of the entry that
+// are given as flags (or in the configuration) of the command
+func applyIPAMEntryFlags(e *ipam.Entry, command string) error {
+	if viper.IsSet(command + ".name") {
+		e.Name = viper.GetString(command + ".name")
+	}
+	if viper.IsSet(command + ".vlan") {
+		// A VLAN ID of 0 removes the VLAN from the entry
+		vlan := viper.GetInt(command + ".vlan")
+		if vlan < 0 || vlan > 4094 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid VLAN: %d (must be between 1 and 4094, or 0 for none)", vlan))
+		}
+		e.VLAN = vlan
+	}
+	if viper.IsSet(command + ".description") {
+		e.Description = viper.GetString(command + ".description")
+	}
+	if viper.IsSet(command + ".expires") {
+		expires, err := ipam.ParseExpiry(viper.GetString(command+".expires"), time.Now())
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		e.Expires = expires
+	}
+	return nil
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamReserveCmd)
+	addIPAMEntryFlags(ipamReserveCmd, "ipam.reserve", true)
+
+	ipamCmd.AddCommand(ipamAllocateCmd)
+	addIPAMEntryFlags(ipamAllocateCmd, "ipam.allocate", true)
+
+	ipamCmd.AddCommand(ipamDeprecateCmd)
+	addIPAMEntryFlags(ipamDeprecateCmd, "ipam.deprecate", false)
+
+	ipamCmd.AddCommand(ipamReleaseCmd)
+}
@@ -0,0 +1,355 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/envelope"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ipam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamPushCmd represents the ipam push command
+var ipamPushCmd = &cobra.Command{
+	Use:   "push [prefix] --url <url> --token <token>",
+	Short: "Push the prefixes of the IPAM store to NetBox",
+	Long: `Push the prefixes of the IPAM store to NetBox.
+
+The prefixes in the IPAM store (or, with a prefix, those that are the prefix
+or are nested in it) are created in the global table of the NetBox instance
+at --url, or updated if NetBox has them with different data. Prefixes that
+are only in NetBox are left as they are. Single addresses (/32 and /128
+prefixes, e.g. the loopbacks of "subnet loopbacks") are IP addresses in
+NetBox, all other prefixes are NetBox prefixes.
+
+The name of a prefix is the description of the NetBox object and its
+description the comments. The reserved and deprecated states are the
+reserved and deprecated statuses, allocated prefixes are active. The VLAN is
+looked up by VLAN ID and must be unique in NetBox. Expiry dates are not
+pushed.
+
+With --from-json, the prefixes are read from the JSON output of a subnet
+planning command (e.g. subnet split --format json) instead of the IPAM
+store, so that a plan can be pushed directly. The name of a prefix is taken
+from the name (or device) field of the records.
+
+The changes are printed before they are made, use --dry-run to review them
+without changing NetBox. The API token is read from --token or from the
+IPTOOL_IPAM_TOKEN environment variable, which keeps it out of the process
+list. The URL and the token can also be set with the ipam.url and ipam.token
+keys in the config file.
+
+Examples:
+  iptool ipam push --url https://netbox.example.com --token $TOKEN --dry-run
+  iptool ipam push 10.0.0.0/16 --url https://netbox.example.com
+  iptool subnet split 10.0.0.0/22 --bits 24 --names web,app,db --format json | iptool ipam push --from-json -`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ipamPushAction(os.Stdout, args)
+	},
+}
+
+// ipamPullCmd represents the ipam pull command
+var ipamPullCmd = &cobra.Command{
+	Use:   "pull [prefix] --url <url> --token <token>",
+	Short: "Pull the prefixes of NetBox into the IPAM store",
+	Long: `Pull the prefixes of NetBox into the IPAM store.
+
+The prefixes and IP addresses in the global table of the NetBox instance at
+--url (or, with a prefix, those that are the prefix or are nested in it)
+are added to the IPAM store, or updated if the store has them with
+different data. Prefixes that are only in the store are left as they are,
+and so are the expiry dates, which are not stored in NetBox. IP addresses
+are added as single addresses (10.0.0.5/24 becomes 10.0.0.5/32).
+
+The fields are mapped as described for "ipam push", NetBox statuses other
+than reserved and deprecated (active, container, dhcp, ...) are allocated.
+
+The changes are printed before they are made, use --dry-run to review them
+without changing the store. The changes are recorded in the audit log of
+the store like any other change.
+
+Examples:
+  iptool ipam pull --url https://netbox.example.com --token $TOKEN --dry-run
+  iptool ipam pull 10.0.0.0/16 --url https://netbox.example.com`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ipamPullAction(os.Stdout, args)
+	},
+}
+
+// ipamPushAction is the action function for the ipam push command
+func ipamPushAction(out io.Writer, args []string) error {
+	within, err := parseSyncScope(args)
+	if err != nil {
+		return err
+	}
+	backend, url, err := getIPAMBackend("ipam.push")
+	if err != nil {
+		return err
+	}
+
+	// Read the prefixes from the JSON output of another command or the store
+	var local []ipam.Entry
+	if fromJSON := viper.GetString("ipam.push.from-json"); fromJSON != "" {
+		if local, err = readIPAMJSON(fromJSON, os.Stdin); err != nil {
+			return err
+		}
+	} else {
+		store, _, err := loadIPAM()
+		if err != nil {
+			return err
+		}
+		local = store.Entries
+	}
+
+	ctx := context.Background()
+	remote, err := backend.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	if within.IsValid() {
+		local, remote = ipam.Within(local, within), ipam.Within(remote, within)
+	}
+
+	changes := ipam.PushChanges(local, remote, backend.Normalize)
+	printSyncChanges(out, changes)
+	summary := syncSummary(changes, len(local))
+
+	// Make the changes (unless it is a dry run), reporting the ones that failed
+	var failed int
+	if viper.GetBool("ipam.push.dry-run") {
+		fmt.Fprintf(out, "Dry run, nothing pushed to %s (%s)\n", url, summary)
+	} else {
+		for _, c := range changes {
+			if err := backend.Put(ctx, *c.After); err != nil {
+				fmt.Fprintf(out, "error: %s: %v\n", c.Prefix, err)
+				failed++
+			}
+		}
+		fmt.Fprintf(out, "Pushed %d change(s) to %s (%s)\n", len(changes)-failed, url, summary)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if failed > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d of %d change(s) failed", failed, len(changes)))
+	}
+	return nil
+}
+
+// ipamPullAction is the action function for the ipam pull command
+func ipamPullAction(out io.Writer, args []string) error {
+	within, err := parseSyncScope(args)
+	if err != nil {
+		return err
+	}
+	backend, url, err := getIPAMBackend("ipam.pull")
+	if err != nil {
+		return err
+	}
+	store, path, err := loadIPAM()
+	if err != nil {
+		return err
+	}
+
+	remote, err := backend.Entries(context.Background())
+	if err != nil {
+		return err
+	}
+	local := store.Entries
+	if within.IsValid() {
+		local, remote = ipam.Within(local, within), ipam.Within(remote, within)
+	}
+
+	changes := ipam.PullChanges(local, remote, backend.Normalize)
+	printSyncChanges(out, changes)
+	summary := syncSummary(changes, len(remote))
+	if viper.GetBool("ipam.pull.dry-run") {
+		fmt.Fprintf(out, "Dry run, nothing pulled into %s (%s)\n", path, summary)
+	} else {
+		for _, c := range changes {
+			store.Add(*c.After, true)
+		}
+		if err := store.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Pulled %d change(s) from %s into %s (%s)\n", len(changes), url, path, summary)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// parseSyncScope is a function that parses the optional prefix argument of
+// the ipam push and pull commands, the zero prefix means all prefixes
+func parseSyncScope(args []string) (netip.Prefix, error) {
+	if len(args) == 0 {
+		return netip.Prefix{}, nil
+	}
+	prefix, err := parseIPAMPrefix(strings.Join(args, " "))
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.MustParsePrefix(prefix), nil
+}
+
+// getIPAMBackend is a function that returns the backend selected with the
+// --backend, --url and --token flags of the command (e.g. ipam.push), the
+// URL and the token fall back to the ipam.url and ipam.token keys
+func getIPAMBackend(command string) (ipam.Backend, string, error) {
+	url := viper.GetString(command + ".url")
+	if url == "" {
+		url = viper.GetString("ipam.url")
+	}
+	if url == "" {
+		return nil, "", exitcode.New(exitcode.Usage, errors.New("no URL specified (use --url or the ipam.url key in the config file)"))
+	}
+	token := viper.GetString(command + ".token")
+	if token == "" {
+		token = viper.GetString("ipam.token")
+	}
+
+	backend, err := ipam.NewBackend(viper.GetString(command+".backend"), url, token)
+	if err != nil {
+		return nil, "", exitcode.New(exitcode.Usage, err)
+	}
+	return backend, url, nil
+}
+
+// readIPAMJSON is a function that reads the prefixes of the JSON output of
+// another command (- for standard input). The name of a prefix is taken
+// from the name or device field of its record.
+func readIPAMJSON(name string, stdin io.Reader) ([]ipam.Entry, error) {
+	r := stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	store := &ipam.Store{}
+	err := envelope.Read(r, func(record envelope.Record) error {
+		prefix, _, err := ipam.NormalizePrefix(record.Target)
+		if err != nil {
+			return err
+		}
+
+		// Records without an object as data have no name
+		var data struct {
+			Name        string `json:"name"`
+			Device      string `json:"device"`
+			Description string `json:"description"`
+			VLAN        int    `json:"vlan"`
+		}
+		json.Unmarshal(record.Data, &data)
+		if data.Name == "" {
+			data.Name = data.Device
+		}
+
+		_, err = store.Add(ipam.Entry{Prefix: prefix.String(), Name: data.Name, Description: data.Description, VLAN: data.VLAN}, true)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	store.Sort()
+	return store.Entries, nil
+}
+
+// printSyncChanges is a function that prints the changes of a push or a
+// pull, one change per line
+func printSyncChanges(out io.Writer, changes []ipam.Change) {
+	prefixWidth := len("Prefix")
+	for _, c := range changes {
+		prefixWidth = max(prefixWidth, len(c.Prefix))
+	}
+	fmtString := fmt.Sprintf("%%-6s  %%-%ds  %%s\n", prefixWidth)
+	for _, c := range changes {
+		fmt.Fprintf(out, fmtString, c.Action, c.Prefix, c.Summary())
+	}
+}
+
+// syncSummary is a function that returns the number of added, updated and
+// unchanged prefixes of a push or a pull of total prefixes
+func syncSummary(changes []ipam.Change, total int) string {
+	var added, updated int
+	for _, c := range changes {
+		if c.Action == ipam.ActionAdd {
+			added++
+		} else {
+			updated++
+		}
+	}
+	return fmt.Sprintf("%d added, %d updated, %d unchanged", added, updated, total-added-updated)
+}
+
+// addIPAMSyncFlags is a function that adds the flags selecting the backend
+// to the ipam push and pull commands and binds them to the configuration of
+// the command, e.g. ipam.push.url
+func addIPAMSyncFlags(cmd *cobra.Command, command string) {
+	cmd.Flags().String("backend", ipam.BackendNetBox, "backend to synchronize with: "+strings.Join(ipam.Backends, ", "))
+	viper.BindPFlag(command+".backend", cmd.Flags().Lookup("backend"))
+	cmd.RegisterFlagCompletionFunc("backend", completeValues(ipam.Backends...))
+	cmd.Flags().String("url", "", "URL of the backend, e.g. https://netbox.example.com")
+	viper.BindPFlag(command+".url", cmd.Flags().Lookup("url"))
+	cmd.Flags().String("token", "", "API token of the backend (or set IPTOOL_IPAM_TOKEN)")
+	viper.BindPFlag(command+".token", cmd.Flags().Lookup("token"))
+	cmd.Flags().BoolP("dry-run", "n", false, "print the changes without making them")
+	viper.BindPFlag(command+".dry-run", cmd.Flags().Lookup("dry-run"))
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamPushCmd)
+	ipamCmd.AddCommand(ipamPullCmd)
+
+	// Define the flags for selecting the backend
+	addIPAMSyncFlags(ipamPushCmd, "ipam.push")
+	addIPAMSyncFlags(ipamPullCmd, "ipam.pull")
+
+	// Define the flag for pushing the JSON output of another command
+	ipamPushCmd.Flags().String("from-json", "", "push the prefixes in the JSON output of another command (- for standard input)")
+	viper.BindPFlag("ipam.push.from-json", ipamPushCmd.Flags().Lookup("from-json"))
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// The iptool-helper command performs the few operations of iptool that
+// require raw socket privileges. It is meant to be installed setuid root or
+// with the CAP_NET_RAW capability, and is started by iptool when needed:
+//
+//	sudo setcap cap_net_raw+ep iptool-helper
+//
+// The helper reads a single JSON request on stdin and writes a single JSON
+// response on stdout. Privileges are dropped as soon as the raw socket has
+// been opened (see privsep.DropPrivileges).
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitcanon/iptool/discovery"
+	"github.com/bitcanon/iptool/ndp"
+	"github.com/bitcanon/iptool/privsep"
+)
+
+// handlers maps every supported operation to its implementation
+var handlers = map[string]privsep.Handler{
+	privsep.OpEchoAllNodes:     echoAllNodes,
+	privsep.OpCaptureDiscovery: captureDiscovery,
+}
+
+// echoAllNodes opens a raw ICMPv6 socket, drops the privileges and sends an
+// echo request to the all-nodes multicast address on the requested interface
+// (from the requested source address, if any)
+func echoAllNodes(req privsep.Request) (*privsep.Response, error) {
+	source, err := req.SourceAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ndp.ListenICMPv6(source)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// The socket is open, the privileges are not needed anymore
+	if err := privsep.DropPrivileges(); err != nil {
+		return nil, err
+	}
+
+	replies, err := ndp.EchoAllNodes(conn, req.Interface, req.Timeout())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &privsep.Response{}
+	for _, addr := range replies {
+		resp.Addresses = append(resp.Addresses, addr.String())
+	}
+	return resp, nil
+}
+
+// captureDiscovery opens a raw packet socket on the requested interface,
+// drops the privileges and returns the LLDP and CDP frames received on it.
+// Only the discovery frames are returned, never other traffic.
+func captureDiscovery(req privsep.Request) (*privsep.Response, error) {
+	sock, err := discovery.Listen(req.Interface)
+	if err != nil {
+		return nil, err
+	}
+	defer sock.Close()
+
+	// The socket is open, the privileges are not needed anymore
+	if err := privsep.DropPrivileges(); err != nil {
+		return nil, err
+	}
+
+	frames, err := sock.ReadFrames(req.Timeout(), req.Count)
+	if err != nil {
+		return nil, err
+	}
+	return &privsep.Response{Frames: frames}, nil
+}
+
+func main() {
+	if err := privsep.Serve(os.Stdin, os.Stdout, handlers); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
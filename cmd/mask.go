@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maskCmd represents the mask command
+var maskCmd = &cobra.Command{
+	Use:   "mask",
+	Short: "Compare and invert IPv4 masks",
+	Long: `Compare and invert IPv4 masks.
+
+The mask commands accept masks in any of the common notations: a prefix
+length (/24 or 24), a dotted-decimal netmask (255.255.255.0) or wildcard
+mask (0.0.0.255), or hexadecimal (ffffff00, with or without 0x). Use
+iptool convert mask to print a mask in all notations.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// parseMaskArg is a function that parses a mask given as an argument and
+// returns the mask and the notation it was given in. Discontiguous masks are
+// only accepted if the --allow-discontiguous flag is set.
+func parseMaskArg(s string) (ip.Mask, ip.MaskFormat, error) {
+	mask, format, err := ip.ParseMaskFormat(s)
+	if err != nil {
+		return 0, "", exitcode.New(exitcode.Usage, err)
+	}
+	if !mask.Contiguous() && !viper.GetBool("allow-discontiguous") {
+		return 0, "", exitcode.New(exitcode.Usage, fmt.Errorf("%w: %s (use --allow-discontiguous to accept it anyway)", ip.ErrDiscontiguousNetmask, s))
+	}
+	return mask, format, nil
+}
+
+func init() {
+	rootCmd.AddCommand(maskCmd)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maskCompareCmd represents the mask compare command
+var maskCompareCmd = &cobra.Command{
+	Use:   "compare <mask> <mask...>",
+	Short: "Verify that masks in different notations are equivalent",
+	Long: `Verify that masks in different notations are equivalent.
+
+Every mask is printed with the notation it was given in and its prefix
+length, netmask, wildcard mask and hexadecimal form, followed by the verdict.
+A wildcard mask is equivalent to the netmask it is the inverse of, so
+0.0.15.255 is equivalent to 255.255.240.0 and /20. The command exits with
+exit code 5 if the masks are not equivalent, which makes it usable in scripts
+that check device configurations.
+
+Examples:
+  iptool mask compare 255.255.240.0 /20
+  iptool mask compare /22 0.0.3.255 0xfffffc00
+  iptool mask compare 255.255.255.0 /23`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return maskCompareAction(os.Stdout, args)
+	},
+}
+
+// maskCompareAction is the action function for the mask compare command
+func maskCompareAction(out io.Writer, args []string) error {
+	// The masks may also be separated by commas
+	var inputs []string
+	for _, arg := range resolveAliases(args) {
+		inputs = append(inputs, strings.FieldsFunc(arg, func(r rune) bool { return r == ',' })...)
+	}
+	if len(inputs) < 2 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("at least two masks are needed for a comparison"))
+	}
+
+	masks := make([]ip.Mask, len(inputs))
+	formats := make([]ip.MaskFormat, len(inputs))
+	for i, input := range inputs {
+		mask, format, err := parseMaskArg(input)
+		if err != nil {
+			return err
+		}
+		masks[i], formats[i] = mask, format
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	table := render.NewTable(out, getRenderOptions("mask.compare", out),
+		render.Column{Title: "Mask"},
+		render.Column{Title: "Notation"},
+		render.Column{Title: "Prefix", Align: render.AlignRight},
+		render.Column{Title: "Netmask"},
+		render.Column{Title: "Wildcard"},
+		render.Column{Title: "Hex"},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	rows := make([][]string, len(masks))
+	for i, mask := range masks {
+		prefix := fmt.Sprintf("/%d", mask.PrefixLength())
+		if !mask.Contiguous() {
+			prefix = "n/a"
+		}
+		rows[i] = []string{strings.TrimSpace(inputs[i]), string(formats[i]), prefix, mask.Netmask(), mask.Wildcard(), mask.Hex()}
+		table.Fit(rows[i]...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+
+	// Compare every mask with the first mask
+	different := 0
+	for _, mask := range masks[1:] {
+		if mask != masks[0] {
+			different++
+		}
+	}
+	if different > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("the masks are not equivalent (%d of %d differ from %s)", different, len(masks)-1, strings.TrimSpace(inputs[0])))
+	}
+	if render.Format(strings.ToLower(viper.GetString("format"))) == render.FormatTable {
+		fmt.Fprintf(out, "The masks are equivalent (%s)\n", masks[0].Netmask())
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	maskCmd.AddCommand(maskCompareCmd)
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(maskCompareCmd, "mask.compare")
+}
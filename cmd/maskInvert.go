@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maskInvertCmd represents the mask invert command
+var maskInvertCmd = &cobra.Command{
+	Use:   "invert <mask...>",
+	Short: "Invert a netmask to a wildcard mask and back",
+	Long: `Invert a netmask to a wildcard mask and back.
+
+Every bit of the mask is flipped: a netmask becomes the wildcard mask used in
+access control lists and OSPF network statements, and a wildcard mask becomes
+the netmask. A prefix length is inverted to a wildcard mask, and a
+hexadecimal netmask to a hexadecimal wildcard mask. One inverted mask is
+printed per line.
+
+Use --verbose to also print the notation of the input and the prefix length.
+
+Examples:
+  iptool mask invert 0.0.7.255
+  iptool mask invert 255.255.240.0
+  iptool mask invert /22 /24 --verbose`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return maskInvertAction(os.Stdout, args)
+	},
+}
+
+// maskInvertAction is the action function for the mask invert command
+func maskInvertAction(out io.Writer, args []string) error {
+	var lines []string
+	for _, arg := range resolveAliases(args) {
+		for _, input := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' }) {
+			mask, format, err := parseMaskArg(input)
+			if err != nil {
+				return err
+			}
+
+			// The netmask of a wildcard mask, the wildcard mask of the others
+			inverted := mask.Wildcard()
+			switch format {
+			case ip.MaskFormatWildcard:
+				inverted = mask.Netmask()
+			case ip.MaskFormatHex:
+				inverted = mask.Invert().Hex()
+			}
+
+			if viper.GetBool("mask.invert.verbose") {
+				inverted = fmt.Sprintf("%s (%s) -> %s (/%d)", strings.TrimSpace(input), format, inverted, mask.PrefixLength())
+			}
+			lines = append(lines, inverted)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	maskCmd.AddCommand(maskInvertCmd)
+
+	// Enable the --verbose flag to print the notation and prefix length
+	maskInvertCmd.Flags().BoolP("verbose", "v", false, "also print the notation of the input and the prefix length")
+	viper.BindPFlag("mask.invert.verbose", maskInvertCmd.Flags().Lookup("verbose"))
+}
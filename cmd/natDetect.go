@@ -0,0 +1,249 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/probe"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/stun"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// natDetectCmd represents the nat detect command
+var natDetectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect the NAT type and the public address with STUN",
+	Long: `Detect the NAT type and the public address with STUN.
+
+Binding requests are sent from a single UDP port to the STUN servers, which
+report the public (mapped) address and port the requests came from. The NAT
+is classified with the tests of RFC 3489 and RFC 5780:
+
+  Open internet           no NAT and no firewall
+  Symmetric UDP firewall  no NAT, but only responses are let in
+  Full cone               anyone can send to the mapped address
+  Restricted cone         the hosts that were sent to can send back
+  Port restricted cone    the address and port that were sent to can send back
+  Symmetric               every destination gets its own mapping
+
+The filtering tests ask the server to respond from its other address and
+port, which only servers that support RFC 5780 (or RFC 3489) do. With other
+servers (e.g. Google's) the mapping is tested by comparing the mapped
+addresses reported by the first two servers, and the filtering is unknown.
+
+Use --lifetime to measure how long the NAT keeps an idle mapping: requests
+are sent after idle periods of 5 seconds, doubling up to the given number of
+seconds, until the mapped address changes. This tells how often VoIP and
+WebRTC clients must send keepalives.
+
+Servers are given as host or host:port (port 3478 by default). The command
+exits with exit code 3 if no server responds (UDP is blocked).
+
+Examples:
+  iptool nat detect
+  iptool nat detect --stun stun.l.google.com:19302
+  iptool nat detect --stun stun.example.com,stun2.example.com --local-port 5060
+  iptool nat detect --lifetime 320 --json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+		return natDetectAction(os.Stdout)
+	},
+}
+
+// natTypeNotes explain what the NAT types mean for peer-to-peer traffic
+var natTypeNotes = map[stun.Type]string{
+	stun.TypeBlocked:            "UDP is blocked, VoIP and WebRTC need a TCP or TLS relay",
+	stun.TypeSymmetricFirewall:  "peers can only reach this host after it sent to them",
+	stun.TypeRestrictedCone:     "peer-to-peer connections work with hole punching (ICE)",
+	stun.TypePortRestrictedCone: "peer-to-peer connections work with hole punching (ICE), except with symmetric NATs",
+	stun.TypeSymmetric:          "peer-to-peer connections usually fail, WebRTC needs a TURN relay",
+	stun.TypeCone:               "peer-to-peer connections work with hole punching (ICE) in most cases",
+}
+
+// natDetectJSON is the JSON output of the nat detect command
+type natDetectJSON struct {
+	stun.Result
+	LifetimeSeconds *float64 `json:"lifetime_seconds,omitempty"`
+	ExpiredSeconds  *float64 `json:"expired_seconds,omitempty"`
+}
+
+// natDetectAction is the action function for the nat detect command
+func natDetectAction(out io.Writer) error {
+	timeout := viper.GetDuration("nat.detect.timeout") * time.Millisecond
+	if timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid timeout: %d (must be greater than 0)", viper.GetInt("nat.detect.timeout")))
+	}
+	lifetime := viper.GetDuration("nat.detect.lifetime") * time.Second
+	if lifetime < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid lifetime: %d (must not be negative)", viper.GetInt("nat.detect.lifetime")))
+	}
+
+	// Resolve the STUN servers, NAT is an IPv4 matter
+	names := resolveAliases(viper.GetStringSlice("nat.detect.stun"))
+	if len(names) == 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no STUN servers given, use --stun"))
+	}
+	servers := make([]netip.AddrPort, len(names))
+	for i, name := range names {
+		host, port, err := probe.SplitHostPort(name, 3478)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		addrs, err := ip.ResolveAddrs(host, ip.FamilyIPv4)
+		if err != nil {
+			return err
+		}
+		if len(addrs) == 0 {
+			return fmt.Errorf("%s has no IPv4 address", host)
+		}
+		servers[i] = netip.AddrPortFrom(addrs[0], uint16(port))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Send all requests from the same port, the NAT keeps one mapping for it
+	localPort := viper.GetInt("nat.detect.local-port")
+	conn, err := net.ListenPacket("udp4", net.JoinHostPort("", strconv.Itoa(localPort)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	result, err := stun.Detect(conn, servers, timeout)
+	if err != nil {
+		return err
+	}
+	output := natDetectJSON{Result: result}
+
+	// Measure the lifetime of the mapping, Ctrl-C stops the measurement
+	var lifetimeErr error
+	if lifetime > 0 && result.Mapped.IsValid() {
+		ctx, stop := ratelimit.InterruptContext(context.Background())
+		progress := func(idle time.Duration) {
+			if !viper.GetBool("nat.detect.json") {
+				fmt.Fprintf(os.Stderr, "Waiting %s before the next request...\n", idle)
+			}
+		}
+		survived, expired, err := stun.Lifetime(ctx, conn, servers[0], result.Mapped, 5*time.Second, lifetime, timeout, progress)
+		stop()
+		seconds := survived.Seconds()
+		output.LifetimeSeconds = &seconds
+		if expired > 0 {
+			seconds := expired.Seconds()
+			output.ExpiredSeconds = &seconds
+		}
+		lifetimeErr = err
+	}
+
+	if viper.GetBool("nat.detect.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			return err
+		}
+	} else {
+		printNATDetect(out, names[0], output)
+	}
+
+	if lifetimeErr != nil && !errors.Is(lifetimeErr, context.Canceled) {
+		return fmt.Errorf("lifetime measurement stopped: %w", lifetimeErr)
+	}
+	if result.Type == stun.TypeBlocked {
+		return exitcode.New(exitcode.Unreachable, errors.New("no response from the STUN server (UDP blocked)"))
+	}
+	return nil
+}
+
+// printNATDetect is a function that prints the result of the NAT detection
+func printNATDetect(out io.Writer, name string, output natDetectJSON) {
+	result := output.Result
+	fmt.Fprintf(out, "NAT type:         %s\n", result.Type)
+	if note, ok := natTypeNotes[result.Type]; ok {
+		fmt.Fprintf(out, "                  (%s)\n", note)
+	}
+	fmt.Fprintf(out, "Local address:    %s\n", result.Local)
+	if result.Mapped.IsValid() {
+		fmt.Fprintf(out, "Mapped address:   %s\n", result.Mapped)
+	}
+	fmt.Fprintf(out, "Mapping:          %s\n", result.Mapping)
+	fmt.Fprintf(out, "Filtering:        %s\n", result.Filtering)
+	fmt.Fprintf(out, "STUN server:      %s (%s)\n", name, result.Server)
+	if result.Other.IsValid() {
+		fmt.Fprintf(out, "Other address:    %s\n", result.Other)
+	}
+	if output.LifetimeSeconds != nil {
+		survived := time.Duration(*output.LifetimeSeconds * float64(time.Second))
+		if output.ExpiredSeconds != nil {
+			expired := time.Duration(*output.ExpiredSeconds * float64(time.Second))
+			fmt.Fprintf(out, "Mapping lifetime: %s to %s (the mapping changed after %s idle)\n", survived, expired, expired)
+		} else {
+			fmt.Fprintf(out, "Mapping lifetime: at least %s\n", survived)
+		}
+	}
+}
+
+// init registers the command and flags
+func init() {
+	natCmd.AddCommand(natDetectCmd)
+
+	// Define the flag for the STUN servers
+	natDetectCmd.Flags().StringSliceP("stun", "s", []string{"stun.l.google.com:19302", "stun1.l.google.com:19302"}, "STUN servers (host or host:port), the second server is used for the mapping test")
+	viper.BindPFlag("nat.detect.stun", natDetectCmd.Flags().Lookup("stun"))
+
+	// Define the flag for the local port to send from
+	natDetectCmd.Flags().IntP("local-port", "p", 0, "local UDP port to send from (default a random port)")
+	viper.BindPFlag("nat.detect.local-port", natDetectCmd.Flags().Lookup("local-port"))
+
+	// Define the flag for the time to wait for a response
+	natDetectCmd.Flags().IntP("timeout", "t", 1000, "time to wait for a response, in milliseconds")
+	viper.BindPFlag("nat.detect.timeout", natDetectCmd.Flags().Lookup("timeout"))
+
+	// Define the flag for measuring the lifetime of the mapping
+	natDetectCmd.Flags().Int("lifetime", 0, "measure the lifetime of an idle mapping, up to this many seconds")
+	viper.BindPFlag("nat.detect.lifetime", natDetectCmd.Flags().Lookup("lifetime"))
+
+	// Enable the --json flag to print the results in JSON format
+	natDetectCmd.Flags().Bool("json", false, "print the results in JSON format")
+	viper.BindPFlag("nat.detect.json", natDetectCmd.Flags().Lookup("json"))
+}
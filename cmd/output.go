@@ -0,0 +1,145 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addMaxSizeFlag is a function that adds the --max-size flag to a command and
+// binds it to the configuration of the command, e.g. sweep.max-size
+func addMaxSizeFlag(cmd *cobra.Command, command string) {
+	cmd.Flags().String("max-size", "", "rotate the output file when it grows beyond this size, e.g. 100MB (default never)")
+	viper.BindPFlag(command+".max-size", cmd.Flags().Lookup("max-size"))
+}
+
+// addTeeFlag is a function that adds the --tee flag to a command and binds it
+// to the configuration of the command, e.g. sweep.tee
+func addTeeFlag(cmd *cobra.Command, command string) {
+	cmd.Flags().Bool("tee", false, "print the output to standard output as well as to the output file")
+	viper.BindPFlag(command+".tee", cmd.Flags().Lookup("tee"))
+}
+
+// getOutputWriter is a function that returns the writer for the output of a
+// command, writing to the file selected with the --output-file flag (or to
+// standard output) and rotating it as selected with the --max-size flag.
+// With the --tee flag, the output is written to out as well.
+func getOutputWriter(command string, filename string, out io.Writer, opts utils.OutputOptions) (*utils.OutputWriter, error) {
+	if size := viper.GetString(command + ".max-size"); size != "" {
+		maxSize, err := utils.ParseBytes(size)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		if maxSize < 1 || filename == "" {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("--max-size requires --output-file and a size of at least 1 byte"))
+		}
+		opts.MaxSize = maxSize
+	}
+	if viper.GetBool(command + ".tee") {
+		opts.Tee = out
+	}
+	return utils.NewOutputWriter(filename, opts)
+}
+
+// globalOutput is the state of the redirection of standard output to the
+// file selected with the global --output flag
+var globalOutput struct {
+	writer *utils.OutputWriter
+	stdout *os.File   // The standard output replaced by the pipe
+	pipe   *os.File   // The write end of the pipe replacing standard output
+	done   chan error // Receives the result of copying the pipe to the file
+}
+
+// startGlobalOutput is a function that validates the global --format flag
+// and redirects standard output to the file selected with the global
+// --output flag (appending to it with --append), so that every command can
+// write its output to a file. The file is written with the same writer as
+// the --output-file flags of the commands, e.g. files ending with .gz are
+// compressed. The global flags are rejected on the commands that would
+// ignore them: --format on commands that do not print tables, and --output
+// together with the --output-file flag of a command.
+func startGlobalOutput(cmd *cobra.Command) error {
+	if format := strings.ToLower(viper.GetString("format")); format != "" && !slices.Contains(render.Formats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(render.Formats, ", ")))
+	}
+	if flag := cmd.Flags().Lookup("format"); flag != nil && flag == cmd.Root().PersistentFlags().Lookup("format") && flag.Changed && !renderCommands[cmd] {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid flag: --format is not supported by %s (it does not print tables)", cmd.CommandPath()))
+	}
+	if cmd.Flags().Changed("output") && cmd.Flags().Changed("output-file") {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid flags: --output cannot be combined with --output-file"))
+	}
+
+	filename := viper.GetString("output")
+	if filename == "" || globalOutput.writer != nil {
+		return nil
+	}
+	writer, err := utils.NewOutputWriter(filename, utils.OutputOptions{Append: viper.GetBool("append")})
+	if err != nil {
+		return err
+	}
+
+	// Replace standard output with a pipe that is copied to the file, the
+	// commands look up os.Stdout when they run
+	r, w, err := os.Pipe()
+	if err != nil {
+		writer.Close()
+		return err
+	}
+	globalOutput.writer = writer
+	globalOutput.stdout = os.Stdout
+	globalOutput.pipe = w
+	globalOutput.done = make(chan error, 1)
+	go func() {
+		_, err := io.Copy(writer, r)
+		r.Close()
+		globalOutput.done <- err
+	}()
+	os.Stdout = w
+	return nil
+}
+
+// stopGlobalOutput is a function that restores standard output and writes
+// the rest of the output to the file selected with the global --output flag.
+// It has no effect if standard output is not redirected.
+func stopGlobalOutput() error {
+	if globalOutput.writer == nil {
+		return nil
+	}
+	os.Stdout = globalOutput.stdout
+	globalOutput.pipe.Close()
+	err := <-globalOutput.done
+	if closeErr := globalOutput.writer.Close(); err == nil {
+		err = closeErr
+	}
+	globalOutput.writer = nil
+	return err
+}
@@ -0,0 +1,295 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/pcap"
+	"github.com/bitcanon/iptool/port"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pcapSummarizeCmd represents the pcap summarize command
+var pcapSummarizeCmd = &cobra.Command{
+	Use:   "summarize <file>",
+	Short: "Summarize the traffic in a packet capture",
+	Long: `Summarize the traffic in a packet capture.
+
+The packets of a capture file (pcap or pcapng) are decoded and summarized:
+the protocols, the top talkers (with the type of every address), the top
+service ports, the top conversations and the traffic per subnet. Everything
+is ranked by bytes, and --top sets the number of rows of every table (0 for
+all rows). Use - to read the capture from standard input.
+
+The addresses are grouped into the subnets given with --subnets (the most
+specific subnet that contains the address), and otherwise into subnets of
+--group bits for IPv4 (24 by default) and --group6 bits for IPv6 (64 by
+default). The service port of a TCP or UDP conversation is the lower of its
+two ports, since clients use ephemeral ports.
+
+Examples:
+  iptool pcap summarize capture.pcap
+  iptool pcap summarize capture.pcapng --top 20
+  iptool pcap summarize capture.pcap --subnets 10.1.0.0/16,10.2.0.0/16 --group 16
+  tcpdump -i eth0 -c 1000 -w - | iptool pcap summarize -
+  iptool pcap summarize capture.pcap --json`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Validate the number of rows and the prefix lengths of the subnets
+		if top := viper.GetInt("pcap.summarize.top"); top < 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --top value: %d (must be 0 or greater)", top))
+		}
+		if bits := viper.GetInt("pcap.summarize.group"); bits < 0 || bits > 32 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --group value: %d (must be between 0 and 32)", bits))
+		}
+		if bits := viper.GetInt("pcap.summarize.group6"); bits < 0 || bits > 128 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --group6 value: %d (must be between 0 and 128)", bits))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no file is provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return pcapSummarizeAction(os.Stdout, os.Stdin, args[0])
+	},
+}
+
+// pcapCountJSON is the JSON representation of a ranked entry of a summary
+type pcapCountJSON struct {
+	Key     string `json:"key"`
+	Type    string `json:"type,omitempty"`
+	Service string `json:"service,omitempty"`
+	Packets int    `json:"packets"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// pcapSummaryJSON is the JSON representation of the summary of a capture
+type pcapSummaryJSON struct {
+	Packets       int             `json:"packets"`
+	Bytes         int64           `json:"bytes"`
+	NonIP         int             `json:"non_ip"`
+	First         string          `json:"first,omitempty"`
+	Last          string          `json:"last,omitempty"`
+	Protocols     []pcapCountJSON `json:"protocols"`
+	Talkers       []pcapCountJSON `json:"talkers"`
+	Ports         []pcapCountJSON `json:"ports"`
+	Conversations []pcapCountJSON `json:"conversations"`
+	Subnets       []pcapCountJSON `json:"subnets"`
+}
+
+// pcapGroup is a function that returns the function that groups the
+// addresses of a capture into the given subnets (the most specific subnet
+// that contains the address) or into subnets of the given prefix lengths
+func pcapGroup(subnets []netip.Prefix, bits4, bits6 int) func(netip.Addr) netip.Prefix {
+	return func(addr netip.Addr) netip.Prefix {
+		addr = addr.Unmap()
+		best := netip.Prefix{}
+		for _, subnet := range subnets {
+			if subnet.Contains(addr) && (!best.IsValid() || subnet.Bits() > best.Bits()) {
+				best = subnet
+			}
+		}
+		if best.IsValid() {
+			return best
+		}
+		if addr.Is4() {
+			return netip.PrefixFrom(addr, bits4).Masked()
+		}
+		return netip.PrefixFrom(addr, bits6).Masked()
+	}
+}
+
+// pcapService is a function that returns the name of the service of a
+// port, or an empty string if the port is not in the ports database
+func pcapService(p pcap.Port) string {
+	services, err := port.Lookup(strconv.Itoa(int(p.Number)), pcap.ProtocolName(p.Protocol))
+	if err != nil || len(services) == 0 {
+		return ""
+	}
+	return services[0].Name
+}
+
+// pcapSummarizeAction is the action function for the pcap summarize command
+func pcapSummarizeAction(out io.Writer, stdin io.Reader, file string) error {
+	// Parse the subnets to group the addresses into
+	var subnets []netip.Prefix
+	for _, s := range viper.GetStringSlice("pcap.summarize.subnets") {
+		subnet, err := ip.ParsePrefix(s)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	group := pcapGroup(subnets, viper.GetInt("pcap.summarize.group"), viper.GetInt("pcap.summarize.group6"))
+
+	// Read the capture from the file or standard input
+	var in io.Reader = stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	summary, err := pcap.Summarize(in, group)
+	if err != nil {
+		// Summarize the packets read before a truncated end of the capture
+		if summary == nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", file, err)
+	}
+
+	// Rank the entries of the summary
+	top := viper.GetInt("pcap.summarize.top")
+	var result pcapSummaryJSON
+	result.Packets, result.Bytes, result.NonIP = summary.Packets, summary.Bytes, summary.NonIP
+	if !summary.First.IsZero() {
+		result.First, result.Last = utils.FormatTimestamp(summary.First), utils.FormatTimestamp(summary.Last)
+	}
+	for _, r := range pcap.Top(summary.Protocols, 0) {
+		result.Protocols = append(result.Protocols, pcapCountJSON{Key: r.Key, Packets: r.Packets, Bytes: r.Bytes})
+	}
+	for _, r := range pcap.Top(summary.Talkers, top) {
+		result.Talkers = append(result.Talkers, pcapCountJSON{Key: r.Key.String(), Type: ip.Classify(r.Key), Packets: r.Packets, Bytes: r.Bytes})
+	}
+	for _, r := range pcap.Top(summary.Ports, top) {
+		result.Ports = append(result.Ports, pcapCountJSON{Key: r.Key.String(), Service: pcapService(r.Key), Packets: r.Packets, Bytes: r.Bytes})
+	}
+	for _, r := range pcap.Top(summary.Conversations, top) {
+		result.Conversations = append(result.Conversations, pcapCountJSON{Key: r.Key.String(), Packets: r.Packets, Bytes: r.Bytes})
+	}
+	for _, r := range pcap.Top(summary.Subnets, top) {
+		result.Subnets = append(result.Subnets, pcapCountJSON{Key: r.Key.String(), Type: ip.Classify(r.Key.Addr()), Packets: r.Packets, Bytes: r.Bytes})
+	}
+
+	if viper.GetBool("pcap.summarize.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		writePcapSummary(out, summary, result)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// writePcapSummary is a function that prints the summary of a capture as
+// a list of tables
+func writePcapSummary(out io.Writer, summary *pcap.Summary, result pcapSummaryJSON) {
+	fmt.Fprintf(out, "Packets:  %d (%d non-IP)\n", result.Packets, result.NonIP)
+	fmt.Fprintf(out, "Bytes:    %s\n", utils.FormatBytes(result.Bytes))
+	if result.First != "" {
+		fmt.Fprintf(out, "Start:    %s\n", result.First)
+		fmt.Fprintf(out, "Duration: %s\n", summary.Last.Sub(summary.First).Round(1e6))
+	}
+
+	// share returns the share of the bytes of an entry in the capture
+	share := func(bytes int64) string {
+		if result.Bytes == 0 {
+			return "0.0%"
+		}
+		return fmt.Sprintf("%.1f%%", float64(bytes)*100/float64(result.Bytes))
+	}
+
+	// writeTable prints a section with the entries of the summary
+	writeTable := func(title string, key render.Column, extra *render.Column, entries []pcapCountJSON) {
+		fmt.Fprintf(out, "\n%s:\n", title)
+		if len(entries) == 0 {
+			fmt.Fprintln(out, "  none")
+			return
+		}
+		columns := []render.Column{key}
+		if extra != nil {
+			columns = append(columns, *extra)
+		}
+		columns = append(columns,
+			render.Column{Title: "Packets", Align: render.AlignRight},
+			render.Column{Title: "Bytes", Align: render.AlignRight},
+			render.Column{Title: "Share", Align: render.AlignRight},
+		)
+		table := render.NewTable(out, render.Options{}, columns...)
+		rows := make([][]string, len(entries))
+		for i, e := range entries {
+			rows[i] = []string{e.Key}
+			if extra != nil {
+				rows[i] = append(rows[i], e.Type+e.Service)
+			}
+			rows[i] = append(rows[i], strconv.Itoa(e.Packets), utils.FormatBytes(e.Bytes), share(e.Bytes))
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	writeTable("Protocols", render.Column{Title: "Protocol"}, nil, result.Protocols)
+	writeTable("Top talkers", render.Column{Title: "Address"}, &render.Column{Title: "Type"}, result.Talkers)
+	writeTable("Top ports", render.Column{Title: "Port"}, &render.Column{Title: "Service"}, result.Ports)
+	writeTable("Top conversations", render.Column{Title: "Conversation"}, nil, result.Conversations)
+	writeTable("Subnets", render.Column{Title: "Subnet"}, &render.Column{Title: "Type"}, result.Subnets)
+}
+
+func init() {
+	pcapCmd.AddCommand(pcapSummarizeCmd)
+
+	// Define the flag for the number of rows of every table
+	pcapSummarizeCmd.Flags().IntP("top", "n", 10, "number of talkers, ports, conversations and subnets to print (0 for all)")
+	viper.BindPFlag("pcap.summarize.top", pcapSummarizeCmd.Flags().Lookup("top"))
+
+	// Define the flags for grouping the addresses into subnets
+	pcapSummarizeCmd.Flags().StringSlice("subnets", nil, "subnets to group the addresses into (e.g. 10.1.0.0/16,10.2.0.0/16)")
+	viper.BindPFlag("pcap.summarize.subnets", pcapSummarizeCmd.Flags().Lookup("subnets"))
+	pcapSummarizeCmd.Flags().Int("group", 24, "prefix length of the subnets of IPv4 addresses outside --subnets")
+	viper.BindPFlag("pcap.summarize.group", pcapSummarizeCmd.Flags().Lookup("group"))
+	pcapSummarizeCmd.Flags().Int("group6", 64, "prefix length of the subnets of IPv6 addresses outside --subnets")
+	viper.BindPFlag("pcap.summarize.group6", pcapSummarizeCmd.Flags().Lookup("group6"))
+
+	// Define the flag for printing the summary in JSON format
+	pcapSummarizeCmd.Flags().Bool("json", false, "print the summary in JSON format")
+	viper.BindPFlag("pcap.summarize.json", pcapSummarizeCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/bitcanon/iptool/plugin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage plugins that extend iptool with new commands",
+	Long: `Manage plugins that extend iptool with new commands.
+
+A plugin is an executable named iptool-<name> in the PATH. Running
+"iptool <name> [args...]" runs the plugin with the arguments, unless <name>
+is a built-in command. The plugin contract (metadata and context passed to
+the plugin in JSON format) is described in docs/plugins.md.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// pluginListCmd represents the plugin list command
+var pluginListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the plugins found in the PATH",
+	Long: `List the plugins found in the PATH.
+
+The name, version and description of every plugin are shown, as reported by
+the plugin when it is run with the --iptool-plugin-info flag.
+
+Examples:
+  iptool plugin list
+  iptool plugin list --json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pluginListAction(os.Stdout)
+	},
+}
+
+// pluginListEntry is a plugin with its metadata, as printed by plugin list
+type pluginListEntry struct {
+	plugin.Plugin
+	Info  *plugin.Info `json:"info,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// pluginListAction is the action function for the plugin list command
+func pluginListAction(out io.Writer) error {
+	var entries []pluginListEntry
+	for _, p := range plugin.List() {
+		entry := pluginListEntry{Plugin: p}
+		if info, err := p.Info(); err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Info = &info
+		}
+		entries = append(entries, entry)
+	}
+
+	// Print the plugins in JSON format
+	if viper.GetBool("plugin.list.json") {
+		if entries == nil {
+			entries = []pluginListEntry{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "No plugins found (executables named %s<name> in the PATH)\n", plugin.Prefix)
+		return nil
+	}
+
+	// Find the length of the longest name and version (for padding)
+	nameWidth, versionWidth := len("Name"), len("Version")
+	for _, e := range entries {
+		nameWidth = max(nameWidth, len(e.Name))
+		if e.Info != nil {
+			versionWidth = max(versionWidth, len(e.Info.Version))
+		}
+	}
+
+	fmtString := fmt.Sprintf("%%-%ds  %%-%ds  %%s\n", nameWidth, versionWidth)
+	fmt.Fprintf(out, fmtString, "Name", "Version", "Description")
+	for _, e := range entries {
+		version, description := "-", "(no plugin info: "+e.Path+")"
+		if e.Info != nil {
+			version, description = e.Info.Version, e.Info.Short
+			if e.Info.APIVersion > plugin.APIVersion {
+				description += fmt.Sprintf(" (requires a newer iptool, plugin API version %d)", e.Info.APIVersion)
+			}
+		}
+		fmt.Fprintf(out, fmtString, e.Name, version, description)
+	}
+
+	return nil
+}
+
+// runPlugin is a function that runs the plugin with the given name, unless
+// the name is a built-in command. It returns the exit code of the plugin
+// and whether a plugin was run.
+func runPlugin(name string, args []string) (int, bool) {
+	// Built-in commands take precedence over plugins
+	if name == "help" {
+		return 0, false
+	}
+	if cmd, _, err := rootCmd.Find([]string{name}); err == nil && cmd != rootCmd {
+		return 0, false
+	}
+
+	p, err := plugin.Find(name)
+	if err != nil {
+		return 0, false
+	}
+
+	// Load the configuration so that the plugin knows which file is used
+	initConfig()
+	executable, _ := os.Executable()
+
+	cmd, err := p.Command(args, plugin.Context{
+		Version:    rootCmd.Version,
+		Executable: executable,
+		ConfigFile: viper.ConfigFileUsed(),
+	})
+	if err == nil {
+		err = cmd.Run()
+	}
+
+	// Pass the exit code of the plugin on to the caller
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: plugin %s: %v\n", name, err)
+		return 1, true
+	}
+	return 0, true
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+
+	// Define the flag for printing the plugins in JSON format
+	pluginListCmd.Flags().Bool("json", false, "print the plugins in JSON format")
+	viper.BindPFlag("plugin.list.json", pluginListCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/port"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// portCmd represents the port command
+var portCmd = &cobra.Command{
+	Use:   "port",
+	Short: "Look up well-known ports and service names",
+	Long: `Look up well-known ports and service names.
+
+The port command maps port numbers to service names and vice versa, using a
+database of well-known and registered ports (IANA service names) that is
+embedded in iptool, so no network access or system files are required.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portCmd)
+}
+
+// addProtocolFlags is a function that adds the --tcp and --udp protocol
+// filters to a command and binds them to the configuration of the command
+func addProtocolFlags(cmd *cobra.Command, command string) {
+	cmd.Flags().BoolP("tcp", "t", false, "only show TCP services")
+	viper.BindPFlag(command+".tcp", cmd.Flags().Lookup("tcp"))
+
+	cmd.Flags().BoolP("udp", "u", false, "only show UDP services")
+	viper.BindPFlag(command+".udp", cmd.Flags().Lookup("udp"))
+}
+
+// getProtocol is a function that returns the protocol selected with the
+// --tcp and --udp flags of a command, or an empty string for all protocols
+func getProtocol(command string) string {
+	tcp, udp := viper.GetBool(command+".tcp"), viper.GetBool(command+".udp")
+	switch {
+	case tcp && !udp:
+		return port.ProtocolTCP
+	case udp && !tcp:
+		return port.ProtocolUDP
+	default:
+		return ""
+	}
+}
+
+// writePortServices is a function that prints a list of services as a
+// table or in JSON format
+func writePortServices(out io.Writer, services []port.Service, asJSON bool) error {
+	if asJSON {
+		if services == nil {
+			services = []port.Service{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(services)
+	}
+
+	// Find the length of the longest name and aliases (for padding)
+	nameWidth, aliasWidth := len("Service"), len("Aliases")
+	for _, s := range services {
+		nameWidth = max(nameWidth, len(s.Name))
+		aliasWidth = max(aliasWidth, len(strings.Join(s.Aliases, ", ")))
+	}
+
+	fmtString := fmt.Sprintf("%%-5s  %%-5s  %%-%ds  %%-%ds  %%s\n", nameWidth, aliasWidth)
+	fmt.Fprintf(out, fmtString, "Port", "Proto", "Service", "Aliases", "Description")
+	for _, s := range services {
+		fmt.Fprintf(out, fmtString, strconv.Itoa(s.Port), s.Protocol, s.Name, strings.Join(s.Aliases, ", "), s.Description)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/port"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// portLookupCmd represents the port lookup command
+var portLookupCmd = &cobra.Command{
+	Use:   "lookup <port|range|name...>",
+	Short: "Look up the services of a port or the ports of a service",
+	Long: `Look up the services of a port or the ports of a service.
+
+A port number (443) or a range of ports (8000-8100) is mapped to the names
+of the services assigned to it, and a service name or alias (https) is
+mapped to its ports. Use --tcp or --udp to only show the services of one
+protocol. The command exits with a non-zero exit code if nothing is found.
+
+Examples:
+  iptool port lookup 443
+  iptool port lookup https
+  iptool port lookup 53 --udp
+  iptool port lookup 5060-5061 rdp
+  iptool port lookup ldap --json`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completePortArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return portLookupAction(os.Stdout, args)
+	},
+}
+
+// portLookupAction is the action function for the port lookup command
+func portLookupAction(out io.Writer, queries []string) error {
+	protocol := getProtocol("port.lookup")
+
+	// Look up every query, listing every service only once
+	var services []port.Service
+	var notFound []string
+	seen := make(map[string]bool)
+	for _, query := range queries {
+		found, err := port.Lookup(query, protocol)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		if len(found) == 0 {
+			notFound = append(notFound, query)
+		}
+		for _, s := range found {
+			if key := fmt.Sprintf("%d/%s", s.Port, s.Protocol); !seen[key] {
+				seen[key] = true
+				services = append(services, s)
+			}
+		}
+	}
+
+	if len(services) > 0 || viper.GetBool("port.lookup.json") {
+		if err := writePortServices(out, services, viper.GetBool("port.lookup.json")); err != nil {
+			return err
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if len(notFound) > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("no services found for: %v (try iptool port search)", notFound))
+	}
+	return nil
+}
+
+func init() {
+	portCmd.AddCommand(portLookupCmd)
+	addProtocolFlags(portLookupCmd, "port.lookup")
+
+	// Define the flag for printing the services in JSON format
+	portLookupCmd.Flags().Bool("json", false, "print the services in JSON format")
+	viper.BindPFlag("port.lookup.json", portLookupCmd.Flags().Lookup("json"))
+}
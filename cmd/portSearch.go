@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/port"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// portSearchCmd represents the port search command
+var portSearchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Search the services by name, alias or description",
+	Long: `Search the services by name, alias or description.
+
+All services with a name, alias or description containing the search term
+(case insensitive) are listed. Use --tcp or --udp to only show the services
+of one protocol.
+
+Examples:
+  iptool port search sql
+  iptool port search "over tls" --tcp
+  iptool port search radius --udp`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return portSearchAction(os.Stdout, strings.Join(args, " "))
+	},
+}
+
+// portSearchAction is the action function for the port search command
+func portSearchAction(out io.Writer, term string) error {
+	services := port.Search(term, getProtocol("port.search"))
+	if len(services) == 0 && !viper.GetBool("port.search.json") {
+		return fmt.Errorf("no services found matching: %s", term)
+	}
+	if err := writePortServices(out, services, viper.GetBool("port.search.json")); err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	portCmd.AddCommand(portSearchCmd)
+	addProtocolFlags(portSearchCmd, "port.search")
+
+	// Define the flag for printing the services in JSON format
+	portSearchCmd.Flags().Bool("json", false, "print the services in JSON format")
+	viper.BindPFlag("port.search.json", portSearchCmd.Flags().Lookup("json"))
+}
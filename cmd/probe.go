@@ -0,0 +1,258 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/probe"
+	"github.com/bitcanon/iptool/results"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// probeCmd represents the probe command
+var probeCmd = &cobra.Command{
+	Use:   "probe <target...>",
+	Short: "Probe a list of targets and report their status",
+	Long: `Probe a list of targets and report their status.
+
+Every target is probed --count times (all targets concurrently), and the
+number of replies, the packet loss and the response times are reported.
+
+Targets are given as <type>://<address>, where the type of probe is one of
+` + strings.Join(probe.Schemes(), ", ") + `. The type defaults to tcp and the TCP
+port to 443. Brace patterns and @<name> group references are expanded.
+
+Use --json to print the results in JSON format, e.g. for scripts.
+
+Examples:
+  iptool probe 10.0.0.1:22 https://www.example.com
+  iptool probe @dns-servers --count 5 --json`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeTargetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return probeAction(os.Stdout, args)
+	},
+}
+
+// probeSettings holds the settings of a series of probes
+type probeSettings struct {
+	count    int
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// getProbeSettings is a function that returns the probe settings of a
+// command from the configuration, e.g. probe.count
+func getProbeSettings(command string) (probeSettings, error) {
+	settings := probeSettings{
+		count:    viper.GetInt(command + ".count"),
+		interval: viper.GetDuration(command+".interval") * time.Millisecond,
+		timeout:  viper.GetDuration(command+".timeout") * time.Millisecond,
+	}
+	if settings.count < 1 || settings.interval < 0 || settings.timeout <= 0 {
+		return settings, exitcode.New(exitcode.Usage, fmt.Errorf("--count and --timeout must be greater than zero and --interval must not be negative"))
+	}
+	return settings, nil
+}
+
+// newProbers is a function that expands the targets and returns a prober for every target
+func newProbers(targets []string) ([]probe.Prober, error) {
+	expanded, err := expandTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	probers := make([]probe.Prober, 0, len(expanded))
+	for _, target := range expanded {
+		prober, err := probe.New(target)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		probers = append(probers, prober)
+	}
+	return probers, nil
+}
+
+// formatProbeResult is a function that returns a short description of a
+// probe result, e.g. "3/3 avg 1.20 ms" or "0/3 (connection refused)"
+func formatProbeResult(r probe.Result) string {
+	if !r.OK() {
+		if r.Error != "" {
+			return fmt.Sprintf("%d/%d (%s)", r.Received, r.Sent, shortError(r.Error))
+		}
+		return fmt.Sprintf("%d/%d", r.Received, r.Sent)
+	}
+	return fmt.Sprintf("%d/%d avg %.2f ms", r.Received, r.Sent, r.AvgMs)
+}
+
+// shortError is a function that returns the last part of an error message,
+// e.g. "connection refused" for "dial tcp 10.0.0.1:22: connect: connection refused"
+func shortError(msg string) string {
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		return msg[i+2:]
+	}
+	return msg
+}
+
+// probeAction is the action function for the probe command
+func probeAction(out io.Writer, args []string) error {
+	settings, err := getProbeSettings("probe")
+	if err != nil {
+		return err
+	}
+	probers, err := newProbers(args)
+	if err != nil {
+		return err
+	}
+
+	// Stop probing when the user presses Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := probe.RunAll(ctx, probers, settings.count, settings.interval, settings.timeout)
+
+	// Record the results if --record is set
+	recordProbeResults(results)
+
+	// Print the results in JSON format
+	if viper.GetBool("probe.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+		return probeError(results)
+	}
+
+	// Find the length of the longest target (for padding)
+	width := len("Target")
+	for _, r := range results {
+		width = max(width, len(r.Target))
+	}
+
+	fmt.Fprintf(out, "%-*s  %-6s %s\n", width, "Target", "Status", "Replies")
+	for _, r := range results {
+		status := "DOWN"
+		if r.OK() {
+			status = "UP"
+		}
+		fmt.Fprintf(out, "%-*s  %-6s %s\n", width, r.Target, status, formatProbeResult(r))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return probeError(results)
+}
+
+// probeError is a function that returns the error of a probe run for the
+// exit code: a partial failure if some targets are down, and a timeout or
+// unreachable error if all targets are down (a timeout if none of them
+// refused the connection or could not be reached)
+func probeError(results []probe.Result) error {
+	down, timeouts := 0, 0
+	for _, r := range results {
+		if r.OK() {
+			continue
+		}
+		down++
+		if r.Error == "" || strings.Contains(r.Error, "timeout") || strings.Contains(r.Error, "deadline exceeded") {
+			timeouts++
+		}
+	}
+
+	switch {
+	case down == 0:
+		return nil
+	case down < len(results):
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d of %d target(s) down", down, len(results)))
+	case timeouts == down:
+		return exitcode.New(exitcode.Timeout, fmt.Errorf("all %d target(s) timed out", down))
+	default:
+		return exitcode.New(exitcode.Unreachable, fmt.Errorf("all %d target(s) down", down))
+	}
+}
+
+// recordProbeResults is a function that records the results of the probes
+// in the results store if --record is set, with the average response time
+func recordProbeResults(list []probe.Result) {
+	records := make([]results.Result, len(list))
+	for i, r := range list {
+		records[i] = results.Result{Kind: results.KindProbe, Target: r.Target, Success: r.OK(), RTTMs: r.AvgMs}
+
+		// Record the host and port of the target, so that it can be found by address
+		if u, err := url.Parse(r.Target); err == nil && u.Host != "" {
+			records[i].Address = u.Hostname()
+			records[i].Port, _ = strconv.Atoi(u.Port())
+		}
+		records[i].Detail = fmt.Sprintf("%d/%d replies", r.Received, r.Sent)
+		if r.Error != "" {
+			records[i].Detail += ", " + shortError(r.Error)
+		}
+	}
+	recordResults(records...)
+}
+
+// addProbeFlags is a function that adds the flags for the probe settings to
+// a command and binds them to the configuration of the command, e.g. probe.count
+func addProbeFlags(cmd *cobra.Command, command string) {
+	// Define the flag for the number of probes per target
+	cmd.Flags().IntP("count", "c", 3, "number of probes to send to every target")
+	viper.BindPFlag(command+".count", cmd.Flags().Lookup("count"))
+
+	// Define the flag for the time between probes
+	cmd.Flags().IntP("interval", "i", 200, "time between probes of a target, in milliseconds")
+	viper.BindPFlag(command+".interval", cmd.Flags().Lookup("interval"))
+
+	// Define the flag for the probe timeout
+	cmd.Flags().IntP("timeout", "t", 1000, "time to wait for a response, in milliseconds")
+	viper.BindPFlag(command+".timeout", cmd.Flags().Lookup("timeout"))
+}
+
+func init() {
+	rootCmd.AddCommand(probeCmd)
+	addProbeFlags(probeCmd, "probe")
+
+	// Define the flag for printing the results in JSON format
+	probeCmd.Flags().Bool("json", false, "print the results in JSON format")
+	viper.BindPFlag("probe.json", probeCmd.Flags().Lookup("json"))
+}
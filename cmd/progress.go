@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/progress"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/viper"
+)
+
+// newProgressBar is a function that returns a progress bar on standard error
+// for a job of total steps (0 if not known in advance). No progress bar is
+// shown (nil is returned) if --no-progress is set, if standard error is not
+// a terminal, or if out, the output written while the job runs (nil if the
+// output is written when the job is done), goes to the same terminal.
+func newProgressBar(label string, total int, out io.Writer) *progress.Bar {
+	if viper.GetBool("no-progress") || !utils.IsTerminal(os.Stderr) {
+		return nil
+	}
+
+	// The bar would be mixed up with the output on the terminal
+	switch w := out.(type) {
+	case *os.File:
+		if utils.IsTerminal(w) {
+			return nil
+		}
+	case *utils.OutputWriter:
+		if w != nil && utils.IsTerminal(w.File()) {
+			return nil
+		}
+	}
+
+	return progress.New(os.Stderr, label, int64(total))
+}
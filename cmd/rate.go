@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addRateFlag is a function that adds the --rate flag to a command and binds
+// it to the configuration of the command, e.g. dns.bench.rate
+func addRateFlag(cmd *cobra.Command, command string) {
+	cmd.Flags().String("rate", "", "maximum number of requests, e.g. 100/s, 600/m or 3600/h (default unlimited)")
+	viper.BindPFlag(command+".rate", cmd.Flags().Lookup("rate"))
+}
+
+// addConcurrencyFlag is a function that adds the --concurrency flag to a
+// command and binds it to the configuration of the command
+func addConcurrencyFlag(cmd *cobra.Command, command string, value int) {
+	cmd.Flags().Int("concurrency", value, "maximum number of requests in flight")
+	viper.BindPFlag(command+".concurrency", cmd.Flags().Lookup("concurrency"))
+}
+
+// getRateLimiter is a function that returns the rate limiter selected with
+// the --rate flag of a command (nil if the rate is unlimited)
+func getRateLimiter(command string) (*ratelimit.Limiter, error) {
+	limiter, err := ratelimit.ParseRate(viper.GetString(command + ".rate"))
+	if err != nil {
+		return nil, exitcode.New(exitcode.Usage, err)
+	}
+	return limiter, nil
+}
+
+// getConcurrency is a function that returns the maximum number of requests
+// in flight selected with the --concurrency flag of a command
+func getConcurrency(command string) (int, error) {
+	concurrency := viper.GetInt(command + ".concurrency")
+	if concurrency < 1 {
+		return 0, exitcode.New(exitcode.Usage, fmt.Errorf("invalid concurrency: %d (must be at least 1)", concurrency))
+	}
+	return concurrency, nil
+}
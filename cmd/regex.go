@@ -0,0 +1,127 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// regexCmd represents the regex command
+var regexCmd = &cobra.Command{
+	Use:   "regex <subnet|range>",
+	Short: "Generate a regular expression matching the addresses in a subnet or range",
+	Long: `Generate a regular expression matching exactly the IPv4 addresses in a
+subnet or an arbitrary range of addresses. Handy for log filtering tools
+that only support regular expressions.
+
+The expression is generated in one of the following dialects:
+  pcre  Perl compatible regular expressions (grep -P, most languages)
+  re2   RE2 (Go, Google Cloud, ClickHouse and others)
+  ere   POSIX extended regular expressions (grep -E, awk)
+
+By default the expression matches addresses anywhere in a line of text (but
+not as a part of a longer number). Use --anchored to match the whole input.
+
+Examples:
+  iptool regex 10.0.0.0/21
+  iptool regex 10.0.0.0 255.255.248.0 --dialect ere
+  iptool regex 192.168.1.10-192.168.1.200 --anchored
+  grep -E "$(iptool regex 10.0.0.0/21 -d ere)" /var/log/syslog`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		input := strings.Join(resolveAliases(args), " ")
+
+		return regexAction(os.Stdout, input)
+	},
+}
+
+// regexAction is the action function for the regex command
+func regexAction(out io.Writer, s string) error {
+	// Parse the regular expression dialect
+	dialect, err := ip.ParseRegexDialect(viper.GetString("regex.dialect"))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Parse the input string as a range, a subnet or a single address
+	r, err := parseRegexInput(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	fmt.Fprintln(out, r.Regex(dialect, viper.GetBool("regex.anchored")))
+	return nil
+}
+
+// parseRegexInput is a function that parses a range of addresses
+// (first-last), a subnet or a single address (without a prefix length) into
+// a range of IPv4 addresses
+func parseRegexInput(s string) (*ip.IPv4Range, error) {
+	if strings.Contains(s, "-") {
+		return ip.ParseIPv4Range(strings.ReplaceAll(s, " ", ""))
+	}
+
+	// A single address is a range of one address
+	if ip.IsIPv4(s) {
+		return ip.ParseIPv4Range(s + "-" + s)
+	}
+
+	network, err := ip.ParseIPv4(s)
+	if err != nil {
+		return nil, err
+	}
+	return ip.ParseIPv4Range(network.Network() + "-" + network.Broadcast())
+}
+
+// init registers the command and flags
+func init() {
+	rootCmd.AddCommand(regexCmd)
+
+	// Define the flag for the regular expression dialect
+	regexCmd.Flags().StringP("dialect", "d", "pcre", "regular expression dialect (pcre, re2 or ere)")
+	viper.BindPFlag("regex.dialect", regexCmd.Flags().Lookup("dialect"))
+	regexCmd.RegisterFlagCompletionFunc("dialect", completeValues("pcre", "re2", "ere"))
+
+	// Enable the --anchored flag to match the whole input
+	regexCmd.Flags().BoolP("anchored", "a", false, "match the whole input (^...$)")
+	viper.BindPFlag("regex.anchored", regexCmd.Flags().Lookup("anchored"))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addRenderFlags is a function that adds the table layout flags --no-header,
+// --wide, --narrow and --columns to a command and binds them to the
+// configuration of the command, e.g. subnet.list.no-header
+func addRenderFlags(cmd *cobra.Command, command string) {
+	renderCommands[cmd] = true
+
+	cmd.Flags().Bool("no-header", false, "do not print the table header")
+	viper.BindPFlag(command+".no-header", cmd.Flags().Lookup("no-header"))
+
+	cmd.Flags().Bool("wide", false, "do not fit the table to the width of the terminal")
+	viper.BindPFlag(command+".wide", cmd.Flags().Lookup("wide"))
+
+	cmd.Flags().Bool("narrow", false, "use a compact table layout with a single space between the columns")
+	viper.BindPFlag(command+".narrow", cmd.Flags().Lookup("narrow"))
+
+	// Commands that already use --columns for their input (e.g. aggregate)
+	// print all columns
+	if cmd.Flags().Lookup("columns") != nil {
+		return
+	}
+	cmd.Flags().StringSlice("columns", nil, "columns to print, in order (e.g. cidr,mask)")
+	viper.BindPFlag(command+".columns", cmd.Flags().Lookup("columns"))
+	renderColumns[command] = true
+}
+
+// renderCommands holds the commands that print tables, which support the
+// global --format flag
+var renderCommands = make(map[*cobra.Command]bool)
+
+// renderColumns holds the commands with the --columns flag of addRenderFlags
+var renderColumns = make(map[string]bool)
+
+// getRenderOptions is a function that returns the table layout selected with
+// the --no-header, --wide, --narrow and --columns flags of a command, and the
+// format selected with the global --format flag. Tables written to a terminal are
+// fitted to its width, unless --wide is set.
+func getRenderOptions(command string, out io.Writer) render.Options {
+	opts := render.Options{
+		NoHeader: viper.GetBool(command + ".no-header"),
+		Narrow:   viper.GetBool(command + ".narrow"),
+		Format:   render.Format(strings.ToLower(viper.GetString("format"))),
+	}
+	if renderColumns[command] {
+		opts.Columns = viper.GetStringSlice(command + ".columns")
+	}
+	if f, ok := out.(*os.File); ok && !viper.GetBool(command+".wide") && utils.IsTerminal(f) {
+		opts.MaxWidth = render.TerminalWidth(f)
+	}
+	return opts
+}
@@ -22,10 +22,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"runtime"
 	"strings"
 
+	"github.com/bitcanon/iptool/cache"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -43,20 +49,60 @@ The iptool is useful when detailed information about an IP address or various
 subnetting information is required. The tool takes input from the user,
 parses the input and presents the user with detailed information.
 
+The defaults of all flags can be set per command in the configuration file
+(e.g. tcp.ping.timeout: 500), see iptool config list for the keys. A flag on
+the command line overrides an IPTOOL_ environment variable (e.g.
+IPTOOL_TCP_PING_TIMEOUT), which overrides the configuration file, which
+overrides the built-in default. Use --no-config to ignore the file.
+
+Use --output to write the output of any command to a file (--append to add
+to it) and --format csv or --format json to print the tables of the commands
+that print tables as CSV or JSON lines. Commands with their own --format flag
+use that instead, and --format is rejected by commands without tables.
+
+The exit code tells the kind of failure: 0 success, 1 error, 2 invalid
+arguments or input, 3 host or network unreachable, 4 timeout and 5 partial
+failure (some targets failed, or a check found problems). Use
+--error-format json to write errors to standard error as JSON objects with
+the message, the kind of failure and the exit code.
+
 Author: Mikael Schultz <mikael@conf-t.se>
 GitHub: https://github.com/bitcanon/iptool
 `,
-	CompletionOptions: cobra.CompletionOptions{
-		DisableDefaultCmd: true,
+	ValidArgsFunction: completePlugins,
+	SilenceErrors:     true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		return startGlobalOutput(cmd)
 	},
 }
 
+// commandStarted is set when the command starts running, errors returned
+// before that are errors of cobra about the command line (unknown commands,
+// flags and wrong numbers of arguments)
+var commandStarted bool
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// Run a plugin (iptool-<name> in the PATH) if the command is not built in
+	if len(os.Args) > 1 {
+		if code, ok := runPlugin(os.Args[1], os.Args[2:]); ok {
+			os.Exit(code)
+		}
+	}
+
 	err := rootCmd.Execute()
+	if err != nil && !commandStarted {
+		err = exitcode.New(exitcode.Usage, err)
+	}
+
+	// Write the rest of the output to the file selected with --output
+	if outputErr := stopGlobalOutput(); err == nil {
+		err = outputErr
+	}
 	if err != nil {
-		os.Exit(1)
+		exitWithError(err)
 	}
 }
 
@@ -74,13 +120,70 @@ func init() {
 	// Add flag for custom config file path
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is "+defaultConfigPath+")")
 
+	// Add persistent flag for ignoring the config file
+	rootCmd.PersistentFlags().Bool("no-config", false, "ignore the config file, only use flags, environment variables and defaults")
+	viper.BindPFlag("no-config", rootCmd.PersistentFlags().Lookup("no-config"))
+
+	// Add persistent flag for the format of error messages
+	rootCmd.PersistentFlags().String("error-format", "text", "format of error messages on standard error (text or json)")
+	viper.BindPFlag("error-format", rootCmd.PersistentFlags().Lookup("error-format"))
+	rootCmd.RegisterFlagCompletionFunc("error-format", completeValues("text", "json"))
+
+	// Mark flag parsing errors as usage errors
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitcode.New(exitcode.Usage, err)
+	})
+
 	// Add persistent flag for debug mode
 	rootCmd.PersistentFlags().Bool("debug", false, "show debug info")
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	rootCmd.PersistentFlags().Lookup("debug").Hidden = true
 
+	// Add persistent flag for accepting discontiguous (wildcard) masks
+	rootCmd.PersistentFlags().Bool("allow-discontiguous", false, "accept non-contiguous masks and treat them as ACL wildcard masks")
+	viper.BindPFlag("allow-discontiguous", rootCmd.PersistentFlags().Lookup("allow-discontiguous"))
+
+	// Add persistent flag for numeric-only operation (no forward or reverse lookups)
+	rootCmd.PersistentFlags().Bool("no-dns", false, "never resolve names, only accept numeric addresses")
+	viper.BindPFlag("no-dns", rootCmd.PersistentFlags().Lookup("no-dns"))
+
+	// Add persistent flag for bypassing the cache of external lookups
+	rootCmd.PersistentFlags().Bool("no-cache", false, "do not use cached results of external lookups (DNS, whois, ASN, GeoIP, OUI)")
+	viper.BindPFlag("no-cache", rootCmd.PersistentFlags().Lookup("no-cache"))
+
+	// Add persistent flag for hiding the progress bar of long jobs (printed on standard error)
+	rootCmd.PersistentFlags().Bool("no-progress", false, "do not show a progress bar on standard error for long jobs (sweep, tcp scan, enrich, subnet split)")
+	viper.BindPFlag("no-progress", rootCmd.PersistentFlags().Lookup("no-progress"))
+
+	// Add persistent flag for recording the results of measurements (see iptool history)
+	rootCmd.PersistentFlags().Bool("record", false, "record the results of tcp ping, probe, sweep, inspect and dashboard in the results store")
+	viper.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record"))
+
+	// Add persistent flags for the format and time zone of timestamps in outputs
+	rootCmd.PersistentFlags().String("time-format", "default", "timestamp format (default, rfc3339, epoch, epoch-ms, unix, unix-ms or a Go time layout)")
+	viper.BindPFlag("time-format", rootCmd.PersistentFlags().Lookup("time-format"))
+	rootCmd.RegisterFlagCompletionFunc("time-format", completeValues(utils.TimeFormatDefault, utils.TimeFormatRFC3339, utils.TimeFormatEpoch, utils.TimeFormatEpochMs, utils.TimeFormatUnix, utils.TimeFormatUnixMs))
+	rootCmd.PersistentFlags().String("time-zone", "local", "time zone of timestamps (local or utc)")
+	viper.BindPFlag("time-zone", rootCmd.PersistentFlags().Lookup("time-zone"))
+	rootCmd.RegisterFlagCompletionFunc("time-zone", completeValues("local", "utc"))
+
+	// Add persistent flags for writing the output of any command to a file
+	rootCmd.PersistentFlags().String("output", "", "write the output of the command to file (.gz files are compressed)")
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	rootCmd.PersistentFlags().Bool("append", false, "append to the file given with --output instead of overwriting it")
+	viper.BindPFlag("append", rootCmd.PersistentFlags().Lookup("append"))
+
+	// Add persistent flag for the format of the tables printed by the commands
+	rootCmd.PersistentFlags().String("format", "table", "format of the tables printed by the commands ("+strings.Join(render.Formats, ", ")+")")
+	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+	rootCmd.RegisterFlagCompletionFunc("format", completeValues(render.Formats...))
+
+	// Add flag for printing the version information in JSON format
+	rootCmd.Flags().BoolVar(&versionJSON, "json", false, "print the version information in JSON format (with --version)")
+
 	// Set a custom version template
-	rootCmd.SetVersionTemplate(`{{ printf "%s %s" .Name .Version }}`)
+	cobra.AddTemplateFunc("versionInfo", versionInfo)
+	rootCmd.SetVersionTemplate(`{{ versionInfo }}`)
 
 }
 
@@ -92,7 +195,9 @@ func initConfig() {
 	} else {
 		// Find home directory.
 		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+		if err != nil {
+			exitWithError(err)
+		}
 
 		// Search config in home directory with name ".iptool" (without extension)
 		viper.AddConfigPath(home)
@@ -111,6 +216,26 @@ func initConfig() {
 	// Print all environment variables loaded in viper
 	// viper.Debug()
 
-	// If a config file is found, read it in
-	viper.ReadInConfig()
+	// If a config file is found, read it in (unless --no-config is set)
+	if !viper.GetBool("no-config") {
+		viper.ReadInConfig()
+	}
+
+	// Disable all name resolution if the --no-dns flag (or config key) is set
+	ip.DisableLookups(viper.GetBool("no-dns"))
+
+	// Bypass the cache of external lookups if the --no-cache flag (or config key) is set
+	cache.Disable(viper.GetBool("no-cache"))
+
+	// Make sure that the timestamp configuration is valid before any output is written
+	if err := utils.ValidateTimeConfig(); err != nil {
+		exitWithError(exitcode.New(exitcode.Usage, err))
+	}
+
+	// Make sure that the error format is valid
+	switch format := strings.ToLower(viper.GetString("error-format")); format {
+	case "text", "json":
+	default:
+		exitWithError(exitcode.New(exitcode.Usage, fmt.Errorf("invalid error format: %s (must be text or json)", format)))
+	}
 }
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitcanon/iptool/route"
+	"github.com/spf13/cobra"
+)
+
+// routeCmd represents the route command
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Inspect the routing table",
+	Long: `Inspect the routing table.
+
+The route command group reads the IPv4 and IPv6 routing tables of the
+operating system (or a routing table file) and shows which route a packet
+to a destination would take.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(routeCmd)
+}
+
+// readRoutes is a function that returns the routes of a routing table file,
+// or the routing table of the operating system if no file is given
+func readRoutes(tableFile string) ([]route.Route, error) {
+	if tableFile == "" {
+		routes, err := route.ReadTable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the routing table: %w", err)
+		}
+		return routes, nil
+	}
+
+	f, err := os.Open(tableFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	routes, err := route.ParseTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", tableFile, err)
+	}
+	return routes, nil
+}
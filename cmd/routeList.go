@@ -0,0 +1,132 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/route"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routeListCmd represents the route list command
+var routeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the routes in the routing table",
+	Long: `List the routes in the routing table.
+
+The IPv4 and IPv6 routes of the operating system are listed with their
+gateway, interface and metric, sorted by prefix. The routes are read from
+/proc/net on Linux and from netstat -rn on macOS and FreeBSD. Use --table to
+list the routes of a routing table file instead (see iptool route match
+--help for the format), and -4 or -6 to only list the routes of one family.
+
+Examples:
+  iptool route list
+  iptool route list -4
+  iptool route list --table routes.txt --json`,
+	Aliases:      []string{"ls"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return routeListAction(os.Stdout)
+	},
+}
+
+// routeListAction is the action function for the route list command
+func routeListAction(out io.Writer) error {
+	routes, err := readRoutes(viper.GetString("route.list.table"))
+	if err != nil {
+		return err
+	}
+	route.Sort(routes)
+
+	// Keep the routes of the selected address family only
+	family := getFamily("route.list")
+	filtered := []route.Route{}
+	for _, r := range routes {
+		if family.Match(r.Prefix.Addr()) {
+			filtered = append(filtered, r)
+		}
+	}
+
+	if viper.GetBool("route.list.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(filtered); err != nil {
+			return err
+		}
+	} else {
+		table := render.NewTable(out, getRenderOptions("route.list", out),
+			render.Column{Title: "Destination"},
+			render.Column{Title: "Gateway"},
+			render.Column{Title: "Interface"},
+			render.Column{Title: "Metric", Align: render.AlignRight},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		rows := make([][]string, len(filtered))
+		for i, r := range filtered {
+			gateway := "direct"
+			if r.Gateway.IsValid() {
+				gateway = r.Gateway.String()
+			}
+			rows[i] = []string{r.Prefix.String(), gateway, r.Interface, fmt.Sprint(r.Metric)}
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	routeCmd.AddCommand(routeListCmd)
+	addFamilyFlags(routeListCmd, "route.list")
+
+	// Define the flag for reading the routes from a file
+	routeListCmd.Flags().StringP("table", "t", "", "read the routes from a routing table file")
+	viper.BindPFlag("route.list.table", routeListCmd.Flags().Lookup("table"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(routeListCmd, "route.list")
+
+	// Define the flag for printing the routes in JSON format
+	routeListCmd.Flags().Bool("json", false, "print the routes in JSON format")
+	viper.BindPFlag("route.list.json", routeListCmd.Flags().Lookup("json"))
+}
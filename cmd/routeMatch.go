@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/route"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// routeMatchCmd represents the route match command
+var routeMatchCmd = &cobra.Command{
+	Use:   "match <address>",
+	Short: "Show the route a packet to a destination would take",
+	Long: `Show the route a packet to a destination would take.
+
+The route is found by longest-prefix match: of the routes whose prefix
+contains the destination, the route with the longest prefix is taken, and
+of those the route with the lowest metric. The other routes to the same
+prefix (ties) and the routes to less specific prefixes that also contain the
+destination (covering routes) are listed too. The routing table of the
+operating system is used, unless a routing table file is given with --table;
+the routes are indexed in a trie, so tables with many thousands of prefixes
+are matched instantly.
+
+A routing table file has one route per line: a prefix (or "default")
+followed by the gateway and the interface, either as positional fields or
+in the format of the Linux ip route command, so that its output can be used
+as is. Empty lines and everything after a # are ignored:
+
+  10.0.0.0/8 via 10.1.1.1 dev eth0 metric 100
+  192.168.0.0/16 192.168.1.1 eth1
+  default 10.1.1.254
+
+Examples:
+  iptool route match 8.8.8.8
+  iptool route match 2001:db8::1
+  iptool route match 10.1.2.3 --table routes.txt
+  ip route show table all > routes.txt; iptool route match 10.1.2.3 -t routes.txt`,
+	Args:              cobra.MaximumNArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no address is provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return routeMatchAction(os.Stdout, resolveAlias(args[0]))
+	},
+}
+
+// routeMatchAction is the action function for the route match command
+func routeMatchAction(out io.Writer, s string) error {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid address: %s", s))
+	}
+	routes, err := readRoutes(viper.GetString("route.match.table"))
+	if err != nil {
+		return err
+	}
+
+	// Index the routes and find the longest-prefix match
+	table := route.NewTable(routes)
+	result, ok := table.Lookup(addr)
+	if !ok {
+		return fmt.Errorf("no route to %s (%d prefixes in the table)", addr, table.Len())
+	}
+
+	if viper.GetBool("route.match.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		r := result.Route
+		gateway := "direct (on-link)"
+		if r.Gateway.IsValid() {
+			gateway = r.Gateway.String()
+		}
+		fmt.Fprintf(out, "Destination : %s\n", addr)
+		fmt.Fprintf(out, "Route       : %s\n", r.Prefix)
+		fmt.Fprintf(out, "Gateway     : %s\n", gateway)
+		if r.Interface != "" {
+			fmt.Fprintf(out, "Interface   : %s\n", r.Interface)
+		}
+		fmt.Fprintf(out, "Metric      : %d\n", r.Metric)
+		writeRouteList(out, "Ties", result.Ties, r.Metric)
+		writeRouteList(out, "Covering", result.Covering, -1)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// writeRouteList is a function that prints a list of routes below a label,
+// the routes with the given metric are marked as equal cost
+func writeRouteList(out io.Writer, label string, routes []route.Route, metric int) {
+	for i, r := range routes {
+		line := r.String()
+		if r.Metric == metric {
+			line += " (equal cost)"
+		}
+
+		// Only the first route is labeled, the others are aligned below it
+		if i == 0 {
+			fmt.Fprintf(out, "%-11s : %s\n", label, line)
+		} else {
+			fmt.Fprintf(out, "%-11s   %s\n", "", line)
+		}
+	}
+}
+
+func init() {
+	routeCmd.AddCommand(routeMatchCmd)
+
+	// Define the flag for reading the routes from a file
+	routeMatchCmd.Flags().StringP("table", "t", "", "match against the routes of a routing table file")
+	viper.BindPFlag("route.match.table", routeMatchCmd.Flags().Lookup("table"))
+
+	// Define the flag for printing the route in JSON format
+	routeMatchCmd.Flags().Bool("json", false, "print the route in JSON format")
+	viper.BindPFlag("route.match.json", routeMatchCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,266 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/tcp"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+)
+
+// selftestCmd represents the selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Verify that iptool works correctly on this platform",
+	Long: `Verify that iptool works correctly on this platform.
+
+The self-test exercises the address parsers, the subnet calculations and
+a TCP probe over the loopback interface, and prints a pass/fail report.
+The command exits with a non-zero exit code if any of the tests fail,
+which makes it useful after installing on a new platform or when
+packaging iptool for a distribution.
+
+Examples:
+  iptool selftest`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+		return selftestAction(os.Stdout)
+	},
+}
+
+// selftest represents a single named test in the self-test
+type selftest struct {
+	name string
+	run  func() error
+}
+
+// errSelftestSkipped is returned by tests that do not apply to the current configuration
+var errSelftestSkipped = errors.New("skipped")
+
+// expectEqual returns an error if the value is not equal to the expected value
+func expectEqual(what string, expected, value any) error {
+	if !reflect.DeepEqual(expected, value) {
+		return fmt.Errorf("expected %s %v, got %v", what, expected, value)
+	}
+	return nil
+}
+
+// selftests is the list of tests run by the selftest command
+var selftests = []selftest{
+	{"IPv4 parsing (CIDR notation)", func() error {
+		ipv4, err := ip.ParseIPv4("192.168.10.20/22")
+		if err != nil {
+			return err
+		}
+		return errors.Join(
+			expectEqual("network", "192.168.8.0", ipv4.Network()),
+			expectEqual("netmask", "255.255.252.0", ipv4.Netmask()),
+			expectEqual("prefix length", 22, ipv4.PrefixLength()),
+		)
+	}},
+	{"IPv4 parsing (dotted-decimal netmask)", func() error {
+		ipv4, err := ip.ParseIPv4("10.0.0.1 255.255.255.252")
+		if err != nil {
+			return err
+		}
+		return expectEqual("CIDR", "10.0.0.1/30", ipv4.String())
+	}},
+	{"IPv4 parsing (hexadecimal notation)", func() error {
+		ipv4, err := ip.ParseIPv4("0xc0a800fe fffffe00")
+		if err != nil {
+			return err
+		}
+		return expectEqual("CIDR", "192.168.0.254/23", ipv4.String())
+	}},
+	{"Netmask validation", func() error {
+		if _, err := ip.ParseIPv4("10.0.0.1 255.0.255.0"); !errors.Is(err, ip.ErrDiscontiguousNetmask) {
+			return fmt.Errorf("expected discontiguous netmask error, got %v", err)
+		}
+		return nil
+	}},
+	{"IPv6 prefix parsing", func() error {
+		prefix, zone, err := ip.ParseIPv6Prefix("fe80::/64%eth0")
+		if err != nil {
+			return err
+		}
+		return errors.Join(
+			expectEqual("prefix", "fe80::/64", prefix.String()),
+			expectEqual("zone", "eth0", zone),
+		)
+	}},
+	{"Subnet calculations", func() error {
+		ipv4, err := ip.ParseIPv4("10.0.0.1/24")
+		if err != nil {
+			return err
+		}
+		return errors.Join(
+			expectEqual("broadcast", "10.0.0.255", ipv4.Broadcast()),
+			expectEqual("first host", "10.0.0.1", ipv4.FirstHost()),
+			expectEqual("last host", "10.0.0.254", ipv4.LastHost()),
+			expectEqual("usable hosts", uint32(254), ipv4.UsableHosts()),
+			expectEqual("network size", uint32(256), ipv4.NetworkSize()),
+			expectEqual("wildcard", "0.0.0.255", ipv4.Wildcard()),
+		)
+	}},
+	{"Address conversions", func() error {
+		return errors.Join(
+			expectEqual("binary", "00001010.00000000.00000000.00000001", ip.IPv4ToBinary("10.0.0.1")),
+			expectEqual("hexadecimal", "0a000001", ip.IPv4ToHex("10.0.0.1")),
+			expectEqual("decimal", "167772161", ip.IPv4ToDecimal("10.0.0.1")),
+			expectEqual("dotted-decimal", "10.0.0.1", ip.IntToIPv4(167772161)),
+		)
+	}},
+	{"Subnet splitting", func() error {
+		ipv4, err := ip.ParseIPv4("10.0.0.0/24")
+		if err != nil {
+			return err
+		}
+		subnets, err := ipv4.Split(26)
+		if err != nil {
+			return err
+		}
+		if err := expectEqual("number of subnets", 4, len(subnets)); err != nil {
+			return err
+		}
+		return expectEqual("last subnet", "10.0.0.192/26", subnets[3].String())
+	}},
+	{"Power of two math", func() error {
+		return errors.Join(
+			expectEqual("closest power of two for 5", uint32(8), utils.ClosestLargerPowerOfTwo(5)),
+			expectEqual("closest power of two for 64", uint32(64), utils.ClosestLargerPowerOfTwo(64)),
+		)
+	}},
+	{"Target pattern expansion", func() error {
+		targets, err := utils.ExpandPattern("web{01..03}")
+		if err != nil {
+			return err
+		}
+		return expectEqual("targets", []string{"web01", "web02", "web03"}, targets)
+	}},
+	{"Name resolution (localhost)", func() error {
+		if ip.LookupsDisabled() {
+			return errSelftestSkipped
+		}
+		_, err := ip.ResolveIP("localhost")
+		return err
+	}},
+	{"TCP ping (loopback)", func() error {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		port := listener.Addr().(*net.TCPAddr).Port
+		_, err = tcp.PingTCP("127.0.0.1", port, nil, 2*time.Second)
+		return err
+	}},
+	{"TCP throughput (loopback)", func() error {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+
+		received := make(chan tcp.SpeedInterval, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(received)
+				return
+			}
+			defer conn.Close()
+			total, _ := tcp.SpeedReceive(conn, time.Second, nil)
+			received <- total
+		}()
+
+		conn, err := net.DialTimeout("tcp", listener.Addr().String(), 2*time.Second)
+		if err != nil {
+			return err
+		}
+		sent, err := tcp.SpeedSend(conn, 100*time.Millisecond, time.Second, nil)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+		return expectEqual("received bytes", sent.Bytes, (<-received).Bytes)
+	}},
+}
+
+// selftestAction runs all self-tests and prints a pass/fail report
+func selftestAction(out io.Writer) error {
+	fmt.Fprintf(out, "Running %s self-test (version %s, %s/%s, %s)\n", rootCmd.Name(), rootCmd.Version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	failed, skipped := 0, 0
+	for _, test := range selftests {
+		start := time.Now()
+		err := test.run()
+		elapsed := time.Since(start).Round(time.Microsecond * 10)
+
+		if errors.Is(err, errSelftestSkipped) {
+			skipped++
+			fmt.Fprintf(out, " [SKIP] %s\n", test.name)
+			continue
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, " [FAIL] %s (%s)\n", test.name, elapsed)
+			fmt.Fprintf(out, "        %s\n", err)
+			continue
+		}
+		fmt.Fprintf(out, " [PASS] %s (%s)\n", test.name, elapsed)
+	}
+
+	fmt.Fprintf(out, "%d passed, %d failed, %d skipped\n", len(selftests)-failed-skipped, failed, skipped)
+	if failed > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d self-test(s) failed", failed))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/server"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the address calculations as an HTTP/JSON API",
+	Long: `Serve the address calculations as an HTTP/JSON API.
+
+The serve command runs a small HTTP server that exposes inspect, subnet
+split, subnet summarize and address classification as JSON endpoints, so
+that other tools and web interfaces can use them without running iptool
+for every request:
+
+  GET  /healthz                                       status and version
+  GET  /v1/inspect?address=10.0.0.1/24                address and network details
+  GET  /v1/classify?address=10.0.0.1,8.8.8.8          address types
+  GET  /v1/subnet/split?prefix=10.0.0.0/22&bits=26    subnets (also networks,
+                                                      offset and limit)
+  GET  /v1/subnet/summarize?prefix=10.0.0.0/25,10.0.0.128/25
+  POST /v1/subnet/summarize {"prefixes": [...]}
+
+Errors are returned with a 4xx status code and a JSON body with an error
+field. Every request is logged with a timestamp. Use --cors-origin to allow
+web interfaces served from another origin to call the API.
+
+Examples:
+  iptool serve --listen :8080
+  iptool serve --listen 127.0.0.1:8080 --cors-origin "*"
+  curl "http://localhost:8080/v1/inspect?address=10.0.0.1/24"`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+
+		// Determine the output file using Viper
+		outputStream, err := utils.GetOutputStream(viper.GetString("serve.output-file"), viper.GetBool("serve.append"))
+		if err != nil {
+			return err
+		}
+		defer outputStream.Close()
+
+		return serveAction(outputStream)
+	},
+}
+
+// statusRecorder records the status code of a response for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the response
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// serveAction is the action function for the serve command, it serves the
+// API until the user presses Ctrl-C
+func serveAction(out io.Writer) error {
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Open the listening socket before logging that the server is running
+	listener, err := net.Listen("tcp", viper.GetString("serve.listen"))
+	if err != nil {
+		return err
+	}
+
+	logger := &connectionLogger{out: out}
+	handler := server.New(server.Options{
+		Version:    rootCmd.Version,
+		CORSOrigin: viper.GetString("serve.cors-origin"),
+	})
+
+	// Log every request with the client address, the status and the duration
+	logged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		logger.log("%s %s %s %d %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), recorder.status, time.Since(start).Round(time.Microsecond))
+	})
+
+	srv := &http.Server{
+		Handler:           logged,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	logger.log("serving the API on http://%s", listener.Addr())
+	return srv.Serve(listener)
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	// Enable the --listen flag to set the address to listen on
+	serveCmd.Flags().StringP("listen", "l", "127.0.0.1:8080", "address and port to listen on (e.g. :8080 for all interfaces)")
+	viper.BindPFlag("serve.listen", serveCmd.Flags().Lookup("listen"))
+
+	// Enable the --cors-origin flag to allow browsers on other origins
+	serveCmd.Flags().String("cors-origin", "", "allow requests from this origin in browsers (e.g. * or https://ipam.example.com)")
+	viper.BindPFlag("serve.cors-origin", serveCmd.Flags().Lookup("cors-origin"))
+
+	// Enable the --output-file flag to write the request log to a file
+	serveCmd.Flags().StringP("output-file", "o", "", "write the request log to file")
+	viper.BindPFlag("serve.output-file", serveCmd.Flags().Lookup("output-file"))
+
+	// Enable the --append flag to append the request log to the output file
+	serveCmd.Flags().BoolP("append", "a", false, "append to the output file")
+	viper.BindPFlag("serve.append", serveCmd.Flags().Lookup("append"))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// setCmd represents the set command
+var setCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Combine lists of addresses and prefixes",
+	Long: `Combine lists of addresses and prefixes.
+
+The set command group treats lists of addresses and prefixes as sets of
+addresses and calculates their union, intersection and difference. The
+result is printed as the smallest list of prefixes that covers exactly the
+addresses of the result, e.g. to maintain the address objects of firewalls.
+
+The lists are files with one or more addresses or prefixes per line
+(separated by commas or spaces, everything after a # is ignored), and - reads
+a list from standard input.`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+}
+
+// addSetFlags is a function that adds the flags shared by the set commands
+func addSetFlags(cmd *cobra.Command, command string) {
+	addFamilyFlags(cmd, command)
+
+	// Define the flag for printing address ranges instead of prefixes
+	cmd.Flags().Bool("ranges", false, "print the result as address ranges instead of prefixes")
+	viper.BindPFlag(command+".ranges", cmd.Flags().Lookup("ranges"))
+
+	// Enable the --output-file flag to write the output to a file
+	cmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag(command+".output-file", cmd.Flags().Lookup("output-file"))
+}
+
+// readSetFile is a function that returns the set of addresses of a list
+// file, or of standard input if the file is -
+func readSetFile(file string, stdin io.Reader) (*ip.Set, error) {
+	if file == "-" {
+		prefixes, err := readPrefixArgs([]string{"-"}, stdin)
+		if err != nil {
+			return nil, err
+		}
+		return ip.NewSet(prefixes), nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prefixes, err := ip.ParsePrefixes(f)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("%s: %w", file, err))
+	}
+	return ip.NewSet(prefixes), nil
+}
+
+// setAction is the action function shared by the set commands, it combines
+// the sets of the files from left to right with the operation
+func setAction(out io.Writer, stdin io.Reader, command string, files []string, operation func(a, b *ip.Set) *ip.Set) error {
+	// Standard input can only be read once
+	stdinUsed := false
+	for _, file := range files {
+		if file == "-" {
+			if stdinUsed {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("standard input (-) can only be given once"))
+			}
+			stdinUsed = true
+		}
+	}
+
+	var result *ip.Set
+	for _, file := range files {
+		set, err := readSetFile(file, stdin)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			result = set
+		} else {
+			result = operation(result, set)
+		}
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString(command+".output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	family := getFamily(command)
+	for _, interval := range result.Intervals() {
+		if !family.Match(interval.From) {
+			continue
+		}
+		if viper.GetBool(command + ".ranges") {
+			fmt.Fprintln(outputStream, interval)
+			continue
+		}
+		for _, prefix := range interval.Prefixes() {
+			fmt.Fprintln(outputStream, prefix)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
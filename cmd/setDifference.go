@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+)
+
+// setDifferenceCmd represents the set difference command
+var setDifferenceCmd = &cobra.Command{
+	Use:     "difference <file> <file...>",
+	Aliases: []string{"diff", "minus"},
+	Short:   "Print the addresses of the first list that are not in the other lists",
+	Long: `Print the addresses of the first list that are not in the other lists.
+
+The result is printed as the smallest list of prefixes that covers exactly
+the addresses of the first list without the addresses of the other lists,
+use --ranges to print address ranges instead. Prefixes of the first list are
+split where addresses are removed from them.
+
+Examples:
+  iptool set difference supernet.txt assigned.txt
+  iptool set difference firewall-object.txt decommissioned.txt`,
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAction(os.Stdout, os.Stdin, "set.difference", args, (*ip.Set).Difference)
+	},
+}
+
+func init() {
+	setCmd.AddCommand(setDifferenceCmd)
+	addSetFlags(setDifferenceCmd, "set.difference")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+)
+
+// setUnionCmd represents the set union command
+var setUnionCmd = &cobra.Command{
+	Use:     "union <file...>",
+	Aliases: []string{"or"},
+	Short:   "Print the addresses that are in any of the lists",
+	Long: `Print the addresses that are in any of the lists.
+
+The result is printed as the smallest list of prefixes that covers exactly
+the addresses of all lists, use --ranges to print address ranges instead.
+
+Examples:
+  iptool set union office.txt datacenter.txt
+  cat blocked.txt | iptool set union - new-blocks.txt -o blocked.txt.new`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setAction(os.Stdout, os.Stdin, "set.union", args, (*ip.Set).Union)
+	},
+}
+
+func init() {
+	setCmd.AddCommand(setUnionCmd)
+	addSetFlags(setUnionCmd, "set.union")
+}
@@ -0,0 +1,351 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/ssh"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// sshFingerprintFormats are the output formats of the ssh fingerprint command
+var sshFingerprintFormats = []string{"table", "csv", "json", "known-hosts"}
+
+// sshFingerprintMaxHostBits is the largest number of host bits of a prefix
+// that is expanded to its addresses (65536 addresses)
+const sshFingerprintMaxHostBits = 16
+
+// sshFingerprintCmd represents the ssh fingerprint command
+var sshFingerprintCmd = &cobra.Command{
+	Use:   "fingerprint <host...>",
+	Short: "Collect the host keys and fingerprints of SSH servers",
+	Long: `Collect the host keys and fingerprints of SSH servers.
+
+iptool connects to the SSH port and runs the key exchange up to the point
+where the server presents its host key, without authenticating. Every key
+type the server offers is collected with a connection of its own, and the
+SHA256 and MD5 fingerprints are printed in the format of ssh-keygen -l.
+Use --types to limit the key types (ed25519, ecdsa, rsa and dsa).
+
+The hosts may be names, addresses, brace patterns (web{01..20}.example.com),
+groups (@name) or prefixes. A prefix is expanded to its addresses (at most
+65536) for batch collection across a subnet, and the addresses without an
+SSH server are silently skipped. Use --format known-hosts to build a
+known_hosts inventory, or --format json or csv to process the keys.
+
+The key exchange is only supported with the ECDH algorithms (curve25519 and
+NIST curves), which every current SSH server supports.
+
+The command exits with exit code 5 if some of the hosts given by name or
+address could not be scanned, and with exit code 3 if no host answered.
+
+Examples:
+  iptool ssh fingerprint 192.0.2.10
+  iptool ssh fingerprint 192.0.2.10 --port 2222 --types ed25519
+  iptool ssh fingerprint 192.0.2.0/24 --format known-hosts -o known_hosts
+  iptool ssh fingerprint web{01..04}.example.com --format json`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no hosts are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		hosts, err := expandTargets(args)
+		if err != nil {
+			return err
+		}
+		return sshFingerprintAction(os.Stdout, hosts)
+	},
+}
+
+// sshFingerprintHost is a host in the output of the ssh fingerprint command
+type sshFingerprintHost struct {
+	Host       string              `json:"host"`
+	Port       int                 `json:"port"`
+	Version    string              `json:"version,omitempty"`
+	Algorithms []string            `json:"algorithms,omitempty"`
+	Keys       []sshFingerprintKey `json:"keys,omitempty"`
+	Error      string              `json:"error,omitempty"`
+
+	// batch is set for the addresses of an expanded prefix
+	batch    bool
+	hostKeys []ssh.HostKey
+}
+
+// sshFingerprintKey is a host key in the output of the ssh fingerprint command
+type sshFingerprintKey struct {
+	Type   string `json:"type"`
+	Bits   int    `json:"bits"`
+	SHA256 string `json:"sha256"`
+	MD5    string `json:"md5"`
+	Key    string `json:"key"`
+}
+
+// expandSSHHosts is a function that expands the prefixes among the hosts to
+// their addresses (without the network and broadcast addresses of IPv4
+// prefixes), the other hosts are kept as they are
+func expandSSHHosts(hosts []string, port int) ([]sshFingerprintHost, error) {
+	var expanded []sshFingerprintHost
+	for _, host := range hosts {
+		prefix, err := netip.ParsePrefix(host)
+		if err != nil {
+			expanded = append(expanded, sshFingerprintHost{Host: host, Port: port})
+			continue
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > sshFingerprintMaxHostBits {
+			return nil, exitcode.New(exitcode.Usage, fmt.Errorf("too many addresses in %s (at most %d)", prefix, 1<<sshFingerprintMaxHostBits))
+		}
+		last := ip.LastAddr(prefix)
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if prefix.Addr().Is4() && hostBits >= 2 && (addr == prefix.Addr() || addr == last) {
+				continue
+			}
+			expanded = append(expanded, sshFingerprintHost{Host: addr.String(), Port: port, batch: true})
+		}
+	}
+	return expanded, nil
+}
+
+// sshFingerprintAction is the action function for the ssh fingerprint command
+func sshFingerprintAction(out io.Writer, args []string) error {
+	format := strings.ToLower(viper.GetString("ssh.fingerprint.format"))
+	if !slices.Contains(sshFingerprintFormats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(sshFingerprintFormats, ", ")))
+	}
+	port := viper.GetInt("ssh.fingerprint.port")
+	if port < 1 || port > 65535 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid port: %d (must be between 1 and 65535)", port))
+	}
+	types := viper.GetStringSlice("ssh.fingerprint.types")
+	for _, t := range types {
+		if _, ok := ssh.KeyTypes[t]; !ok {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid key type: %s (must be one of %s)", t, strings.Join(ssh.DefaultKeyTypes, ", ")))
+		}
+	}
+	timeout := viper.GetDuration("ssh.fingerprint.timeout") * time.Millisecond
+	if timeout <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid timeout: %d (must be greater than 0)", viper.GetInt("ssh.fingerprint.timeout")))
+	}
+	limiter, err := getRateLimiter("ssh.fingerprint")
+	if err != nil {
+		return err
+	}
+	concurrency, err := getConcurrency("ssh.fingerprint")
+	if err != nil {
+		return err
+	}
+	hosts, err := expandSSHHosts(args, port)
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Collect the keys of the hosts, at most --concurrency at a time and at
+	// the --rate, when Ctrl-C is pressed the scans in flight are completed
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+	bar := newProgressBar("fingerprint", len(hosts), nil)
+	scanned := make([]bool, len(hosts))
+	ratelimit.Run(ctx, len(hosts), concurrency, limiter, func(ctx context.Context, i int) {
+		defer bar.Add(1)
+		h := &hosts[i]
+		scanned[i] = true
+		result, err := ssh.Scan(ctx, net.JoinHostPort(h.Host, strconv.Itoa(h.Port)), types, timeout)
+		if result != nil {
+			h.Version, h.Algorithms = result.Version, result.Algorithms
+			h.hostKeys = result.Keys
+			for _, key := range result.Keys {
+				h.Keys = append(h.Keys, sshFingerprintKey{Type: key.Type, Bits: key.Bits, SHA256: key.SHA256(), MD5: key.MD5(), Key: key.Base64()})
+			}
+		}
+		if err != nil {
+			h.Error = err.Error()
+		}
+	})
+	bar.Finish()
+
+	// The addresses of a prefix without an SSH server are not reported, nor
+	// are the hosts that were not scanned because of Ctrl-C
+	var results []sshFingerprintHost
+	answered, failed := 0, 0
+	for i, h := range hosts {
+		if !scanned[i] || (h.batch && h.Version == "") {
+			continue
+		}
+		if h.Version != "" {
+			answered++
+		}
+		if h.Error != "" && !h.batch {
+			failed++
+			if len(hosts) > 1 {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", h.Error)
+			}
+		}
+		results = append(results, h)
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("ssh.fingerprint.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// An empty table is not printed when no host answered
+	if answered > 0 || format != "table" {
+		if err := writeSSHFingerprints(out, outputStream, format, results, len(hosts)); err != nil {
+			return err
+		}
+	}
+
+	// Report the hosts that could not be scanned with the exit code
+	if answered == 0 {
+		if len(hosts) == 1 && hosts[0].Error != "" {
+			return exitcode.New(exitcode.Unreachable, errors.New(hosts[0].Error))
+		}
+		return exitcode.New(exitcode.Unreachable, errors.New("no SSH server answered"))
+	}
+	if failed > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d host(s) could not be scanned", failed))
+	}
+	return nil
+}
+
+// writeSSHFingerprints is a function that writes the host keys in the
+// output format, the summary of the table format is written to out
+func writeSSHFingerprints(out, w io.Writer, format string, results []sshFingerprintHost, total int) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []sshFingerprintHost{}
+		}
+		return encoder.Encode(results)
+	case "known-hosts":
+		for _, h := range results {
+			for _, k := range h.hostKeys {
+				if _, err := fmt.Fprintln(w, k.KnownHostsLine(h.Host, h.Port)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"host", "port", "version", "type", "bits", "sha256", "md5"})
+		for _, h := range results {
+			for _, k := range h.Keys {
+				cw.Write([]string{h.Host, strconv.Itoa(h.Port), h.Version, k.Type, strconv.Itoa(k.Bits), k.SHA256, k.MD5})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	table := render.NewTable(w, getRenderOptions("ssh.fingerprint", w),
+		render.Column{Title: "Host"},
+		render.Column{Title: "Port", Align: render.AlignRight},
+		render.Column{Title: "Type"},
+		render.Column{Title: "Bits", Align: render.AlignRight},
+		render.Column{Title: "SHA256"},
+		render.Column{Title: "MD5", Truncate: true},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	var rows [][]string
+	keys := 0
+	for _, h := range results {
+		for _, k := range h.Keys {
+			rows = append(rows, []string{h.Host, strconv.Itoa(h.Port), k.Type, strconv.Itoa(k.Bits), k.SHA256, k.MD5})
+			keys++
+		}
+	}
+	for _, row := range rows {
+		table.Fit(row...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+	answered := 0
+	for _, h := range results {
+		if h.Version != "" {
+			answered++
+		}
+	}
+	fmt.Fprintf(out, "\n%d key(s) collected from %d of %d host(s)\n", keys, answered, total)
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	sshCmd.AddCommand(sshFingerprintCmd)
+
+	// Define the flags for the port, the key types and the time to wait
+	sshFingerprintCmd.Flags().IntP("port", "p", 22, "SSH port of the hosts")
+	viper.BindPFlag("ssh.fingerprint.port", sshFingerprintCmd.Flags().Lookup("port"))
+	sshFingerprintCmd.Flags().StringSlice("types", ssh.DefaultKeyTypes, "host key types to collect (ed25519, ecdsa, rsa and dsa)")
+	viper.BindPFlag("ssh.fingerprint.types", sshFingerprintCmd.Flags().Lookup("types"))
+	sshFingerprintCmd.RegisterFlagCompletionFunc("types", completeValues(ssh.DefaultKeyTypes...))
+	sshFingerprintCmd.Flags().IntP("timeout", "t", 5000, "time to wait for every connection, in milliseconds")
+	viper.BindPFlag("ssh.fingerprint.timeout", sshFingerprintCmd.Flags().Lookup("timeout"))
+	addRateFlag(sshFingerprintCmd, "ssh.fingerprint")
+	addConcurrencyFlag(sshFingerprintCmd, "ssh.fingerprint", 50)
+
+	// Define the flags for the output
+	sshFingerprintCmd.Flags().StringP("format", "f", "table", "output format (table, csv, json or known-hosts)")
+	viper.BindPFlag("ssh.fingerprint.format", sshFingerprintCmd.Flags().Lookup("format"))
+	sshFingerprintCmd.RegisterFlagCompletionFunc("format", completeValues(sshFingerprintFormats...))
+	sshFingerprintCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("ssh.fingerprint.output-file", sshFingerprintCmd.Flags().Lookup("output-file"))
+	addRenderFlags(sshFingerprintCmd, "ssh.fingerprint")
+}
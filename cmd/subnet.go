@@ -22,6 +22,14 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/bitcanon/iptool/envelope"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
 	"github.com/spf13/cobra"
 )
 
@@ -41,3 +49,33 @@ The subnet command provides tools for generating and manipulating subnets.`,
 func init() {
 	rootCmd.AddCommand(subnetCmd)
 }
+
+// readPrefixArgs is a function that parses the prefixes given as arguments,
+// separated by commas or whitespace. An argument of - reads a newline or
+// comma separated list of prefixes from the input (standard input), or the
+// targets of the JSON records written by another command with --format json.
+func readPrefixArgs(args []string, stdin io.Reader) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, arg := range args {
+		var r io.Reader = strings.NewReader(resolveAlias(arg))
+		if arg == "-" {
+			buffered := bufio.NewReader(stdin)
+			r = buffered
+
+			// Read the targets of JSON records as a list of prefixes
+			if envelope.IsJSON(buffered) {
+				targets, err := envelope.ReadTargets(buffered)
+				if err != nil {
+					return nil, err
+				}
+				r = strings.NewReader(strings.Join(targets, "\n"))
+			}
+		}
+		list, err := ip.ParsePrefixes(r)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		prefixes = append(prefixes, list...)
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/plan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetCheckCmd represents the subnet check command
+var subnetCheckCmd = &cobra.Command{
+	Use:   "check --plan <file>",
+	Short: "Validate an address plan file",
+	Long: `Validate an address plan file.
+
+The address plan is a YAML file with a list of parent blocks, each divided
+into child allocations:
+
+  blocks:
+    - prefix: 10.0.0.0/16
+      name: datacenter
+      allocations:
+        - prefix: 10.0.0.0/24
+          name: servers
+        - prefix: 10.0.1.0/24
+          name: storage
+
+The plan is checked for duplicate and overlapping prefixes, allocations
+outside of their parent block and unallocated space (gaps) in the blocks.
+The command exits with a non-zero exit code if any violations are found,
+which makes it suitable for running in CI pipelines. Gaps are reported as
+warnings and only fail the check if the --fail-on-gaps flag is set.
+
+Examples:
+  iptool subnet check --plan plan.yaml
+  iptool subnet check --plan plan.yaml --fail-on-gaps
+  iptool subnet check --plan plan.yaml --quiet`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// No arguments allowed
+		if len(args) > 0 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %v", args))
+		}
+
+		// The plan file is required
+		filename := viper.GetString("subnet.check.plan")
+		if filename == "" {
+			return exitcode.New(exitcode.Usage, errors.New("no address plan specified (use --plan <file>)"))
+		}
+
+		return subnetCheckAction(os.Stdout, filename)
+	},
+}
+
+// subnetCheckAction is the action function for the subnet check command
+func subnetCheckAction(out io.Writer, filename string) error {
+	// Load the address plan from the file
+	p, err := plan.Load(filename)
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Validate the address plan
+	findings := plan.Check(p)
+	failOnGaps := viper.GetBool("subnet.check.fail-on-gaps")
+	quiet := viper.GetBool("subnet.check.quiet")
+
+	// Print the findings and count the violations
+	violations := 0
+	for _, f := range findings {
+		if f.Severity == plan.SeverityError || failOnGaps {
+			violations++
+		} else if quiet {
+			// Warnings are not printed in quiet mode
+			continue
+		}
+		fmt.Fprintln(out, f)
+	}
+
+	if violations > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d violation(s) found in address plan %s", violations, filename))
+	}
+
+	if !quiet {
+		fmt.Fprintf(out, "Address plan %s is valid (%d block(s), %d warning(s))\n", filename, len(p.Blocks), len(findings))
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetCheckCmd)
+
+	// Define the flag for the address plan file
+	subnetCheckCmd.Flags().StringP("plan", "p", "", "address plan file (YAML) to validate")
+	viper.BindPFlag("subnet.check.plan", subnetCheckCmd.Flags().Lookup("plan"))
+
+	// Enable the --fail-on-gaps flag to treat unallocated space as a violation
+	subnetCheckCmd.Flags().Bool("fail-on-gaps", false, "treat unallocated space in a block as a violation")
+	viper.BindPFlag("subnet.check.fail-on-gaps", subnetCheckCmd.Flags().Lookup("fail-on-gaps"))
+
+	// Enable the --quiet flag to only print violations
+	subnetCheckCmd.Flags().BoolP("quiet", "q", false, "only print violations")
+	viper.BindPFlag("subnet.check.quiet", subnetCheckCmd.Flags().Lookup("quiet"))
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetConflictCmd represents the subnet conflict command
+var subnetConflictCmd = &cobra.Command{
+	Use:   "conflict <prefix...|->",
+	Short: "Check proposed prefixes against the local networks",
+	Long: `Check proposed prefixes against the local networks.
+
+Every proposed prefix is compared with the networks configured on the
+interfaces of this machine and the routes in its routing table, and the
+overlaps are reported: the proposed prefix is equal to a local network,
+contains it or lies inside it. Run this before configuring a new VPN or VLAN
+to find out whether it would shadow (or be shadowed by) a network that is
+already in use. Default routes (0.0.0.0/0 and ::/0) are ignored.
+
+Use --table to check against a routing table file instead of the routing
+table of this machine (see iptool route match --help for the format), and
+--no-routes to only check the interface networks.
+
+The prefixes are given as arguments, separated by commas or spaces, or are
+read from standard input (one or more per line) when - is given. The command
+exits with exit code 5 if any conflicts are found.
+
+Examples:
+  iptool subnet conflict 10.10.0.0/16
+  iptool subnet conflict 192.168.0.0/24,172.16.0.0/12 --no-routes
+  iptool subnet conflict 10.8.0.0/24 --table routes.txt --json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return subnetConflictAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// localNetwork is a network in use on this machine, configured on an
+// interface or reachable through a route
+type localNetwork struct {
+	Prefix    netip.Prefix `json:"prefix"`
+	Source    string       `json:"source"`
+	Interface string       `json:"interface,omitempty"`
+	Gateway   string       `json:"gateway,omitempty"`
+}
+
+// subnetConflict is an overlap between a proposed prefix and a local network
+type subnetConflict struct {
+	Proposed netip.Prefix `json:"proposed"`
+	Relation string       `json:"relation"`
+	localNetwork
+}
+
+// subnetConflictAction is the action function for the subnet conflict command
+func subnetConflictAction(out io.Writer, stdin io.Reader, args []string) error {
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+
+	networks, err := readLocalNetworks(viper.GetString("subnet.conflict.table"), !viper.GetBool("subnet.conflict.no-routes"))
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	conflicts := []subnetConflict{}
+	for _, p := range prefixes {
+		for _, n := range networks {
+			if relation := prefixRelation(p, n.Prefix); relation != "" {
+				conflicts = append(conflicts, subnetConflict{Proposed: p, Relation: relation, localNetwork: n})
+			}
+		}
+	}
+
+	if viper.GetBool("subnet.conflict.json") {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(conflicts); err != nil {
+			return err
+		}
+	} else if len(conflicts) > 0 {
+		table := render.NewTable(out, getRenderOptions("subnet.conflict", out),
+			render.Column{Title: "Proposed"},
+			render.Column{Title: "Relation"},
+			render.Column{Title: "Local Network"},
+			render.Column{Title: "Source"},
+			render.Column{Title: "Interface"},
+			render.Column{Title: "Gateway"},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		rows := make([][]string, len(conflicts))
+		for i, c := range conflicts {
+			rows[i] = []string{c.Proposed.String(), c.Relation, c.Prefix.String(), c.Source, c.Interface, c.Gateway}
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("found %d conflict(s) with the local networks", len(conflicts)))
+	}
+	if !viper.GetBool("subnet.conflict.json") {
+		fmt.Fprintf(out, "No conflicts with the %d local network(s)\n", len(networks))
+	}
+	return nil
+}
+
+// prefixRelation is a function that returns how a proposed prefix overlaps
+// a local network: equal, contains or inside (empty if they do not overlap)
+func prefixRelation(proposed, local netip.Prefix) string {
+	switch {
+	case !proposed.Overlaps(local):
+		return ""
+	case proposed == local:
+		return "equal"
+	case proposed.Bits() < local.Bits():
+		return "contains"
+	}
+	return "inside"
+}
+
+// readLocalNetworks is a function that returns the networks configured on
+// the interfaces of this machine and, if routes is set, the networks in the
+// routing table (or routing table file), except the default routes. Routes
+// to the network of an interface are left out, the interface is listed.
+func readLocalNetworks(tableFile string, routes bool) ([]localNetwork, error) {
+	var networks []localNetwork
+	seen := make(map[string]bool)
+
+	// The networks of the interfaces (not of a routing table file)
+	if tableFile == "" {
+		interfaces, err := net.Interfaces()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the interfaces: %w", err)
+		}
+		for _, iface := range interfaces {
+			addrs, err := iface.Addrs()
+			if err != nil {
+				continue
+			}
+			for _, addr := range addrs {
+				ipNet, ok := addr.(*net.IPNet)
+				if !ok {
+					continue
+				}
+				prefix, err := ip.ParsePrefix(ipNet.String())
+				if err != nil {
+					continue
+				}
+				key := prefix.String() + " " + iface.Name
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				networks = append(networks, localNetwork{Prefix: prefix, Source: "interface", Interface: iface.Name})
+			}
+		}
+	}
+	if !routes {
+		return networks, nil
+	}
+
+	list, err := readRoutes(tableFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		key := r.Prefix.String() + " " + r.Interface
+		if r.Prefix.Bits() == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		n := localNetwork{Prefix: r.Prefix, Source: "route", Interface: r.Interface}
+		if r.Gateway.IsValid() {
+			n.Gateway = r.Gateway.String()
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetConflictCmd)
+
+	// Define the flags for the local networks to check against
+	subnetConflictCmd.Flags().StringP("table", "t", "", "read the routes from a routing table file instead of this machine")
+	viper.BindPFlag("subnet.conflict.table", subnetConflictCmd.Flags().Lookup("table"))
+	subnetConflictCmd.Flags().Bool("no-routes", false, "only check the networks of the interfaces, not the routes")
+	viper.BindPFlag("subnet.conflict.no-routes", subnetConflictCmd.Flags().Lookup("no-routes"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetConflictCmd, "subnet.conflict")
+
+	// Define the flag for printing the conflicts in JSON format
+	subnetConflictCmd.Flags().Bool("json", false, "print the conflicts in JSON format")
+	viper.BindPFlag("subnet.conflict.json", subnetConflictCmd.Flags().Lookup("json"))
+}
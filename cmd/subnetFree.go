@@ -0,0 +1,207 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetFreeCmd represents the subnet free command
+var subnetFreeCmd = &cobra.Command{
+	Use:   "free <prefix>",
+	Short: "Find the unallocated space in a prefix",
+	Long: `Find the unallocated space in a prefix.
+
+The free blocks of the prefix, i.e. the addresses not covered by any of the
+allocated prefixes, are printed as the smallest list of prefixes, followed by
+the number of free addresses. The allocated prefixes are read from the files
+given with --allocated-file (one or more prefixes or addresses per line,
+everything after a # is ignored, - reads standard input). Allocated prefixes
+outside of the prefix are ignored.
+
+Use --size to only print the free blocks that can hold a subnet of that
+prefix length, e.g. --size 24 only prints the free /24s and larger blocks.
+
+Examples:
+  iptool subnet free 10.0.0.0/16 --allocated-file used.txt
+  iptool subnet free 10.0.0.0/16 --allocated-file used.txt --size 24
+  iptool ipam list --json | iptool subnet free 10.0.0.0/8 -a -`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return subnetFreeAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// freeBlock is a free block of a prefix
+type freeBlock struct {
+	Prefix    netip.Prefix `json:"prefix"`
+	First     netip.Addr   `json:"first"`
+	Last      netip.Addr   `json:"last"`
+	Addresses *big.Int     `json:"addresses"`
+}
+
+// prefixAddresses is a function that returns the number of addresses of a prefix
+func prefixAddresses(prefix netip.Prefix) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+}
+
+// subnetFreeAction is the action function for the subnet free command
+func subnetFreeAction(out io.Writer, stdin io.Reader, args []string) error {
+	// Parse the parent prefix, e.g. 10.0.0.0/16
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	if len(prefixes) != 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("exactly one prefix must be given"))
+	}
+	parent := prefixes[0]
+
+	// The size is a prefix length of the family of the parent
+	size := viper.GetInt("subnet.free.size")
+	if size < 0 || size > parent.Addr().BitLen() {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --size value: %d (must be between 0 and %d)", size, parent.Addr().BitLen()))
+	}
+
+	// Read the allocated prefixes, standard input can only be read once
+	files := viper.GetStringSlice("subnet.free.allocated-file")
+	if len(files) == 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no allocated prefixes given, use --allocated-file (- reads standard input)"))
+	}
+	var allocated []netip.Prefix
+	stdinUsed := false
+	for _, file := range files {
+		if file == "-" {
+			if stdinUsed {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("standard input (-) can only be given once"))
+			}
+			stdinUsed = true
+		}
+		set, err := readSetFile(file, stdin)
+		if err != nil {
+			return err
+		}
+		allocated = append(allocated, set.Prefixes()...)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Count the free addresses of all free blocks, also the ones smaller than --size
+	freeAddresses := new(big.Int)
+	for _, prefix := range ip.FreeBlocks(parent, allocated, 0) {
+		freeAddresses.Add(freeAddresses, prefixAddresses(prefix))
+	}
+
+	var blocks []freeBlock
+	for _, prefix := range ip.FreeBlocks(parent, allocated, size) {
+		blocks = append(blocks, freeBlock{Prefix: prefix, First: prefix.Addr(), Last: ip.LastAddr(prefix), Addresses: prefixAddresses(prefix)})
+	}
+
+	if viper.GetBool("subnet.free.json") {
+		encoder := json.NewEncoder(out)
+		for _, block := range blocks {
+			if err := encoder.Encode(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Print the free blocks, the table is left out if there are none
+	format := render.Format(strings.ToLower(viper.GetString("format")))
+	if len(blocks) > 0 || format != render.FormatTable {
+		table := render.NewTable(out, getRenderOptions("subnet.free", out),
+			render.Column{Title: "Prefix"},
+			render.Column{Title: "First"},
+			render.Column{Title: "Last"},
+			render.Column{Title: "Addresses", Align: render.AlignRight},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		rows := make([][]string, len(blocks))
+		for i, block := range blocks {
+			rows[i] = []string{block.Prefix.String(), block.First.String(), block.Last.String(), block.Addresses.String()}
+			table.Fit(rows[i]...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+	}
+
+	// Summarize the free space of the prefix in the table format
+	if format == render.FormatTable {
+		total := prefixAddresses(parent.Masked())
+		percent, _ := new(big.Float).Quo(new(big.Float).SetInt(freeAddresses), new(big.Float).SetInt(total)).Float64()
+		if len(blocks) > 0 {
+			fmt.Fprintln(out)
+		}
+		description := "free blocks"
+		if size > 0 {
+			description = fmt.Sprintf("free blocks of /%d or larger", size)
+		}
+		fmt.Fprintf(out, "%d %s, %s of %s addresses free (%.1f%%)\n", len(blocks), description, freeAddresses, total, percent*100)
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetFreeCmd)
+
+	// Define the flags for the allocated prefixes and the minimum size of the free blocks
+	subnetFreeCmd.Flags().StringSliceP("allocated-file", "a", nil, "file with the allocated prefixes (- reads standard input)")
+	viper.BindPFlag("subnet.free.allocated-file", subnetFreeCmd.Flags().Lookup("allocated-file"))
+	subnetFreeCmd.Flags().IntP("size", "s", 0, "only print the free blocks that can hold a subnet of this prefix length")
+	viper.BindPFlag("subnet.free.size", subnetFreeCmd.Flags().Lookup("size"))
+	subnetFreeCmd.RegisterFlagCompletionFunc("size", completePrefixLengths(1, 128))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetFreeCmd, "subnet.free")
+
+	// Define the flag for printing the free blocks in JSON format
+	subnetFreeCmd.Flags().Bool("json", false, "print the free blocks in JSON format")
+	viper.BindPFlag("subnet.free.json", subnetFreeCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetFromRangeCmd represents the subnet from-range command
+var subnetFromRangeCmd = &cobra.Command{
+	Use:   "from-range <first>-<last>",
+	Short: "Find the subnet(s) matching an arbitrary IPv4 address range",
+	Long: `Find the subnet(s) matching an arbitrary IPv4 address range.
+
+Determines whether the range corresponds exactly to a single subnet in CIDR
+notation. If it does not, the smallest subnet covering the whole range is
+reported together with the number of excess addresses, and the list of
+subnets that together cover exactly the range. This is useful when
+translating legacy range-based firewall rules into prefixes.
+
+Examples:
+  iptool subnet from-range 10.0.4.0-10.0.7.255
+  iptool subnet from-range 10.0.4.10-10.0.7.200
+  iptool subnet from-range 192.168.1.0 - 192.168.1.127`,
+	Aliases:      []string{"fr"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		input := strings.Join(args, "")
+
+		// Get the output file name from the viper configuration
+		outputFile := viper.GetString("subnet.from-range.output-file")
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(outputFile, false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return subnetFromRangeAction(out, input)
+	},
+}
+
+// subnetFromRangeAction is the action function for the subnet from-range command
+func subnetFromRangeAction(out io.Writer, s string) error {
+	// Parse the input string as a range of IPv4 addresses
+	r, err := ip.ParseIPv4Range(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	covering := r.CoveringPrefix()
+
+	// Print only the matching subnets if the --quiet flag is set
+	if viper.GetBool("subnet.from-range.quiet") {
+		for _, network := range r.CIDRs() {
+			fmt.Fprintln(out, network.String())
+		}
+		return nil
+	}
+
+	fmt.Fprintf(out, "Range            : %s (%d addresses)\n", r, r.Size())
+
+	// The range is an exact match for a single subnet
+	if r.IsCIDR() {
+		fmt.Fprintf(out, "Exact match      : %s\n", covering)
+		return nil
+	}
+
+	// The range does not match a single subnet, print the covering subnet and the excess
+	fmt.Fprintf(out, "Exact match      : none\n")
+	fmt.Fprintf(out, "Covering subnet  : %s (%d addresses)\n", covering, uint64(1)<<(32-covering.PrefixLength()))
+
+	// The excess addresses are found before and/or after the range
+	var excess []string
+	if network := ip.IPv4ToInt(covering.Network()); network < r.First {
+		excess = append(excess, fmt.Sprintf("%s-%s", covering.Network(), ip.IntToIPv4(r.First-1)))
+	}
+	if broadcast := ip.IPv4ToInt(covering.Broadcast()); broadcast > r.Last {
+		excess = append(excess, fmt.Sprintf("%s-%s", ip.IntToIPv4(r.Last+1), covering.Broadcast()))
+	}
+	fmt.Fprintf(out, "Excess addresses : %d (%s)\n", r.Excess(), strings.Join(excess, " and "))
+
+	// Print the subnets that together cover exactly the range
+	networks := r.CIDRs()
+	fmt.Fprintf(out, "Exact subnets    : %d\n", len(networks))
+	for _, network := range networks {
+		fmt.Fprintf(out, "  %s\n", network)
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetFromRangeCmd)
+
+	// Enable the --quiet flag to print only the list of exact subnets
+	subnetFromRangeCmd.Flags().BoolP("quiet", "q", false, "only print the subnets that cover exactly the range")
+	viper.BindPFlag("subnet.from-range.quiet", subnetFromRangeCmd.Flags().Lookup("quiet"))
+
+	// Enable the --output-file flag to write the output to a file
+	subnetFromRangeCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.from-range.output-file", subnetFromRangeCmd.Flags().Lookup("output-file"))
+}
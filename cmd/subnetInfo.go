@@ -0,0 +1,165 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetInfoCmd represents the subnet info command
+var subnetInfoCmd = &cobra.Command{
+	Use:   "info <prefix...>",
+	Short: "Show the special-purpose registry entry of a prefix",
+	Long: `Show the special-purpose registry entry of a prefix.
+
+The prefix is looked up in an embedded copy of the IANA IPv4 and IPv6
+Special-Purpose Address Registries (RFC 6890), and the purpose, RFC,
+allocation date and attributes (valid as source or destination, forwardable,
+globally reachable and reserved by protocol) of every registry entry
+covering the prefix are printed. The registry entries inside the prefix are
+listed as well, e.g. for 192.0.0.0/24.
+
+The prefixes can be given as arguments, as addresses (e.g. 192.0.0.9) or
+read from standard input with -.
+
+Examples:
+  iptool subnet info 100.64.0.0/10
+  iptool subnet info 192.0.0.0/24 2001:db8::/32
+  iptool subnet info 10.1.2.3 --json`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no prefixes are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		prefixes, err := readPrefixArgs(args, os.Stdin)
+		if err != nil {
+			return err
+		}
+		return subnetInfoAction(os.Stdout, prefixes)
+	},
+}
+
+// subnetInfoJSON is the JSON output of a prefix of the subnet info command
+type subnetInfoJSON struct {
+	Prefix   netip.Prefix        `json:"prefix"`
+	Covering []ip.SpecialPurpose `json:"covering"`
+	Inside   []ip.SpecialPurpose `json:"inside"`
+}
+
+// subnetInfoAction is the action function for the subnet info command
+func subnetInfoAction(out io.Writer, prefixes []netip.Prefix) error {
+	if viper.GetBool("subnet.info.json") {
+		results := make([]subnetInfoJSON, len(prefixes))
+		for i, prefix := range prefixes {
+			covering, inside := ip.LookupSpecialPurpose(prefix)
+			results[i] = subnetInfoJSON{Prefix: prefix, Covering: covering, Inside: inside}
+			if results[i].Covering == nil {
+				results[i].Covering = []ip.SpecialPurpose{}
+			}
+			if results[i].Inside == nil {
+				results[i].Inside = []ip.SpecialPurpose{}
+			}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for i, prefix := range prefixes {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			printSpecialPurpose(out, prefix)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// printSpecialPurpose is a function that prints the registry entries
+// covering the prefix, and the entries inside it, in the same layout as the
+// inspect command
+func printSpecialPurpose(out io.Writer, prefix netip.Prefix) {
+	covering, inside := ip.LookupSpecialPurpose(prefix)
+	if len(covering) == 0 && len(inside) == 0 {
+		fmt.Fprintf(out, "%s is not in the IANA special-purpose address registries\n", prefix)
+		return
+	}
+
+	for i, s := range covering {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Special-Purpose Registry Entry (%s):\n", prefix)
+		fmt.Fprintf(out, " %-20s : %s\n", "Address block", s.Prefix)
+		fmt.Fprintf(out, " %-20s : %s\n", "Name", s.Name)
+		fmt.Fprintf(out, " %-20s : %s\n", "RFC", s.RFC)
+		fmt.Fprintf(out, " %-20s : %s\n", "Allocation date", s.Allocated)
+
+		// Deprecated entries have a termination date and no attributes
+		if s.Terminated != "" {
+			fmt.Fprintf(out, " %-20s : %s\n", "Termination date", s.Terminated)
+			continue
+		}
+		fmt.Fprintf(out, " %-20s : %s\n", "Source", s.Source)
+		fmt.Fprintf(out, " %-20s : %s\n", "Destination", s.Destination)
+		fmt.Fprintf(out, " %-20s : %s\n", "Forwardable", s.Forwardable)
+		fmt.Fprintf(out, " %-20s : %s\n", "Globally reachable", s.GloballyReachable)
+		fmt.Fprintf(out, " %-20s : %s\n", "Reserved-by-protocol", s.ReservedByProtocol)
+	}
+
+	if len(inside) > 0 {
+		if len(covering) > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Special-Purpose Entries Inside %s:\n", prefix)
+		for _, s := range inside {
+			fmt.Fprintf(out, " %-20s : %s (%s)\n", s.Prefix, s.Name, s.RFC)
+		}
+	}
+}
+
+func init() {
+	subnetCmd.AddCommand(subnetInfoCmd)
+
+	// Define the flag for printing the entries in JSON format
+	subnetInfoCmd.Flags().Bool("json", false, "print the registry entries in JSON format")
+	viper.BindPFlag("subnet.info.json", subnetInfoCmd.Flags().Lookup("json"))
+}
@@ -30,7 +30,9 @@ import (
 	"strings"
 
 	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
 	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -44,16 +46,33 @@ var subnetListCmd = &cobra.Command{
 Filter the list by specifying one or more prefix lengths (integers
 between 0 and 32) as an argument, separated by commas.
 
+Use --min-hosts and --max-hosts to only list the prefix lengths with a number
+of usable hosts in that range, which adds a Hosts column. The first row is
+the longest prefix length with room for --min-hosts hosts, the answer to
+"what prefix length do I need for N hosts".
+
+Use --columns to choose the columns and their order: cidr, mask, addresses,
+hosts, wildcard and hex (the subnet mask in hexadecimal, only printed when
+selected).
+
+The table is fitted to the width of the terminal, use --wide to never fit the
+table, --narrow to always use the compact layout and --no-header to leave out
+the header.
+
 Examples:
   iptool subnet list
   iptool subnet list -p 8,16,24
+  iptool subnet list -p 24,25,26 --no-header
+  iptool subnet list --min-hosts 500
+  iptool subnet list --min-hosts 500 --max-hosts 5000
+  iptool subnet list --columns cidr,mask,wildcard,hosts,hex
 `,
 	Aliases:      []string{"ls"},
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// No arguments allowed
 		if len(args) > 0 {
-			return fmt.Errorf("invalid argument(s): %s", strings.Join(args, " "))
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid argument(s): %s", strings.Join(args, " ")))
 		}
 
 		input := strings.Join(args, " ")
@@ -63,9 +82,24 @@ Examples:
 
 // subnetListAction prints a list of IPv4 subnets
 func subnetListAction(out io.Writer, s string) error {
-	// Print the header for the table
-	fmt.Fprintf(out, "CIDR  Subnet Mask      Addresses   Wildcard Mask\n")
-	fmt.Fprintf(out, "--------------------------------------------------\n")
+	// Get the host count filters, a Hosts column is added when filtering
+	minHosts := viper.GetInt64("subnet.list.min-hosts")
+	maxHosts := viper.GetInt64("subnet.list.max-hosts")
+	filterHosts := minHosts > 0 || maxHosts > 0
+
+	// Create the table (CIDR, Subnet Mask, Addresses, Hosts, Wildcard Mask and
+	// Hex Mask), the Hosts column is only printed by default when filtering
+	table := render.NewTable(out, getRenderOptions("subnet.list", out),
+		render.Column{Title: "CIDR", Align: render.AlignRight},
+		render.Column{Title: "Subnet Mask", Name: "mask", Width: len("255.255.255.255")},
+		render.Column{Title: "Addresses", Width: len("4294967296"), Align: render.AlignRight},
+		render.Column{Title: "Hosts", Width: len("4294967294"), Align: render.AlignRight, Hidden: !filterHosts},
+		render.Column{Title: "Wildcard Mask", Name: "wildcard", Width: len("255.255.255.255")},
+		render.Column{Title: "Hex Mask", Name: "hex", Width: len("0xffffffff"), Hidden: true},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
 
 	// Get the prefix lengths from the viper configuration
 	prefixList := viper.GetIntSlice("subnet.list.prefix-lengths")
@@ -78,6 +112,7 @@ func subnetListAction(out io.Writer, s string) error {
 	}
 
 	// Loop through all subnets
+	printed := 0
 	for _, i := range prefixList {
 		// Print information about the subnet
 		s = fmt.Sprintf("0.0.0.0/%d", i)
@@ -86,8 +121,24 @@ func subnetListAction(out io.Writer, s string) error {
 			return err
 		}
 
+		// Skip the subnets outside the host count filters
+		hosts := int64(subnet.UsableHosts())
+		if (minHosts > 0 && hosts < minHosts) || (maxHosts > 0 && hosts > maxHosts) {
+			continue
+		}
+
 		// Print information about the subnet
-		fmt.Fprintf(out, "%4s  %-16s %-11d %-10s\n", "/"+strconv.Itoa(subnet.PrefixLength()), subnet.Netmask(), subnet.NetworkSize(), subnet.Wildcard())
+		cells := []string{"/" + strconv.Itoa(subnet.PrefixLength()), subnet.Netmask(), fmt.Sprint(subnet.NetworkSize()),
+			fmt.Sprint(hosts), subnet.Wildcard(), "0x" + subnet.Mask.String()}
+		if err := table.Row(cells...); err != nil {
+			return err
+		}
+		printed++
+	}
+
+	// Tell the user if no prefix length matches the host count filters
+	if filterHosts && printed == 0 {
+		return errors.New("no prefix length matches --min-hosts and --max-hosts")
 	}
 
 	// Print the configuration debug if the --debug flag is set
@@ -106,14 +157,35 @@ func init() {
 	subnetListCmd.Flags().IntSliceP("prefix-lengths", "p", []int{}, "a list of prefix lengths (0-32)")
 	viper.BindPFlag("subnet.list.prefix-lengths", subnetListCmd.Flags().Lookup("prefix-lengths"))
 
+	// Define the flags for filtering by the number of usable hosts
+	subnetListCmd.Flags().Int64("min-hosts", 0, "only list prefix lengths with at least this many usable hosts")
+	viper.BindPFlag("subnet.list.min-hosts", subnetListCmd.Flags().Lookup("min-hosts"))
+	subnetListCmd.Flags().Int64("max-hosts", 0, "only list prefix lengths with at most this many usable hosts")
+	viper.BindPFlag("subnet.list.max-hosts", subnetListCmd.Flags().Lookup("max-hosts"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetListCmd, "subnet.list")
+	subnetListCmd.RegisterFlagCompletionFunc("columns", completeList(func() []string {
+		return []string{"cidr", "mask", "addresses", "hosts", "wildcard", "hex"}
+	}))
+
 	// Validate the prefix lengths
 	subnetListCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
 		for _, length := range viper.GetIntSlice("subnet.list.prefix-lengths") {
 			if length < 0 || length > 32 {
 				message := fmt.Sprintf("invalid prefix length: %d (must be between 0 and 32)", length)
-				return errors.New(message)
+				return exitcode.New(exitcode.Usage, errors.New(message))
 			}
 		}
+
+		// Validate the host count filters
+		minHosts, maxHosts := viper.GetInt64("subnet.list.min-hosts"), viper.GetInt64("subnet.list.max-hosts")
+		if minHosts < 0 || maxHosts < 0 {
+			return exitcode.New(exitcode.Usage, errors.New("invalid host count: --min-hosts and --max-hosts must be 0 or greater"))
+		}
+		if maxHosts > 0 && minHosts > maxHosts {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid host count: --min-hosts %d is greater than --max-hosts %d", minHosts, maxHosts))
+		}
 		return nil
 	}
 }
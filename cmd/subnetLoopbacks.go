@@ -0,0 +1,289 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/iac"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// subnetLoopbacksFormats are the output formats of the subnet loopbacks command
+var subnetLoopbacksFormats = []string{"table", "csv", "json", "yaml", iac.FormatAnsibleInventory, iac.FormatTerraformTFVars}
+
+// subnetLoopbacksCmd represents the subnet loopbacks command
+var subnetLoopbacksCmd = &cobra.Command{
+	Use:   "loopbacks <prefix>",
+	Short: "Assign loopback addresses from a prefix to a list of devices",
+	Long: `Assign loopback addresses from a prefix to a list of devices.
+
+The loopback addresses (/32 or /128 host prefixes) are assigned in order from
+the start of the prefix, skipping the network and broadcast addresses of IPv4
+prefixes and the Subnet-Router anycast address (::) of IPv6 prefixes.
+
+The number of loopbacks is given with --count, or is the number of devices in
+the file given with --names-file, which has the name of a device on every line
+(everything after a # is ignored), e.g.:
+
+  core1
+  core2
+  edge1   # Stockholm
+
+The --format flag selects the output format: table (default), csv, json,
+yaml, ansible-inventory or terraform-tfvars. The csv, json and yaml formats
+list the device, the interface (set with --interface) and the address of
+every loopback, ready to be imported into a source of truth. The
+ansible-inventory format lists the loopbacks in the loopbacks variable of the
+all group, and every device as a host with the loopback_interface and
+loopback_address variables. The terraform-tfvars format assigns the
+loopbacks to the loopbacks variable.
+
+Examples:
+  iptool subnet loopbacks 10.255.255.0/24 --count 40
+  iptool subnet loopbacks 10.255.255.0/24 --names-file routers.txt
+  iptool subnet loopbacks 10.255.255.0/24 --names-file routers.txt --format yaml -o loopbacks.yaml
+  iptool subnet loopbacks 10.255.255.0/24 --names-file routers.txt --format terraform-tfvars -o loopbacks.auto.tfvars
+  iptool subnet loopbacks 2001:db8:ffff::/64 --names-file routers.txt --interface lo0 --format csv`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(viper.GetString("subnet.loopbacks.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return subnetLoopbacksAction(out, os.Stdin, args)
+	},
+}
+
+// loopback is a loopback address assigned to a device
+type loopback struct {
+	Device    string `json:"device,omitempty" yaml:"device,omitempty"`
+	Interface string `json:"interface" yaml:"interface"`
+	Address   string `json:"address" yaml:"address"`
+}
+
+// readDeviceNames is a function that reads the names of the devices from a
+// file, one name per line (- reads standard input)
+func readDeviceNames(file string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 1 {
+			return nil, fmt.Errorf("%s: line %d: expected the name of a device, got %d fields", file, line, len(fields))
+		}
+		if slices.Contains(names, fields[0]) {
+			return nil, fmt.Errorf("%s: line %d: duplicate device %s", file, line, fields[0])
+		}
+		names = append(names, fields[0])
+	}
+	return names, scanner.Err()
+}
+
+// subnetLoopbacksAction is the action function for the subnet loopbacks command
+func subnetLoopbacksAction(out io.Writer, stdin io.Reader, args []string) error {
+	// Parse the prefix to assign the loopbacks from, e.g. 10.255.255.0/24
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	if len(prefixes) != 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("exactly one prefix must be given"))
+	}
+	parent := prefixes[0]
+
+	// Check the output format
+	format := strings.ToLower(viper.GetString("subnet.loopbacks.format"))
+	if !slices.Contains(subnetLoopbacksFormats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(subnetLoopbacksFormats, ", ")))
+	}
+
+	// Read the names of the devices if --names-file is set
+	var names []string
+	if file := viper.GetString("subnet.loopbacks.names-file"); file != "" {
+		if names, err = readDeviceNames(file, stdin); err != nil {
+			return err
+		}
+	}
+
+	// The number of loopbacks defaults to the number of devices
+	count := viper.GetInt("subnet.loopbacks.count")
+	if count == 0 {
+		count = len(names)
+	}
+	if count < 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no loopbacks to assign, use --count or --names-file"))
+	}
+	if count < len(names) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--count %d is less than the %d devices in the names file", count, len(names)))
+	}
+
+	prefixList, err := ip.AllocateLoopbacks(parent, count)
+	if err != nil {
+		return err
+	}
+	iface := viper.GetString("subnet.loopbacks.interface")
+	loopbacks := make([]loopback, len(prefixList))
+	for i, prefix := range prefixList {
+		loopbacks[i] = loopback{Interface: iface, Address: prefix.String()}
+		if i < len(names) {
+			loopbacks[i].Device = names[i]
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return writeLoopbacks(out, format, loopbacks)
+}
+
+// writeLoopbacks is a function that writes the loopbacks in the output format
+func writeLoopbacks(w io.Writer, format string, loopbacks []loopback) error {
+	switch format {
+	case iac.FormatAnsibleInventory, iac.FormatTerraformTFVars:
+		writer, err := iac.NewWriter(w, format, "loopbacks")
+		if err != nil {
+			return err
+		}
+		for _, l := range loopbacks {
+			record := iac.Record{{Key: "interface", Value: l.Interface}, {Key: "address", Value: l.Address}}
+			if l.Device != "" {
+				record = append(iac.Record{{Key: "device", Value: l.Device}}, record...)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+
+		// Every device is a host of the inventory, with its loopback
+		for _, l := range loopbacks {
+			if l.Device == "" {
+				continue
+			}
+			if err := writer.WriteHost(l.Device, iac.Record{{Key: "loopback_interface", Value: l.Interface}, {Key: "loopback_address", Value: l.Address}}); err != nil {
+				return err
+			}
+		}
+		return writer.Close()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(loopbacks)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(map[string][]loopback{"loopbacks": loopbacks}); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"device", "interface", "address"})
+		for _, l := range loopbacks {
+			cw.Write([]string{l.Device, l.Interface, l.Address})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	table := render.NewTable(w, getRenderOptions("subnet.loopbacks", w),
+		render.Column{Title: "Device", Truncate: true},
+		render.Column{Title: "Interface"},
+		render.Column{Title: "Address"},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	for _, l := range loopbacks {
+		table.Fit(l.Device, l.Interface, l.Address)
+	}
+	table.Header()
+	for _, l := range loopbacks {
+		table.Row(l.Device, l.Interface, l.Address)
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetLoopbacksCmd)
+
+	// Define the flags for the number of loopbacks and the names of the devices
+	subnetLoopbacksCmd.Flags().IntP("count", "c", 0, "number of loopbacks to assign (default the number of devices in --names-file)")
+	viper.BindPFlag("subnet.loopbacks.count", subnetLoopbacksCmd.Flags().Lookup("count"))
+	subnetLoopbacksCmd.Flags().StringP("names-file", "n", "", "file with the names of the devices, one per line (- reads standard input)")
+	viper.BindPFlag("subnet.loopbacks.names-file", subnetLoopbacksCmd.Flags().Lookup("names-file"))
+	subnetLoopbacksCmd.Flags().StringP("interface", "i", "Loopback0", "name of the loopback interface of the devices")
+	viper.BindPFlag("subnet.loopbacks.interface", subnetLoopbacksCmd.Flags().Lookup("interface"))
+
+	// Define the flag for the output format
+	subnetLoopbacksCmd.Flags().String("format", "table", "output format ("+strings.Join(subnetLoopbacksFormats, ", ")+")")
+	viper.BindPFlag("subnet.loopbacks.format", subnetLoopbacksCmd.Flags().Lookup("format"))
+	subnetLoopbacksCmd.RegisterFlagCompletionFunc("format", completeValues(subnetLoopbacksFormats...))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetLoopbacksCmd, "subnet.loopbacks")
+
+	// Add flag for --output-file path
+	subnetLoopbacksCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.loopbacks.output-file", subnetLoopbacksCmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetOverlapsCmd represents the subnet overlaps command
+var subnetOverlapsCmd = &cobra.Command{
+	Use:   "overlaps <prefix...|->",
+	Short: "Find overlapping and duplicate prefixes in a list",
+	Long: `Find overlapping and duplicate prefixes in a list.
+
+Every pair of prefixes where one prefix contains the other is reported. The
+command exits with a non-zero exit code when overlaps are found. Use -4 or -6
+to only check the prefixes of one address family.
+
+The prefixes are given as arguments, separated by commas or spaces, or are
+read from standard input (one or more per line) when - is given.
+
+Examples:
+  iptool subnet overlaps 10.0.0.0/16 10.0.4.0/22 10.1.0.0/16
+  cat prefixes.txt | iptool subnet overlaps -`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return subnetOverlapsAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// subnetOverlapsAction is the action function for the subnet overlaps command
+func subnetOverlapsAction(out io.Writer, stdin io.Reader, args []string) error {
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	prefixes = getFamily("subnet.overlaps").FilterPrefixes(prefixes)
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("subnet.overlaps.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	overlaps := ip.FindOverlaps(prefixes)
+	for _, o := range overlaps {
+		if o.Outer == o.Inner {
+			fmt.Fprintf(outputStream, "duplicate: %s\n", o.Outer)
+		} else {
+			fmt.Fprintf(outputStream, "overlap: %s contains %s\n", o.Outer, o.Inner)
+		}
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if len(overlaps) > 0 {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("found %d overlapping prefix pair(s)", len(overlaps)))
+	}
+	fmt.Fprintln(out, "No overlapping prefixes found")
+	return nil
+}
+
+func init() {
+	subnetCmd.AddCommand(subnetOverlapsCmd)
+	addFamilyFlags(subnetOverlapsCmd, "subnet.overlaps")
+
+	// Enable the --output-file flag to write the output to a file
+	subnetOverlapsCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.overlaps.output-file", subnetOverlapsCmd.Flags().Lookup("output-file"))
+}
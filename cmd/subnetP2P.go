@@ -0,0 +1,310 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/iac"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetP2PFormats are the output formats of the subnet p2p command
+var subnetP2PFormats = []string{"table", "csv", "json", iac.FormatAnsibleInventory, iac.FormatTerraformTFVars}
+
+// subnetP2PCmd represents the subnet p2p command
+var subnetP2PCmd = &cobra.Command{
+	Use:   "p2p <prefix>",
+	Short: "Allocate point-to-point link subnets from a prefix",
+	Long: `Allocate point-to-point link subnets from a prefix.
+
+The link subnets are allocated in order from the start of the prefix, and
+the addresses of the A-end and the B-end of every link are printed. IPv4
+links are /30 subnets, where the ends are the first two usable addresses,
+or /31 subnets (RFC 3021) with --use-31, where the ends are the two
+addresses of the subnet. IPv6 links are /127 subnets (RFC 6164).
+
+The number of links is given with --links, or is the number of links in
+the file given with --names-file, which has the names of the devices at
+the A-end and the B-end of every link on a line (separated by whitespace
+or a comma, everything after a # is ignored), e.g.:
+
+  core1 core2
+  core1 edge1   # uplink
+
+The --format flag selects the output format: table (default), csv, json,
+ansible-inventory or terraform-tfvars. The ansible-inventory format lists
+the links in the p2p_links variable of the all group, and every named
+device as a host with its ends of the links in the p2p_interfaces variable.
+The terraform-tfvars format assigns the links to the p2p_links variable.
+
+Examples:
+  iptool subnet p2p 10.255.0.0/24 --links 60
+  iptool subnet p2p 10.255.0.0/24 --links 60 --use-31
+  iptool subnet p2p 10.255.0.0/24 --names-file links.txt --format csv
+  iptool subnet p2p 10.255.0.0/24 --names-file links.txt --format ansible-inventory -o hosts.yaml
+  iptool subnet p2p 2001:db8:ff::/64 --links 10`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(viper.GetString("subnet.p2p.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return subnetP2PAction(out, os.Stdin, args)
+	},
+}
+
+// readLinkNames is a function that reads the names of the devices at the
+// A-end and the B-end of every link from a file (- reads standard input)
+func readLinkNames(file string, stdin io.Reader) ([][2]string, error) {
+	r := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names [][2]string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: line %d: expected the names of the A-end and the B-end, got %d field(s)", file, line, len(fields))
+		}
+		names = append(names, [2]string{fields[0], fields[1]})
+	}
+	return names, scanner.Err()
+}
+
+// subnetP2PAction is the action function for the subnet p2p command
+func subnetP2PAction(out io.Writer, stdin io.Reader, args []string) error {
+	// Parse the prefix to allocate the links from, e.g. 10.255.0.0/24
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	if len(prefixes) != 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("exactly one prefix must be given"))
+	}
+	parent := prefixes[0]
+
+	// Check the output format
+	format := strings.ToLower(viper.GetString("subnet.p2p.format"))
+	if !slices.Contains(subnetP2PFormats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(subnetP2PFormats, ", ")))
+	}
+
+	// Read the names of the ends of the links if --names-file is set
+	var names [][2]string
+	if file := viper.GetString("subnet.p2p.names-file"); file != "" {
+		if names, err = readLinkNames(file, stdin); err != nil {
+			return err
+		}
+	}
+
+	// The number of links defaults to the number of named links
+	count := viper.GetInt("subnet.p2p.links")
+	if count == 0 {
+		count = len(names)
+	}
+	if count < 1 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("no links to allocate, use --links or --names-file"))
+	}
+	if count < len(names) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--links %d is less than the %d links in the names file", count, len(names)))
+	}
+
+	// IPv4 links are /30 (or /31 with --use-31), IPv6 links are /127
+	bits := 30
+	if viper.GetBool("subnet.p2p.use-31") {
+		bits = 31
+	}
+	if parent.Addr().Is6() {
+		bits = 127
+	}
+	links, err := ip.AllocateLinks(parent, bits, count)
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	if iac.IsFormat(format) {
+		return writeP2PLinks(out, format, links, names)
+	}
+
+	// Create the table, the name columns are only printed when the links are named
+	columns := []render.Column{
+		{Title: "Link", Align: render.AlignRight},
+		{Title: "Prefix"},
+		{Title: "A-end"},
+		{Title: "B-end"},
+	}
+	if len(names) > 0 {
+		columns = append(columns, render.Column{Title: "A-end name", Truncate: true}, render.Column{Title: "B-end name", Truncate: true})
+	}
+	opts := getRenderOptions("subnet.p2p", out)
+	opts.Format = render.Format(format)
+	table := render.NewTable(out, opts, columns...)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	rows := make([][]string, len(links))
+	for i, link := range links {
+		rows[i] = []string{strconv.Itoa(i + 1), link.Prefix.String(), link.A.String(), link.B.String()}
+		if len(names) > 0 {
+			// Links beyond the named links have no names
+			var name [2]string
+			if i < len(names) {
+				name = names[i]
+			}
+			rows[i] = append(rows[i], name[0], name[1])
+		}
+		table.Fit(rows[i]...)
+	}
+	table.Header()
+	for _, row := range rows {
+		table.Row(row...)
+	}
+
+	// Summarize the allocation in the table format
+	if format == "table" {
+		capacity := ip.LinkCapacity(parent, bits)
+		free := fmt.Sprint(capacity - count)
+		if capacity == int(^uint(0)>>1) {
+			free = "many"
+		}
+		fmt.Fprintf(out, "\n%d /%d links allocated from %s, room for %s more\n", count, bits, parent, free)
+	}
+	return nil
+}
+
+// writeP2PLinks is a function that writes the links in one of the
+// infrastructure as code formats, with a host for every named device in
+// the Ansible inventory
+func writeP2PLinks(w io.Writer, format string, links []ip.Link, names [][2]string) error {
+	writer, err := iac.NewWriter(w, format, "p2p_links")
+	if err != nil {
+		return err
+	}
+
+	// The interfaces of the devices, in the order the devices are named
+	var devices []string
+	interfaces := map[string][]iac.Record{}
+	addInterface := func(device string, link int, prefix, address, peer, peerAddress string) {
+		if _, ok := interfaces[device]; !ok {
+			devices = append(devices, device)
+		}
+		interfaces[device] = append(interfaces[device], iac.Record{
+			{Key: "link", Value: link},
+			{Key: "prefix", Value: prefix},
+			{Key: "address", Value: address},
+			{Key: "peer", Value: peer},
+			{Key: "peer_address", Value: peerAddress},
+		})
+	}
+
+	for i, link := range links {
+		record := iac.Record{
+			{Key: "link", Value: i + 1},
+			{Key: "prefix", Value: link.Prefix.String()},
+			{Key: "a_end", Value: link.A.String()},
+			{Key: "b_end", Value: link.B.String()},
+		}
+		if i < len(names) {
+			a, b := names[i][0], names[i][1]
+			record = append(record, iac.Field{Key: "a_end_name", Value: a}, iac.Field{Key: "b_end_name", Value: b})
+			addInterface(a, i+1, link.Prefix.String(), link.A.String(), b, link.B.String())
+			addInterface(b, i+1, link.Prefix.String(), link.B.String(), a, link.A.String())
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	for _, device := range devices {
+		if err := writer.WriteHost(device, iac.Record{{Key: "p2p_interfaces", Value: interfaces[device]}}); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetP2PCmd)
+
+	// Define the flags for the number of links and the size of the link subnets
+	subnetP2PCmd.Flags().IntP("links", "c", 0, "number of links to allocate (default the number of links in --names-file)")
+	viper.BindPFlag("subnet.p2p.links", subnetP2PCmd.Flags().Lookup("links"))
+	subnetP2PCmd.Flags().Bool("use-31", false, "allocate /31 links (RFC 3021) instead of /30 links")
+	viper.BindPFlag("subnet.p2p.use-31", subnetP2PCmd.Flags().Lookup("use-31"))
+
+	// Define the flag for the file with the names of the ends of the links
+	subnetP2PCmd.Flags().StringP("names-file", "n", "", "file with the A-end and B-end names of every link (- reads standard input)")
+	viper.BindPFlag("subnet.p2p.names-file", subnetP2PCmd.Flags().Lookup("names-file"))
+
+	// Define the flag for the output format
+	subnetP2PCmd.Flags().String("format", "table", "output format ("+strings.Join(subnetP2PFormats, ", ")+")")
+	viper.BindPFlag("subnet.p2p.format", subnetP2PCmd.Flags().Lookup("format"))
+	subnetP2PCmd.RegisterFlagCompletionFunc("format", completeValues(subnetP2PFormats...))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetP2PCmd, "subnet.p2p")
+
+	// Add flag for --output-file path
+	subnetP2PCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.p2p.output-file", subnetP2PCmd.Flags().Lookup("output-file"))
+}
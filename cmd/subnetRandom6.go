@@ -0,0 +1,166 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetRandom6Cmd represents the subnet random6 command
+var subnetRandom6Cmd = &cobra.Command{
+	Use:   "random6 <prefix>",
+	Short: "Generate IPv6 addresses in a prefix for documentation and labs",
+	Long: `Generate IPv6 addresses in a prefix for documentation and labs.
+
+The addresses are generated in one of the styles of real IPv6 networks:
+
+  random      random host bits, like temporary addresses (RFC 8981)
+  sequential  numbered from ::1, like manually assigned addresses
+  eui64       modified EUI-64 interface identifiers of random MAC addresses,
+              like the SLAAC addresses of older hosts (requires a /64)
+  stable      stable, semantically opaque addresses (RFC 7217), like the
+              SLAAC addresses of current operating systems (requires a /64)
+
+The addresses are unique and the subnet-router anycast address (the address
+of the prefix itself) is never generated.
+
+Use --seed to generate the same random and eui64 addresses every time. The
+stable addresses are derived from --secret (random unless given), the
+--interface name and the --network-id, every address being the address of a
+host with a secret key of its own, derived from the secret and the number of
+the host. With the same --secret, the same addresses are generated.
+
+Examples:
+  iptool subnet random6 2001:db8::/64
+  iptool subnet random6 2001:db8::/64 --count 10 --style eui64 --seed 42
+  iptool subnet random6 2001:db8:1:2::/64 --style stable --secret lab1
+  iptool subnet random6 2001:db8::/120 --count 5 --style sequential --cidr`,
+	Args:              cobra.MaximumNArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(viper.GetString("subnet.random6.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return subnetRandom6Action(out, resolveAlias(args[0]))
+	},
+}
+
+// subnetRandom6Action is the action function for the subnet random6 command
+func subnetRandom6Action(out io.Writer, s string) error {
+	prefix, err := ip.ParsePrefix(s)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	if !prefix.Addr().Is6() {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid IPv6 prefix: %s", s))
+	}
+
+	style := strings.ToLower(viper.GetString("subnet.random6.style"))
+	opts := ip.GenerateOptions{
+		Interface: viper.GetString("subnet.random6.interface"),
+		NetworkID: viper.GetString("subnet.random6.network-id"),
+	}
+
+	// A seed of 0 means a random seed
+	if seed := viper.GetInt64("subnet.random6.seed"); seed != 0 {
+		opts.Rand = mathrand.New(mathrand.NewSource(seed))
+	}
+
+	// Without --secret, the stable addresses are derived from a random secret
+	opts.Secret = []byte(viper.GetString("subnet.random6.secret"))
+	if len(opts.Secret) == 0 {
+		opts.Secret = make([]byte, 16)
+		if _, err := rand.Read(opts.Secret); err != nil {
+			return err
+		}
+	}
+
+	addrs, err := ip.GenerateIPv6(prefix, viper.GetInt("subnet.random6.count"), style, opts)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Print the addresses, with the prefix length if the --cidr flag is set
+	for _, addr := range addrs {
+		if viper.GetBool("subnet.random6.cidr") {
+			fmt.Fprintf(out, "%s/%d\n", addr, prefix.Bits())
+			continue
+		}
+		fmt.Fprintln(out, addr)
+	}
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetRandom6Cmd)
+
+	// Define the flags for the number and the style of the addresses
+	subnetRandom6Cmd.Flags().IntP("count", "c", 10, "number of addresses to generate")
+	viper.BindPFlag("subnet.random6.count", subnetRandom6Cmd.Flags().Lookup("count"))
+	subnetRandom6Cmd.Flags().StringP("style", "s", ip.StyleRandom, "style of the addresses: random, sequential, eui64 or stable")
+	viper.BindPFlag("subnet.random6.style", subnetRandom6Cmd.Flags().Lookup("style"))
+	subnetRandom6Cmd.RegisterFlagCompletionFunc("style", completeValues(ip.Styles...))
+	subnetRandom6Cmd.Flags().Int64("seed", 0, "seed of the random and eui64 addresses, to generate the same addresses every time (default random)")
+	viper.BindPFlag("subnet.random6.seed", subnetRandom6Cmd.Flags().Lookup("seed"))
+
+	// Define the flags for the parameters of the stable addresses (RFC 7217)
+	subnetRandom6Cmd.Flags().String("secret", "", "secret key of the stable addresses (default random)")
+	viper.BindPFlag("subnet.random6.secret", subnetRandom6Cmd.Flags().Lookup("secret"))
+	subnetRandom6Cmd.Flags().String("interface", "eth0", "interface name of the stable addresses")
+	viper.BindPFlag("subnet.random6.interface", subnetRandom6Cmd.Flags().Lookup("interface"))
+	subnetRandom6Cmd.Flags().String("network-id", "", "network ID of the stable addresses, e.g. the SSID of a wireless network")
+	viper.BindPFlag("subnet.random6.network-id", subnetRandom6Cmd.Flags().Lookup("network-id"))
+
+	// Define the flags for the output
+	subnetRandom6Cmd.Flags().Bool("cidr", false, "print the addresses with the prefix length")
+	viper.BindPFlag("subnet.random6.cidr", subnetRandom6Cmd.Flags().Lookup("cidr"))
+	subnetRandom6Cmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.random6.output-file", subnetRandom6Cmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetSortCmd represents the subnet sort command
+var subnetSortCmd = &cobra.Command{
+	Use:   "sort [prefix...|-]",
+	Short: "Sort a list of prefixes numerically",
+	Long: `Sort a list of prefixes numerically.
+
+IPv4 prefixes are sorted before IPv6 prefixes, then by address and prefix
+length, so that a prefix is listed before the prefixes it contains. Use
+--dedupe to remove duplicate prefixes and --remove-contained to also remove
+the prefixes that are already covered by another prefix in the list. Use -4
+or -6 to only keep the prefixes of one address family.
+
+The prefixes are read from the file given with --input-file, or are given as
+arguments, separated by commas or spaces. Use - to read them from standard
+input (one or more per line).
+
+Examples:
+  iptool subnet sort --input-file nets.txt
+  iptool subnet sort --input-file nets.txt --dedupe --remove-contained
+  iptool subnet sort 10.0.2.0/24 10.0.0.0/24 10.0.1.0/24
+  cat nets.txt | iptool subnet sort - --dedupe
+  iptool subnet sort --input-file nets.txt -6`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no input is provided, print a short help text
+		if len(args) == 0 && viper.GetString("subnet.sort.input-file") == "" {
+			cmd.Help()
+			return nil
+		}
+		return subnetSortAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// subnetSortAction is the action function for the subnet sort command
+func subnetSortAction(out io.Writer, stdin io.Reader, args []string) error {
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+
+	// Read the prefixes from the input file
+	if inputFile := viper.GetString("subnet.sort.input-file"); inputFile != "" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		list, err := ip.ParsePrefixes(file)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("%s: %w", inputFile, err))
+		}
+		prefixes = append(prefixes, list...)
+	}
+
+	// Keep the prefixes of the selected address family only
+	prefixes = getFamily("subnet.sort").FilterPrefixes(prefixes)
+
+	// Sort the prefixes and remove the duplicates and covered prefixes if requested
+	ip.SortPrefixes(prefixes)
+	if viper.GetBool("subnet.sort.remove-contained") {
+		prefixes = ip.RemoveContained(prefixes)
+	} else if viper.GetBool("subnet.sort.dedupe") {
+		prefixes = ip.DedupePrefixes(prefixes)
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("subnet.sort.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	for _, prefix := range prefixes {
+		fmt.Fprintln(outputStream, prefix)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	subnetCmd.AddCommand(subnetSortCmd)
+	addFamilyFlags(subnetSortCmd, "subnet.sort")
+
+	// Define the flag for reading the prefixes from a file
+	subnetSortCmd.Flags().StringP("input-file", "i", "", "read the prefixes from file")
+	viper.BindPFlag("subnet.sort.input-file", subnetSortCmd.Flags().Lookup("input-file"))
+
+	// Define the flag for removing duplicate prefixes
+	subnetSortCmd.Flags().BoolP("dedupe", "d", false, "remove duplicate prefixes")
+	viper.BindPFlag("subnet.sort.dedupe", subnetSortCmd.Flags().Lookup("dedupe"))
+
+	// Define the flag for removing prefixes covered by other prefixes
+	subnetSortCmd.Flags().BoolP("remove-contained", "r", false, "remove prefixes contained in another prefix in the list (implies --dedupe)")
+	viper.BindPFlag("subnet.sort.remove-contained", subnetSortCmd.Flags().Lookup("remove-contained"))
+
+	// Enable the --output-file flag to write the output to a file
+	subnetSortCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.sort.output-file", subnetSortCmd.Flags().Lookup("output-file"))
+}
@@ -25,13 +25,22 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/netip"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/envelope"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/iac"
 	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/progress"
+	"github.com/bitcanon/iptool/render"
 	"github.com/bitcanon/iptool/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -41,18 +50,70 @@ var subnetSplitCmd = &cobra.Command{
 	Short: "Splits a given subnet into smaller subnets",
 	Long: `Splits a given subnet into smaller subnets based on the specified size or number of subnets.
 
+Very large splits can be narrowed down with --offset (skip the first N subnets)
+and --limit (print at most N subnets), or paged with --page-size, which pauses
+after every page when the output is written to a terminal.
+
+To continue an existing allocation plan, use --start to begin at the subnet
+containing an address instead of the first subnet (--offset then skips
+subnets from there), and --count (an alias of --limit) to print only the
+next N subnets.
+
+The --format flag selects the output format: table (default), csv, json,
+markdown, markdown-checklist, ansible-inventory or terraform-tfvars. The
+markdown-checklist format adds a checkbox and an empty "Assigned to" column
+to every subnet, so that the table can be pasted into a wiki page to track
+which subnets have been allocated. The json format writes one subnet record
+per line, which can be read by the commands that accept JSON input (e.g.
+iptool subnet summarize -). The ansible-inventory and terraform-tfvars
+formats write the subnets as the subnets variable of an Ansible inventory
+(YAML) or of a Terraform variable definitions (.tfvars) file.
+
+The subnets can be labeled with --names (e.g. mgmt,voice,data,guest), which adds
+a name column to the output. The names are assigned to the subnets in order,
+starting at the first subnet, or at a later one when --skip N is given.
+
+Splits of large networks (e.g. a /8) can be sharded into multiple files with
+--split-output-by, so that no single file grows to gigabytes. With index, a new
+file is started every --rows-per-file subnets (10000 by default) and the files
+are numbered (subnets-00001.csv, subnets-00002.csv, ...). With prefix, every
+file holds the subnets of one block of at most --rows-per-file subnets and is
+named after the block (e.g. subnets-10.0.0.0_16.csv). The files are named after
+--output-file, which is required, and every file has its own header.
+
+Multi-tier address plans are split in one go with --levels, e.g. --levels
+26,28,30 splits the network into /26s, every /26 into /28s and every /28 into
+/30s. The table shows the hierarchy by indenting the prefixes, and the csv and
+json formats add the parent of every subnet.
+
+The table is fitted to the width of the terminal, by moving the columns closer
+together and truncating long names. Use --wide to never fit the table, --narrow
+to always use the compact layout and --no-header to leave out the header.
+
 Examples:
   iptool subnet split 10.0.0.0/24 --bits 30
   iptool subnet split 10.0.0.0/8 --bits 16 --limit 10
+  iptool subnet split 10.0.0.0/8 --bits 30 --offset 1000 --limit 100
+  iptool subnet split 10.0.0.0/16 --bits 24 --start 10.0.2.0 --count 4
+  iptool subnet split 10.0.0.0/8 --bits 30 --page-size 50
+  iptool subnet split 10.0.0.0/22 --bits 26 --format markdown-checklist
+  iptool subnet split 10.0.0.0/24 --networks 4 --names mgmt,voice,data,guest
+  iptool subnet split 10.0.0.0/22 --bits 24 --names voice,data --skip 1
+  iptool subnet split 10.0.0.0/8 --bits 30 --csv -o subnets.csv --split-output-by index
+  iptool subnet split 10.0.0.0/8 --bits 24 --csv -o subnets.csv --split-output-by prefix --rows-per-file 256
+  iptool subnet split 10.0.0.0/24 --networks 4 --names mgmt,voice,data,guest --format terraform-tfvars
+  iptool subnet split 10.0.0.0/24 --levels 26,28
+  iptool subnet split 10.0.0.0/16 --levels 20,24,26 --csv -o plan.csv
   iptool subnet split 10.0.0.0 255.255.255.0 --networks 4`,
-	SilenceUsage: true,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If no arguments are provided, print a short help text
 		if len(args) == 0 {
 			cmd.Help()
 			return nil
 		}
-		input := args[0]
+		input := strings.Join(resolveAliases(args), " ")
 
 		return subnetSplitAction(os.Stdout, input)
 	},
@@ -63,7 +124,12 @@ func subnetSplitAction(out io.Writer, s string) error {
 	// Parse the input string as an IP address
 	network, err := ip.ParseIPv4(s)
 	if err != nil {
-		return err
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Split the network into multiple levels if --levels is set
+	if levels := viper.GetIntSlice("subnet.split.levels"); len(levels) > 0 {
+		return subnetSplitLevelsAction(out, network, levels)
 	}
 
 	// Parse the network count and bits from the configuration
@@ -72,12 +138,12 @@ func subnetSplitAction(out io.Writer, s string) error {
 
 	// If both bits and networks are specified, return an error
 	if bits > 0 && networks > 0 {
-		return fmt.Errorf("both --bits and --networks cannot be specified at the same time, see --help for more information")
+		return exitcode.New(exitcode.Usage, fmt.Errorf("both --bits and --networks cannot be specified at the same time, see --help for more information"))
 	}
 
 	// If neither bits nor networks are specified, return an error
 	if bits == 0 && networks == 0 {
-		return fmt.Errorf("either --bits or --networks must be specified, see --help for more information")
+		return exitcode.New(exitcode.Usage, fmt.Errorf("either --bits or --networks must be specified, see --help for more information"))
 	}
 
 	// If the number of networks is specified, calculate the number of bits required
@@ -95,76 +161,355 @@ func subnetSplitAction(out io.Writer, s string) error {
 		bits = 32 - hostBits
 	}
 
-	// Split the subnet into smaller subnets
-	prefixList, err := network.Split(bits)
+	// Determine the range of subnets to print
+	total, err := network.SubnetCount(bits)
 	if err != nil {
-		return err
+		return exitcode.New(exitcode.Usage, err)
+	}
+	offset := uint64(viper.GetInt("subnet.split.offset"))
+
+	// Start at the subnet containing the address given with --start, the
+	// offset then skips subnets from there
+	if start := viper.GetString("subnet.split.start"); start != "" {
+		if !ip.IsIPv4(start) {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --start address: %s", start))
+		}
+		index, err := network.SubnetIndex(bits, start)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("the --start address %w", err))
+		}
+		offset += index
 	}
 
-	// Find the length of the longest broadcast address (for padding)
-	// This is used to align Prefix, Network, Broadcast, First, Last, Hosts
+	// Print at most --limit subnets
+	limit := viper.GetInt("subnet.split.limit")
+	count := total - min(offset, total)
+	if limit > 0 && uint64(limit) < count {
+		count = uint64(limit)
+	}
+
+	// Find the length of the longest broadcast address (for the column widths)
+	// This is used to align Prefix, Network, Broadcast, First, Last
 	maxLength := 0
-	for _, prefix := range prefixList {
+	err = network.SplitFunc(bits, offset, func(index uint64, prefix *ip.IPv4) bool {
 		broadcast := prefix.Broadcast()
 		if len(broadcast) > maxLength {
 			maxLength = len(broadcast)
 		}
+		return index+1 < offset+count
+	})
+	if err != nil {
+		return err
 	}
-	maxLength += 1
 
-	// Format string for padding
-	fmtString := fmt.Sprintf("%%-%ds %%-%ds %%-%ds %%-%ds %%-%ds %%s\n", maxLength+3, maxLength, maxLength, maxLength, maxLength)
-
-	// Calculate the total length of the output
-	columns := 5
-	spacesBetweenColumns := 2 * columns
-	totalLength := (maxLength * columns) + spacesBetweenColumns + 3
-
-	// Create a string of dashes of the total length
-	dashLine := strings.Repeat("-", totalLength)
+	// Label the subnets with the names, starting at the subnet given with --skip
+	names := viper.GetStringSlice("subnet.split.names")
+	skip := uint64(viper.GetInt("subnet.split.skip"))
+	nameOf := func(index uint64) string {
+		if index < skip || index-skip >= uint64(len(names)) {
+			return ""
+		}
+		return names[index-skip]
+	}
 
 	// Determine the output file using Viper
 	outputFile := viper.GetString("subnet.split.output-file")
 
-	// Get the output stream
-	outputStream, err := utils.GetOutputStream(outputFile, false)
-	if err != nil {
+	// Shard the output into multiple files if --split-output-by is set
+	splitBy := strings.ToLower(viper.GetString("subnet.split.split-output-by"))
+	rowsPerFile := uint64(viper.GetInt("subnet.split.rows-per-file"))
+	shardBits := 0
+	if splitBy == "prefix" {
+		// Every file holds the subnets of one block, with at most --rows-per-file subnets
+		shardBits = max(bits-int(math.Log2(float64(rowsPerFile))), network.PrefixLength())
+	}
+
+	// Create the table, the name column is only printed when the subnets are named
+	var columns []render.Column
+	if len(names) > 0 {
+		columns = append(columns, render.Column{Title: "Name", Truncate: true})
+	}
+	columns = append(columns,
+		render.Column{Title: "Prefix", Width: maxLength + 3},
+		render.Column{Title: "Network", Width: maxLength},
+		render.Column{Title: "First", Width: maxLength},
+		render.Column{Title: "Last", Width: maxLength},
+		render.Column{Title: "Broadcast", Width: maxLength},
+		render.Column{Title: "Hosts"},
+	)
+
+	// The output stream, table and records of the current file
+	var outputStream *utils.OutputWriter
+	var table *render.Table
+	var records *envelope.Writer
+	var iacWriter *iac.Writer
+	format := subnetSplitFormat()
+
+	// openOutput opens the output file (or standard output) and prints the
+	// header, which is repeated in every file when the output is rotated
+	openOutput := func(name string) error {
+		csvName, markdownName, markdownNameLine := "", "", ""
+		if len(names) > 0 {
+			csvName, markdownName, markdownNameLine = "name,", "| Name ", "|------"
+		}
+		header := ""
+		switch format {
+		case "csv":
+			header = fmt.Sprintf("%sprefix,network,first,last,broadcast,hosts\n", csvName)
+		case "markdown":
+			header = fmt.Sprintf("%s| Prefix | Network | First | Last | Broadcast | Hosts |\n", markdownName)
+			header += fmt.Sprintf("%s|--------|---------|-------|------|-----------|------:|\n", markdownNameLine)
+		case "markdown-checklist":
+			header = fmt.Sprintf("| Allocated %s| Prefix | Network | First | Last | Broadcast | Hosts | Assigned to |\n", markdownName)
+			header += fmt.Sprintf("|:---------:%s|--------|---------|-------|------|-----------|------:|-------------|\n", markdownNameLine)
+		}
+
+		// The output is buffered, large splits print millions of lines
+		outputStream, err = getOutputWriter("subnet.split", name, out, utils.OutputOptions{Header: header})
+		if err != nil {
+			return err
+		}
+		records = envelope.NewWriter(outputStream)
+		if iac.IsFormat(format) {
+			if iacWriter, err = iac.NewWriter(outputStream, format, "subnets"); err != nil {
+				return err
+			}
+		}
+		table = render.NewTable(outputStream, getRenderOptions("subnet.split", outputStream.File()), columns...)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		for _, name := range names {
+			table.Fit(name)
+		}
+		if format == "table" {
+			table.Header()
+		}
+		return nil
+	}
+
+	// closeOutput flushes and closes the current file
+	closeOutput := func() error {
+		if outputStream == nil {
+			return nil
+		}
+
+		// End the list of subnets of the infrastructure as code formats
+		if iacWriter != nil {
+			if err := iacWriter.Close(); err != nil {
+				return err
+			}
+			iacWriter = nil
+		}
+		err := outputStream.Close()
+		outputStream = nil
 		return err
 	}
-	defer outputStream.Close()
+	defer closeOutput()
 
-	// Print the subnets
-	// Start with the header (Prefix, Network, Broadcast, First, Last, Hosts)
-	if viper.GetBool("subnet.split.csv") {
-		fmt.Fprintf(outputStream, "prefix,network,first,last,broadcast,hosts\n")
-	} else {
-		fmt.Fprintf(outputStream, fmtString, "Prefix", "Network", "First", "Last", "Broadcast", "Hosts")
-		fmt.Fprintf(outputStream, dashLine+"\n")
+	// Open the output file now, the shards are opened as the subnets are printed
+	if splitBy == "" {
+		if err := openOutput(outputFile); err != nil {
+			return err
+		}
 	}
 
-	// Subnet counter
-	counter := 1
-	limit := viper.GetInt("subnet.split.limit")
+	// Only page the output when writing to an interactive terminal (the
+	// sharded output is always written to files, opened as it is printed)
+	pageSize := uint64(max(viper.GetInt("subnet.split.page-size"), 0))
+	if outputFile != "" || !utils.IsTerminal(os.Stdin) || !utils.IsTerminal(os.Stdout) {
+		pageSize = 0
+	}
+
+	// Subnet counter and the shard of the current file
+	printed := uint64(0)
+	shard, shardRows := "", uint64(0)
+
+	// Show the progress of large splits written to files (or piped)
+	var bar *progress.Bar
+	if pageSize == 0 {
+		bar = newProgressBar("split", int(count), outputStream)
+	}
+	defer bar.Finish()
+
+	var writeErr error
+	err = network.SplitFunc(bits, offset, func(index uint64, prefix *ip.IPv4) bool {
+		// Limit the output to the specified number of subnets
+		if printed >= count {
+			return false
+		}
+		bar.Add(1)
+
+		// Pause after every page until the user asks for more
+		if pageSize > 0 && printed > 0 && printed%pageSize == 0 {
+			outputStream.Flush()
+			if !utils.PromptMore(os.Stdin, os.Stderr, fmt.Sprintf("-- %d of %d subnets, press Enter for more or q to quit --", offset+printed, total)) {
+				return false
+			}
+		}
 
-	for _, prefix := range prefixList {
 		pfx := prefix.String()
 		network := prefix.Network()
 		broadcast := prefix.Broadcast()
 		first := prefix.FirstHost()
 		last := prefix.LastHost()
 		hosts := prefix.UsableHosts()
+		name := nameOf(index)
+
+		// Start a new file when the subnet belongs to the next shard
+		if splitBy != "" {
+			next := subnetSplitShard(splitBy, printed, rowsPerFile, network, shardBits)
+			if next != shard {
+				if shard != "" {
+					fmt.Fprintf(out, "%s: %d subnets\n", subnetSplitShardFile(outputFile, shard), shardRows)
+				}
+				if writeErr = closeOutput(); writeErr != nil {
+					return false
+				}
+				if writeErr = openOutput(subnetSplitShardFile(outputFile, next)); writeErr != nil {
+					return false
+				}
+				shard, shardRows = next, 0
+			}
+			shardRows++
+		}
+		printed++
 
-		// Limit the output to the specified number of subnets
-		if limit > 0 && counter > limit {
-			break
+		// Prepend the name column if the subnets are named
+		csvName, markdownName := "", ""
+		if len(names) > 0 {
+			csvName, markdownName = name+",", "| "+name+" "
+		}
+
+		switch format {
+		case "csv":
+			fmt.Fprintf(outputStream, "%s%s,%s,%s,%s,%s,%s\n", csvName, pfx, network, first, last, broadcast, fmt.Sprint(hosts))
+		case "json":
+			records.Write(envelope.KindSubnet, pfx, subnetSplitJSON{Name: name, Prefix: pfx, Network: network, First: first, Last: last, Broadcast: broadcast, Hosts: hosts})
+		case "markdown":
+			fmt.Fprintf(outputStream, "%s| %s | %s | %s | %s | %s | %d |\n", markdownName, pfx, network, first, last, broadcast, hosts)
+		case "markdown-checklist":
+			fmt.Fprintf(outputStream, "| [ ] %s| %s | %s | %s | %s | %s | %d | |\n", markdownName, pfx, network, first, last, broadcast, hosts)
+		case iac.FormatAnsibleInventory, iac.FormatTerraformTFVars:
+			record := iac.Record{{Key: "prefix", Value: pfx}, {Key: "network", Value: network}, {Key: "first", Value: first}, {Key: "last", Value: last}, {Key: "broadcast", Value: broadcast}, {Key: "hosts", Value: hosts}}
+			if len(names) > 0 {
+				record = append(iac.Record{{Key: "name", Value: name}}, record...)
+			}
+			iacWriter.Write(record)
+		default:
+			cells := []string{pfx, network, first, last, broadcast, fmt.Sprint(hosts)}
+			if len(names) > 0 {
+				cells = append([]string{name}, cells...)
+			}
+			table.Row(cells...)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if shard != "" {
+		fmt.Fprintf(out, "%s: %d subnets\n", subnetSplitShardFile(outputFile, shard), shardRows)
+	}
+	if err := closeOutput(); err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// subnetSplitLevelsAction is the action function for the subnet split
+// command with --levels, it splits the network into subnets of the first
+// level, every subnet into subnets of the next level and so on
+func subnetSplitLevelsAction(out io.Writer, network *ip.IPv4, levels []int) error {
+	// Every level must be longer than the level before it
+	previous := network.PrefixLength()
+	for _, level := range levels {
+		if level <= previous || level > 32 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --levels value: /%d (the levels must be increasing prefix lengths from /%d to /32)", level, network.PrefixLength()+1))
 		}
-		counter++
+		previous = level
+	}
+
+	// Determine the output file using Viper, the output is buffered since
+	// deep splits print millions of lines
+	format := subnetSplitFormat()
+	header := ""
+	if format == "csv" {
+		header = "parent,prefix,network,first,last,broadcast,hosts\n"
+	}
+	outputStream, err := getOutputWriter("subnet.split", viper.GetString("subnet.split.output-file"), out, utils.OutputOptions{Header: header})
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+	records := envelope.NewWriter(outputStream)
+
+	// The broadcast address of the network is the longest address in it,
+	// and the prefixes are indented by two spaces per level
+	maxLength := len(network.Broadcast())
+	table := render.NewTable(outputStream, getRenderOptions("subnet.split", outputStream.File()),
+		render.Column{Title: "Prefix", Width: maxLength + 3 + 2*(len(levels)-1)},
+		render.Column{Title: "Network", Width: maxLength},
+		render.Column{Title: "First", Width: maxLength},
+		render.Column{Title: "Last", Width: maxLength},
+		render.Column{Title: "Broadcast", Width: maxLength},
+		render.Column{Title: "Hosts"},
+	)
+	if err := table.Err(); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	if format == "table" {
+		table.Header()
+	}
 
-		if viper.GetBool("subnet.split.csv") {
-			fmt.Fprintf(outputStream, "%s,%s,%s,%s,%s,%s\n", pfx, network, first, last, broadcast, fmt.Sprint(hosts))
-		} else {
-			fmt.Fprintf(outputStream, fmtString, pfx, network, first, last, broadcast, fmt.Sprint(hosts))
+	// split prints the subnets of a parent at a level, each followed by its
+	// own subnets at the next levels
+	var split func(parent *ip.IPv4, depth int) error
+	split = func(parent *ip.IPv4, depth int) error {
+		var splitErr error
+		err := parent.SplitFunc(levels[depth], 0, func(index uint64, prefix *ip.IPv4) bool {
+			pfx := prefix.String()
+			network := prefix.Network()
+			broadcast := prefix.Broadcast()
+			first := prefix.FirstHost()
+			last := prefix.LastHost()
+			hosts := prefix.UsableHosts()
+
+			switch format {
+			case "csv":
+				fmt.Fprintf(outputStream, "%s,%s,%s,%s,%s,%s,%d\n", parent, pfx, network, first, last, broadcast, hosts)
+			case "json":
+				records.Write(envelope.KindSubnet, pfx, subnetSplitJSON{Parent: parent.String(), Prefix: pfx, Network: network, First: first, Last: last, Broadcast: broadcast, Hosts: hosts})
+			default:
+				table.Row(strings.Repeat("  ", depth)+pfx, network, first, last, broadcast, fmt.Sprint(hosts))
+			}
+
+			// Split the subnet into the next level
+			if depth+1 < len(levels) {
+				splitErr = split(prefix, depth+1)
+			}
+			return splitErr == nil
+		})
+		if err != nil {
+			return err
 		}
+		return splitErr
+	}
+	if err := split(network, 0); err != nil {
+		return err
+	}
+	if err := outputStream.Close(); err != nil {
+		return err
 	}
 
 	// Print the configuration debug if the --debug flag is set
@@ -175,12 +520,57 @@ func subnetSplitAction(out io.Writer, s string) error {
 	return nil
 }
 
+// subnetSplitShard is a function that returns the shard of a subnet when the
+// output is split into multiple files: the number of the file (starting at
+// 1) when splitting by index, or the block of shardBits bits that contains
+// the subnet when splitting by prefix (e.g. 10.0.0.0_16)
+func subnetSplitShard(splitBy string, printed, rowsPerFile uint64, network string, shardBits int) string {
+	if splitBy == "index" {
+		return fmt.Sprintf("%05d", printed/rowsPerFile+1)
+	}
+	block := netip.PrefixFrom(netip.MustParseAddr(network), shardBits).Masked()
+	return fmt.Sprintf("%s_%d", block.Addr(), shardBits)
+}
+
+// subnetSplitShardFile is a function that returns the name of the file of a
+// shard, the output file with the shard added before the extension, e.g.
+// subnets-00001.csv or subnets-10.0.0.0_16.csv for subnets.csv
+func subnetSplitShardFile(outputFile, shard string) string {
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "-" + shard + ext
+}
+
+// subnetSplitFormats are the output formats of the subnet split command
+var subnetSplitFormats = []string{"table", "csv", "json", "markdown", "markdown-checklist", iac.FormatAnsibleInventory, iac.FormatTerraformTFVars}
+
+// subnetSplitJSON is the data of a subnet record written by --format json
+type subnetSplitJSON struct {
+	Name      string `json:"name,omitempty"`
+	Parent    string `json:"parent,omitempty"`
+	Prefix    string `json:"prefix"`
+	Network   string `json:"network"`
+	First     string `json:"first"`
+	Last      string `json:"last"`
+	Broadcast string `json:"broadcast"`
+	Hosts     uint32 `json:"hosts"`
+}
+
+// subnetSplitFormat is a function that returns the output format of the
+// subnet split command, the --csv flag is a shorthand for --format csv
+func subnetSplitFormat() string {
+	if viper.GetBool("subnet.split.csv") {
+		return "csv"
+	}
+	return strings.ToLower(viper.GetString("subnet.split.format"))
+}
+
 func init() {
 	subnetCmd.AddCommand(subnetSplitCmd)
 
 	// Define the flag for specifying the size of the subnets
 	subnetSplitCmd.Flags().IntP("bits", "b", 0, "subnet size in bits for network division")
 	viper.BindPFlag("subnet.split.bits", subnetSplitCmd.Flags().Lookup("bits"))
+	subnetSplitCmd.RegisterFlagCompletionFunc("bits", completePrefixLengths(1, 32))
 
 	// Define the flag for specifying the number of subnets to split the network into
 	subnetSplitCmd.Flags().IntP("networks", "n", 0, "number of subnets to divide the network into")
@@ -190,11 +580,110 @@ func init() {
 	subnetSplitCmd.Flags().BoolP("csv", "c", false, "output in CSV format")
 	viper.BindPFlag("subnet.split.csv", subnetSplitCmd.Flags().Lookup("csv"))
 
+	// Define the flag for selecting the output format
+	subnetSplitCmd.Flags().StringP("format", "f", "table", "output format (table, csv, json, markdown, markdown-checklist, ansible-inventory or terraform-tfvars)")
+	viper.BindPFlag("subnet.split.format", subnetSplitCmd.Flags().Lookup("format"))
+	subnetSplitCmd.RegisterFlagCompletionFunc("format", completeValues(subnetSplitFormats...))
+
 	// Define the flag for allowing the user to output to a file
 	subnetSplitCmd.Flags().StringP("output-file", "o", "", "write output to file")
 	viper.BindPFlag("subnet.split.output-file", subnetSplitCmd.Flags().Lookup("output-file"))
 
+	// Define the flags for copying the output to stdout and rotating the output file
+	addTeeFlag(subnetSplitCmd, "subnet.split")
+	addMaxSizeFlag(subnetSplitCmd, "subnet.split")
+
 	// Define the flag for allowing the user to limit the output to a specific number of subnets
 	subnetSplitCmd.Flags().IntP("limit", "l", 0, "limit the number of subnets in the output")
 	viper.BindPFlag("subnet.split.limit", subnetSplitCmd.Flags().Lookup("limit"))
+
+	// Define the flag for allowing the user to skip a number of subnets at the start of the output
+	subnetSplitCmd.Flags().Int("offset", 0, "skip the first N subnets in the output")
+	viper.BindPFlag("subnet.split.offset", subnetSplitCmd.Flags().Lookup("offset"))
+
+	// Define the flags for continuing an allocation plan at a given subnet
+	subnetSplitCmd.Flags().String("start", "", "start at the subnet containing this address instead of the first subnet")
+	viper.BindPFlag("subnet.split.start", subnetSplitCmd.Flags().Lookup("start"))
+
+	// Accept --count as well, the number of subnets to print from --start
+	subnetSplitCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "count" {
+			name = "limit"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	// Define the flags for labeling the subnets with names
+	subnetSplitCmd.Flags().StringSlice("names", nil, "label the subnets with the names, in order (e.g. mgmt,voice,data,guest)")
+	viper.BindPFlag("subnet.split.names", subnetSplitCmd.Flags().Lookup("names"))
+	subnetSplitCmd.Flags().Int("skip", 0, "leave the first N subnets unnamed and start naming at the next one")
+	viper.BindPFlag("subnet.split.skip", subnetSplitCmd.Flags().Lookup("skip"))
+
+	// Define the flag for splitting the network into multiple levels
+	subnetSplitCmd.Flags().IntSlice("levels", nil, "split into multiple levels of subnets (e.g. 26,28,30)")
+	viper.BindPFlag("subnet.split.levels", subnetSplitCmd.Flags().Lookup("levels"))
+
+	// Define the table layout flags (--no-header, --wide, --narrow and --columns)
+	addRenderFlags(subnetSplitCmd, "subnet.split")
+
+	// Define the flags for sharding the output into multiple files
+	subnetSplitCmd.Flags().String("split-output-by", "", "split the output into multiple files by index or prefix (requires --output-file)")
+	viper.BindPFlag("subnet.split.split-output-by", subnetSplitCmd.Flags().Lookup("split-output-by"))
+	subnetSplitCmd.RegisterFlagCompletionFunc("split-output-by", completeValues("index", "prefix"))
+	subnetSplitCmd.Flags().Int("rows-per-file", 10000, "maximum number of subnets per file with --split-output-by")
+	viper.BindPFlag("subnet.split.rows-per-file", subnetSplitCmd.Flags().Lookup("rows-per-file"))
+
+	// Define the flag for allowing the user to page the output in a terminal
+	subnetSplitCmd.Flags().Int("page-size", 0, "pause after every N subnets when writing to a terminal")
+	viper.BindPFlag("subnet.split.page-size", subnetSplitCmd.Flags().Lookup("page-size"))
+
+	// Validate the paging flags
+	subnetSplitCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		for _, key := range []string{"limit", "offset", "page-size", "skip"} {
+			if viper.GetInt("subnet.split."+key) < 0 {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --%s value: %d (must not be negative)", key, viper.GetInt("subnet.split."+key)))
+			}
+		}
+
+		// Validate the output format
+		if format := subnetSplitFormat(); !slices.Contains(subnetSplitFormats, format) {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid output format: %s (must be one of %s)", format, strings.Join(subnetSplitFormats, ", ")))
+		}
+
+		// The columns are only selected in the table format
+		if cmd.Flags().Changed("columns") && subnetSplitFormat() != "table" {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("--columns is only supported with the table format"))
+		}
+
+		// The levels replace the size of the subnets and cannot be combined
+		// with the flags that select or label single subnets
+		if len(viper.GetIntSlice("subnet.split.levels")) > 0 {
+			for _, flag := range []string{"bits", "networks", "limit", "offset", "start", "names", "split-output-by", "page-size"} {
+				if cmd.Flags().Changed(flag) {
+					return exitcode.New(exitcode.Usage, fmt.Errorf("--levels cannot be combined with --%s", flag))
+				}
+			}
+			if format := subnetSplitFormat(); format != "table" && format != "csv" && format != "json" {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("--levels only supports the table, csv and json formats"))
+			}
+		}
+
+		// Validate the sharding of the output
+		switch splitBy := strings.ToLower(viper.GetString("subnet.split.split-output-by")); splitBy {
+		case "":
+		case "index", "prefix":
+			if viper.GetString("subnet.split.max-size") != "" {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("--split-output-by cannot be combined with --max-size"))
+			}
+			if viper.GetString("subnet.split.output-file") == "" {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("--split-output-by requires --output-file (the base name of the files)"))
+			}
+			if rows := viper.GetInt("subnet.split.rows-per-file"); rows < 1 {
+				return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --rows-per-file value: %d (must be at least 1)", rows))
+			}
+		default:
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --split-output-by value: %s (must be index or prefix)", splitBy))
+		}
+		return nil
+	}
 }
@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetSummarizeCmd represents the subnet summarize command
+var subnetSummarizeCmd = &cobra.Command{
+	Use:     "summarize <prefix...|->",
+	Aliases: []string{"sum", "aggregate"},
+	Short:   "Summarize a list of prefixes into the fewest covering prefixes",
+	Long: `Summarize a list of prefixes into the fewest covering prefixes.
+
+Duplicate prefixes and prefixes contained in other prefixes are removed, and
+adjacent prefixes are merged into their common parent. The result covers
+exactly the same addresses as the input. IPv4 and IPv6 prefixes can be mixed,
+use -4 or -6 to only process the prefixes of one address family.
+
+The prefixes are given as arguments, separated by commas or spaces, or are
+read from standard input (one or more per line) when - is given.
+
+Examples:
+  iptool subnet summarize 10.0.0.0/24 10.0.1.0/24
+  iptool subnet summarize 192.168.0.0/25,192.168.0.128/25,192.168.1.0/24
+  cat routes.txt | iptool subnet summarize -`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		return subnetSummarizeAction(os.Stdout, os.Stdin, args)
+	},
+}
+
+// subnetSummarizeAction is the action function for the subnet summarize command
+func subnetSummarizeAction(out io.Writer, stdin io.Reader, args []string) error {
+	prefixes, err := readPrefixArgs(args, stdin)
+	if err != nil {
+		return err
+	}
+	prefixes = getFamily("subnet.summarize").FilterPrefixes(prefixes)
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("subnet.summarize.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	for _, prefix := range ip.Summarize(prefixes) {
+		fmt.Fprintln(outputStream, prefix)
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	subnetCmd.AddCommand(subnetSummarizeCmd)
+	addFamilyFlags(subnetSummarizeCmd, "subnet.summarize")
+
+	// Enable the --output-file flag to write the output to a file
+	subnetSummarizeCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.summarize.output-file", subnetSummarizeCmd.Flags().Lookup("output-file"))
+}
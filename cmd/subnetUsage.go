@@ -0,0 +1,207 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// subnetUsageCmd represents the subnet usage command
+var subnetUsageCmd = &cobra.Command{
+	Use:   "usage <prefix> --hosts-file <file|->",
+	Short: "Calculate the address usage of a subnet from a list of used addresses",
+	Long: `Calculate the address usage of a subnet from a list of used addresses.
+
+The used addresses are read from a file with one address per line, or from
+standard input when the file name is -. A line can also contain a range of
+addresses (first-last) or a prefix, and only the first field of each line is
+used, so that a hosts file, a DHCP lease export or an ARP table can be used
+as it is. Addresses used more than once are counted once, and addresses
+outside the prefix are ignored.
+
+The report shows the number of used and free addresses, the largest range of
+contiguous free addresses, the largest subnet that can still be allocated,
+and how fragmented the free addresses are (the percentage of the free
+addresses outside the largest free range).
+
+Examples:
+  iptool subnet usage 10.0.4.0/22 --hosts-file used.txt
+  iptool subnet usage 10.0.4.0/22 -f used.txt --free
+  arp -an | grep -o '[0-9.]\{7,\}' | iptool subnet usage 10.0.4.0/22 -f -`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		if viper.GetString("subnet.usage.hosts-file") == "" {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("no used addresses given, use --hosts-file"))
+		}
+
+		// Get the output stream
+		out, err := utils.GetOutputStream(viper.GetString("subnet.usage.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return subnetUsageAction(out, os.Stdin, strings.Join(args, " "))
+	},
+}
+
+// subnetUsageJSON is the JSON output of the subnet usage command
+type subnetUsageJSON struct {
+	Prefix           string   `json:"prefix"`
+	Size             uint64   `json:"size"`
+	Used             uint64   `json:"used"`
+	Free             uint64   `json:"free"`
+	Outside          uint64   `json:"outside"`
+	LargestFreeRange string   `json:"largest_free_range,omitempty"`
+	LargestFreeBlock string   `json:"largest_free_block,omitempty"`
+	Fragmentation    float64  `json:"fragmentation"`
+	FreeRanges       []string `json:"free_ranges"`
+}
+
+// subnetUsageAction is the action function for the subnet usage command
+func subnetUsageAction(out io.Writer, stdin io.Reader, arg string) error {
+	// Parse the prefix, the address is masked to the network address
+	prefix, err := ip.ParseIPv4(resolveAlias(arg))
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	if prefix.IP.To4() == nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid IPv4 prefix: %s", arg))
+	}
+
+	// Read the used addresses from the hosts file or standard input
+	var r io.Reader = stdin
+	hostsFile := viper.GetString("subnet.usage.hosts-file")
+	if hostsFile != "-" {
+		file, err := os.Open(hostsFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	used, err := ip.ParseUsedAddresses(r)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("%s: %w", hostsFile, err))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	usage := ip.SubnetUsage(prefix, used)
+	largestRange := usage.LargestFreeRange()
+	largestBlock := usage.LargestFreeBlock()
+
+	if viper.GetBool("subnet.usage.json") {
+		result := subnetUsageJSON{
+			Prefix:        fmt.Sprintf("%s/%d", prefix.Network(), prefix.PrefixLength()),
+			Size:          usage.Size(),
+			Used:          usage.Used,
+			Free:          usage.Free,
+			Outside:       usage.Outside,
+			Fragmentation: usage.Fragmentation(),
+			FreeRanges:    []string{},
+		}
+		if largestRange != nil {
+			result.LargestFreeRange = largestRange.String()
+			result.LargestFreeBlock = largestBlock.String()
+		}
+		for _, r := range usage.FreeRanges {
+			result.FreeRanges = append(result.FreeRanges, r.String())
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	// percent returns the share of the prefix as a percentage
+	percent := func(n uint64) float64 {
+		return 100 * float64(n) / float64(usage.Size())
+	}
+
+	fmt.Fprintf(out, "Prefix             : %s/%d (%d addresses)\n", prefix.Network(), prefix.PrefixLength(), usage.Size())
+	fmt.Fprintf(out, "Used addresses     : %d (%.1f%%)\n", usage.Used, percent(usage.Used))
+	fmt.Fprintf(out, "Free addresses     : %d (%.1f%%)\n", usage.Free, percent(usage.Free))
+	if usage.Outside > 0 {
+		fmt.Fprintf(out, "Outside prefix     : %d (ignored)\n", usage.Outside)
+	}
+
+	// The prefix is full, there are no free ranges to report
+	if largestRange == nil {
+		fmt.Fprintf(out, "Largest free range : none\n")
+		return nil
+	}
+	fmt.Fprintf(out, "Free ranges        : %d\n", len(usage.FreeRanges))
+	fmt.Fprintf(out, "Largest free range : %s (%d addresses)\n", largestRange, largestRange.Size())
+	fmt.Fprintf(out, "Largest free block : %s\n", largestBlock)
+	fmt.Fprintf(out, "Fragmentation      : %.1f%%\n", usage.Fragmentation())
+
+	// Print the free ranges if the --free flag is set
+	if viper.GetBool("subnet.usage.free") {
+		fmt.Fprintln(out)
+		for _, r := range usage.FreeRanges {
+			fmt.Fprintf(out, "  %-31s %d\n", r, r.Size())
+		}
+	}
+
+	return nil
+}
+
+// init registers the command and flags
+func init() {
+	subnetCmd.AddCommand(subnetUsageCmd)
+
+	// Enable the --hosts-file flag to read the used addresses from a file
+	subnetUsageCmd.Flags().StringP("hosts-file", "f", "", "read the used addresses from file (- for standard input)")
+	viper.BindPFlag("subnet.usage.hosts-file", subnetUsageCmd.Flags().Lookup("hosts-file"))
+
+	// Enable the --free flag to list the free ranges
+	subnetUsageCmd.Flags().Bool("free", false, "list the ranges of free addresses")
+	viper.BindPFlag("subnet.usage.free", subnetUsageCmd.Flags().Lookup("free"))
+
+	// Enable the --json flag to print the usage in JSON format
+	subnetUsageCmd.Flags().Bool("json", false, "print the usage in JSON format")
+	viper.BindPFlag("subnet.usage.json", subnetUsageCmd.Flags().Lookup("json"))
+
+	// Enable the --output-file flag to write the output to a file
+	subnetUsageCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("subnet.usage.output-file", subnetUsageCmd.Flags().Lookup("output-file"))
+}
@@ -0,0 +1,337 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ndp"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/results"
+	"github.com/bitcanon/iptool/scan"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// sweepCmd represents the sweep command
+var sweepCmd = &cobra.Command{
+	Use:   "sweep <prefix...>",
+	Short: "Discover live hosts in a network",
+	Long: `Discover live hosts in a network.
+
+Brute-force sweeping an IPv6 /64 is not feasible, so IPv6 sweeps use neighbor
+discovery instead (--ipv6-nd). An ICMPv6 echo request is sent to the link-local
+all-nodes multicast address (ff02::1) and the replies are merged with the
+neighbor (NDP) cache of the operating system. The hosts reply from their
+link-local addresses, which are listed also when a global prefix is swept.
+
+The interface to use is given as a zone after the prefix (e.g. %eth0).
+Sending ICMPv6 requires raw socket privileges (root or CAP_NET_RAW).
+Use --source to send the echo request from a specific local address, or from
+the IPv6 address of an interface.
+
+Several prefixes are swept one at a time, use --concurrency to sweep more
+prefixes in parallel and --rate to limit the number of sweeps started (e.g.
+10/m). When Ctrl-C is pressed, no more sweeps are started and the results
+of the sweeps in flight are written (press Ctrl-C again to quit immediately).
+
+The prefixes can also be read from the JSON output of another command with
+--from-json (- for standard input). With --format json, one host record is
+written per line, which can be piped into e.g. iptool enrich --from-json -.
+
+Results of other scanners can be merged into the sweep with --import, which
+reads Nmap or masscan XML (-oX) and greppable (-oG) output as well as the
+JSON output of sweep. With --format nmap-xml or nmap-grep, the results are
+written in the Nmap formats, so that they can be processed by the tools of
+existing scanning pipelines. Without prefixes, --import only converts the
+imported results to the output format.
+
+Examples:
+  iptool sweep --ipv6-nd fe80::/64%eth0
+  iptool sweep --ipv6-nd 2001:db8:1::/64%eth0 --timeout 5000
+  iptool sweep --ipv6-nd 2001:db8:1::/64%eth0 --source 2001:db8:1::10
+  iptool sweep --ipv6-nd fe80::/64%eth0 --csv -o neighbors.csv
+  iptool sweep --ipv6-nd fe80::/64%eth0 --format json | iptool enrich --from-json -
+  iptool sweep --ipv6-nd fe80::/64%eth0 --import nmap.xml --format nmap-xml
+  iptool sweep --import masscan.xml --format json -o hosts.json`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no prefixes are provided, print a short help text
+		if len(args) == 0 && viper.GetString("sweep.from-json") == "" && len(viper.GetStringSlice("sweep.import")) == 0 {
+			cmd.Help()
+			return nil
+		}
+
+		// Read the prefixes from the JSON input
+		prefixes := resolveAliases(args)
+		if fromJSON := viper.GetString("sweep.from-json"); fromJSON != "" {
+			targets, err := readJSONTargets(fromJSON, os.Stdin)
+			if err != nil {
+				return err
+			}
+			prefixes = append(prefixes, targets...)
+		}
+
+		return sweepAction(os.Stdout, prefixes)
+	},
+}
+
+// sweepFormats are the output formats of the sweep command
+var sweepFormats = []string{"table", "csv", "json", "nmap-xml", "nmap-grep"}
+
+// sweepAction is the action function for the sweep command
+func sweepAction(out io.Writer, prefixes []string) error {
+	// Only neighbor discovery based sweeps are supported for now
+	if len(prefixes) > 0 && !viper.GetBool("sweep.ipv6-nd") {
+		return exitcode.New(exitcode.Usage, errors.New("only IPv6 neighbor discovery sweeps are supported, see --help for more information"))
+	}
+
+	// Check the output format, the --csv flag is a shorthand for --format csv
+	format := viper.GetString("sweep.format")
+	if viper.GetBool("sweep.csv") {
+		format = "csv"
+	}
+	if !slices.Contains(sweepFormats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(sweepFormats, ", ")))
+	}
+
+	// A JSON or XML document cannot be split into multiple files
+	if viper.GetString("sweep.max-size") != "" && (format == "json" || format == "nmap-xml") {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("--max-size cannot be combined with the %s format", format))
+	}
+
+	// Read the results of other scanners to merge into the sweep
+	var imported [][]scan.Host
+	for _, name := range viper.GetStringSlice("sweep.import") {
+		hosts, err := scan.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		imported = append(imported, hosts)
+	}
+
+	// Discover the neighbors on the link of every prefix
+	run := scan.Run{Scanner: "iptool", Version: rootCmd.Version, Args: strings.Join(os.Args, " "), Start: time.Now()}
+	timeout := viper.GetDuration("sweep.timeout") * time.Millisecond
+	source, err := ip.SourceAddress(viper.GetString("sweep.source"), true)
+	if err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+	limiter, err := getRateLimiter("sweep")
+	if err != nil {
+		return err
+	}
+	concurrency, err := getConcurrency("sweep")
+	if err != nil {
+		return err
+	}
+
+	// Parse the prefixes and the interfaces (zones) before sending anything
+	type sweepJob struct {
+		prefix *net.IPNet
+		iface  string
+		hosts  []scan.Host
+		err    error
+	}
+	jobs := make([]sweepJob, len(prefixes))
+	var interfaces []string
+	for i, s := range prefixes {
+		prefix, iface, err := ip.ParseIPv6Prefix(s)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		if iface == "" {
+			return ip.ErrMissingZone
+		}
+		jobs[i] = sweepJob{prefix: prefix, iface: iface}
+		if !slices.Contains(interfaces, iface) {
+			interfaces = append(interfaces, iface)
+		}
+	}
+
+	// Sweep the prefixes, at most --concurrency at a time and at the --rate,
+	// when Ctrl-C is pressed the sweeps in flight are completed
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+	bar := newProgressBar("sweep", len(jobs), nil)
+	ratelimit.Run(ctx, len(jobs), concurrency, limiter, func(_ context.Context, i int) {
+		defer bar.Add(1)
+		job := &jobs[i]
+		neighbors, err := ndp.Discover(job.iface, job.prefix, source, timeout)
+		if err != nil {
+			job.err = err
+			return
+		}
+		for _, n := range neighbors {
+			job.hosts = append(job.hosts, scan.Host{Address: n.IP.String(), MAC: n.MAC.String(), State: n.State, Source: n.Source, Interface: job.iface})
+		}
+	})
+	bar.Finish()
+
+	var found []scan.Host
+	for _, job := range jobs {
+		if job.err != nil {
+			return job.err
+		}
+		found = append(found, job.hosts...)
+	}
+
+	// Record the hosts found if --record is set
+	records := make([]results.Result, len(found))
+	for i, h := range found {
+		records[i] = results.Result{Kind: results.KindSweep, Target: h.Address, Address: h.Address, Success: true, Detail: strings.TrimSpace(h.State + " " + h.MAC)}
+	}
+	recordResults(records...)
+	run.End = time.Now()
+
+	// The hosts found by the sweep take precedence over the imported hosts
+	hosts := scan.Merge(append(imported, found)...)
+
+	// The header of the line-based formats is repeated in every rotated file
+	fmtString := "%-40s %-18s %-11s %-11s %s\n"
+	var opts utils.OutputOptions
+	switch format {
+	case "csv":
+		opts.Header = "address,mac,state,source,ports\n"
+	case "table":
+		opts.Header = fmt.Sprintf(fmtString, "Address", "MAC", "State", "Source", "Ports") + strings.Repeat("-", 94) + "\n"
+	}
+
+	// Get the output stream for the output file selected with --output-file
+	outputStream, err := getOutputWriter("sweep", viper.GetString("sweep.output-file"), out, opts)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Print the hosts
+	switch format {
+	case "json":
+		if err := scan.WriteJSON(outputStream, hosts); err != nil {
+			return err
+		}
+	case "nmap-xml":
+		if err := scan.WriteXML(outputStream, run, hosts); err != nil {
+			return err
+		}
+	case "nmap-grep":
+		if err := scan.WriteGreppable(outputStream, run, hosts); err != nil {
+			return err
+		}
+	case "csv":
+		for _, h := range hosts {
+			fmt.Fprintf(outputStream, "%s,%s,%s,%s,%s\n", h.Address, h.MAC, h.State, h.Source, formatSweepPorts(h.Ports, " "))
+		}
+	default:
+		for _, h := range hosts {
+			fmt.Fprintf(outputStream, fmtString, h.Address, h.MAC, h.State, h.Source, formatSweepPorts(h.Ports, ", "))
+		}
+	}
+
+	// Print the summary and flush the output
+	if format == "table" {
+		if len(interfaces) > 0 {
+			fmt.Fprintf(outputStream, "\n%d hosts found on %s\n", len(hosts), strings.Join(interfaces, ", "))
+		} else {
+			fmt.Fprintf(outputStream, "\n%d hosts imported\n", len(hosts))
+		}
+	}
+	if err := outputStream.Close(); err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+// formatSweepPorts is a function that returns the open ports of a host as a
+// list of port/protocol, separated by sep
+func formatSweepPorts(ports []scan.Port, sep string) string {
+	var open []string
+	for _, p := range ports {
+		if p.State == "open" {
+			open = append(open, p.String())
+		}
+	}
+	return strings.Join(open, sep)
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+
+	// Define the flag for enabling IPv6 neighbor discovery
+	sweepCmd.Flags().Bool("ipv6-nd", false, "discover on-link IPv6 hosts using neighbor discovery")
+	viper.BindPFlag("sweep.ipv6-nd", sweepCmd.Flags().Lookup("ipv6-nd"))
+
+	// Define the flag for the time to wait for replies
+	sweepCmd.Flags().IntP("timeout", "t", 3000, "time to wait for replies, in milliseconds")
+	viper.BindPFlag("sweep.timeout", sweepCmd.Flags().Lookup("timeout"))
+
+	// Define the flag for allowing the user to output in CSV format
+	sweepCmd.Flags().BoolP("csv", "c", false, "output in CSV format")
+	viper.BindPFlag("sweep.csv", sweepCmd.Flags().Lookup("csv"))
+
+	// Define the flag for selecting the output format
+	sweepCmd.Flags().StringP("format", "f", "table", "output format (table, csv, json, nmap-xml or nmap-grep)")
+	viper.BindPFlag("sweep.format", sweepCmd.Flags().Lookup("format"))
+	sweepCmd.RegisterFlagCompletionFunc("format", completeValues(sweepFormats...))
+
+	// Define the flag for merging the results of other scanners
+	sweepCmd.Flags().StringSliceP("import", "i", nil, "merge the hosts of Nmap/masscan XML or greppable output, or sweep JSON output")
+	viper.BindPFlag("sweep.import", sweepCmd.Flags().Lookup("import"))
+
+	// Define the flag for reading the prefixes from JSON records
+	sweepCmd.Flags().String("from-json", "", "read the prefixes from the JSON output of another command (- for standard input)")
+	viper.BindPFlag("sweep.from-json", sweepCmd.Flags().Lookup("from-json"))
+
+	// Define the flags for the rate and number of concurrent sweeps
+	addRateFlag(sweepCmd, "sweep")
+	addConcurrencyFlag(sweepCmd, "sweep", 1)
+
+	// Define the flag for the source address or interface
+	sweepCmd.Flags().StringP("source", "S", "", "send the echo request from this local address or interface")
+	viper.BindPFlag("sweep.source", sweepCmd.Flags().Lookup("source"))
+
+	// Define the flag for allowing the user to output to a file
+	sweepCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("sweep.output-file", sweepCmd.Flags().Lookup("output-file"))
+
+	// Define the flags for copying the output to stdout and rotating the output file
+	addTeeFlag(sweepCmd, "sweep")
+	addMaxSizeFlag(sweepCmd, "sweep")
+}
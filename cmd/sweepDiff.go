@@ -0,0 +1,171 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/scan"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// sweepDiffCmd represents the sweep diff command
+var sweepDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two saved sweep or scan results",
+	Long: `Compare two saved sweep or scan results.
+
+The hosts and ports that appeared, disappeared or changed state between the
+old and the new result are listed, one change per line:
+
+  +  a host or port appeared
+  -  a host or port disappeared
+  ~  the state (or MAC address) of a host, or the state of a port, changed
+
+The results can be sweep JSON output (--format json) as well as Nmap or
+masscan XML and greppable output, so results of different tools can be
+compared. Hosts that are down count as absent. Use --fail to exit with a
+non-zero exit code if anything changed, e.g. in a scheduled job, and
+--webhook-url to post the changes to a chat or alerting webhook (see
+'iptool tcp ping --help' for the webhook flags).
+
+Examples:
+  iptool sweep diff monday.json tuesday.json
+  iptool sweep diff baseline.xml latest.xml --fail
+  iptool sweep diff old.json new.json --json
+  iptool sweep diff old.json new.json --webhook-url $SLACK_URL --webhook-format slack`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		if len(args) != 2 {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("expected an old and a new result, got %d argument(s)", len(args)))
+		}
+
+		// Determine the output file using Viper
+		outputStream, err := utils.GetOutputStream(viper.GetString("sweep.diff.output-file"), false)
+		if err != nil {
+			return err
+		}
+		defer outputStream.Close()
+
+		return sweepDiffAction(outputStream, args[0], args[1])
+	},
+}
+
+// sweepDiffSymbols are the symbols of the kinds of changes
+var sweepDiffSymbols = map[string]string{
+	scan.ChangeAppeared:    "+",
+	scan.ChangeDisappeared: "-",
+	scan.ChangeChanged:     "~",
+}
+
+// sweepDiffAction is the action function for the sweep diff command
+func sweepDiffAction(out io.Writer, oldFile, newFile string) error {
+	old, err := scan.ReadFile(oldFile)
+	if err != nil {
+		return err
+	}
+	new, err := scan.ReadFile(newFile)
+	if err != nil {
+		return err
+	}
+
+	// The changes are posted to the webhook, if set
+	webhook, err := getWebhookDispatcher("sweep.diff")
+	if err != nil {
+		return err
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	changes := scan.Diff(old, new)
+
+	// Post a single alert listing all changes, so that a large diff does
+	// not flood the webhook
+	if len(changes) > 0 {
+		lines := []string{fmt.Sprintf("%d change(s) between %s and %s", len(changes), oldFile, newFile)}
+		for _, c := range changes {
+			lines = append(lines, sweepDiffSymbols[c.Kind]+" "+c.String())
+		}
+		event := notify.NewEvent(newFile, "", 0, "changed", "")
+		event.Message = strings.Join(lines, "\n")
+		event.Source = "sweep diff"
+		webhook.Notify(event)
+	}
+	defer webhook.Close(notify.Timeout)
+
+	if viper.GetBool("sweep.diff.json") {
+		if changes == nil {
+			changes = []scan.Change{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			return err
+		}
+	} else if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes")
+	} else {
+		for _, c := range changes {
+			fmt.Fprintf(out, "%s %s\n", sweepDiffSymbols[c.Kind], c)
+		}
+	}
+
+	if len(changes) > 0 && viper.GetBool("sweep.diff.fail") {
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d change(s) found", len(changes)))
+	}
+	return nil
+}
+
+func init() {
+	sweepCmd.AddCommand(sweepDiffCmd)
+
+	// Enable the --fail flag to exit with a non-zero exit code on changes
+	sweepDiffCmd.Flags().Bool("fail", false, "exit with a non-zero exit code if anything changed")
+	viper.BindPFlag("sweep.diff.fail", sweepDiffCmd.Flags().Lookup("fail"))
+
+	// Enable the --json flag to print the changes in JSON format
+	sweepDiffCmd.Flags().Bool("json", false, "print the changes in JSON format")
+	viper.BindPFlag("sweep.diff.json", sweepDiffCmd.Flags().Lookup("json"))
+
+	// Enable the --output-file flag to write the output to a file
+	sweepDiffCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("sweep.diff.output-file", sweepDiffCmd.Flags().Lookup("output-file"))
+
+	// Enable the --webhook-* flags to post the changes to a webhook
+	addWebhookFlags(sweepDiffCmd, "sweep.diff")
+}
@@ -22,7 +22,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
+
+	"github.com/bitcanon/iptool/exitcode"
 )
 
 // tcpCmd represents the tcp command
@@ -64,3 +73,99 @@ TCP Header Format:
 func init() {
 	rootCmd.AddCommand(tcpCmd)
 }
+
+// parseHostPortArgs parses the arguments of a TCP command in the format
+// "<host> [port]" or "<host>:<port>" and returns the host and the port.
+// If no port is specified, the default port is returned.
+func parseHostPortArgs(args []string, defaultPort int) (string, int, error) {
+	// Check that the user provided one or two arguments
+	if len(args) < 1 || len(args) > 2 {
+		return "", 0, exitcode.New(exitcode.Usage, errors.New("invalid number of arguments"))
+	}
+
+	// Check if the user used the format host:port (or [address]:port for
+	// IPv6), a bare IPv6 address is a host without a port
+	if _, err := netip.ParseAddr(strings.Trim(args[0], "[]")); err == nil {
+		args = append([]string{strings.Trim(args[0], "[]")}, args[1:]...)
+	} else if strings.Contains(args[0], ":") {
+		// Split the host and port
+		host, port, err := net.SplitHostPort(args[0])
+		if err != nil {
+			return "", 0, exitcode.New(exitcode.Usage, err)
+		}
+		args = []string{host, port}
+	}
+
+	// Parse the port
+	port := defaultPort
+	if len(args) == 2 {
+		p, err := parsePort(args[1])
+		if err != nil {
+			return "", 0, err
+		}
+		port = p
+	}
+
+	return resolveAlias(args[0]), port, nil
+}
+
+// parsePort converts a port number to an integer and checks that it is valid
+func parsePort(s string) (int, error) {
+	// Convert the port to an integer
+	p, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, exitcode.New(exitcode.Usage, err)
+	}
+
+	// Check that the port is valid
+	if p < 1 || p > 65535 {
+		return 0, exitcode.New(exitcode.Usage, errors.New("invalid port number, must be between 1 and 65535"))
+	}
+
+	return p, nil
+}
+
+// parsePorts parses a list of ports and port ranges separated by commas
+// or given as separate arguments (e.g. "80,443 8000-8010") and returns the
+// ports in the order given, without duplicates.
+func parsePorts(args []string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+
+	for _, arg := range args {
+		for _, field := range strings.Split(arg, ",") {
+			if field == "" {
+				continue
+			}
+
+			// A port range is given as first-last
+			first, last := field, field
+			if i := strings.Index(field, "-"); i > 0 {
+				first, last = field[:i], field[i+1:]
+			}
+			from, err := parsePort(first)
+			if err != nil {
+				return nil, err
+			}
+			to, err := parsePort(last)
+			if err != nil {
+				return nil, err
+			}
+			if from > to {
+				return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid port range: %s", field))
+			}
+
+			for p := from; p <= to; p++ {
+				if !seen[p] {
+					seen[p] = true
+					ports = append(ports, p)
+				}
+			}
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, exitcode.New(exitcode.Usage, errors.New("no ports specified"))
+	}
+	return ports, nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// listenCmd represents the listen command
+var listenCmd = &cobra.Command{
+	Use:   "listen <port> [port...]",
+	Short: "Listen for TCP connections on one or more ports",
+	Long: `Listen for TCP connections on one or more ports.
+
+The TCP listen command opens a listening socket on every port given and
+logs each inbound connection with a timestamp and the source address and
+port. This is the other half of testing firewall rules with tcp ping: run
+tcp listen on the destination host and tcp ping from the source host.
+
+Ports can be given as separate arguments, as a comma separated list or
+as ranges (e.g. 8000-8010).
+
+By default, data received on a connection is discarded. Use --echo to
+send it back to the client, or --response to send a canned response and
+close the connection.
+
+Example:
+  iptool tcp listen 8080
+  iptool tcp listen 80,443 8000-8010
+  iptool tcp listen 8080 --echo
+  iptool tcp listen 8080 --response "HTTP/1.0 200 OK\r\n\r\nhello\r\n"
+  iptool tcp listen 8080 --bind 10.0.0.1 --output-file connections.log`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completePortArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no arguments are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+
+		// Parse the ports to listen on
+		ports, err := parsePorts(args)
+		if err != nil {
+			return err
+		}
+
+		return tcpListenAction(os.Stdout, ports)
+	},
+}
+
+// listenResponseLinger is the time to wait for the client to close the
+// connection after the canned response has been sent
+const listenResponseLinger = 5 * time.Second
+
+// connectionLogger writes one line per connection event to the output,
+// connections are handled concurrently so the writes are serialized
+type connectionLogger struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+// log writes a timestamped line to the output
+func (l *connectionLogger) log(format string, a ...any) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	fmt.Fprintf(l.out, "%s %s\n", utils.GetTimestamp(), fmt.Sprintf(format, a...))
+}
+
+// tcpListenAction listens on the ports and handles the connections until
+// the user presses Ctrl-C
+func tcpListenAction(out io.Writer, ports []int) error {
+	echo := viper.GetBool("tcp.listen.echo")
+	response := viper.GetString("tcp.listen.response")
+	bind := viper.GetString("tcp.listen.bind")
+
+	// Echo and canned response are mutually exclusive
+	if echo && response != "" {
+		return exitcode.New(exitcode.Usage, errors.New("the --echo and --response flags cannot be used together"))
+	}
+
+	// Interpret escape sequences such as \r\n in the canned response
+	if response != "" {
+		unquoted, err := strconv.Unquote(`"` + response + `"`)
+		if err != nil {
+			return exitcode.New(exitcode.Usage, fmt.Errorf("invalid response: %s", err))
+		}
+		response = unquoted
+	}
+
+	// Determine the output file using Viper
+	outputFile := viper.GetString("tcp.listen.output-file")
+	appendOutput := viper.GetBool("tcp.listen.append")
+
+	// Get the output stream
+	outputStream, err := utils.GetOutputStream(outputFile, appendOutput)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	// Open all listening sockets before accepting any connections
+	var listeners []net.Listener
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", net.JoinHostPort(bind, strconv.Itoa(port)))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	logger := &connectionLogger{out: outputStream}
+	for _, listener := range listeners {
+		logger.log("listening on %s", listener.Addr())
+	}
+
+	// Let the user know where the log is written
+	if outputFile != "" {
+		fmt.Fprintf(out, "Listening on %d port(s), logging connections to %s. Press Ctrl-C to stop.\n", len(listeners), outputFile)
+	}
+
+	// Accept connections on every port, the first fatal error stops the command
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					errs <- err
+					return
+				}
+				go handleListenConnection(logger, conn, echo, response)
+			}
+		}(listener)
+	}
+
+	return <-errs
+}
+
+// handleListenConnection logs the connection and serves it according to
+// the configured mode (discard, echo or canned response)
+func handleListenConnection(logger *connectionLogger, conn net.Conn, echo bool, response string) {
+	defer conn.Close()
+
+	start := time.Now()
+	logger.log("connection from %s to %s", conn.RemoteAddr(), conn.LocalAddr())
+
+	var received int64
+	var err error
+	switch {
+	case response != "":
+		// Send the canned response and close the sending side of the connection
+		if _, err = io.WriteString(conn, response); err == nil {
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.CloseWrite()
+			}
+
+			// Give the client some time to read the response and close the connection,
+			// closing a connection with unread data would reset it
+			conn.SetReadDeadline(time.Now().Add(listenResponseLinger))
+			received, _ = io.Copy(io.Discard, conn)
+		}
+	case echo:
+		// Send everything received back to the client
+		received, err = io.Copy(conn, conn)
+	default:
+		// Discard everything received until the client closes the connection
+		received, err = io.Copy(io.Discard, conn)
+	}
+
+	if err != nil {
+		logger.log("connection from %s closed with error: %s", conn.RemoteAddr(), err)
+		return
+	}
+	logger.log("connection from %s closed (%s received, duration %s)", conn.RemoteAddr(), utils.FormatBytes(received), time.Since(start).Round(time.Millisecond))
+}
+
+func init() {
+	tcpCmd.AddCommand(listenCmd)
+
+	// Enable the --bind flag for the listen command
+	listenCmd.Flags().StringP("bind", "b", "", "local address to listen on (default all addresses)")
+	viper.BindPFlag("tcp.listen.bind", listenCmd.Flags().Lookup("bind"))
+
+	// Enable the --echo flag for the listen command
+	listenCmd.Flags().BoolP("echo", "e", false, "send received data back to the client")
+	viper.BindPFlag("tcp.listen.echo", listenCmd.Flags().Lookup("echo"))
+
+	// Enable the --response flag for the listen command
+	listenCmd.Flags().StringP("response", "r", "", "send a canned response and close the connection (escape sequences like \\r\\n are supported)")
+	viper.BindPFlag("tcp.listen.response", listenCmd.Flags().Lookup("response"))
+
+	// Enable the --output-file flag for the listen command
+	listenCmd.Flags().StringP("output-file", "o", "", "write the connection log to file")
+	viper.BindPFlag("tcp.listen.output-file", listenCmd.Flags().Lookup("output-file"))
+
+	// Enable the --append flag for the listen command
+	listenCmd.Flags().BoolP("append", "a", false, "append to the output file")
+	viper.BindPFlag("tcp.listen.append", listenCmd.Flags().Lookup("append"))
+}
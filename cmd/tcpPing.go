@@ -22,18 +22,25 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"net"
+	"net/netip"
 	"os"
 	"os/signal"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/bitcanon/iptool/exitcode"
 	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/results"
+	"github.com/bitcanon/iptool/stats"
 	"github.com/bitcanon/iptool/tcp"
 	"github.com/bitcanon/iptool/utils"
 	"github.com/spf13/cobra"
@@ -53,285 +60,630 @@ prints the response time, until the user presses Ctrl-C.
 
 If no port is specified, the default port 443 is used.
 
+The destination may contain brace patterns that expand into
+several hosts, e.g. web{01..20}.example.com or 10.0.{1..4}.1.
+The hosts are then pinged in turn and statistics are printed
+for every host.
+
+Named groups of targets defined in the configuration file
+(groups.<name>) are referenced as @<name>.
+
+Host names are resolved to both their IPv4 (A) and IPv6 (AAAA)
+addresses, and every ping connects like a dual-stack client using
+happy eyeballs (RFC 8305): the addresses are tried in turn, IPv6
+first, starting the next attempt after --fallback-delay or as soon as
+an attempt fails. The address that answered and the time until the
+connection was established are printed. Use -4 or -6 to only ping
+the IPv4 or IPv6 addresses of the host.
+
+On multi-homed machines, use --source to send the pings from a
+specific local address or interface (its first IPv4 address, or its
+first IPv6 address with -6), only the addresses of the family of the
+source are pinged.
+
+Use --jitter to vary the delay between pings randomly, so that the
+pings do not phase-lock with periodic events in the network, and
+--adaptive to wait one (smoothed) round-trip time between pings
+instead of the delay, like ping -A. Adaptive mode never waits longer
+than the delay and falls back to the delay after a timeout.
+
+Use --quiet in scripts and cron jobs to only print the statistics.
+
+Host names are resolved once when the ping starts. Use --resolve-every to
+resolve them again periodically (e.g. every 30s) and follow DNS changes
+during long-running pings, such as anycast failovers or blue/green
+cutovers. A change of the addresses of a host is logged, also with --quiet.
+
+To use the ping as a lightweight monitor, --bell rings the terminal bell
+when a host goes down or recovers, and --notify-on-change runs a command
+(using the shell) or posts to a webhook URL (http:// or https://) when a
+host goes down or recovers. A host that does not answer the first ping is
+reported as down. The command gets the change in the IPTOOL_TARGET,
+IPTOOL_ADDRESS, IPTOOL_PORT, IPTOOL_STATE (up or down),
+IPTOOL_PREVIOUS_STATE, IPTOOL_MESSAGE and IPTOOL_EVENT (JSON) environment
+variables, the webhook gets the JSON event in the body of a POST request.
+
+Use --alert-rtt to also report a host as slow when a response takes longer
+than the threshold, and --webhook-url to post the changes to a chat or
+alerting webhook in the --webhook-format (generic, slack or teams) or a
+payload rendered from --webhook-template. Webhook requests are retried
+(--webhook-retries) and rate limited (--webhook-rate).
+
+The timestamps of the CSV records and of the --verbose output use the
+global --time-format and --time-zone, use --timestamp-format to select
+a format for tcp ping only: rfc3339 (with the time zone offset), unix
+or unix-ms (seconds or milliseconds since the epoch).
+
 Example:
   iptool tcp ping 1.0.0.1
   iptool tcp ping 1.0.0.1 443
-  iptool tcp ping 1.0.0.1:53 --timeout 500`,
-	SilenceUsage: true,
+  iptool tcp ping 1.0.0.1:53 --timeout 500
+  iptool tcp ping 10.0.{1..4}.1 22 -c 3
+  iptool tcp ping @dns-servers 53
+  iptool tcp ping 1.0.0.1 --summary-interval 60s
+  iptool tcp ping 1.0.0.1 --source eth1
+  iptool tcp ping www.example.com -6
+  iptool tcp ping 1.0.0.1 --jitter 100ms
+  iptool tcp ping 1.0.0.1 --adaptive -c 100
+  iptool tcp ping 1.0.0.1 -c 10 --quiet
+  iptool tcp ping www.example.com --resolve-every 30s
+  iptool tcp ping 10.0.0.1 22 --bell --notify-on-change 'notify-send "$IPTOOL_MESSAGE"'
+  iptool tcp ping 10.0.0.1 --alert-rtt 200ms --webhook-url $SLACK_URL --webhook-format slack
+  iptool tcp ping 1.0.0.1 --csv -o ping.csv --timestamp-format rfc3339`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeHostPortArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Check that the user provided one or two arguments
-		if len(args) < 1 || len(args) > 2 {
-			return errors.New("invalid number of arguments")
+		// Parse the host and the port
+		host, port, err := parseHostPortArgs(args, 443)
+		if err != nil {
+			return err
 		}
 
-		// Check if the user used the format host:port
-		if strings.Contains(args[0], ":") {
-			// Split the host and port
-			hostPort := strings.Split(args[0], ":")
-			args[0] = hostPort[0]
-			args = append(args, hostPort[1])
+		// Expand the host (or host pattern or @group)
+		hosts, err := expandTargets([]string{host})
+		if err != nil {
+			return err
 		}
 
-		// Parse the host
-		host := args[0]
+		return tcpPingAction(os.Stdout, hosts, port)
+	},
+}
 
-		// Parse the port
-		port := 443
-		if len(args) == 2 {
-			// Convert the port to an integer
-			p, err := strconv.Atoi(args[1])
-			if err != nil {
-				return err
-			}
+// pingTarget holds the packet counters and response time statistics
+// of a single destination pinged by the tcp ping command
+type pingTarget struct {
+	host  string
+	ip    string       // The address that answered the last ping
+	addrs []netip.Addr // The addresses of the host, in happy eyeballs order
 
-			// Check that the port is valid
-			if p < 1 || p > 65535 {
-				return errors.New("invalid port number, must be between 1 and 65535")
-			}
+	// State of the target (up, slow or down, empty before the first ping)
+	state string
 
-			// Set the port
-			port = p
-		}
+	// Packet counters
+	packetsSent     int
+	packetsReceived int
 
-		return tcpPingAction(os.Stdout, host, port)
-	},
+	// Response time statistics
+	stats stats.Stats
+
+	// Packet counter and response time statistics of the current summary interval
+	intervalSent  int
+	intervalStats stats.Stats
 }
 
-func tcpPingAction(out io.Writer, host string, port int) error {
-	// Define the delay duration
-	delay := viper.GetDuration("tcp.ping.delay") * time.Millisecond
+// setAddrs sets the addresses of the target after resolving its host again,
+// and returns a printable line if the addresses changed (or could not be
+// resolved, in which case the previous addresses are kept)
+func (t *pingTarget) setAddrs(addrs []netip.Addr, err error) string {
+	if err != nil {
+		return fmt.Sprintf("[%s] Failed to resolve %s again, keeping the previous addresses: %v\n", tcpPingTimestamp(), t.host, err)
+	}
+	if sameAddrs(addrs, t.addrs) {
+		return ""
+	}
+
+	// Report the address that answered the last ping from the new addresses
+	previous := joinAddrs(t.addrs)
+	t.addrs = addrs
+	if addr, err := netip.ParseAddr(t.ip); err != nil || !slices.Contains(addrs, addr) {
+		t.ip = addrs[0].String()
+	}
+	return fmt.Sprintf("[%s] %s changed address: %s -> %s\n", tcpPingTimestamp(), t.host, previous, joinAddrs(addrs))
+}
+
+// sameAddrs is a function that reports whether two lists hold the same
+// addresses, in any order (DNS servers rotate the order of the answers)
+func sameAddrs(a, b []netip.Addr) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.SortFunc(a, netip.Addr.Compare)
+	slices.SortFunc(b, netip.Addr.Compare)
+	return slices.Equal(a, b)
+}
+
+// joinAddrs is a function that returns the addresses separated by commas
+func joinAddrs(addrs []netip.Addr) string {
+	s := make([]string, len(addrs))
+	for i, addr := range addrs {
+		s[i] = addr.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// setState sets the state of the target after a ping (up, slow or down)
+// and returns the previous state and whether the state changed. The first
+// ping only changes the state if the target is not up.
+func (t *pingTarget) setState(state string) (previous string, changed bool) {
+	previous, t.state = t.state, state
+	return previous, state != previous && (previous != "" || state != notify.StateUp)
+}
+
+// update adds a response time to the statistics of the target
+func (t *pingTarget) update(responseTime time.Duration) {
+	// 3-way handshake completed, update packets received
+	t.packetsReceived++
+
+	// Update the response time statistics
+	t.stats.Add(responseTime)
+	t.intervalStats.Add(responseTime)
+}
+
+// intervalSummary returns the statistics of the current summary interval
+// as a printable line and starts a new interval
+func (t *pingTarget) intervalSummary() string {
+	sent, s := t.intervalSent, t.intervalStats
+	t.intervalSent, t.intervalStats = 0, stats.Stats{}
+
+	// Calculate packet loss
+	packetLoss := 0
+	if sent > 0 {
+		packetLoss = (sent - s.Count()) * 100 / sent
+	}
+	outStr := fmt.Sprintf("[%s] %s summary: %d sent, %d received, %d%% loss", tcpPingTimestamp(), t.host, sent, s.Count(), packetLoss)
+
+	// Without responses there are no response time statistics
+	if s.Count() == 0 {
+		return outStr + "\n"
+	}
+
+	// Calculate min, avg, max and p95 response times
+	minResponseTime := s.Min().Round(time.Microsecond * 10)
+	avgResponseTime := s.Mean().Round(time.Microsecond * 10)
+	maxResponseTime := s.Max().Round(time.Microsecond * 10)
+	p95ResponseTime := s.Percentile(95).Round(time.Microsecond * 10)
+
+	return outStr + fmt.Sprintf(", rtt min/avg/max/p95 = %s/%s/%s/%s\n", minResponseTime, avgResponseTime, maxResponseTime, p95ResponseTime)
+}
+
+// statistics returns the ping statistics of the target as a printable string
+func (t *pingTarget) statistics(totalTime time.Duration) string {
+	// Calculate total time
+	totalTimeMs := totalTime.Round(time.Millisecond * 10)
+
+	// Calculate min, avg, max and mdev response times
+	avgResponseTimeMs := t.stats.Mean().Round(time.Microsecond * 10)
+	minResponseTimeMs := t.stats.Min().Round(time.Microsecond * 10)
+	maxResponseTimeMs := t.stats.Max().Round(time.Microsecond * 10)
+	mdevResponseTimeMs := t.stats.StdDev().Round(time.Microsecond * 10)
+
+	// Calculate percentiles and jitter
+	p50ResponseTimeMs := t.stats.Percentile(50).Round(time.Microsecond * 10)
+	p90ResponseTimeMs := t.stats.Percentile(90).Round(time.Microsecond * 10)
+	p99ResponseTimeMs := t.stats.Percentile(99).Round(time.Microsecond * 10)
+	jitterMs := t.stats.Jitter().Round(time.Microsecond * 10)
+
+	// Calculate packet loss
+	packetLoss := 0
+	if t.packetsSent > 0 {
+		packetLoss = (t.packetsSent - t.packetsReceived) * 100 / t.packetsSent
+	}
+
+	outStr := fmt.Sprintf("--- %s ping statistics ---\n", t.host)
+	outStr += fmt.Sprintf("%d packets transmitted, %d received, %d%% packet loss, time %s\n", t.packetsSent, t.packetsReceived, packetLoss, totalTimeMs)
+	outStr += fmt.Sprintf("rtt min/avg/max/mdev = %s/%s/%s/%s\n", minResponseTimeMs, avgResponseTimeMs, maxResponseTimeMs, mdevResponseTimeMs)
+	outStr += fmt.Sprintf("rtt p50/p90/p99 = %s/%s/%s, jitter = %s\n", p50ResponseTimeMs, p90ResponseTimeMs, p99ResponseTimeMs, jitterMs)
+	return outStr
+}
+
+func tcpPingAction(out io.Writer, hosts []string, port int) error {
+	// Define the delay between pings, with the optional jitter and adaptive mode
+	scheduler := &tcp.Scheduler{
+		Delay:    viper.GetDuration("tcp.ping.delay") * time.Millisecond,
+		Jitter:   viper.GetDuration("tcp.ping.jitter"),
+		Adaptive: viper.GetBool("tcp.ping.adaptive"),
+	}
 
 	// Define the number of packets to send
 	count := viper.GetInt("tcp.ping.count")
 
 	// If the --csv flag is set and --output-file is not set, return an error
 	if viper.GetBool("tcp.ping.csv") && !viper.IsSet("tcp.ping.output-file") {
-		return csvFlagError
+		return exitcode.New(exitcode.Usage, csvFlagError)
 	}
 
-	// Resolve the IP address of the destination
-	ip, err := ip.ResolveIP(host)
+	// Validate the format of the timestamps
+	if err := utils.ValidateTimeFormat(viper.GetString("tcp.ping.timestamp-format")); err != nil {
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Resolve the source address (or interface) to send the pings from, the
+	// first IPv6 address of an interface is used with -6
+	family := getFamily("tcp.ping")
+	sourceName := viper.GetString("tcp.ping.source")
+	sourceIPv6 := family == ip.FamilyIPv6
+	if addr, err := netip.ParseAddr(sourceName); err == nil {
+		sourceIPv6 = addr.Is6()
+	}
+	source, err := ip.SourceAddress(sourceName, sourceIPv6)
 	if err != nil {
-		return err
+		return exitcode.New(exitcode.Usage, err)
+	}
+
+	// Only the addresses of the family of the source can be pinged
+	if source != nil {
+		family = ip.FamilyIPv6
+		if source.IP.To4() != nil {
+			family = ip.FamilyIPv4
+		}
 	}
 
+	// Resolve the IPv4 and IPv6 addresses of every destination
+	targets := make([]*pingTarget, 0, len(hosts))
+	for _, host := range hosts {
+		addrs, err := ip.ResolveAddrs(host, family)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, &pingTarget{host: host, ip: addrs[0].String(), addrs: addrs})
+	}
+
+	// The mutex protects the statistics from being read while they are updated
+	var mutex sync.Mutex
+
 	// Create a channel to receive interrupt signals
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
-	// Packet counters
-	packetsSent := 0
-	packetsReceived := 0
-
-	// Response times
-	minResponseTime := time.Duration(0)
-	maxResponseTime := time.Duration(0)
-	avgResponseTime := time.Duration(0)
-	mdevResponseTime := time.Duration(0)
-	totResponseTime := time.Duration(0)
-	totResponseDeviation := time.Duration(0)
-
 	// Start the timer
 	startTime := time.Now()
 
 	// Determine the output file using Viper
 	outputFile := viper.GetString("tcp.ping.output-file")
-	append := viper.GetBool("tcp.ping.append")
+	csvOutput := viper.GetBool("tcp.ping.csv")
+
+	// The CSV records are written to the output file, otherwise the file
+	// gets a copy of the output printed to stdout. Every ping is flushed to
+	// the file right away, so that it can be followed while pinging.
+	opts := utils.OutputOptions{Append: viper.GetBool("tcp.ping.append"), Stream: true}
+	if csvOutput {
+		opts.Header = "timestamp,host,ip,port,status,response_time_ms\n"
+	} else {
+		opts.Tee = out
+	}
 
 	// Get the output stream
-	outputStream, err := utils.GetOutputStream(outputFile, append)
+	outputStream, err := getOutputWriter("tcp.ping", outputFile, out, opts)
 	if err != nil {
 		return err
 	}
 	defer outputStream.Close()
 
-	// Print start message (Initiate 3-way handshake with one.one.one.one (1.1.1.1) on port 443.)
-	startMsg := fmt.Sprintf("Initiating 3-way handshakes with %s (%s) on port %d.\n", host, ip, port)
+	// The text output is printed to stdout, and to the file as well if
+	// --output-file is set and --csv is not set
+	text := out
+	if outputStream.IsFile() && !csvOutput {
+		text = outputStream
+	}
 
-	// Print the compiled string to stdout
-	fmt.Fprint(out, startMsg)
+	// The webhook alerts are posted in the background, in order and rate limited
+	webhook, err := getWebhookDispatcher("tcp.ping")
+	if err != nil {
+		return err
+	}
 
-	// Print CSV header if --csv is set
-	csvStartMsg := fmt.Sprintf("timestamp,host,ip,port,status,response_time_ms\n")
+	// A response slower than --alert-rtt changes the state of the target to slow
+	alertRTT := viper.GetDuration("tcp.ping.alert-rtt")
+	if alertRTT < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --alert-rtt value: %s (must be positive)", alertRTT))
+	}
 
-	// Print to file as well if --output-file is set
-	if !viper.GetBool("tcp.ping.append") {
-		if viper.IsSet("tcp.ping.output-file") && viper.GetBool("tcp.ping.csv") {
-			fmt.Fprint(outputStream, csvStartMsg)
-		} else if viper.IsSet("tcp.ping.output-file") {
-			fmt.Fprint(outputStream, startMsg)
-		}
+	// Print start message (Initiate 3-way handshake with one.one.one.one (1.1.1.1) on port 443.)
+	startMsg := ""
+	for _, target := range targets {
+		startMsg += fmt.Sprintf("Initiating 3-way handshakes with %s (%s) on port %d.\n", target.host, joinAddrs(target.addrs), port)
 	}
 
+	// Print the compiled string
+	fmt.Fprint(text, startMsg)
+
+	// The notifications of state changes run in the background, the last
+	// ones are waited for before exiting
+	bell := viper.GetBool("tcp.ping.bell")
+	notifyHook := viper.GetString("tcp.ping.notify-on-change")
+	var notifications sync.WaitGroup
+
 	// Start a goroutine that will print a message when a signal (Ctrl-C) is received
 	go func() {
 		sig := <-interrupt
 
 		// Ctrl-C was pressed, print statistics and exit
 		if sig == os.Interrupt {
-			// Calculate mean deviation
-			if packetsReceived > 1 {
-				mdevResponseTime = totResponseDeviation / time.Duration(packetsReceived)
-			}
+			notifications.Wait()
+			webhook.Close(notify.Timeout)
+			mutex.Lock()
 
 			// Calculate total time
 			totalTime := time.Since(startTime)
-			totalTimeMs := totalTime.Round(time.Millisecond * 10)
-
-			// Calculate min, avg, max and mdev response times
-			avgResponseTimeMs := avgResponseTime.Round(time.Microsecond * 10)
-			minResponseTimeMs := minResponseTime.Round(time.Microsecond * 10)
-			maxResponseTimeMs := maxResponseTime.Round(time.Microsecond * 10)
-			mdevResponseTimeMs := mdevResponseTime.Round(time.Microsecond * 10)
-
-			// Calculate packet loss
-			packetLoss := (packetsSent - packetsReceived) * 100 / packetsSent
 
 			outStr := fmt.Sprintf("^C\n")
-			outStr += fmt.Sprintf("--- %s ping statistics ---\n", host)
-			outStr += fmt.Sprintf("%d packets transmitted, %d received, %d%% packet loss, time %s\n", packetsSent, packetsReceived, packetLoss, totalTimeMs)
-			outStr += fmt.Sprintf("rtt min/avg/max/mdev = %s/%s/%s/%s\n", minResponseTimeMs, avgResponseTimeMs, maxResponseTimeMs, mdevResponseTimeMs)
+			for _, target := range targets {
+				outStr += target.statistics(totalTime)
+			}
 
-			// Print the compiled string to stdout
-			fmt.Fprint(out, outStr)
+			// Print the compiled string
+			fmt.Fprint(text, outStr)
+
+			// Flush the output files before exiting
+			if err := outputStream.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := stopGlobalOutput(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
 
-			// Print to file as well if --output-file is set and --csv is not set
-			if viper.IsSet("tcp.ping.output-file") && !viper.GetBool("tcp.ping.csv") {
-				fmt.Fprint(outputStream, outStr)
+			// Exit with a failure if pings were lost
+			if err := tcpPingLoss(targets); err != nil {
+				exitWithError(err)
 			}
 			os.Exit(0)
 		}
 	}()
 
-	// Set timeout duration for the TCP ping (default 2000 ms)
-	timeoutMs := viper.GetDuration("tcp.ping.timeout") * time.Millisecond
-
-	// Perform the TCP ping until user presses Ctrl-C
-	for {
-		// Send SYN packet and wait for SYN/ACK response
-		packetsSent++
-
-		// Send SYN packet and wait for SYN/ACK response
-		responseTime, err := tcp.PingTCP(host, port, timeoutMs)
-
-		// Check if the ping timed out
-		if err != nil {
-			// Get current time for timestamp
-			currentTime := utils.GetTimestamp()
-
-			// Format the CSV output string
-			csvOutStr := fmt.Sprintf("%027s,%s,%s,%d,%s,%d\n", currentTime, host, ip, port, "offline", 0)
+	// Print per-interval statistics if the --summary-interval flag is set
+	if summaryInterval := viper.GetDuration("tcp.ping.summary-interval"); summaryInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(summaryInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				mutex.Lock()
+				outStr := ""
+				for _, target := range targets {
+					outStr += target.intervalSummary()
+				}
 
-			// Print to file as well if --output-file is set
-			if viper.IsSet("tcp.ping.output-file") && viper.GetBool("tcp.ping.csv") {
-				fmt.Fprint(outputStream, csvOutStr)
+				// Print the compiled string
+				fmt.Fprint(text, outStr)
+				mutex.Unlock()
 			}
+		}()
+	}
 
-			if viper.GetBool("tcp.ping.verbose") {
-				// Format the output string
-				outStr := fmt.Sprintf("[%027s] Request timeout for %s: port=%d timeout=%s\n", currentTime, ip, port, timeoutMs)
+	// The CSV records are only written with --csv
+	var csvStream io.Writer
+	if csvOutput {
+		csvStream = outputStream
+	}
 
-				// Print the compiled string to stdout
-				fmt.Fprint(out, outStr)
+	// Set timeout duration for the TCP ping (default 2000 ms)
+	timeoutMs := viper.GetDuration("tcp.ping.timeout") * time.Millisecond
 
-				// Print to file as well if --output-file is set
-				if viper.IsSet("tcp.ping.output-file") && !viper.GetBool("tcp.ping.csv") {
-					fmt.Fprint(outputStream, outStr)
-				}
-			} else {
-				// Format the output string
-				outStr := fmt.Sprintf("Request timeout for %s: port=%d timeout=%s\n", ip, port, timeoutMs)
+	// Set the delay before the next address of a host is tried
+	fallbackDelay := viper.GetDuration("tcp.ping.fallback-delay")
+	if fallbackDelay <= 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --fallback-delay value: %s (must be positive)", fallbackDelay))
+	}
 
-				// Print the compiled string to stdout
-				fmt.Fprint(out, outStr)
+	// Resolve the hosts again every --resolve-every to follow DNS changes
+	resolveEvery := viper.GetDuration("tcp.ping.resolve-every")
+	if resolveEvery < 0 {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid --resolve-every value: %s (must be positive)", resolveEvery))
+	}
+	lastResolved := time.Now()
 
-				// Print to file as well if --output-file is set
-				if viper.IsSet("tcp.ping.output-file") && !viper.GetBool("tcp.ping.csv") {
-					fmt.Fprint(outputStream, outStr)
+	// Perform the TCP ping until user presses Ctrl-C
+	for {
+		if resolveEvery > 0 && time.Since(lastResolved) >= resolveEvery {
+			lastResolved = time.Now()
+			for _, target := range targets {
+				// Numeric addresses do not change, the DNS query is sent
+				// without holding the lock
+				if _, err := netip.ParseAddr(target.host); err == nil {
+					continue
 				}
+				addrs, err := ip.LookupAddrs(target.host, family)
+				mutex.Lock()
+				fmt.Fprint(text, target.setAddrs(addrs, err))
+				mutex.Unlock()
 			}
-			continue
 		}
 
-		// 3-way handshake completed, update packets received
-		packetsReceived++
-
-		// Update total response time
-		totResponseTime += responseTime
-
-		// Update min/max response times
-		if packetsReceived == 1 {
-			minResponseTime = responseTime
-			maxResponseTime = responseTime
-		} else {
-			if responseTime < minResponseTime {
-				minResponseTime = responseTime
+		for _, target := range targets {
+			responseTime, ok := tcpPingTarget(text, csvStream, target, port, source, timeoutMs, fallbackDelay, &mutex)
+			scheduler.Observe(responseTime, ok)
+
+			// Ring the bell and notify when the target goes down, gets slow or recovers
+			state := notify.StateUp
+			switch {
+			case !ok:
+				state = notify.StateDown
+			case alertRTT > 0 && responseTime > alertRTT:
+				state = notify.StateSlow
 			}
-			if responseTime > maxResponseTime {
-				maxResponseTime = responseTime
+			previous, changed := target.setState(state)
+			if !changed {
+				continue
+			}
+			if bell {
+				fmt.Fprint(out, "\a")
+			}
+			mutex.Lock()
+			event := notify.NewEvent(target.host, target.ip, port, target.state, previous)
+			mutex.Unlock()
+			event.Source = "tcp ping"
+			webhook.Notify(event)
+			if notifyHook != "" {
+				notifications.Add(1)
+				go func() {
+					defer notifications.Done()
+					if err := notify.Send(context.Background(), notifyHook, event); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: notify %s: %v\n", event.Target, err)
+					}
+				}()
 			}
 		}
 
-		// Update mean response time
-		avgResponseTime = totResponseTime / time.Duration(packetsReceived)
+		// Check if the user specified a number of packets to send
+		if count > 0 && targets[len(targets)-1].packetsSent >= count {
+			// Raise interrupt signal to stop the ping loop
+			interrupt <- os.Interrupt
 
-		// Update mean deviation (mdev)
-		// This is an average of how far each ping RTT is from the mean RTT. The higher mdev is, the more variable the RTT is (over time).
-		stdResponseDeviation := float64(responseTime - avgResponseTime)
-		stdResponseDeviation = math.Sqrt(math.Pow(stdResponseDeviation, 2))
+			// Wait for the statistics to be printed
+			select {}
+		}
 
-		// Update total response deviation for later calculation of mdev
-		totResponseDeviation += time.Duration(stdResponseDeviation)
+		// Pause execution until the next ping is due
+		time.Sleep(scheduler.Next())
+	}
+}
 
-		// Convert responseTime to float64
-		responseTimeFloat := float64(responseTime) / float64(time.Millisecond)
+// tcpPingLoss returns an Unreachable error if none of the pings were
+// answered, and a Partial error if some of them were lost
+func tcpPingLoss(targets []*pingTarget) error {
+	sent, received := 0, 0
+	for _, target := range targets {
+		sent += target.packetsSent
+		received += target.packetsReceived
+	}
+	switch {
+	case sent > 0 && received == 0:
+		return exitcode.New(exitcode.Unreachable, fmt.Errorf("no response to %d ping(s)", sent))
+	case received < sent:
+		return exitcode.New(exitcode.Partial, fmt.Errorf("%d of %d ping(s) lost", sent-received, sent))
+	}
+	return nil
+}
 
+// tcpPingTarget sends a single TCP ping to the target, prints the result to
+// out and the CSV record to csvStream (if not nil) and returns the response
+// time (ok is false if the ping timed out)
+func tcpPingTarget(out io.Writer, csvStream io.Writer, target *pingTarget, port int, source *net.IPAddr, timeoutMs, fallbackDelay time.Duration, mutex *sync.Mutex) (responseTime time.Duration, ok bool) {
+	host := target.host
+
+	// Send SYN packet and wait for SYN/ACK response
+	mutex.Lock()
+	target.packetsSent++
+	target.intervalSent++
+	packetsSent := target.packetsSent
+	mutex.Unlock()
+
+	// Send SYN packets to the addresses of the host and wait for the first SYN/ACK response
+	addr, responseTime, err := tcp.PingHappyEyeballs(target.addrs, port, source, timeoutMs, fallbackDelay)
+
+	// Report the address that answered, or the first address if none did
+	mutex.Lock()
+	if err == nil {
+		target.ip = addr.String()
+	}
+	ip := target.ip
+	mutex.Unlock()
+
+	// Record the result if --record is set
+	recordResults(results.Result{
+		Kind:    results.KindTCPPing,
+		Target:  host,
+		Address: ip,
+		Port:    port,
+		Success: err == nil,
+		RTTMs:   durationMs(responseTime),
+	})
+
+	// Hold the lock while updating the statistics and printing the result
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Check if the ping timed out
+	if err != nil {
 		// Get current time for timestamp
-		currentTime := utils.GetTimestamp()
+		currentTime := tcpPingTimestamp()
 
 		// Format the CSV output string
-		csvOutStr := fmt.Sprintf("%s,%s,%s,%d,%s,%.4f\n", currentTime, host, ip, port, "online", responseTimeFloat)
+		csvOutStr := fmt.Sprintf("%s,%s,%s,%d,%s,%d\n", currentTime, host, ip, port, "offline", 0)
 
-		// Print to file as well if --output-file is set
-		if viper.IsSet("tcp.ping.output-file") && viper.GetBool("tcp.ping.csv") {
-			fmt.Fprint(outputStream, csvOutStr)
+		// Write the CSV record if --csv is set
+		if csvStream != nil {
+			fmt.Fprint(csvStream, csvOutStr)
 		}
 
-		// Print response information (debug or normal output)
-		if viper.GetBool("tcp.ping.verbose") {
+		// Only the statistics are printed if the --quiet flag is set
+		if viper.GetBool("tcp.ping.quiet") {
+			return responseTime, false
+		}
 
+		if viper.GetBool("tcp.ping.verbose") {
 			// Format the output string
-			formatStr := "[%s] Received SYN/ACK from %s: port=%d tcp_seq=%d time=%-8s mrtt=%s\n"
+			outStr := fmt.Sprintf("[%s] Request timeout for %s: port=%d timeout=%s\n", currentTime, ip, port, timeoutMs)
 
-			// Print to stdout
-			fmt.Fprintf(out, formatStr, currentTime, ip, port, packetsSent, responseTime.Round(time.Microsecond*10), avgResponseTime.Round(time.Microsecond*10))
-
-			// Print to file as well if --output-file is set
-			if viper.IsSet("tcp.ping.output-file") && !viper.GetBool("tcp.ping.csv") {
-				fmt.Fprintf(outputStream, formatStr, currentTime, ip, port, packetsSent, responseTime.Round(time.Microsecond*10), avgResponseTime.Round(time.Microsecond*10))
-			}
+			// Print the compiled string
+			fmt.Fprint(out, outStr)
 		} else {
 			// Format the output string
-			formatStr := "Received SYN/ACK from %s: port=%d tcp_seq=%d time=%s\n"
-
-			// Print to stdout
-			fmt.Fprintf(out, formatStr, ip, port, packetsSent, responseTime.Round(time.Microsecond*10))
+			outStr := fmt.Sprintf("Request timeout for %s: port=%d timeout=%s\n", ip, port, timeoutMs)
 
-			// Print to file as well if --output-file is set
-			if viper.IsSet("tcp.ping.output-file") && !viper.GetBool("tcp.ping.csv") {
-				fmt.Fprintf(outputStream, formatStr, ip, port, packetsSent, responseTime.Round(time.Microsecond*10))
-			}
+			// Print the compiled string
+			fmt.Fprint(out, outStr)
 		}
+		return responseTime, false
+	}
 
-		// Check if the user specified a number of packets to send
-		if count > 0 && packetsSent >= count {
-			// Raise interrupt signal to stop the ping loop
-			interrupt <- os.Interrupt
-		}
+	// Update the response time statistics
+	target.update(responseTime)
+	avgResponseTime := target.stats.Mean()
+
+	// Convert responseTime to float64
+	responseTimeFloat := float64(responseTime) / float64(time.Millisecond)
+
+	// Get current time for timestamp
+	currentTime := tcpPingTimestamp()
+
+	// Format the CSV output string
+	csvOutStr := fmt.Sprintf("%s,%s,%s,%d,%s,%.4f\n", currentTime, host, ip, port, "online", responseTimeFloat)
+
+	// Write the CSV record if --csv is set
+	if csvStream != nil {
+		fmt.Fprint(csvStream, csvOutStr)
+	}
+
+	// Only the statistics are printed if the --quiet flag is set
+	if viper.GetBool("tcp.ping.quiet") {
+		return responseTime, true
+	}
+
+	// Print response information (debug or normal output)
+	if viper.GetBool("tcp.ping.verbose") {
+
+		// Format the output string
+		formatStr := "[%s] Received SYN/ACK from %s: port=%d tcp_seq=%d time=%-8s mrtt=%s\n"
+
+		// Print the result
+		fmt.Fprintf(out, formatStr, currentTime, ip, port, packetsSent, responseTime.Round(time.Microsecond*10), avgResponseTime.Round(time.Microsecond*10))
+	} else {
+		// Format the output string
+		formatStr := "Received SYN/ACK from %s: port=%d tcp_seq=%d time=%s\n"
+
+		// Print the result
+		fmt.Fprintf(out, formatStr, ip, port, packetsSent, responseTime.Round(time.Microsecond*10))
+	}
+	return responseTime, true
+}
 
-		// Pause execution for the specified delay duration
-		time.Sleep(delay)
+// tcpPingTimestamp returns the current time formatted with the format
+// selected with --timestamp-format, or the global --time-format if not set
+func tcpPingTimestamp() string {
+	if format := viper.GetString("tcp.ping.timestamp-format"); format != "" {
+		return utils.FormatTimestampAs(time.Now(), format)
 	}
+	return utils.GetTimestamp()
 }
 
 func init() {
@@ -345,6 +697,12 @@ func init() {
 	pingCmd.Flags().IntP("delay", "d", 1000, "delay between pings, in milliseconds")
 	viper.BindPFlag("tcp.ping.delay", pingCmd.Flags().Lookup("delay"))
 
+	// Enable the --jitter and --adaptive flags to vary the delay between pings
+	pingCmd.Flags().Duration("jitter", 0, "vary the delay between pings randomly by up to this duration (e.g. 100ms)")
+	viper.BindPFlag("tcp.ping.jitter", pingCmd.Flags().Lookup("jitter"))
+	pingCmd.Flags().BoolP("adaptive", "A", false, "wait one round-trip time between pings instead of the delay (like ping -A)")
+	viper.BindPFlag("tcp.ping.adaptive", pingCmd.Flags().Lookup("adaptive"))
+
 	// Enable the --count flag for the ping command
 	pingCmd.Flags().IntP("count", "c", 0, "")
 	viper.BindPFlag("tcp.ping.count", pingCmd.Flags().Lookup("count"))
@@ -354,6 +712,36 @@ func init() {
 	pingCmd.Flags().BoolP("verbose", "v", false, "show timestamps and mean round-trip time (mrtt)")
 	viper.BindPFlag("tcp.ping.verbose", pingCmd.Flags().Lookup("verbose"))
 
+	// Enable the --quiet flag for the ping command
+	pingCmd.Flags().BoolP("quiet", "q", false, "only print the statistics, not every ping (CSV output is still written)")
+	viper.BindPFlag("tcp.ping.quiet", pingCmd.Flags().Lookup("quiet"))
+
+	// Enable the --summary-interval flag for the ping command
+	pingCmd.Flags().Duration("summary-interval", 0, "print min/avg/max/p95/loss statistics for every interval (e.g. 60s)")
+	viper.BindPFlag("tcp.ping.summary-interval", pingCmd.Flags().Lookup("summary-interval"))
+
+	// Add flags for the address family and the happy eyeballs fallback delay
+	addFamilyFlags(pingCmd, "tcp.ping")
+	pingCmd.Flags().Duration("fallback-delay", tcp.FallbackDelay, "time to wait for an address before the next address of the host is tried")
+	viper.BindPFlag("tcp.ping.fallback-delay", pingCmd.Flags().Lookup("fallback-delay"))
+
+	// Add flag for resolving the hosts again during long-running pings
+	pingCmd.Flags().Duration("resolve-every", 0, "resolve the hosts again at this interval and follow address changes (e.g. 30s)")
+	viper.BindPFlag("tcp.ping.resolve-every", pingCmd.Flags().Lookup("resolve-every"))
+
+	// Add flags for alerting when a host goes down or recovers
+	pingCmd.Flags().Bool("bell", false, "ring the terminal bell when a host goes down or recovers")
+	viper.BindPFlag("tcp.ping.bell", pingCmd.Flags().Lookup("bell"))
+	pingCmd.Flags().String("notify-on-change", "", "command or webhook URL to notify when a host goes down or recovers")
+	viper.BindPFlag("tcp.ping.notify-on-change", pingCmd.Flags().Lookup("notify-on-change"))
+	pingCmd.Flags().Duration("alert-rtt", 0, "report a host as slow when a response takes longer than this (e.g. 200ms)")
+	viper.BindPFlag("tcp.ping.alert-rtt", pingCmd.Flags().Lookup("alert-rtt"))
+	addWebhookFlags(pingCmd, "tcp.ping")
+
+	// Add flag for --source address or interface
+	pingCmd.Flags().StringP("source", "S", "", "send the pings from this local address or interface")
+	viper.BindPFlag("tcp.ping.source", pingCmd.Flags().Lookup("source"))
+
 	// Add flag for --output-file path
 	pingCmd.PersistentFlags().StringP("output-file", "o", "", "write output to file")
 	viper.BindPFlag("tcp.ping.output-file", pingCmd.PersistentFlags().Lookup("output-file"))
@@ -366,4 +754,12 @@ func init() {
 	pingCmd.PersistentFlags().BoolP("csv", "C", false, "write output in CSV format")
 	viper.BindPFlag("tcp.ping.csv", pingCmd.PersistentFlags().Lookup("csv"))
 
+	// Add flag for the format of the timestamps
+	pingCmd.Flags().String("timestamp-format", "", "timestamp format of CSV and verbose output (rfc3339, unix, unix-ms or a Go time layout, default --time-format)")
+	viper.BindPFlag("tcp.ping.timestamp-format", pingCmd.Flags().Lookup("timestamp-format"))
+	pingCmd.RegisterFlagCompletionFunc("timestamp-format", completeValues(utils.TimeFormatRFC3339, utils.TimeFormatUnix, utils.TimeFormatUnixMs))
+
+	// Add flag for rotating the output file by size
+	addMaxSizeFlag(pingCmd, "tcp.ping")
+
 }
@@ -0,0 +1,308 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bitcanon/iptool/debug"
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/port"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/render"
+	"github.com/bitcanon/iptool/scan"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tcpScanDefaultPorts are the ports scanned if --ports is not given
+const tcpScanDefaultPorts = "21,22,23,25,53,80,110,143,443,445,465,587,993,995,1433,3306,3389,5432,5900,6379,8080,8443"
+
+// tcpScanFormats are the output formats of the tcp scan command
+var tcpScanFormats = []string{"table", "csv", "json", "nmap-xml", "nmap-grep"}
+
+// tcpScanCmd represents the tcp scan command
+var tcpScanCmd = &cobra.Command{
+	Use:   "scan <host...>",
+	Short: "Scan the TCP ports of hosts and identify their services",
+	Long: `Scan the TCP ports of hosts and identify their services.
+
+Every port is scanned with a full TCP connection (a connect scan), a port is
+open if the connection is accepted, closed if it is refused and filtered if
+there is no response within the timeout.
+
+The services on the open ports are fingerprinted: iptool reads the greeting
+of the service (SSH, FTP, SMTP, POP3, IMAP, VNC and MySQL send one), sends an
+HTTP request if there is none, and tries a TLS handshake if neither is
+recognized. The service and version columns show the result, or the
+registered service of the port with a ? if the service was not recognized.
+Use --no-fingerprint to only scan the ports.
+
+The hosts may contain brace patterns (web{01..20}.example.com) and groups
+(@name). Only scan hosts you are allowed to scan.
+
+Examples:
+  iptool tcp scan 192.0.2.10
+  iptool tcp scan 192.0.2.10 --ports 1-1024
+  iptool tcp scan web{01..04}.example.com -p 22,80,443 --format nmap-xml -o scan.xml
+  iptool tcp scan 192.0.2.10 -p 8000-8100 --rate 100/s --all`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAliasArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// If no hosts are provided, print a short help text
+		if len(args) == 0 {
+			cmd.Help()
+			return nil
+		}
+		hosts, err := expandTargets(args)
+		if err != nil {
+			return err
+		}
+		return tcpScanAction(os.Stdout, hosts)
+	},
+}
+
+// parsePortList is a function that parses a list of ports and ranges of
+// ports separated by commas (e.g. 22,80,8000-8100) into sorted unique ports
+func parsePortList(s string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(s, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		from, to, err := port.ParsePortRange(field)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+		for p := max(from, 1); p <= to; p++ {
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid port list: %s (no ports)", s))
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports), nil
+}
+
+// scanPortState is a function that returns the state of a port from the
+// error of a connection to it: open, closed (refused) or filtered
+func scanPortState(err error) string {
+	if err == nil {
+		return "open"
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "closed"
+	}
+	return "filtered"
+}
+
+// probableService is a function that returns the registered service of a
+// TCP port with a ? to mark it as a guess, e.g. http? for port 80
+func probableService(number int) string {
+	services, _ := port.Lookup(strconv.Itoa(number), port.ProtocolTCP)
+	if len(services) == 0 {
+		return ""
+	}
+	return services[0].Name + "?"
+}
+
+// tcpScanAction is the action function for the tcp scan command
+func tcpScanAction(out io.Writer, hosts []string) error {
+	ports, err := parsePortList(viper.GetString("tcp.scan.ports"))
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(viper.GetString("tcp.scan.format"))
+	if !slices.Contains(tcpScanFormats, format) {
+		return exitcode.New(exitcode.Usage, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(tcpScanFormats, ", ")))
+	}
+	timeout := viper.GetDuration("tcp.scan.timeout") * time.Millisecond
+	limiter, err := getRateLimiter("tcp.scan")
+	if err != nil {
+		return err
+	}
+	concurrency, err := getConcurrency("tcp.scan")
+	if err != nil {
+		return err
+	}
+
+	// Resolve the addresses of the hosts before sending anything
+	results := make([]scan.Host, len(hosts))
+	for i, host := range hosts {
+		address := host
+		if _, err := netip.ParseAddr(host); err != nil {
+			if address, err = ip.ResolveIP(host); err != nil {
+				return err
+			}
+		}
+		results[i] = scan.Host{Address: address, State: "up", Source: "iptool"}
+		if address != host {
+			results[i].Hostname = host
+		}
+		results[i].Ports = make([]scan.Port, len(ports))
+	}
+
+	// Scan every port of every host, at most --concurrency at a time and at
+	// the --rate, when Ctrl-C is pressed the connections in flight are completed
+	run := scan.Run{Scanner: "iptool", Version: rootCmd.Version, Args: strings.Join(os.Args, " "), Start: time.Now()}
+	fingerprint := !viper.GetBool("tcp.scan.no-fingerprint")
+	ctx, stop := ratelimit.InterruptContext(context.Background())
+	defer stop()
+	bar := newProgressBar("scan", len(hosts)*len(ports), nil)
+	ratelimit.Run(ctx, len(hosts)*len(ports), concurrency, limiter, func(ctx context.Context, i int) {
+		defer bar.Add(1)
+		host, number := &results[i/len(ports)], ports[i%len(ports)]
+		address := net.JoinHostPort(host.Address, strconv.Itoa(number))
+
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		p := scan.Port{Port: number, Protocol: port.ProtocolTCP, State: scanPortState(err)}
+		if p.State == "open" && fingerprint {
+			fp := scan.FingerprintPort(ctx, address, timeout)
+			p.Service, p.Version = fp.Service, fp.Version
+		}
+		if p.Service == "" {
+			p.Service = probableService(number)
+		}
+		host.Ports[i%len(ports)] = p
+	})
+	bar.Finish()
+	run.End = time.Now()
+
+	// Only the open ports are printed unless --all is set, ports that were
+	// not scanned because of Ctrl-C have no state
+	for i := range results {
+		results[i].Ports = slices.DeleteFunc(results[i].Ports, func(p scan.Port) bool {
+			return p.State == "" || (p.State != "open" && !viper.GetBool("tcp.scan.all"))
+		})
+	}
+
+	// Determine the output file using Viper
+	outputStream, err := utils.GetOutputStream(viper.GetString("tcp.scan.output-file"), false)
+	if err != nil {
+		return err
+	}
+	defer outputStream.Close()
+
+	switch format {
+	case "json":
+		if err := scan.WriteJSON(outputStream, results); err != nil {
+			return err
+		}
+	case "nmap-xml":
+		if err := scan.WriteXML(outputStream, run, results); err != nil {
+			return err
+		}
+	case "nmap-grep":
+		if err := scan.WriteGreppable(outputStream, run, results); err != nil {
+			return err
+		}
+	case "csv":
+		w := csv.NewWriter(outputStream)
+		w.Write([]string{"address", "port", "protocol", "state", "service", "version"})
+		for _, h := range results {
+			for _, p := range h.Ports {
+				w.Write([]string{h.Address, strconv.Itoa(p.Port), p.Protocol, p.State, p.Service, p.Version})
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	default:
+		table := render.NewTable(outputStream, getRenderOptions("tcp.scan", outputStream),
+			render.Column{Title: "Address"},
+			render.Column{Title: "Port", Align: render.AlignRight},
+			render.Column{Title: "State"},
+			render.Column{Title: "Service"},
+			render.Column{Title: "Version", Truncate: true},
+		)
+		if err := table.Err(); err != nil {
+			return exitcode.New(exitcode.Usage, err)
+		}
+		var rows [][]string
+		for _, h := range results {
+			for _, p := range h.Ports {
+				rows = append(rows, []string{h.Address, p.String(), p.State, p.Service, p.Version})
+			}
+		}
+		for _, row := range rows {
+			table.Fit(row...)
+		}
+		table.Header()
+		for _, row := range rows {
+			table.Row(row...)
+		}
+		fmt.Fprintf(out, "\n%d host(s), %d port(s) scanned in %s\n", len(results), len(ports), run.End.Sub(run.Start).Round(time.Millisecond))
+	}
+
+	// Print the configuration debug if the --debug flag is set
+	if viper.GetBool("debug") {
+		debug.PrintConfigDebug()
+	}
+
+	return nil
+}
+
+func init() {
+	tcpCmd.AddCommand(tcpScanCmd)
+
+	// Define the flags for the ports to scan and the time to wait
+	tcpScanCmd.Flags().StringP("ports", "p", tcpScanDefaultPorts, "ports and ranges of ports to scan (e.g. 22,80,8000-8100)")
+	viper.BindPFlag("tcp.scan.ports", tcpScanCmd.Flags().Lookup("ports"))
+	tcpScanCmd.Flags().IntP("timeout", "t", 1000, "time to wait for a connection or a response, in milliseconds")
+	viper.BindPFlag("tcp.scan.timeout", tcpScanCmd.Flags().Lookup("timeout"))
+	addRateFlag(tcpScanCmd, "tcp.scan")
+	addConcurrencyFlag(tcpScanCmd, "tcp.scan", 100)
+
+	// Define the flags for the fingerprinting and the ports to print
+	tcpScanCmd.Flags().Bool("no-fingerprint", false, "do not identify the services on the open ports")
+	viper.BindPFlag("tcp.scan.no-fingerprint", tcpScanCmd.Flags().Lookup("no-fingerprint"))
+	tcpScanCmd.Flags().BoolP("all", "a", false, "also print the closed and filtered ports")
+	viper.BindPFlag("tcp.scan.all", tcpScanCmd.Flags().Lookup("all"))
+
+	// Define the flags for the output
+	tcpScanCmd.Flags().StringP("format", "f", "table", "output format (table, csv, json, nmap-xml or nmap-grep)")
+	viper.BindPFlag("tcp.scan.format", tcpScanCmd.Flags().Lookup("format"))
+	tcpScanCmd.RegisterFlagCompletionFunc("format", completeValues(tcpScanFormats...))
+	tcpScanCmd.Flags().StringP("output-file", "o", "", "write output to file")
+	viper.BindPFlag("tcp.scan.output-file", tcpScanCmd.Flags().Lookup("output-file"))
+	addRenderFlags(tcpScanCmd, "tcp.scan")
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/tcp"
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultSpeedPort is the default port of the throughput test (same as iperf3)
+const defaultSpeedPort = 5201
+
+// speedCmd represents the speed command
+var speedCmd = &cobra.Command{
+	Use:   "speed <host> [port]",
+	Short: "Measure the TCP throughput to a host",
+	Long: `Measure the TCP throughput to a host.
+
+The TCP speed command sends data over a TCP connection for a
+configurable duration and reports the throughput (in Mbps) for
+every interval as well as for the whole test. On Linux the number
+of retransmitted segments is reported as well.
+
+The other end must run the command in server mode (--server).
+
+If no port is specified, the default port 5201 is used.
+
+Example:
+  iptool tcp speed --server
+  iptool tcp speed --server 9000
+  iptool tcp speed 10.0.0.1
+  iptool tcp speed 10.0.0.1:9000 --duration 30 --interval 5`,
+	SilenceUsage:      true,
+	ValidArgsFunction: completeHostPortArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// In server mode the only (optional) argument is the port
+		if viper.GetBool("tcp.speed.server") {
+			if len(args) > 1 {
+				return exitcode.New(exitcode.Usage, errors.New("invalid number of arguments"))
+			}
+			port := defaultSpeedPort
+			if len(args) == 1 {
+				p, err := parsePort(args[0])
+				if err != nil {
+					return err
+				}
+				port = p
+			}
+			return tcpSpeedServerAction(os.Stdout, port)
+		}
+
+		// Parse the host and the port
+		host, port, err := parseHostPortArgs(args, defaultSpeedPort)
+		if err != nil {
+			return err
+		}
+
+		return tcpSpeedAction(os.Stdout, host, port)
+	},
+}
+
+// speedFmtString is the format string used for every line of speed output
+const speedFmtString = "%-15s %-13s %-15s %s\n"
+
+// printSpeedHeader prints the header of the speed output table
+func printSpeedHeader(out io.Writer) {
+	fmt.Fprintf(out, speedFmtString, "Interval", "Transfer", "Bitrate", "Retr")
+}
+
+// printSpeedInterval prints the statistics of a single interval
+func printSpeedInterval(out io.Writer, s tcp.SpeedInterval, suffix string) {
+	interval := fmt.Sprintf("%.2f-%.2f s", s.Start.Seconds(), s.End.Seconds())
+	bitrate := fmt.Sprintf("%.2f Mbps", s.Mbps())
+	retransmits := "n/a"
+	if s.Retransmits >= 0 {
+		retransmits = strconv.Itoa(s.Retransmits)
+	}
+	fmt.Fprintf(out, speedFmtString, interval, utils.FormatBytes(s.Bytes), bitrate, retransmits+suffix)
+}
+
+// tcpSpeedAction runs a throughput test against a host in server mode
+func tcpSpeedAction(out io.Writer, host string, port int) error {
+	// Define the test duration and the reporting interval
+	duration := viper.GetDuration("tcp.speed.duration") * time.Second
+	interval := viper.GetDuration("tcp.speed.interval") * time.Second
+	if duration <= 0 || interval <= 0 {
+		return exitcode.New(exitcode.Usage, errors.New("the duration and the interval must be greater than zero"))
+	}
+
+	// Resolve the IP address of the destination
+	ip, err := ip.ResolveIP(host)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server
+	timeout := viper.GetDuration("tcp.speed.timeout") * time.Millisecond
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(out, "Sending data to %s (%s) on port %d for %s.\n", host, ip, port, duration)
+	printSpeedHeader(out)
+
+	// Send data and print the statistics of every interval
+	total, err := tcp.SpeedSend(conn, duration, interval, func(s tcp.SpeedInterval) {
+		printSpeedInterval(out, s, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	// Print the statistics of the whole test
+	fmt.Fprintf(out, "--- %s speed statistics ---\n", host)
+	printSpeedInterval(out, total, "  sender")
+
+	return nil
+}
+
+// tcpSpeedServerAction listens for throughput tests on the port and
+// reports the received data, until the user presses Ctrl-C
+func tcpSpeedServerAction(out io.Writer, port int) error {
+	interval := viper.GetDuration("tcp.speed.interval") * time.Second
+	if interval <= 0 {
+		return exitcode.New(exitcode.Usage, errors.New("the interval must be greater than zero"))
+	}
+
+	// Start listening on the port
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	fmt.Fprintf(out, "Server listening on port %d.\n", port)
+
+	// Serve one test at a time
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "\nAccepted connection from %s.\n", conn.RemoteAddr())
+		printSpeedHeader(out)
+
+		// Receive data and print the statistics of every interval
+		total, err := tcp.SpeedReceive(conn, interval, func(s tcp.SpeedInterval) {
+			printSpeedInterval(out, s, "")
+		})
+		conn.Close()
+		if err != nil {
+			fmt.Fprintf(out, "Error receiving data: %s\n", err)
+			continue
+		}
+
+		// Print the statistics of the whole test
+		fmt.Fprintf(out, "--- %s speed statistics ---\n", conn.RemoteAddr())
+		printSpeedInterval(out, total, "  receiver")
+	}
+}
+
+func init() {
+	tcpCmd.AddCommand(speedCmd)
+
+	// Enable the --server flag for the speed command
+	speedCmd.Flags().BoolP("server", "s", false, "run in server mode and wait for tests")
+	viper.BindPFlag("tcp.speed.server", speedCmd.Flags().Lookup("server"))
+
+	// Enable the --duration flag for the speed command
+	speedCmd.Flags().IntP("duration", "d", 10, "duration of the test, in seconds")
+	viper.BindPFlag("tcp.speed.duration", speedCmd.Flags().Lookup("duration"))
+
+	// Enable the --interval flag for the speed command
+	speedCmd.Flags().IntP("interval", "i", 1, "time between reports, in seconds")
+	viper.BindPFlag("tcp.speed.interval", speedCmd.Flags().Lookup("interval"))
+
+	// Enable the --timeout flag for the speed command
+	speedCmd.Flags().IntP("timeout", "t", 2000, "time to wait for the connection, in milliseconds")
+	viper.BindPFlag("tcp.speed.timeout", speedCmd.Flags().Lookup("timeout"))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/bitcanon/iptool/privsep"
+)
+
+// Build information, set at build time using the linker, e.g.
+// go build -ldflags "-X github.com/bitcanon/iptool/cmd.commit=$(git rev-parse HEAD)"
+var (
+	commit    = ""
+	buildDate = ""
+)
+
+// versionJSON is set by the --json flag to print the version information in JSON format
+var versionJSON bool
+
+// buildInfo holds the version and build information printed by --version --json
+type buildInfo struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit"`
+	BuildDate string          `json:"build_date"`
+	GoVersion string          `json:"go_version"`
+	Platform  string          `json:"platform"`
+	Features  map[string]bool `json:"features"`
+}
+
+// getBuildInfo returns the build information of the running binary. Values not
+// set at build time are taken from the version control information embedded
+// by the Go toolchain, if available.
+func getBuildInfo() buildInfo {
+	info := buildInfo{
+		Name:      rootCmd.Name(),
+		Version:   rootCmd.Version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Features:  getFeatures(),
+	}
+
+	// Fall back to the version control information embedded by the toolchain
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	return info
+}
+
+// getFeatures returns the optional features and whether they are available in this build
+func getFeatures() map[string]bool {
+	_, helperErr := privsep.FindHelper()
+	return map[string]bool{
+		"ipv6-nd":           true,
+		"lldp-cdp":          runtime.GOOS == "linux",
+		"pcap":              true,
+		"privileged-helper": helperErr == nil,
+		"raw-sockets":       runtime.GOOS != "windows",
+		"tcp-retransmits":   runtime.GOOS == "linux",
+	}
+}
+
+// versionInfo returns the version information printed by the --version flag
+func versionInfo() string {
+	info := getBuildInfo()
+	if !versionJSON {
+		return fmt.Sprintf("%s %s", info.Name, info.Version)
+	}
+
+	// Map keys are sorted by the encoder, so the output is deterministic
+	output, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(output) + "\n"
+}
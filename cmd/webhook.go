@@ -0,0 +1,108 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/exitcode"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/notify"
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// webhookBackoff is the time to wait before the first retry of a failed
+// webhook request, doubled for every next retry
+const webhookBackoff = time.Second
+
+// addWebhookFlags is a function that adds the --webhook-* flags to a
+// monitoring command and binds them to the configuration of the command,
+// e.g. tcp.ping.webhook-url
+func addWebhookFlags(cmd *cobra.Command, command string) {
+	cmd.Flags().String("webhook-url", "", "post alerts to this webhook URL")
+	viper.BindPFlag(command+".webhook-url", cmd.Flags().Lookup("webhook-url"))
+	cmd.Flags().String("webhook-format", notify.FormatGeneric, "payload format of the webhook: "+strings.Join(notify.Formats, ", "))
+	viper.BindPFlag(command+".webhook-format", cmd.Flags().Lookup("webhook-format"))
+	cmd.RegisterFlagCompletionFunc("webhook-format", completeValues(notify.Formats...))
+	cmd.Flags().String("webhook-template", "", "file with a Go template of the JSON payload (overrides --webhook-format)")
+	viper.BindPFlag(command+".webhook-template", cmd.Flags().Lookup("webhook-template"))
+	cmd.Flags().Int("webhook-retries", 3, "number of times a failed webhook request is retried")
+	viper.BindPFlag(command+".webhook-retries", cmd.Flags().Lookup("webhook-retries"))
+	cmd.Flags().String("webhook-rate", "30/m", "maximum number of webhook requests, e.g. 10/m (0 for unlimited)")
+	viper.BindPFlag(command+".webhook-rate", cmd.Flags().Lookup("webhook-rate"))
+}
+
+// getWebhookDispatcher is a function that returns a dispatcher posting
+// alerts to the webhook selected with the --webhook-* flags of a command,
+// or nil if no webhook URL is set
+func getWebhookDispatcher(command string) (*notify.Dispatcher, error) {
+	hook := viper.GetString(command + ".webhook-url")
+	if hook == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(hook)
+	if err != nil || !notify.IsURL(hook) || u.Host == "" {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid --webhook-url: %s (must be an http:// or https:// URL)", hook))
+	}
+
+	// The host of the webhook is resolved when the events are posted, which
+	// is not possible with --no-dns
+	if _, err := netip.ParseAddr(u.Hostname()); err != nil && ip.LookupsDisabled() {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid --webhook-url: %s (the host must be an address with --no-dns)", hook))
+	}
+
+	// Check the payload format, a template overrides the format
+	webhook := &notify.Webhook{URL: hook, Format: strings.ToLower(viper.GetString(command + ".webhook-format")), Backoff: webhookBackoff}
+	if !slices.Contains(notify.Formats, webhook.Format) {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid --webhook-format: %s (must be one of %s)", webhook.Format, strings.Join(notify.Formats, ", ")))
+	}
+	if file := viper.GetString(command + ".webhook-template"); file != "" {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if webhook.Template, err = notify.ParseTemplate(string(text)); err != nil {
+			return nil, exitcode.New(exitcode.Usage, err)
+		}
+	}
+
+	// Check the retries and the rate limit of the requests
+	webhook.Retries = viper.GetInt(command + ".webhook-retries")
+	if webhook.Retries < 0 {
+		return nil, exitcode.New(exitcode.Usage, fmt.Errorf("invalid --webhook-retries: %d (must not be negative)", webhook.Retries))
+	}
+	limiter, err := ratelimit.ParseRate(viper.GetString(command + ".webhook-rate"))
+	if err != nil {
+		return nil, exitcode.New(exitcode.Usage, err)
+	}
+	webhook.Limiter = limiter
+
+	return notify.NewDispatcher(webhook, os.Stderr), nil
+}
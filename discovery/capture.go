@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package discovery
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/bitcanon/iptool/privsep"
+)
+
+var ErrPermission = errors.New("permission denied: capturing frames requires raw socket privileges (run as root or install iptool-helper)")
+
+// Capture is a function that listens for LLDP and CDP frames on the
+// interface iface for the time wait, or until count neighbors have been
+// found (if count is not 0), and returns the neighbors sorted by protocol,
+// name and port. If the process lacks the privileges to open a raw socket,
+// the frames are captured by the privileged helper process (iptool-helper)
+// if it is installed.
+func Capture(iface string, wait time.Duration, count int) ([]Neighbor, error) {
+	// Make sure that the interface exists
+	if _, err := net.InterfaceByName(iface); err != nil {
+		return nil, err
+	}
+
+	frames, err := captureFrames(iface, wait, count)
+	if errors.Is(err, ErrPermission) {
+		frames, err = captureFramesHelper(iface, wait, count)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Neighbors(frames), nil
+}
+
+// captureFrames is a function that opens a raw socket on the interface
+// iface and returns the discovery frames received on it
+func captureFrames(iface string, wait time.Duration, count int) ([][]byte, error) {
+	sock, err := Listen(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer sock.Close()
+
+	return sock.ReadFrames(wait, count)
+}
+
+// captureFramesHelper is a function that asks the privileged helper process
+// to capture the discovery frames on the interface iface
+func captureFramesHelper(iface string, wait time.Duration, count int) ([][]byte, error) {
+	resp, err := privsep.Call(privsep.Request{
+		Op:        privsep.OpCaptureDiscovery,
+		Interface: iface,
+		TimeoutMs: int(wait.Milliseconds()),
+		Count:     count,
+	})
+	if errors.Is(err, privsep.ErrHelperNotFound) {
+		return nil, ErrPermission
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Frames, nil
+}
+
+// Neighbors is a function that decodes the discovery frames and returns the
+// neighbors sorted by protocol, name and port. A neighbor announcing itself
+// several times is only returned once, with the values of its last frame.
+func Neighbors(frames [][]byte) []Neighbor {
+	index := make(map[string]int)
+	var neighbors []Neighbor
+	for _, frame := range frames {
+		n, ok := Decode(frame)
+		if !ok {
+			continue
+		}
+		if i, ok := index[n.key()]; ok {
+			neighbors[i] = n
+			continue
+		}
+		index[n.key()] = len(neighbors)
+		neighbors = append(neighbors, n)
+	}
+
+	sort.SliceStable(neighbors, func(i, j int) bool {
+		a, b := neighbors[i], neighbors[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol > b.Protocol
+		}
+		if a.Name() != b.Name() {
+			return a.Name() < b.Name()
+		}
+		return a.Port < b.Port
+	})
+	return neighbors
+}
@@ -0,0 +1,309 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package discovery decodes the link layer discovery frames sent by switches
+// and routers, LLDP (IEEE 802.1AB) and CDP (Cisco Discovery Protocol), and
+// captures them on a network interface.
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Protocols of the discovery frames
+const (
+	ProtocolLLDP = "lldp"
+	ProtocolCDP  = "cdp"
+)
+
+// etherTypeLLDP and etherTypeVLAN are the EtherTypes of LLDP frames and of
+// 802.1Q tagged frames
+const (
+	etherTypeLLDP = 0x88cc
+	etherTypeVLAN = 0x8100
+)
+
+// Multicast destination addresses of the discovery frames
+var (
+	LLDPMulticast = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+	CDPMulticast  = net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}
+)
+
+// cdpSNAP is the LLC/SNAP header of CDP frames (DSAP, SSAP, control, the
+// Cisco OUI and the CDP protocol ID)
+var cdpSNAP = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00}
+
+// Neighbor is a switch or router announcing itself on the link, the TTL is
+// the number of seconds the announcement is valid
+type Neighbor struct {
+	Protocol          string `json:"protocol"`
+	Source            string `json:"source"`
+	Chassis           string `json:"chassis,omitempty"`
+	SystemName        string `json:"system_name,omitempty"`
+	Port              string `json:"port,omitempty"`
+	PortDescription   string `json:"port_description,omitempty"`
+	VLAN              int    `json:"vlan,omitempty"`
+	ManagementAddress string `json:"management_address,omitempty"`
+	Platform          string `json:"platform,omitempty"`
+	TTL               int    `json:"ttl"`
+}
+
+// Name is a function that returns the name of the neighbor: the system name
+// if it was announced, otherwise the chassis ID
+func (n Neighbor) Name() string {
+	if n.SystemName != "" {
+		return n.SystemName
+	}
+	return n.Chassis
+}
+
+// key is a function that returns the identity of the neighbor, a neighbor
+// announces itself again every 30 to 60 seconds
+func (n Neighbor) key() string {
+	return n.Protocol + "|" + n.Source + "|" + n.Chassis + "|" + n.Port
+}
+
+// IsDiscoveryFrame is a function that reports whether an Ethernet frame is
+// addressed to the LLDP or CDP multicast address
+func IsDiscoveryFrame(frame []byte) bool {
+	return len(frame) >= 14 && (bytes.Equal(frame[:6], LLDPMulticast) || bytes.Equal(frame[:6], CDPMulticast))
+}
+
+// Decode is a function that decodes an Ethernet frame carrying LLDP or CDP.
+// It returns false if the frame is not a (valid) discovery frame.
+func Decode(frame []byte) (Neighbor, bool) {
+	if !IsDiscoveryFrame(frame) {
+		return Neighbor{}, false
+	}
+	source := net.HardwareAddr(frame[6:12]).String()
+
+	// Skip the 802.1Q tag, if any
+	payload := frame[12:]
+	if binary.BigEndian.Uint16(payload) == etherTypeVLAN {
+		if len(payload) < 6 {
+			return Neighbor{}, false
+		}
+		payload = payload[4:]
+	}
+	typeOrLength := binary.BigEndian.Uint16(payload)
+	payload = payload[2:]
+
+	var n Neighbor
+	var err error
+	switch {
+	case typeOrLength == etherTypeLLDP:
+		n, err = decodeLLDP(payload)
+	case typeOrLength <= 1500 && bytes.HasPrefix(payload, cdpSNAP):
+		// 802.3 frames carry the length instead of the EtherType, the
+		// padding after the length is not part of the frame
+		if int(typeOrLength) < len(cdpSNAP) || int(typeOrLength) > len(payload) {
+			return Neighbor{}, false
+		}
+		payload = payload[:typeOrLength]
+		n, err = decodeCDP(payload[len(cdpSNAP):])
+	default:
+		return Neighbor{}, false
+	}
+	if err != nil {
+		return Neighbor{}, false
+	}
+	n.Source = source
+	return n, true
+}
+
+// decodeLLDP is a function that decodes the TLVs of an LLDP data unit
+func decodeLLDP(data []byte) (Neighbor, error) {
+	n := Neighbor{Protocol: ProtocolLLDP}
+tlvs:
+	for len(data) >= 2 {
+		// Every TLV has a 7 bit type and a 9 bit length
+		header := binary.BigEndian.Uint16(data)
+		typ, length := int(header>>9), int(header&0x1ff)
+		if len(data) < 2+length {
+			return n, fmt.Errorf("invalid LLDP TLV %d: length %d", typ, length)
+		}
+		value := data[2 : 2+length]
+		data = data[2+length:]
+
+		switch typ {
+		case 0: // End of LLDPDU
+			break tlvs
+		case 1: // Chassis ID
+			if length < 2 {
+				return n, fmt.Errorf("invalid LLDP chassis ID")
+			}
+			n.Chassis = lldpID(value[0], value[1:], 4)
+		case 2: // Port ID
+			if length < 2 {
+				return n, fmt.Errorf("invalid LLDP port ID")
+			}
+			n.Port = lldpID(value[0], value[1:], 3)
+		case 3: // Time to live
+			if length >= 2 {
+				n.TTL = int(binary.BigEndian.Uint16(value))
+			}
+		case 4: // Port description
+			n.PortDescription = printable(value)
+		case 5: // System name
+			n.SystemName = printable(value)
+		case 6: // System description
+			n.Platform = printable(value)
+		case 8: // Management address, the first one is kept
+			if n.ManagementAddress == "" && length >= 2 && int(value[0]) >= 1 && int(value[0]) < length {
+				n.ManagementAddress = address(value[1], value[2:1+int(value[0])])
+			}
+		case 127: // Organizationally specific, the 802.1 port VLAN ID
+			if length >= 6 && bytes.Equal(value[:4], []byte{0x00, 0x80, 0xc2, 0x01}) {
+				n.VLAN = int(binary.BigEndian.Uint16(value[4:]))
+			}
+		}
+	}
+	if n.Chassis == "" {
+		return n, fmt.Errorf("invalid LLDP frame: no chassis ID")
+	}
+	return n, nil
+}
+
+// lldpID is a function that formats a chassis or port ID: MAC addresses
+// (subtype macSubtype) and network addresses (subtype 5 for chassis IDs and 4
+// for port IDs) are formatted as such, the other subtypes are names
+func lldpID(subtype byte, value []byte, macSubtype byte) string {
+	switch {
+	case subtype == macSubtype && len(value) == 6:
+		return net.HardwareAddr(value).String()
+	case subtype == macSubtype+1 && len(value) > 1:
+		return address(value[0], value[1:])
+	}
+	return printable(value)
+}
+
+// address is a function that formats an address with an IANA address family
+// number (1 is IPv4, 2 is IPv6 and 6 is a MAC address)
+func address(family byte, value []byte) string {
+	switch {
+	case family == 1 && len(value) == 4, family == 2 && len(value) == 16:
+		addr, _ := netip.AddrFromSlice(value)
+		return addr.String()
+	case family == 6 && len(value) == 6:
+		return net.HardwareAddr(value).String()
+	}
+	return fmt.Sprintf("%x", value)
+}
+
+// decodeCDP is a function that decodes a CDP packet: the version, the time
+// to live and the checksum, followed by TLVs with 16 bit types and lengths
+// (the length includes the 4 byte header)
+func decodeCDP(data []byte) (Neighbor, error) {
+	n := Neighbor{Protocol: ProtocolCDP}
+	if len(data) < 4 {
+		return n, fmt.Errorf("invalid CDP packet: %d bytes", len(data))
+	}
+	n.TTL = int(data[1])
+	data = data[4:]
+
+	var addresses, management string
+	for len(data) >= 4 {
+		typ, length := binary.BigEndian.Uint16(data), int(binary.BigEndian.Uint16(data[2:]))
+		if length < 4 || len(data) < length {
+			return n, fmt.Errorf("invalid CDP TLV %d: length %d", typ, length)
+		}
+		value := data[4:length]
+		data = data[length:]
+
+		switch typ {
+		case 0x0001: // Device ID
+			n.Chassis = printable(value)
+		case 0x0002: // Addresses
+			addresses = cdpAddress(value)
+		case 0x0003: // Port ID
+			n.Port = printable(value)
+		case 0x0006: // Platform
+			n.Platform = printable(value)
+		case 0x000a: // Native VLAN
+			if len(value) >= 2 {
+				n.VLAN = int(binary.BigEndian.Uint16(value))
+			}
+		case 0x0016: // Management addresses
+			management = cdpAddress(value)
+		}
+	}
+	if n.Chassis == "" {
+		return n, fmt.Errorf("invalid CDP packet: no device ID")
+	}
+
+	// CDP has no system name, the device ID is the name of the device
+	n.SystemName = n.Chassis
+	n.ManagementAddress = management
+	if n.ManagementAddress == "" {
+		n.ManagementAddress = addresses
+	}
+	return n, nil
+}
+
+// cdpAddress is a function that returns the first IP address of a CDP
+// address TLV: a 32 bit count followed by the addresses, each with a
+// protocol type, a protocol (0xcc is IPv4, an 802.2 header is IPv6) and an
+// address, prefixed by their lengths
+func cdpAddress(value []byte) string {
+	if len(value) < 4 {
+		return ""
+	}
+	count := binary.BigEndian.Uint32(value)
+	value = value[4:]
+	for i := uint32(0); i < count && len(value) >= 2; i++ {
+		protocolLength := int(value[1])
+		if len(value) < 2+protocolLength+2 {
+			return ""
+		}
+		protocol := value[2 : 2+protocolLength]
+		value = value[2+protocolLength:]
+		addressLength := int(binary.BigEndian.Uint16(value))
+		if len(value) < 2+addressLength {
+			return ""
+		}
+		raw := value[2 : 2+addressLength]
+		value = value[2+addressLength:]
+
+		if addr, ok := netip.AddrFromSlice(raw); ok && (len(protocol) == 1 && protocol[0] == 0xcc || len(protocol) == 8) {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+// printable is a function that returns the printable characters of a string
+// announced by a neighbor, without the trailing NUL bytes and white space
+func printable(value []byte) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' || r == 0x7f {
+			return -1
+		}
+		if r == '\n' {
+			return ' '
+		}
+		return r
+	}, string(value)))
+}
@@ -0,0 +1,165 @@
+package discovery_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/bitcanon/iptool/discovery"
+)
+
+// source is the MAC address of the switch sending the test frames
+var source = []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+// lldpTLV is a function that returns an LLDP TLV with a 7 bit type and a 9 bit length
+func lldpTLV(typ int, value ...byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(typ<<9|len(value))), value...)
+}
+
+// lldpFrame is a function that returns an Ethernet frame carrying the TLVs
+func lldpFrame(tlvs ...[]byte) []byte {
+	frame := append(append([]byte{}, discovery.LLDPMulticast...), source...)
+	frame = append(frame, 0x88, 0xcc)
+	for _, tlv := range tlvs {
+		frame = append(frame, tlv...)
+	}
+	return append(frame, 0x00, 0x00)
+}
+
+// cdpTLV is a function that returns a CDP TLV with a 16 bit type and length
+func cdpTLV(typ uint16, value ...byte) []byte {
+	tlv := binary.BigEndian.AppendUint16(nil, typ)
+	tlv = binary.BigEndian.AppendUint16(tlv, uint16(4+len(value)))
+	return append(tlv, value...)
+}
+
+// cdpFrame is a function that returns an 802.3 frame with an LLC/SNAP header
+// carrying a CDP packet with the TLVs, padded to the minimum frame size
+func cdpFrame(tlvs ...[]byte) []byte {
+	payload := []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00, 0x02, 180, 0x00, 0x00}
+	for _, tlv := range tlvs {
+		payload = append(payload, tlv...)
+	}
+	frame := append(append([]byte{}, discovery.CDPMulticast...), source...)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	frame = append(frame, payload...)
+	for len(frame) < 60 {
+		frame = append(frame, 0)
+	}
+	return frame
+}
+
+// cdpLength is a function that overwrites the 802.3 length field of a frame
+func cdpLength(frame []byte, length uint16) []byte {
+	binary.BigEndian.PutUint16(frame[12:], length)
+	return frame
+}
+
+// TestDecode tests the decoding of LLDP and CDP frames
+func TestDecode(t *testing.T) {
+	cdpAddress := []byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0xcc, 0x00, 0x04, 192, 0, 2, 1}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		frame    []byte
+		expected discovery.Neighbor
+		ok       bool
+	}{
+		{
+			name: "LLDP",
+			frame: lldpFrame(
+				lldpTLV(1, append([]byte{4}, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee)...),
+				lldpTLV(2, append([]byte{5}, "Gi1/0/12"...)...),
+				lldpTLV(3, 0x00, 0x78),
+				lldpTLV(4, []byte("server-a\x00")...),
+				lldpTLV(5, []byte("sw-core-01")...),
+				lldpTLV(6, []byte("Cisco IOS\nVersion 15.2")...),
+				lldpTLV(8, 0x05, 0x01, 192, 0, 2, 10, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00),
+				lldpTLV(127, 0x00, 0x80, 0xc2, 0x01, 0x00, 0x64),
+			),
+			expected: discovery.Neighbor{
+				Protocol: "lldp", Source: "00:11:22:33:44:55", Chassis: "00:aa:bb:cc:dd:ee", SystemName: "sw-core-01",
+				Port: "Gi1/0/12", PortDescription: "server-a", VLAN: 100, ManagementAddress: "192.0.2.10",
+				Platform: "Cisco IOS Version 15.2", TTL: 120,
+			},
+			ok: true,
+		},
+		{
+			name: "LLDPTagged",
+			frame: append(append(append([]byte{}, discovery.LLDPMulticast...), source...),
+				append([]byte{0x81, 0x00, 0x00, 0x0a, 0x88, 0xcc},
+					append(lldpTLV(1, append([]byte{7}, "sw1"...)...), lldpTLV(2, append([]byte{3}, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01)...)...)...)...),
+			expected: discovery.Neighbor{Protocol: "lldp", Source: "00:11:22:33:44:55", Chassis: "sw1", Port: "00:aa:bb:cc:dd:01"},
+			ok:       true,
+		},
+		{
+			name: "CDP",
+			frame: cdpFrame(
+				cdpTLV(0x0001, []byte("sw-access-02.example.com")...),
+				cdpTLV(0x0002, cdpAddress...),
+				cdpTLV(0x0003, []byte("GigabitEthernet0/5")...),
+				cdpTLV(0x0006, []byte("cisco WS-C2960X-48TS-L")...),
+				cdpTLV(0x000a, 0x00, 0x14),
+			),
+			expected: discovery.Neighbor{
+				Protocol: "cdp", Source: "00:11:22:33:44:55", Chassis: "sw-access-02.example.com", SystemName: "sw-access-02.example.com",
+				Port: "GigabitEthernet0/5", VLAN: 20, ManagementAddress: "192.0.2.1", Platform: "cisco WS-C2960X-48TS-L", TTL: 180,
+			},
+			ok: true,
+		},
+		{name: "LLDPWithoutChassis", frame: lldpFrame(lldpTLV(3, 0x00, 0x78)), ok: false},
+		{name: "LLDPTruncated", frame: lldpFrame([]byte{0x02, 0x20, 0x05}), ok: false},
+		{name: "CDPTruncated", frame: cdpFrame(cdpTLV(0x0001, []byte("sw")...)[:3]), ok: false},
+		{name: "CDPShortLength", frame: cdpLength(cdpFrame(cdpTLV(0x0001, []byte("sw")...)), 4), ok: false},
+		{name: "CDPLengthBeyondFrame", frame: cdpLength(cdpFrame(cdpTLV(0x0001, []byte("sw")...)), 1500), ok: false},
+		{name: "OtherDestination", frame: append(make([]byte, 12), 0x88, 0xcc, 0x02, 0x04, 0x04, 0x00, 0x00, 0x00), ok: false},
+		{name: "Short", frame: []byte{0x01, 0x80, 0xc2}, ok: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			neighbor, ok := discovery.Decode(tc.frame)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %v, got %v (%+v)", tc.ok, ok, neighbor)
+			}
+			if ok && neighbor != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, neighbor)
+			}
+		})
+	}
+}
+
+// TestNeighbors tests that repeated announcements are merged and sorted
+func TestNeighbors(t *testing.T) {
+	frames := [][]byte{
+		lldpFrame(lldpTLV(1, append([]byte{7}, "sw2"...)...), lldpTLV(2, append([]byte{7}, "1"...)...), lldpTLV(3, 0x00, 0x78)),
+		cdpFrame(cdpTLV(0x0001, []byte("sw1")...), cdpTLV(0x0003, []byte("Gi0/1")...)),
+		lldpFrame(lldpTLV(1, append([]byte{7}, "sw1"...)...), lldpTLV(2, append([]byte{7}, "1"...)...)),
+		lldpFrame(lldpTLV(1, append([]byte{7}, "sw2"...)...), lldpTLV(2, append([]byte{7}, "1"...)...), lldpTLV(3, 0x00, 0x3c)),
+		{0x00},
+	}
+	neighbors := discovery.Neighbors(frames)
+
+	// Setup test cases
+	testCases := []struct {
+		protocol string
+		name     string
+		ttl      int
+	}{
+		{protocol: "lldp", name: "sw1"},
+		{protocol: "lldp", name: "sw2", ttl: 60},
+		{protocol: "cdp", name: "sw1", ttl: 180},
+	}
+
+	// Run test cases
+	if len(neighbors) != len(testCases) {
+		t.Fatalf("expected %d neighbors, got %d (%+v)", len(testCases), len(neighbors), neighbors)
+	}
+	for i, tc := range testCases {
+		n := neighbors[i]
+		if n.Protocol != tc.protocol || n.Name() != tc.name || n.TTL != tc.ttl {
+			t.Errorf("neighbor %d: expected %s %s %d, got %s %s %d", i, tc.protocol, tc.name, tc.ttl, n.Protocol, n.Name(), n.TTL)
+		}
+	}
+}
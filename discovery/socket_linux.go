@@ -0,0 +1,144 @@
+//go:build linux
+
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package discovery
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// filter is a classic BPF program that only accepts the frames addressed to
+// the LLDP (01:80:c2:00:00:0e) or CDP (01:00:0c:cc:cc:cc) multicast address,
+// so that the rest of the traffic on the interface is not copied to the socket
+var filter = []unix.SockFilter{
+	{Code: 0x28, K: 0},                        // ldh [0]
+	{Code: 0x15, Jt: 1, Jf: 0, K: 0x0180},     // jeq #0x0180, lldp
+	{Code: 0x15, Jt: 2, Jf: 5, K: 0x0100},     // jeq #0x0100, cdp, reject
+	{Code: 0x20, K: 2},                        // lldp: ld [2]
+	{Code: 0x15, Jt: 2, Jf: 3, K: 0xc200000e}, // jeq #0xc200000e, accept, reject
+	{Code: 0x20, K: 2},                        // cdp: ld [2]
+	{Code: 0x15, Jt: 0, Jf: 1, K: 0x0ccccccc}, // jeq #0x0ccccccc, accept, reject
+	{Code: 0x06, K: 0xffff},                   // accept: ret #0xffff
+	{Code: 0x06, K: 0},                        // reject: ret #0
+}
+
+// Socket is a raw packet socket receiving the discovery frames of an interface
+type Socket struct {
+	fd int
+}
+
+// htons is a function that converts a 16 bit number to network byte order
+func htons(n uint16) uint16 {
+	return n<<8 | n>>8
+}
+
+// Listen is a function that opens a raw packet socket on the interface iface
+// and joins the LLDP and CDP multicast groups. Opening the socket requires
+// raw socket privileges (root or CAP_NET_RAW).
+func Listen(iface string) (*Socket, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(htons(unix.ETH_P_ALL)))
+	if err != nil {
+		if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+			return nil, ErrPermission
+		}
+		return nil, err
+	}
+	sock := &Socket{fd: fd}
+
+	// Attach the filter before binding, so that no other frames are queued
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.SetsockoptSockFprog(fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog); err != nil {
+		sock.Close()
+		return nil, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: ifi.Index}); err != nil {
+		sock.Close()
+		return nil, err
+	}
+
+	// Join the multicast groups, the network card drops the frames otherwise
+	for _, group := range []net.HardwareAddr{LLDPMulticast, CDPMulticast} {
+		mreq := unix.PacketMreq{Ifindex: int32(ifi.Index), Type: unix.PACKET_MR_MULTICAST, Alen: uint16(len(group))}
+		copy(mreq.Address[:], group)
+		if err := unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+			sock.Close()
+			return nil, err
+		}
+	}
+
+	return sock, nil
+}
+
+// ReadFrames is a function that returns the discovery frames received on the
+// socket during the time wait, or until frames from count different
+// neighbors have been received (if count is not 0)
+func (s *Socket) ReadFrames(wait time.Duration, count int) ([][]byte, error) {
+	deadline := time.Now().Add(wait)
+	seen := make(map[string]bool)
+	var frames [][]byte
+	buf := make([]byte, 65536)
+	for {
+		// Wait at most the remaining time for the next frame
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return frames, nil
+		}
+		timeout := unix.NsecToTimeval(max(remaining, time.Millisecond).Nanoseconds())
+		if err := unix.SetsockoptTimeval(s.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+			return nil, err
+		}
+
+		n, _, err := unix.Recvfrom(s.fd, buf, 0)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the frames that decode, and stop when enough neighbors are found
+		neighbor, ok := Decode(buf[:n])
+		if !ok {
+			continue
+		}
+		frames = append(frames, append([]byte(nil), buf[:n]...))
+		seen[neighbor.key()] = true
+		if count > 0 && len(seen) >= count {
+			return frames, nil
+		}
+	}
+}
+
+// Close is a function that closes the socket
+func (s *Socket) Close() error {
+	return unix.Close(s.fd)
+}
@@ -0,0 +1,51 @@
+//go:build !linux
+
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package discovery
+
+import (
+	"errors"
+	"time"
+)
+
+var errUnsupported = errors.New("capturing LLDP and CDP frames is not supported on this platform")
+
+// Socket is a raw packet socket receiving the discovery frames of an interface
+type Socket struct{}
+
+// Listen is a function that opens a raw packet socket on the interface
+// iface. It is not supported on this platform.
+func Listen(iface string) (*Socket, error) {
+	return nil, errUnsupported
+}
+
+// ReadFrames is a function that returns the discovery frames received on
+// the socket. It is not supported on this platform.
+func (s *Socket) ReadFrames(wait time.Duration, count int) ([][]byte, error) {
+	return nil, errUnsupported
+}
+
+// Close is a function that closes the socket
+func (s *Socket) Close() error {
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitcanon/iptool/ratelimit"
+	"github.com/bitcanon/iptool/stats"
+)
+
+// DefaultBenchNames are the names queried by a benchmark when no names are
+// given, popular domains that most resolvers have cached
+var DefaultBenchNames = []string{
+	"google.com", "youtube.com", "facebook.com", "wikipedia.org", "amazon.com",
+	"github.com", "microsoft.com", "apple.com", "cloudflare.com", "netflix.com",
+}
+
+// BenchResult is the result of a benchmark of a DNS server
+type BenchResult struct {
+	Server   string
+	Queries  int
+	Failures int
+	Stats    stats.Stats
+
+	// Errors counts the failures by error message
+	Errors map[string]int
+}
+
+// FailureRate is a function that returns the percentage of failed queries
+func (r *BenchResult) FailureRate() float64 {
+	if r.Queries == 0 {
+		return 0
+	}
+	return 100 * float64(r.Failures) / float64(r.Queries)
+}
+
+// ServerAddress is a function that returns the address and port of a DNS
+// server given as an address (port 53) or as address:port
+func ServerAddress(server string) (string, error) {
+	if addr, err := netip.ParseAddr(strings.Trim(server, "[]")); err == nil {
+		return net.JoinHostPort(addr.String(), "53"), nil
+	}
+	if addrPort, err := netip.ParseAddrPort(server); err == nil {
+		return addrPort.String(), nil
+	}
+	return "", fmt.Errorf("invalid DNS server: %s (expected an address or address:port)", server)
+}
+
+// NewResolver is a function that returns a resolver that sends its queries
+// to the DNS server at address (address:port) instead of the system resolver
+func NewResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Bench is a function that sends a number of A queries for the names (in
+// turn) to a DNS server and measures the response times. At most concurrency
+// queries are in flight and the queries are sent at the rate of the limiter
+// (nil for unlimited). A query fails if the server does not respond within
+// the timeout or responds with an error. A response that the name does not
+// exist (NXDOMAIN) is an answer and does not fail the query. When ctx is
+// done, no more queries are sent but the queries in flight are completed.
+func Bench(ctx context.Context, server string, names []string, queries int, timeout time.Duration, limiter *ratelimit.Limiter, concurrency int) (*BenchResult, error) {
+	s, err := ParseServer(server)
+	if err != nil {
+		return nil, err
+	}
+	return BenchServer(ctx, s, names, queries, timeout, limiter, concurrency)
+}
+
+// BenchServer is a function that benchmarks a DNS server like Bench, with
+// the transport of the server (see ParseServer)
+func BenchServer(ctx context.Context, server Server, names []string, queries int, timeout time.Duration, limiter *ratelimit.Limiter, concurrency int) (*BenchResult, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no names to query")
+	}
+
+	resolver := server.Resolver()
+	result := &BenchResult{Server: server.Name, Errors: make(map[string]int)}
+	var mutex sync.Mutex
+	ratelimit.Run(ctx, queries, concurrency, limiter, func(ctx context.Context, i int) {
+		// Query fully qualified names, so that the search domains are not tried
+		name := strings.TrimSuffix(names[i%len(names)], ".") + "."
+
+		queryCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		_, err := resolver.LookupNetIP(queryCtx, "ip4", name)
+		rtt := time.Since(start)
+		cancel()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		result.Queries++
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			result.Failures++
+			result.Errors[benchError(err)]++
+			return
+		}
+		result.Stats.Add(rtt)
+	})
+	return result, nil
+}
+
+// benchError is a function that returns a short description of the error
+// of a failed query, without the name and server that differ per query
+func benchError(err error) string {
+	var dnsErr *net.DNSError
+	message := err.Error()
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		message = dnsErr.Err
+	}
+
+	// Keep the last part of wrapped network errors (e.g. connection refused)
+	if i := strings.LastIndex(message, ": "); i >= 0 {
+		message = message[i+2:]
+	}
+	return message
+}
@@ -0,0 +1,155 @@
+package dns_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/dns"
+)
+
+// dnsResponse returns the response of the DNS server for the tests to a
+// query: A queries are answered with 192.0.2.1, queries for names starting
+// with nx with NXDOMAIN and queries for names starting with fail with
+// SERVFAIL. Queries for names starting with drop are not answered (nil).
+// Names in the blocklist zones listed.test and refused.test are answered
+// with 127.0.0.2 (and a TXT reason) and 127.255.255.254, names in the zone
+// clean.test with NXDOMAIN and names in the zone fail.test with SERVFAIL.
+func dnsResponse(query []byte) []byte {
+	n := len(query)
+
+	// Find the end of the question (name, type and class)
+	end := 12
+	var labels []string
+	for end < n && query[end] != 0 {
+		labels = append(labels, string(query[end+1:end+1+int(query[end])]))
+		end += int(query[end]) + 1
+	}
+	end += 5
+	name := strings.Join(labels, ".")
+
+	// Copy the header and the question, and set the response flags
+	resp := append([]byte{}, query[:end]...)
+	resp[2] |= 0x80
+	resp[3] = 0x80
+	binary.BigEndian.PutUint16(resp[10:], 0)
+	switch {
+	case strings.HasPrefix(name, "drop"):
+		return nil
+	case strings.HasSuffix(name, ".listed.test") && binary.BigEndian.Uint16(query[end-4:]) == 16:
+		// Answer TXT queries: name pointer, type TXT, class IN, TTL, length and text
+		reason := "Listed for the tests"
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 12, 0, 16, 0, 1, 0, 0, 0, 60, 0, byte(len(reason)+1), byte(len(reason)))
+		resp = append(resp, reason...)
+	case strings.HasSuffix(name, ".listed.test") && binary.BigEndian.Uint16(query[end-4:]) == 1:
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 2)
+	case strings.HasSuffix(name, ".refused.test") && binary.BigEndian.Uint16(query[end-4:]) == 1:
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 255, 255, 254)
+	case strings.HasPrefix(name, "nx"), strings.HasSuffix(name, ".clean.test"):
+		resp[3] |= 3
+	case strings.HasPrefix(name, "fail"), strings.HasSuffix(name, ".fail.test"):
+		resp[3] |= 2
+	case binary.BigEndian.Uint16(query[end-4:]) == 1:
+		// Answer A queries: name pointer, type A, class IN, TTL, length and address
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1)
+	}
+	return resp
+}
+
+// startDNSServer starts a DNS server for the tests (see dnsResponse)
+func startDNSServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := dnsResponse(buf[:n]); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestBench(t *testing.T) {
+	server := startDNSServer(t)
+
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		names       []string
+		concurrency int
+		failures    int
+		errors      []string
+	}{
+		{name: "Answers", names: []string{"example.com", "example.org"}},
+		{name: "NXDOMAIN", names: []string{"nx.example.com"}},
+		{name: "Failures", names: []string{"example.com", "fail.example.com", "drop.example.com", "example.org"}, failures: 4, errors: []string{"timeout", "server misbehaving"}},
+		{name: "Concurrent", names: []string{"example.com", "drop.example.com"}, concurrency: 4, failures: 4, errors: []string{"timeout"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := dns.Bench(context.Background(), server, tc.names, 8, 200*time.Millisecond, nil, tc.concurrency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Queries != 8 || result.Failures != tc.failures {
+				t.Errorf("expected 8 queries and %d failures, got %d and %d", tc.failures, result.Queries, result.Failures)
+			}
+			if result.Stats.Count() != 8-tc.failures {
+				t.Errorf("expected %d samples, got %d", 8-tc.failures, result.Stats.Count())
+			}
+			for _, e := range tc.errors {
+				if result.Errors[e] == 0 {
+					t.Errorf("expected error %q, got %v", e, result.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		server    string
+		expected  string
+		expectErr bool
+	}{
+		{server: "1.1.1.1", expected: "1.1.1.1:53"},
+		{server: "1.1.1.1:5353", expected: "1.1.1.1:5353"},
+		{server: "2606:4700::1111", expected: "[2606:4700::1111]:53"},
+		{server: "[2606:4700::1111]", expected: "[2606:4700::1111]:53"},
+		{server: "[2606:4700::1111]:5353", expected: "[2606:4700::1111]:5353"},
+		{server: "dns.example.com", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.server, func(t *testing.T) {
+			result, err := dns.ServerAddress(tc.server)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
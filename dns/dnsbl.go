@@ -0,0 +1,167 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBlocklists are the DNS blocklists checked when no blocklists are
+// given, widely used blocklists that can be queried without registration
+var DefaultBlocklists = []string{
+	"zen.spamhaus.org", "bl.spamcop.net", "b.barracudacentral.org", "psbl.surriel.com",
+}
+
+// listedPrefix is the prefix of the return codes of a listed address (RFC 5782)
+var listedPrefix = netip.MustParsePrefix("127.0.0.0/8")
+
+// errorPrefix is the prefix of the return codes that Spamhaus uses to refuse
+// a query (e.g. 127.255.255.254 for queries through a public resolver),
+// which are errors and do not mean that the address is listed
+var errorPrefix = netip.MustParsePrefix("127.255.255.0/24")
+
+// returnCodes describes the return codes of the blocklists that encode the
+// reason of a listing in the return code
+var returnCodes = map[string]map[string]string{
+	"zen.spamhaus.org": {
+		"127.0.0.2":  "SBL",
+		"127.0.0.3":  "SBL CSS",
+		"127.0.0.4":  "XBL",
+		"127.0.0.5":  "XBL",
+		"127.0.0.6":  "XBL",
+		"127.0.0.7":  "XBL",
+		"127.0.0.9":  "SBL DROP",
+		"127.0.0.10": "PBL ISP",
+		"127.0.0.11": "PBL Spamhaus",
+	},
+}
+
+// errorCodes describes the return codes in errorPrefix
+var errorCodes = map[string]string{
+	"127.255.255.252": "typing error in the blocklist name",
+	"127.255.255.254": "query through a public or open resolver",
+	"127.255.255.255": "excessive number of queries",
+}
+
+// Listing is the result of checking an address against a DNS blocklist.
+// Codes are the return codes (A records) of a listed address and Reasons
+// the texts (TXT records) that the blocklist publishes for the listing.
+type Listing struct {
+	Blocklist string        `json:"blocklist"`
+	Listed    bool          `json:"listed"`
+	Codes     []string      `json:"codes,omitempty"`
+	Reasons   []string      `json:"reasons,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"-"`
+}
+
+// CodeDescription is a function that returns the description of a return
+// code of a blocklist, or an empty string if the code is not known
+func CodeDescription(blocklist, code string) string {
+	return returnCodes[strings.ToLower(blocklist)][code]
+}
+
+// CheckBlocklist is a function that checks if an address is listed in a DNS
+// blocklist by querying the reversed address in the blocklist zone (e.g.
+// 2.0.0.127.zen.spamhaus.org for 127.0.0.2). A listed address resolves to
+// return codes in 127.0.0.0/8, and the reasons are looked up in the TXT
+// records of the name. A name that does not exist means not listed.
+func CheckBlocklist(ctx context.Context, resolver *net.Resolver, addr netip.Addr, blocklist string) Listing {
+	start := time.Now()
+	listing := Listing{Blocklist: blocklist}
+
+	name := ReverseLabels(addr) + "." + strings.TrimSuffix(blocklist, ".")
+	addrs, err := resolver.LookupHost(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		listing.Duration = time.Since(start)
+		return listing
+	}
+	if err != nil {
+		listing.Error = err.Error()
+		listing.Duration = time.Since(start)
+		return listing
+	}
+
+	// Keep the return codes in 127.0.0.0/8, and report the codes that
+	// refuse the query as errors
+	for _, a := range addrs {
+		code, err := netip.ParseAddr(a)
+		if err != nil || !listedPrefix.Contains(code) {
+			continue
+		}
+		if errorPrefix.Contains(code) {
+			description := errorCodes[a]
+			if description == "" {
+				description = "query refused"
+			}
+			listing.Error = fmt.Sprintf("%s (%s)", description, a)
+			listing.Duration = time.Since(start)
+			return listing
+		}
+		listing.Codes = append(listing.Codes, a)
+	}
+	sort.Slice(listing.Codes, func(i, j int) bool {
+		return netip.MustParseAddr(listing.Codes[i]).Less(netip.MustParseAddr(listing.Codes[j]))
+	})
+	listing.Listed = len(listing.Codes) > 0
+
+	// The reasons are optional, so a failed TXT lookup is not an error
+	if listing.Listed {
+		if txts, err := resolver.LookupTXT(ctx, name); err == nil {
+			listing.Reasons = txts
+		}
+	}
+	listing.Duration = time.Since(start)
+	return listing
+}
+
+// CheckBlocklists is a function that checks an address against the DNS
+// blocklists in parallel and returns the listings in the order of the
+// blocklists. Each check is bounded by the timeout (0 for no timeout).
+func CheckBlocklists(ctx context.Context, resolver *net.Resolver, addr netip.Addr, blocklists []string, timeout time.Duration) []Listing {
+	listings := make([]Listing, len(blocklists))
+	var wg sync.WaitGroup
+	for i, blocklist := range blocklists {
+		wg.Add(1)
+		go func(i int, blocklist string) {
+			defer wg.Done()
+			ctx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			listings[i] = CheckBlocklist(ctx, resolver, addr, blocklist)
+		}(i, blocklist)
+	}
+	wg.Wait()
+	return listings
+}
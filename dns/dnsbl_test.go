@@ -0,0 +1,67 @@
+package dns_test
+
+import (
+	"context"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/dns"
+)
+
+func TestCheckBlocklists(t *testing.T) {
+	resolver := dns.NewResolver(startDNSServer(t))
+	blocklists := []string{"listed.test", "clean.test", "refused.test", "fail.test", "other.test"}
+
+	// Setup test cases
+	testCases := []dns.Listing{
+		{Blocklist: "listed.test", Listed: true, Codes: []string{"127.0.0.2"}, Reasons: []string{"Listed for the tests"}},
+		{Blocklist: "clean.test"},
+		{Blocklist: "refused.test", Error: "query through a public or open resolver (127.255.255.254)"},
+		{Blocklist: "fail.test"},
+		{Blocklist: "other.test"},
+	}
+
+	// Run test cases
+	listings := dns.CheckBlocklists(context.Background(), resolver, netip.MustParseAddr("192.0.2.1"), blocklists, time.Second)
+	if len(listings) != len(testCases) {
+		t.Fatalf("expected %d listings, got %d", len(testCases), len(listings))
+	}
+	for i, tc := range testCases {
+		t.Run(tc.Blocklist, func(t *testing.T) {
+			listing := listings[i]
+			listing.Duration = 0
+			if tc.Blocklist == "fail.test" {
+				// The error message of a failed query depends on the resolver
+				if listing.Listed || listing.Error == "" {
+					t.Errorf("expected an error, got %+v", listing)
+				}
+				return
+			}
+			if !reflect.DeepEqual(listing, tc) {
+				t.Errorf("expected %+v, got %+v", tc, listing)
+			}
+		})
+	}
+}
+
+func TestCodeDescription(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		blocklist string
+		code      string
+		expected  string
+	}{
+		{blocklist: "zen.spamhaus.org", code: "127.0.0.2", expected: "SBL"},
+		{blocklist: "ZEN.spamhaus.org", code: "127.0.0.11", expected: "PBL Spamhaus"},
+		{blocklist: "bl.spamcop.net", code: "127.0.0.2", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		if got := dns.CodeDescription(tc.blocklist, tc.code); got != tc.expected {
+			t.Errorf("%s %s: expected %q, got %q", tc.blocklist, tc.code, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ReverseZone represents a reverse DNS zone covering (a part of) a prefix.
+// Zones for IPv4 prefixes longer than /24 are classless zones (RFC 2317),
+// which must be delegated from the parent zone using CNAME records.
+type ReverseZone struct {
+	Name      string
+	Prefix    netip.Prefix
+	Classless bool
+	Parent    string
+}
+
+// ReverseName is a function that returns the reverse DNS name (in-addr.arpa
+// or ip6.arpa) of an IP address, e.g. 1.0.12.10.in-addr.arpa for 10.12.0.1
+func ReverseName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	return ReverseLabels(addr) + "." + arpaSuffix(addr)
+}
+
+// ReverseLabels is a function that returns the octets (IPv4) or nibbles
+// (IPv6) of an IP address in reverse order, as used in reverse DNS and DNS
+// blocklist queries, e.g. 1.0.12.10 for 10.12.0.1
+func ReverseLabels(addr netip.Addr) string {
+	labels := addrLabels(addr.Unmap())
+	reverse(labels)
+	return strings.Join(labels, ".")
+}
+
+// ReverseZones is a function that returns the reverse DNS zones for a
+// prefix. Reverse zones are delegated on octet (IPv4) or nibble (IPv6)
+// boundaries, so a prefix that is not aligned on such a boundary is covered
+// by several zones, e.g. 10.12.0.0/15 is covered by 12.10.in-addr.arpa and
+// 13.10.in-addr.arpa. IPv4 prefixes longer than /24 are covered by a single
+// classless zone using RFC 2317 style naming, e.g. 0/26.2.0.192.in-addr.arpa.
+func ReverseZones(prefix netip.Prefix) ([]ReverseZone, error) {
+	if !prefix.IsValid() {
+		return nil, fmt.Errorf("invalid prefix: %s", prefix)
+	}
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+
+	// The size of a label in bits (an octet for IPv4 and a nibble for IPv6)
+	labelBits := 4
+	if addr.Is4() {
+		labelBits = 8
+	}
+
+	// IPv4 prefixes longer than /24 need a classless (RFC 2317) zone
+	if addr.Is4() && prefix.Bits() > 24 {
+		parent, _ := addr.Prefix(24)
+		parentName := zoneName(parent, labelBits)
+		octets := addr.As4()
+		name := fmt.Sprintf("%d/%d.%s", octets[3], prefix.Bits(), parentName)
+		return []ReverseZone{{Name: name, Prefix: prefix, Classless: true, Parent: parentName}}, nil
+	}
+
+	// Round the prefix length up to the next label boundary
+	zoneBits := (prefix.Bits() + labelBits - 1) / labelBits * labelBits
+	extraBits := zoneBits - prefix.Bits()
+
+	// Enumerate the zones covering the prefix
+	zones := make([]ReverseZone, 0, 1<<extraBits)
+	zone, _ := addr.Prefix(zoneBits)
+	for i := 0; i < 1<<extraBits; i++ {
+		if i > 0 {
+			zone = nextPrefix(zone)
+		}
+		zones = append(zones, ReverseZone{Name: zoneName(zone, labelBits), Prefix: zone})
+	}
+
+	return zones, nil
+}
+
+// ClasslessCNAME is a function that returns the CNAME record that must be
+// added to the parent zone to delegate the reverse name of addr to the
+// classless (RFC 2317) zone, e.g.
+// 1.2.0.192.in-addr.arpa. IN CNAME 1.0/26.2.0.192.in-addr.arpa.
+func (z ReverseZone) ClasslessCNAME(addr netip.Addr) string {
+	octets := addr.As4()
+	return fmt.Sprintf("%s. IN CNAME %d.%s.", ReverseName(addr), octets[3], z.Name)
+}
+
+// PTRName is a function that returns the owner name of the PTR record of
+// addr in the zone. Classless zones use the names that the CNAME records in
+// the parent zone point to.
+func (z ReverseZone) PTRName(addr netip.Addr) string {
+	if z.Classless {
+		octets := addr.As4()
+		return fmt.Sprintf("%d.%s", octets[3], z.Name)
+	}
+	return ReverseName(addr)
+}
+
+// zoneName is a function that returns the reverse zone name of a prefix
+// that is aligned on a label boundary
+func zoneName(prefix netip.Prefix, labelBits int) string {
+	labels := addrLabels(prefix.Addr())[:prefix.Bits()/labelBits]
+	reverse(labels)
+	return strings.Join(append(labels, arpaSuffix(prefix.Addr())), ".")
+}
+
+// addrLabels is a function that returns the labels of an address in
+// network order: the octets of an IPv4 address or the nibbles of an IPv6 address
+func addrLabels(addr netip.Addr) []string {
+	var labels []string
+	if addr.Is4() {
+		for _, b := range addr.As4() {
+			labels = append(labels, strconv.Itoa(int(b)))
+		}
+		return labels
+	}
+	for _, b := range addr.As16() {
+		labels = append(labels, strconv.FormatUint(uint64(b>>4), 16), strconv.FormatUint(uint64(b&0xf), 16))
+	}
+	return labels
+}
+
+// arpaSuffix is a function that returns the reverse DNS domain of the address family
+func arpaSuffix(addr netip.Addr) string {
+	if addr.Is4() {
+		return "in-addr.arpa"
+	}
+	return "ip6.arpa"
+}
+
+// nextPrefix is a function that returns the prefix of the same length
+// directly following the prefix
+func nextPrefix(prefix netip.Prefix) netip.Prefix {
+	b := prefix.Addr().AsSlice()
+
+	// Add one at the last bit of the prefix, carrying over to the preceding bytes
+	bit := prefix.Bits() - 1
+	for i := bit / 8; i >= 0; i-- {
+		increment := byte(1)
+		if i == bit/8 {
+			increment = 1 << (7 - bit%8)
+		}
+		b[i] += increment
+		if b[i] >= increment {
+			break
+		}
+	}
+
+	addr, _ := netip.AddrFromSlice(b)
+	return netip.PrefixFrom(addr, prefix.Bits())
+}
+
+// reverse is a function that reverses the order of the labels in place
+func reverse(labels []string) {
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+}
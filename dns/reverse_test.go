@@ -0,0 +1,100 @@
+package dns_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/dns"
+)
+
+func TestReverseName(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "10.12.0.1", expected: "1.0.12.10.in-addr.arpa"},
+		{input: "192.0.2.255", expected: "255.2.0.192.in-addr.arpa"},
+		{input: "2001:db8::1", expected: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+		{input: "::ffff:10.0.0.1", expected: "1.0.0.10.in-addr.arpa"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := dns.ReverseName(netip.MustParseAddr(tc.input)); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name              string
+		input             string
+		expected          []string
+		expectedClassless bool
+		expectErr         bool
+	}{
+		{name: "Slash16", input: "10.12.0.0/16", expected: []string{"12.10.in-addr.arpa"}},
+		{name: "Slash24", input: "192.0.2.0/24", expected: []string{"2.0.192.in-addr.arpa"}},
+		{name: "Slash15", input: "10.12.0.0/15", expected: []string{"12.10.in-addr.arpa", "13.10.in-addr.arpa"}},
+		{name: "Slash22", input: "10.0.4.0/22", expected: []string{"4.0.10.in-addr.arpa", "5.0.10.in-addr.arpa", "6.0.10.in-addr.arpa", "7.0.10.in-addr.arpa"}},
+		{name: "HostBitsSet", input: "10.12.3.4/16", expected: []string{"12.10.in-addr.arpa"}},
+		{name: "Slash0", input: "0.0.0.0/0", expected: []string{"in-addr.arpa"}},
+		{name: "LastOctet", input: "255.255.254.0/23", expected: []string{"254.255.255.in-addr.arpa", "255.255.255.in-addr.arpa"}},
+		{name: "Classless", input: "192.0.2.64/26", expected: []string{"64/26.2.0.192.in-addr.arpa"}, expectedClassless: true},
+		{name: "Slash32", input: "192.0.2.1/32", expected: []string{"1/32.2.0.192.in-addr.arpa"}, expectedClassless: true},
+		{name: "IPv6Slash48", input: "2001:db8:1::/48", expected: []string{"1.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{name: "IPv6Slash47", input: "2001:db8:1e::/47", expected: []string{"e.1.0.0.8.b.d.0.1.0.0.2.ip6.arpa", "f.1.0.0.8.b.d.0.1.0.0.2.ip6.arpa"}},
+		{name: "IPv6Invalid", input: "2001:db8::/200", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, _ := netip.ParsePrefix(tc.input)
+			zones, err := dns.ReverseZones(prefix)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, zone := range zones {
+				names = append(names, zone.Name)
+				if zone.Classless != tc.expectedClassless {
+					t.Errorf("expected classless %t for %s", tc.expectedClassless, zone.Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
+
+func TestClasslessRecords(t *testing.T) {
+	zones, err := dns.ReverseZones(netip.MustParsePrefix("192.0.2.64/26"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := netip.MustParseAddr("192.0.2.65")
+
+	expectedCNAME := "65.2.0.192.in-addr.arpa. IN CNAME 65.64/26.2.0.192.in-addr.arpa."
+	if got := zones[0].ClasslessCNAME(addr); got != expectedCNAME {
+		t.Errorf("expected %s, got %s", expectedCNAME, got)
+	}
+
+	expectedPTR := "65.64/26.2.0.192.in-addr.arpa"
+	if got := zones[0].PTRName(addr); got != expectedPTR {
+		t.Errorf("expected %s, got %s", expectedPTR, got)
+	}
+}
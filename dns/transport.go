@@ -0,0 +1,243 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The transports that DNS queries are sent with
+const (
+	// TransportUDP sends plaintext queries over UDP (and TCP for truncated
+	// responses), the classic DNS transport
+	TransportUDP = "udp"
+	// TransportTLS sends queries over TLS (DNS over TLS, RFC 7858)
+	TransportTLS = "tls"
+	// TransportHTTPS sends queries as HTTPS requests (DNS over HTTPS, RFC 8484)
+	TransportHTTPS = "https"
+)
+
+// defaultTLSPort is the port of DNS over TLS servers
+const defaultTLSPort = "853"
+
+// maxMessageSize is the maximum size of a DNS message
+const maxMessageSize = 65535
+
+// Server is a DNS server and the transport its queries are sent with
+type Server struct {
+	// Name is the server as given, e.g. tls://1.1.1.1:853
+	Name string
+	// Transport is the transport the queries are sent with
+	Transport string
+	// Address is the address:port (or host:port) of the server for the
+	// udp and tls transports, and the URL of the server for https
+	Address string
+	// ServerName is the name the TLS certificate of the server is
+	// verified against (tls transport)
+	ServerName string
+	// HTTPClient sends the requests of the https transport, nil for the
+	// default client
+	HTTPClient *http.Client
+
+	sessions tls.ClientSessionCache
+}
+
+// ParseServer is a function that parses a DNS server given as an address or
+// address:port (plaintext, port 53), as tls://host[:port] (DNS over TLS,
+// port 853) or as an https:// URL (DNS over HTTPS)
+func ParseServer(server string) (Server, error) {
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			return Server{}, fmt.Errorf("invalid DNS over HTTPS server: %s (expected a URL, e.g. https://cloudflare-dns.com/dns-query)", server)
+		}
+		return Server{Name: server, Transport: TransportHTTPS, Address: server}, nil
+
+	case strings.HasPrefix(server, "tls://"):
+		hostPort := strings.TrimPrefix(server, "tls://")
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			// The port is optional
+			host, port = strings.Trim(hostPort, "[]"), defaultTLSPort
+		}
+		if host == "" || strings.ContainsAny(host, "/[]") {
+			return Server{}, fmt.Errorf("invalid DNS over TLS server: %s (expected tls://host[:port], e.g. tls://1.1.1.1:853)", server)
+		}
+		return Server{
+			Name:       server,
+			Transport:  TransportTLS,
+			Address:    net.JoinHostPort(host, port),
+			ServerName: host,
+			sessions:   tls.NewLRUClientSessionCache(16),
+		}, nil
+	}
+
+	address, err := ServerAddress(server)
+	if err != nil {
+		return Server{}, err
+	}
+	return Server{Name: server, Transport: TransportUDP, Address: address}, nil
+}
+
+// Resolver is a function that returns a resolver that sends its queries to
+// the server, with the transport of the server
+func (s Server) Resolver() *net.Resolver {
+	switch s.Transport {
+	case TransportTLS:
+		// Resume the TLS sessions, so that only the first query pays for a
+		// full handshake. A TLS connection is not a packet connection, so
+		// the resolver frames the messages as over TCP, as DoT requires.
+		config := &tls.Config{ServerName: s.ServerName, ClientSessionCache: s.sessions}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				dialer := tls.Dialer{Config: config}
+				return dialer.DialContext(ctx, "tcp", s.Address)
+			},
+		}
+	case TransportHTTPS:
+		client := s.HTTPClient
+		if client == nil {
+			client = http.DefaultClient
+		}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return &httpsConn{url: s.Address, client: client}, nil
+			},
+		}
+	}
+	return NewResolver(s.Address)
+}
+
+// httpsConn is a connection that sends the DNS messages written to it as
+// DNS over HTTPS requests, and returns the responses when read. The messages
+// are framed as over TCP, with a two byte length before every message.
+type httpsConn struct {
+	url      string
+	client   *http.Client
+	deadline time.Time
+	query    []byte
+	response bytes.Reader
+}
+
+// Write is a function that buffers the messages written to the connection
+func (c *httpsConn) Write(p []byte) (int, error) {
+	c.query = append(c.query, p...)
+	return len(p), nil
+}
+
+// Read is a function that sends the buffered message, if there is no
+// response left to read, and reads the response
+func (c *httpsConn) Read(p []byte) (int, error) {
+	if c.response.Len() == 0 {
+		if err := c.roundTrip(); err != nil {
+			return 0, err
+		}
+	}
+	return c.response.Read(p)
+}
+
+// roundTrip is a function that sends the buffered message as a POST request
+// and stores the response, framed with its length
+func (c *httpsConn) roundTrip() error {
+	if len(c.query) < 2 || len(c.query) < 2+(int(c.query[0])<<8|int(c.query[1])) {
+		return io.ErrUnexpectedEOF
+	}
+	n := int(c.query[0])<<8 | int(c.query[1])
+	message := c.query[2 : 2+n]
+	c.query = c.query[2+n:]
+
+	ctx := context.Background()
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 || len(body) > maxMessageSize {
+		return errors.New("invalid DNS over HTTPS response")
+	}
+	c.response.Reset(append([]byte{byte(len(body) >> 8), byte(len(body))}, body...))
+	return nil
+}
+
+// Close is a function that closes the connection, there is nothing to close
+// since the requests are sent with the HTTP client
+func (c *httpsConn) Close() error { return nil }
+
+// LocalAddr is a function that returns the local address of the connection
+func (c *httpsConn) LocalAddr() net.Addr { return httpsAddr("") }
+
+// RemoteAddr is a function that returns the URL of the server
+func (c *httpsConn) RemoteAddr() net.Addr { return httpsAddr(c.url) }
+
+// SetDeadline is a function that sets the deadline of the requests
+func (c *httpsConn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+// SetReadDeadline is a function that sets the deadline of the requests
+func (c *httpsConn) SetReadDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+// SetWriteDeadline is a function that has no effect, the requests are only
+// sent when the response is read
+func (c *httpsConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// httpsAddr is the address of a DNS over HTTPS server, its URL
+type httpsAddr string
+
+// Network is a function that returns the name of the network
+func (a httpsAddr) Network() string { return TransportHTTPS }
+
+// String is a function that returns the URL of the server
+func (a httpsAddr) String() string { return string(a) }
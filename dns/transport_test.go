@@ -0,0 +1,112 @@
+package dns_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/dns"
+)
+
+func TestParseServer(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input      string
+		transport  string
+		address    string
+		serverName string
+		expectErr  bool
+	}{
+		{input: "1.1.1.1", transport: dns.TransportUDP, address: "1.1.1.1:53"},
+		{input: "10.0.0.53:5353", transport: dns.TransportUDP, address: "10.0.0.53:5353"},
+		{input: "2606:4700:4700::1111", transport: dns.TransportUDP, address: "[2606:4700:4700::1111]:53"},
+		{input: "tls://1.1.1.1", transport: dns.TransportTLS, address: "1.1.1.1:853", serverName: "1.1.1.1"},
+		{input: "tls://1.1.1.1:8853", transport: dns.TransportTLS, address: "1.1.1.1:8853", serverName: "1.1.1.1"},
+		{input: "tls://[2606:4700:4700::1111]", transport: dns.TransportTLS, address: "[2606:4700:4700::1111]:853", serverName: "2606:4700:4700::1111"},
+		{input: "tls://dns.quad9.net:853", transport: dns.TransportTLS, address: "dns.quad9.net:853", serverName: "dns.quad9.net"},
+		{input: "https://cloudflare-dns.com/dns-query", transport: dns.TransportHTTPS, address: "https://cloudflare-dns.com/dns-query"},
+		{input: "tls://", expectErr: true},
+		{input: "https://", expectErr: true},
+		{input: "dns.example.com", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			server, err := dns.ParseServer(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", server)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if server.Name != tc.input || server.Transport != tc.transport || server.Address != tc.address || server.ServerName != tc.serverName {
+				t.Errorf("expected %s %s %q, got %s %s %q", tc.transport, tc.address, tc.serverName, server.Transport, server.Address, server.ServerName)
+			}
+		})
+	}
+}
+
+func TestHTTPSTransport(t *testing.T) {
+	// Answer the DNS messages posted to the server
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := io.ReadAll(r.Body)
+		if err != nil || r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		resp := dnsResponse(query)
+		if resp == nil {
+			http.Error(w, "dropped", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(resp)
+	}))
+	defer ts.Close()
+
+	server, err := dns.ParseServer(ts.URL + "/dns-query")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.HTTPClient = ts.Client()
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		host      string
+		expected  string
+		expectErr bool
+	}{
+		{name: "Answer", host: "example.com.", expected: "192.0.2.1"},
+		{name: "NXDOMAIN", host: "nx.example.com.", expectErr: true},
+		{name: "HTTPError", host: "drop.example.com.", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			addrs, err := server.Resolver().LookupNetIP(ctx, "ip4", tc.host)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", addrs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(addrs) != 1 || addrs[0].String() != tc.expected {
+				t.Errorf("expected [%s], got %v", tc.expected, addrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bitcanon/iptool/dns"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/route"
+)
+
+// DefaultTargets are the anycast addresses of public DNS services that are
+// connected to by the internet reachability check (Cloudflare, Google and
+// Quad9 over IPv4, Cloudflare and Google over IPv6)
+var DefaultTargets = []string{
+	"1.1.1.1:443",
+	"8.8.8.8:443",
+	"9.9.9.9:443",
+	"[2606:4700:4700::1111]:443",
+	"[2001:4860:4860::8888]:443",
+}
+
+// DefaultNames are the names resolved by the DNS health check
+var DefaultNames = []string{"example.com", "cloudflare.com", "google.com"}
+
+// ReferenceResolver is the public resolver the system resolver is compared
+// with when it fails, to tell a broken resolver from a broken network
+const ReferenceResolver = "1.1.1.1"
+
+// publicAddressServer answers queries for publicAddressName with the
+// address the query came from (the public address of a NAT)
+const (
+	publicAddressServer = "208.67.222.222"
+	publicAddressName   = "myip.opendns.com."
+)
+
+// slowLookup is the lookup time above which the resolver is reported as slow
+const slowLookup = 300 * time.Millisecond
+
+// gatewayPorts are the TCP ports the gateway is probed on, a gateway that
+// accepts or refuses a connection on any of them is reachable
+var gatewayPorts = []string{"53", "80", "443"}
+
+// Config holds the targets of the checks
+type Config struct {
+	// Targets are the host:port addresses connected to by the internet
+	// reachability check
+	Targets []string
+	// Names are the names resolved by the DNS health check
+	Names []string
+}
+
+// Checks is a function that returns the checks of the network environment:
+// local name resolution, the default gateway, internet reachability, the MTU,
+// NAT detection and DNS health
+func Checks(cfg Config) []Check {
+	return []Check{
+		{Name: "Local name resolution", Run: checkLocalResolution},
+		{Name: "Default gateway", Run: checkGateway},
+		{Name: "Internet reachability", Run: func(ctx context.Context) (Status, string) {
+			return checkInternet(ctx, cfg.Targets)
+		}},
+		{Name: "MTU", Run: checkMTU},
+		{Name: "NAT detection", Run: checkNAT},
+		{Name: "DNS health", Run: func(ctx context.Context) (Status, string) {
+			return checkDNS(ctx, cfg.Names)
+		}},
+	}
+}
+
+// checkLocalResolution resolves localhost and the name of the host, which
+// should work without a network (from the hosts file)
+func checkLocalResolution(ctx context.Context) (Status, string) {
+	if ip.LookupsDisabled() {
+		return StatusSkip, ip.ErrLookupsDisabled.Error()
+	}
+	if _, err := net.DefaultResolver.LookupHost(ctx, "localhost"); err != nil {
+		return StatusFail, fmt.Sprintf("localhost does not resolve (%s)", shortError(err))
+	}
+
+	// The name of the host often only resolves with a working network
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return StatusPass, "localhost resolves"
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return StatusWarn, fmt.Sprintf("localhost resolves, but the hostname %s does not (%s)", hostname, shortError(err))
+	}
+	return StatusPass, fmt.Sprintf("localhost and %s (%s) resolve", hostname, strings.Join(addrs, ", "))
+}
+
+// defaultRoutes returns the IPv4 and IPv6 default routes with the lowest
+// metric, the routes are invalid (zero) if there is no default route
+func defaultRoutes() (v4, v6 route.Route, err error) {
+	routes, err := route.ReadTable()
+	if err != nil {
+		return v4, v6, err
+	}
+
+	// The routes are sorted by prefix and metric, keep the first per family
+	for _, r := range routes {
+		if r.Prefix.Bits() != 0 || (!r.Gateway.IsValid() && r.Interface == "") {
+			continue
+		}
+		if r.Prefix.Addr().Is4() && !v4.Prefix.IsValid() {
+			v4 = r
+		}
+		if r.Prefix.Addr().Is6() && !v6.Prefix.IsValid() {
+			v6 = r
+		}
+	}
+	return v4, v6, nil
+}
+
+// checkGateway finds the default gateway and probes it on a few TCP ports.
+// Both an accepted and a refused connection prove that the gateway is
+// reachable, gateways that drop the probes are reported as a warning.
+func checkGateway(ctx context.Context) (Status, string) {
+	v4, v6, err := defaultRoutes()
+	if err != nil {
+		return StatusSkip, fmt.Sprintf("cannot read the routing table (%s)", shortError(err))
+	}
+	gateway := v4
+	if !gateway.Prefix.IsValid() {
+		gateway = v6
+	}
+	if !gateway.Prefix.IsValid() {
+		return StatusFail, "no default route"
+	}
+	if !gateway.Gateway.IsValid() {
+		return StatusPass, fmt.Sprintf("default route via %s (point-to-point link)", gateway.Interface)
+	}
+
+	// Probe the ports in parallel, the first answer wins
+	addr := gateway.Gateway.String()
+	if gateway.Gateway.Is6() && gateway.Gateway.IsLinkLocalUnicast() {
+		addr = gateway.Gateway.WithZone(gateway.Interface).String()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	answers := make(chan time.Duration, len(gatewayPorts))
+	var wg sync.WaitGroup
+	for _, port := range gatewayPorts {
+		wg.Add(1)
+		go func(port string) {
+			defer wg.Done()
+			if rtt, err := dialReachable(ctx, net.JoinHostPort(addr, port)); err == nil {
+				answers <- rtt
+			}
+		}(port)
+	}
+	go func() {
+		wg.Wait()
+		close(answers)
+	}()
+
+	via := fmt.Sprintf("%s via %s", gateway.Gateway, gateway.Interface)
+	if rtt, ok := <-answers; ok {
+		return StatusPass, fmt.Sprintf("%s answers (%s)", via, formatRTT(rtt))
+	}
+	return StatusWarn, fmt.Sprintf("%s does not answer on TCP ports %s (it may drop the probes)", via, strings.Join(gatewayPorts, ", "))
+}
+
+// dialReachable connects to the address and returns the time it took. A
+// refused connection is an answer too, the host is reachable.
+func dialReachable(ctx context.Context, address string) (time.Duration, error) {
+	var dialer net.Dialer
+
+	// Start the timer
+	start := time.Now()
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	if conn != nil {
+		conn.Close()
+	}
+	return rtt, nil
+}
+
+// checkInternet connects to the targets in parallel. The check fails if no
+// target is reachable, and warns if an IPv4 target is not reachable (IPv6
+// is optional, its reachability is only reported).
+func checkInternet(ctx context.Context, targets []string) (Status, string) {
+	type answer struct {
+		ipv6 bool
+		rtt  time.Duration
+		err  error
+	}
+	answers := make([]answer, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		host, _, _ := net.SplitHostPort(target)
+		addr, _ := netip.ParseAddr(host)
+		answers[i].ipv6 = addr.Is6()
+
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			answers[i].rtt, answers[i].err = dialReachable(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	// Count the reachable targets per family and find the fastest
+	var reached, total [2]int
+	var best time.Duration
+	var lastErr error
+	for _, a := range answers {
+		family := 0
+		if a.ipv6 {
+			family = 1
+		}
+		total[family]++
+		if a.err != nil {
+			lastErr = a.err
+			continue
+		}
+		reached[family]++
+		if best == 0 || a.rtt < best {
+			best = a.rtt
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d IPv4 and %d/%d IPv6 targets reachable", reached[0], total[0], reached[1], total[1])
+	switch {
+	case reached[0]+reached[1] == 0:
+		if lastErr != nil {
+			return StatusFail, fmt.Sprintf("%s (%s)", summary, shortError(lastErr))
+		}
+		return StatusFail, summary
+	case reached[0] < total[0]:
+		return StatusWarn, fmt.Sprintf("%s, fastest %s", summary, formatRTT(best))
+	}
+	return StatusPass, fmt.Sprintf("%s, fastest %s", summary, formatRTT(best))
+}
+
+// checkMTU reports the MTU of the interface of the default route. A link
+// with an MTU below 1500 is usually a tunnel or PPPoE, which breaks sites
+// when path MTU discovery is blocked.
+func checkMTU(ctx context.Context) (Status, string) {
+	v4, v6, err := defaultRoutes()
+	if err != nil {
+		return StatusSkip, fmt.Sprintf("cannot read the routing table (%s)", shortError(err))
+	}
+	name := v4.Interface
+	if name == "" {
+		name = v6.Interface
+	}
+	if name == "" {
+		return StatusSkip, "no default route"
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return StatusSkip, fmt.Sprintf("cannot read the interface %s (%s)", name, shortError(err))
+	}
+
+	switch {
+	case iface.MTU < 1280:
+		return StatusFail, fmt.Sprintf("%s has an MTU of %d, below the IPv6 minimum of 1280", name, iface.MTU)
+	case iface.MTU < 1500:
+		return StatusWarn, fmt.Sprintf("%s has an MTU of %d (tunnel or PPPoE), large packets are fragmented or dropped", name, iface.MTU)
+	}
+	return StatusPass, fmt.Sprintf("%s has an MTU of %d", name, iface.MTU)
+}
+
+// checkNAT compares the local address used to reach the internet with the
+// public address reported by an external resolver
+func checkNAT(ctx context.Context) (Status, string) {
+	if ip.LookupsDisabled() {
+		return StatusSkip, ip.ErrLookupsDisabled.Error()
+	}
+
+	// Find the local address, connecting a UDP socket sends no packets
+	conn, err := net.Dial("udp4", net.JoinHostPort(publicAddressServer, "53"))
+	if err != nil {
+		return StatusSkip, fmt.Sprintf("no IPv4 route to the internet (%s)", shortError(err))
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+	conn.Close()
+	kind := ip.Classify(local)
+
+	// Ask the resolver for the address the query comes from
+	server, err := dns.ParseServer(publicAddressServer)
+	if err != nil {
+		return StatusSkip, err.Error()
+	}
+	addrs, err := server.Resolver().LookupNetIP(ctx, "ip4", publicAddressName)
+	if err != nil || len(addrs) == 0 {
+		return StatusWarn, fmt.Sprintf("local address %s (%s), the public address is unknown (%s)", local, strings.ToLower(kind), shortError(err))
+	}
+	public := addrs[0].Unmap()
+
+	switch {
+	case public == local:
+		return StatusPass, fmt.Sprintf("no NAT, the public address is %s", public)
+	case kind == "Shared address space (CGNAT)":
+		return StatusWarn, fmt.Sprintf("behind carrier-grade NAT: local %s, public %s (inbound connections are not possible)", local, public)
+	}
+	return StatusPass, fmt.Sprintf("behind NAT: local %s, public %s", local, public)
+}
+
+// checkDNS resolves the names with the system resolver. When the system
+// resolver fails, the reference resolver is tried to tell a broken resolver
+// from a broken network.
+func checkDNS(ctx context.Context, names []string) (Status, string) {
+	if ip.LookupsDisabled() {
+		return StatusSkip, ip.ErrLookupsDisabled.Error()
+	}
+	if len(names) == 0 {
+		return StatusSkip, "no names to resolve"
+	}
+
+	resolved := 0
+	var total time.Duration
+	var lastErr error
+	for _, name := range names {
+		start := time.Now()
+		if _, err := net.DefaultResolver.LookupHost(ctx, name); err != nil {
+			lastErr = err
+			continue
+		}
+		total += time.Since(start)
+		resolved++
+	}
+
+	summary := fmt.Sprintf("%d/%d names resolved", resolved, len(names))
+	if resolved == 0 {
+		server, _ := dns.ParseServer(ReferenceResolver)
+		if _, err := server.Resolver().LookupHost(ctx, names[0]); err == nil {
+			return StatusFail, fmt.Sprintf("%s, but %s resolves them: the system resolver is broken (%s)", summary, ReferenceResolver, shortError(lastErr))
+		}
+		return StatusFail, fmt.Sprintf("%s (%s)", summary, shortError(lastErr))
+	}
+
+	average := total / time.Duration(resolved)
+	switch {
+	case resolved < len(names):
+		return StatusWarn, fmt.Sprintf("%s, average %s (%s)", summary, formatRTT(average), shortError(lastErr))
+	case average > slowLookup:
+		return StatusWarn, fmt.Sprintf("%s, average %s (slow resolver)", summary, formatRTT(average))
+	}
+	return StatusPass, fmt.Sprintf("%s, average %s", summary, formatRTT(average))
+}
+
+// formatRTT returns a duration in milliseconds with two decimals
+func formatRTT(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", float64(d)/float64(time.Millisecond))
+}
+
+// shortError returns the last part of an error message, e.g. "connection
+// refused" for "dial tcp 10.0.0.1:22: connect: connection refused"
+func shortError(err error) string {
+	if err == nil {
+		return "no answer"
+	}
+	msg := err.Error()
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		return msg[i+2:]
+	}
+	return msg
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package doctor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	// StatusPass means that the check found no problem
+	StatusPass Status = iota
+	// StatusWarn means that the check found a problem that degrades the
+	// network, but does not break it (e.g. a slow resolver)
+	StatusWarn
+	// StatusFail means that the check found a problem that breaks the
+	// network (e.g. no default route)
+	StatusFail
+	// StatusSkip means that the check does not apply (e.g. the MTU when
+	// there is no default route)
+	StatusSkip
+)
+
+// String is a function that returns the status in upper case, e.g. PASS
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	case StatusFail:
+		return "FAIL"
+	}
+	return "SKIP"
+}
+
+// MarshalText is a function that encodes the status as text (in JSON)
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Check is a named check of the network environment. The check returns its
+// status and a one-line summary of what it found, and must return when the
+// context is done.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail"`
+	Duration time.Duration `json:"-"`
+}
+
+// Run is a function that runs the checks in parallel and returns their
+// results in the order of the checks. Every check is given the timeout, a
+// check that has not returned by then fails.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check, timeout)
+		}(i, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// runCheck runs a check with the timeout, a check that does not return in
+// time (e.g. blocked in a system call) is abandoned and fails
+func runCheck(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Start the timer
+	start := time.Now()
+
+	// Run the check in the background, the channel is buffered so that an
+	// abandoned check does not leak a blocked goroutine
+	done := make(chan Result, 1)
+	go func() {
+		status, detail := check.Run(ctx)
+		done <- Result{Name: check.Name, Status: status, Detail: detail}
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		// Give the check a moment to report its own timeout
+		select {
+		case result = <-done:
+		case <-time.After(100 * time.Millisecond):
+			result = Result{Name: check.Name, Status: StatusFail, Detail: "timed out after " + timeout.String()}
+		}
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// Worst is a function that returns the worst status of the results (fail,
+// warn or pass), skipped checks are ignored
+func Worst(results []Result) Status {
+	worst := StatusPass
+	for _, r := range results {
+		if r.Status != StatusSkip && r.Status > worst {
+			worst = r.Status
+		}
+	}
+	return worst
+}
+
+// Count is a function that returns the number of results with the status
+func Count(results []Result, status Status) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,90 @@
+package doctor_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/doctor"
+)
+
+// TestRun tests that the checks are run in parallel, in order and with the timeout
+func TestRun(t *testing.T) {
+	checks := []doctor.Check{
+		{Name: "slow", Run: func(ctx context.Context) (doctor.Status, string) {
+			time.Sleep(50 * time.Millisecond)
+			return doctor.StatusPass, "done"
+		}},
+		{Name: "warn", Run: func(ctx context.Context) (doctor.Status, string) {
+			return doctor.StatusWarn, "degraded"
+		}},
+		{Name: "context", Run: func(ctx context.Context) (doctor.Status, string) {
+			<-ctx.Done()
+			return doctor.StatusFail, "gave up"
+		}},
+		{Name: "stuck", Run: func(ctx context.Context) (doctor.Status, string) {
+			time.Sleep(time.Second)
+			return doctor.StatusPass, "too late"
+		}},
+	}
+
+	start := time.Now()
+	results := doctor.Run(context.Background(), checks, 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+		t.Errorf("expected the checks to run in parallel, took %s", elapsed)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name   string
+		status doctor.Status
+		detail string
+	}{
+		{name: "slow", status: doctor.StatusPass, detail: "done"},
+		{name: "warn", status: doctor.StatusWarn, detail: "degraded"},
+		{name: "context", status: doctor.StatusFail, detail: "gave up"},
+		{name: "stuck", status: doctor.StatusFail, detail: "timed out after 200ms"},
+	}
+
+	// Run test cases
+	if len(results) != len(testCases) {
+		t.Fatalf("expected %d results, got %d", len(testCases), len(results))
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := results[i]
+			if r.Name != tc.name || r.Status != tc.status || r.Detail != tc.detail {
+				t.Errorf("expected %s %s %q, got %s %s %q", tc.name, tc.status, tc.detail, r.Name, r.Status, r.Detail)
+			}
+		})
+	}
+}
+
+// TestWorst tests the overall status of the results
+func TestWorst(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		statuses []doctor.Status
+		expected doctor.Status
+	}{
+		{name: "Empty", statuses: nil, expected: doctor.StatusPass},
+		{name: "Pass", statuses: []doctor.Status{doctor.StatusPass, doctor.StatusPass}, expected: doctor.StatusPass},
+		{name: "SkipIgnored", statuses: []doctor.Status{doctor.StatusPass, doctor.StatusSkip}, expected: doctor.StatusPass},
+		{name: "Warn", statuses: []doctor.Status{doctor.StatusWarn, doctor.StatusSkip}, expected: doctor.StatusWarn},
+		{name: "Fail", statuses: []doctor.Status{doctor.StatusFail, doctor.StatusWarn, doctor.StatusPass}, expected: doctor.StatusFail},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var results []doctor.Result
+			for _, status := range tc.statuses {
+				results = append(results, doctor.Result{Status: status})
+			}
+			if worst := doctor.Worst(results); worst != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, worst)
+			}
+		})
+	}
+}
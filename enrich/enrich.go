@@ -0,0 +1,269 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitcanon/iptool/ratelimit"
+)
+
+// Result holds the enrichment data of a single input address. Only the
+// fields of the sources used are filled in.
+type Result struct {
+	Input    string   `json:"input"`
+	IP       string   `json:"ip,omitempty"`
+	RDNS     []string `json:"rdns,omitempty"`
+	ASN      string   `json:"asn,omitempty"`
+	ASName   string   `json:"as_name,omitempty"`
+	Prefix   string   `json:"prefix,omitempty"`
+	Country  string   `json:"country,omitempty"`
+	Registry string   `json:"registry,omitempty"`
+	Listed   []string `json:"listed,omitempty"`
+	Class    string   `json:"classification,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Source is a source of enrichment data. The lookup function fills in
+// the fields of the result that the source provides.
+type Source struct {
+	Name    string
+	Aliases []string
+	Columns []string
+	Lookup  func(ctx context.Context, addr netip.Addr, r *Result) error
+}
+
+// sources is the registry of the available sources, by name
+var sources = map[string]Source{}
+
+// Register is a function that makes a source available by its name
+func Register(s Source) {
+	sources[s.Name] = s
+}
+
+// SourceNames is a function that returns the names of the available sources
+func SourceNames() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupSource is a function that returns the source with the name or alias
+func lookupSource(name string) (Source, bool) {
+	if s, ok := sources[name]; ok {
+		return s, true
+	}
+	for _, s := range sources {
+		if slices.Contains(s.Aliases, name) {
+			return s, true
+		}
+	}
+	return Source{}, false
+}
+
+// ParseSources is a function that returns the sources with the given names
+// or aliases (e.g. ptr for rdns)
+func ParseSources(names []string) ([]Source, error) {
+	var result []Source
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		s, ok := lookupSource(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown enrichment source: %s (available: %s)", name, strings.Join(SourceNames(), ", "))
+		}
+		if !seen[s.Name] {
+			seen[s.Name] = true
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// Columns is a function that returns the names of the CSV columns for the sources
+func Columns(srcs []Source) []string {
+	return append([]string{"input", "ip"}, SourceColumns(srcs)...)
+}
+
+// SourceColumns is a function that returns the names of the columns filled
+// in by the sources, followed by the errors column. These are the columns
+// appended to the records of a log file.
+func SourceColumns(srcs []Source) []string {
+	var columns []string
+	for _, s := range srcs {
+		columns = append(columns, s.Columns...)
+	}
+	return append(columns, "errors")
+}
+
+// Row is a function that returns the values of the result for the CSV
+// columns returned by Columns
+func (r *Result) Row(srcs []Source) []string {
+	return append([]string{r.Input, r.IP}, r.Values(srcs)...)
+}
+
+// Values is a function that returns the values of the result for the
+// columns returned by SourceColumns
+func (r *Result) Values(srcs []Source) []string {
+	values := map[string]string{
+		"rdns":     strings.Join(r.RDNS, " "),
+		"asn":      r.ASN,
+		"as_name":  r.ASName,
+		"prefix":   r.Prefix,
+		"country":  r.Country,
+		"registry": r.Registry,
+		"listed":   strings.Join(r.Listed, " "),
+
+		"classification": r.Class,
+	}
+	var row []string
+	for _, s := range srcs {
+		for _, column := range s.Columns {
+			row = append(row, values[column])
+		}
+	}
+	return append(row, strings.Join(r.Errors, "; "))
+}
+
+// Enrich is a function that looks up the input address in every source.
+// Errors of the individual sources are recorded in the result, so that a
+// failing source does not prevent the other sources from being used.
+func Enrich(ctx context.Context, input string, srcs []Source) Result {
+	r := Result{Input: input}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(input))
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("invalid IP address: %s", input))
+		return r
+	}
+	addr = addr.Unmap()
+	r.IP = addr.String()
+
+	for _, s := range srcs {
+		if err := s.Lookup(ctx, addr, &r); err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", s.Name, err))
+		}
+	}
+	return r
+}
+
+// reorderWindow is the number of inputs per worker that may be in flight
+const reorderWindow = 16
+
+// Pipeline is a function that enriches the addresses received on the input
+// channel concurrently, using the given number of workers, and sends the
+// results on the returned channel. The results are sent in the same order
+// as the addresses are received. The returned channel is closed when the
+// input channel has been closed and all results have been sent. The lookups
+// are started at the rate of the limiter (nil for unlimited). When ctx is
+// done, no more addresses are accepted but the lookups in flight are
+// completed and their results sent (draining).
+func Pipeline(ctx context.Context, input <-chan string, srcs []Source, workers int, limiter *ratelimit.Limiter) <-chan Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		index int
+		input string
+	}
+	type result struct {
+		index int
+		Result
+	}
+
+	jobs := make(chan job)
+	results := make(chan result)
+	output := make(chan Result)
+
+	// The window limits the number of inputs in flight, which bounds the
+	// memory used for reordering when a single lookup is slow
+	window := make(chan struct{}, workers*reorderWindow)
+
+	// Number the inputs so that the results can be put back in order
+	go func() {
+		defer close(jobs)
+		index := 0
+		for in := range input {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index: index, input: in}:
+				index++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Start the workers, the lookups in flight are not canceled with ctx
+	drain := context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				limiter.Wait(drain)
+				results <- result{index: j.index, Result: Enrich(drain, j.input, srcs)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Reorder the results, buffering the ones that finish early
+	go func() {
+		defer close(output)
+		next := 0
+		pending := make(map[int]Result)
+		for r := range results {
+			pending[r.index] = r.Result
+			for {
+				res, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				output <- res
+				<-window
+				next++
+			}
+		}
+	}()
+
+	return output
+}
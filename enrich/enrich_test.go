@@ -0,0 +1,161 @@
+package enrich_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/enrich"
+)
+
+// testSource is a source that sleeps for a random time before filling in the
+// AS number, so that the results of the pipeline finish out of order
+var testSource = enrich.Source{
+	Name:    "test",
+	Columns: []string{"asn"},
+	Lookup: func(ctx context.Context, addr netip.Addr, r *enrich.Result) error {
+		time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+		if addr.Is6() {
+			return errors.New("IPv6 not supported")
+		}
+		r.ASN = fmt.Sprint(addr.As4()[3])
+		return nil
+	},
+}
+
+func init() {
+	enrich.Register(testSource)
+}
+
+func TestPipelineOrder(t *testing.T) {
+	// Feed 500 addresses to the pipeline
+	input := make(chan string)
+	go func() {
+		defer close(input)
+		for i := 0; i < 500; i++ {
+			input <- fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		}
+	}()
+
+	sources, err := enrich.ParseSources([]string{"test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The results must be in the same order as the input
+	i := 0
+	for r := range enrich.Pipeline(context.Background(), input, sources, 20, nil) {
+		expected := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		if r.Input != expected {
+			t.Fatalf("expected result %d for %s, got %s", i, expected, r.Input)
+		}
+		if r.ASN != fmt.Sprint(i%256) {
+			t.Errorf("expected ASN %d, got %s", i%256, r.ASN)
+		}
+		i++
+	}
+	if i != 500 {
+		t.Errorf("expected 500 results, got %d", i)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	sources := []enrich.Source{testSource}
+
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		input       string
+		expectedRow []string
+	}{
+		{name: "IPv4", input: "192.0.2.7", expectedRow: []string{"192.0.2.7", "192.0.2.7", "7", ""}},
+		{name: "IPv4Mapped", input: "::ffff:192.0.2.7", expectedRow: []string{"::ffff:192.0.2.7", "192.0.2.7", "7", ""}},
+		{name: "SourceError", input: "2001:db8::1", expectedRow: []string{"2001:db8::1", "2001:db8::1", "", "test: IPv6 not supported"}},
+		{name: "InvalidAddress", input: "bogus", expectedRow: []string{"bogus", "", "", "invalid IP address: bogus"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := enrich.Enrich(context.Background(), tc.input, sources)
+			if row := r.Row(sources); !reflect.DeepEqual(row, tc.expectedRow) {
+				t.Errorf("expected row %q, got %q", tc.expectedRow, row)
+			}
+		})
+	}
+
+	expectedColumns := []string{"input", "ip", "asn", "errors"}
+	if columns := enrich.SourceColumns(sources); !reflect.DeepEqual(columns, expectedColumns[2:]) {
+		t.Errorf("expected source columns %v, got %v", expectedColumns[2:], columns)
+	}
+	if columns := enrich.Columns(sources); !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("expected columns %v, got %v", expectedColumns, columns)
+	}
+}
+
+func TestClassification(t *testing.T) {
+	sources, _ := enrich.ParseSources([]string{"classification"})
+
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "10.1.2.3", expected: "Private"},
+		{input: "100.64.0.1", expected: "Shared address space (CGNAT)"},
+		{input: "8.8.8.8", expected: "Global unicast"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			r := enrich.Enrich(context.Background(), tc.input, sources)
+			if values := r.Values(sources); values[0] != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, values[0])
+			}
+		})
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     []string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "All", input: []string{"rdns", "asn", "geo", "rep"}, expected: []string{"rdns", "asn", "geo", "rep"}},
+		{name: "Duplicates", input: []string{"asn", "ASN", " asn"}, expected: []string{"asn"}},
+		{name: "Aliases", input: []string{"ptr", "rdns", "class"}, expected: []string{"rdns", "classification"}},
+		{name: "Unknown", input: []string{"asn", "whois"}, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sources, err := enrich.ParseSources(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, s := range sources {
+				names = append(names, s.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/cache"
+	"github.com/bitcanon/iptool/dns"
+	"github.com/bitcanon/iptool/ip"
+	"github.com/spf13/viper"
+)
+
+// Team Cymru IP to ASN mapping service (https://www.team-cymru.com/ip-asn-mapping)
+const (
+	cymruOriginV4 = "origin.asn.cymru.com"
+	cymruOriginV6 = "origin6.asn.cymru.com"
+	cymruASN      = "asn.cymru.com"
+)
+
+// asnCacheTTL is the time an ASN lookup is kept in the cache
+const asnCacheTTL = 24 * time.Hour
+
+// DefaultDNSBLs are the DNS blocklists used by the rep source unless the
+// enrich.dnsbl configuration key is set
+var DefaultDNSBLs = []string{"zen.spamhaus.org", "bl.spamcop.net"}
+
+// ErrNotFound is returned when the ASN mapping service has no data for an
+// address (e.g. private addresses), which is not reported as an error
+var ErrNotFound = errors.New("not found")
+
+// cymruOrigin holds the fields of an origin TXT record of Team Cymru
+type cymruOrigin struct {
+	ASN      string `json:"asn"`
+	Prefix   string `json:"prefix"`
+	Country  string `json:"country"`
+	Registry string `json:"registry"`
+	ASName   string `json:"as_name"`
+}
+
+func init() {
+	Register(Source{Name: "rdns", Aliases: []string{"ptr"}, Columns: []string{"rdns"}, Lookup: lookupRDNS})
+	Register(Source{Name: "asn", Columns: []string{"asn", "as_name", "prefix"}, Lookup: lookupASN})
+	Register(Source{Name: "geo", Columns: []string{"country", "registry"}, Lookup: lookupGeo})
+	Register(Source{Name: "rep", Columns: []string{"listed"}, Lookup: lookupReputation})
+	Register(Source{Name: "classification", Aliases: []string{"class"}, Columns: []string{"classification"}, Lookup: lookupClassification})
+}
+
+// lookupClassification classifies the address (e.g. Private, Loopback or
+// Global unicast), without any network lookups
+func lookupClassification(ctx context.Context, addr netip.Addr, r *Result) error {
+	r.Class = ip.Classify(addr)
+	return nil
+}
+
+// lookupRDNS looks up the host names (PTR records) of the address
+func lookupRDNS(ctx context.Context, addr netip.Addr, r *Result) error {
+	names, err := ip.ReverseLookup(addr.String())
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.RDNS = names
+	return nil
+}
+
+// lookupASN looks up the origin AS and the announced prefix of the address
+func lookupASN(ctx context.Context, addr netip.Addr, r *Result) error {
+	origin, err := lookupCymru(ctx, addr)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.ASN, r.ASName, r.Prefix = origin.ASN, origin.ASName, origin.Prefix
+	return nil
+}
+
+// lookupGeo looks up the country and the registry that the address is allocated to.
+// The country is the one registered with the regional internet registry.
+func lookupGeo(ctx context.Context, addr netip.Addr, r *Result) error {
+	origin, err := lookupCymru(ctx, addr)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.Country, r.Registry = origin.Country, origin.Registry
+	return nil
+}
+
+// lookupCymru looks up the origin of the address using the DNS interface of
+// the Team Cymru IP to ASN mapping service. The result is cached.
+func lookupCymru(ctx context.Context, addr netip.Addr) (cymruOrigin, error) {
+	if ip.LookupsDisabled() {
+		return cymruOrigin{}, ip.ErrLookupsDisabled
+	}
+
+	return cache.Remember(cache.NamespaceASN, addr.String(), asnCacheTTL, func() (cymruOrigin, error) {
+		// Look up the origin AS of the address
+		zone := cymruOriginV4
+		if addr.Is6() {
+			zone = cymruOriginV6
+		}
+		records, err := net.DefaultResolver.LookupTXT(ctx, dns.ReverseLabels(addr)+"."+zone)
+		if err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				return cymruOrigin{}, ErrNotFound
+			}
+			return cymruOrigin{}, err
+		}
+		origin, err := parseCymruOrigin(records)
+		if err != nil {
+			return cymruOrigin{}, err
+		}
+
+		// Look up the name of the AS, a missing name is not an error
+		records, err = net.DefaultResolver.LookupTXT(ctx, "AS"+origin.ASN+"."+cymruASN)
+		if err == nil {
+			origin.ASName = parseCymruASName(records)
+		}
+		return origin, nil
+	})
+}
+
+// parseCymruOrigin parses the origin TXT records of Team Cymru, e.g.
+// "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11". If the address is
+// announced by several origins, the first one is used.
+func parseCymruOrigin(records []string) (cymruOrigin, error) {
+	for _, record := range records {
+		fields := strings.Split(record, "|")
+		if len(fields) < 4 {
+			continue
+		}
+		asns := strings.Fields(fields[0])
+		if len(asns) == 0 {
+			continue
+		}
+		return cymruOrigin{
+			ASN:      asns[0],
+			Prefix:   strings.TrimSpace(fields[1]),
+			Country:  strings.TrimSpace(fields[2]),
+			Registry: strings.TrimSpace(fields[3]),
+		}, nil
+	}
+	return cymruOrigin{}, fmt.Errorf("invalid origin record: %q", records)
+}
+
+// parseCymruASName parses the AS TXT records of Team Cymru, e.g.
+// "13335 | US | arin | 2010-07-14 | CLOUDFLARENET, US", and returns the name
+func parseCymruASName(records []string) string {
+	for _, record := range records {
+		fields := strings.Split(record, "|")
+		if len(fields) >= 5 {
+			return strings.TrimSpace(fields[4])
+		}
+	}
+	return ""
+}
+
+// lookupReputation checks if the address is listed in the DNS blocklists
+// configured in enrich.dnsbl (or the default blocklists)
+func lookupReputation(ctx context.Context, addr netip.Addr, r *Result) error {
+	if ip.LookupsDisabled() {
+		return ip.ErrLookupsDisabled
+	}
+
+	blocklists := viper.GetStringSlice("enrich.dnsbl")
+	if len(blocklists) == 0 {
+		blocklists = DefaultDNSBLs
+	}
+
+	var errs []error
+	for _, blocklist := range blocklists {
+		listing := dns.CheckBlocklist(ctx, net.DefaultResolver, addr, blocklist)
+		if listing.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", blocklist, listing.Error))
+			continue
+		}
+		if listing.Listed {
+			r.Listed = append(r.Listed, blocklist)
+		}
+	}
+	return errors.Join(errs...)
+}
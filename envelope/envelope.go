@@ -0,0 +1,184 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package envelope defines the JSON records that iptool commands write with
+// --format json and read with --from-json, so that the output of one command
+// can be piped into another:
+//
+//	{"schema": "iptool/v1", "kind": "subnet", "target": "10.0.0.0/26", "data": {...}}
+//
+// A stream is one record per line (JSON Lines). The target is the address or
+// prefix the record is about, which is what the next command in the pipeline
+// works on, and the data is the command specific result.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Schema is the schema version of the records
+const Schema = "iptool/v1"
+
+// The kinds of records written by the commands
+const (
+	KindSubnet = "subnet"
+	KindHost   = "host"
+)
+
+// targetFields are the fields that are used as the target of JSON objects
+// that are not records (e.g. the output of enrich --format json or of
+// other tools), in order of preference
+var targetFields = []string{"target", "address", "ip", "prefix", "input"}
+
+// Record is a record of a JSON stream
+type Record struct {
+	Schema string          `json:"schema"`
+	Kind   string          `json:"kind"`
+	Target string          `json:"target"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Writer writes records to a JSON stream
+type Writer struct {
+	encoder *json.Encoder
+}
+
+// NewWriter is a function that returns a writer of records to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: json.NewEncoder(w)}
+}
+
+// Write is a function that writes a record of the kind about the target,
+// with data as the command specific result
+func (w *Writer) Write(kind, target string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return w.encoder.Encode(Record{Schema: Schema, Kind: kind, Target: target, Data: raw})
+}
+
+// Read is a function that reads a JSON stream and calls fn for every
+// record. The stream is a sequence of JSON values (e.g. JSON Lines) or a
+// JSON array. Objects that are not records are accepted as well, their
+// target is taken from the first field of target, address, ip, prefix or
+// input, and the whole object is the data. Plain strings are used as
+// targets.
+func Read(r io.Reader, fn func(Record) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid JSON input: %w", err)
+		}
+
+		// Read the elements of an array one by one
+		values := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			values = nil
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return fmt.Errorf("invalid JSON input: %w", err)
+			}
+		}
+
+		for _, value := range values {
+			record, err := parseRecord(value)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", n, err)
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ReadTargets is a function that returns the targets of the records of a
+// JSON stream, see Read
+func ReadTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	err := Read(r, func(record Record) error {
+		targets = append(targets, record.Target)
+		return nil
+	})
+	return targets, err
+}
+
+// parseRecord is a function that parses a JSON value as a record
+func parseRecord(raw json.RawMessage) (Record, error) {
+	// A plain string is a target
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return Record{Schema: Schema, Target: s}, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Record{}, errors.New("expected a JSON object or string")
+	}
+
+	// A record written by iptool
+	if string(fields["schema"]) == `"`+Schema+`"` {
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return Record{}, err
+		}
+		if record.Target == "" {
+			return Record{}, errors.New("missing target")
+		}
+		return record, nil
+	}
+
+	// Any other object, take the target from the well-known fields
+	for _, field := range targetFields {
+		var target string
+		if json.Unmarshal(fields[field], &target) == nil && target != "" {
+			return Record{Schema: Schema, Target: target, Data: raw}, nil
+		}
+	}
+	return Record{}, fmt.Errorf("missing target (expected one of the fields %v)", targetFields)
+}
+
+// IsJSON is a function that reports whether the buffered input starts with
+// a JSON object or array, without consuming the input
+func IsJSON(r *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := r.Peek(i)
+		if err != nil || len(b) < i {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
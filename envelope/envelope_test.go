@@ -0,0 +1,97 @@
+package envelope_test
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/envelope"
+)
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	writer := envelope.NewWriter(&buf)
+	writer.Write(envelope.KindSubnet, "10.0.0.0/26", map[string]int{"hosts": 62})
+	writer.Write(envelope.KindHost, "fe80::1", map[string]string{"mac": "00:11:22:33:44:55"})
+
+	expected := `{"schema":"iptool/v1","kind":"subnet","target":"10.0.0.0/26","data":{"hosts":62}}` + "\n" +
+		`{"schema":"iptool/v1","kind":"host","target":"fe80::1","data":{"mac":"00:11:22:33:44:55"}}` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, buf.String())
+	}
+
+	var kinds []string
+	err := envelope.Read(&buf, func(r envelope.Record) error {
+		kinds = append(kinds, r.Kind)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kinds, []string{envelope.KindSubnet, envelope.KindHost}) {
+		t.Errorf("expected kinds subnet and host, got %v", kinds)
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "Records", input: `{"schema":"iptool/v1","kind":"subnet","target":"10.0.0.0/26"}` + "\n" + `{"schema":"iptool/v1","kind":"subnet","target":"10.0.0.64/26"}`, expected: []string{"10.0.0.0/26", "10.0.0.64/26"}},
+		{name: "Array", input: `[{"schema":"iptool/v1","target":"10.0.0.1"}, "10.0.0.2"]`, expected: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "Enrich output", input: `{"input":"1.1.1.1","ip":"1.1.1.1","prefix":"1.1.1.0/24"}`, expected: []string{"1.1.1.1"}},
+		{name: "Other objects", input: `{"address":"10.0.0.1","name":"gw"} {"prefix":"10.1.0.0/16"}`, expected: []string{"10.0.0.1", "10.1.0.0/16"}},
+		{name: "Empty", input: "", expected: nil},
+		{name: "Missing target", input: `{"name":"gw"}`, expectErr: true},
+		{name: "Record without target", input: `{"schema":"iptool/v1","kind":"host"}`, expectErr: true},
+		{name: "Invalid", input: `{"target":`, expectErr: true},
+		{name: "Number", input: `42`, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			targets, err := envelope.ReadTargets(strings.NewReader(tc.input))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && !reflect.DeepEqual(targets, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, targets)
+			}
+		})
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{input: `{"target":"10.0.0.1"}`, expected: true},
+		{input: "\n  [\"10.0.0.1\"]", expected: true},
+		{input: "10.0.0.0/24\n", expected: false},
+		{input: "", expected: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.input))
+			if result := envelope.IsJSON(r); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+
+			// The input is not consumed
+			if rest, _ := r.ReadString(0); rest != tc.input {
+				t.Errorf("expected input %q to be unread, got %q", tc.input, rest)
+			}
+		})
+	}
+}
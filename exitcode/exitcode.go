@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package exitcode
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// The exit codes of iptool. Scripts can rely on these codes to tell the
+// kinds of failure apart, the codes will not change between versions.
+const (
+	// OK is the exit code of a command that succeeded
+	OK = 0
+	// Failure is the exit code of any other error
+	Failure = 1
+	// Usage is the exit code of invalid arguments, flags or input
+	Usage = 2
+	// Unreachable is the exit code of a host or network that cannot be reached
+	// (connection refused, no route, name not found)
+	Unreachable = 3
+	// Timeout is the exit code of a host that did not respond in time
+	Timeout = 4
+	// Partial is the exit code of a command that ran, but found failures:
+	// some targets failed, or a check found problems (violations, changes,
+	// alerts or invalid input lines)
+	Partial = 5
+)
+
+// kinds are the names of the exit codes, used in the JSON error output
+var kinds = map[int]string{
+	OK:          "ok",
+	Failure:     "error",
+	Usage:       "usage",
+	Unreachable: "unreachable",
+	Timeout:     "timeout",
+	Partial:     "partial",
+}
+
+// Kind is a function that returns the name of an exit code (e.g. timeout)
+func Kind(code int) string {
+	if kind, ok := kinds[code]; ok {
+		return kind
+	}
+	return "error"
+}
+
+// Error is an error with the exit code of the command
+type Error struct {
+	Code int
+	Err  error
+}
+
+// Error is a function that returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap is a function that returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New is a function that wraps an error with an exit code
+func New(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Classify is a function that returns the exit code of an error. Errors
+// wrapped with New keep their code (invalid arguments, flags and input are
+// wrapped with Usage where they are found), and timeouts and unreachable
+// hosts are recognized from the network errors. Anything else is a Failure.
+func Classify(err error) int {
+	if err == nil {
+		return OK
+	}
+
+	// The code given by the command
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	// Timeouts
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout
+	}
+
+	// Hosts and networks that cannot be reached. Other network errors (e.g.
+	// an address already in use by a listener) are not about the target.
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) ||
+		errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+		return Unreachable
+	}
+
+	return Failure
+}
@@ -0,0 +1,53 @@
+package exitcode_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/bitcanon/iptool/exitcode"
+)
+
+func TestClassify(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "Nil", err: nil, expected: exitcode.OK},
+		{name: "Other", err: errors.New("something failed"), expected: exitcode.Failure},
+		{name: "Explicit", err: exitcode.New(exitcode.Partial, errors.New("invalid checksum")), expected: exitcode.Partial},
+		{name: "ExplicitWrapped", err: fmt.Errorf("file: %w", exitcode.New(exitcode.Timeout, errors.New("slow"))), expected: exitcode.Timeout},
+		{name: "Usage", err: exitcode.New(exitcode.Usage, errors.New("invalid prefix: 10.0.0.0/33")), expected: exitcode.Usage},
+		{name: "InvalidResponse", err: errors.New("invalid response from the remote host"), expected: exitcode.Failure},
+		{name: "NumError", err: fmt.Errorf("results: %w", &strconv.NumError{Func: "Atoi", Num: "x", Err: strconv.ErrSyntax}), expected: exitcode.Failure},
+		{name: "Deadline", err: fmt.Errorf("probe: %w", context.DeadlineExceeded), expected: exitcode.Timeout},
+		{name: "DNSTimeout", err: &net.DNSError{Err: "timeout", Name: "example.com", IsTimeout: true}, expected: exitcode.Timeout},
+		{name: "DNSNotFound", err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, expected: exitcode.Unreachable},
+		{name: "Refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, expected: exitcode.Unreachable},
+		{name: "DialFailed", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network is down")}, expected: exitcode.Unreachable},
+		{name: "AddressInUse", err: &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}, expected: exitcode.Failure},
+		{name: "NoRoute", err: fmt.Errorf("connect: %w", syscall.EHOSTUNREACH), expected: exitcode.Unreachable},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitcode.Classify(tc.err); got != tc.expected {
+				t.Errorf("expected %d (%s), got %d (%s)", tc.expected, exitcode.Kind(tc.expected), got, exitcode.Kind(got))
+			}
+		})
+	}
+}
+
+func TestNewNil(t *testing.T) {
+	if err := exitcode.New(exitcode.Partial, nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
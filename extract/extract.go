@@ -0,0 +1,318 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package extract
+
+import (
+	"container/list"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ipv4Candidate matches anything that looks like a dotted-decimal IPv4 address
+var ipv4Candidate = regexp.MustCompile(`[0-9]{1,3}(?:\.[0-9]{1,3}){3}`)
+
+// ipv6Candidate matches runs of hexadecimal digits, colons and dots with at
+// least two colons, which are then validated by parsing them
+var ipv6Candidate = regexp.MustCompile(`[0-9A-Fa-f.]*:[0-9A-Fa-f.]*:[0-9A-Fa-f:.]*`)
+
+// match is an address found in a line, with its position in the line
+type match struct {
+	start, end int
+	addr       netip.Addr
+}
+
+// Find is a function that returns the IPv4 and IPv6 addresses found in a
+// line of text, in the order they appear. Addresses that are part of a
+// longer sequence of digits and dots (such as version numbers) are ignored.
+func Find(line string) []netip.Addr {
+	matches := findMatches(line)
+	addrs := make([]netip.Addr, len(matches))
+	for i, m := range matches {
+		addrs[i] = m.addr
+	}
+	return addrs
+}
+
+// Replace is a function that returns the line with every IPv4 and IPv6
+// address found in it (see Find) replaced by the address returned by fn.
+// The rest of the line is left untouched.
+func Replace(line string, fn func(netip.Addr) netip.Addr) string {
+	matches := findMatches(line)
+	if len(matches) == 0 {
+		return line
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(line[last:m.start])
+		b.WriteString(fn(m.addr).String())
+		last = m.end
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// Token is an address found in a line of text, together with the prefix
+// length written after it (as in 10.0.0.0/8) and the port written after it
+// (as in 10.0.0.1:443 or [2001:db8::1]:443), if any
+type Token struct {
+	Addr netip.Addr
+	Bits int // Prefix length, or -1 if there is none
+	Port int // Port number, or 0 if there is none
+}
+
+// Prefix is a function that returns the prefix of the token, and whether
+// a prefix length was found after the address
+func (t Token) Prefix() (netip.Prefix, bool) {
+	if t.Bits < 0 {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(t.Addr, t.Bits), true
+}
+
+// String is a function that returns the token as it would be written in a
+// text, i.e. the address followed by the prefix length or the port
+func (t Token) String() string {
+	switch {
+	case t.Bits >= 0:
+		return t.Addr.String() + "/" + strconv.Itoa(t.Bits)
+	case t.Port > 0:
+		return netip.AddrPortFrom(t.Addr, uint16(t.Port)).String()
+	}
+	return t.Addr.String()
+}
+
+// Compare is a function that compares two tokens by address, then by prefix
+// length and then by port, it returns -1, 0 or 1
+func (t Token) Compare(u Token) int {
+	if c := t.Addr.Compare(u.Addr); c != 0 {
+		return c
+	}
+	switch {
+	case t.Bits != u.Bits:
+		return compareInt(t.Bits, u.Bits)
+	case t.Port != u.Port:
+		return compareInt(t.Port, u.Port)
+	}
+	return 0
+}
+
+// compareInt is a function that compares two integers, it returns -1, 0 or 1
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// FindTokens is a function that returns the addresses found in a line of
+// text (see Find) in the order they appear, with the prefix length or the
+// port written directly after them. A prefix length is only recognized if
+// it is valid for the address family, and a port if it is in 1-65535.
+func FindTokens(line string) []Token {
+	matches := findMatches(line)
+	tokens := make([]Token, len(matches))
+	for i, m := range matches {
+		tokens[i] = Token{Addr: m.addr, Bits: -1}
+
+		// Look for a prefix length, e.g. 10.0.0.0/8 or 2001:db8::/32
+		if bits, ok := number(line, m.end, '/'); ok && bits <= m.addr.BitLen() {
+			tokens[i].Bits = bits
+			continue
+		}
+
+		// Look for a port, IPv6 addresses must be written in brackets
+		// since the port would be part of the address otherwise
+		end := m.end
+		if m.addr.Is6() && !m.addr.Is4In6() {
+			if m.start == 0 || line[m.start-1] != '[' || end >= len(line) || line[end] != ']' {
+				continue
+			}
+			end++
+		}
+		if port, ok := number(line, end, ':'); ok && port > 0 && port <= 65535 {
+			tokens[i].Port = port
+		}
+	}
+	return tokens
+}
+
+// number is a function that parses the decimal number following the
+// separator at position pos of a line, e.g. the 24 in "/24". The number
+// must have at most 5 digits and must not be followed by another digit.
+func number(line string, pos int, sep byte) (int, bool) {
+	if pos >= len(line) || line[pos] != sep {
+		return 0, false
+	}
+	start := pos + 1
+	end := start
+	for end < len(line) && isDigit(line[end]) {
+		end++
+	}
+	if end == start || end-start > 5 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[start:end])
+	return n, err == nil
+}
+
+// findMatches is a function that returns the addresses found in a line of
+// text with their positions, in the order they appear
+func findMatches(line string) []match {
+	var matches []match
+
+	// Find the IPv6 addresses, including the ones with an embedded IPv4 address
+	for _, loc := range ipv6Candidate.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+
+		// Strip the punctuation that ends a sentence or a host:port pair
+		for end > start && line[end-1] == '.' {
+			end--
+		}
+		if end-start > 1 && line[end-1] == ':' && line[end-2] != ':' {
+			end--
+		}
+
+		// Skip candidates that are part of a word, such as std::string
+		if (start > 0 && isAlphanumeric(line[start-1])) || (end < len(line) && isAlphanumeric(line[end])) {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(line[start:end]); err == nil && hasHexDigit(line[start:end]) {
+			matches = append(matches, match{start: start, end: end, addr: addr})
+		}
+	}
+
+	// Find the IPv4 addresses that are not part of an IPv6 address
+	for _, loc := range ipv4Candidate.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		if !isBoundary(line, start, end) || within(matches, start) {
+			continue
+		}
+		if addr, err := netip.ParseAddr(line[start:end]); err == nil {
+			matches = append(matches, match{start: start, end: end, addr: addr})
+		}
+	}
+
+	// Return the addresses in the order they appear in the line
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	return matches
+}
+
+// isBoundary is a function that checks that an IPv4 candidate is not
+// preceded or followed by more digits or dot-separated digits
+func isBoundary(line string, start, end int) bool {
+	if start > 0 && (isDigit(line[start-1]) || line[start-1] == '.') {
+		return false
+	}
+	if end < len(line) && isDigit(line[end]) {
+		return false
+	}
+	if end+1 < len(line) && line[end] == '.' && isDigit(line[end+1]) {
+		return false
+	}
+	return true
+}
+
+// within is a function that checks if a position is inside one of the matches
+func within(matches []match, pos int) bool {
+	for _, m := range matches {
+		if pos >= m.start && pos < m.end {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigit is a function that checks if a byte is a decimal digit
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// isAlphanumeric is a function that checks if a byte is a letter or a digit
+func isAlphanumeric(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// hasHexDigit is a function that checks if a string contains a hexadecimal
+// digit, so that a lone "::" in the text is not reported as an address
+func hasHexDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isDigit(s[i]) || (s[i] >= 'a' && s[i] <= 'f') || (s[i] >= 'A' && s[i] <= 'F') {
+			return true
+		}
+	}
+	return false
+}
+
+// Window is a set of recently seen addresses (or tokens) with a bounded
+// size. When the window is full, the least recently seen address is
+// forgotten, so the memory used stays bounded when following a log file for
+// a long time.
+type Window[K comparable] struct {
+	size     int
+	order    *list.List
+	elements map[K]*list.Element
+}
+
+// NewWindow is a function that returns a window remembering at most size
+// addresses, a size of 0 (or less) means that the window is unbounded
+func NewWindow[K comparable](size int) *Window[K] {
+	return &Window[K]{
+		size:     size,
+		order:    list.New(),
+		elements: make(map[K]*list.Element),
+	}
+}
+
+// Seen is a function that reports whether the address is in the window, and
+// remembers it as the most recently seen address
+func (w *Window[K]) Seen(addr K) bool {
+	if e, ok := w.elements[addr]; ok {
+		w.order.MoveToFront(e)
+		return true
+	}
+
+	w.elements[addr] = w.order.PushFront(addr)
+
+	// Forget the least recently seen address when the window is full
+	if w.size > 0 && w.order.Len() > w.size {
+		oldest := w.order.Back()
+		w.order.Remove(oldest)
+		delete(w.elements, oldest.Value.(K))
+	}
+	return false
+}
+
+// Len is a function that returns the number of addresses in the window
+func (w *Window[K]) Len() int {
+	return w.order.Len()
+}
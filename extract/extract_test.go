@@ -0,0 +1,251 @@
+package extract_test
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/extract"
+)
+
+func TestFind(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{name: "Empty", line: "", expected: nil},
+		{name: "NoAddress", line: "Connection closed by authenticating user", expected: nil},
+		{name: "IPv4", line: "Failed password for root from 203.0.113.7 port 52214 ssh2", expected: []string{"203.0.113.7"}},
+		{name: "IPv4Port", line: "src=10.0.0.1:443 dst=10.0.0.2:51234", expected: []string{"10.0.0.1", "10.0.0.2"}},
+		{name: "IPv4EndOfSentence", line: "Blocked 192.0.2.1.", expected: []string{"192.0.2.1"}},
+		{name: "IPv4Invalid", line: "from 300.1.1.1", expected: nil},
+		{name: "VersionNumber", line: "version 1.2.3.4.5 and 1.2.3.4", expected: []string{"1.2.3.4"}},
+		{name: "Timestamp", line: "Mar 12 12:34:56 host sshd[123]: from 198.51.100.9", expected: []string{"198.51.100.9"}},
+		{name: "IPv6", line: "Accepted publickey from 2001:db8::1 port 22", expected: []string{"2001:db8::1"}},
+		{name: "IPv6Bracketed", line: "GET [2001:db8::cafe]:8080/index.html", expected: []string{"2001:db8::cafe"}},
+		{name: "IPv6Colon", line: "client 2001:db8::2: connection reset", expected: []string{"2001:db8::2"}},
+		{name: "IPv6EmbeddedIPv4", line: "peer ::ffff:192.0.2.10 connected", expected: []string{"::ffff:192.0.2.10"}},
+		{name: "DoubleColon", line: "std::string is not an address", expected: nil},
+		{name: "PartOfWord", line: "ns::abc::def", expected: nil},
+		{name: "Mixed", line: "2001:db8::1 -> 10.1.2.3 -> fe80::1%eth0", expected: []string{"2001:db8::1", "10.1.2.3", "fe80::1"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, addr := range extract.Find(tc.line) {
+				got = append(got, addr.String())
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFindTokens(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{name: "Empty", line: "", expected: nil},
+		{name: "Address", line: "from 203.0.113.7 port 22", expected: []string{"203.0.113.7"}},
+		{name: "IPv4Prefix", line: "route 10.0.0.0/8 via 192.0.2.1", expected: []string{"10.0.0.0/8", "192.0.2.1"}},
+		{name: "IPv4PrefixTooLong", line: "route 10.0.0.0/33", expected: []string{"10.0.0.0"}},
+		{name: "IPv4HostPrefix", line: "inet 192.168.1.10/24 brd 192.168.1.255", expected: []string{"192.168.1.10/24", "192.168.1.255"}},
+		{name: "IPv6Prefix", line: "allow 2001:db8::/32;", expected: []string{"2001:db8::/32"}},
+		{name: "IPv6PrefixTooLong", line: "allow 2001:db8::/129;", expected: []string{"2001:db8::"}},
+		{name: "IPv4Port", line: "src=10.0.0.1:443 dst=10.0.0.2:51234", expected: []string{"10.0.0.1:443", "10.0.0.2:51234"}},
+		{name: "IPv4PortInvalid", line: "src=10.0.0.1:70000", expected: []string{"10.0.0.1"}},
+		{name: "IPv4PortZero", line: "src=10.0.0.1:0", expected: []string{"10.0.0.1"}},
+		{name: "IPv6Port", line: "GET [2001:db8::cafe]:8080/index.html", expected: []string{"[2001:db8::cafe]:8080"}},
+		{name: "IPv6Bracketed", line: "listen [::1];", expected: []string{"::1"}},
+		{name: "IPv6Colon", line: "client 2001:db8::2: connection reset", expected: []string{"2001:db8::2"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, token := range extract.FindTokens(tc.line) {
+				got = append(got, token.String())
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTokenCompare(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		a, b     string
+		expected int
+	}{
+		{name: "Equal", a: "10.0.0.1", b: "10.0.0.1", expected: 0},
+		{name: "Address", a: "10.0.0.1", b: "10.0.0.2", expected: -1},
+		{name: "Family", a: "2001:db8::1", b: "10.0.0.1", expected: 1},
+		{name: "AddressBeforePrefix", a: "10.0.0.0", b: "10.0.0.0/8", expected: -1},
+		{name: "PrefixLength", a: "10.0.0.0/16", b: "10.0.0.0/8", expected: 1},
+		{name: "Port", a: "10.0.0.1:80", b: "10.0.0.1:443", expected: -1},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := extract.FindTokens(tc.a)[0]
+			b := extract.FindTokens(tc.b)[0]
+			if got := a.Compare(b); got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	// Replace every address with the first address of its family
+	replace := func(addr netip.Addr) netip.Addr {
+		if addr.Is4() {
+			return netip.MustParseAddr("0.0.0.0")
+		}
+		return netip.IPv6Unspecified()
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "Empty", line: "", expected: ""},
+		{name: "NoAddress", line: "version 1.2.3.4.5", expected: "version 1.2.3.4.5"},
+		{name: "IPv4Port", line: "src=10.0.0.1:443 dst=10.0.0.2:51234", expected: "src=0.0.0.0:443 dst=0.0.0.0:51234"},
+		{name: "IPv6Bracketed", line: "GET [2001:db8::cafe]:8080/", expected: "GET [::]:8080/"},
+		{name: "Mixed", line: "2001:db8::1 -> 10.1.2.3 -> fe80::1%eth0.", expected: ":: -> 0.0.0.0 -> ::%eth0."},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := extract.Replace(tc.line, replace); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	a := netip.MustParseAddr("10.0.0.1")
+	b := netip.MustParseAddr("10.0.0.2")
+	c := netip.MustParseAddr("10.0.0.3")
+
+	w := extract.NewWindow[netip.Addr](2)
+
+	// Setup test cases, in order
+	testCases := []struct {
+		addr     netip.Addr
+		expected bool
+	}{
+		{addr: a, expected: false},
+		{addr: b, expected: false},
+		{addr: a, expected: true},
+		{addr: c, expected: false}, // Forgets b, the least recently seen
+		{addr: a, expected: true},
+		{addr: b, expected: false},
+	}
+
+	// Run test cases
+	for i, tc := range testCases {
+		if got := w.Seen(tc.addr); got != tc.expected {
+			t.Errorf("step %d: expected Seen(%s) = %v, got %v", i, tc.addr, tc.expected, got)
+		}
+	}
+	if w.Len() != 2 {
+		t.Errorf("expected window length 2, got %d", w.Len())
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte("old line\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- extract.Follow(ctx, path, 10*time.Millisecond, func(line string) {
+			lines <- line
+		})
+	}()
+
+	// expect is a helper that waits for the next line
+	expect := func(expected string) {
+		t.Helper()
+		select {
+		case line := <-lines:
+			if line != expected {
+				t.Fatalf("expected line %q, got %q", expected, line)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for line %q", expected)
+		}
+	}
+
+	// appendFile is a helper that appends data to the log file
+	appendFile := func(data string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fmt.Fprint(f, data)
+		f.Close()
+	}
+
+	// Give Follow time to skip the current content
+	time.Sleep(50 * time.Millisecond)
+
+	// Lines are reported when they are complete
+	appendFile("first ")
+	time.Sleep(50 * time.Millisecond)
+	appendFile("line\nsecond line\n")
+	expect("first line")
+	expect("second line")
+
+	// A truncated file is read from the start
+	if err := os.WriteFile(path, []byte("truncated\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect("truncated")
+
+	// A replaced (rotated) file is read from the start
+	rotated := path + ".new"
+	if err := os.WriteFile(rotated, []byte("rotated\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Rename(rotated, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect("rotated")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package extract
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Follow is a function that follows a growing file, like tail -f, and calls
+// the function for every line appended to the file. Reading starts at the
+// end of the file. The file is reopened when it is truncated or replaced
+// (log rotation). Follow returns when the context is cancelled.
+func Follow(ctx context.Context, path string, interval time.Duration, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	// Skip the current content of the file
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+
+	// partial holds a line that has not been terminated by a newline yet
+	var partial strings.Builder
+
+	for {
+		// Read all complete lines that are available
+		for {
+			chunk, err := reader.ReadString('\n')
+			offset += int64(len(chunk))
+			partial.WriteString(chunk)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					return err
+				}
+				break
+			}
+			fn(strings.TrimRight(partial.String(), "\r\n"))
+			partial.Reset()
+		}
+
+		// Wait for more data to be written
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		// Check if the file has been rotated or truncated
+		current, err := os.Stat(path)
+		if err != nil {
+			// The file is being rotated, try again later
+			continue
+		}
+		opened, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(current, opened) {
+			// The file has been replaced, read the new file from the start
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file = newFile
+			reader.Reset(file)
+			offset = 0
+			partial.Reset()
+		} else if current.Size() < offset {
+			// The file has been truncated, read it again from the start
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			offset = 0
+			partial.Reset()
+		}
+	}
+}
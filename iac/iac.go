@@ -0,0 +1,257 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package iac writes the addressing plans of the subnet planning commands in
+// formats that infrastructure as code tools read directly: an Ansible
+// inventory in YAML format, where the records are a variable of the all
+// group (and the devices are hosts with their own variables), or a Terraform
+// variable definitions (.tfvars) file, where the records are a list of
+// objects assigned to a variable:
+//
+//	subnets = [
+//	  {
+//	    prefix = "10.0.0.0/26"
+//	    hosts  = 62
+//	  },
+//	]
+//
+// The records are written one at a time, so that large plans are streamed.
+package iac
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the written files
+const (
+	FormatAnsibleInventory = "ansible-inventory"
+	FormatTerraformTFVars  = "terraform-tfvars"
+)
+
+// Formats is the list of the infrastructure as code formats
+var Formats = []string{FormatAnsibleInventory, FormatTerraformTFVars}
+
+// Field is a field of a record. The value is a string, an integer or a list
+// of records.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Record is a record of an addressing plan, a list of fields in order
+type Record []Field
+
+// Writer writes the records of an addressing plan as the variable of an
+// Ansible inventory or a Terraform variable definitions file
+type Writer struct {
+	w        io.Writer
+	format   string
+	variable string
+	records  int
+	hosts    int
+	err      error
+}
+
+// NewWriter is a function that returns a writer of the records assigned to
+// the variable (e.g. subnets) in the format to w
+func NewWriter(w io.Writer, format, variable string) (*Writer, error) {
+	if format != FormatAnsibleInventory && format != FormatTerraformTFVars {
+		return nil, fmt.Errorf("invalid format: %s (must be one of %s)", format, strings.Join(Formats, ", "))
+	}
+	return &Writer{w: w, format: format, variable: variable}, nil
+}
+
+// IsFormat is a function that reports whether the format is one of the
+// infrastructure as code formats
+func IsFormat(format string) bool {
+	return format == FormatAnsibleInventory || format == FormatTerraformTFVars
+}
+
+// printf is a function that writes to the output, keeping the first error
+func (w *Writer) printf(format string, a ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, a...)
+	}
+}
+
+// Write is a function that writes a record of the variable. The records
+// must be written before the hosts.
+func (w *Writer) Write(r Record) error {
+	if w.hosts > 0 {
+		return fmt.Errorf("the records must be written before the hosts")
+	}
+	if w.records == 0 {
+		w.printf("%s", w.header())
+	}
+	w.records++
+
+	if w.format == FormatTerraformTFVars {
+		w.printf("%s,\n", hclRecord(r, 1))
+	} else {
+		w.printf("%s", yamlRecord(r, 3, true))
+	}
+	return w.err
+}
+
+// WriteHost is a function that writes a host with its variables to the
+// Ansible inventory (e.g. a router with the address of its loopback). The
+// hosts are left out of Terraform variable definitions, where the records
+// of the variable are used instead.
+func (w *Writer) WriteHost(name string, vars Record) error {
+	if w.format != FormatAnsibleInventory {
+		return nil
+	}
+	if w.hosts == 0 {
+		w.closeRecords()
+		w.printf("  hosts:\n")
+	}
+	w.hosts++
+
+	w.printf("    %s:", yamlScalar(name))
+	if len(vars) == 0 {
+		w.printf(" {}\n")
+		return w.err
+	}
+	w.printf("\n%s", yamlRecord(vars, 3, false))
+	return w.err
+}
+
+// Close is a function that ends the variable (and writes an empty list if
+// no records were written), it does not close the underlying writer
+func (w *Writer) Close() error {
+	if w.hosts == 0 {
+		w.closeRecords()
+	}
+	return w.err
+}
+
+// header is a function that returns the start of the list of records
+func (w *Writer) header() string {
+	if w.format == FormatTerraformTFVars {
+		return w.variable + " = [\n"
+	}
+	return "all:\n  vars:\n    " + w.variable + ":\n"
+}
+
+// closeRecords is a function that ends the list of records
+func (w *Writer) closeRecords() {
+	switch {
+	case w.format == FormatTerraformTFVars && w.records == 0:
+		w.printf("%s = []\n", w.variable)
+	case w.format == FormatTerraformTFVars:
+		w.printf("]\n")
+	case w.records == 0:
+		w.printf("all:\n  vars:\n    %s: []\n", w.variable)
+	}
+}
+
+// yamlScalar is a function that returns a value as a YAML scalar, quoted
+// where needed (e.g. yes, 1.0 or names with a colon)
+func yamlScalar(v any) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(v))
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// yamlRecord is a function that returns a record as a YAML mapping indented
+// by depth levels of two spaces, as an item of a list if item is set
+func yamlRecord(r Record, depth int, item bool) string {
+	var sb strings.Builder
+	indent := strings.Repeat("  ", depth)
+	for i, field := range r {
+		prefix := indent
+		if item {
+			prefix = indent + "  "
+			if i == 0 {
+				prefix = indent + "- "
+			}
+		}
+		records, isList := field.Value.([]Record)
+		switch {
+		case isList && len(records) == 0:
+			fmt.Fprintf(&sb, "%s%s: []\n", prefix, field.Key)
+		case isList:
+			// The items are indented below the key of the list
+			fmt.Fprintf(&sb, "%s%s:\n", prefix, field.Key)
+			itemDepth := depth + 1
+			if item {
+				itemDepth++
+			}
+			for _, record := range records {
+				sb.WriteString(yamlRecord(record, itemDepth, true))
+			}
+		default:
+			fmt.Fprintf(&sb, "%s%s: %s\n", prefix, field.Key, yamlScalar(field.Value))
+		}
+	}
+	return sb.String()
+}
+
+// hclString is a function that returns a string as a quoted HCL string, the
+// template sequences ${ and %{ are escaped
+func hclString(s string) string {
+	s = strconv.Quote(s)
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
+
+// hclRecord is a function that returns a record as an HCL object indented
+// by depth levels of two spaces, with the equals signs aligned like
+// terraform fmt does
+func hclRecord(r Record, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	width := 0
+	for _, field := range r {
+		width = max(width, len(field.Key))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(indent + "{\n")
+	for _, field := range r {
+		fmt.Fprintf(&sb, "%s  %-*s = ", indent, width, field.Key)
+		switch value := field.Value.(type) {
+		case string:
+			sb.WriteString(hclString(value))
+		case []Record:
+			if len(value) == 0 {
+				sb.WriteString("[]")
+				break
+			}
+			sb.WriteString("[\n")
+			for _, record := range value {
+				sb.WriteString(hclRecord(record, depth+2) + ",\n")
+			}
+			sb.WriteString(indent + "  ]")
+		default:
+			fmt.Fprint(&sb, value)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(indent + "}")
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package iac_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/iac"
+	"gopkg.in/yaml.v3"
+)
+
+// records are the records written by the tests
+var records = []iac.Record{
+	{{Key: "name", Value: "core"}, {Key: "prefix", Value: "10.0.0.0/26"}, {Key: "hosts", Value: 62}},
+	{{Key: "name", Value: "yes"}, {Key: "prefix", Value: "10.0.0.64/26"}, {Key: "hosts", Value: 62}},
+}
+
+func TestTerraformTFVars(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		records  []iac.Record
+		expected string
+	}{
+		{
+			name:    "Records",
+			records: records,
+			expected: `subnets = [
+  {
+    name   = "core"
+    prefix = "10.0.0.0/26"
+    hosts  = 62
+  },
+  {
+    name   = "yes"
+    prefix = "10.0.0.64/26"
+    hosts  = 62
+  },
+]
+`,
+		},
+		{name: "Empty", expected: "subnets = []\n"},
+		{
+			name:    "Escaped",
+			records: []iac.Record{{{Key: "name", Value: `a "${b}"`}, {Key: "links", Value: []iac.Record{{{Key: "peer", Value: "edge1"}}}}}},
+			expected: `subnets = [
+  {
+    name  = "a \"$${b}\""
+    links = [
+      {
+        peer = "edge1"
+      },
+    ]
+  },
+]
+`,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := iac.NewWriter(&buf, iac.FormatTerraformTFVars, "subnets")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tc.records {
+				w.Write(r)
+			}
+			w.WriteHost("core1", nil)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestAnsibleInventory(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := iac.NewWriter(&buf, iac.FormatAnsibleInventory, "subnets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		w.Write(r)
+	}
+	w.WriteHost("core1", iac.Record{{Key: "loopback", Value: "10.255.255.1/32"}, {Key: "links", Value: []iac.Record{
+		{{Key: "prefix", Value: "10.255.0.0/31"}, {Key: "peer", Value: "edge1"}},
+	}}})
+	w.WriteHost("edge1", nil)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(records[0]); err == nil {
+		t.Error("expected an error for a record written after the hosts")
+	}
+
+	// The inventory is parsed back to check the structure and the quoting
+	var inventory map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &inventory); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, buf.String())
+	}
+	expected := map[string]any{
+		"all": map[string]any{
+			"vars": map[string]any{
+				"subnets": []any{
+					map[string]any{"name": "core", "prefix": "10.0.0.0/26", "hosts": 62},
+					map[string]any{"name": "yes", "prefix": "10.0.0.64/26", "hosts": 62},
+				},
+			},
+			"hosts": map[string]any{
+				"core1": map[string]any{
+					"loopback": "10.255.255.1/32",
+					"links":    []any{map[string]any{"prefix": "10.255.0.0/31", "peer": "edge1"}},
+				},
+				"edge1": map[string]any{},
+			},
+		},
+	}
+	if !reflect.DeepEqual(inventory, expected) {
+		t.Errorf("unexpected inventory:\n%s", buf.String())
+	}
+
+	// An empty list of records is still a valid inventory
+	buf.Reset()
+	w, _ = iac.NewWriter(&buf, iac.FormatAnsibleInventory, "subnets")
+	w.Close()
+	if expected := "all:\n  vars:\n    subnets: []\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if _, err := iac.NewWriter(&buf, "puppet", "subnets"); err == nil {
+		t.Error("expected an error for an invalid format")
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"net/netip"
+)
+
+// ipv4Types maps well-known IPv4 prefixes (RFC 6890) to a description of the
+// address type. The list is ordered from the most to the least specific prefix.
+var ipv4Types = []struct {
+	prefix      netip.Prefix
+	description string
+}{
+	{netip.MustParsePrefix("255.255.255.255/32"), "Limited broadcast"},
+	{netip.MustParsePrefix("192.0.0.0/24"), "IETF protocol assignments"},
+	{netip.MustParsePrefix("192.0.2.0/24"), "Documentation"},
+	{netip.MustParsePrefix("198.51.100.0/24"), "Documentation"},
+	{netip.MustParsePrefix("203.0.113.0/24"), "Documentation"},
+	{netip.MustParsePrefix("192.88.99.0/24"), "6to4 relay anycast"},
+	{netip.MustParsePrefix("169.254.0.0/16"), "Link-local"},
+	{netip.MustParsePrefix("192.168.0.0/16"), "Private"},
+	{netip.MustParsePrefix("198.18.0.0/15"), "Benchmarking"},
+	{netip.MustParsePrefix("172.16.0.0/12"), "Private"},
+	{netip.MustParsePrefix("100.64.0.0/10"), "Shared address space (CGNAT)"},
+	{netip.MustParsePrefix("0.0.0.0/8"), "This network"},
+	{netip.MustParsePrefix("10.0.0.0/8"), "Private"},
+	{netip.MustParsePrefix("127.0.0.0/8"), "Loopback"},
+	{netip.MustParsePrefix("224.0.0.0/4"), "Multicast"},
+	{netip.MustParsePrefix("240.0.0.0/4"), "Reserved"},
+}
+
+// Classify is a function that returns a description of the type of an IPv4
+// or IPv6 address (e.g. "Private", "Loopback" or "Global unicast")
+func Classify(addr netip.Addr) string {
+	addr = addr.WithZone("")
+	if !addr.Is4() {
+		return ipv6Type(addr)
+	}
+	for _, t := range ipv4Types {
+		if t.prefix.Contains(addr) {
+			return t.description
+		}
+	}
+	return "Global unicast"
+}
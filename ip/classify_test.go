@@ -0,0 +1,47 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestClassify(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "10.1.2.3", expected: "Private"},
+		{addr: "172.31.255.255", expected: "Private"},
+		{addr: "172.32.0.1", expected: "Global unicast"},
+		{addr: "100.64.0.1", expected: "Shared address space (CGNAT)"},
+		{addr: "127.0.0.1", expected: "Loopback"},
+		{addr: "169.254.1.1", expected: "Link-local"},
+		{addr: "192.0.2.10", expected: "Documentation"},
+		{addr: "224.0.0.5", expected: "Multicast"},
+		{addr: "255.255.255.255", expected: "Limited broadcast"},
+		{addr: "8.8.8.8", expected: "Global unicast"},
+		{addr: "fe80::1%eth0", expected: "Link-local unicast"},
+		{addr: "2001:db8::1", expected: "Documentation"},
+		{addr: "2a00::1", expected: "Global unicast"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			result := ip.Classify(netip.MustParseAddr(tc.addr))
+			if result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func BenchmarkClassify(b *testing.B) {
+	addrs := []netip.Addr{netip.MustParseAddr("8.8.8.8"), netip.MustParseAddr("2001:db8::1")}
+	for i := 0; i < b.N; i++ {
+		ip.Classify(addrs[i%len(addrs)])
+	}
+}
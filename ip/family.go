@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Family is an address family filter for lists of addresses and prefixes
+type Family int
+
+const (
+	// FamilyAny matches both IPv4 and IPv6
+	FamilyAny Family = iota
+	// FamilyIPv4 matches IPv4 only (including IPv4-mapped IPv6 addresses)
+	FamilyIPv4
+	// FamilyIPv6 matches IPv6 only
+	FamilyIPv6
+)
+
+// ParseFamily is a function that returns the family filter selected by the
+// --ipv4 and --ipv6 flags, selecting both is the same as selecting none
+func ParseFamily(ipv4, ipv6 bool) Family {
+	switch {
+	case ipv4 && !ipv6:
+		return FamilyIPv4
+	case ipv6 && !ipv4:
+		return FamilyIPv6
+	default:
+		return FamilyAny
+	}
+}
+
+// String is a function that returns the name of the family
+func (f Family) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	case FamilyAny:
+		return "any"
+	}
+	return fmt.Sprintf("Family(%d)", int(f))
+}
+
+// Match is a function that checks if an address belongs to the family
+func (f Family) Match(addr netip.Addr) bool {
+	switch f {
+	case FamilyIPv4:
+		return addr.Unmap().Is4()
+	case FamilyIPv6:
+		return addr.Is6() && !addr.Is4In6()
+	}
+	return true
+}
+
+// FilterPrefixes is a function that returns the prefixes of the family
+func (f Family) FilterPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	if f == FamilyAny {
+		return prefixes
+	}
+	var result []netip.Prefix
+	for _, p := range prefixes {
+		if f.Match(p.Addr()) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
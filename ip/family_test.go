@@ -0,0 +1,83 @@
+package ip_test
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestParseFamily(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		ipv4, ipv6 bool
+		expected   ip.Family
+	}{
+		{ipv4: false, ipv6: false, expected: ip.FamilyAny},
+		{ipv4: true, ipv6: false, expected: ip.FamilyIPv4},
+		{ipv4: false, ipv6: true, expected: ip.FamilyIPv6},
+		{ipv4: true, ipv6: true, expected: ip.FamilyAny},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		if got := ip.ParseFamily(tc.ipv4, tc.ipv6); got != tc.expected {
+			t.Errorf("ParseFamily(%v, %v): expected %s, got %s", tc.ipv4, tc.ipv6, tc.expected, got)
+		}
+	}
+}
+
+func TestFamilyMatch(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		family   ip.Family
+		expected bool
+	}{
+		{addr: "10.0.0.1", family: ip.FamilyAny, expected: true},
+		{addr: "2001:db8::1", family: ip.FamilyAny, expected: true},
+		{addr: "10.0.0.1", family: ip.FamilyIPv4, expected: true},
+		{addr: "10.0.0.1", family: ip.FamilyIPv6, expected: false},
+		{addr: "2001:db8::1", family: ip.FamilyIPv4, expected: false},
+		{addr: "2001:db8::1", family: ip.FamilyIPv6, expected: true},
+		{addr: "::ffff:10.0.0.1", family: ip.FamilyIPv4, expected: true},
+		{addr: "::ffff:10.0.0.1", family: ip.FamilyIPv6, expected: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr+"/"+tc.family.String(), func(t *testing.T) {
+			if got := tc.family.Match(netip.MustParseAddr(tc.addr)); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterPrefixes(t *testing.T) {
+	prefixes, err := ip.ParsePrefixes(strings.NewReader("10.0.0.0/8 2001:db8::/32 192.168.0.0/16 fe80::/10"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		family   ip.Family
+		expected []string
+	}{
+		{family: ip.FamilyAny, expected: []string{"10.0.0.0/8", "2001:db8::/32", "192.168.0.0/16", "fe80::/10"}},
+		{family: ip.FamilyIPv4, expected: []string{"10.0.0.0/8", "192.168.0.0/16"}},
+		{family: ip.FamilyIPv6, expected: []string{"2001:db8::/32", "fe80::/10"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.family.String(), func(t *testing.T) {
+			if got := prefixStrings(tc.family.FilterPrefixes(prefixes)); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// IPv6Forms holds the different notations of an IPv6 address
+type IPv6Forms struct {
+	Compressed string `json:"compressed"`
+	Expanded   string `json:"expanded"`
+	Mixed      string `json:"mixed"`
+	Reverse    string `json:"reverse"`
+	URL        string `json:"url"`
+}
+
+// ParseIPv6Address is a function that parses a single IPv6 address in any
+// valid notation, with an optional zone (fe80::1%eth0) and optionally
+// enclosed in brackets as in URLs ([2001:db8::1]). IPv4 addresses are
+// rejected, IPv4-mapped IPv6 addresses (::ffff:192.0.2.1) are accepted.
+func ParseIPv6Address(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+
+		// The zone is percent-encoded in URLs (RFC 6874)
+		s = strings.Replace(s, "%25", "%", 1)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		// Strip the "ParseAddr(...): " prefix for a readable reason
+		reason := err.Error()
+		if i := strings.Index(reason, "): "); i >= 0 {
+			reason = reason[i+3:]
+		}
+		return netip.Addr{}, fmt.Errorf("invalid IPv6 address: %s (%s)", s, reason)
+	}
+	if !addr.Is6() {
+		return netip.Addr{}, fmt.Errorf("invalid IPv6 address: %s (IPv4 address)", s)
+	}
+	return addr, nil
+}
+
+// FormatIPv6 is a function that returns the IPv6 address in compressed
+// notation (RFC 5952), expanded notation (all 32 hex digits), mixed notation
+// with the last 32 bits as an IPv4 address (RFC 5952 section 5), reverse
+// nibble notation (ip6.arpa) and bracketed notation for URLs (RFC 3986,
+// with the zone percent-encoded as in RFC 6874).
+func FormatIPv6(addr netip.Addr) IPv6Forms {
+	zone := addr.Zone()
+	bytes := addr.As16()
+
+	// Split the address into 16-bit groups
+	groups := make([]uint16, 8)
+	for i := range groups {
+		groups[i] = uint16(bytes[i*2])<<8 | uint16(bytes[i*2+1])
+	}
+
+	// Expanded notation
+	expanded := make([]string, 8)
+	for i, g := range groups {
+		expanded[i] = fmt.Sprintf("%04x", g)
+	}
+
+	// Mixed notation, the first 96 bits compressed followed by an IPv4 address
+	mixed := compressGroups(groups[:6])
+	if !strings.HasSuffix(mixed, ":") {
+		mixed += ":"
+	}
+	mixed += fmt.Sprintf("%d.%d.%d.%d", bytes[12], bytes[13], bytes[14], bytes[15])
+
+	// Reverse nibble notation
+	nibbles := make([]string, 0, 32)
+	for i := 15; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x", bytes[i]&0x0f), fmt.Sprintf("%x", bytes[i]>>4))
+	}
+
+	// The compressed notation of netip follows RFC 5952 (including the mixed
+	// notation of IPv4-mapped addresses)
+	compressed := addr.WithZone("").String()
+
+	forms := IPv6Forms{
+		Compressed: compressed,
+		Expanded:   strings.Join(expanded, ":"),
+		Mixed:      mixed,
+		Reverse:    strings.Join(nibbles, ".") + ".ip6.arpa",
+		URL:        "[" + compressed + "]",
+	}
+	if zone != "" {
+		forms.Compressed += "%" + zone
+		forms.Expanded += "%" + zone
+		forms.Mixed += "%" + zone
+		forms.URL = "[" + compressed + "%25" + zone + "]"
+	}
+	return forms
+}
+
+// compressGroups is a function that writes 16-bit groups in the notation of
+// RFC 5952 (used for the IPv6 part of the mixed notation): lower case hex without leading zeros and the longest run of two
+// or more zero groups (the first one if there is a tie) replaced by "::"
+func compressGroups(groups []uint16) string {
+	// Find the longest run of zero groups
+	bestStart, bestLen := -1, 1
+	for i := 0; i < len(groups); {
+		if groups[i] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(groups) && groups[j] == 0 {
+			j++
+		}
+		if j-i > bestLen {
+			bestStart, bestLen = i, j-i
+		}
+		i = j
+	}
+
+	hex := func(groups []uint16) string {
+		s := make([]string, len(groups))
+		for i, g := range groups {
+			s[i] = fmt.Sprintf("%x", g)
+		}
+		return strings.Join(s, ":")
+	}
+	if bestStart < 0 {
+		return hex(groups)
+	}
+	return hex(groups[:bestStart]) + "::" + hex(groups[bestStart+bestLen:])
+}
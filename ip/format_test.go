@@ -0,0 +1,90 @@
+package ip_test
+
+import (
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestFormatIPv6(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  ip.IPv6Forms
+		expectErr bool
+	}{
+		{
+			input: "2001:0db8:0000:0000:0000:0000:0000:0001",
+			expected: ip.IPv6Forms{
+				Compressed: "2001:db8::1",
+				Expanded:   "2001:0db8:0000:0000:0000:0000:0000:0001",
+				Mixed:      "2001:db8::0.0.0.1",
+				Reverse:    "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				URL:        "[2001:db8::1]",
+			},
+		},
+		{
+			input: "2001:DB8:0:0:1:0:0:1",
+			expected: ip.IPv6Forms{
+				Compressed: "2001:db8::1:0:0:1",
+				Expanded:   "2001:0db8:0000:0000:0001:0000:0000:0001",
+				Mixed:      "2001:db8::1:0:0.0.0.1",
+				Reverse:    "1.0.0.0.0.0.0.0.0.0.0.0.1.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+				URL:        "[2001:db8::1:0:0:1]",
+			},
+		},
+		{
+			input: "[fe80::1%25eth0]",
+			expected: ip.IPv6Forms{
+				Compressed: "fe80::1%eth0",
+				Expanded:   "fe80:0000:0000:0000:0000:0000:0000:0001%eth0",
+				Mixed:      "fe80::0.0.0.1%eth0",
+				Reverse:    "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa",
+				URL:        "[fe80::1%25eth0]",
+			},
+		},
+		{
+			input: "::ffff:c000:201",
+			expected: ip.IPv6Forms{
+				Compressed: "::ffff:192.0.2.1",
+				Expanded:   "0000:0000:0000:0000:0000:ffff:c000:0201",
+				Mixed:      "::ffff:192.0.2.1",
+				Reverse:    "1.0.2.0.0.0.0.c.f.f.f.f.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa",
+				URL:        "[::ffff:192.0.2.1]",
+			},
+		},
+		{
+			input: "1:2:3:4:5:6:7:8",
+			expected: ip.IPv6Forms{
+				Compressed: "1:2:3:4:5:6:7:8",
+				Expanded:   "0001:0002:0003:0004:0005:0006:0007:0008",
+				Mixed:      "1:2:3:4:5:6:0.7.0.8",
+				Reverse:    "8.0.0.0.7.0.0.0.6.0.0.0.5.0.0.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.ip6.arpa",
+				URL:        "[1:2:3:4:5:6:7:8]",
+			},
+		},
+		{input: "192.0.2.1", expectErr: true},
+		{input: "2001:db8::1::1", expectErr: true},
+		{input: "2001:db8::g", expectErr: true},
+		{input: "", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			addr, err := ip.ParseIPv6Address(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if forms := ip.FormatIPv6(addr); forms != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, forms)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net"
+	"net/netip"
+
+	"github.com/bitcanon/iptool/mac"
+)
+
+// Styles of generated IPv6 addresses
+const (
+	// StyleRandom addresses have random host bits, like temporary addresses (RFC 8981)
+	StyleRandom = "random"
+
+	// StyleSequential addresses are numbered from the first address after the
+	// subnet-router anycast address, like manually assigned addresses
+	StyleSequential = "sequential"
+
+	// StyleEUI64 addresses have a modified EUI-64 interface identifier of a
+	// random MAC address, like SLAAC addresses of older hosts (RFC 4862)
+	StyleEUI64 = "eui64"
+
+	// StyleStable addresses are stable, semantically opaque addresses (RFC
+	// 7217), like SLAAC addresses of current operating systems
+	StyleStable = "stable"
+)
+
+// Styles is the list of supported styles of generated IPv6 addresses
+var Styles = []string{StyleRandom, StyleSequential, StyleEUI64, StyleStable}
+
+// reservedIIDs is the range of reserved subnet anycast interface identifiers
+// (RFC 5453), which are not used for generated addresses
+var reservedIIDs = [2]uint64{0xfdffffffffffff80, 0xfdffffffffffffff}
+
+// GenerateOptions are the options of GenerateIPv6. Rand is the source of
+// the random and EUI-64 addresses, Secret, Interface and NetworkID are the
+// parameters of the stable addresses (see StableIPv6).
+type GenerateOptions struct {
+	Rand      *rand.Rand
+	Secret    []byte
+	Interface string
+	NetworkID string
+}
+
+// GenerateIPv6 is a function that generates count unique addresses in an
+// IPv6 prefix in the given style. The EUI-64 and stable styles require a
+// /64, as SLAAC does. The subnet-router anycast address (the address of the
+// prefix itself) is never generated.
+func GenerateIPv6(prefix netip.Prefix, count int, style string, opts GenerateOptions) ([]netip.Addr, error) {
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return nil, fmt.Errorf("invalid IPv6 prefix: %s", prefix)
+	}
+	prefix = prefix.Masked()
+	if count < 0 {
+		return nil, fmt.Errorf("invalid count: %d (must be 0 or greater)", count)
+	}
+	if (style == StyleEUI64 || style == StyleStable) && prefix.Bits() != 64 {
+		return nil, fmt.Errorf("the %s style requires a /64 prefix, got %s", style, prefix)
+	}
+
+	// The prefix must have room for the addresses, without the subnet-router anycast address
+	hostBits := 128 - prefix.Bits()
+	if hostBits < 64 {
+		if available := uint64(1)<<hostBits - 1; uint64(count) > available {
+			return nil, fmt.Errorf("%s has room for %d addresses, %d requested", prefix, available, count)
+		}
+	}
+
+	if opts.Rand == nil {
+		opts.Rand = rand.New(rand.NewSource(rand.Int63()))
+	}
+	addrs := make([]netip.Addr, 0, count)
+	seen := make(map[netip.Addr]bool, count)
+	switch style {
+	case StyleSequential:
+		for addr := prefix.Addr().Next(); len(addrs) < count; addr = addr.Next() {
+			addrs = append(addrs, addr)
+		}
+		return addrs, nil
+	case StyleRandom, StyleEUI64, StyleStable:
+		for host := 0; len(addrs) < count; host++ {
+			var addr netip.Addr
+			switch style {
+			case StyleRandom:
+				addr = randomIPv6(prefix, opts.Rand)
+			case StyleEUI64:
+				addr = eui64IPv6(prefix, RandomMAC(opts.Rand))
+			case StyleStable:
+				// Every host has a secret key of its own, derived from the
+				// secret and the number of the host
+				secret := binary.BigEndian.AppendUint32(append([]byte{}, opts.Secret...), uint32(host))
+				addr = StableIPv6(prefix, opts.Interface, opts.NetworkID, secret)
+			}
+			if addr == prefix.Addr() || seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("invalid style: %s (must be one of random, sequential, eui64 or stable)", style)
+}
+
+// randomIPv6 is a function that returns an address in the prefix with
+// random host bits
+func randomIPv6(prefix netip.Prefix, r *rand.Rand) netip.Addr {
+	var random [16]byte
+	r.Read(random[:])
+	host := new(big.Int).SetBytes(random[:])
+	host.Rsh(host, uint(prefix.Bits()))
+	base := new(big.Int).SetBytes(prefix.Addr().AsSlice())
+	var b [16]byte
+	base.Or(base, host).FillBytes(b[:])
+	return netip.AddrFrom16(b)
+}
+
+// RandomMAC is a function that returns a random unicast, universally
+// administered MAC address, like the address of a network card
+func RandomMAC(r *rand.Rand) net.HardwareAddr {
+	hw := make(net.HardwareAddr, 6)
+	r.Read(hw)
+	hw[0] &^= 0x03
+	return hw
+}
+
+// eui64IPv6 is a function that returns the SLAAC address of a MAC address
+// in a /64, with the modified EUI-64 interface identifier of the MAC address
+func eui64IPv6(prefix netip.Prefix, hw net.HardwareAddr) netip.Addr {
+	id, _ := mac.EUI64(hw)
+	b := prefix.Addr().As16()
+	copy(b[8:], id)
+	return netip.AddrFrom16(b)
+}
+
+// StableIPv6 is a function that returns the stable, semantically opaque
+// address (RFC 7217) of an interface in a /64: the interface identifier is
+// the first 64 bits of the SHA-256 hash of the prefix, the name of the
+// interface, the network ID (e.g. the SSID of a wireless network), the DAD
+// counter and the secret key. The DAD counter is increased until the
+// interface identifier is not a reserved one (RFC 5453).
+func StableIPv6(prefix netip.Prefix, iface, networkID string, secret []byte) netip.Addr {
+	b := prefix.Masked().Addr().As16()
+	for counter := 0; ; counter++ {
+		h := sha256.New()
+		h.Write(b[:8])
+		h.Write([]byte(iface))
+		h.Write([]byte(networkID))
+		h.Write([]byte{byte(counter)})
+		h.Write(secret)
+		iid := binary.BigEndian.Uint64(h.Sum(nil))
+		if iid != 0 && (iid < reservedIIDs[0] || iid > reservedIIDs[1]) {
+			binary.BigEndian.PutUint64(b[8:], iid)
+			return netip.AddrFrom16(b)
+		}
+	}
+}
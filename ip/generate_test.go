@@ -0,0 +1,113 @@
+package ip_test
+
+import (
+	"math/rand"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestGenerateIPv6(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		prefix    string
+		count     int
+		style     string
+		expectErr bool
+	}{
+		{prefix: "2001:db8::/64", count: 100, style: ip.StyleRandom},
+		{prefix: "2001:db8::/64", count: 100, style: ip.StyleEUI64},
+		{prefix: "2001:db8::/64", count: 100, style: ip.StyleStable},
+		{prefix: "2001:db8::/124", count: 15, style: ip.StyleRandom},
+		{prefix: "2001:db8::/124", count: 15, style: ip.StyleSequential},
+		{prefix: "2001:db8::/124", count: 16, style: ip.StyleSequential, expectErr: true},
+		{prefix: "2001:db8::/48", count: 1, style: ip.StyleEUI64, expectErr: true},
+		{prefix: "10.0.0.0/8", count: 1, style: ip.StyleRandom, expectErr: true},
+		{prefix: "2001:db8::/64", count: 1, style: "foo", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.prefix+" "+tc.style, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tc.prefix)
+			addrs, err := ip.GenerateIPv6(prefix, tc.count, tc.style, ip.GenerateOptions{Rand: rand.New(rand.NewSource(1)), Secret: []byte("secret")})
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The addresses are unique addresses in the prefix, other than the
+			// subnet-router anycast address
+			seen := make(map[netip.Addr]bool)
+			for _, addr := range addrs {
+				if !prefix.Contains(addr) || addr == prefix.Addr() || seen[addr] {
+					t.Errorf("unexpected address: %s", addr)
+				}
+				seen[addr] = true
+			}
+			if len(addrs) != tc.count {
+				t.Errorf("expected %d addresses, got %d", tc.count, len(addrs))
+			}
+		})
+	}
+}
+
+func TestGenerateIPv6Styles(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8:1:2::/64")
+
+	// Sequential addresses start after the subnet-router anycast address
+	addrs, _ := ip.GenerateIPv6(prefix, 2, ip.StyleSequential, ip.GenerateOptions{})
+	if !slices.Equal(addrs, []netip.Addr{netip.MustParseAddr("2001:db8:1:2::1"), netip.MustParseAddr("2001:db8:1:2::2")}) {
+		t.Errorf("unexpected sequential addresses: %v", addrs)
+	}
+
+	// EUI-64 interface identifiers have ff:fe in the middle and the
+	// universal/local bit inverted (set, as the MAC addresses are universal)
+	addrs, _ = ip.GenerateIPv6(prefix, 10, ip.StyleEUI64, ip.GenerateOptions{})
+	for _, addr := range addrs {
+		b := addr.As16()
+		if b[11] != 0xff || b[12] != 0xfe || b[8]&0x02 == 0 {
+			t.Errorf("not a modified EUI-64 address: %s", addr)
+		}
+	}
+
+	// The same secret gives the same stable addresses, another secret others
+	opts := ip.GenerateOptions{Secret: []byte("secret"), Interface: "eth0"}
+	first, _ := ip.GenerateIPv6(prefix, 3, ip.StyleStable, opts)
+	second, _ := ip.GenerateIPv6(prefix, 3, ip.StyleStable, opts)
+	opts.Secret = []byte("other")
+	third, _ := ip.GenerateIPv6(prefix, 3, ip.StyleStable, opts)
+	if !slices.Equal(first, second) || slices.Equal(first, third) {
+		t.Errorf("expected stable addresses: %v, %v and %v", first, second, third)
+	}
+}
+
+func TestStableIPv6(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8::/64")
+	secret := []byte("secret")
+
+	// The address depends on the prefix, the interface and the network ID
+	addr := ip.StableIPv6(prefix, "eth0", "", secret)
+	if addr != ip.StableIPv6(prefix, "eth0", "", secret) {
+		t.Error("expected the same address for the same parameters")
+	}
+	for _, other := range []netip.Addr{
+		ip.StableIPv6(netip.MustParsePrefix("2001:db8:0:1::/64"), "eth0", "", secret),
+		ip.StableIPv6(prefix, "eth1", "", secret),
+		ip.StableIPv6(prefix, "eth0", "office", secret),
+	} {
+		if other == addr {
+			t.Errorf("expected another address than %s", addr)
+		}
+	}
+	if !prefix.Contains(addr) {
+		t.Errorf("expected an address in %s, got %s", prefix, addr)
+	}
+}
@@ -23,8 +23,13 @@ package ip
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"net/netip"
 	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/cache"
 )
 
 var ErrInvalidNetmask = errors.New("invalid netmask")
@@ -41,33 +46,108 @@ func ParseIP(s string) (net.IP, *net.IPNet, error) {
 
 // Function that checks if an IP address is an IPv4 address
 func IsIPv4(s string) bool {
-	ipv4 := net.ParseIP(s)
-	if ipv4 != nil && strings.Contains(s, ".") {
-		return true
-	}
-	return false
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Zone() == "" && strings.Contains(s, ".")
 }
 
 // IsIPv6 is a function that checks if an IP address is an IPv6 address
 func IsIPv6(s string) bool {
-	ipv6 := net.ParseIP(s)
-	if ipv6 != nil && strings.Contains(s, ":") {
-		return true
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Zone() == "" && strings.Contains(s, ":")
+}
+
+// parsePrefix is a function that parses an address with a prefix length
+// (e.g. 2001:db8::1/64) without masking the address. Notations that net/netip
+// is stricter about than net.ParseCIDR (e.g. a prefix length with leading
+// zeros) fall back to net.ParseCIDR to keep accepting them.
+func parsePrefix(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err == nil {
+		return prefix, nil
 	}
-	return false
+	ip, ipnet, cidrErr := net.ParseCIDR(s)
+	if cidrErr != nil {
+		return netip.Prefix{}, err
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	if ip.To4() != nil && !strings.Contains(s, ":") {
+		addr = addr.Unmap()
+	}
+	ones, _ := ipnet.Mask.Size()
+	return netip.PrefixFrom(addr, ones), nil
+}
+
+// prefixToIPNet is a function that converts a netip.Prefix to a net.IPNet,
+// for the types that expose the net package types in their API
+func prefixToIPNet(prefix netip.Prefix) *net.IPNet {
+	addr := prefix.Addr()
+	return &net.IPNet{IP: net.IP(addr.AsSlice()), Mask: net.CIDRMask(prefix.Bits(), addr.BitLen())}
+}
+
+var ErrLookupsDisabled = errors.New("name resolution is disabled (--no-dns)")
+
+// dnsCacheTTL is the time a resolved address is kept in the cache
+const dnsCacheTTL = 5 * time.Minute
+
+// lookupsDisabled controls whether ResolveIP is allowed to query DNS
+var lookupsDisabled bool
+
+// DisableLookups is a function that disables (or re-enables) all name
+// resolution in the package. When disabled, only numeric addresses are
+// accepted, which avoids leaking query names and slow lookups on networks
+// with broken DNS.
+func DisableLookups(disable bool) {
+	lookupsDisabled = disable
+}
+
+// LookupsDisabled is a function that returns true if name resolution is disabled
+func LookupsDisabled() bool {
+	return lookupsDisabled
 }
 
 // ResolveIP is a function that resolves a hostname to an IP address
-// and returns the first IPv4 address found.
+// and returns the first IPv4 address found. Numeric IPv4 addresses are
+// returned as is, without querying DNS.
 func ResolveIP(hostname string) (string, error) {
-	ips, err := net.LookupIP(hostname)
-	if err != nil {
-		return "", err
+	if IsIPv4(hostname) {
+		return hostname, nil
 	}
-	for _, ip := range ips {
-		if ip.To4() != nil {
-			return ip.String(), nil
+	if lookupsDisabled {
+		return "", fmt.Errorf("cannot resolve %s: %w", hostname, ErrLookupsDisabled)
+	}
+
+	// Successful lookups are cached to speed up repeated runs
+	return cache.Remember(cache.NamespaceDNS, hostname, dnsCacheTTL, func() (string, error) {
+		ips, err := net.LookupIP(hostname)
+		if err != nil {
+			return "", err
+		}
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				return ip.String(), nil
+			}
 		}
+		return "", errors.New("no IPv4 address found")
+	})
+}
+
+// ReverseLookup is a function that returns the host names (PTR records) of
+// an IP address. Successful lookups are cached.
+func ReverseLookup(addr string) ([]string, error) {
+	if lookupsDisabled {
+		return nil, fmt.Errorf("cannot look up %s: %w", addr, ErrLookupsDisabled)
 	}
-	return "", errors.New("no IPv4 address found")
+
+	return cache.Remember(cache.NamespaceDNS, "ptr:"+addr, dnsCacheTTL, func() ([]string, error) {
+		names, err := net.LookupAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// Remove the trailing dot of the fully qualified names
+		for i := range names {
+			names[i] = strings.TrimSuffix(names[i], ".")
+		}
+		return names, nil
+	})
 }
@@ -0,0 +1,43 @@
+package ip_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestResolveIPLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "NumericAddress", input: "192.168.0.1", expected: "192.168.0.1"},
+		{name: "Hostname", input: "localhost", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := ip.ResolveIP(tc.input)
+			if tc.expectErr {
+				if !errors.Is(err, ip.ErrLookupsDisabled) {
+					t.Errorf("expected ErrLookupsDisabled, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if addr != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, addr)
+			}
+		})
+	}
+}
@@ -24,9 +24,8 @@ package ip
 import (
 	"errors"
 	"fmt"
-	"math"
 	"net"
-	"regexp"
+	"net/netip"
 	"strconv"
 	"strings"
 )
@@ -37,10 +36,75 @@ var ErrInvalidHexAddress = errors.New("invalid hexadecimal IPv4 address")
 // and a network address. It also contains functions for calculating the
 // broadcast address, the first and last usable host addresses, the number of
 // usable hosts and the size of the network in number of IP addresses.
+//
+// The address and the mask are also kept as 32-bit integers when the IPv4 is
+// created by ParseIPv4 or Subnet, so that the calculations do not have to
+// convert the byte slices over and over again when processing many networks.
 type IPv4 struct {
 	IP   net.IP
 	Mask net.IPMask
 	Net  *net.IPNet
+
+	// Cached integer representation of IP and Mask (valid if cached is true)
+	addr   uint32
+	mask   uint32
+	cached bool
+}
+
+// newIPv4FromInt is a function that returns an IPv4 with the given address and
+// mask (integers), with the integer representation cached
+func newIPv4FromInt(addr, mask uint32) *IPv4 {
+	ip := net.IP{byte(addr >> 24), byte(addr >> 16), byte(addr >> 8), byte(addr)}
+	ipMask := net.IPv4Mask(byte(mask>>24), byte(mask>>16), byte(mask>>8), byte(mask))
+	network := addr & mask
+	netIP := net.IP{byte(network >> 24), byte(network >> 16), byte(network >> 8), byte(network)}
+	return &IPv4{IP: ip, Mask: ipMask, Net: &net.IPNet{IP: netIP, Mask: ipMask}, addr: addr, mask: mask, cached: true}
+}
+
+// IPv4FromPrefix is a function that returns an IPv4 with the address and
+// prefix length of the prefix. An IPv4-mapped IPv6 prefix is unmapped. An
+// error is returned if the prefix is not an IPv4 prefix.
+func IPv4FromPrefix(prefix netip.Prefix) (*IPv4, error) {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr, bits = addr.Unmap(), bits-96
+	}
+	if !prefix.IsValid() || !addr.Is4() {
+		return nil, fmt.Errorf("invalid IPv4 prefix: %s", prefix)
+	}
+	a := addr.As4()
+	return newIPv4FromInt(bytesToUint32(a[:]), uint32(MaskFromPrefixLength(bits))), nil
+}
+
+// Addr is a function that returns the IP address as a netip.Addr
+func (ip *IPv4) Addr() netip.Addr {
+	addr, _, _ := ip.ints()
+	return netip.AddrFrom4([4]byte{byte(addr >> 24), byte(addr >> 16), byte(addr >> 8), byte(addr)})
+}
+
+// Prefix is a function that returns the IP address and the prefix length as a netip.Prefix
+func (ip *IPv4) Prefix() netip.Prefix {
+	return netip.PrefixFrom(ip.Addr(), ip.PrefixLength())
+}
+
+// ints is a function that returns the address and the mask as 32-bit integers.
+// The cached values are used if present, otherwise they are calculated from
+// IP and Mask (e.g. when the IPv4 was created as a struct literal). The last
+// return value is false if the address is not an IPv4 address.
+func (ip *IPv4) ints() (uint32, uint32, bool) {
+	if ip.cached {
+		return ip.addr, ip.mask, true
+	}
+	addr := ip.IP.To4()
+	if addr == nil || len(ip.Mask) != net.IPv4len {
+		return 0, 0, false
+	}
+	return bytesToUint32(addr), bytesToUint32(ip.Mask), true
+}
+
+// bytesToUint32 is a function that converts four bytes (big-endian) to a 32-bit integer
+func bytesToUint32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
 }
 
 // Address is a function that returns the IP address in dotted-decimal notation
@@ -50,40 +114,12 @@ func (ip *IPv4) Address() string {
 
 // Netmask is a function that returns the netmask in dotted-decimal notation
 func (ip *IPv4) Netmask() string {
-	// Convert the hexadecimal string to an integer
-	hexInt, err := strconv.ParseUint(ip.Mask.String(), 16, 32)
-	if err != nil {
-		return ""
-	}
-
-	// Convert the integer to dotted-decimal notation
-	dottedDecimal := fmt.Sprintf("%d.%d.%d.%d",
-		(hexInt>>24)&0xFF,
-		(hexInt>>16)&0xFF,
-		(hexInt>>8)&0xFF,
-		hexInt&0xFF,
-	)
-
-	return dottedDecimal
+	return MaskFromIPMask(ip.Mask).Netmask()
 }
 
 // Wildcard is a function that returns the wildcard mask in dotted-decimal notation
 func (ip *IPv4) Wildcard() string {
-	// Convert the hexadecimal string to an integer
-	hexInt, err := strconv.ParseUint(ip.Mask.String(), 16, 32)
-	if err != nil {
-		return ""
-	}
-
-	// Convert the integer to dotted-decimal notation
-	dottedDecimal := fmt.Sprintf("%d.%d.%d.%d",
-		(hexInt>>24)&0xFF^0xFF,
-		(hexInt>>16)&0xFF^0xFF,
-		(hexInt>>8)&0xFF^0xFF,
-		hexInt&0xFF^0xFF,
-	)
-
-	return dottedDecimal
+	return MaskFromIPMask(ip.Mask).Wildcard()
 }
 
 // Network is a function that returns the network address of the network
@@ -93,197 +129,200 @@ func (ip *IPv4) Network() string {
 
 // PrefixLength is a function that returns the number of bits set in the netmask
 func (ip *IPv4) PrefixLength() int {
+	if ip.cached {
+		return Mask(ip.mask).PrefixLength()
+	}
 	ones, _ := ip.Net.Mask.Size()
 	return ones
 }
 
 // Broadcast is a function that returns the broadcast address in the network
 func (ip *IPv4) Broadcast() string {
-	// Convert the IP address to a 32-bit integer
-	ipInt := ip.IP.To4()
-	if ipInt == nil {
+	addr, mask, ok := ip.ints()
+	if !ok {
 		return ""
 	}
-	ipInt32 := uint32(ipInt[0])<<24 | uint32(ipInt[1])<<16 | uint32(ipInt[2])<<8 | uint32(ipInt[3])
-
-	// Convert the netmask to a 32-bit integer
-	maskInt := ip.Mask
-	maskInt32 := uint32(maskInt[0])<<24 | uint32(maskInt[1])<<16 | uint32(maskInt[2])<<8 | uint32(maskInt[3])
-
-	// Calculate the broadcast address
-	broadcastInt32 := ipInt32 | ^maskInt32
-
-	// Convert the broadcast address to dotted-decimal notation
-	broadcast := fmt.Sprintf("%d.%d.%d.%d",
-		(broadcastInt32>>24)&0xFF,
-		(broadcastInt32>>16)&0xFF,
-		(broadcastInt32>>8)&0xFF,
-		broadcastInt32&0xFF,
-	)
 
-	return broadcast
+	// The broadcast address has all host bits set
+	return IntToIPv4(addr | ^mask)
 }
 
 // FirstHost is a function that returns the first usable host address in the network
 func (ip *IPv4) FirstHost() string {
-	// Convert the IP address to a 32-bit integer
-	ipInt := ip.IP.To4()
-	if ipInt == nil {
+	addr, mask, ok := ip.ints()
+	if !ok {
 		return ""
 	}
-	ipInt32 := uint32(ipInt[0])<<24 | uint32(ipInt[1])<<16 | uint32(ipInt[2])<<8 | uint32(ipInt[3])
 
-	// Convert the netmask to a 32-bit integer
-	maskInt := ip.Mask
-	maskInt32 := uint32(maskInt[0])<<24 | uint32(maskInt[1])<<16 | uint32(maskInt[2])<<8 | uint32(maskInt[3])
-
-	// Calculate the first host address
-	firstHostInt32 := ipInt32 & maskInt32
-
-	switch maskInt32 {
-	// If maskInt32 is 0xFFFFFFFF, the network is a /32 network and the first host address is the same as the network address
+	switch mask {
+	// If the mask is 0xFFFFFFFF, the network is a /32 network and the first host address is the same as the network address
 	case 0xFFFFFFFF:
-		firstHostInt32 = ipInt32
-	// If maskInt32 is 0xFFFFFFFE, the network is a /31 network and the first host address is the same as the network address
+		return IntToIPv4(addr)
+	// If the mask is 0xFFFFFFFE, the network is a /31 network and the first host address is the same as the network address
 	case 0xFFFFFFFE:
-		firstHostInt32 = ipInt32
+		return IntToIPv4(addr)
 	// Else, the first host address is the network address + 1
 	default:
-		firstHostInt32 = firstHostInt32 + 1
+		return IntToIPv4(addr&mask + 1)
 	}
-
-	// Convert the first host address to dotted-decimal notation
-	firstHost := fmt.Sprintf("%d.%d.%d.%d",
-		(firstHostInt32>>24)&0xFF,
-		(firstHostInt32>>16)&0xFF,
-		(firstHostInt32>>8)&0xFF,
-		firstHostInt32&0xFF,
-	)
-
-	return firstHost
 }
 
 // LastHost is a function that returns the last usable host address in the network
 func (ip *IPv4) LastHost() string {
-	// Convert the IP address to a 32-bit integer
-	ipInt := ip.IP.To4()
-	if ipInt == nil {
+	addr, mask, ok := ip.ints()
+	if !ok {
 		return ""
 	}
-	ipInt32 := uint32(ipInt[0])<<24 | uint32(ipInt[1])<<16 | uint32(ipInt[2])<<8 | uint32(ipInt[3])
-
-	// Convert the netmask to a 32-bit integer
-	maskInt := ip.Mask
-	maskInt32 := uint32(maskInt[0])<<24 | uint32(maskInt[1])<<16 | uint32(maskInt[2])<<8 | uint32(maskInt[3])
 
-	// Calculate the last host address
-	lastHostInt32 := ipInt32 & maskInt32 //| ^maskInt32 - 1
-
-	switch maskInt32 {
-	// If maskInt32 is 0xFFFFFFFF, the network is a /32 network and the last host address is the same as the network and broadcast address
+	switch mask {
+	// If the mask is 0xFFFFFFFF, the network is a /32 network and the last host address is the same as the network and broadcast address
 	case 0xFFFFFFFF:
-		lastHostInt32 = ipInt32
-	// If maskInt32 is 0xFFFFFFFE, the network is a /31 network and the last host address is the same as the broadcast address
+		return IntToIPv4(addr)
+	// If the mask is 0xFFFFFFFE, the network is a /31 network and the last host address is the same as the broadcast address
 	case 0xFFFFFFFE:
-		lastHostInt32 = ipInt32 | ^maskInt32
+		return IntToIPv4(addr | ^mask)
 	// Else, the last host address is the broadcast address - 1
 	default:
-		lastHostInt32 = lastHostInt32 | ^maskInt32 - 1
+		return IntToIPv4(addr&mask | ^mask - 1)
 	}
-
-	// Convert the last host address to dotted-decimal notation
-	lastHost := fmt.Sprintf("%d.%d.%d.%d",
-		(lastHostInt32>>24)&0xFF,
-		(lastHostInt32>>16)&0xFF,
-		(lastHostInt32>>8)&0xFF,
-		lastHostInt32&0xFF,
-	)
-
-	return lastHost
 }
 
 // String is a function that returns the IP address and the prefix length in CIDR notation
 func (ip *IPv4) String() string {
-	return fmt.Sprintf("%s/%d", ip.IP.String(), ip.PrefixLength())
+	return ip.IP.String() + "/" + strconv.Itoa(ip.PrefixLength())
 }
 
 // UsableHosts is a function that returns the number of usable hosts in the network
 func (ip *IPv4) UsableHosts() uint32 {
-	// Convert the netmask to a 32-bit integer
-	maskInt := ip.Mask
-	maskInt32 := uint32(maskInt[0])<<24 | uint32(maskInt[1])<<16 | uint32(maskInt[2])<<8 | uint32(maskInt[3])
-
-	// Get the number of bits set in the netmask
-	ones, _ := ip.Net.Mask.Size()
+	_, mask, _ := ip.ints()
 
+	switch ip.PrefixLength() {
 	// In a /32 network, there are no usable hosts
-	if ones == 32 {
+	case 32:
 		return 0
-	}
-
 	// In a /31 network, there are two usable hosts
-	if ones == 31 {
+	case 31:
 		return 2
 	}
 
 	// Calculate the number of usable hosts
-	usableHosts := ^maskInt32 - 1
-
-	return usableHosts
+	return ^mask - 1
 }
 
 // NetworkSize is a function that returns the size of the network in number of IP addresses
 func (ip *IPv4) NetworkSize() uint32 {
-	// Convert the netmask to a 32-bit integer
-	maskInt := ip.Mask
-	maskInt32 := uint32(maskInt[0])<<24 | uint32(maskInt[1])<<16 | uint32(maskInt[2])<<8 | uint32(maskInt[3])
-
-	// Get the number of bits set in the netmask
-	ones, _ := ip.Net.Mask.Size()
-
-	// Calculate the network size
-	networkSize := ^maskInt32 + 1
+	_, mask, _ := ip.ints()
 
 	// In a /0 network, the network size is 2^32 = 4294967296
 	// But since we are using uint32, the maximum value is 4294967295
-	if ones == 0 {
+	if mask == 0 {
 		return 4294967295
 	}
 
-	return networkSize
+	// Calculate the network size
+	return ^mask + 1
 }
 
 // NetmaskPrefixLength is a function that takes a netmask in dotted-decimal notation
-// (e.g. 255.255.255.0) as input and returns the number of bits set in the netmask
+// (e.g. 255.255.255.0) as input and returns the number of bits set in the netmask.
+// An error wrapping ErrDiscontiguousNetmask is returned if the bits set in the
+// netmask are not contiguous (e.g. 255.0.255.0).
 func NetmaskPrefixLength(mask string) (int, error) {
 	// Try to parse the netmask
-	ip := net.ParseIP(mask)
-	if ip == nil {
-		return 0, ErrInvalidNetmask
+	maskInt32, err := parseMask(mask)
+	if err != nil {
+		return 0, err
 	}
 
-	// Count the number of bits set in the netmask
-	ones, bits := net.IPMask(ip.To4()).Size()
-
-	// Make sure that the netmask is a valid IPv4 netmask
-	if bits != 32 {
-		return 0, ErrInvalidNetmask
+	// Make sure that the netmask is a valid (contiguous) IPv4 netmask
+	if !IsContiguousMask(maskInt32) {
+		return 0, fmt.Errorf("%w: %s (the mask bits must be contiguous from the left)", ErrDiscontiguousNetmask, mask)
 	}
 
 	// Return the number of bits set in the netmask
-	return ones, nil
+	return Mask(maskInt32).PrefixLength(), nil
+}
+
+// parseHex is a function that parses exactly 8 hexadecimal digits, optionally
+// prefixed with "0x", and returns them as a 32-bit integer. The last return
+// value is false if the string is not a hexadecimal IPv4 address.
+func parseHex(s string) (uint32, bool) {
+	s = strings.TrimPrefix(s, "0x")
+	if len(s) != 8 {
+		return 0, false
+	}
+
+	var value uint32
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		value = value<<4 | uint32(c)
+	}
+	return value, true
+}
+
+// parseDottedDecimal is a function that parses an IPv4 address in
+// dotted-decimal notation (e.g. 192.168.0.1) and returns it as a 32-bit
+// integer without allocating. Like net.ParseIP, octets with leading zeros are
+// rejected. The last return value is false if the string could not be parsed.
+func parseDottedDecimal(s string) (uint32, bool) {
+	var value uint32
+	octets := 0
+	for i := 0; i < len(s); {
+		// Parse the digits of the octet
+		start, octet := i, 0
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			octet = octet*10 + int(s[i]-'0')
+			if octet > 255 {
+				return 0, false
+			}
+		}
+		if i == start || (i-start > 1 && s[start] == '0') {
+			return 0, false
+		}
+		value = value<<8 | uint32(octet)
+		octets++
+
+		// Expect a dot between the octets
+		if i < len(s) {
+			if s[i] != '.' || octets == 4 || i+1 == len(s) {
+				return 0, false
+			}
+			i++
+		}
+	}
+	return value, octets == 4
+}
+
+// parseIPv4Int is a function that parses an IPv4 address and returns it as a
+// 32-bit integer. Plain dotted-decimal addresses are parsed directly, any
+// other notation accepted by net.ParseIP (e.g. ::ffff:10.0.0.1) falls back to
+// the standard library.
+func parseIPv4Int(s string) (uint32, bool) {
+	if value, ok := parseDottedDecimal(s); ok {
+		return value, true
+	}
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return 0, false
+	}
+	return bytesToUint32(ip), true
 }
 
 // IsHexIPv4 is a function that takes a string as input and returns true if the
 // string is a valid hexadecimal IPv4 address. Otherwise it returns false.
 func IsIPv4Hex(hexIP string) bool {
-	// Remove dots and "0x" prefix if present
-	hexIP = regexp.MustCompile(`0x`).ReplaceAllString(hexIP, "")
-
-	// Check if the remaining string is exactly 8 hexadecimal digits
-	match, _ := regexp.MatchString(`^[0-9a-fA-F]{8}$`, hexIP)
-
-	return match
+	_, ok := parseHex(hexIP)
+	return ok
 }
 
 // ParseIPv4 is a function that takes a string as input and returns an IPv4 address
@@ -305,42 +344,49 @@ func ParseIPv4(s string) (*IPv4, error) {
 		return r == '/' || r == ' '
 	})
 
-	// If a part is in hexadecimal notation, convert it to dotted-decimal notation
-	for i := 0; i < len(parts); i++ {
-		// If the part is in hexadecimal notation, convert it to dotted-decimal notation
-		if IsIPv4Hex(parts[i]) {
-			ipv4, err := ParseIPv4FromHex(parts[i])
-			if err != nil {
-				return nil, err
-			}
-			parts[i] = ipv4
-		}
+	// If the input string does not contain a netmask or prefix length,
+	// assume that the netmask is 24 bits
+	if len(parts) == 1 {
+		parts = append(parts, "24")
+	} else if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid IP address: %s", s)
 	}
 
-	// If the input string contains two parts, check if the second part is a netmask
-	// in dotted-decimal notation (255.255.255.0) or CIDR notation (24)
-	if len(parts) == 2 {
-		// If the netmask is in dotted-decimal notation, convert it to CIDR notation
-		if IsIPv4(parts[1]) {
-			ones, err := NetmaskPrefixLength(parts[1])
-			if err != nil {
-				return nil, err
-			}
-			parts[1] = strconv.Itoa(ones)
+	// Parse the IP address in hexadecimal or dotted-decimal notation
+	addr, ok := parseHex(parts[0])
+	if !ok {
+		addr, ok = parseDottedDecimal(parts[0])
+	}
+
+	// Parse the netmask in hexadecimal, dotted-decimal (255.255.255.0) or
+	// CIDR notation (24)
+	var mask uint32
+	if value, isHex := parseHex(parts[1]); isHex {
+		parts[1] = IntToIPv4(value)
+	}
+	if IsIPv4(parts[1]) {
+		ones, err := NetmaskPrefixLength(parts[1])
+		if err != nil {
+			return nil, err
 		}
-	} else if len(parts) == 1 {
-		// If the input string does not contain a netmask or prefix length,
-		// assume that the netmask is 24 bits
-		parts = append(parts, "24")
+		mask = uint32(MaskFromPrefixLength(ones))
+		parts[1] = strconv.Itoa(ones)
+	} else if ones, err := strconv.Atoi(parts[1]); err == nil && ones >= 0 && ones <= 32 && parts[1] == strconv.Itoa(ones) {
+		mask = uint32(MaskFromPrefixLength(ones))
 	} else {
-		return nil, fmt.Errorf("invalid IP address: %s", s)
+		ok = false
 	}
 
-	// Reassemble the input string
-	s = strings.Join(parts, "/")
+	// The common case is handled without parsing the strings again
+	if ok {
+		return newIPv4FromInt(addr, mask), nil
+	}
 
-	// Parse the input string
-	ip, ipnet, err := net.ParseCIDR(s)
+	// Leave anything else (and the error messages) to the standard library
+	if value, isHex := parseHex(parts[0]); isHex {
+		parts[0] = IntToIPv4(value)
+	}
+	ip, ipnet, err := net.ParseCIDR(strings.Join(parts, "/"))
 	if err != nil {
 		return nil, err
 	}
@@ -351,160 +397,171 @@ func ParseIPv4(s string) (*IPv4, error) {
 // IPv4 address in dotted-decimal notation. The input string must be a valid
 // hexadecimal IPv4 address.
 func ParseIPv4FromHex(hexIP string) (string, error) {
-	// Remove dots and "0x" prefix if present
-	hexIP = regexp.MustCompile(`0x`).ReplaceAllString(hexIP, "")
-
 	// A valid hexadecimal IPv4 address must be exactly 8 hexadecimal digits
-	if len(hexIP) != 8 {
+	if len(strings.TrimPrefix(hexIP, "0x")) != 8 {
 		return "", fmt.Errorf("invalid length for hex IP address")
 	}
 
-	// Create a slice of bytes with length 4 to store the IP address
-	ipBytes := make([]byte, 4)
-
-	// Convert each pair of hexadecimal digits to a byte
-	for i := 0; i < 4; i++ {
-		byteValue, err := strconv.ParseInt(hexIP[i*2:i*2+2], 16, 16)
-		if err != nil {
-			return "", ErrInvalidHexAddress
-		}
-		ipBytes[i] = byte(byteValue)
+	value, ok := parseHex(hexIP)
+	if !ok {
+		return "", ErrInvalidHexAddress
 	}
-
-	// Convert the slice of bytes to an IP address
-	ip := net.IPv4(ipBytes[0], ipBytes[1], ipBytes[2], ipBytes[3])
-
-	return ip.String(), nil
+	return IntToIPv4(value), nil
 }
 
 // IPv4ToBinary is a function that takes an IPv4 address in dotted-decimal
 // notation as input and returns the IP address in binary notation.
 func IPv4ToBinary(ipStr string) string {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return ""
-	}
-
-	ip = ip.To4()
-	if ip == nil {
+	value, ok := parseIPv4Int(ipStr)
+	if !ok {
 		return ""
 	}
 
-	binaryIP := make([]string, 4)
-	for i, byteValue := range ip {
-		binaryIP[i] = fmt.Sprintf("%08b", byteValue)
+	// Write the 32 bits with a dot between every octet
+	buf := make([]byte, 0, 35)
+	for i := 31; i >= 0; i-- {
+		buf = append(buf, '0'+byte(value>>i&1))
+		if i%8 == 0 && i > 0 {
+			buf = append(buf, '.')
+		}
 	}
-
-	return strings.Join(binaryIP, ".")
+	return string(buf)
 }
 
 // IPv4ToHex is a function that takes an IPv4 address in dotted-decimal
 // notation as input and returns the IP address in hexadecimal notation.
 func IPv4ToHex(ipStr string) string {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
+	value, ok := parseIPv4Int(ipStr)
+	if !ok {
 		return ""
 	}
-
-	ip = ip.To4()
-	if ip == nil {
-		return ""
-	}
-
-	hexIP := ""
-	for _, byteValue := range ip {
-		hexIP += fmt.Sprintf("%02x", byteValue)
-	}
-
-	return hexIP
+	return Mask(value).Hex()[2:]
 }
 
 // IPv4ToDecimal is a function that takes an IPv4 address in dotted-decimal
 // notation as input and returns the IP address in decimal notation (integer).
 func IPv4ToDecimal(ipStr string) string {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
+	value, ok := parseIPv4Int(ipStr)
+	if !ok {
 		return ""
 	}
+	return strconv.FormatUint(uint64(value), 10)
+}
 
-	ip = ip.To4()
-	if ip == nil {
-		return ""
+// IPv4ToInt is a function that takes an IPv4 address in dotted-decimal
+// notation as input and returns the IP address in decimal notation (integer).
+func IPv4ToInt(ipStr string) uint32 {
+	value, _ := parseIPv4Int(ipStr)
+	return value
+}
+
+// IntToIPv4 is a function that takes an IP address in decimal notation (integer)
+// as input and returns the IP address in dotted-decimal notation.
+func IntToIPv4(ipInt uint32) string {
+	buf := make([]byte, 0, 15)
+	for i := 24; i >= 0; i -= 8 {
+		buf = strconv.AppendUint(buf, uint64(byte(ipInt>>i)), 10)
+		if i > 0 {
+			buf = append(buf, '.')
+		}
 	}
+	return string(buf)
+}
 
-	decimalIP := 0
-	for _, byteValue := range ip {
-		decimalIP = decimalIP*256 + int(byteValue)
+// SubnetCount is a function that returns the number of subnets of the given
+// size (in bits) that the network can be split into.
+func (ip *IPv4) SubnetCount(bits int) (uint64, error) {
+	// Make sure that the number of bits is greater than or equal to the prefix length
+	if ip.PrefixLength() > bits {
+		return 0, fmt.Errorf("the number of bits must be greater than or equal to the prefix length")
+	}
+	if bits > 32 {
+		return 0, fmt.Errorf("the number of bits must be less than or equal to 32")
 	}
 
-	return fmt.Sprintf("%d", decimalIP)
+	return uint64(1) << (bits - ip.PrefixLength()), nil
 }
 
-// IPv4ToInt is a function that takes an IPv4 address in dotted-decimal
-// notation as input and returns the IP address in decimal notation (integer).
-func IPv4ToInt(ipStr string) uint32 {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return 0
+// Subnet is a function that returns the subnet with the given index when the
+// network is split into subnets of the given size (in bits). The first subnet
+// has index 0.
+func (ip *IPv4) Subnet(bits int, index uint64) (*IPv4, error) {
+	count, err := ip.SubnetCount(bits)
+	if err != nil {
+		return nil, err
+	}
+	if index >= count {
+		return nil, fmt.Errorf("subnet index %d out of range (the network contains %d subnets)", index, count)
 	}
 
-	ip = ip.To4()
-	if ip == nil {
-		return 0
+	// Calculate the network address of the subnet
+	addr, mask, _ := ip.ints()
+	subnetSize := uint64(1) << (32 - bits)
+	network := uint64(addr&mask) + index*subnetSize
+
+	// Create the subnet without parsing strings, this is much faster for large splits
+	return newIPv4FromInt(uint32(network), uint32(MaskFromPrefixLength(bits))), nil
+}
+
+// SubnetIndex is a function that returns the index of the subnet that
+// contains the address when the network is split into subnets of the given
+// size (in bits), the inverse of Subnet. The address must be inside the
+// network and does not have to be the network address of the subnet.
+func (ip *IPv4) SubnetIndex(bits int, addr string) (uint64, error) {
+	if _, err := ip.SubnetCount(bits); err != nil {
+		return 0, err
+	}
+	if !IsIPv4(addr) {
+		return 0, fmt.Errorf("invalid IPv4 address: %s", addr)
 	}
 
-	decimalIP := uint32(0)
-	for _, byteValue := range ip {
-		decimalIP = decimalIP*256 + uint32(byteValue)
+	// The address must be inside the network
+	value := IPv4ToInt(addr)
+	network, mask, _ := ip.ints()
+	if value&mask != network&mask {
+		return 0, fmt.Errorf("%s is outside of %s/%d", addr, ip.Network(), ip.PrefixLength())
 	}
 
-	return decimalIP
+	return uint64(value-network&mask) >> (32 - bits), nil
 }
 
-// IntToIPv4 is a function that takes an IP address in decimal notation (integer)
-// as input and returns the IP address in dotted-decimal notation.
-func IntToIPv4(ipInt uint32) string {
-	ip := make(net.IP, 4)
-	ip[0] = byte(ipInt >> 24)
-	ip[1] = byte(ipInt >> 16)
-	ip[2] = byte(ipInt >> 8)
-	ip[3] = byte(ipInt)
-	return ip.String()
+// SplitFunc is a function that splits the network into subnets of the given
+// size (in bits) and calls fn for every subnet, starting at the subnet with
+// index offset. The iteration stops when fn returns false. Unlike Split, the
+// subnets are streamed one at a time, which keeps the memory usage constant
+// even when splitting very large networks.
+func (ip *IPv4) SplitFunc(bits int, offset uint64, fn func(index uint64, subnet *IPv4) bool) error {
+	count, err := ip.SubnetCount(bits)
+	if err != nil {
+		return err
+	}
+
+	for i := offset; i < count; i++ {
+		subnet, err := ip.Subnet(bits, i)
+		if err != nil {
+			return err
+		}
+		if !fn(i, subnet) {
+			break
+		}
+	}
+
+	return nil
 }
 
 // Split is a function that takes an IPv4 address and a number of bits as input
 // and returns a list of subnets as output.
 func (ip *IPv4) Split(bits int) ([]*IPv4, error) {
-	// Make sure that the number of bits is greater than or equal to the prefix length
-	if ip.PrefixLength() > bits {
-		return nil, fmt.Errorf("the number of bits must be greater than or equal to the prefix length")
-	}
-
-	// Calculate the size of the subnets as defined by the number of bits
-	subnetSize := int(math.Pow(2, float64(32-bits)))
-
-	// Print the number of subnets
-	subnetCount := int(ip.NetworkSize()) / subnetSize
-
-	// Get the first subnet in the range
-	startSubnet := IPv4ToInt(ip.Network())
-
 	// List of subnets
-	subnets := make([]*IPv4, subnetCount)
-	err := error(nil)
+	var subnets []*IPv4
 
 	// Iterate over the subnets
-	for i := 0; i < subnetCount; i++ {
-		increment := uint32(i * subnetSize)
-		// Convert the subnet to dotted-decimal notation
-		subnet := IntToIPv4(startSubnet + increment)
-		// fmt.Printf("Subnet: %s/%d\n", subnet, bits)
-
-		// Parse the subnet
-		subnets[i], err = ParseIPv4(fmt.Sprintf("%s/%d", subnet, bits))
-		if err != nil {
-			return nil, err
-		}
+	err := ip.SplitFunc(bits, 0, func(index uint64, subnet *IPv4) bool {
+		subnets = append(subnets, subnet)
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return subnets, nil
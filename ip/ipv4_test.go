@@ -1,6 +1,8 @@
 package ip_test
 
 import (
+	"net/netip"
+	"strings"
 	"testing"
 
 	"github.com/bitcanon/iptool/ip"
@@ -412,3 +414,272 @@ func TestParseIPv4FromHex(t *testing.T) {
 		})
 	}
 }
+
+func TestIPv4SplitFunc(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		input         string
+		bits          int
+		offset        uint64
+		limit         int
+		expectedCount uint64
+		expected      []string
+	}{
+		{name: "Slash24To26", input: "10.0.0.0/24", bits: 26, limit: 10, expectedCount: 4, expected: []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"}},
+		{name: "Offset", input: "10.0.0.0/24", bits: 26, offset: 2, limit: 10, expectedCount: 4, expected: []string{"10.0.0.128/26", "10.0.0.192/26"}},
+		{name: "Limit", input: "10.0.0.0/8", bits: 30, offset: 1000, limit: 2, expectedCount: 4194304, expected: []string{"10.0.15.160/30", "10.0.15.164/30"}},
+		{name: "Slash0", input: "0.0.0.0/0", bits: 32, offset: 4294967294, limit: 10, expectedCount: 4294967296, expected: []string{"255.255.255.254/32", "255.255.255.255/32"}},
+		{name: "SameSize", input: "10.0.0.1/24", bits: 24, limit: 10, expectedCount: 1, expected: []string{"10.0.0.0/24"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ipv4, err := ip.ParseIPv4(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			count, err := ipv4.SubnetCount(tc.bits)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != tc.expectedCount {
+				t.Errorf("expected subnet count %d, got %d", tc.expectedCount, count)
+			}
+
+			var subnets []string
+			err = ipv4.SplitFunc(tc.bits, tc.offset, func(index uint64, subnet *ip.IPv4) bool {
+				subnets = append(subnets, subnet.String())
+				return len(subnets) < tc.limit
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(subnets) != len(tc.expected) {
+				t.Fatalf("expected subnets %v, got %v", tc.expected, subnets)
+			}
+			for i := range subnets {
+				if subnets[i] != tc.expected[i] {
+					t.Errorf("expected subnet %q, got %q", tc.expected[i], subnets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIPv4SubnetIndex(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		input       string
+		bits        int
+		addr        string
+		expected    uint64
+		expectError bool
+	}{
+		{name: "First", input: "10.0.0.0/16", bits: 24, addr: "10.0.0.0", expected: 0},
+		{name: "Inside", input: "10.0.0.0/16", bits: 24, addr: "10.0.2.0", expected: 2},
+		{name: "Unaligned", input: "10.0.0.0/16", bits: 24, addr: "10.0.2.77", expected: 2},
+		{name: "LastChild", input: "10.0.0.0/16", bits: 24, addr: "10.0.255.255", expected: 255},
+		{name: "HostBits", input: "10.0.1.1/16", bits: 30, addr: "10.0.1.5", expected: 65},
+		{name: "Slash0", input: "0.0.0.0/0", bits: 32, addr: "255.255.255.255", expected: 4294967295},
+		{name: "Before", input: "10.0.0.0/16", bits: 24, addr: "9.255.255.255", expectError: true},
+		{name: "After", input: "10.0.0.0/16", bits: 24, addr: "10.1.0.0", expectError: true},
+		{name: "InvalidAddress", input: "10.0.0.0/16", bits: 24, addr: "10.0.0", expectError: true},
+		{name: "InvalidBits", input: "10.0.0.0/16", bits: 8, addr: "10.0.0.1", expectError: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ipv4, err := ip.ParseIPv4(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			index, err := ipv4.SubnetIndex(tc.bits, tc.addr)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got index %d", index)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if index != tc.expected {
+				t.Errorf("expected index %d, got %d", tc.expected, index)
+			}
+
+			// The subnet with the index contains the address
+			subnet, err := ipv4.Subnet(tc.bits, index)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !subnet.Prefix().Contains(netip.MustParseAddr(tc.addr)) {
+				t.Errorf("subnet %s does not contain %s", subnet, tc.addr)
+			}
+		})
+	}
+}
+
+func TestIPv4SplitInvalidBits(t *testing.T) {
+	ipv4, err := ip.ParseIPv4("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ipv4.Split(16); err == nil {
+		t.Errorf("expected error when splitting into larger subnets")
+	}
+	if _, err := ipv4.Split(33); err == nil {
+		t.Errorf("expected error when splitting into more than 32 bits")
+	}
+}
+
+func TestIPv4Conversions(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name            string
+		input           string
+		expectedInt     uint32
+		expectedHex     string
+		expectedBinary  string
+		expectedDecimal string
+	}{
+		{name: "Zero", input: "0.0.0.0", expectedInt: 0, expectedHex: "00000000", expectedBinary: "00000000.00000000.00000000.00000000", expectedDecimal: "0"},
+		{name: "Private", input: "192.168.0.254", expectedInt: 3232235774, expectedHex: "c0a800fe", expectedBinary: "11000000.10101000.00000000.11111110", expectedDecimal: "3232235774"},
+		{name: "Max", input: "255.255.255.255", expectedInt: 4294967295, expectedHex: "ffffffff", expectedBinary: "11111111.11111111.11111111.11111111", expectedDecimal: "4294967295"},
+		{name: "IPv4Mapped", input: "::ffff:10.0.0.1", expectedInt: 167772161, expectedHex: "0a000001", expectedBinary: "00001010.00000000.00000000.00000001", expectedDecimal: "167772161"},
+		{name: "LeadingZero", input: "10.0.0.01"},
+		{name: "OctetTooLarge", input: "10.0.0.256"},
+		{name: "TooFewOctets", input: "10.0.0"},
+		{name: "TooManyOctets", input: "10.0.0.1.2"},
+		{name: "TrailingDot", input: "10.0.0.1."},
+		{name: "EmptyOctet", input: "10..0.1"},
+		{name: "IPv6", input: "2001:db8::1"},
+		{name: "Empty", input: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ip.IPv4ToInt(tc.input); got != tc.expectedInt {
+				t.Errorf("expected integer %d, got %d", tc.expectedInt, got)
+			}
+			if got := ip.IPv4ToHex(tc.input); got != tc.expectedHex {
+				t.Errorf("expected hex %q, got %q", tc.expectedHex, got)
+			}
+			if got := ip.IPv4ToBinary(tc.input); got != tc.expectedBinary {
+				t.Errorf("expected binary %q, got %q", tc.expectedBinary, got)
+			}
+			if got := ip.IPv4ToDecimal(tc.input); got != tc.expectedDecimal {
+				t.Errorf("expected decimal %q, got %q", tc.expectedDecimal, got)
+			}
+			if tc.expectedHex != "" && ip.IntToIPv4(tc.expectedInt) != strings.TrimPrefix(tc.input, "::ffff:") {
+				t.Errorf("expected address %q, got %q", tc.input, ip.IntToIPv4(tc.expectedInt))
+			}
+		})
+	}
+}
+
+func TestIPv4FromPrefix(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name              string
+		prefix            netip.Prefix
+		expectedString    string
+		expectedNetwork   string
+		expectedBroadcast string
+		expectErr         bool
+	}{
+		{name: "Prefix", prefix: netip.MustParsePrefix("192.168.100.14/22"), expectedString: "192.168.100.14/22", expectedNetwork: "192.168.100.0", expectedBroadcast: "192.168.103.255"},
+		{name: "Host", prefix: netip.MustParsePrefix("10.0.0.1/32"), expectedString: "10.0.0.1/32", expectedNetwork: "10.0.0.1", expectedBroadcast: "10.0.0.1"},
+		{name: "IPv4Mapped", prefix: netip.MustParsePrefix("::ffff:10.0.0.1/120"), expectedString: "10.0.0.1/24", expectedNetwork: "10.0.0.0", expectedBroadcast: "10.0.0.255"},
+		{name: "IPv6", prefix: netip.MustParsePrefix("2001:db8::/32"), expectErr: true},
+		{name: "Invalid", prefix: netip.Prefix{}, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ipv4, err := ip.IPv4FromPrefix(tc.prefix)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ipv4.String(); got != tc.expectedString {
+				t.Errorf("expected %q, got %q", tc.expectedString, got)
+			}
+			if got := ipv4.Network(); got != tc.expectedNetwork {
+				t.Errorf("expected network %q, got %q", tc.expectedNetwork, got)
+			}
+			if got := ipv4.Broadcast(); got != tc.expectedBroadcast {
+				t.Errorf("expected broadcast %q, got %q", tc.expectedBroadcast, got)
+			}
+			if got := ipv4.Prefix().String(); got != tc.expectedString {
+				t.Errorf("expected prefix %q, got %q", tc.expectedString, got)
+			}
+		})
+	}
+}
+
+func BenchmarkParseIPv4(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := ip.ParseIPv4("192.168.100.14/22"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseIPv4Hex(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := ip.ParseIPv4("0xc0a8640e 0xfffffc00"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIsIPv4Hex(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ip.IsIPv4Hex("0xc0a8640e")
+	}
+}
+
+func BenchmarkIPv4ToInt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ip.IPv4ToInt("192.168.100.14")
+	}
+}
+
+func BenchmarkIPv4Calculations(b *testing.B) {
+	ipv4, err := ip.ParseIPv4("192.168.100.14/22")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ipv4.Broadcast()
+		ipv4.FirstHost()
+		ipv4.LastHost()
+		ipv4.UsableHosts()
+	}
+}
+
+func BenchmarkIPv4Split(b *testing.B) {
+	ipv4, err := ip.ParseIPv4("10.0.0.0/8")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ipv4.SplitFunc(24, 0, func(index uint64, subnet *ip.IPv4) bool {
+			return index < 1000
+		})
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+var ErrMissingZone = errors.New("missing zone (interface) for link-local prefix")
+
+// ParseIPv6Prefix is a function that takes an IPv6 prefix with an optional
+// zone (e.g. "fe80::/64%eth0") as input and returns the prefix and the zone.
+// If no prefix length is given, a prefix length of 64 bits is assumed.
+func ParseIPv6Prefix(s string) (*net.IPNet, string, error) {
+	// Split off the zone (interface name) if present
+	zone := ""
+	if i := strings.LastIndex(s, "%"); i >= 0 {
+		zone = s[i+1:]
+		s = s[:i]
+	}
+
+	// If the input string does not contain a prefix length, assume a /64
+	if !strings.Contains(s, "/") {
+		s += "/64"
+	}
+
+	// Parse the input string
+	prefix, err := parsePrefix(s)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Make sure that the address is an IPv6 address
+	addr := prefix.Addr()
+	if addr.Is4() || addr.Is4In6() {
+		return nil, "", fmt.Errorf("invalid IPv6 prefix: %s", s)
+	}
+
+	// Link-local prefixes are only meaningful together with a zone
+	if addr.IsLinkLocalUnicast() && zone == "" {
+		return nil, "", ErrMissingZone
+	}
+
+	return prefixToIPNet(prefix.Masked()), zone, nil
+}
+
+// The IPv6 struct represents an IPv6 address as an IP address, an optional
+// zone (interface) and the network (prefix) that the address belongs to.
+type IPv6 struct {
+	IP   net.IP
+	Zone string
+	Net  *net.IPNet
+}
+
+// ParseIPv6 is a function that takes an IPv6 address with an optional prefix
+// length and zone as input (e.g. "2001:db8::1/64" or "fe80::1%eth0") and
+// returns an IPv6. If no prefix length is given, a prefix length of 64 bits
+// is assumed.
+func ParseIPv6(s string) (*IPv6, error) {
+	// Split off the zone (interface name) if present
+	zone := ""
+	if i := strings.LastIndex(s, "%"); i >= 0 {
+		zone = s[i+1:]
+		s = s[:i]
+	}
+
+	// If the input string does not contain a prefix length, assume a /64
+	if !strings.Contains(s, "/") {
+		s += "/64"
+	}
+
+	// Parse the input string
+	prefix, err := parsePrefix(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IPv6 address: %s", s)
+	}
+	ipv6, err := IPv6FromPrefix(prefix, zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IPv6 address: %s", s)
+	}
+	return ipv6, nil
+}
+
+// IPv6FromPrefix is a function that returns an IPv6 with the address and
+// prefix length of the prefix and the given zone (may be empty). An error is
+// returned if the prefix is not an IPv6 prefix.
+func IPv6FromPrefix(prefix netip.Prefix, zone string) (*IPv6, error) {
+	addr := prefix.Addr()
+	if !prefix.IsValid() || addr.Is4() || addr.Is4In6() {
+		return nil, fmt.Errorf("invalid IPv6 address: %s", prefix)
+	}
+	return &IPv6{IP: net.IP(addr.AsSlice()), Zone: zone, Net: prefixToIPNet(prefix.Masked())}, nil
+}
+
+// Addr is a function that returns the IPv6 address (including the zone) as a netip.Addr
+func (ip *IPv6) Addr() netip.Addr {
+	addr, _ := netip.AddrFromSlice(ip.IP.To16())
+	return addr.WithZone(ip.Zone)
+}
+
+// Prefix is a function that returns the IPv6 address and the prefix length as a netip.Prefix
+func (ip *IPv6) Prefix() netip.Prefix {
+	addr, _ := netip.AddrFromSlice(ip.IP.To16())
+	return netip.PrefixFrom(addr, ip.PrefixLength())
+}
+
+// Address is a function that returns the IPv6 address in compressed notation (RFC 5952)
+func (ip *IPv6) Address() string {
+	return ip.IP.String()
+}
+
+// Expanded is a function that returns the IPv6 address with all leading zeros
+// and all groups written out (e.g. 2001:0db8:0000:0000:0000:0000:0000:0001)
+func (ip *IPv6) Expanded() string {
+	return ExpandIPv6(ip.IP)
+}
+
+// Network is a function that returns the network address of the prefix
+func (ip *IPv6) Network() string {
+	return ip.Net.IP.String()
+}
+
+// PrefixLength is a function that returns the length of the prefix in bits
+func (ip *IPv6) PrefixLength() int {
+	ones, _ := ip.Net.Mask.Size()
+	return ones
+}
+
+// String is a function that returns the IP address and the prefix length in CIDR notation
+func (ip *IPv6) String() string {
+	return fmt.Sprintf("%s/%d", ip.IP.String(), ip.PrefixLength())
+}
+
+// LastAddress is a function that returns the last address in the prefix
+func (ip *IPv6) LastAddress() string {
+	return LastAddr(ip.Prefix()).String()
+}
+
+// NetworkSize is a function that returns the number of addresses in the prefix
+func (ip *IPv6) NetworkSize() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(128-ip.PrefixLength()))
+}
+
+// Type is a function that returns the type of the IPv6 address
+func (ip *IPv6) Type() string {
+	return IPv6Type(ip.IP)
+}
+
+// ExpandIPv6 is a function that returns the IPv6 address with all leading
+// zeros and all groups written out
+func ExpandIPv6(addr net.IP) string {
+	addr = addr.To16()
+	if addr == nil {
+		return ""
+	}
+	return netip.AddrFrom16([16]byte(addr)).StringExpanded()
+}
+
+// ipv6Types maps well-known IPv6 prefixes to a description of the address type.
+// The list is ordered from the most to the least specific prefix.
+var ipv6Types = []struct {
+	prefix      netip.Prefix
+	description string
+}{
+	{netip.MustParsePrefix("::1/128"), "Loopback"},
+	{netip.MustParsePrefix("::/128"), "Unspecified"},
+	{netip.MustParsePrefix("::ffff:0:0/96"), "IPv4-mapped"},
+	{netip.MustParsePrefix("64:ff9b::/96"), "IPv4/IPv6 translation (NAT64)"},
+	{netip.MustParsePrefix("2001:db8::/32"), "Documentation"},
+	{netip.MustParsePrefix("2002::/16"), "6to4"},
+	{netip.MustParsePrefix("2001::/32"), "Teredo"},
+	{netip.MustParsePrefix("fe80::/10"), "Link-local unicast"},
+	{netip.MustParsePrefix("fc00::/7"), "Unique local unicast"},
+	{netip.MustParsePrefix("ff00::/8"), "Multicast"},
+	{netip.MustParsePrefix("2000::/3"), "Global unicast"},
+}
+
+// IPv6Type is a function that returns a description of the type of the IPv6
+// address (e.g. "Global unicast" or "Link-local unicast")
+func IPv6Type(addr net.IP) string {
+	a, _ := netip.AddrFromSlice(addr.To16())
+	return ipv6Type(a)
+}
+
+// ipv6Type is a function that returns a description of the type of the IPv6 address
+func ipv6Type(addr netip.Addr) string {
+	for _, t := range ipv6Types {
+		if t.prefix.Contains(addr) {
+			return t.description
+		}
+	}
+	return "Reserved"
+}
+
+// SLAACAddress is a function that combines a /64 prefix with a 64-bit
+// interface identifier (e.g. a modified EUI-64) into an IPv6 address, the
+// way stateless address autoconfiguration (RFC 4862) does.
+func SLAACAddress(prefix *net.IPNet, interfaceID []byte) (net.IP, error) {
+	if ones, bits := prefix.Mask.Size(); ones != 64 || bits != 128 {
+		return nil, fmt.Errorf("SLAAC requires a /64 prefix, got %s", prefix)
+	}
+	if len(interfaceID) != 8 {
+		return nil, fmt.Errorf("invalid interface identifier length: %d (must be 8 bytes)", len(interfaceID))
+	}
+
+	addr := make(net.IP, net.IPv6len)
+	copy(addr[:8], prefix.IP.To16()[:8])
+	copy(addr[8:], interfaceID)
+	return addr, nil
+}
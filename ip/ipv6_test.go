@@ -0,0 +1,186 @@
+package ip_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestParseIPv6Prefix(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name         string
+		input        string
+		expectedNet  string
+		expectedZone string
+		expectErr    bool
+	}{
+		{name: "LinkLocalWithZone", input: "fe80::/64%eth0", expectedNet: "fe80::/64", expectedZone: "eth0"},
+		{name: "LinkLocalNoLength", input: "fe80::1%en0", expectedNet: "fe80::/64", expectedZone: "en0"},
+		{name: "GlobalWithZone", input: "2001:db8:1::/48%eth1", expectedNet: "2001:db8:1::/48", expectedZone: "eth1"},
+		{name: "GlobalWithoutZone", input: "2001:db8::/64", expectedNet: "2001:db8::/64", expectedZone: ""},
+		{name: "LinkLocalWithoutZone", input: "fe80::/64", expectErr: true},
+		{name: "IPv4Prefix", input: "10.0.0.0/24", expectErr: true},
+		{name: "Garbage", input: "not-a-prefix%eth0", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, zone, err := ip.ParseIPv6Prefix(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prefix.String() != tc.expectedNet {
+				t.Errorf("expected prefix %q, got %q", tc.expectedNet, prefix.String())
+			}
+			if zone != tc.expectedZone {
+				t.Errorf("expected zone %q, got %q", tc.expectedZone, zone)
+			}
+		})
+	}
+}
+
+func TestParseIPv6(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name             string
+		input            string
+		expectedAddress  string
+		expectedExpanded string
+		expectedNetwork  string
+		expectedLast     string
+		expectedType     string
+		expectErr        bool
+	}{
+		{name: "DefaultPrefix", input: "2001:db8::1", expectedAddress: "2001:db8::1", expectedExpanded: "2001:0db8:0000:0000:0000:0000:0000:0001", expectedNetwork: "2001:db8::/64", expectedLast: "2001:db8::ffff:ffff:ffff:ffff", expectedType: "Documentation"},
+		{name: "Prefix48", input: "2a00:1450:4001::/48", expectedAddress: "2a00:1450:4001::", expectedExpanded: "2a00:1450:4001:0000:0000:0000:0000:0000", expectedNetwork: "2a00:1450:4001::/48", expectedLast: "2a00:1450:4001:ffff:ffff:ffff:ffff:ffff", expectedType: "Global unicast"},
+		{name: "LinkLocalZone", input: "fe80::1%eth0", expectedAddress: "fe80::1", expectedExpanded: "fe80:0000:0000:0000:0000:0000:0000:0001", expectedNetwork: "fe80::/64", expectedLast: "fe80::ffff:ffff:ffff:ffff", expectedType: "Link-local unicast"},
+		{name: "UniqueLocal", input: "fd12:3456::1/128", expectedAddress: "fd12:3456::1", expectedExpanded: "fd12:3456:0000:0000:0000:0000:0000:0001", expectedNetwork: "fd12:3456::1/128", expectedLast: "fd12:3456::1", expectedType: "Unique local unicast"},
+		{name: "LeadingZeroPrefixLength", input: "2001:db8::1/064", expectedAddress: "2001:db8::1", expectedExpanded: "2001:0db8:0000:0000:0000:0000:0000:0001", expectedNetwork: "2001:db8::/64", expectedLast: "2001:db8::ffff:ffff:ffff:ffff", expectedType: "Documentation"},
+		{name: "IPv4", input: "192.168.0.1", expectErr: true},
+		{name: "IPv4Mapped", input: "::ffff:192.168.0.1/120", expectErr: true},
+		{name: "Invalid", input: "2001:db8::g", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ipv6, err := ip.ParseIPv6(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ipv6.Address(); got != tc.expectedAddress {
+				t.Errorf("expected address %s, got %s", tc.expectedAddress, got)
+			}
+			if got := ipv6.Expanded(); got != tc.expectedExpanded {
+				t.Errorf("expected expanded %s, got %s", tc.expectedExpanded, got)
+			}
+			if got := ipv6.Net.String(); got != tc.expectedNetwork {
+				t.Errorf("expected network %s, got %s", tc.expectedNetwork, got)
+			}
+			if got := ipv6.LastAddress(); got != tc.expectedLast {
+				t.Errorf("expected last address %s, got %s", tc.expectedLast, got)
+			}
+			if got := ipv6.Type(); got != tc.expectedType {
+				t.Errorf("expected type %s, got %s", tc.expectedType, got)
+			}
+		})
+	}
+}
+
+func TestSLAACAddress(t *testing.T) {
+	// Modified EUI-64 interface ID for MAC address 00:11:22:33:44:55
+	interfaceID := []byte{0x02, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55}
+
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		prefix      string
+		interfaceID []byte
+		expected    string
+		expectErr   bool
+	}{
+		{name: "Global", prefix: "2001:db8:1:2::/64", interfaceID: interfaceID, expected: "2001:db8:1:2:211:22ff:fe33:4455"},
+		{name: "LinkLocal", prefix: "fe80::/64", interfaceID: interfaceID, expected: "fe80::211:22ff:fe33:4455"},
+		{name: "NotSlash64", prefix: "2001:db8::/48", interfaceID: interfaceID, expectErr: true},
+		{name: "ShortInterfaceID", prefix: "2001:db8::/64", interfaceID: interfaceID[:6], expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, prefix, _ := net.ParseCIDR(tc.prefix)
+			addr, err := ip.SLAACAddress(prefix, tc.interfaceID)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if addr.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, addr)
+			}
+		})
+	}
+}
+
+func TestIPv6FromPrefix(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name           string
+		prefix         netip.Prefix
+		zone           string
+		expectedString string
+		expectedPrefix string
+		expectedAddr   string
+		expectErr      bool
+	}{
+		{name: "Global", prefix: netip.MustParsePrefix("2001:db8::1/48"), expectedString: "2001:db8::1/48", expectedPrefix: "2001:db8::1/48", expectedAddr: "2001:db8::1"},
+		{name: "Zone", prefix: netip.MustParsePrefix("fe80::1/64"), zone: "eth0", expectedString: "fe80::1/64", expectedPrefix: "fe80::1/64", expectedAddr: "fe80::1%eth0"},
+		{name: "IPv4", prefix: netip.MustParsePrefix("10.0.0.0/8"), expectErr: true},
+		{name: "Invalid", prefix: netip.Prefix{}, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ipv6, err := ip.IPv6FromPrefix(tc.prefix, tc.zone)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ipv6.String(); got != tc.expectedString {
+				t.Errorf("expected %s, got %s", tc.expectedString, got)
+			}
+			if got := ipv6.Prefix().String(); got != tc.expectedPrefix {
+				t.Errorf("expected prefix %s, got %s", tc.expectedPrefix, got)
+			}
+			if got := ipv6.Addr().String(); got != tc.expectedAddr {
+				t.Errorf("expected address %s, got %s", tc.expectedAddr, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// AllocateLoopbacks is a function that allocates count loopback addresses
+// (/32 or /128 host prefixes) in order from the start of the parent prefix.
+// The network and broadcast addresses of IPv4 prefixes and the
+// Subnet-Router anycast address of IPv6 prefixes are skipped, unless the
+// prefix is a /31 (or /127) or smaller.
+func AllocateLoopbacks(parent netip.Prefix, count int) ([]netip.Prefix, error) {
+	parent = parent.Masked()
+	bits := parent.Addr().BitLen()
+
+	// Find the first and the last address that may be allocated
+	first, last := parent.Addr(), LastAddr(parent)
+	if bits-parent.Bits() > 1 {
+		first = first.Next()
+		if parent.Addr().Is4() {
+			last = last.Prev()
+		}
+	}
+
+	// Check that the addresses fit, the capacity of IPv6 prefixes is capped
+	capacity := LinkCapacity(parent, bits)
+	if bits-parent.Bits() > 1 {
+		capacity--
+		if parent.Addr().Is4() {
+			capacity--
+		}
+	}
+	if count > capacity {
+		return nil, fmt.Errorf("%s holds %d loopback addresses, %d requested", parent, capacity, count)
+	}
+
+	loopbacks := make([]netip.Prefix, 0, count)
+	for addr := first; len(loopbacks) < count && addr.Compare(last) <= 0; addr = addr.Next() {
+		loopbacks = append(loopbacks, netip.PrefixFrom(addr, bits))
+	}
+	return loopbacks, nil
+}
@@ -0,0 +1,48 @@
+package ip_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestAllocateLoopbacks(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		parent    string
+		count     int
+		expected  []string
+		expectErr bool
+	}{
+		{name: "IPv4", parent: "10.255.255.0/24", count: 3, expected: []string{"10.255.255.1/32", "10.255.255.2/32", "10.255.255.3/32"}},
+		{name: "IPv4Full", parent: "10.255.255.0/30", count: 2, expected: []string{"10.255.255.1/32", "10.255.255.2/32"}},
+		{name: "IPv4Slash31", parent: "10.255.255.0/31", count: 2, expected: []string{"10.255.255.0/32", "10.255.255.1/32"}},
+		{name: "IPv4Slash32", parent: "10.255.255.7/32", count: 1, expected: []string{"10.255.255.7/32"}},
+		{name: "IPv6", parent: "2001:db8:ffff::/64", count: 2, expected: []string{"2001:db8:ffff::1/128", "2001:db8:ffff::2/128"}},
+		{name: "IPv6Slash126", parent: "2001:db8:ffff::/126", count: 3, expected: []string{"2001:db8:ffff::1/128", "2001:db8:ffff::2/128", "2001:db8:ffff::3/128"}},
+		{name: "TooMany", parent: "10.255.255.0/30", count: 3, expectErr: true},
+		{name: "TooManyIPv6", parent: "2001:db8:ffff::/126", count: 4, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loopbacks, err := ip.AllocateLoopbacks(netip.MustParsePrefix(tc.parent), tc.count)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := prefixStrings(loopbacks); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
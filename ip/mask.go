@@ -0,0 +1,270 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var ErrDiscontiguousNetmask = errors.New("non-contiguous netmask")
+
+// IsContiguousMask is a function that returns true if the one bits in the
+// mask are contiguous from the left (e.g. 255.255.240.0) and false if they
+// are not (e.g. 255.0.255.0).
+func IsContiguousMask(mask uint32) bool {
+	inverted := ^mask
+	return inverted&(inverted+1) == 0
+}
+
+// parseMask is a function that parses a netmask in dotted-decimal notation
+// and returns it as a 32-bit integer without checking for contiguity.
+func parseMask(mask string) (uint32, error) {
+	value, ok := parseIPv4Int(mask)
+	if !ok || !strings.Contains(mask, ".") {
+		return 0, ErrInvalidNetmask
+	}
+	return value, nil
+}
+
+// MaskedIPv4 represents an IPv4 address combined with an arbitrary mask.
+// Unlike IPv4, the mask does not have to be contiguous, which gives the mask
+// the semantics of an ACL wildcard mask (e.g. 10.0.0.0 0.255.0.255).
+type MaskedIPv4 struct {
+	IP   uint32
+	Mask uint32
+}
+
+// ParseIPv4Masked is a function that takes an IPv4 address and a mask
+// separated by a space or a slash as input (e.g. "10.0.0.1 255.0.255.0") and
+// returns a MaskedIPv4. Both the address and the mask can be given in
+// dotted-decimal or hexadecimal notation, and the mask may be discontiguous.
+func ParseIPv4Masked(s string) (*MaskedIPv4, error) {
+	// Try to split the input string into an IP address and a netmask
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == ' '
+	})
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid IP address and mask: %s", s)
+	}
+
+	// If a part is in hexadecimal notation, convert it to dotted-decimal notation
+	for i := range parts {
+		if IsIPv4Hex(parts[i]) {
+			ipv4, err := ParseIPv4FromHex(parts[i])
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = ipv4
+		}
+	}
+
+	// Parse the IP address
+	if !IsIPv4(parts[0]) {
+		return nil, fmt.Errorf("invalid IP address: %s", parts[0])
+	}
+
+	// Parse the mask
+	mask, err := parseMask(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaskedIPv4{IP: IPv4ToInt(parts[0]), Mask: mask}, nil
+}
+
+// Address is a function that returns the IP address in dotted-decimal notation
+func (m *MaskedIPv4) Address() string {
+	return IntToIPv4(m.IP)
+}
+
+// Netmask is a function that returns the mask in dotted-decimal notation
+func (m *MaskedIPv4) Netmask() string {
+	return IntToIPv4(m.Mask)
+}
+
+// Wildcard is a function that returns the inverted mask in dotted-decimal notation
+func (m *MaskedIPv4) Wildcard() string {
+	return IntToIPv4(^m.Mask)
+}
+
+// Base is a function that returns the address with all wildcard bits cleared,
+// i.e. the address used together with the wildcard mask in an ACL entry
+func (m *MaskedIPv4) Base() string {
+	return IntToIPv4(m.IP & m.Mask)
+}
+
+// Contiguous is a function that returns true if the mask is a contiguous netmask
+func (m *MaskedIPv4) Contiguous() bool {
+	return IsContiguousMask(m.Mask)
+}
+
+// MatchCount is a function that returns the number of addresses matched by
+// the address and wildcard mask combination
+func (m *MaskedIPv4) MatchCount() uint64 {
+	return uint64(1) << bits.OnesCount32(^m.Mask)
+}
+
+// Matches is a function that returns true if the address addr (in
+// dotted-decimal notation) is matched by the address and wildcard mask
+func (m *MaskedIPv4) Matches(addr string) bool {
+	return IPv4ToInt(addr)&m.Mask == m.IP&m.Mask
+}
+
+// ACLEntry is a function that returns the address and wildcard mask in the
+// format used by access control lists (e.g. "10.0.0.0 0.255.0.255")
+func (m *MaskedIPv4) ACLEntry() string {
+	return fmt.Sprintf("%s %s", m.Base(), m.Wildcard())
+}
+
+// Mask represents an IPv4 netmask as a 32-bit integer
+type Mask uint32
+
+// MaskFormat is the notation a mask is given in
+type MaskFormat string
+
+const (
+	// MaskFormatPrefix is a prefix length, e.g. /24
+	MaskFormatPrefix MaskFormat = "prefix length"
+	// MaskFormatNetmask is a dotted-decimal netmask, e.g. 255.255.255.0
+	MaskFormatNetmask MaskFormat = "netmask"
+	// MaskFormatWildcard is a dotted-decimal (or hexadecimal) wildcard
+	// mask, e.g. 0.0.0.255
+	MaskFormatWildcard MaskFormat = "wildcard mask"
+	// MaskFormatHex is a hexadecimal netmask, e.g. 0xffffff00
+	MaskFormatHex MaskFormat = "hexadecimal"
+)
+
+// MaskFromIPMask is a function that returns the mask of an IPv4 net.IPMask
+// (4 bytes, or 16 bytes with the IPv4 mask in the last 4 bytes)
+func MaskFromIPMask(m net.IPMask) Mask {
+	if len(m) < 4 {
+		return 0
+	}
+	m = m[len(m)-4:]
+	return Mask(uint32(m[0])<<24 | uint32(m[1])<<16 | uint32(m[2])<<8 | uint32(m[3]))
+}
+
+// ParseMask is a function that takes an IPv4 netmask in any of the common
+// formats as input and returns the netmask. The accepted formats are:
+// - Prefix length: "/24" or "24"
+// - Dotted-decimal netmask: "255.255.255.0"
+// - Dotted-decimal wildcard mask: "0.0.0.255"
+// - Hexadecimal: "ffffff00" or "0xffffff00"
+//
+// A dotted-decimal mask is treated as a wildcard mask if it is not a
+// contiguous netmask, but its inverse is. The returned netmask may be
+// discontiguous (e.g. 255.0.255.0), use Contiguous to check.
+func ParseMask(s string) (Mask, error) {
+	mask, _, err := ParseMaskFormat(s)
+	return mask, err
+}
+
+// ParseMaskFormat is a function that parses a mask like ParseMask, and also
+// returns the notation the mask was given in. A mask that was inverted
+// because it is a wildcard mask is reported as a wildcard mask, also when it
+// was given in hexadecimal notation.
+func ParseMaskFormat(s string) (Mask, MaskFormat, error) {
+	s = strings.TrimSpace(s)
+
+	// Prefix length, with or without a leading slash
+	if n, err := strconv.Atoi(strings.TrimPrefix(s, "/")); err == nil && !IsIPv4Hex(s) {
+		if n < 0 || n > 32 {
+			return 0, "", fmt.Errorf("invalid prefix length: %d (must be between 0 and 32)", n)
+		}
+		return MaskFromPrefixLength(n), MaskFormatPrefix, nil
+	}
+
+	// Hexadecimal notation
+	mask, format := uint32(0), MaskFormatHex
+	if value, ok := parseHex(s); ok {
+		mask = value
+	} else {
+		// Dotted-decimal netmask or wildcard mask
+		value, err := parseMask(s)
+		if err != nil {
+			return 0, "", fmt.Errorf("%w: %s", ErrInvalidNetmask, s)
+		}
+		mask, format = value, MaskFormatNetmask
+	}
+	if !IsContiguousMask(mask) && IsContiguousMask(^mask) {
+		return Mask(^mask), MaskFormatWildcard, nil
+	}
+	return Mask(mask), format, nil
+}
+
+// MaskFromPrefixLength is a function that returns the contiguous mask with the
+// given number of bits set (0-32)
+func MaskFromPrefixLength(n int) Mask {
+	return Mask(^uint32(0) << (32 - n))
+}
+
+// Contiguous is a function that returns true if the mask is a contiguous netmask
+func (m Mask) Contiguous() bool {
+	return IsContiguousMask(uint32(m))
+}
+
+// PrefixLength is a function that returns the number of bits set in the mask
+func (m Mask) PrefixLength() int {
+	return bits.OnesCount32(uint32(m))
+}
+
+// Netmask is a function that returns the mask in dotted-decimal notation
+func (m Mask) Netmask() string {
+	return IntToIPv4(uint32(m))
+}
+
+// Wildcard is a function that returns the inverted mask in dotted-decimal notation
+func (m Mask) Wildcard() string {
+	return IntToIPv4(^uint32(m))
+}
+
+// Hex is a function that returns the mask in hexadecimal notation
+func (m Mask) Hex() string {
+	const digits = "0123456789abcdef"
+	buf := []byte("0x00000000")
+	for i := 9; i >= 2; i-- {
+		buf[i] = digits[m&0xf]
+		m >>= 4
+	}
+	return string(buf)
+}
+
+// Binary is a function that returns the mask in dotted binary notation
+func (m Mask) Binary() string {
+	return IPv4ToBinary(m.Netmask())
+}
+
+// Invert is a function that returns the inverted mask, e.g. the wildcard
+// mask 0.0.7.255 of the netmask 255.255.248.0
+func (m Mask) Invert() Mask {
+	return ^m
+}
+
+// NetworkSize is a function that returns the number of addresses covered by the mask
+func (m Mask) NetworkSize() uint64 {
+	return uint64(1) << bits.OnesCount32(^uint32(m))
+}
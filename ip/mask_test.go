@@ -0,0 +1,189 @@
+package ip_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestNetmaskPrefixLength(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		input       string
+		expected    int
+		expectedErr error
+	}{
+		{name: "Slash24", input: "255.255.255.0", expected: 24},
+		{name: "Slash20", input: "255.255.240.0", expected: 20},
+		{name: "Slash32", input: "255.255.255.255", expected: 32},
+		{name: "Slash0", input: "0.0.0.0", expected: 0},
+		{name: "Discontiguous", input: "255.0.255.0", expectedErr: ip.ErrDiscontiguousNetmask},
+		{name: "WildcardMask", input: "0.0.0.255", expectedErr: ip.ErrDiscontiguousNetmask},
+		{name: "NotAMask", input: "255.255.255", expectedErr: ip.ErrInvalidNetmask},
+		{name: "IPv6", input: "ffff::", expectedErr: ip.ErrInvalidNetmask},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ones, err := ip.NetmaskPrefixLength(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if ones != tc.expected {
+				t.Errorf("expected prefix length %d, got %d", tc.expected, ones)
+			}
+		})
+	}
+}
+
+func TestParseIPv4Masked(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name             string
+		input            string
+		expectedWildcard string
+		expectedACL      string
+		expectedCount    uint64
+		expectedContig   bool
+	}{
+		{name: "Discontiguous", input: "10.1.2.3 255.0.255.0", expectedWildcard: "0.255.0.255", expectedACL: "10.0.2.0 0.255.0.255", expectedCount: 65536},
+		{name: "OddHosts", input: "10.0.0.1 255.255.255.1", expectedWildcard: "0.0.0.254", expectedACL: "10.0.0.1 0.0.0.254", expectedCount: 128},
+		{name: "Contiguous", input: "10.0.0.1/255.255.255.0", expectedWildcard: "0.0.0.255", expectedACL: "10.0.0.0 0.0.0.255", expectedCount: 256, expectedContig: true},
+		{name: "Hex", input: "0a010203 ff00ff00", expectedWildcard: "0.255.0.255", expectedACL: "10.0.2.0 0.255.0.255", expectedCount: 65536},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			masked, err := ip.ParseIPv4Masked(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if masked.Wildcard() != tc.expectedWildcard {
+				t.Errorf("expected wildcard %q, got %q", tc.expectedWildcard, masked.Wildcard())
+			}
+			if masked.ACLEntry() != tc.expectedACL {
+				t.Errorf("expected ACL entry %q, got %q", tc.expectedACL, masked.ACLEntry())
+			}
+			if masked.MatchCount() != tc.expectedCount {
+				t.Errorf("expected match count %d, got %d", tc.expectedCount, masked.MatchCount())
+			}
+			if masked.Contiguous() != tc.expectedContig {
+				t.Errorf("expected contiguous %t, got %t", tc.expectedContig, masked.Contiguous())
+			}
+		})
+	}
+}
+
+func TestParseMask(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name               string
+		input              string
+		expectedNetmask    string
+		expectedPrefix     int
+		expectedContiguous bool
+		expectErr          bool
+	}{
+		{name: "PrefixWithSlash", input: "/24", expectedNetmask: "255.255.255.0", expectedPrefix: 24, expectedContiguous: true},
+		{name: "PrefixWithoutSlash", input: "20", expectedNetmask: "255.255.240.0", expectedPrefix: 20, expectedContiguous: true},
+		{name: "PrefixZero", input: "/0", expectedNetmask: "0.0.0.0", expectedPrefix: 0, expectedContiguous: true},
+		{name: "Prefix32", input: "/32", expectedNetmask: "255.255.255.255", expectedPrefix: 32, expectedContiguous: true},
+		{name: "Netmask", input: "255.255.252.0", expectedNetmask: "255.255.252.0", expectedPrefix: 22, expectedContiguous: true},
+		{name: "Wildcard", input: "0.0.3.255", expectedNetmask: "255.255.252.0", expectedPrefix: 22, expectedContiguous: true},
+		{name: "Hex", input: "ffffff00", expectedNetmask: "255.255.255.0", expectedPrefix: 24, expectedContiguous: true},
+		{name: "HexWithPrefix", input: "0xfffffff0", expectedNetmask: "255.255.255.240", expectedPrefix: 28, expectedContiguous: true},
+		{name: "Discontiguous", input: "255.0.255.0", expectedNetmask: "255.0.255.0", expectedPrefix: 16, expectedContiguous: false},
+		{name: "PrefixTooLong", input: "/33", expectErr: true},
+		{name: "Negative", input: "/-1", expectErr: true},
+		{name: "Invalid", input: "255.255.255", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mask, err := ip.ParseMask(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := mask.Netmask(); got != tc.expectedNetmask {
+				t.Errorf("expected netmask %s, got %s", tc.expectedNetmask, got)
+			}
+			if got := mask.PrefixLength(); got != tc.expectedPrefix {
+				t.Errorf("expected prefix length %d, got %d", tc.expectedPrefix, got)
+			}
+			if got := mask.Contiguous(); got != tc.expectedContiguous {
+				t.Errorf("expected contiguous %t, got %t", tc.expectedContiguous, got)
+			}
+		})
+	}
+}
+
+func TestParseMaskFormat(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input          string
+		expectedFormat ip.MaskFormat
+		expectedMask   string
+		expectedInvert string
+	}{
+		{input: "/20", expectedFormat: ip.MaskFormatPrefix, expectedMask: "255.255.240.0", expectedInvert: "0.0.15.255"},
+		{input: "255.255.240.0", expectedFormat: ip.MaskFormatNetmask, expectedMask: "255.255.240.0", expectedInvert: "0.0.15.255"},
+		{input: "0.0.7.255", expectedFormat: ip.MaskFormatWildcard, expectedMask: "255.255.248.0", expectedInvert: "0.0.7.255"},
+		{input: "0xfffff000", expectedFormat: ip.MaskFormatHex, expectedMask: "255.255.240.0", expectedInvert: "0.0.15.255"},
+		{input: "00000fff", expectedFormat: ip.MaskFormatWildcard, expectedMask: "255.255.240.0", expectedInvert: "0.0.15.255"},
+		{input: "255.255.255.255", expectedFormat: ip.MaskFormatNetmask, expectedMask: "255.255.255.255", expectedInvert: "0.0.0.0"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			mask, format, err := ip.ParseMaskFormat(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format != tc.expectedFormat {
+				t.Errorf("expected format %s, got %s", tc.expectedFormat, format)
+			}
+			if got := mask.Netmask(); got != tc.expectedMask {
+				t.Errorf("expected netmask %s, got %s", tc.expectedMask, got)
+			}
+			if got := mask.Invert().Netmask(); got != tc.expectedInvert {
+				t.Errorf("expected inverted mask %s, got %s", tc.expectedInvert, got)
+			}
+		})
+	}
+}
+
+func TestMaskFromIPMask(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    net.IPMask
+		expected string
+	}{
+		{name: "IPv4", input: net.CIDRMask(22, 32), expected: "255.255.252.0"},
+		{name: "Bytes", input: net.IPv4Mask(255, 255, 255, 128), expected: "255.255.255.128"},
+		{name: "Sixteen", input: append(net.IPMask(make([]byte, 12)), 255, 0, 0, 0), expected: "255.0.0.0"},
+		{name: "Nil", input: nil, expected: "0.0.0.0"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ip.MaskFromIPMask(tc.input).Netmask(); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
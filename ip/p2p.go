@@ -0,0 +1,80 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Link is a point-to-point link subnet and the addresses of its two ends
+type Link struct {
+	Prefix netip.Prefix
+	A      netip.Addr
+	B      netip.Addr
+}
+
+// LinkCapacity is a function that returns the number of link subnets with
+// the prefix length bits that fit in the parent prefix, capped at the
+// largest int so that the capacity of IPv6 prefixes does not overflow
+func LinkCapacity(parent netip.Prefix, bits int) int {
+	shift := bits - parent.Bits()
+	switch {
+	case shift < 0:
+		return 0
+	case shift >= 62:
+		return int(^uint(0) >> 1)
+	}
+	return 1 << shift
+}
+
+// AllocateLinks is a function that allocates count point-to-point link
+// subnets with the prefix length bits from the start of the parent prefix.
+// The ends of /31 and /127 links (RFC 3021 and RFC 6164) are the two
+// addresses of the subnet, the ends of larger links (e.g. /30) are the
+// first two usable addresses (after the network address).
+func AllocateLinks(parent netip.Prefix, bits, count int) ([]Link, error) {
+	parent = parent.Masked()
+	hostBits := parent.Addr().BitLen() - bits
+	if hostBits < 1 || hostBits > 2 {
+		return nil, fmt.Errorf("invalid link prefix length: /%d (must be /%d or /%d)", bits, parent.Addr().BitLen()-2, parent.Addr().BitLen()-1)
+	}
+	if bits < parent.Bits() {
+		return nil, fmt.Errorf("%s is too small for a /%d link", parent, bits)
+	}
+	if capacity := LinkCapacity(parent, bits); count > capacity {
+		return nil, fmt.Errorf("%s holds %d /%d links, %d links requested", parent, capacity, bits, count)
+	}
+
+	links := make([]Link, 0, count)
+	addr := parent.Addr()
+	for i := 0; i < count; i++ {
+		link := Link{Prefix: netip.PrefixFrom(addr, bits), A: addr, B: addr.Next()}
+		if hostBits == 2 {
+			// Skip the network address of the subnet
+			link.A, link.B = link.B, link.B.Next()
+		}
+		links = append(links, link)
+		addr = LastAddr(link.Prefix).Next()
+	}
+	return links, nil
+}
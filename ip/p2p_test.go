@@ -0,0 +1,90 @@
+package ip_test
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestAllocateLinks(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		parent    string
+		bits      int
+		count     int
+		expected  []string
+		expectErr bool
+	}{
+		{name: "Slash30", parent: "10.255.0.0/24", bits: 30, count: 3, expected: []string{
+			"10.255.0.0/30 10.255.0.1 10.255.0.2",
+			"10.255.0.4/30 10.255.0.5 10.255.0.6",
+			"10.255.0.8/30 10.255.0.9 10.255.0.10",
+		}},
+		{name: "Slash31", parent: "10.255.0.0/24", bits: 31, count: 2, expected: []string{
+			"10.255.0.0/31 10.255.0.0 10.255.0.1",
+			"10.255.0.2/31 10.255.0.2 10.255.0.3",
+		}},
+		{name: "HostBits", parent: "10.255.0.9/29", bits: 31, count: 1, expected: []string{
+			"10.255.0.8/31 10.255.0.8 10.255.0.9",
+		}},
+		{name: "Full", parent: "10.255.0.0/29", bits: 30, count: 2, expected: []string{
+			"10.255.0.0/30 10.255.0.1 10.255.0.2",
+			"10.255.0.4/30 10.255.0.5 10.255.0.6",
+		}},
+		{name: "IPv6", parent: "2001:db8:ff::/64", bits: 127, count: 2, expected: []string{
+			"2001:db8:ff::/127 2001:db8:ff:: 2001:db8:ff::1",
+			"2001:db8:ff::2/127 2001:db8:ff::2 2001:db8:ff::3",
+		}},
+		{name: "TooMany", parent: "10.255.0.0/29", bits: 30, count: 3, expectErr: true},
+		{name: "InvalidSize", parent: "10.255.0.0/24", bits: 29, count: 1, expectErr: true},
+		{name: "ParentTooSmall", parent: "10.255.0.0/31", bits: 30, count: 1, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			links, err := ip.AllocateLinks(netip.MustParsePrefix(tc.parent), tc.bits, tc.count)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(links) != len(tc.expected) {
+				t.Fatalf("expected %d links, got %d", len(tc.expected), len(links))
+			}
+			for i, link := range links {
+				if got := fmt.Sprintf("%s %s %s", link.Prefix, link.A, link.B); got != tc.expected[i] {
+					t.Errorf("expected %s, got %s", tc.expected[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkCapacity(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		parent   string
+		bits     int
+		expected int
+	}{
+		{parent: "10.255.0.0/24", bits: 30, expected: 64},
+		{parent: "10.255.0.0/24", bits: 31, expected: 128},
+		{parent: "10.255.0.0/31", bits: 30, expected: 0},
+		{parent: "2001:db8::/32", bits: 127, expected: int(^uint(0) >> 1)},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		if got := ip.LinkCapacity(netip.MustParsePrefix(tc.parent), tc.bits); got != tc.expected {
+			t.Errorf("%s /%d: expected %d, got %d", tc.parent, tc.bits, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Overlap is a pair of overlapping prefixes, the first prefix contains the
+// second (or the prefixes are the same)
+type Overlap struct {
+	Outer netip.Prefix
+	Inner netip.Prefix
+}
+
+// ParsePrefix is a function that parses a prefix in CIDR notation, or a
+// single address (as a /32 or /128 prefix). Host bits are cleared, so that
+// 10.0.0.1/24 is parsed as 10.0.0.0/24.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid prefix: %s", s)
+		}
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix: %s", s)
+	}
+	return prefix.Masked(), nil
+}
+
+// ParsePrefixes is a function that parses a list of prefixes separated by
+// newlines, commas or whitespace. Everything after a # on a line is ignored.
+func ParsePrefixes(r io.Reader) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})
+		for _, field := range fields {
+			prefix, err := ParsePrefix(field)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return prefixes, nil
+}
+
+// ComparePrefixes is a function that compares two prefixes. IPv4 prefixes
+// are sorted before IPv6 prefixes, then by address and prefix length, so
+// that a prefix is sorted before the prefixes it contains.
+func ComparePrefixes(a, b netip.Prefix) int {
+	if a.Addr().Is4() != b.Addr().Is4() {
+		if a.Addr().Is4() {
+			return -1
+		}
+		return 1
+	}
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+	return a.Bits() - b.Bits()
+}
+
+// SortPrefixes is a function that sorts a list of prefixes in place, see
+// ComparePrefixes for the order
+func SortPrefixes(prefixes []netip.Prefix) {
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return ComparePrefixes(prefixes[i], prefixes[j]) < 0
+	})
+}
+
+// DedupePrefixes is a function that returns the prefixes of a sorted list
+// without duplicates, keeping the first occurrence of each prefix
+func DedupePrefixes(sorted []netip.Prefix) []netip.Prefix {
+	var result []netip.Prefix
+	for _, p := range sorted {
+		if n := len(result); n > 0 && result[n-1] == p {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// RemoveContained is a function that returns the prefixes of a sorted list
+// that are not contained in another prefix of the list. Duplicates are
+// removed as well, as a prefix is contained in itself.
+func RemoveContained(sorted []netip.Prefix) []netip.Prefix {
+	var result []netip.Prefix
+	for _, p := range sorted {
+		if n := len(result); n > 0 && result[n-1].Bits() <= p.Bits() && result[n-1].Contains(p.Addr()) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// Summarize is a function that returns the smallest list of prefixes that
+// covers exactly the same addresses as the given prefixes. Duplicates and
+// prefixes contained in other prefixes are removed, and adjacent prefixes
+// are merged into their common parent where possible.
+func Summarize(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, len(prefixes))
+	for i, p := range prefixes {
+		sorted[i] = p.Masked()
+	}
+	SortPrefixes(sorted)
+
+	var result []netip.Prefix
+	for _, p := range RemoveContained(sorted) {
+		result = append(result, p)
+
+		// Merge the last two prefixes into their parent as long as they are siblings
+		for n := len(result); n >= 2; n = len(result) {
+			a, b := result[n-2], result[n-1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().Is4() != b.Addr().Is4() {
+				break
+			}
+			parent, _ := a.Addr().Prefix(a.Bits() - 1)
+			if !parent.Contains(b.Addr()) {
+				break
+			}
+			result = append(result[:n-2], parent)
+		}
+	}
+
+	return result
+}
+
+// FindOverlaps is a function that returns all pairs of prefixes in the list
+// that overlap, including duplicates. The pairs are sorted by the outer
+// prefix and then by the inner prefix.
+func FindOverlaps(prefixes []netip.Prefix) []Overlap {
+	sorted := make([]netip.Prefix, len(prefixes))
+	copy(sorted, prefixes)
+	SortPrefixes(sorted)
+
+	var overlaps []Overlap
+
+	// The stack holds the chain of prefixes containing the current prefix,
+	// prefixes either contain each other or do not overlap at all
+	var stack []netip.Prefix
+	for _, p := range sorted {
+		for len(stack) > 0 && !stack[len(stack)-1].Contains(p.Addr()) {
+			stack = stack[:len(stack)-1]
+		}
+		for _, outer := range stack {
+			overlaps = append(overlaps, Overlap{Outer: outer, Inner: p})
+		}
+		stack = append(stack, p)
+	}
+
+	return overlaps
+}
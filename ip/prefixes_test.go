@@ -0,0 +1,184 @@
+package ip_test
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// prefixStrings is a helper that converts a list of prefixes to strings
+func prefixStrings(prefixes []netip.Prefix) []string {
+	var result []string
+	for _, p := range prefixes {
+		result = append(result, p.String())
+	}
+	return result
+}
+
+func TestParsePrefixes(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "Empty", input: "", expected: nil},
+		{name: "Newlines", input: "10.0.0.0/24\n10.0.1.0/24\n", expected: []string{"10.0.0.0/24", "10.0.1.0/24"}},
+		{name: "CommasAndSpaces", input: "10.0.0.0/24, 10.0.1.0/24 10.0.2.0/24", expected: []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"}},
+		{name: "Comments", input: "# routes\n10.0.0.0/8 # private\n\n", expected: []string{"10.0.0.0/8"}},
+		{name: "Address", input: "192.0.2.1,2001:db8::1", expected: []string{"192.0.2.1/32", "2001:db8::1/128"}},
+		{name: "HostBits", input: "10.0.0.1/24", expected: []string{"10.0.0.0/24"}},
+		{name: "WindowsLineEndings", input: "10.0.0.0/24\r\n10.0.1.0/24\r\n", expected: []string{"10.0.0.0/24", "10.0.1.0/24"}},
+		{name: "Invalid", input: "10.0.0.0/24\nfoo\n", expectErr: true},
+		{name: "InvalidLength", input: "10.0.0.0/33", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := ip.ParsePrefixes(strings.NewReader(tc.input))
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := prefixStrings(prefixes); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "Empty", input: "", expected: nil},
+		{name: "Siblings", input: "10.0.0.0/24 10.0.1.0/24", expected: []string{"10.0.0.0/23"}},
+		{name: "NotSiblings", input: "10.0.1.0/24 10.0.2.0/24", expected: []string{"10.0.1.0/24", "10.0.2.0/24"}},
+		{name: "Cascade", input: "10.0.0.0/24 10.0.1.0/25 10.0.1.128/25 10.0.2.0/23", expected: []string{"10.0.0.0/22"}},
+		{name: "Unsorted", input: "10.0.3.0/24 10.0.1.0/24 10.0.2.0/24 10.0.0.0/24", expected: []string{"10.0.0.0/22"}},
+		{name: "Duplicates", input: "10.0.0.0/24 10.0.0.0/24", expected: []string{"10.0.0.0/24"}},
+		{name: "Contained", input: "10.0.0.0/16 10.0.4.0/22 10.0.0.1", expected: []string{"10.0.0.0/16"}},
+		{name: "WholeAddressSpace", input: "0.0.0.0/1 128.0.0.0/1", expected: []string{"0.0.0.0/0"}},
+		{name: "Mixed", input: "2001:db8:8000::/33 10.0.0.0/25 2001:db8::/33 10.0.0.128/25", expected: []string{"10.0.0.0/24", "2001:db8::/32"}},
+		{name: "FamiliesNotMerged", input: "0.0.0.0/0 ::/0", expected: []string{"0.0.0.0/0", "::/0"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := ip.ParsePrefixes(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := prefixStrings(ip.Summarize(prefixes)); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{name: "None", input: "10.0.0.0/24 10.0.1.0/24 2001:db8::/32", expected: nil},
+		{name: "Contained", input: "10.0.4.0/22 10.0.0.0/16", expected: []string{"10.0.0.0/16>10.0.4.0/22"}},
+		{name: "Duplicate", input: "10.0.0.0/24 10.0.0.0/24", expected: []string{"10.0.0.0/24>10.0.0.0/24"}},
+		{name: "Nested", input: "10.0.0.0/8 10.1.0.0/16 10.1.1.0/24 10.2.0.0/16", expected: []string{"10.0.0.0/8>10.1.0.0/16", "10.0.0.0/8>10.1.1.0/24", "10.1.0.0/16>10.1.1.0/24", "10.0.0.0/8>10.2.0.0/16"}},
+		{name: "FamiliesDoNotOverlap", input: "0.0.0.0/0 ::/0", expected: nil},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := ip.ParsePrefixes(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, o := range ip.FindOverlaps(prefixes) {
+				got = append(got, fmt.Sprintf("%s>%s", o.Outer, o.Inner))
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSortPrefixes(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name                     string
+		input                    string
+		expectedSorted           []string
+		expectedDeduped          []string
+		expectedWithoutContained []string
+	}{
+		{
+			name:                     "Numeric",
+			input:                    "10.0.10.0/24 10.0.2.0/24 9.0.0.0/8",
+			expectedSorted:           []string{"9.0.0.0/8", "10.0.2.0/24", "10.0.10.0/24"},
+			expectedDeduped:          []string{"9.0.0.0/8", "10.0.2.0/24", "10.0.10.0/24"},
+			expectedWithoutContained: []string{"9.0.0.0/8", "10.0.2.0/24", "10.0.10.0/24"},
+		},
+		{
+			name:                     "ParentFirst",
+			input:                    "10.0.0.0/24 10.0.0.0/16 10.0.0.0/8",
+			expectedSorted:           []string{"10.0.0.0/8", "10.0.0.0/16", "10.0.0.0/24"},
+			expectedDeduped:          []string{"10.0.0.0/8", "10.0.0.0/16", "10.0.0.0/24"},
+			expectedWithoutContained: []string{"10.0.0.0/8"},
+		},
+		{
+			name:                     "Duplicates",
+			input:                    "10.0.2.0/24 10.0.1.0/24 10.0.2.0/24",
+			expectedSorted:           []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.2.0/24"},
+			expectedDeduped:          []string{"10.0.1.0/24", "10.0.2.0/24"},
+			expectedWithoutContained: []string{"10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:                     "IPv4BeforeIPv6",
+			input:                    "2001:db8::/32 ::ffff:0:0/96 255.255.255.255",
+			expectedSorted:           []string{"255.255.255.255/32", "::ffff:0.0.0.0/96", "2001:db8::/32"},
+			expectedDeduped:          []string{"255.255.255.255/32", "::ffff:0.0.0.0/96", "2001:db8::/32"},
+			expectedWithoutContained: []string{"255.255.255.255/32", "::ffff:0.0.0.0/96", "2001:db8::/32"},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes, err := ip.ParsePrefixes(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ip.SortPrefixes(prefixes)
+			if got := prefixStrings(prefixes); !reflect.DeepEqual(got, tc.expectedSorted) {
+				t.Errorf("expected sorted %v, got %v", tc.expectedSorted, got)
+			}
+			if got := prefixStrings(ip.DedupePrefixes(prefixes)); !reflect.DeepEqual(got, tc.expectedDeduped) {
+				t.Errorf("expected deduped %v, got %v", tc.expectedDeduped, got)
+			}
+			if got := prefixStrings(ip.RemoveContained(prefixes)); !reflect.DeepEqual(got, tc.expectedWithoutContained) {
+				t.Errorf("expected without contained %v, got %v", tc.expectedWithoutContained, got)
+			}
+		})
+	}
+}
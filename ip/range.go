@@ -0,0 +1,128 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// The IPv4Range struct represents an arbitrary (inclusive) range of IPv4
+// addresses, from the first address to the last address.
+type IPv4Range struct {
+	First uint32
+	Last  uint32
+}
+
+// ParseIPv4Range is a function that takes a range of IPv4 addresses in the
+// format "first-last" (e.g. "10.0.4.0-10.0.7.255") as input and returns an
+// IPv4Range. The first address must be less than or equal to the last address.
+func ParseIPv4Range(s string) (*IPv4Range, error) {
+	// Split the input string into the first and last address
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid IPv4 range: %s (expected first-last)", s)
+	}
+
+	first := strings.TrimSpace(parts[0])
+	last := strings.TrimSpace(parts[1])
+
+	// Make sure that both addresses are IPv4 addresses
+	if !IsIPv4(first) {
+		return nil, fmt.Errorf("invalid IPv4 address: %s", first)
+	}
+	if !IsIPv4(last) {
+		return nil, fmt.Errorf("invalid IPv4 address: %s", last)
+	}
+
+	r := &IPv4Range{First: IPv4ToInt(first), Last: IPv4ToInt(last)}
+	if r.First > r.Last {
+		return nil, fmt.Errorf("invalid IPv4 range: %s (the first address is greater than the last address)", s)
+	}
+
+	return r, nil
+}
+
+// String is a function that returns the range in the format "first-last"
+func (r *IPv4Range) String() string {
+	return fmt.Sprintf("%s-%s", IntToIPv4(r.First), IntToIPv4(r.Last))
+}
+
+// Size is a function that returns the number of addresses in the range
+func (r *IPv4Range) Size() uint64 {
+	return uint64(r.Last) - uint64(r.First) + 1
+}
+
+// CoveringPrefix is a function that returns the smallest network (the longest
+// prefix) that contains every address in the range
+func (r *IPv4Range) CoveringPrefix() *IPv4 {
+	// The prefix length is the number of leading bits the addresses have in common
+	prefixLength := bits.LeadingZeros32(r.First ^ r.Last)
+	return newIPv4(r.First, prefixLength)
+}
+
+// IsCIDR is a function that checks if the range corresponds exactly to a
+// single network in CIDR notation
+func (r *IPv4Range) IsCIDR() bool {
+	return r.Excess() == 0
+}
+
+// Excess is a function that returns the number of addresses that are in the
+// covering prefix (see CoveringPrefix) but not in the range
+func (r *IPv4Range) Excess() uint64 {
+	prefixLength := bits.LeadingZeros32(r.First ^ r.Last)
+	return uint64(1)<<(32-prefixLength) - r.Size()
+}
+
+// CIDRs is a function that returns the shortest list of networks in CIDR
+// notation that together cover exactly the addresses in the range
+func (r *IPv4Range) CIDRs() []*IPv4 {
+	var networks []*IPv4
+
+	start := uint64(r.First)
+	end := uint64(r.Last)
+	for start <= end {
+		// Find the largest block that is aligned on the start address...
+		size := uint64(1) << 32
+		if start != 0 {
+			size = start & -start
+		}
+
+		// ...and that does not extend past the end of the range
+		for start+size-1 > end {
+			size >>= 1
+		}
+
+		networks = append(networks, newIPv4(uint32(start), 32-bits.TrailingZeros64(size)))
+		start += size
+	}
+
+	return networks
+}
+
+// newIPv4 is a function that returns the network with the given network
+// address (integer) and prefix length as an IPv4
+func newIPv4(network uint32, prefixLength int) *IPv4 {
+	mask := uint32(MaskFromPrefixLength(prefixLength))
+	return newIPv4FromInt(network&mask, mask)
+}
@@ -0,0 +1,69 @@
+package ip_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestParseIPv4Range(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name             string
+		input            string
+		expectedCovering string
+		expectedSize     uint64
+		expectedExcess   uint64
+		expectedCIDRs    []string
+		expectErr        bool
+	}{
+		{name: "ExactSlash22", input: "10.0.4.0-10.0.7.255", expectedCovering: "10.0.4.0/22", expectedSize: 1024, expectedExcess: 0, expectedCIDRs: []string{"10.0.4.0/22"}},
+		{name: "SingleAddress", input: "1.2.3.4-1.2.3.4", expectedCovering: "1.2.3.4/32", expectedSize: 1, expectedExcess: 0, expectedCIDRs: []string{"1.2.3.4/32"}},
+		{name: "WholeAddressSpace", input: "0.0.0.0-255.255.255.255", expectedCovering: "0.0.0.0/0", expectedSize: 4294967296, expectedExcess: 0, expectedCIDRs: []string{"0.0.0.0/0"}},
+		{name: "Spaces", input: "192.168.1.0 - 192.168.1.127", expectedCovering: "192.168.1.0/25", expectedSize: 128, expectedExcess: 0, expectedCIDRs: []string{"192.168.1.0/25"}},
+		{name: "Unaligned", input: "10.0.0.1-10.0.0.6", expectedCovering: "10.0.0.0/29", expectedSize: 6, expectedExcess: 2, expectedCIDRs: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{name: "CrossingOctets", input: "10.0.3.0-10.0.4.255", expectedCovering: "10.0.0.0/21", expectedSize: 512, expectedExcess: 1536, expectedCIDRs: []string{"10.0.3.0/24", "10.0.4.0/24"}},
+		{name: "LastAddress", input: "255.255.255.254-255.255.255.255", expectedCovering: "255.255.255.254/31", expectedSize: 2, expectedExcess: 0, expectedCIDRs: []string{"255.255.255.254/31"}},
+		{name: "Reversed", input: "10.0.0.5-10.0.0.1", expectErr: true},
+		{name: "MissingLast", input: "10.0.0.1", expectErr: true},
+		{name: "InvalidAddress", input: "10.0.0.1-10.0.0.256", expectErr: true},
+		{name: "IPv6", input: "2001:db8::1-2001:db8::2", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := ip.ParseIPv4Range(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.CoveringPrefix().String(); got != tc.expectedCovering {
+				t.Errorf("expected covering prefix %s, got %s", tc.expectedCovering, got)
+			}
+			if got := r.Size(); got != tc.expectedSize {
+				t.Errorf("expected size %d, got %d", tc.expectedSize, got)
+			}
+			if got := r.Excess(); got != tc.expectedExcess {
+				t.Errorf("expected excess %d, got %d", tc.expectedExcess, got)
+			}
+			if got := r.IsCIDR(); got != (tc.expectedExcess == 0) {
+				t.Errorf("expected IsCIDR %t, got %t", tc.expectedExcess == 0, got)
+			}
+
+			var cidrs []string
+			for _, network := range r.CIDRs() {
+				cidrs = append(cidrs, network.String())
+			}
+			if !reflect.DeepEqual(cidrs, tc.expectedCIDRs) {
+				t.Errorf("expected CIDRs %v, got %v", tc.expectedCIDRs, cidrs)
+			}
+		})
+	}
+}
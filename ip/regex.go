@@ -0,0 +1,246 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RegexDialect is the flavor of regular expression generated by Regex
+type RegexDialect string
+
+// Supported regular expression dialects
+const (
+	RegexPCRE RegexDialect = "pcre"
+	RegexRE2  RegexDialect = "re2"
+	RegexERE  RegexDialect = "ere"
+)
+
+// ParseRegexDialect is a function that returns the regular expression dialect
+// with the given name. The name "grep" is accepted as an alias for ere.
+func ParseRegexDialect(s string) (RegexDialect, error) {
+	switch strings.ToLower(s) {
+	case "pcre", "perl":
+		return RegexPCRE, nil
+	case "re2", "go":
+		return RegexRE2, nil
+	case "ere", "grep", "grep-e", "egrep":
+		return RegexERE, nil
+	}
+	return "", fmt.Errorf("invalid regex dialect: %s (must be pcre, re2 or ere)", s)
+}
+
+// group is a function that returns the alternatives as a group in the dialect.
+// POSIX extended regular expressions (grep -E) do not support non-capturing groups.
+func (d RegexDialect) group(alternatives []string) string {
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	if d == RegexERE {
+		return "(" + strings.Join(alternatives, "|") + ")"
+	}
+	return "(?:" + strings.Join(alternatives, "|") + ")"
+}
+
+// Regex is a function that returns a regular expression in the given dialect
+// that matches exactly the IPv4 addresses in the range. If anchored is true,
+// the expression must match the whole input (^...$), otherwise the expression
+// matches the addresses anywhere in a line of text, but not as a part of a
+// longer number (e.g. 10.0.0.1 in 110.0.0.10).
+func (r *IPv4Range) Regex(dialect RegexDialect, anchored bool) string {
+	var first, last [4]int
+	for i := 0; i < 4; i++ {
+		first[i] = int(r.First>>(24-8*i)) & 0xff
+		last[i] = int(r.Last>>(24-8*i)) & 0xff
+	}
+	expr := dialect.octetsRegex(first[:], last[:])
+
+	// Make sure that the expression does not match parts of longer numbers
+	switch {
+	case anchored:
+		return "^" + expr + "$"
+	case dialect == RegexPCRE:
+		return `(?<![0-9.])` + expr + `(?!\.?[0-9])`
+	default:
+		// RE2 and POSIX ERE do not support lookarounds, use word boundaries instead
+		return `\b` + expr + `\b`
+	}
+}
+
+// octetsRegex is a function that returns a regular expression matching the
+// dotted octets from first to last (inclusive). The octets are split into a
+// fixed common part, and a range on the first octet that differs, which is
+// handled recursively.
+func (d RegexDialect) octetsRegex(first, last []int) string {
+	// The last octet is a plain number range
+	if len(first) == 1 {
+		return numberRangeRegex(d, first[0], last[0])
+	}
+
+	// Octets in common are matched literally
+	if first[0] == last[0] {
+		return strconv.Itoa(first[0]) + `\.` + d.octetsRegex(first[1:], last[1:])
+	}
+
+	// Check if the remaining octets span the whole range (x.0.0 to y.255.255)
+	min := isAll(first[1:], 0)
+	max := isAll(last[1:], 255)
+	any := d.octetsRegex(make([]int, len(first)-1), fill(len(first)-1, 255))
+
+	var alternatives []string
+
+	// The first value of the octet, followed by the remaining octets from first
+	lo := first[0]
+	if !min {
+		alternatives = append(alternatives, strconv.Itoa(lo)+`\.`+d.octetsRegex(first[1:], fill(len(first)-1, 255)))
+		lo++
+	}
+
+	// The last value of the octet, followed by the remaining octets up to last
+	hi := last[0]
+	var tail string
+	if !max {
+		tail = strconv.Itoa(hi) + `\.` + d.octetsRegex(make([]int, len(first)-1), last[1:])
+		hi--
+	}
+
+	// The values in between are followed by any remaining octets
+	if lo <= hi {
+		alternatives = append(alternatives, numberRangeRegex(d, lo, hi)+`\.`+any)
+	}
+	if tail != "" {
+		alternatives = append(alternatives, tail)
+	}
+
+	return d.group(alternatives)
+}
+
+// numberRangeRegex is a function that returns a regular expression matching
+// the decimal numbers from lo to hi (inclusive, without leading zeros)
+func numberRangeRegex(d RegexDialect, lo, hi int) string {
+	var alternatives []string
+
+	// Split the range into ranges with the same number of digits
+	for lo <= hi {
+		digits := len(strconv.Itoa(lo))
+		end := hi
+		if limit := pow10(digits) - 1; end > limit {
+			end = limit
+		}
+		alternatives = append(alternatives, sameLengthRangeRegex(strconv.Itoa(lo), strconv.Itoa(end))...)
+		lo = end + 1
+	}
+
+	return d.group(alternatives)
+}
+
+// sameLengthRangeRegex is a function that returns the alternatives matching
+// the numbers from lo to hi, where lo and hi have the same number of digits
+func sameLengthRangeRegex(lo, hi string) []string {
+	if lo == hi {
+		return []string{lo}
+	}
+
+	// A single digit range is a character class
+	if len(lo) == 1 {
+		return []string{digitClass(lo[0], hi[0])}
+	}
+
+	// Common leading digits are matched literally
+	if lo[0] == hi[0] {
+		var alternatives []string
+		for _, a := range sameLengthRangeRegex(lo[1:], hi[1:]) {
+			alternatives = append(alternatives, lo[:1]+a)
+		}
+		return alternatives
+	}
+
+	var alternatives []string
+	rest := len(lo) - 1
+	loDigit, hiDigit := lo[0], hi[0]
+
+	// lo up to the end of its leading digit (e.g. 123-199)
+	if lo[1:] != strings.Repeat("0", rest) {
+		for _, a := range sameLengthRangeRegex(lo[1:], strings.Repeat("9", rest)) {
+			alternatives = append(alternatives, lo[:1]+a)
+		}
+		loDigit++
+	}
+
+	// The start of the leading digit of hi up to hi (e.g. 200-245)
+	var tail []string
+	if hi[1:] != strings.Repeat("9", rest) {
+		for _, a := range sameLengthRangeRegex(strings.Repeat("0", rest), hi[1:]) {
+			tail = append(tail, hi[:1]+a)
+		}
+		hiDigit--
+	}
+
+	// Any number with a leading digit in between (e.g. [2-4][0-9][0-9])
+	if loDigit <= hiDigit {
+		alternatives = append(alternatives, digitClass(loDigit, hiDigit)+strings.Repeat("[0-9]", rest))
+	}
+
+	return append(alternatives, tail...)
+}
+
+// digitClass is a function that returns a character class matching the digits from lo to hi
+func digitClass(lo, hi byte) string {
+	switch {
+	case lo == hi:
+		return string(lo)
+	case lo == '0' && hi == '9':
+		return "[0-9]"
+	default:
+		return fmt.Sprintf("[%c-%c]", lo, hi)
+	}
+}
+
+// isAll is a function that checks if all values are equal to v
+func isAll(values []int, v int) bool {
+	for _, value := range values {
+		if value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// fill is a function that returns a slice of length n with all values set to v
+func fill(n, v int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+// pow10 is a function that returns 10 to the power of n
+func pow10(n int) int {
+	result := 1
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
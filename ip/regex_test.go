@@ -0,0 +1,114 @@
+package ip_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestIPv4RangeRegex(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "Slash21", input: "10.0.0.0-10.0.7.255"},
+		{name: "Slash24", input: "192.168.1.0-192.168.1.255"},
+		{name: "PartialOctet", input: "192.168.1.10-192.168.1.200"},
+		{name: "CrossingOctets", input: "10.0.0.250-10.0.3.7"},
+		{name: "CrossingTwoOctets", input: "10.0.255.200-10.2.0.9"},
+		{name: "SingleAddress", input: "1.2.3.4-1.2.3.4"},
+		{name: "EndOfAddressSpace", input: "255.255.254.100-255.255.255.255"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := ip.ParseIPv4Range(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			re := regexp.MustCompile(r.Regex(ip.RegexRE2, true))
+
+			// Check every address in and around the range
+			from := uint64(r.First)
+			if from > 1024 {
+				from -= 1024
+			}
+			to := uint64(r.Last) + 1024
+			if to > 0xffffffff {
+				to = 0xffffffff
+			}
+			for i := from; i <= to; i++ {
+				addr := ip.IntToIPv4(uint32(i))
+				expected := uint32(i) >= r.First && uint32(i) <= r.Last
+				if re.MatchString(addr) != expected {
+					t.Fatalf("expected match %t for %s with %s", expected, addr, re)
+				}
+			}
+		})
+	}
+}
+
+func TestIPv4RangeRegexUnanchored(t *testing.T) {
+	r, err := ip.ParseIPv4Range("10.0.0.0-10.0.0.255")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	re := regexp.MustCompile(r.Regex(ip.RegexRE2, false))
+
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "connection from 10.0.0.25 port 22", expected: "10.0.0.25"},
+		{input: "src=10.0.0.255,dst=8.8.8.8", expected: "10.0.0.255"},
+		{input: "connection from 110.0.0.25 port 22", expected: ""},
+		{input: "connection from 10.0.1.25 port 22", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := re.FindString(tc.input); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRegexDialect(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  ip.RegexDialect
+		expectErr bool
+	}{
+		{input: "pcre", expected: ip.RegexPCRE},
+		{input: "RE2", expected: ip.RegexRE2},
+		{input: "grep", expected: ip.RegexERE},
+		{input: "ere", expected: ip.RegexERE},
+		{input: "sed", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			dialect, err := ip.ParseRegexDialect(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dialect != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, dialect)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/bitcanon/iptool/cache"
+)
+
+// ResolveAddrs is a function that resolves a hostname to its IPv4 (A) and
+// IPv6 (AAAA) addresses of the family, ordered for a happy eyeballs
+// connection (see InterleaveFamilies). Numeric addresses are returned as
+// is, without querying DNS.
+func ResolveAddrs(hostname string, family Family) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(hostname); err == nil {
+		if !family.Match(addr) {
+			return nil, fmt.Errorf("%s is not an %s address", hostname, family)
+		}
+		return []netip.Addr{addr}, nil
+	}
+	if lookupsDisabled {
+		return nil, fmt.Errorf("cannot resolve %s: %w", hostname, ErrLookupsDisabled)
+	}
+
+	// Successful lookups are cached to speed up repeated runs
+	names, err := cache.Remember(cache.NamespaceDNS, "addrs:"+hostname, dnsCacheTTL, func() ([]string, error) {
+		return lookupAddrNames(hostname)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return familyAddrs(hostname, names, family)
+}
+
+// LookupAddrs is a function that resolves a hostname like ResolveAddrs,
+// but always queries DNS instead of using the cache, to follow changes of
+// the addresses of a host while it is monitored
+func LookupAddrs(hostname string, family Family) ([]netip.Addr, error) {
+	if _, err := netip.ParseAddr(hostname); err == nil {
+		return ResolveAddrs(hostname, family)
+	}
+	if lookupsDisabled {
+		return nil, fmt.Errorf("cannot resolve %s: %w", hostname, ErrLookupsDisabled)
+	}
+	names, err := lookupAddrNames(hostname)
+	if err != nil {
+		return nil, err
+	}
+	return familyAddrs(hostname, names, family)
+}
+
+// lookupAddrNames is a function that queries the addresses of a hostname
+func lookupAddrNames(hostname string) ([]string, error) {
+	ips, err := net.LookupIP(hostname)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(ips))
+	for i, ip := range ips {
+		names[i] = ip.String()
+	}
+	return names, nil
+}
+
+// familyAddrs is a function that parses the addresses of a hostname and
+// keeps the addresses of the family, in happy eyeballs order
+func familyAddrs(hostname string, names []string, family Family) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, name := range names {
+		if addr, err := netip.ParseAddr(name); err == nil && family.Match(addr.Unmap()) {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+	if len(addrs) == 0 {
+		if family == FamilyAny {
+			return nil, fmt.Errorf("no address found for %s", hostname)
+		}
+		return nil, fmt.Errorf("no %s address found for %s", family, hostname)
+	}
+	return InterleaveFamilies(addrs), nil
+}
+
+// InterleaveFamilies is a function that orders addresses for a happy eyeballs
+// connection (RFC 8305): the families alternate, starting with IPv6, and the
+// addresses of a family keep their order
+func InterleaveFamilies(addrs []netip.Addr) []netip.Addr {
+	var ipv4, ipv6 []netip.Addr
+	for _, addr := range addrs {
+		if addr.Is4() || addr.Is4In6() {
+			ipv4 = append(ipv4, addr)
+		} else {
+			ipv6 = append(ipv6, addr)
+		}
+	}
+
+	ordered := make([]netip.Addr, 0, len(addrs))
+	for i := 0; i < max(len(ipv4), len(ipv6)); i++ {
+		if i < len(ipv6) {
+			ordered = append(ordered, ipv6[i])
+		}
+		if i < len(ipv4) {
+			ordered = append(ordered, ipv4[i])
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,103 @@
+package ip_test
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestResolveAddrs(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		family    ip.Family
+		expected  []string
+		expectErr bool
+	}{
+		{name: "IPv4", input: "192.0.2.1", family: ip.FamilyAny, expected: []string{"192.0.2.1"}},
+		{name: "IPv6", input: "2001:db8::1", family: ip.FamilyAny, expected: []string{"2001:db8::1"}},
+		{name: "IPv6Zone", input: "fe80::1%eth0", family: ip.FamilyIPv6, expected: []string{"fe80::1%eth0"}},
+		{name: "WrongFamily", input: "2001:db8::1", family: ip.FamilyIPv4, expectErr: true},
+		{name: "Hostname", input: "localhost", family: ip.FamilyAny, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs, err := ip.ResolveAddrs(tc.input, tc.family)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", addrs)
+				}
+				if tc.input == "localhost" && !errors.Is(err, ip.ErrLookupsDisabled) {
+					t.Errorf("expected ErrLookupsDisabled, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, addr := range addrs {
+				got = append(got, addr.String())
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLookupAddrs(t *testing.T) {
+	// Numeric addresses are returned as is
+	addrs, err := ip.LookupAddrs("192.0.2.1", ip.FamilyAny)
+	if err != nil || len(addrs) != 1 || addrs[0] != netip.MustParseAddr("192.0.2.1") {
+		t.Errorf("expected 192.0.2.1, got %v (%v)", addrs, err)
+	}
+
+	// Hostnames are always looked up, so they fail when lookups are disabled
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+	if _, err := ip.LookupAddrs("localhost", ip.FamilyAny); !errors.Is(err, ip.ErrLookupsDisabled) {
+		t.Errorf("expected ErrLookupsDisabled, got %v", err)
+	}
+}
+
+func TestInterleaveFamilies(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{name: "Empty", input: nil, expected: nil},
+		{name: "IPv4Only", input: []string{"192.0.2.1", "192.0.2.2"}, expected: []string{"192.0.2.1", "192.0.2.2"}},
+		{name: "IPv6First", input: []string{"192.0.2.1", "2001:db8::1"}, expected: []string{"2001:db8::1", "192.0.2.1"}},
+		{name: "Alternate", input: []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "2001:db8::2"}, expected: []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2"}},
+		{name: "MoreIPv4", input: []string{"2001:db8::1", "192.0.2.1", "192.0.2.2", "192.0.2.3"}, expected: []string{"2001:db8::1", "192.0.2.1", "192.0.2.2", "192.0.2.3"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var addrs []netip.Addr
+			for _, s := range tc.input {
+				addrs = append(addrs, netip.MustParseAddr(s))
+			}
+			var got []string
+			for _, addr := range ip.InterleaveFamilies(addrs) {
+				got = append(got, addr.String())
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// Interval is a range of addresses from the first to the last address,
+// both included. Both addresses are of the same address family.
+type Interval struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// String is a function that returns the interval in the from-to notation
+func (i Interval) String() string {
+	return i.From.String() + "-" + i.To.String()
+}
+
+// Prefixes is a function that returns the smallest list of prefixes that
+// covers exactly the addresses of the interval
+func (i Interval) Prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	from := i.From
+	for {
+		// Grow the prefix as long as it starts at the first address and
+		// ends before the last address of the interval
+		bits := from.BitLen()
+		for bits > 0 {
+			parent := netip.PrefixFrom(from, bits-1).Masked()
+			if parent.Addr() != from || LastAddr(parent).Compare(i.To) > 0 {
+				break
+			}
+			bits--
+		}
+
+		prefix := netip.PrefixFrom(from, bits)
+		prefixes = append(prefixes, prefix)
+		last := LastAddr(prefix)
+		if last.Compare(i.To) >= 0 {
+			return prefixes
+		}
+		from = last.Next()
+	}
+}
+
+// LastAddr is a function that returns the last address of a prefix
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr()
+	b := addr.AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// Set is a set of addresses stored as a sorted list of disjoint intervals,
+// so that set operations on large lists of prefixes are linear in the number
+// of intervals. IPv4 and IPv6 addresses can be mixed.
+type Set struct {
+	intervals []Interval
+}
+
+// NewSet is a function that returns the set of addresses covered by a list
+// of prefixes. IPv4-mapped IPv6 prefixes are added as IPv4 prefixes.
+func NewSet(prefixes []netip.Prefix) *Set {
+	intervals := make([]Interval, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p.Addr().Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		p = p.Masked()
+		intervals = append(intervals, Interval{From: p.Addr(), To: LastAddr(p)})
+	}
+	return newSet(intervals)
+}
+
+// newSet is a function that sorts the intervals and merges the overlapping
+// and adjacent intervals
+func newSet(intervals []Interval) *Set {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].From.Less(intervals[j].From)
+	})
+
+	var merged []Interval
+	for _, i := range intervals {
+		if n := len(merged); n > 0 {
+			// The next address of the last address of a family is invalid,
+			// so intervals of different families are never merged
+			last := &merged[n-1]
+			if i.From.Compare(last.To) <= 0 || i.From == last.To.Next() {
+				if i.To.Compare(last.To) > 0 {
+					last.To = i.To
+				}
+				continue
+			}
+		}
+		merged = append(merged, i)
+	}
+	return &Set{intervals: merged}
+}
+
+// Intervals is a function that returns the sorted, disjoint intervals of
+// the set
+func (s *Set) Intervals() []Interval {
+	return s.intervals
+}
+
+// Contains is a function that reports whether the address is in the set.
+// IPv4-mapped IPv6 addresses are looked up as IPv4 addresses.
+func (s *Set) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	i := sort.Search(len(s.intervals), func(i int) bool {
+		return addr.Compare(s.intervals[i].To) <= 0
+	})
+	return i < len(s.intervals) && addr.Compare(s.intervals[i].From) >= 0
+}
+
+// Prefixes is a function that returns the smallest sorted list of prefixes
+// that covers exactly the addresses of the set
+func (s *Set) Prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, i := range s.intervals {
+		prefixes = append(prefixes, i.Prefixes()...)
+	}
+	return prefixes
+}
+
+// Union is a function that returns the addresses that are in either set
+func (s *Set) Union(other *Set) *Set {
+	intervals := make([]Interval, 0, len(s.intervals)+len(other.intervals))
+	intervals = append(intervals, s.intervals...)
+	intervals = append(intervals, other.intervals...)
+	return newSet(intervals)
+}
+
+// Intersect is a function that returns the addresses that are in both sets
+func (s *Set) Intersect(other *Set) *Set {
+	var result []Interval
+	a, b := s.intervals, other.intervals
+	for len(a) > 0 && len(b) > 0 {
+		// The overlap of the first intervals, if any
+		from, to := a[0].From, a[0].To
+		if b[0].From.Compare(from) > 0 {
+			from = b[0].From
+		}
+		if b[0].To.Compare(to) < 0 {
+			to = b[0].To
+		}
+		if from.Compare(to) <= 0 {
+			result = append(result, Interval{From: from, To: to})
+		}
+
+		// Drop the interval that ends first, it cannot overlap anything else
+		if a[0].To.Compare(b[0].To) < 0 {
+			a = a[1:]
+		} else {
+			b = b[1:]
+		}
+	}
+	return &Set{intervals: result}
+}
+
+// Difference is a function that returns the addresses that are in the set
+// but not in the other set
+func (s *Set) Difference(other *Set) *Set {
+	var result []Interval
+	b := other.intervals
+	for _, i := range s.intervals {
+		// Skip the intervals that end before this interval
+		for len(b) > 0 && b[0].To.Less(i.From) {
+			b = b[1:]
+		}
+
+		// Cut the overlapping intervals out of this interval
+		from := i.From
+		remaining := true
+		for _, cut := range b {
+			if cut.From.Compare(i.To) > 0 {
+				break
+			}
+			if cut.From.Compare(from) > 0 {
+				result = append(result, Interval{From: from, To: cut.From.Prev()})
+			}
+			if cut.To.Compare(i.To) >= 0 {
+				remaining = false
+				break
+			}
+			from = cut.To.Next()
+		}
+		if remaining {
+			result = append(result, Interval{From: from, To: i.To})
+		}
+	}
+	return &Set{intervals: result}
+}
+
+// FreeBlocks is a function that returns the free space of the parent prefix,
+// i.e. the addresses not covered by the allocated prefixes, as the smallest
+// list of prefixes. Allocated prefixes outside of the parent are ignored. If
+// bits is greater than 0, only the free blocks with a prefix length of at
+// most bits are returned, i.e. the blocks that can hold a /bits subnet.
+func FreeBlocks(parent netip.Prefix, allocated []netip.Prefix, bits int) []netip.Prefix {
+	free := NewSet([]netip.Prefix{parent.Masked()}).Difference(NewSet(allocated))
+
+	var blocks []netip.Prefix
+	for _, prefix := range free.Prefixes() {
+		if bits > 0 && prefix.Bits() > bits {
+			continue
+		}
+		blocks = append(blocks, prefix)
+	}
+	return blocks
+}
@@ -0,0 +1,200 @@
+package ip_test
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// newSet is a helper that returns the set of a comma-separated list of prefixes
+func newSet(t *testing.T, s string) *ip.Set {
+	prefixes, err := ip.ParsePrefixes(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("invalid prefixes %q: %v", s, err)
+	}
+	return ip.NewSet(prefixes)
+}
+
+func TestSetOperations(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name       string
+		a          string
+		b          string
+		union      []string
+		intersect  []string
+		difference []string
+	}{
+		{
+			name:       "Disjoint",
+			a:          "10.0.0.0/24",
+			b:          "10.0.2.0/24",
+			union:      []string{"10.0.0.0/24", "10.0.2.0/24"},
+			intersect:  nil,
+			difference: []string{"10.0.0.0/24"},
+		},
+		{
+			name:       "Adjacent",
+			a:          "10.0.0.0/24",
+			b:          "10.0.1.0/24",
+			union:      []string{"10.0.0.0/23"},
+			intersect:  nil,
+			difference: []string{"10.0.0.0/24"},
+		},
+		{
+			name:       "Contained",
+			a:          "10.0.0.0/24",
+			b:          "10.0.0.64/26",
+			union:      []string{"10.0.0.0/24"},
+			intersect:  []string{"10.0.0.64/26"},
+			difference: []string{"10.0.0.0/26", "10.0.0.128/25"},
+		},
+		{
+			name:       "Addresses",
+			a:          "192.0.2.0/30",
+			b:          "192.0.2.1,192.0.2.2",
+			union:      []string{"192.0.2.0/30"},
+			intersect:  []string{"192.0.2.1/32", "192.0.2.2/32"},
+			difference: []string{"192.0.2.0/32", "192.0.2.3/32"},
+		},
+		{
+			name:       "Overlapping",
+			a:          "10.0.0.0/23,10.0.4.0/24",
+			b:          "10.0.1.0/24,10.0.2.0/23",
+			union:      []string{"10.0.0.0/22", "10.0.4.0/24"},
+			intersect:  []string{"10.0.1.0/24"},
+			difference: []string{"10.0.0.0/24", "10.0.4.0/24"},
+		},
+		{
+			name:       "Everything",
+			a:          "0.0.0.0/0",
+			b:          "10.0.0.0/8",
+			union:      []string{"0.0.0.0/0"},
+			intersect:  []string{"10.0.0.0/8"},
+			difference: []string{"0.0.0.0/5", "8.0.0.0/7", "11.0.0.0/8", "12.0.0.0/6", "16.0.0.0/4", "32.0.0.0/3", "64.0.0.0/2", "128.0.0.0/1"},
+		},
+		{
+			name:       "MixedFamilies",
+			a:          "255.255.255.0/24,2001:db8::/32",
+			b:          "::/127,2001:db8::/33",
+			union:      []string{"255.255.255.0/24", "::/127", "2001:db8::/32"},
+			intersect:  []string{"2001:db8::/33"},
+			difference: []string{"255.255.255.0/24", "2001:db8:8000::/33"},
+		},
+		{
+			name:       "IPv4Mapped",
+			a:          "::ffff:10.0.0.0/120",
+			b:          "10.0.0.0/25",
+			union:      []string{"10.0.0.0/24"},
+			intersect:  []string{"10.0.0.0/25"},
+			difference: []string{"10.0.0.128/25"},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := newSet(t, tc.a), newSet(t, tc.b)
+			if got := prefixStrings(a.Union(b).Prefixes()); !reflect.DeepEqual(got, tc.union) {
+				t.Errorf("Union: expected %v, got %v", tc.union, got)
+			}
+			if got := prefixStrings(a.Intersect(b).Prefixes()); !reflect.DeepEqual(got, tc.intersect) {
+				t.Errorf("Intersect: expected %v, got %v", tc.intersect, got)
+			}
+			if got := prefixStrings(a.Difference(b).Prefixes()); !reflect.DeepEqual(got, tc.difference) {
+				t.Errorf("Difference: expected %v, got %v", tc.difference, got)
+			}
+		})
+	}
+}
+
+func TestIntervalPrefixes(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		from     string
+		to       string
+		expected []string
+	}{
+		{from: "10.0.0.0", to: "10.0.0.0", expected: []string{"10.0.0.0/32"}},
+		{from: "10.0.0.0", to: "10.0.0.255", expected: []string{"10.0.0.0/24"}},
+		{from: "10.0.0.1", to: "10.0.0.6", expected: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{from: "0.0.0.0", to: "255.255.255.255", expected: []string{"0.0.0.0/0"}},
+		{from: "2001:db8::", to: "2001:db8::2", expected: []string{"2001:db8::/127", "2001:db8::2/128"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.from+"-"+tc.to, func(t *testing.T) {
+			interval := ip.Interval{From: netip.MustParseAddr(tc.from), To: netip.MustParseAddr(tc.to)}
+			if got := prefixStrings(interval.Prefixes()); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetContains(t *testing.T) {
+	set := newSet(t, "10.0.0.0/8 192.168.1.0/24 2001:db8::/32")
+
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "10.0.0.0", expected: true},
+		{addr: "10.255.255.255", expected: true},
+		{addr: "11.0.0.0", expected: false},
+		{addr: "192.168.1.77", expected: true},
+		{addr: "192.168.2.1", expected: false},
+		{addr: "::ffff:10.1.2.3", expected: true},
+		{addr: "2001:db8::1%eth0", expected: true},
+		{addr: "2001:db9::1", expected: false},
+		{addr: "0.0.0.0", expected: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := set.Contains(netip.MustParseAddr(tc.addr)); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFreeBlocks(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		parent    string
+		allocated string
+		bits      int
+		expected  []string
+	}{
+		{name: "NothingAllocated", parent: "10.0.0.0/16", allocated: "", expected: []string{"10.0.0.0/16"}},
+		{name: "FullyAllocated", parent: "10.0.0.0/24", allocated: "10.0.0.0/25,10.0.0.128/25", expected: nil},
+		{name: "Gaps", parent: "10.0.0.0/22", allocated: "10.0.0.0/24,10.0.2.64/26", expected: []string{"10.0.1.0/24", "10.0.2.0/26", "10.0.2.128/25", "10.0.3.0/24"}},
+		{name: "MinimumSize", parent: "10.0.0.0/22", allocated: "10.0.0.0/24,10.0.2.64/26", bits: 25, expected: []string{"10.0.1.0/24", "10.0.2.128/25", "10.0.3.0/24"}},
+		{name: "OutsideParent", parent: "10.0.0.0/24", allocated: "10.0.0.0/25,10.1.0.0/16,10.0.0.0/8", expected: nil},
+		{name: "PartlyOutside", parent: "10.0.1.0/24", allocated: "10.0.0.0/23", expected: nil},
+		{name: "HostBits", parent: "10.0.0.5/24", allocated: "10.0.0.0/25", expected: []string{"10.0.0.128/25"}},
+		{name: "IPv6", parent: "2001:db8::/48", allocated: "2001:db8::/50", bits: 49, expected: []string{"2001:db8:0:8000::/49"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allocated, err := ip.ParsePrefixes(strings.NewReader(tc.allocated))
+			if err != nil {
+				t.Fatalf("invalid prefixes %q: %v", tc.allocated, err)
+			}
+			got := prefixStrings(ip.FreeBlocks(netip.MustParsePrefix(tc.parent), allocated, tc.bits))
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
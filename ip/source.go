@@ -0,0 +1,93 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SourceAddress is a function that resolves the source of a probe, a local
+// address or the name of a network interface, to the address the outgoing
+// socket is bound to. For an interface, the first address of the family
+// (IPv6 if ipv6 is true, otherwise IPv4) is returned, global addresses
+// before link-local ones. An empty source returns nil (any address).
+func SourceAddress(source string, ipv6 bool) (*net.IPAddr, error) {
+	if source == "" {
+		return nil, nil
+	}
+
+	// The source is an address (link-local IPv6 addresses may have a zone)
+	if isAddress(source) {
+		addr, err := net.ResolveIPAddr("ip", source)
+		if err != nil {
+			return nil, err
+		}
+		if (addr.IP.To4() == nil) != ipv6 {
+			return nil, fmt.Errorf("invalid source address: %s (must be an %s address)", source, familyName(ipv6))
+		}
+		return addr, nil
+	}
+
+	// The source is the name of an interface
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %s (must be a local address or interface)", source)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var linkLocal *net.IPAddr
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || (ipnet.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		if !ipnet.IP.IsLinkLocalUnicast() {
+			return &net.IPAddr{IP: ipnet.IP}, nil
+		}
+		if linkLocal == nil {
+			linkLocal = &net.IPAddr{IP: ipnet.IP, Zone: iface.Name}
+		}
+	}
+	if linkLocal != nil {
+		return linkLocal, nil
+	}
+	return nil, fmt.Errorf("interface %s has no %s address", source, familyName(ipv6))
+}
+
+// isAddress returns true if s is a numeric IP address, with or without zone
+func isAddress(s string) bool {
+	address, _, _ := strings.Cut(s, "%")
+	return net.ParseIP(address) != nil
+}
+
+// familyName returns the name of the address family
+func familyName(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
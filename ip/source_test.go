@@ -0,0 +1,66 @@
+package ip_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestSourceAddress(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		source        string
+		ipv6          bool
+		expected      string
+		expectedError string
+	}{
+		{name: "Empty", source: "", expected: "<nil>"},
+		{name: "IPv4", source: "192.0.2.1", expected: "192.0.2.1"},
+		{name: "IPv6", source: "2001:db8::1", ipv6: true, expected: "2001:db8::1"},
+		{name: "IPv6WithZone", source: "fe80::1%eth0", ipv6: true, expected: "fe80::1%eth0"},
+		{name: "WrongFamilyIPv4", source: "2001:db8::1", expectedError: "must be an IPv4 address"},
+		{name: "WrongFamilyIPv6", source: "192.0.2.1", ipv6: true, expectedError: "must be an IPv6 address"},
+		{name: "UnknownInterface", source: "nope0", expectedError: "must be a local address or interface"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := ip.SourceAddress(tc.source, tc.ipv6)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if addr.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, addr.String())
+			}
+		})
+	}
+}
+
+func TestSourceAddressInterface(t *testing.T) {
+	// Use the IPv4 loopback interface of the machine
+	interfaces, _ := net.Interfaces()
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		addr, err := ip.SourceAddress(iface.Name, false)
+		if err != nil {
+			continue
+		}
+		if !addr.IP.IsLoopback() || addr.IP.To4() == nil {
+			t.Errorf("expected an IPv4 loopback address for %s, got %s", iface.Name, addr)
+		}
+		return
+	}
+	t.Skip("no IPv4 loopback interface available")
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	_ "embed"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// specialCSV is the embedded copy of the IANA IPv4 and IPv6 Special-Purpose
+// Address Registries
+//
+//go:embed special.csv
+var specialCSV string
+
+// SpecialPurpose is an entry in the IANA special-purpose address registries.
+// The source, destination, forwardable, globally reachable and reserved by
+// protocol attributes are true, false or n/a, and empty for deprecated entries.
+type SpecialPurpose struct {
+	Prefix             netip.Prefix `json:"prefix"`
+	Name               string       `json:"name"`
+	RFC                string       `json:"rfc"`
+	Allocated          string       `json:"allocated"`
+	Terminated         string       `json:"terminated,omitempty"`
+	Source             string       `json:"source"`
+	Destination        string       `json:"destination"`
+	Forwardable        string       `json:"forwardable"`
+	GloballyReachable  string       `json:"globally_reachable"`
+	ReservedByProtocol string       `json:"reserved_by_protocol"`
+}
+
+// specialPurposes holds the parsed registry, parsed on first use
+var (
+	specialPurposes     []SpecialPurpose
+	specialPurposesOnce sync.Once
+)
+
+// SpecialPurposes is a function that returns all entries in the embedded
+// special-purpose address registries, sorted by prefix
+func SpecialPurposes() []SpecialPurpose {
+	specialPurposesOnce.Do(func() {
+		specialPurposes = parseSpecialPurposes(specialCSV)
+	})
+	return specialPurposes
+}
+
+// parseSpecialPurposes is a function that parses the registry. Lines
+// starting with # are comments, invalid lines are skipped.
+func parseSpecialPurposes(data string) []SpecialPurpose {
+	var list []SpecialPurpose
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) != 10 {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			continue
+		}
+		list = append(list, SpecialPurpose{
+			Prefix:             prefix,
+			Name:               fields[1],
+			RFC:                fields[2],
+			Allocated:          fields[3],
+			Terminated:         fields[4],
+			Source:             fields[5],
+			Destination:        fields[6],
+			Forwardable:        fields[7],
+			GloballyReachable:  fields[8],
+			ReservedByProtocol: fields[9],
+		})
+	}
+	slices.SortStableFunc(list, func(a, b SpecialPurpose) int {
+		return ComparePrefixes(a.Prefix, b.Prefix)
+	})
+	return list
+}
+
+// LookupSpecialPurpose is a function that returns the registry entries
+// covering the prefix (the prefix is equal to or inside the entry), from the
+// least to the most specific, and the entries inside the prefix
+func LookupSpecialPurpose(prefix netip.Prefix) (covering, inside []SpecialPurpose) {
+	prefix = prefix.Masked()
+	for _, s := range SpecialPurposes() {
+		switch {
+		case s.Prefix.Bits() <= prefix.Bits() && s.Prefix.Contains(prefix.Addr()):
+			covering = append(covering, s)
+		case s.Prefix.Bits() > prefix.Bits() && prefix.Contains(s.Prefix.Addr()):
+			inside = append(inside, s)
+		}
+	}
+	return covering, inside
+}
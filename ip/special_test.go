@@ -0,0 +1,60 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// TestSpecialPurposes tests that every line of the embedded registry is parsed
+func TestSpecialPurposes(t *testing.T) {
+	entries := ip.SpecialPurposes()
+	if len(entries) < 40 {
+		t.Fatalf("expected at least 40 entries, got %d", len(entries))
+	}
+	for _, s := range entries {
+		if s.Name == "" || s.RFC == "" || s.Allocated == "" || s.Prefix != s.Prefix.Masked() {
+			t.Errorf("invalid entry: %+v", s)
+		}
+	}
+}
+
+// TestLookupSpecialPurpose tests the lookup of the entries covering and inside a prefix
+func TestLookupSpecialPurpose(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name             string
+		prefix           string
+		expectedCovering []string
+		expectedInside   int
+	}{
+		{name: "ExactMatch", prefix: "100.64.0.0/10", expectedCovering: []string{"Shared Address Space"}},
+		{name: "InsideEntry", prefix: "10.20.0.0/16", expectedCovering: []string{"Private-Use"}},
+		{name: "Address", prefix: "192.0.0.9/32", expectedCovering: []string{"IETF Protocol Assignments", "Port Control Protocol Anycast"}},
+		{name: "ContainsEntries", prefix: "192.0.0.0/24", expectedCovering: []string{"IETF Protocol Assignments"}, expectedInside: 6},
+		{name: "Nested", prefix: "0.0.0.0/32", expectedCovering: []string{"This network", "This host on this network"}},
+		{name: "IPv6", prefix: "2001:db8:1::/48", expectedCovering: []string{"Documentation"}},
+		{name: "Teredo", prefix: "2001::/32", expectedCovering: []string{"IETF Protocol Assignments", "TEREDO"}},
+		{name: "Global", prefix: "8.8.8.0/24"},
+		{name: "Everything", prefix: "0.0.0.0/0", expectedInside: 25},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			covering, inside := ip.LookupSpecialPurpose(netip.MustParsePrefix(tc.prefix))
+			if len(covering) != len(tc.expectedCovering) {
+				t.Fatalf("expected %d covering entries, got %+v", len(tc.expectedCovering), covering)
+			}
+			for i, name := range tc.expectedCovering {
+				if covering[i].Name != name {
+					t.Errorf("expected covering entry %d to be %q, got %q", i, name, covering[i].Name)
+				}
+			}
+			if len(inside) != tc.expectedInside {
+				t.Errorf("expected %d entries inside, got %d", tc.expectedInside, len(inside))
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Prefixes of the IPv4/IPv6 transition mechanisms
+var (
+	// SixToFourPrefix is the prefix of 6to4 addresses (RFC 3056)
+	SixToFourPrefix = netip.MustParsePrefix("2002::/16")
+
+	// NAT64WellKnownPrefix is the well-known prefix of NAT64 (RFC 6052)
+	NAT64WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+
+	// TeredoPrefix is the prefix of Teredo addresses (RFC 4380)
+	TeredoPrefix = netip.MustParsePrefix("2001::/32")
+)
+
+// Teredo holds the parameters embedded in a Teredo address (RFC 4380)
+type Teredo struct {
+	Server netip.Addr `json:"server"`
+	Client netip.Addr `json:"client"`
+	Port   uint16     `json:"port"`
+	Flags  uint16     `json:"flags"`
+	Cone   bool       `json:"cone"`
+}
+
+// SixToFour is a function that returns the 6to4 prefix (a /48 in
+// 2002::/16) of a public IPv4 address (RFC 3056)
+func SixToFour(v4 netip.Addr) (netip.Prefix, error) {
+	if !v4.Is4() {
+		return netip.Prefix{}, fmt.Errorf("invalid IPv4 address: %s", v4)
+	}
+	var b [16]byte
+	b[0], b[1] = 0x20, 0x02
+	a := v4.As4()
+	copy(b[2:6], a[:])
+	return netip.PrefixFrom(netip.AddrFrom16(b), 48), nil
+}
+
+// ExtractSixToFour is a function that returns the IPv4 address embedded in
+// a 6to4 address
+func ExtractSixToFour(v6 netip.Addr) (netip.Addr, error) {
+	if !SixToFourPrefix.Contains(v6.WithZone("")) {
+		return netip.Addr{}, fmt.Errorf("not a 6to4 address: %s (must be in %s)", v6, SixToFourPrefix)
+	}
+	b := v6.As16()
+	return netip.AddrFrom4([4]byte(b[2:6])), nil
+}
+
+// validNAT64Prefix is a function that checks that a NAT64 prefix has one of
+// the prefix lengths of RFC 6052 (32, 40, 48, 56, 64 or 96)
+func validNAT64Prefix(prefix netip.Prefix) error {
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return fmt.Errorf("invalid NAT64 prefix: %s (must be an IPv6 prefix)", prefix)
+	}
+	switch prefix.Bits() {
+	case 32, 40, 48, 56, 64, 96:
+		return nil
+	}
+	return fmt.Errorf("invalid NAT64 prefix length: /%d (must be 32, 40, 48, 56, 64 or 96)", prefix.Bits())
+}
+
+// NAT64Embed is a function that embeds an IPv4 address in a NAT64 prefix
+// (RFC 6052). The IPv4 address follows the prefix, skipping bits 64 to 71
+// (the u octet) which must be zero.
+func NAT64Embed(prefix netip.Prefix, v4 netip.Addr) (netip.Addr, error) {
+	if err := validNAT64Prefix(prefix); err != nil {
+		return netip.Addr{}, err
+	}
+	if !v4.Is4() {
+		return netip.Addr{}, fmt.Errorf("invalid IPv4 address: %s", v4)
+	}
+
+	b := prefix.Masked().Addr().As16()
+	a := v4.As4()
+	pos := prefix.Bits() / 8
+	for _, octet := range a {
+		if pos == 8 {
+			pos++
+		}
+		b[pos] = octet
+		pos++
+	}
+	return netip.AddrFrom16(b), nil
+}
+
+// NAT64Extract is a function that extracts the IPv4 address embedded in an
+// IPv6 address with a NAT64 prefix (RFC 6052)
+func NAT64Extract(prefix netip.Prefix, v6 netip.Addr) (netip.Addr, error) {
+	if err := validNAT64Prefix(prefix); err != nil {
+		return netip.Addr{}, err
+	}
+	if !prefix.Masked().Contains(v6.WithZone("")) {
+		return netip.Addr{}, fmt.Errorf("address %s is not in the NAT64 prefix %s", v6, prefix.Masked())
+	}
+
+	b := v6.As16()
+	if prefix.Bits() < 96 && b[8] != 0 {
+		return netip.Addr{}, fmt.Errorf("invalid NAT64 address: %s (bits 64 to 71 must be zero)", v6)
+	}
+	var a [4]byte
+	pos := prefix.Bits() / 8
+	for i := range a {
+		if pos == 8 {
+			pos++
+		}
+		a[i] = b[pos]
+		pos++
+	}
+	return netip.AddrFrom4(a), nil
+}
+
+// ParseTeredo is a function that decodes the parameters of a Teredo
+// address: the IPv4 address of the Teredo server, the flags, and the
+// public IPv4 address and UDP port of the client (obfuscated by inverting
+// all bits, RFC 4380 section 4)
+func ParseTeredo(v6 netip.Addr) (Teredo, error) {
+	if !TeredoPrefix.Contains(v6.WithZone("")) {
+		return Teredo{}, fmt.Errorf("not a Teredo address: %s (must be in %s)", v6, TeredoPrefix)
+	}
+	b := v6.As16()
+	flags := binary.BigEndian.Uint16(b[8:10])
+	client := [4]byte{^b[12], ^b[13], ^b[14], ^b[15]}
+	return Teredo{
+		Server: netip.AddrFrom4([4]byte(b[4:8])),
+		Client: netip.AddrFrom4(client),
+		Port:   ^binary.BigEndian.Uint16(b[10:12]),
+		Flags:  flags,
+		Cone:   flags&0x8000 != 0,
+	}, nil
+}
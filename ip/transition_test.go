@@ -0,0 +1,122 @@
+package ip_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestSixToFour(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "192.0.2.1", expected: "2002:c000:201::/48"},
+		{input: "198.51.100.254", expected: "2002:c633:64fe::/48"},
+		{input: "2001:db8::1", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			prefix, err := ip.SixToFour(netip.MustParseAddr(tc.input))
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if prefix.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, prefix)
+			}
+
+			// The IPv4 address is extracted from the prefix
+			v4, err := ip.ExtractSixToFour(prefix.Addr())
+			if err != nil || v4.String() != tc.input {
+				t.Errorf("expected %s, got %s (%v)", tc.input, v4, err)
+			}
+		})
+	}
+
+	// Other addresses are rejected
+	if _, err := ip.ExtractSixToFour(netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("expected an error for an address outside 2002::/16")
+	}
+}
+
+func TestNAT64(t *testing.T) {
+	// Setup test cases (the examples of RFC 6052 section 2.4)
+	testCases := []struct {
+		prefix    string
+		expected  string
+		expectErr bool
+	}{
+		{prefix: "2001:db8::/32", expected: "2001:db8:c000:221::"},
+		{prefix: "2001:db8:100::/40", expected: "2001:db8:1c0:2:21::"},
+		{prefix: "2001:db8:122::/48", expected: "2001:db8:122:c000:2:2100::"},
+		{prefix: "2001:db8:122:300::/56", expected: "2001:db8:122:3c0:0:221::"},
+		{prefix: "2001:db8:122:344::/64", expected: "2001:db8:122:344:c0:2:2100:0"},
+		{prefix: "2001:db8:122:344::/96", expected: "2001:db8:122:344::c000:221"},
+		{prefix: "64:ff9b::/96", expected: "64:ff9b::c000:221"},
+		{prefix: "2001:db8::/33", expectErr: true},
+		{prefix: "10.0.0.0/8", expectErr: true},
+	}
+
+	// Run test cases
+	v4 := netip.MustParseAddr("192.0.2.33")
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tc.prefix)
+			v6, err := ip.NAT64Embed(prefix, v4)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if v6.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, v6)
+			}
+
+			// The IPv4 address is extracted from the IPv6 address
+			got, err := ip.NAT64Extract(prefix, v6)
+			if err != nil || got != v4 {
+				t.Errorf("expected %s, got %s (%v)", v4, got, err)
+			}
+		})
+	}
+
+	// Addresses outside the prefix or with a non-zero u octet are rejected
+	if _, err := ip.NAT64Extract(ip.NAT64WellKnownPrefix, netip.MustParseAddr("2001:db8::c000:221")); err == nil {
+		t.Error("expected an error for an address outside the prefix")
+	}
+	if _, err := ip.NAT64Extract(netip.MustParsePrefix("2001:db8::/32"), netip.MustParseAddr("2001:db8:c000:221:ff00::")); err == nil {
+		t.Error("expected an error for a non-zero u octet")
+	}
+}
+
+func TestParseTeredo(t *testing.T) {
+	// The example of RFC 4380 section 4
+	teredo, err := ip.ParseTeredo(netip.MustParseAddr("2001:0:4136:e378:8000:63bf:3fff:fdd2"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ip.Teredo{
+		Server: netip.MustParseAddr("65.54.227.120"),
+		Client: netip.MustParseAddr("192.0.2.45"),
+		Port:   40000,
+		Flags:  0x8000,
+		Cone:   true,
+	}
+	if teredo != expected {
+		t.Errorf("expected %+v, got %+v", expected, teredo)
+	}
+
+	// Other addresses are rejected
+	if _, err := ip.ParseTeredo(netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("expected an error for an address outside 2001::/32")
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import "net/netip"
+
+// Trie is a binary trie of IPv4 and IPv6 prefixes for longest-prefix
+// matching. Every prefix holds one or more values (e.g. the routes to the
+// prefix). IPv4-mapped IPv6 addresses and prefixes are stored and matched
+// as IPv4. The zero value is not usable, use NewTrie.
+type Trie[V any] struct {
+	root4 *trieNode[V]
+	root6 *trieNode[V]
+	size  int
+}
+
+// trieNode is a node of a trie, the node of a prefix has the prefix set
+type trieNode[V any] struct {
+	children [2]*trieNode[V]
+	prefix   netip.Prefix
+	values   []V
+}
+
+// TrieMatch represents a prefix of a trie that contains an address, with
+// the values of the prefix
+type TrieMatch[V any] struct {
+	Prefix netip.Prefix
+	Values []V
+}
+
+// NewTrie is a function that returns an empty trie
+func NewTrie[V any]() *Trie[V] {
+	return &Trie[V]{root4: &trieNode[V]{}, root6: &trieNode[V]{}}
+}
+
+// Len is a function that returns the number of prefixes in the trie
+func (t *Trie[V]) Len() int {
+	return t.size
+}
+
+// root is a function that returns the root node of the family of an address
+func (t *Trie[V]) root(addr netip.Addr) *trieNode[V] {
+	if addr.Is4() {
+		return t.root4
+	}
+	return t.root6
+}
+
+// bit is a function that returns the bit of an address at the position i,
+// counted from the most significant bit
+func bit(addr netip.Addr, i int) int {
+	b := addr.As16()
+	if addr.Is4() {
+		i += 96
+	}
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// Insert is a function that adds a value to a prefix of the trie. Host bits
+// of the prefix are cleared, and a prefix can hold several values. Invalid
+// prefixes are ignored.
+func (t *Trie[V]) Insert(prefix netip.Prefix, value V) {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr, bits = addr.Unmap(), bits-96
+	}
+	prefix = netip.PrefixFrom(addr.WithZone(""), bits).Masked()
+	if !prefix.IsValid() {
+		return
+	}
+
+	node := t.root(prefix.Addr())
+	for i := 0; i < prefix.Bits(); i++ {
+		b := bit(prefix.Addr(), i)
+		if node.children[b] == nil {
+			node.children[b] = &trieNode[V]{}
+		}
+		node = node.children[b]
+	}
+	if !node.prefix.IsValid() {
+		node.prefix = prefix
+		t.size++
+	}
+	node.values = append(node.values, value)
+}
+
+// Matches is a function that returns all prefixes of the trie that contain
+// the address, from the most specific (the longest-prefix match) to the
+// least specific prefix
+func (t *Trie[V]) Matches(addr netip.Addr) []TrieMatch[V] {
+	addr = addr.Unmap().WithZone("")
+	var matches []TrieMatch[V]
+	node := t.root(addr)
+	for i := 0; node != nil; i++ {
+		if node.prefix.IsValid() {
+			matches = append(matches, TrieMatch[V]{Prefix: node.prefix, Values: node.values})
+		}
+		if i >= addr.BitLen() {
+			break
+		}
+		node = node.children[bit(addr, i)]
+	}
+
+	// The prefixes are found from the least to the most specific
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches
+}
+
+// Within is a function that returns all prefixes of the trie that are
+// within the prefix (including the prefix itself), in address order with
+// less specific prefixes first
+func (t *Trie[V]) Within(prefix netip.Prefix) []TrieMatch[V] {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr, bits = addr.Unmap(), bits-96
+	}
+	prefix = netip.PrefixFrom(addr.WithZone(""), bits).Masked()
+	if !prefix.IsValid() {
+		return nil
+	}
+
+	// Find the node of the prefix, there are no prefixes within it if the
+	// path ends before the node
+	node := t.root(prefix.Addr())
+	for i := 0; node != nil && i < prefix.Bits(); i++ {
+		node = node.children[bit(prefix.Addr(), i)]
+	}
+
+	// Collect the prefixes below the node in depth-first order
+	var matches []TrieMatch[V]
+	var walk func(n *trieNode[V])
+	walk = func(n *trieNode[V]) {
+		if n == nil {
+			return
+		}
+		if n.prefix.IsValid() {
+			matches = append(matches, TrieMatch[V]{Prefix: n.prefix, Values: n.values})
+		}
+		walk(n.children[0])
+		walk(n.children[1])
+	}
+	walk(node)
+	return matches
+}
+
+// Lookup is a function that returns the longest prefix of the trie that
+// contains the address, with its values. The last return value is false if
+// no prefix contains the address.
+func (t *Trie[V]) Lookup(addr netip.Addr) (netip.Prefix, []V, bool) {
+	addr = addr.Unmap().WithZone("")
+	var best *trieNode[V]
+	node := t.root(addr)
+	for i := 0; node != nil; i++ {
+		if node.prefix.IsValid() {
+			best = node
+		}
+		if i >= addr.BitLen() {
+			break
+		}
+		node = node.children[bit(addr, i)]
+	}
+	if best == nil {
+		return netip.Prefix{}, nil, false
+	}
+	return best.prefix, best.values, true
+}
+
+// Contains is a function that checks if any prefix of the trie contains
+// the address
+func (t *Trie[V]) Contains(addr netip.Addr) bool {
+	_, _, ok := t.Lookup(addr)
+	return ok
+}
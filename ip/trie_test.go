@@ -0,0 +1,124 @@
+package ip_test
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestTrie(t *testing.T) {
+	trie := ip.NewTrie[string]()
+	for _, entry := range []struct{ prefix, value string }{
+		{"0.0.0.0/0", "default"},
+		{"10.0.0.0/8", "a"},
+		{"10.1.0.0/16", "b"},
+		{"10.1.0.0/16", "c"},
+		{"10.1.2.3/32", "host"},
+		{"10.1.2.0/25", "d"},
+		{"2001:db8::/32", "v6"},
+		{"::ffff:192.168.0.0/112", "mapped"},
+	} {
+		trie.Insert(netip.MustParsePrefix(entry.prefix), entry.value)
+	}
+	if trie.Len() != 7 {
+		t.Errorf("expected 7 prefixes, got %d", trie.Len())
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		expected string
+		matches  string
+	}{
+		{addr: "10.1.2.3", expected: "10.1.2.3/32 [host]", matches: "10.1.2.3/32 10.1.2.0/25 10.1.0.0/16 10.0.0.0/8 0.0.0.0/0"},
+		{addr: "10.1.2.200", expected: "10.1.0.0/16 [b c]", matches: "10.1.0.0/16 10.0.0.0/8 0.0.0.0/0"},
+		{addr: "10.200.0.1", expected: "10.0.0.0/8 [a]", matches: "10.0.0.0/8 0.0.0.0/0"},
+		{addr: "192.168.7.7", expected: "192.168.0.0/16 [mapped]", matches: "192.168.0.0/16 0.0.0.0/0"},
+		{addr: "::ffff:10.1.2.3", expected: "10.1.2.3/32 [host]", matches: "10.1.2.3/32 10.1.2.0/25 10.1.0.0/16 10.0.0.0/8 0.0.0.0/0"},
+		{addr: "2001:db8::1", expected: "2001:db8::/32 [v6]", matches: "2001:db8::/32"},
+		{addr: "2001:db9::1", expected: "none"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			addr := netip.MustParseAddr(tc.addr)
+			got := "none"
+			if prefix, values, ok := trie.Lookup(addr); ok {
+				got = fmt.Sprintf("%s %v", prefix, values)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+			if trie.Contains(addr) != (tc.expected != "none") {
+				t.Errorf("unexpected Contains result")
+			}
+
+			var matches []string
+			for _, m := range trie.Matches(addr) {
+				matches = append(matches, m.Prefix.String())
+			}
+			if strings.Join(matches, " ") != tc.matches {
+				t.Errorf("expected matches %s, got %s", tc.matches, strings.Join(matches, " "))
+			}
+		})
+	}
+}
+
+func TestTrieWithin(t *testing.T) {
+	trie := ip.NewTrie[string]()
+	for _, prefix := range []string{"10.0.0.0/8", "10.1.2.0/25", "10.1.0.0/16", "10.1.2.3/32", "192.168.0.0/16", "2001:db8::/32"} {
+		trie.Insert(netip.MustParsePrefix(prefix), prefix)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		prefix   string
+		expected string
+	}{
+		{prefix: "0.0.0.0/0", expected: "10.0.0.0/8 10.1.0.0/16 10.1.2.0/25 10.1.2.3/32 192.168.0.0/16"},
+		{prefix: "10.0.0.0/8", expected: "10.0.0.0/8 10.1.0.0/16 10.1.2.0/25 10.1.2.3/32"},
+		{prefix: "10.1.2.0/24", expected: "10.1.2.0/25 10.1.2.3/32"},
+		{prefix: "::ffff:10.1.2.0/120", expected: "10.1.2.0/25 10.1.2.3/32"},
+		{prefix: "10.1.2.4/30", expected: ""},
+		{prefix: "10.1.2.3/32", expected: "10.1.2.3/32"},
+		{prefix: "::/0", expected: "2001:db8::/32"},
+		{prefix: "2001:db8:1::/48", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			var got []string
+			for _, m := range trie.Within(netip.MustParsePrefix(tc.prefix)) {
+				got = append(got, m.Prefix.String())
+			}
+			if strings.Join(got, " ") != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestTrieLarge(t *testing.T) {
+	// Insert all /24 prefixes of 10.0.0.0/12 and check a few lookups
+	trie := ip.NewTrie[int]()
+	for i := 0; i < 4096; i++ {
+		trie.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24), i)
+	}
+	if trie.Len() != 4096 {
+		t.Fatalf("expected 4096 prefixes, got %d", trie.Len())
+	}
+	for _, i := range []int{0, 1, 255, 256, 4095} {
+		addr := netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 42})
+		if _, values, ok := trie.Lookup(addr); !ok || values[0] != i {
+			t.Errorf("%s: expected %d, got %v", addr, i, values)
+		}
+	}
+	if trie.Contains(netip.MustParseAddr("10.16.0.1")) {
+		t.Error("expected no match for 10.16.0.1")
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Usage is the address usage of an IPv4 prefix
+type Usage struct {
+	Prefix  *IPv4
+	Used    uint64
+	Free    uint64
+	Outside uint64
+
+	// FreeRanges are the ranges of contiguous free addresses in the prefix
+	FreeRanges []*IPv4Range
+}
+
+// ParseUsedAddresses is a function that reads a list of used addresses,
+// one per line. A line can also contain a range of addresses (first-last)
+// or a prefix, which are used completely. Only the first field of a line
+// is parsed, so a hosts file or a list of "address hostname" pairs can be
+// used as it is. Empty lines and comments starting with # are skipped.
+func ParseUsedAddresses(r io.Reader) ([]*IPv4Range, error) {
+	var ranges []*IPv4Range
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		r, err := parseUsedField(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges = append(ranges, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// parseUsedField is a function that parses an address, a range of
+// addresses or a prefix as a range
+func parseUsedField(s string) (*IPv4Range, error) {
+	switch {
+	case strings.Contains(s, "-"):
+		return ParseIPv4Range(s)
+	case strings.Contains(s, "/"):
+		network, err := ParseIPv4(s)
+		if err != nil {
+			return nil, err
+		}
+		return &IPv4Range{First: IPv4ToInt(network.Network()), Last: IPv4ToInt(network.Broadcast())}, nil
+	}
+
+	n, ok := parseIPv4Int(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid IPv4 address: %s", s)
+	}
+	return &IPv4Range{First: n, Last: n}, nil
+}
+
+// SubnetUsage is a function that calculates the usage of a prefix from the
+// used addresses. Addresses that are used more than once are only counted
+// once, and addresses outside the prefix are counted as outside.
+func SubnetUsage(prefix *IPv4, used []*IPv4Range) *Usage {
+	first := uint64(IPv4ToInt(prefix.Network()))
+	last := uint64(IPv4ToInt(prefix.Broadcast()))
+	u := &Usage{Prefix: prefix}
+
+	// Sort the used ranges so that the free gaps can be found in one pass
+	sorted := make([]*IPv4Range, len(used))
+	copy(sorted, used)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].First < sorted[j].First })
+
+	// next is the first address that is not yet known to be used
+	next := first
+	for _, r := range sorted {
+		start, end := uint64(r.First), uint64(r.Last)
+
+		// Count the addresses outside the prefix and clip the range
+		if start < first {
+			u.Outside += min(end+1, first) - start
+			start = first
+		}
+		if end > last {
+			u.Outside += end - max(start, last+1) + 1
+			end = last
+		}
+		if start > end || end < next {
+			continue
+		}
+
+		// Record the gap before the range as free
+		if start > next {
+			u.FreeRanges = append(u.FreeRanges, &IPv4Range{First: uint32(next), Last: uint32(start - 1)})
+		}
+		u.Used += end - max(start, next) + 1
+		next = end + 1
+	}
+	if next <= last {
+		u.FreeRanges = append(u.FreeRanges, &IPv4Range{First: uint32(next), Last: uint32(last)})
+	}
+
+	u.Free = last - first + 1 - u.Used
+	return u
+}
+
+// Size is a function that returns the number of addresses in the prefix
+func (u *Usage) Size() uint64 {
+	return u.Used + u.Free
+}
+
+// LargestFreeRange is a function that returns the largest range of
+// contiguous free addresses, or nil if the prefix is full
+func (u *Usage) LargestFreeRange() *IPv4Range {
+	var largest *IPv4Range
+	for _, r := range u.FreeRanges {
+		if largest == nil || r.Size() > largest.Size() {
+			largest = r
+		}
+	}
+	return largest
+}
+
+// LargestFreeBlock is a function that returns the largest free subnet (a
+// free block aligned on its size), which is the largest subnet that can
+// still be allocated from the prefix, or nil if the prefix is full
+func (u *Usage) LargestFreeBlock() *IPv4 {
+	var largest *IPv4
+	for _, r := range u.FreeRanges {
+		for _, network := range r.CIDRs() {
+			if largest == nil || network.PrefixLength() < largest.PrefixLength() {
+				largest = network
+			}
+		}
+	}
+	return largest
+}
+
+// Fragmentation is a function that returns the percentage of the free
+// addresses that are not in the largest free range. It is 0 when all free
+// addresses are contiguous and approaches 100 when the free addresses are
+// scattered in many small ranges.
+func (u *Usage) Fragmentation() float64 {
+	largest := u.LargestFreeRange()
+	if largest == nil {
+		return 0
+	}
+	return 100 * float64(u.Free-largest.Size()) / float64(u.Free)
+}
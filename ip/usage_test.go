@@ -0,0 +1,124 @@
+package ip_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+func TestParseUsedAddresses(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "Addresses", input: "10.0.4.1\n10.0.4.2\n", expected: []string{"10.0.4.1-10.0.4.1", "10.0.4.2-10.0.4.2"}},
+		{name: "Hosts file", input: "# servers\n10.0.4.1  web01 web\n\n10.0.4.2\tdb01\n", expected: []string{"10.0.4.1-10.0.4.1", "10.0.4.2-10.0.4.2"}},
+		{name: "Range", input: "10.0.4.10-10.0.4.20", expected: []string{"10.0.4.10-10.0.4.20"}},
+		{name: "Prefix", input: "10.0.5.0/25", expected: []string{"10.0.5.0-10.0.5.127"}},
+		{name: "Invalid", input: "10.0.4.1\nweb01\n", expectErr: true},
+		{name: "IPv6", input: "2001:db8::1", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ranges, err := ip.ParseUsedAddresses(strings.NewReader(tc.input))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			var result []string
+			for _, r := range ranges {
+				result = append(result, r.String())
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSubnetUsage(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		prefix        string
+		used          string
+		usedCount     uint64
+		outside       uint64
+		freeRanges    []string
+		largestBlock  string
+		fragmentation float64
+	}{
+		{
+			name:         "Empty",
+			prefix:       "10.0.4.0/22",
+			freeRanges:   []string{"10.0.4.0-10.0.7.255"},
+			largestBlock: "10.0.4.0/22",
+		},
+		{
+			name:          "Scattered",
+			prefix:        "10.0.4.0/24",
+			used:          "10.0.4.0-10.0.4.9\n10.0.4.5\n10.0.4.128\n10.0.4.200-10.0.4.255",
+			usedCount:     67,
+			freeRanges:    []string{"10.0.4.10-10.0.4.127", "10.0.4.129-10.0.4.199"},
+			largestBlock:  "10.0.4.64/26",
+			fragmentation: 100 * 71.0 / 189.0,
+		},
+		{
+			name:         "Outside",
+			prefix:       "10.0.4.0/24",
+			used:         "10.0.3.250-10.0.4.1\n10.0.4.254-10.0.5.1\n192.168.1.1",
+			usedCount:    4,
+			outside:      9,
+			freeRanges:   []string{"10.0.4.2-10.0.4.253"},
+			largestBlock: "10.0.4.64/26",
+		},
+		{
+			name:      "Full",
+			prefix:    "10.0.4.0/30",
+			used:      "10.0.4.0/30",
+			usedCount: 4,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, err := ip.ParseIPv4(tc.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			used, err := ip.ParseUsedAddresses(strings.NewReader(tc.used))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			u := ip.SubnetUsage(prefix, used)
+			if u.Used != tc.usedCount || u.Outside != tc.outside {
+				t.Errorf("expected %d used and %d outside, got %d and %d", tc.usedCount, tc.outside, u.Used, u.Outside)
+			}
+			var freeRanges []string
+			for _, r := range u.FreeRanges {
+				freeRanges = append(freeRanges, r.String())
+			}
+			if !reflect.DeepEqual(freeRanges, tc.freeRanges) {
+				t.Errorf("expected free ranges %v, got %v", tc.freeRanges, freeRanges)
+			}
+			largestBlock := ""
+			if block := u.LargestFreeBlock(); block != nil {
+				largestBlock = block.String()
+			}
+			if largestBlock != tc.largestBlock {
+				t.Errorf("expected largest free block %q, got %q", tc.largestBlock, largestBlock)
+			}
+			if u.Fragmentation() != tc.fragmentation {
+				t.Errorf("expected fragmentation %f, got %f", tc.fragmentation, u.Fragmentation())
+			}
+		})
+	}
+}
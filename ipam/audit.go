@@ -0,0 +1,228 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+)
+
+// Change represents a change of an entry in the IPAM store, as recorded in
+// the audit log. Before is nil for added entries and After is nil for
+// removed entries.
+type Change struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Action  string    `json:"action"`
+	Prefix  string    `json:"prefix"`
+	Before  *Entry    `json:"before,omitempty"`
+	After   *Entry    `json:"after,omitempty"`
+}
+
+// LogPath is a function that returns the path of the audit log of the IPAM
+// store, a file next to the store with the extension .log (ipam.yaml is
+// logged to ipam.log)
+func LogPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".log"
+}
+
+// Diff is a function that compares two versions of the entries and returns
+// the changes, without the metadata (time, user, host and command)
+func Diff(before, after []Entry) []Change {
+	old := make(map[string]Entry, len(before))
+	for _, e := range before {
+		old[e.Prefix] = e
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(after))
+	for _, e := range after {
+		e := e
+		seen[e.Prefix] = true
+		prev, ok := old[e.Prefix]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Action: ActionAdd, Prefix: e.Prefix, After: &e})
+		case prev != e:
+			changes = append(changes, Change{Action: ActionUpdate, Prefix: e.Prefix, Before: &prev, After: &e})
+		}
+	}
+	for _, e := range before {
+		e := e
+		if !seen[e.Prefix] {
+			changes = append(changes, Change{Action: ActionRemove, Prefix: e.Prefix, Before: &e})
+		}
+	}
+	return changes
+}
+
+// auditUser is a function that returns the name of the user making the
+// changes. IPTOOL_USER takes precedence, e.g. to record the name of the
+// engineer when the changes are made by a shared account or a CI job.
+func auditUser() string {
+	for _, env := range []string{"IPTOOL_USER", "SUDO_USER", "USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// AppendLog is a function that appends the changes to the audit log,
+// setting the time, user, host and command of the changes
+func AppendLog(path string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Collect the metadata of the changes from the environment
+	now := time.Now()
+	name := auditUser()
+	host, _ := os.Hostname()
+	command := strings.Join(os.Args, " ")
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Write all changes in one write, so that concurrent writers do not interleave lines
+	var data []byte
+	for _, c := range changes {
+		c.Time, c.User, c.Host, c.Command = now, name, host, command
+		line, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadLog is a function that reads the changes in the audit log. A missing
+// log has no changes.
+func ReadLog(path string) ([]Change, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var changes []Change
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var c Change
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid audit log entry: %w", path, line, err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, scanner.Err()
+}
+
+// Summary is a function that returns a short description of the change,
+// the fields of an added entry or the fields that were changed
+func (c Change) Summary() string {
+	switch c.Action {
+	case ActionAdd:
+		return strings.Join(entryFields(*c.After), ", ")
+	case ActionRemove:
+		return "removed (was " + strings.Join(append([]string{c.Before.Status()}, entryFields(*c.Before)[1:]...), ", ") + ")"
+	}
+
+	// Describe the fields that changed
+	var fields []string
+	before, after := fieldMap(*c.Before), fieldMap(*c.After)
+	for _, name := range []string{FieldState, FieldName, FieldVLAN, FieldDescription, FieldExpires} {
+		if before[name] != after[name] {
+			fields = append(fields, fmt.Sprintf("%s: %s -> %s", name, orDash(before[name]), orDash(after[name])))
+		}
+	}
+	return strings.Join(fields, ", ")
+}
+
+// fieldMap is a function that returns the fields of an entry as strings
+func fieldMap(e Entry) map[string]string {
+	vlan := ""
+	if e.VLAN > 0 {
+		vlan = strconv.Itoa(e.VLAN)
+	}
+	return map[string]string{
+		FieldState:       e.Status(),
+		FieldName:        e.Name,
+		FieldVLAN:        vlan,
+		FieldDescription: e.Description,
+		FieldExpires:     e.Expires,
+	}
+}
+
+// entryFields is a function that returns the non-empty fields of an entry
+// as name=value pairs, starting with the state
+func entryFields(e Entry) []string {
+	m := fieldMap(e)
+	fields := []string{m[FieldState]}
+	for _, name := range []string{FieldName, FieldVLAN, FieldDescription, FieldExpires} {
+		if m[name] != "" {
+			fields = append(fields, fmt.Sprintf("%s=%s", name, m[name]))
+		}
+	}
+	return fields
+}
+
+// orDash is a function that returns a dash for empty values
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package ipam_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+func TestDiff(t *testing.T) {
+	before := []ipam.Entry{
+		{Prefix: "10.0.0.0/24", Name: "servers"},
+		{Prefix: "10.0.1.0/24", Name: "lab", State: ipam.StateReserved, Expires: "2024-12-31"},
+		{Prefix: "10.0.2.0/24", Name: "old"},
+	}
+	after := []ipam.Entry{
+		{Prefix: "10.0.0.0/24", Name: "servers"},
+		{Prefix: "10.0.1.0/24", Name: "lab", VLAN: 10},
+		{Prefix: "10.0.3.0/24", Name: "new", State: ipam.StateReserved},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		action  string
+		prefix  string
+		summary string
+	}{
+		{action: ipam.ActionUpdate, prefix: "10.0.1.0/24", summary: "state: reserved -> allocated, vlan: - -> 10, expires: 2024-12-31 -> -"},
+		{action: ipam.ActionAdd, prefix: "10.0.3.0/24", summary: "reserved, name=new"},
+		{action: ipam.ActionRemove, prefix: "10.0.2.0/24", summary: "removed (was allocated, name=old)"},
+	}
+
+	// Run test cases
+	changes := ipam.Diff(before, after)
+	if len(changes) != len(testCases) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(testCases), len(changes), changes)
+	}
+	for i, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			c := changes[i]
+			if c.Action != tc.action || c.Prefix != tc.prefix {
+				t.Errorf("expected %s %s, got %s %s", tc.action, tc.prefix, c.Action, c.Prefix)
+			}
+			if summary := c.Summary(); summary != tc.summary {
+				t.Errorf("expected summary %q, got %q", tc.summary, summary)
+			}
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Setenv("IPTOOL_USER", "alice")
+	path := filepath.Join(t.TempDir(), "ipam.yaml")
+	if logPath := ipam.LogPath(path); filepath.Base(logPath) != "ipam.log" {
+		t.Fatalf("expected ipam.log, got %s", logPath)
+	}
+
+	// Add an entry, change it and save without changes
+	store, err := ipam.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Add(ipam.Entry{Prefix: "10.0.0.0/24", State: ipam.StateReserved}, false)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Add(ipam.Entry{Prefix: "10.0.0.0/24"}, true)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Changes made after loading the store again are logged too
+	store, err = ipam.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Remove("10.0.0.0/24")
+	if err := store.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes, err := ipam.ReadLog(ipam.LogPath(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actions []string
+	for _, c := range changes {
+		actions = append(actions, c.Action)
+		if c.User != "alice" || c.Time.IsZero() {
+			t.Errorf("expected user alice and a time, got %q and %v", c.User, c.Time)
+		}
+	}
+	expected := []string{ipam.ActionAdd, ipam.ActionUpdate, ipam.ActionRemove}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("expected actions %v, got %v", expected, actions)
+	}
+}
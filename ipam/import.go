@@ -0,0 +1,359 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// Fields of an entry that can be mapped to the columns of a spreadsheet
+const (
+	FieldPrefix      = "prefix"
+	FieldName        = "name"
+	FieldVLAN        = "vlan"
+	FieldDescription = "description"
+	FieldState       = "state"
+	FieldExpires     = "expires"
+)
+
+// Fields is the list of all fields that can be mapped
+var Fields = []string{FieldPrefix, FieldName, FieldVLAN, FieldDescription, FieldState, FieldExpires}
+
+// Mapping maps the fields of an entry to the columns of a spreadsheet. A
+// column is either a column number (starting at 1) or a header name.
+type Mapping map[string]string
+
+// ImportOptions controls how a spreadsheet is read by ReadCSV
+type ImportOptions struct {
+	Mapping Mapping
+
+	// Delimiter is the field delimiter, it is detected from the first line if zero
+	Delimiter rune
+}
+
+// Row is the result of reading one row of a spreadsheet. Notes describe
+// how the row was normalized (e.g. a netmask converted to a prefix length).
+type Row struct {
+	Line  int
+	Entry Entry
+	Notes []string
+	Err   error
+}
+
+// ParseMapping is a function that parses a mapping of fields to columns in
+// the format "prefix=2,name=1,vlan=3" or "prefix=Network,name=Site". The
+// prefix field is required.
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping: %q (expected field=column)", pair)
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("invalid mapping: unknown field %q (must be one of %s)", field, strings.Join(Fields, ", "))
+		}
+		if _, dup := m[field]; dup {
+			return nil, fmt.Errorf("invalid mapping: field %q mapped more than once", field)
+		}
+		if n, err := strconv.Atoi(column); err == nil && n < 1 {
+			return nil, fmt.Errorf("invalid mapping: column %d of field %q (columns start at 1)", n, field)
+		}
+		m[field] = column
+	}
+	if _, ok := m[FieldPrefix]; !ok {
+		return nil, errors.New("invalid mapping: the prefix field must be mapped to a column")
+	}
+	return m, nil
+}
+
+// isField is a function that checks if s is the name of a mappable field
+func isField(s string) bool {
+	for _, f := range Fields {
+		if s == f {
+			return true
+		}
+	}
+	return false
+}
+
+// usesHeader is a function that checks if any field is mapped by header name
+func (m Mapping) usesHeader() bool {
+	for _, column := range m {
+		if _, err := strconv.Atoi(column); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve is a function that returns the (zero based) column index of every
+// mapped field, looking up the header names in the header row
+func (m Mapping) resolve(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for field, column := range m {
+		if n, err := strconv.Atoi(column); err == nil {
+			columns[field] = n - 1
+			continue
+		}
+		found := false
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				columns[field], found = i, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q of field %q not found in the header", column, field)
+		}
+	}
+	return columns, nil
+}
+
+// ReadCSV is a function that reads the rows of a spreadsheet exported as CSV
+// and turns them into IPAM entries using the mapping in the options. The
+// first row is treated as a header if any field is mapped by header name,
+// or if its prefix column does not contain a prefix. Empty rows are skipped.
+// Rows that cannot be parsed are returned with Err set, only errors in the
+// file itself (e.g. broken quoting) are returned as an error.
+func ReadCSV(r io.Reader, opts ImportOptions) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spreadsheets exported from Excel often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = opts.Delimiter
+	if reader.Comma == 0 {
+		reader.Comma = detectDelimiter(data)
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	// Read the first row, which may be the header
+	first, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the columns, using the first row as the header if needed
+	columns, err := opts.Mapping.resolve(first)
+	if err != nil {
+		return nil, err
+	}
+	header := opts.Mapping.usesHeader()
+	if !header && !isEmpty(first) {
+		_, _, err := NormalizePrefix(field(first, columns, FieldPrefix))
+		header = err != nil
+	}
+
+	var rows []Row
+	record := first
+	for {
+		line, _ := reader.FieldPos(0)
+		if !header && !isEmpty(record) {
+			row := Row{Line: line}
+			row.Entry, row.Notes, row.Err = parseRecord(record, columns)
+			rows = append(rows, row)
+		}
+		header = false
+
+		record, err = reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRecord is a function that turns a row of the spreadsheet into an entry
+func parseRecord(record []string, columns map[string]int) (Entry, []string, error) {
+	var notes []string
+
+	raw := field(record, columns, FieldPrefix)
+	if raw == "" {
+		return Entry{}, nil, errors.New("no prefix")
+	}
+	prefix, note, err := NormalizePrefix(raw)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	vlan, err := NormalizeVLAN(field(record, columns, FieldVLAN))
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	state, err := ParseState(field(record, columns, FieldState))
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	expires, err := ParseExpiry(field(record, columns, FieldExpires), time.Now())
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	return Entry{
+		Prefix:      prefix.String(),
+		Name:        field(record, columns, FieldName),
+		VLAN:        vlan,
+		Description: field(record, columns, FieldDescription),
+		State:       state,
+		Expires:     expires,
+	}, notes, nil
+}
+
+// field is a function that returns the trimmed value of the column mapped to
+// the field, or an empty string if the field is not mapped or the row is short
+func field(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.Join(strings.Fields(record[i]), " ")
+}
+
+// isEmpty is a function that checks if all columns of a row are blank
+func isEmpty(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// detectDelimiter is a function that guesses the delimiter of a CSV file
+// from its first line. Spreadsheets exported in locales using the decimal
+// comma are often separated by semicolons.
+func detectDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, count := ',', bytes.Count(line, []byte(","))
+	for _, d := range []rune{';', '\t', '|'} {
+		if n := bytes.Count(line, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// NormalizePrefix is a function that parses a prefix as it is commonly
+// written in spreadsheets and returns it in CIDR notation. The accepted
+// formats are CIDR notation (10.0.0.0/24), an address followed by a netmask
+// (10.0.0.0 255.255.255.0 or 10.0.0.0/255.255.255.0) and a single address
+// (as a /32 or /128 prefix). Host bits are cleared. The returned note
+// describes the normalization, it is empty if the input was already in
+// canonical form.
+func NormalizePrefix(s string) (netip.Prefix, string, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == ' ' || r == '\t'
+	})
+	if len(parts) == 0 || len(parts) > 2 {
+		return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q", s)
+	}
+
+	addr, err := netip.ParseAddr(parts[0])
+	if err != nil {
+		return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q", s)
+	}
+	addr = addr.Unmap()
+
+	// Determine the prefix length from the prefix length or the netmask
+	bits := addr.BitLen()
+	if len(parts) == 2 {
+		n, err := strconv.Atoi(parts[1])
+		switch {
+		case err == nil:
+			bits = n
+		case addr.Is4():
+			mask, err := ip.ParseMask(parts[1])
+			if err != nil {
+				return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q: %w", s, err)
+			}
+			if !mask.Contiguous() {
+				return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q: netmask %s is not contiguous", s, parts[1])
+			}
+			bits = mask.PrefixLength()
+		default:
+			return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q", s)
+		}
+	}
+
+	prefix := netip.PrefixFrom(addr, bits)
+	if !prefix.IsValid() {
+		return netip.Prefix{}, "", fmt.Errorf("invalid prefix: %q: invalid prefix length", s)
+	}
+
+	// Describe the normalization
+	switch {
+	case prefix.Masked() != prefix:
+		return prefix.Masked(), fmt.Sprintf("host bits cleared: %s -> %s", s, prefix.Masked()), nil
+	case prefix.String() != s:
+		return prefix, fmt.Sprintf("normalized: %s -> %s", s, prefix), nil
+	}
+	return prefix, "", nil
+}
+
+// NormalizeVLAN is a function that parses a VLAN ID as it is commonly
+// written in spreadsheets (e.g. "10", "VLAN 10" or "vlan10"). An empty
+// value is returned as 0 (no VLAN).
+func NormalizeVLAN(s string) (int, error) {
+	v := strings.TrimSpace(s)
+	if len(v) >= 4 && strings.EqualFold(v[:4], "vlan") {
+		v = strings.TrimSpace(v[4:])
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || id < 1 || id > 4094 {
+		return 0, fmt.Errorf("invalid VLAN: %q (must be between 1 and 4094)", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,224 @@
+package ipam_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+func TestNormalizePrefix(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  string
+		note      bool
+		expectErr bool
+	}{
+		{name: "CIDR", input: "10.0.0.0/24", expected: "10.0.0.0/24"},
+		{name: "Whitespace", input: " 10.0.0.0/24 ", expected: "10.0.0.0/24"},
+		{name: "Netmask", input: "10.0.0.0 255.255.255.0", expected: "10.0.0.0/24", note: true},
+		{name: "SlashNetmask", input: "10.0.0.0/255.255.0.0", expected: "10.0.0.0/16", note: true},
+		{name: "HostBits", input: "10.0.0.1/24", expected: "10.0.0.0/24", note: true},
+		{name: "Address", input: "192.0.2.1", expected: "192.0.2.1/32", note: true},
+		{name: "IPv6", input: "2001:db8::/32", expected: "2001:db8::/32"},
+		{name: "IPv6HostBits", input: "2001:db8::1/64", expected: "2001:db8::/64", note: true},
+		{name: "IPv6Netmask", input: "2001:db8:: ffff::", expectErr: true},
+		{name: "Discontiguous", input: "10.0.0.0 255.0.255.0", expectErr: true},
+		{name: "InvalidLength", input: "10.0.0.0/33", expectErr: true},
+		{name: "Invalid", input: "10.0.x.0/24", expectErr: true},
+		{name: "Empty", input: "", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, note, err := ipam.NormalizePrefix(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", prefix)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prefix.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, prefix)
+			}
+			if (note != "") != tc.note {
+				t.Errorf("expected note %v, got %q", tc.note, note)
+			}
+		})
+	}
+}
+
+func TestNormalizeVLAN(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  int
+		expectErr bool
+	}{
+		{input: "", expected: 0},
+		{input: "10", expected: 10},
+		{input: "VLAN 10", expected: 10},
+		{input: "vlan20", expected: 20},
+		{input: "0", expectErr: true},
+		{input: "4095", expectErr: true},
+		{input: "servers", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			vlan, err := ipam.NormalizeVLAN(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %d", vlan)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if vlan != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, vlan)
+			}
+		})
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  ipam.Mapping
+		expectErr bool
+	}{
+		{name: "Numbers", input: "prefix=2,name=1,vlan=3", expected: ipam.Mapping{"prefix": "2", "name": "1", "vlan": "3"}},
+		{name: "Headers", input: "prefix=Network, Name=Site", expected: ipam.Mapping{"prefix": "Network", "name": "Site"}},
+		{name: "NoPrefix", input: "name=1", expectErr: true},
+		{name: "UnknownField", input: "prefix=1,owner=2", expectErr: true},
+		{name: "Duplicate", input: "prefix=1,prefix=2", expectErr: true},
+		{name: "ZeroColumn", input: "prefix=0", expectErr: true},
+		{name: "NoColumn", input: "prefix", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, err := ipam.ParseMapping(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", mapping)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(mapping, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, mapping)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		mapping   string
+		expected  []ipam.Entry
+		lines     []int
+		invalid   []int
+		expectErr bool
+	}{
+		{
+			name:     "NoHeader",
+			input:    "servers,10.0.0.0/24,10\nstorage,10.0.1.0/24,\n",
+			mapping:  "prefix=2,name=1,vlan=3",
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "servers", VLAN: 10}, {Prefix: "10.0.1.0/24", Name: "storage"}},
+			lines:    []int{1, 2},
+		},
+		{
+			name:     "DetectedHeader",
+			input:    "Name,Prefix\nservers,10.0.0.0/24\n",
+			mapping:  "prefix=2,name=1",
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "servers"}},
+			lines:    []int{2},
+		},
+		{
+			name:     "HeaderNames",
+			input:    "\xef\xbb\xbfSite;Network;Notes\nHQ;10.0.0.0 255.255.0.0;\"main; site\"\n\n;;\nlab;10.1.0.1/16;\n",
+			mapping:  "prefix=network,name=site,description=Notes",
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/16", Name: "HQ", Description: "main; site"}, {Prefix: "10.1.0.0/16", Name: "lab"}},
+			lines:    []int{2, 5},
+		},
+		{
+			name:     "InvalidRows",
+			input:    "10.0.0.0/24,VLAN 10\nfoo,1\n10.0.1.0/24,9999\n",
+			mapping:  "prefix=1,vlan=2",
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24", VLAN: 10}, {}, {}},
+			lines:    []int{1, 2, 3},
+			invalid:  []int{2, 3},
+		},
+		{
+			name:     "ShortRow",
+			input:    "Prefix,Name\n10.0.0.0/24\n",
+			mapping:  "prefix=1,name=2",
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24"}},
+			lines:    []int{2},
+		},
+		{
+			name:      "UnknownHeader",
+			input:     "Network,Site\n10.0.0.0/24,HQ\n",
+			mapping:   "prefix=Prefix",
+			expectErr: true,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, err := ipam.ParseMapping(tc.mapping)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rows, err := ipam.ReadCSV(strings.NewReader(tc.input), ipam.ImportOptions{Mapping: mapping})
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var entries []ipam.Entry
+			var lines, invalid []int
+			for _, row := range rows {
+				entries = append(entries, row.Entry)
+				lines = append(lines, row.Line)
+				if row.Err != nil {
+					invalid = append(invalid, row.Line)
+				}
+			}
+			if !reflect.DeepEqual(entries, tc.expected) {
+				t.Errorf("expected entries %v, got %v", tc.expected, entries)
+			}
+			if !reflect.DeepEqual(lines, tc.lines) {
+				t.Errorf("expected lines %v, got %v", tc.lines, lines)
+			}
+			if !reflect.DeepEqual(invalid, tc.invalid) {
+				t.Errorf("expected invalid lines %v, got %v", tc.invalid, invalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LockTimeout is the time Save waits for the lock held by someone else
+var LockTimeout = 10 * time.Second
+
+// staleLockAge is the age of a lock after which it is considered to be left
+// behind by a crashed process and is removed. Saving the store takes
+// milliseconds, so a lock this old is never held by a running process.
+const staleLockAge = 2 * time.Minute
+
+// lockRetryInterval is the time between attempts to take the lock
+const lockRetryInterval = 50 * time.Millisecond
+
+// ErrLocked is returned when the store is locked by someone else
+var ErrLocked = errors.New("IPAM store is locked")
+
+// LockPath is a function that returns the path of the lock file of the
+// IPAM store (ipam.yaml is locked with ipam.yaml.lock)
+func LockPath(path string) string {
+	return path + ".lock"
+}
+
+// Lock is a function that locks the IPAM store for writing and returns a
+// function that releases the lock. The lock is a file that is created
+// exclusively next to the store, which works for local files as well as
+// for files shared on network drives, and contains the user, host and
+// process holding the lock. If the store is locked, Lock retries until the
+// timeout expires. Locks older than two minutes are considered stale and
+// are removed.
+func Lock(path string, timeout time.Duration) (func() error, error) {
+	lockPath := LockPath(path)
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s@%s pid %d", auditUser(), host, os.Getpid())
+
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintln(file, owner)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			info, err := os.Stat(lockPath)
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			return func() error { return removeLock(lockPath, info) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		// Remove stale locks left behind by crashed processes
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			if err := removeLock(lockPath, info); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			data, _ := os.ReadFile(lockPath)
+			return nil, fmt.Errorf("%w by %s (remove %s if the lock is stale)", ErrLocked, strings.TrimSpace(string(data)), lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// removeLock is a function that removes the lock file, if it still is the
+// file described by info. Another process may have removed the lock and
+// taken a new one since (e.g. when both found the same stale lock), so the
+// lock file is first moved aside atomically and put back if it turns out to
+// be the lock of someone else.
+func removeLock(lockPath string, info os.FileInfo) error {
+	aside := fmt.Sprintf("%s.%d.%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockPath, aside); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	// Put the lock of the other process back, unless yet another process
+	// has taken the lock in the meantime (renaming is the fallback for file
+	// systems without hard links)
+	if current, err := os.Stat(aside); err == nil && !sameLock(info, current) {
+		if err := os.Link(aside, lockPath); err != nil && !errors.Is(err, os.ErrExist) {
+			return os.Rename(aside, lockPath)
+		}
+	}
+	return os.Remove(aside)
+}
+
+// sameLock is a function that reports whether two file infos describe the
+// same lock file. The inode of a removed lock is often reused right away for
+// the next lock, so the modification time is compared as well.
+func sameLock(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime())
+}
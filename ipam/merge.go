@@ -0,0 +1,113 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"errors"
+)
+
+// ErrMergeConflict is returned when the same prefix was changed differently
+// in two versions of the IPAM store
+var ErrMergeConflict = errors.New("conflicting changes in the IPAM store")
+
+// Merge is a function that merges two versions of the entries (ours and
+// theirs) that were both changed from a common base version. The entries
+// are merged per prefix: a prefix changed (added, updated or removed) in
+// only one of the versions gets that change, and a prefix changed the same
+// way in both versions is kept. A prefix changed differently in both
+// versions is a conflict, the prefixes in conflict are returned and our
+// version of them is kept in the merged entries.
+func Merge(base, ours, theirs []Entry) ([]Entry, []string) {
+	index := func(entries []Entry) map[string]Entry {
+		m := make(map[string]Entry, len(entries))
+		for _, e := range entries {
+			m[e.Prefix] = e
+		}
+		return m
+	}
+	b, o, t := index(base), index(ours), index(theirs)
+
+	// Collect the prefixes of all versions, in order of appearance
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, list := range [][]Entry{ours, theirs, base} {
+		for _, e := range list {
+			if !seen[e.Prefix] {
+				seen[e.Prefix] = true
+				prefixes = append(prefixes, e.Prefix)
+			}
+		}
+	}
+
+	var merged []Entry
+	var conflicts []string
+	for _, prefix := range prefixes {
+		be, inBase := b[prefix]
+		oe, inOurs := o[prefix]
+		te, inTheirs := t[prefix]
+		same := func(e1 Entry, ok1 bool, e2 Entry, ok2 bool) bool {
+			return ok1 == ok2 && (!ok1 || e1 == e2)
+		}
+
+		// Take the version that changed, ours if both changed the same way
+		switch {
+		case same(oe, inOurs, be, inBase):
+			if inTheirs {
+				merged = append(merged, te)
+			}
+		case same(te, inTheirs, be, inBase), same(oe, inOurs, te, inTheirs):
+			if inOurs {
+				merged = append(merged, oe)
+			}
+		default:
+			conflicts = append(conflicts, prefix)
+			if inOurs {
+				merged = append(merged, oe)
+			}
+		}
+	}
+
+	s := &Store{Entries: merged}
+	s.Sort()
+	return s.Entries, conflicts
+}
+
+// MergeFiles is a function that merges the IPAM store files ours and theirs
+// that were both changed from the file base, and writes the result to ours.
+// It is meant to be used as a git merge driver. The prefixes in conflict
+// are returned, our version of them is written.
+func MergeFiles(base, ours, theirs string) ([]string, error) {
+	stores := make([]*Store, 3)
+	for i, path := range []string{base, ours, theirs} {
+		s, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		stores[i] = s
+	}
+
+	merged, conflicts := Merge(stores[0].Entries, stores[1].Entries, stores[2].Entries)
+	if err := writeEntries(ours, merged); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
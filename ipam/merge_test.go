@@ -0,0 +1,152 @@
+package ipam_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+func TestMerge(t *testing.T) {
+	base := []ipam.Entry{
+		{Prefix: "10.0.0.0/24", Name: "servers"},
+		{Prefix: "10.0.1.0/24", Name: "clients"},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		ours      []ipam.Entry
+		theirs    []ipam.Entry
+		expected  []ipam.Entry
+		conflicts []string
+	}{
+		{
+			name:     "Both added",
+			ours:     append(base[:2:2], ipam.Entry{Prefix: "10.0.2.0/24", Name: "lab"}),
+			theirs:   append(base[:2:2], ipam.Entry{Prefix: "10.0.3.0/24", Name: "dmz"}),
+			expected: append(base[:2:2], ipam.Entry{Prefix: "10.0.2.0/24", Name: "lab"}, ipam.Entry{Prefix: "10.0.3.0/24", Name: "dmz"}),
+		},
+		{
+			name:     "Updated and removed",
+			ours:     []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+			theirs:   []ipam.Entry{base[0]},
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}},
+		},
+		{
+			name:     "Same change",
+			ours:     []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+			theirs:   []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+			expected: []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+		},
+		{
+			name:      "Conflicting updates",
+			ours:      []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+			theirs:    []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "db"}, base[1]},
+			expected:  []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "web"}, base[1]},
+			conflicts: []string{"10.0.0.0/24"},
+		},
+		{
+			name:      "Updated and removed by them",
+			ours:      []ipam.Entry{base[0], {Prefix: "10.0.1.0/24", Name: "wifi"}},
+			theirs:    []ipam.Entry{base[0]},
+			expected:  []ipam.Entry{base[0], {Prefix: "10.0.1.0/24", Name: "wifi"}},
+			conflicts: []string{"10.0.1.0/24"},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, conflicts := ipam.Merge(base, tc.ours, tc.theirs)
+			if !reflect.DeepEqual(merged, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, merged)
+			}
+			if !reflect.DeepEqual(conflicts, tc.conflicts) {
+				t.Errorf("expected conflicts %v, got %v", tc.conflicts, conflicts)
+			}
+		})
+	}
+}
+
+func TestSaveMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.yaml")
+	store, _ := ipam.Load(path)
+	store.Add(ipam.Entry{Prefix: "10.0.0.0/24", Name: "servers"}, false)
+	if err := store.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two users load the store and change it at the same time
+	first, _ := ipam.Load(path)
+	second, _ := ipam.Load(path)
+	first.Add(ipam.Entry{Prefix: "10.0.1.0/24", Name: "clients"}, false)
+	second.Add(ipam.Entry{Prefix: "10.0.2.0/24", Name: "lab"}, false)
+	if err := first.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	store, _ = ipam.Load(path)
+	var prefixes []string
+	for _, e := range store.Entries {
+		prefixes = append(prefixes, e.Prefix)
+	}
+	expected := []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"}
+	if !reflect.DeepEqual(prefixes, expected) {
+		t.Errorf("expected %v, got %v", expected, prefixes)
+	}
+
+	// Conflicting changes of the same prefix are rejected
+	first, _ = ipam.Load(path)
+	second, _ = ipam.Load(path)
+	first.Add(ipam.Entry{Prefix: "10.0.2.0/24", Name: "lab-east"}, true)
+	second.Add(ipam.Entry{Prefix: "10.0.2.0/24", Name: "lab-west"}, true)
+	if err := first.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(path); !errors.Is(err, ipam.ErrMergeConflict) {
+		t.Errorf("expected error %v, got %v", ipam.ErrMergeConflict, err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.yaml")
+	unlock, err := ipam.Lock(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The store can't be locked twice
+	if _, err := ipam.Lock(path, 100*time.Millisecond); !errors.Is(err, ipam.ErrLocked) {
+		t.Errorf("expected error %v, got %v", ipam.ErrLocked, err)
+	}
+
+	// Stale locks are removed
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(ipam.LockPath(path), old, old)
+	unlock2, err := ipam.Lock(path, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected stale lock to be removed, got %v", err)
+	}
+
+	// The holder of the stale lock does not remove the new lock
+	if err := unlock(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(ipam.LockPath(path)); err != nil {
+		t.Errorf("expected the new lock to be kept, got %v", err)
+	}
+	if err := unlock2(); err != nil {
+		t.Error(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 0 {
+		t.Errorf("expected no files left behind, got %d", len(entries))
+	}
+}
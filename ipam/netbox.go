@@ -0,0 +1,377 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// netboxPageSize is the number of objects read from NetBox per request
+const netboxPageSize = 1000
+
+// NetBox endpoints of the prefixes and IP addresses
+const (
+	netboxPrefixes  = "/api/ipam/prefixes/"
+	netboxAddresses = "/api/ipam/ip-addresses/"
+	netboxVLANs     = "/api/ipam/vlans/"
+)
+
+// NetBox is a backend that synchronizes the entries with the prefixes and
+// IP addresses of a NetBox instance, in the global table (without a VRF).
+// Single addresses (/32 and /128 entries) are IP addresses in NetBox, all
+// other entries are prefixes. The name of an entry is the description of
+// the NetBox object, the description of the entry its comments, and the
+// VLAN is the VLAN of the prefix (looked up by VLAN ID). Expiry dates are
+// not stored in NetBox.
+type NetBox struct {
+	URL    string
+	Token  string
+	Client *http.Client
+
+	// objects holds the NetBox objects read by Entries, by prefix
+	objects map[string]netboxObject
+
+	// vlans holds the NetBox ids of the VLANs, by VLAN ID
+	vlans map[int]int
+}
+
+// netboxObject represents a prefix or an IP address in NetBox
+type netboxObject struct {
+	ID          int           `json:"id"`
+	Prefix      string        `json:"prefix,omitempty"`
+	Address     string        `json:"address,omitempty"`
+	Description string        `json:"description"`
+	Comments    string        `json:"comments"`
+	Status      *netboxChoice `json:"status"`
+	VLAN        *struct {
+		ID  int `json:"id"`
+		VID int `json:"vid"`
+	} `json:"vlan"`
+}
+
+// netboxChoice represents the value of a choice field in NetBox (e.g. the
+// status of a prefix)
+type netboxChoice struct {
+	Value string `json:"value"`
+}
+
+// netboxPage represents a page of the objects of a NetBox list endpoint
+type netboxPage struct {
+	Count   int               `json:"count"`
+	Results []json.RawMessage `json:"results"`
+}
+
+// NewNetBox is a function that returns a NetBox backend for the instance at
+// the URL (e.g. https://netbox.example.com), authenticated with an API token
+func NewNetBox(rawURL, token string) (*NetBox, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %s (must be an http:// or https:// URL)", rawURL)
+	}
+	if token == "" {
+		return nil, errors.New("no NetBox API token specified")
+	}
+
+	// Accept the URL of the API as well as the URL of the instance
+	base := strings.TrimSuffix(strings.TrimSuffix(rawURL, "/"), "/api")
+	return &NetBox{URL: base, Token: token, Client: &http.Client{Timeout: SyncTimeout}}, nil
+}
+
+// Entries is a function that reads the prefixes and IP addresses in the
+// global table of NetBox and returns them as entries, sorted by prefix. An
+// IP address is returned as a single address (10.0.0.5/24 becomes
+// 10.0.0.5/32), and is skipped if there is a prefix for it.
+func (n *NetBox) Entries(ctx context.Context) ([]Entry, error) {
+	n.objects = make(map[string]netboxObject)
+	s := &Store{}
+	for _, endpoint := range []string{netboxPrefixes, netboxAddresses} {
+		objects, err := n.list(ctx, endpoint, url.Values{"vrf_id": {"null"}})
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range objects {
+			var o netboxObject
+			if err := json.Unmarshal(raw, &o); err != nil {
+				return nil, fmt.Errorf("invalid NetBox object: %w", err)
+			}
+			e, err := o.entry()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := n.objects[e.Prefix]; dup {
+				continue
+			}
+			n.objects[e.Prefix] = o
+			s.Entries = append(s.Entries, e)
+		}
+	}
+	s.Sort()
+	return s.Entries, nil
+}
+
+// entry is a function that returns the NetBox object as an entry
+func (o netboxObject) entry() (Entry, error) {
+	var prefix netip.Prefix
+	var err error
+	if o.Address != "" {
+		prefix, err = netip.ParsePrefix(o.Address)
+		prefix = netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen())
+	} else {
+		prefix, err = netip.ParsePrefix(o.Prefix)
+		prefix = prefix.Masked()
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid NetBox object %d: %w", o.ID, err)
+	}
+
+	e := Entry{Prefix: prefix.String(), Name: o.Description, Description: o.Comments}
+	if o.Status != nil {
+		e.State = netboxState(o.Status.Value)
+	}
+	if o.VLAN != nil {
+		e.VLAN = o.VLAN.VID
+	}
+	return e, nil
+}
+
+// Normalize is a function that returns the entry with the fields stored in
+// NetBox only, the expiry date is dropped and so is the VLAN of a single
+// address (IP addresses have no VLAN in NetBox)
+func (n *NetBox) Normalize(e Entry) Entry {
+	e.Expires = ""
+	if n.isAddress(e) {
+		e.VLAN = 0
+	}
+	return e
+}
+
+// isAddress is a function that checks if the entry is an IP address in
+// NetBox, single addresses are IP addresses unless NetBox has a prefix for
+// them
+func (n *NetBox) isAddress(e Entry) bool {
+	if current, exists := n.objects[e.Prefix]; exists {
+		return current.Address != ""
+	}
+	return e.IsHost()
+}
+
+// Put is a function that creates the prefix or IP address of the entry in
+// NetBox, or updates it if it was read by Entries
+func (n *NetBox) Put(ctx context.Context, e Entry) error {
+	current, exists := n.objects[e.Prefix]
+	endpoint := netboxPrefixes
+	if n.isAddress(e) {
+		endpoint = netboxAddresses
+	}
+
+	body := map[string]any{"description": e.Name, "comments": e.Description}
+	if status := netboxStatus(e.State, current.Status); status != "" {
+		body["status"] = status
+	}
+	if endpoint == netboxPrefixes {
+		body["vlan"] = nil
+		if e.VLAN > 0 {
+			id, err := n.vlan(ctx, e.VLAN)
+			if err != nil {
+				return err
+			}
+			body["vlan"] = id
+		}
+	}
+
+	// Update the object if it exists, otherwise create it
+	var o netboxObject
+	if exists {
+		if err := n.do(ctx, http.MethodPatch, endpoint+strconv.Itoa(current.ID)+"/", nil, body, &o); err != nil {
+			return err
+		}
+	} else {
+		if endpoint == netboxPrefixes {
+			body["prefix"] = e.Prefix
+		} else {
+			body["address"] = e.Prefix
+		}
+		if err := n.do(ctx, http.MethodPost, endpoint, nil, body, &o); err != nil {
+			return err
+		}
+	}
+	if n.objects == nil {
+		n.objects = make(map[string]netboxObject)
+	}
+	n.objects[e.Prefix] = o
+	return nil
+}
+
+// vlan is a function that returns the NetBox id of the VLAN with the VLAN
+// ID, which must be unique in NetBox
+func (n *NetBox) vlan(ctx context.Context, vid int) (int, error) {
+	if id, ok := n.vlans[vid]; ok {
+		return id, nil
+	}
+	objects, err := n.list(ctx, netboxVLANs, url.Values{"vid": {strconv.Itoa(vid)}})
+	if err != nil {
+		return 0, err
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("VLAN %d not found in NetBox", vid)
+	case 1:
+	default:
+		return 0, fmt.Errorf("VLAN %d is not unique in NetBox (%d VLANs)", vid, len(objects))
+	}
+
+	var v struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(objects[0], &v); err != nil {
+		return 0, fmt.Errorf("invalid NetBox VLAN: %w", err)
+	}
+	if n.vlans == nil {
+		n.vlans = make(map[int]int)
+	}
+	n.vlans[vid] = v.ID
+	return v.ID, nil
+}
+
+// list is a function that reads all objects of a NetBox list endpoint,
+// page by page
+func (n *NetBox) list(ctx context.Context, endpoint string, query url.Values) ([]json.RawMessage, error) {
+	var objects []json.RawMessage
+	for offset := 0; ; offset += netboxPageSize {
+		q := url.Values{"limit": {strconv.Itoa(netboxPageSize)}, "offset": {strconv.Itoa(offset)}}
+		for k, v := range query {
+			q[k] = v
+		}
+		var page netboxPage
+		if err := n.do(ctx, http.MethodGet, endpoint, q, nil, &page); err != nil {
+			return nil, err
+		}
+		objects = append(objects, page.Results...)
+		if len(page.Results) == 0 || len(objects) >= page.Count {
+			return objects, nil
+		}
+	}
+}
+
+// do is a function that sends a request to the NetBox API and decodes the
+// JSON response into result. Error responses are returned as errors with
+// the message of NetBox.
+func (n *NetBox) do(ctx context.Context, method, endpoint string, query url.Values, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := n.URL + endpoint
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+
+	// NetBox is resolved by the HTTP client, unless name resolution is disabled
+	if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil && ip.LookupsDisabled() {
+		return fmt.Errorf("netbox: cannot resolve %s: %w", req.URL.Hostname(), ip.ErrLookupsDisabled)
+	}
+	req.Header.Set("Authorization", "Token "+n.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("netbox: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("netbox: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("netbox: %s %s: %s%s", method, endpoint, resp.Status, netboxMessage(data))
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("netbox: %s %s: invalid response: %w", method, endpoint, err)
+	}
+	return nil
+}
+
+// netboxMessage is a function that returns the error message in the body
+// of a NetBox error response, prefixed with a colon, the detail of the
+// error or the validation errors of the fields
+func netboxMessage(data []byte) string {
+	var detail struct {
+		Detail string `json:"detail"`
+	}
+	if err := json.Unmarshal(data, &detail); err == nil && detail.Detail != "" {
+		return ": " + detail.Detail
+	}
+	if msg := strings.Join(strings.Fields(string(data)), " "); msg != "" && len(msg) <= 200 && json.Valid(data) {
+		return ": " + msg
+	}
+	return ""
+}
+
+// netboxState is a function that returns the reservation state of a NetBox
+// status, statuses other than reserved and deprecated (active, container,
+// dhcp, ...) are allocated
+func netboxState(status string) string {
+	switch status {
+	case StateReserved, StateDeprecated:
+		return status
+	}
+	return ""
+}
+
+// netboxStatus is a function that returns the NetBox status of a
+// reservation state. An allocated entry is active, unless its current
+// status is another allocated status (e.g. container), which is kept by
+// returning an empty status.
+func netboxStatus(state string, current *netboxChoice) string {
+	if state != "" {
+		return state
+	}
+	if current != nil && current.Value != "" && netboxState(current.Value) == "" {
+		return ""
+	}
+	return "active"
+}
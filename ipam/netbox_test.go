@@ -0,0 +1,250 @@
+package ipam_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ipam"
+)
+
+// fakeNetBox is a minimal NetBox API serving prefixes, IP addresses and VLANs
+type fakeNetBox struct {
+	mu       sync.Mutex
+	objects  map[string][]map[string]any
+	requests []string
+}
+
+// newFakeNetBox is a function that starts a fake NetBox with some objects
+func newFakeNetBox(t *testing.T) (*fakeNetBox, *httptest.Server) {
+	f := &fakeNetBox{objects: map[string][]map[string]any{
+		"prefixes": {
+			{"id": 1, "prefix": "10.0.0.0/24", "description": "web", "comments": "", "status": map[string]any{"value": "active"}, "vlan": map[string]any{"id": 7, "vid": 10}},
+			{"id": 2, "prefix": "10.0.1.0/24", "description": "app", "comments": "", "status": map[string]any{"value": "reserved"}, "vlan": nil},
+		},
+		"ip-addresses": {
+			{"id": 1, "address": "10.0.0.5/24", "description": "gw", "comments": "", "status": map[string]any{"value": "active"}},
+		},
+		"vlans": {
+			{"id": 7, "vid": 10},
+			{"id": 8, "vid": 20},
+		},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// serve is a function that handles the requests to the fake NetBox
+func (f *fakeNetBox) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Token secret" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"detail": "Invalid token"}`))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/ipam/"), "/"), "/")
+	kind := parts[0]
+	switch {
+	case r.Method == http.MethodGet:
+		var results []map[string]any
+		for _, o := range f.objects[kind] {
+			if vid := r.URL.Query().Get("vid"); vid != "" && strconv.Itoa(o["vid"].(int)) != vid {
+				continue
+			}
+			results = append(results, o)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"count": len(results), "results": results})
+	case r.Method == http.MethodPost:
+		var o map[string]any
+		json.NewDecoder(r.Body).Decode(&o)
+		f.nest(o)
+		o["id"] = len(f.objects[kind]) + 100
+		f.objects[kind] = append(f.objects[kind], o)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(o)
+	case r.Method == http.MethodPatch:
+		var patch map[string]any
+		json.NewDecoder(r.Body).Decode(&patch)
+		f.nest(patch)
+		for _, o := range f.objects[kind] {
+			if strconv.Itoa(o["id"].(int)) == parts[1] {
+				for k, v := range patch {
+					o[k] = v
+				}
+				json.NewEncoder(w).Encode(o)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// nest is a function that replaces the status and the VLAN written to the
+// fake NetBox with the nested objects NetBox returns
+func (f *fakeNetBox) nest(o map[string]any) {
+	if status, ok := o["status"].(string); ok {
+		o["status"] = map[string]any{"value": status}
+	}
+	if id, ok := o["vlan"].(float64); ok {
+		for _, v := range f.objects["vlans"] {
+			if v["id"] == int(id) {
+				o["vlan"] = map[string]any{"id": v["id"], "vid": v["vid"]}
+			}
+		}
+	}
+}
+
+func TestNetBoxEntries(t *testing.T) {
+	_, server := newFakeNetBox(t)
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		token     string
+		expected  []ipam.Entry
+		expectErr string
+	}{
+		{name: "Entries", token: "secret", expected: []ipam.Entry{
+			{Prefix: "10.0.0.0/24", Name: "web", VLAN: 10},
+			{Prefix: "10.0.0.5/32", Name: "gw"},
+			{Prefix: "10.0.1.0/24", Name: "app", State: ipam.StateReserved},
+		}},
+		{name: "InvalidToken", token: "wrong", expectErr: "403 Forbidden: Invalid token"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend, err := ipam.NewNetBox(server.URL, tc.token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			entries, err := backend.Entries(context.Background())
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(entries, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, entries)
+			}
+		})
+	}
+}
+
+func TestNetBoxLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// The name of the NetBox instance is not resolved with --no-dns
+	backend, err := ipam.NewNetBox("https://netbox.invalid", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := backend.Entries(context.Background()); !errors.Is(err, ip.ErrLookupsDisabled) {
+		t.Errorf("expected %v, got %v", ip.ErrLookupsDisabled, err)
+	}
+}
+
+func TestNetBoxPut(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		entry     ipam.Entry
+		request   string
+		expected  map[string]any
+		expectErr string
+	}{
+		{
+			name:     "CreatePrefix",
+			entry:    ipam.Entry{Prefix: "10.0.2.0/24", Name: "db", VLAN: 20, Description: "database servers"},
+			request:  "POST /api/ipam/prefixes/",
+			expected: map[string]any{"prefix": "10.0.2.0/24", "description": "db", "comments": "database servers", "status": map[string]any{"value": "active"}, "vlan": map[string]any{"id": 8, "vid": 20}},
+		},
+		{
+			name:     "CreateAddress",
+			entry:    ipam.Entry{Prefix: "10.255.255.1/32", Name: "core1", State: ipam.StateReserved},
+			request:  "POST /api/ipam/ip-addresses/",
+			expected: map[string]any{"address": "10.255.255.1/32", "description": "core1", "comments": "", "status": map[string]any{"value": "reserved"}},
+		},
+		{
+			name:     "UpdatePrefix",
+			entry:    ipam.Entry{Prefix: "10.0.1.0/24", Name: "application"},
+			request:  "PATCH /api/ipam/prefixes/2/",
+			expected: map[string]any{"id": 2, "prefix": "10.0.1.0/24", "description": "application", "comments": "", "status": map[string]any{"value": "active"}, "vlan": nil},
+		},
+		{
+			name:     "UpdateAddress",
+			entry:    ipam.Entry{Prefix: "10.0.0.5/32", Name: "gateway"},
+			request:  "PATCH /api/ipam/ip-addresses/1/",
+			expected: map[string]any{"id": 1, "address": "10.0.0.5/24", "description": "gateway", "comments": "", "status": map[string]any{"value": "active"}},
+		},
+		{
+			name:      "UnknownVLAN",
+			entry:     ipam.Entry{Prefix: "10.0.3.0/24", VLAN: 30},
+			expectErr: "VLAN 30 not found in NetBox",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, server := newFakeNetBox(t)
+			backend, err := ipam.NewNetBox(server.URL, "secret")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := backend.Entries(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = backend.Put(context.Background(), tc.entry)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The last request creates or updates the object
+			if last := fake.requests[len(fake.requests)-1]; last != tc.request {
+				t.Errorf("expected request %q, got %q", tc.request, last)
+			}
+			kind := strings.Split(strings.TrimPrefix(tc.request[strings.Index(tc.request, "/"):], "/api/ipam/"), "/")[0]
+			objects := fake.objects[kind]
+			got := objects[len(objects)-1]
+			if strings.HasPrefix(tc.request, "PATCH") {
+				for _, o := range objects {
+					if o["id"] == tc.expected["id"] {
+						got = o
+					}
+				}
+			} else {
+				delete(got, "id")
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
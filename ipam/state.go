@@ -0,0 +1,116 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/utils"
+)
+
+// Reservation states of the entries in the IPAM store. A prefix is reserved
+// before it is taken into use, allocated while it is in use and deprecated
+// when it is being phased out and should be reclaimed.
+const (
+	StateReserved   = "reserved"
+	StateAllocated  = "allocated"
+	StateDeprecated = "deprecated"
+)
+
+// States is the list of all reservation states
+var States = []string{StateReserved, StateAllocated, StateDeprecated}
+
+// DateLayout is the layout of the expiry dates in the IPAM store
+const DateLayout = "2006-01-02"
+
+// ParseState is a function that parses a reservation state (case
+// insensitive) and returns it as it is stored in the entries. Allocated is
+// the default state and is stored as an empty state.
+func ParseState(s string) (string, error) {
+	state := strings.ToLower(strings.TrimSpace(s))
+	if state == "" || state == StateAllocated {
+		return "", nil
+	}
+	for _, valid := range States {
+		if state == valid {
+			return state, nil
+		}
+	}
+	return "", fmt.Errorf("invalid state: %q (must be one of %s)", s, strings.Join(States, ", "))
+}
+
+// ParseExpiry is a function that parses an expiry date, either a date
+// (2024-12-31) or a duration from now (30d, 2w or 12h), and returns the
+// date in the format of the IPAM store. An empty value means no expiry.
+func ParseExpiry(s string, now time.Time) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if date, err := time.ParseInLocation(DateLayout, s, now.Location()); err == nil {
+		return date.Format(DateLayout), nil
+	}
+	d, err := utils.ParseDuration(s)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("invalid expiry: %q (must be a date like 2024-12-31 or a duration like 30d)", s)
+	}
+	return now.Add(d).Format(DateLayout), nil
+}
+
+// Status is a function that returns the reservation state of the entry,
+// entries without a state are allocated
+func (e Entry) Status() string {
+	if e.State == "" {
+		return StateAllocated
+	}
+	return e.State
+}
+
+// Expiry is a function that returns the time the entry expires (the start
+// of the expiry date in the location of now) and false if the entry does
+// not expire or the date is invalid
+func (e Entry) Expiry(loc *time.Location) (time.Time, bool) {
+	if e.Expires == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(DateLayout, e.Expires, loc)
+	return t, err == nil
+}
+
+// Expiring is a function that returns the entries that expire within the
+// duration from now, including the entries that have already expired,
+// sorted by expiry date
+func (s *Store) Expiring(now time.Time, within time.Duration) []Entry {
+	var expiring []Entry
+	for _, e := range s.Entries {
+		if t, ok := e.Expiry(now.Location()); ok && !t.After(now.Add(within)) {
+			expiring = append(expiring, e)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].Expires < expiring[j].Expires
+	})
+	return expiring
+}
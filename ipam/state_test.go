@@ -0,0 +1,117 @@
+package ipam_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+func TestParseState(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "", expected: ""},
+		{input: "allocated", expected: ""},
+		{input: "Reserved", expected: ipam.StateReserved},
+		{input: " deprecated ", expected: ipam.StateDeprecated},
+		{input: "free", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			state, err := ipam.ParseState(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", state)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if state != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, state)
+			}
+		})
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{input: "", expected: ""},
+		{input: "2024-12-31", expected: "2024-12-31"},
+		{input: "30d", expected: "2024-04-04"},
+		{input: "2w", expected: "2024-03-19"},
+		{input: "12h", expected: "2024-03-06"},
+		{input: "2024-13-01", expectErr: true},
+		{input: "-1d", expectErr: true},
+		{input: "soon", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expires, err := ipam.ParseExpiry(tc.input, now)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", expires)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expires != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, expires)
+			}
+		})
+	}
+}
+
+func TestStoreExpiring(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	store := &ipam.Store{Entries: []ipam.Entry{
+		{Prefix: "10.0.0.0/24"},
+		{Prefix: "10.0.1.0/24", State: ipam.StateReserved, Expires: "2024-04-30"},
+		{Prefix: "10.0.2.0/24", State: ipam.StateReserved, Expires: "2024-03-20"},
+		{Prefix: "10.0.3.0/24", State: ipam.StateDeprecated, Expires: "2024-01-01"},
+		{Prefix: "10.0.4.0/24", Expires: "invalid"},
+	}}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		within   time.Duration
+		expected []string
+	}{
+		{name: "Expired", within: 0, expected: []string{"10.0.3.0/24"}},
+		{name: "TwoWeeks", within: 15 * 24 * time.Hour, expected: []string{"10.0.3.0/24", "10.0.2.0/24"}},
+		{name: "Year", within: 365 * 24 * time.Hour, expected: []string{"10.0.3.0/24", "10.0.2.0/24", "10.0.1.0/24"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, e := range store.Expiring(now, tc.within) {
+				got = append(got, e.Prefix)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
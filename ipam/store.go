@@ -0,0 +1,244 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConflict is returned when an entry is added for a prefix that is
+// already in the store with different data
+var ErrConflict = errors.New("prefix already exists with different data")
+
+// Store represents the IPAM store, a list of prefixes with their metadata.
+// Prefixes may be nested, a /24 inside a /16 is part of the hierarchy of
+// the /16, but every prefix can only be in the store once.
+type Store struct {
+	Entries []Entry `yaml:"entries"`
+
+	// loaded holds the entries as they were loaded, for the audit log
+	loaded []Entry
+}
+
+// Entry represents a prefix in the IPAM store. The state is empty for
+// allocated prefixes (see Status) and the expiry date is optional.
+type Entry struct {
+	Prefix      string `yaml:"prefix" json:"prefix"`
+	Name        string `yaml:"name,omitempty" json:"name,omitempty"`
+	VLAN        int    `yaml:"vlan,omitempty" json:"vlan,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	State       string `yaml:"state,omitempty" json:"state,omitempty"`
+	Expires     string `yaml:"expires,omitempty" json:"expires,omitempty"`
+}
+
+// DefaultPath is a function that returns the path of the IPAM store in the
+// configuration directory of the user (e.g. ~/.config/iptool/ipam.yaml on Linux)
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iptool", "ipam.yaml"), nil
+}
+
+// Load is a function that reads the IPAM store from a YAML file. A missing
+// file is treated as an empty store.
+func Load(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Store{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Store
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid IPAM store %s: %w", path, err)
+	}
+	s.loaded = slices.Clone(s.Entries)
+	return &s, nil
+}
+
+// Save is a function that writes the IPAM store to a YAML file. The entries
+// are sorted by prefix, which keeps the file readable and the diffs small
+// when the store is kept in version control.
+//
+// The store is locked while it is saved (see Lock). If the file was changed
+// by someone else since the store was loaded, the changes are merged with a
+// three-way merge (see Merge), and ErrMergeConflict is returned if the same
+// prefix was changed differently. The changes since the store was loaded
+// are appended to the audit log (see LogPath).
+func (s *Store) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := Lock(path, LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Merge the changes made by others since the store was loaded
+	changes := Diff(s.loaded, s.Entries)
+	current, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if len(Diff(s.loaded, current.Entries)) > 0 {
+		merged, conflicts := Merge(s.loaded, s.Entries, current.Entries)
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%w: %s (load the store again and retry)", ErrMergeConflict, strings.Join(conflicts, ", "))
+		}
+		s.Entries = merged
+	}
+
+	s.Sort()
+	if err := writeEntries(path, s.Entries); err != nil {
+		return err
+	}
+
+	// Record the changes in the audit log
+	if err := AppendLog(LogPath(path), changes); err != nil {
+		return fmt.Errorf("IPAM store saved, but the audit log could not be written: %w", err)
+	}
+	s.loaded = slices.Clone(s.Entries)
+	return nil
+}
+
+// writeEntries is a function that writes the entries to a YAML file. The
+// entries are written to a temporary file which is renamed, so that the
+// store is never left half written.
+func writeEntries(path string, entries []Entry) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&Store{Entries: entries}); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ipam-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Sort is a function that sorts the entries by prefix (IPv4 before IPv6,
+// then by address and prefix length)
+func (s *Store) Sort() {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		a, errA := netip.ParsePrefix(s.Entries[i].Prefix)
+		b, errB := netip.ParsePrefix(s.Entries[j].Prefix)
+		if errA != nil || errB != nil {
+			return s.Entries[i].Prefix < s.Entries[j].Prefix
+		}
+		return ip.ComparePrefixes(a, b) < 0
+	})
+}
+
+// Find is a function that returns the index of the entry with the prefix,
+// or -1 if the prefix is not in the store
+func (s *Store) Find(prefix string) int {
+	for i, e := range s.Entries {
+		if e.Prefix == prefix {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add is a function that adds an entry to the store. Adding an entry that
+// is already in the store with the same data is a no-op and returns false.
+// If the prefix is in the store with different data, ErrConflict is
+// returned unless update is set, in which case the entry is replaced.
+func (s *Store) Add(e Entry, update bool) (bool, error) {
+	i := s.Find(e.Prefix)
+	switch {
+	case i < 0:
+		s.Entries = append(s.Entries, e)
+		return true, nil
+	case s.Entries[i] == e:
+		return false, nil
+	case update:
+		s.Entries[i] = e
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrConflict, s.Entries[i])
+	}
+}
+
+// Remove is a function that removes the entry with the prefix from the
+// store and returns false if the prefix is not in the store
+func (s *Store) Remove(prefix string) bool {
+	i := s.Find(prefix)
+	if i < 0 {
+		return false
+	}
+	s.Entries = append(s.Entries[:i], s.Entries[i+1:]...)
+	return true
+}
+
+// Overlaps is a function that returns the entries in the store that
+// overlap the prefix, i.e. the entries that contain the prefix or are
+// contained in it. An entry with the same prefix is not included.
+func (s *Store) Overlaps(prefix netip.Prefix) []Entry {
+	var overlaps []Entry
+	for _, e := range s.Entries {
+		p, err := netip.ParsePrefix(e.Prefix)
+		if err != nil || p == prefix {
+			continue
+		}
+		if p.Overlaps(prefix) {
+			overlaps = append(overlaps, e)
+		}
+	}
+	return overlaps
+}
+
+// String is a function that returns the prefix of the entry with its name (if any)
+func (e Entry) String() string {
+	if e.Name == "" {
+		return e.Prefix
+	}
+	return fmt.Sprintf("%s (%s)", e.Prefix, e.Name)
+}
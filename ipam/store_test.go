@@ -0,0 +1,111 @@
+package ipam_test
+
+import (
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+func TestStoreAdd(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		entry     ipam.Entry
+		update    bool
+		changed   bool
+		expectErr error
+	}{
+		{name: "New", entry: ipam.Entry{Prefix: "10.0.1.0/24", Name: "storage"}, changed: true},
+		{name: "Nested", entry: ipam.Entry{Prefix: "10.0.0.0/16", Name: "site"}, changed: true},
+		{name: "Unchanged", entry: ipam.Entry{Prefix: "10.0.0.0/24", Name: "servers", VLAN: 10}},
+		{name: "Conflict", entry: ipam.Entry{Prefix: "10.0.0.0/24", Name: "other"}, expectErr: ipam.ErrConflict},
+		{name: "Update", entry: ipam.Entry{Prefix: "10.0.0.0/24", Name: "other"}, update: true, changed: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &ipam.Store{Entries: []ipam.Entry{{Prefix: "10.0.0.0/24", Name: "servers", VLAN: 10}}}
+			changed, err := store.Add(tc.entry, tc.update)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if changed != tc.changed {
+				t.Errorf("expected changed %v, got %v", tc.changed, changed)
+			}
+			if i := store.Find(tc.entry.Prefix); err == nil && store.Entries[i] != tc.entry {
+				t.Errorf("expected entry %v, got %v", tc.entry, store.Entries[i])
+			}
+		})
+	}
+}
+
+func TestStoreOverlaps(t *testing.T) {
+	store := &ipam.Store{Entries: []ipam.Entry{
+		{Prefix: "10.0.0.0/16"},
+		{Prefix: "10.0.1.0/24"},
+		{Prefix: "10.1.0.0/16"},
+	}}
+
+	// Setup test cases
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{prefix: "10.0.1.0/24", expected: []string{"10.0.0.0/16"}},
+		{prefix: "10.0.0.0/8", expected: []string{"10.0.0.0/16", "10.0.1.0/24", "10.1.0.0/16"}},
+		{prefix: "10.2.0.0/16", expected: nil},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			var got []string
+			for _, e := range store.Overlaps(netip.MustParsePrefix(tc.prefix)) {
+				got = append(got, e.Prefix)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iptool", "ipam.yaml")
+
+	// A missing store is empty
+	store, err := ipam.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.Entries) != 0 {
+		t.Fatalf("expected an empty store, got %v", store.Entries)
+	}
+
+	// The entries are saved in order
+	store.Entries = []ipam.Entry{
+		{Prefix: "2001:db8::/32", Name: "v6"},
+		{Prefix: "10.0.1.0/24", Name: "storage", VLAN: 20},
+		{Prefix: "10.0.0.0/16", Name: "site", Description: "main site"},
+	}
+	if err := store.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := ipam.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ipam.Entry{
+		{Prefix: "10.0.0.0/16", Name: "site", Description: "main site"},
+		{Prefix: "10.0.1.0/24", Name: "storage", VLAN: 20},
+		{Prefix: "2001:db8::/32", Name: "v6"},
+	}
+	if !reflect.DeepEqual(loaded.Entries, expected) {
+		t.Errorf("expected %v, got %v", expected, loaded.Entries)
+	}
+}
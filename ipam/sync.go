@@ -0,0 +1,136 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Backends the IPAM store can be synchronized with
+const (
+	BackendNetBox = "netbox"
+)
+
+// Backends is the list of all backends
+var Backends = []string{BackendNetBox}
+
+// SyncTimeout is the time a request to a backend may take
+const SyncTimeout = 30 * time.Second
+
+// Backend represents an external IPAM system that the entries of the store
+// are pushed to and pulled from
+type Backend interface {
+	// Entries returns the entries in the backend
+	Entries(ctx context.Context) ([]Entry, error)
+
+	// Normalize returns the entry with only the fields the backend stores
+	Normalize(e Entry) Entry
+
+	// Put creates the entry in the backend, or updates it if it exists
+	Put(ctx context.Context, e Entry) error
+}
+
+// NewBackend is a function that returns the backend with the name, using
+// the base URL of its API and the token to authenticate
+func NewBackend(name, url, token string) (Backend, error) {
+	switch strings.ToLower(name) {
+	case BackendNetBox:
+		return NewNetBox(url, token)
+	}
+	return nil, fmt.Errorf("invalid backend: %s (must be one of %s)", name, strings.Join(Backends, ", "))
+}
+
+// PushChanges is a function that returns the changes that bring the
+// entries of a backend (remote) in line with the local entries. Entries
+// that are only in the backend are left as they are, so no changes remove
+// entries.
+func PushChanges(local, remote []Entry, normalize func(Entry) Entry) []Change {
+	entries := make([]Entry, len(local))
+	for i, e := range local {
+		entries[i] = normalize(e)
+	}
+	return withoutRemovals(Diff(remote, entries))
+}
+
+// PullChanges is a function that returns the changes that bring the local
+// entries in line with the entries of a backend (remote). The fields the
+// backend does not store (e.g. the expiry date) are kept, and entries that
+// are only in the local store are left as they are.
+func PullChanges(local, remote []Entry, normalize func(Entry) Entry) []Change {
+	index := make(map[string]Entry, len(local))
+	for _, e := range local {
+		index[e.Prefix] = e
+	}
+
+	entries := make([]Entry, 0, len(remote))
+	for _, r := range remote {
+		if l, ok := index[r.Prefix]; ok {
+			n := normalize(l)
+			if n.VLAN == 0 {
+				r.VLAN = l.VLAN
+			}
+			if n.Expires == "" {
+				r.Expires = l.Expires
+			}
+		}
+		entries = append(entries, r)
+	}
+	return withoutRemovals(Diff(local, entries))
+}
+
+// withoutRemovals is a function that drops the removals from the changes
+func withoutRemovals(changes []Change) []Change {
+	kept := changes[:0]
+	for _, c := range changes {
+		if c.Action != ActionRemove {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// Within is a function that returns the entries that are the prefix or
+// are nested in it
+func Within(entries []Entry, prefix netip.Prefix) []Entry {
+	var within []Entry
+	for _, e := range entries {
+		p, err := netip.ParsePrefix(e.Prefix)
+		if err != nil {
+			continue
+		}
+		if p.Bits() >= prefix.Bits() && prefix.Contains(p.Addr()) {
+			within = append(within, e)
+		}
+	}
+	return within
+}
+
+// IsHost is a function that checks if the entry is a single address (a /32
+// or /128 prefix), which backends store as an IP address
+func (e Entry) IsHost() bool {
+	p, err := netip.ParsePrefix(e.Prefix)
+	return err == nil && p.Bits() == p.Addr().BitLen()
+}
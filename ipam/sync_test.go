@@ -0,0 +1,152 @@
+package ipam_test
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/ipam"
+)
+
+// dropExpiry is a normalize function of a backend without expiry dates
+func dropExpiry(e ipam.Entry) ipam.Entry {
+	e.Expires = ""
+	return e
+}
+
+func TestPushChanges(t *testing.T) {
+	local := []ipam.Entry{
+		{Prefix: "10.0.0.0/24", Name: "web"},
+		{Prefix: "10.0.1.0/24", Name: "app", Expires: "2030-01-01"},
+		{Prefix: "10.0.2.0/24", Name: "db", State: ipam.StateReserved},
+	}
+	remote := []ipam.Entry{
+		{Prefix: "10.0.1.0/24", Name: "app"},
+		{Prefix: "10.0.2.0/24", Name: "database"},
+		{Prefix: "10.0.3.0/24", Name: "remote only"},
+	}
+
+	changes := ipam.PushChanges(local, remote, dropExpiry)
+
+	// Setup test cases
+	testCases := []struct {
+		action string
+		prefix string
+		after  ipam.Entry
+	}{
+		{action: ipam.ActionAdd, prefix: "10.0.0.0/24", after: ipam.Entry{Prefix: "10.0.0.0/24", Name: "web"}},
+		{action: ipam.ActionUpdate, prefix: "10.0.2.0/24", after: ipam.Entry{Prefix: "10.0.2.0/24", Name: "db", State: ipam.StateReserved}},
+	}
+
+	// Run test cases
+	if len(changes) != len(testCases) {
+		t.Fatalf("expected %d changes, got %v", len(testCases), changes)
+	}
+	for i, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			c := changes[i]
+			if c.Action != tc.action || c.Prefix != tc.prefix || *c.After != tc.after {
+				t.Errorf("expected %s %s %v, got %s %s %v", tc.action, tc.prefix, tc.after, c.Action, c.Prefix, *c.After)
+			}
+		})
+	}
+}
+
+func TestPullChanges(t *testing.T) {
+	local := []ipam.Entry{
+		{Prefix: "10.0.1.0/24", Name: "app", Expires: "2030-01-01"},
+		{Prefix: "10.0.2.0/24", Name: "db", Expires: "2030-01-01"},
+		{Prefix: "10.0.4.0/24", Name: "local only"},
+	}
+	remote := []ipam.Entry{
+		{Prefix: "10.0.0.0/24", Name: "web"},
+		{Prefix: "10.0.1.0/24", Name: "app"},
+		{Prefix: "10.0.2.0/24", Name: "database"},
+	}
+
+	changes := ipam.PullChanges(local, remote, dropExpiry)
+
+	// Setup test cases
+	testCases := []struct {
+		action string
+		prefix string
+		after  ipam.Entry
+	}{
+		{action: ipam.ActionAdd, prefix: "10.0.0.0/24", after: ipam.Entry{Prefix: "10.0.0.0/24", Name: "web"}},
+		{action: ipam.ActionUpdate, prefix: "10.0.2.0/24", after: ipam.Entry{Prefix: "10.0.2.0/24", Name: "database", Expires: "2030-01-01"}},
+	}
+
+	// Run test cases
+	if len(changes) != len(testCases) {
+		t.Fatalf("expected %d changes, got %v", len(testCases), changes)
+	}
+	for i, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			c := changes[i]
+			if c.Action != tc.action || c.Prefix != tc.prefix || *c.After != tc.after {
+				t.Errorf("expected %s %s %v, got %s %s %v", tc.action, tc.prefix, tc.after, c.Action, c.Prefix, *c.After)
+			}
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	entries := []ipam.Entry{
+		{Prefix: "10.0.0.0/8"},
+		{Prefix: "10.0.0.0/16"},
+		{Prefix: "10.0.1.0/24"},
+		{Prefix: "10.0.1.5/32"},
+		{Prefix: "10.1.0.0/16"},
+		{Prefix: "2001:db8::/32"},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{prefix: "10.0.0.0/16", expected: []string{"10.0.0.0/16", "10.0.1.0/24", "10.0.1.5/32"}},
+		{prefix: "10.0.1.0/24", expected: []string{"10.0.1.0/24", "10.0.1.5/32"}},
+		{prefix: "192.168.0.0/16", expected: nil},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			var got []string
+			for _, e := range ipam.Within(entries, netip.MustParsePrefix(tc.prefix)) {
+				got = append(got, e.Prefix)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		backend   string
+		url       string
+		token     string
+		expectErr bool
+	}{
+		{name: "NetBox", backend: "netbox", url: "https://netbox.example.com", token: "secret"},
+		{name: "NetBoxAPI", backend: "NetBox", url: "https://netbox.example.com/api/", token: "secret"},
+		{name: "UnknownBackend", backend: "phpipam", url: "https://ipam.example.com", token: "secret", expectErr: true},
+		{name: "InvalidURL", backend: "netbox", url: "netbox.example.com", token: "secret", expectErr: true},
+		{name: "NoToken", backend: "netbox", url: "https://netbox.example.com", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ipam.NewBackend(tc.backend, tc.url, tc.token)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package mac
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidMAC = errors.New("invalid MAC address")
+
+// bareMAC matches a MAC address written without separators (e.g. 001122334455)
+var bareMAC = regexp.MustCompile(`^[0-9a-fA-F]{12}$`)
+
+// ParseMAC is a function that parses a 48-bit MAC address in any of the common
+// notations: 00:11:22:33:44:55, 00-11-22-33-44-55, 0011.2233.4455 or 001122334455
+func ParseMAC(s string) (net.HardwareAddr, error) {
+	// Insert colons in MAC addresses written without separators
+	if bareMAC.MatchString(s) {
+		s = strings.Join([]string{s[0:2], s[2:4], s[4:6], s[6:8], s[8:10], s[10:12]}, ":")
+	}
+
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMAC, s)
+	}
+	return hw, nil
+}
+
+// EUI64 is a function that converts a 48-bit MAC address into a modified
+// EUI-64 interface identifier (RFC 4291, appendix A) by inserting ff:fe in
+// the middle of the address and inverting the universal/local bit.
+func EUI64(hw net.HardwareAddr) ([]byte, error) {
+	if len(hw) != 6 {
+		return nil, ErrInvalidMAC
+	}
+
+	id := []byte{hw[0] ^ 0x02, hw[1], hw[2], 0xff, 0xfe, hw[3], hw[4], hw[5]}
+	return id, nil
+}
+
+// IsLocallyAdministered is a function that returns true if the universal/local
+// bit is set in the MAC address, i.e. the address is not vendor assigned
+func IsLocallyAdministered(hw net.HardwareAddr) bool {
+	return len(hw) > 0 && hw[0]&0x02 != 0
+}
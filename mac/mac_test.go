@@ -0,0 +1,49 @@
+package mac_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bitcanon/iptool/mac"
+)
+
+func TestEUI64(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "Colons", input: "00:11:22:33:44:55", expected: "021122fffe334455"},
+		{name: "Dashes", input: "00-11-22-33-44-55", expected: "021122fffe334455"},
+		{name: "CiscoDots", input: "0011.2233.4455", expected: "021122fffe334455"},
+		{name: "Bare", input: "001122334455", expected: "021122fffe334455"},
+		{name: "LocalBitSet", input: "02:00:5e:10:00:01", expected: "00005efffe100001"},
+		{name: "Invalid", input: "00:11:22:33:44", expectErr: true},
+		{name: "EUI64Input", input: "00:11:22:33:44:55:66:77", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hw, err := mac.ParseMAC(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			id, err := mac.EUI64(hw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprintf("%x", id) != tc.expected {
+				t.Errorf("expected interface ID %q, got %x", tc.expected, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ndp
+
+import (
+	"net"
+	"strings"
+)
+
+// parseLinuxNeighbors is a function that parses the output of the Linux
+// command "ip -6 neigh show dev <iface>" into a list of neighbors, e.g.
+// "fe80::1 lladdr 00:11:22:33:44:55 router REACHABLE"
+func parseLinuxNeighbors(output string) []Neighbor {
+	var neighbors []Neighbor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		addr := net.ParseIP(fields[0])
+		if addr == nil {
+			continue
+		}
+
+		n := Neighbor{IP: addr, State: fields[len(fields)-1]}
+		for i := 1; i < len(fields)-1; i++ {
+			if fields[i] == "lladdr" {
+				n.MAC, _ = net.ParseMAC(fields[i+1])
+			}
+		}
+
+		// Skip entries that are known to be unreachable
+		if n.State == "FAILED" || n.State == "INCOMPLETE" {
+			continue
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
+
+// parseBSDNeighbors is a function that parses the output of the BSD/macOS
+// command "ndp -an" into a list of neighbors for the interface iface, e.g.
+// "fe80::1%en0 0:11:22:33:44:55 en0 23h59m58s S R"
+func parseBSDNeighbors(output, iface string) []Neighbor {
+	var neighbors []Neighbor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] != iface {
+			continue
+		}
+
+		// Strip the zone from the address
+		addr := net.ParseIP(strings.SplitN(fields[0], "%", 2)[0])
+		if addr == nil {
+			continue
+		}
+
+		n := Neighbor{IP: addr}
+		n.MAC, _ = net.ParseMAC(padMAC(fields[1]))
+		if len(fields) > 4 {
+			n.State = fields[4]
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors
+}
+
+// padMAC is a function that adds the leading zeros that ndp leaves out of
+// the octets of MAC addresses (e.g. 0:11:22:33:44:5 is 00:11:22:33:44:05)
+func padMAC(s string) string {
+	octets := strings.Split(s, ":")
+	for i, octet := range octets {
+		if len(octet) == 1 {
+			octets[i] = "0" + octet
+		}
+	}
+	return strings.Join(octets, ":")
+}
@@ -0,0 +1,54 @@
+package ndp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinuxNeighbors(t *testing.T) {
+	output := `fe80::1 lladdr 00:11:22:33:44:55 router REACHABLE
+2001:db8::10 lladdr 00:11:22:33:44:66 STALE
+fe80::2 FAILED
+fe80::3 INCOMPLETE
+not-an-address lladdr 00:11:22:33:44:77 REACHABLE
+
+fe80::4 lladdr 00:11:22:33:44:88 DELAY
+`
+	expected := []Neighbor{
+		{IP: net.ParseIP("fe80::1"), MAC: mustParseMAC("00:11:22:33:44:55"), State: "REACHABLE"},
+		{IP: net.ParseIP("2001:db8::10"), MAC: mustParseMAC("00:11:22:33:44:66"), State: "STALE"},
+		{IP: net.ParseIP("fe80::4"), MAC: mustParseMAC("00:11:22:33:44:88"), State: "DELAY"},
+	}
+	if got := parseLinuxNeighbors(output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseBSDNeighbors(t *testing.T) {
+	output := `Neighbor                             Linklayer Address  Netif Expire    1s 5s
+fe80::1%en0                          0:11:22:33:44:55     en0 23h59m58s S  R
+2001:db8::10                         0:11:22:33:44:66     en0 permanent R
+fe80::2%en1                          0:11:22:33:44:77     en1 1s        D
+fe80::5%en0                          a:b:c:d:e:f          en0 1s        R
+fe80::3%en0                          (incomplete)         en0 expired   N
+`
+	expected := []Neighbor{
+		{IP: net.ParseIP("fe80::1"), MAC: mustParseMAC("00:11:22:33:44:55"), State: "S"},
+		{IP: net.ParseIP("2001:db8::10"), MAC: mustParseMAC("00:11:22:33:44:66"), State: "R"},
+		{IP: net.ParseIP("fe80::5"), MAC: mustParseMAC("0a:0b:0c:0d:0e:0f"), State: "R"},
+		{IP: net.ParseIP("fe80::3"), State: "N"},
+	}
+	if got := parseBSDNeighbors(output, "en0"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+// mustParseMAC is a function that parses a MAC address of the test data
+func mustParseMAC(s string) net.HardwareAddr {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		panic(err)
+	}
+	return mac
+}
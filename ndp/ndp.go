@@ -0,0 +1,227 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ndp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bitcanon/iptool/privsep"
+)
+
+// ICMPv6 message types used during discovery
+const (
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// AllNodes is the link-local all-nodes multicast address (RFC 4291)
+var AllNodes = net.ParseIP("ff02::1")
+
+var ErrPermission = errors.New("permission denied: sending ICMPv6 requires raw socket privileges (run as root or install iptool-helper)")
+
+// Neighbor represents an on-link IPv6 host found during discovery
+type Neighbor struct {
+	IP     net.IP
+	MAC    net.HardwareAddr
+	State  string
+	Source string
+}
+
+// Discover is a function that enumerates live IPv6 hosts on the link attached
+// to the interface iface. It sends an ICMPv6 echo request to the all-nodes
+// multicast address, collects the replies until the timeout expires, and then
+// merges the result with the neighbor (NDP) cache of the operating system.
+// Only hosts inside prefix are returned (all hosts are returned if prefix is
+// nil), and the link-local addresses of the hosts on the link: hosts answer
+// the multicast request from their link-local address.
+// The echo request is sent from the source address if it is not nil.
+func Discover(iface string, prefix *net.IPNet, source *net.IPAddr, timeout time.Duration) ([]Neighbor, error) {
+	// Make sure that the interface exists
+	if _, err := net.InterfaceByName(iface); err != nil {
+		return nil, err
+	}
+
+	// Send the multicast echo request and collect the replies
+	replies, err := echoAllNodes(iface, source, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge the replies with the neighbor cache
+	neighbors := make(map[string]*Neighbor)
+	for _, addr := range replies {
+		neighbors[addr.String()] = &Neighbor{IP: addr, Source: "echo"}
+	}
+
+	// The neighbor cache is best effort, it is not available on every platform
+	cache, _ := ReadCache(iface)
+	for _, entry := range cache {
+		if n, ok := neighbors[entry.IP.String()]; ok {
+			n.MAC = entry.MAC
+			n.State = entry.State
+			n.Source = "echo+cache"
+			continue
+		}
+		entry.Source = "cache"
+		e := entry
+		neighbors[entry.IP.String()] = &e
+	}
+
+	// Filter the neighbors on the prefix and sort them by address
+	result := make([]Neighbor, 0, len(neighbors))
+	for _, n := range neighbors {
+		if prefix != nil && !prefix.Contains(n.IP) && !n.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		result = append(result, *n)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return string(result[i].IP.To16()) < string(result[j].IP.To16())
+	})
+
+	return result, nil
+}
+
+// echoAllNodes is a function that returns the addresses replying to an ICMPv6
+// echo request sent to the all-nodes multicast address on the interface iface.
+// If the process lacks the privileges to open a raw socket, the request is
+// delegated to the privileged helper process (iptool-helper) if it is installed.
+func echoAllNodes(iface string, source *net.IPAddr, timeout time.Duration) ([]net.IP, error) {
+	conn, err := ListenICMPv6(source)
+	if errors.Is(err, ErrPermission) {
+		return echoAllNodesHelper(iface, source, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return EchoAllNodes(conn, iface, timeout)
+}
+
+// echoAllNodesHelper is a function that asks the privileged helper process
+// to send the echo request and returns the addresses that replied
+func echoAllNodesHelper(iface string, source *net.IPAddr, timeout time.Duration) ([]net.IP, error) {
+	req := privsep.Request{
+		Op:        privsep.OpEchoAllNodes,
+		Interface: iface,
+		TimeoutMs: int(timeout.Milliseconds()),
+	}
+	if source != nil {
+		req.Source = source.String()
+	}
+	resp, err := privsep.Call(req)
+	if errors.Is(err, privsep.ErrHelperNotFound) {
+		return nil, ErrPermission
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var replies []net.IP
+	for _, addr := range resp.Addresses {
+		if ip := net.ParseIP(addr); ip != nil {
+			replies = append(replies, ip)
+		}
+	}
+	return replies, nil
+}
+
+// ListenICMPv6 is a function that opens a raw ICMPv6 socket, bound to the
+// source address if it is not nil. Opening the socket requires raw socket
+// privileges (root or CAP_NET_RAW).
+func ListenICMPv6(source *net.IPAddr) (net.PacketConn, error) {
+	address := "::"
+	if source != nil {
+		address = source.String()
+	}
+	conn, err := net.ListenPacket("ip6:ipv6-icmp", address)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, ErrPermission
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// EchoAllNodes is a function that sends an ICMPv6 echo request to the
+// all-nodes multicast address on the interface iface using the raw socket
+// conn, and returns the source address of every echo reply received before
+// the timeout expires.
+func EchoAllNodes(conn net.PacketConn, iface string, timeout time.Duration) ([]net.IP, error) {
+	// Use the lower 16 bits of the process ID as the echo identifier
+	id := uint16(os.Getpid() & 0xffff)
+
+	// Send the echo request (the kernel calculates the ICMPv6 checksum)
+	dst := &net.IPAddr{IP: AllNodes, Zone: iface}
+	if _, err := conn.WriteTo(MarshalEcho(id, 1), dst); err != nil {
+		return nil, err
+	}
+
+	// Collect the replies until the timeout expires
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	seen := make(map[string]bool)
+	var replies []net.IP
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		// Only accept echo replies carrying our identifier
+		if n < 8 || buf[0] != icmpv6EchoReply || binary.BigEndian.Uint16(buf[4:6]) != id {
+			continue
+		}
+
+		src := addr.(*net.IPAddr).IP
+		if !seen[src.String()] {
+			seen[src.String()] = true
+			replies = append(replies, src)
+		}
+	}
+
+	return replies, nil
+}
+
+// MarshalEcho is a function that returns an ICMPv6 echo request message with
+// the given identifier and sequence number. The checksum is left as zero since
+// it is calculated by the kernel for ICMPv6 raw sockets.
+func MarshalEcho(id, seq uint16) []byte {
+	msg := make([]byte, 8)
+	msg[0] = icmpv6EchoRequest
+	msg[1] = 0
+	binary.BigEndian.PutUint16(msg[4:6], id)
+	binary.BigEndian.PutUint16(msg[6:8], seq)
+	return msg
+}
@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package notify tells users and other systems about changes of the state
+// of monitored targets, by running a command (e.g. notify-send or a mail
+// script) or by posting the change to a webhook URL.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// States of a monitored target
+const (
+	StateUp   = "up"
+	StateDown = "down"
+
+	// StateSlow is the state of a target that answers, but slower than a threshold
+	StateSlow = "slow"
+
+	// StateAlert and StateOK are the states of a check with an alert condition
+	StateAlert = "alert"
+	StateOK    = "ok"
+)
+
+// Timeout is the time a command or webhook may take before it is canceled
+const Timeout = 30 * time.Second
+
+// Event is a change of the state of a monitored target, e.g. a host that
+// stops answering pings (down) or answers again (up)
+type Event struct {
+	Source   string    `json:"source,omitempty"`
+	Target   string    `json:"target"`
+	Address  string    `json:"address,omitempty"`
+	Port     int       `json:"port,omitempty"`
+	State    string    `json:"state"`
+	Previous string    `json:"previous,omitempty"`
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message"`
+}
+
+// NewEvent is a function that returns the event of a target changing to a
+// state, with a message describing the change
+func NewEvent(target, address string, port int, state, previous string) Event {
+	e := Event{Target: target, Address: address, Port: port, State: state, Previous: previous, Time: time.Now()}
+	name := target
+	if port > 0 {
+		name = fmt.Sprintf("%s port %d", target, port)
+	}
+	switch {
+	case previous == "":
+		e.Message = fmt.Sprintf("%s is %s", name, state)
+	case state == StateUp && previous == StateDown:
+		e.Message = fmt.Sprintf("%s is up again (was %s)", name, previous)
+	default:
+		e.Message = fmt.Sprintf("%s is %s (was %s)", name, state, previous)
+	}
+	return e
+}
+
+// Env is a function that returns the environment variables describing the
+// event, which are passed to commands: IPTOOL_TARGET, IPTOOL_ADDRESS,
+// IPTOOL_PORT, IPTOOL_STATE, IPTOOL_PREVIOUS_STATE, IPTOOL_MESSAGE and
+// IPTOOL_EVENT (the event in JSON format)
+func (e Event) Env() []string {
+	event, _ := json.Marshal(e)
+	return []string{
+		"IPTOOL_TARGET=" + e.Target,
+		"IPTOOL_ADDRESS=" + e.Address,
+		"IPTOOL_PORT=" + strconv.Itoa(e.Port),
+		"IPTOOL_STATE=" + e.State,
+		"IPTOOL_PREVIOUS_STATE=" + e.Previous,
+		"IPTOOL_MESSAGE=" + e.Message,
+		"IPTOOL_EVENT=" + string(event),
+	}
+}
+
+// IsURL is a function that reports whether a hook is a webhook URL
+// (http:// or https://) rather than a command
+func IsURL(hook string) bool {
+	return strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://")
+}
+
+// Send is a function that sends an event to a hook: a webhook URL gets the
+// event posted in JSON format, any other hook is run as a command using the
+// shell with the event in its environment (see Event.Env)
+func Send(ctx context.Context, hook string, e Event) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	if IsURL(hook) {
+		return Post(ctx, http.DefaultClient, hook, e)
+	}
+	return Command(ctx, hook, e.Env())
+}
+
+// Post is a function that posts an event in JSON format to a webhook URL,
+// a response status other than 2xx is an error
+func Post(ctx context.Context, client *http.Client, url string, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = postPayload(ctx, client, url, body)
+	return err
+}
+
+// Command is a function that runs a command using the shell (cmd on
+// Windows), with the variables added to its environment. The output of
+// the command is written to the output of iptool.
+func Command(ctx context.Context, command string, env []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
@@ -0,0 +1,86 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/notify"
+)
+
+func TestNewEvent(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		state    string
+		previous string
+		expected string
+	}{
+		{state: notify.StateDown, expected: "example.com port 443 is down"},
+		{state: notify.StateDown, previous: notify.StateUp, expected: "example.com port 443 is down (was up)"},
+		{state: notify.StateUp, previous: notify.StateDown, expected: "example.com port 443 is up again (was down)"},
+		{state: notify.StateSlow, previous: notify.StateUp, expected: "example.com port 443 is slow (was up)"},
+		{state: notify.StateUp, previous: notify.StateSlow, expected: "example.com port 443 is up (was slow)"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		e := notify.NewEvent("example.com", "192.0.2.1", 443, tc.state, tc.previous)
+		if e.Message != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, e.Message)
+		}
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	var received notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	e := notify.NewEvent("example.com", "192.0.2.1", 443, notify.StateDown, notify.StateUp)
+	if err := notify.Send(context.Background(), server.URL+"/hook", e); err != nil {
+		t.Fatal(err)
+	}
+	if received.Target != "example.com" || received.State != notify.StateDown || received.Message != e.Message {
+		t.Errorf("unexpected event: %+v", received)
+	}
+	if err := notify.Send(context.Background(), server.URL+"/fail", e); err == nil {
+		t.Error("expected an error for a failed webhook")
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command uses the POSIX shell")
+	}
+	file := filepath.Join(t.TempDir(), "event")
+
+	e := notify.NewEvent("example.com", "192.0.2.1", 443, notify.StateUp, notify.StateDown)
+	if err := notify.Send(context.Background(), `echo "$IPTOOL_TARGET $IPTOOL_STATE $IPTOOL_PREVIOUS_STATE" > `+file, e); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "example.com up down" {
+		t.Errorf("unexpected environment: %s", got)
+	}
+	if err := notify.Send(context.Background(), "exit 1", e); err == nil {
+		t.Error("expected an error for a failed command")
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ratelimit"
+)
+
+// Payload formats of webhooks
+const (
+	// FormatGeneric posts the event itself in JSON format
+	FormatGeneric = "generic"
+
+	// FormatSlack posts a Slack message (incoming webhook)
+	FormatSlack = "slack"
+
+	// FormatTeams posts a Microsoft Teams message card (incoming webhook)
+	FormatTeams = "teams"
+)
+
+// Formats is the list of supported payload formats of webhooks
+var Formats = []string{FormatGeneric, FormatSlack, FormatTeams}
+
+// maxRetryAfter is the longest Retry-After of a webhook that is honored
+const maxRetryAfter = time.Minute
+
+// queueSize is the number of events a dispatcher holds before it drops events
+const queueSize = 100
+
+// Webhook sends events to a webhook URL with a payload in one of the
+// Formats, or rendered from a template. Failed requests (network errors, 429
+// and 5xx responses) are retried up to Retries times, waiting Backoff before
+// the first retry and twice as long before every next retry (or as long as
+// the Retry-After header asks). The Limiter limits the rate of the requests.
+type Webhook struct {
+	URL      string
+	Format   string
+	Template *template.Template
+	Retries  int
+	Backoff  time.Duration
+	Limiter  *ratelimit.Limiter
+	Client   *http.Client
+}
+
+// templateFuncs are the functions available in payload templates: json
+// encodes a value as a JSON value (e.g. a quoted and escaped string)
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseTemplate is a function that parses a payload template, a Go text
+// template executed with the event, e.g. {"text": {{json .Message}}}
+func ParseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("payload").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return t, nil
+}
+
+// title is a function that returns the title of an event in the chat
+// messages, e.g. [iptool tcp ping] example.com port 443 is down (was up)
+func title(e Event) string {
+	if e.Source == "" {
+		return "[iptool] " + e.Message
+	}
+	return "[iptool " + e.Source + "] " + e.Message
+}
+
+// Payload is a function that returns the body posted to the webhook for an
+// event, rendered from the template if set or in the format of the webhook
+func (w *Webhook) Payload(e Event) ([]byte, error) {
+	if w.Template != nil {
+		var buf bytes.Buffer
+		if err := w.Template.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("webhook template: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": title(e)})
+	case FormatTeams:
+		// Red for down and alerting targets, green for the others
+		color := "2EB67D"
+		if e.State == StateDown || e.State == StateAlert || e.State == StateSlow {
+			color = "E01E5A"
+		}
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    e.Message,
+			"themeColor": color,
+			"title":      title(e),
+			"text":       fmt.Sprintf("%s at %s", e.Message, e.Time.Format(time.RFC3339)),
+		})
+	case FormatGeneric, "":
+		return json.Marshal(e)
+	}
+	return nil, fmt.Errorf("invalid webhook format: %s (must be one of %s)", w.Format, strings.Join(Formats, ", "))
+}
+
+// Send is a function that posts an event to the webhook, waiting for the
+// rate limiter and retrying failed requests
+func (w *Webhook) Send(ctx context.Context, e Event) error {
+	body, err := w.Payload(e)
+	if err != nil {
+		return err
+	}
+	if err := w.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: Timeout}
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := postPayload(ctx, client, w.URL, body)
+		if err == nil || retryAfter < 0 || attempt >= w.Retries {
+			return err
+		}
+
+		// Wait before the next attempt, as long as the server asks if it does
+		delay := max(backoff, retryAfter)
+		backoff *= 2
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// postPayload is a function that posts a JSON payload to a URL. If the
+// request failed and may be retried, the returned delay is the Retry-After
+// of the response (0 if not given), otherwise it is negative.
+func postPayload(ctx context.Context, client *http.Client, url string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil && ip.LookupsDisabled() {
+		return -1, fmt.Errorf("cannot resolve %s: %w", req.URL.Hostname(), ip.ErrLookupsDisabled)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return 0, nil
+	}
+
+	// Too many requests and server errors are retried, other errors are final
+	err = fmt.Errorf("webhook %s: %s", url, resp.Status)
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return -1, err
+	}
+	seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter), err
+}
+
+// Dispatcher sends events to a webhook in the background, one at a time and
+// in order, so that a slow or rate limited webhook does not hold up the
+// monitoring loop. Events are dropped (and reported) when the queue is full.
+// A nil dispatcher discards the events.
+type Dispatcher struct {
+	webhook *Webhook
+	events  chan Event
+	done    chan struct{}
+	errs    io.Writer
+}
+
+// NewDispatcher is a function that returns a dispatcher for the webhook,
+// which reports the failures to errs (standard error if nil)
+func NewDispatcher(w *Webhook, errs io.Writer) *Dispatcher {
+	if errs == nil {
+		errs = os.Stderr
+	}
+	d := &Dispatcher{webhook: w, events: make(chan Event, queueSize), done: make(chan struct{}), errs: errs}
+	go func() {
+		defer close(d.done)
+		for e := range d.events {
+			if err := w.Send(context.Background(), e); err != nil {
+				fmt.Fprintf(errs, "Error: webhook: %v\n", err)
+			}
+		}
+	}()
+	return d
+}
+
+// Notify is a function that queues an event to be sent to the webhook
+func (d *Dispatcher) Notify(e Event) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.events <- e:
+	default:
+		fmt.Fprintf(d.errs, "Error: webhook: %v\n", errors.New("too many events queued, event dropped: "+e.Message))
+	}
+}
+
+// Close is a function that stops the dispatcher after the queued events
+// are sent, waiting at most timeout for them
+func (d *Dispatcher) Close(timeout time.Duration) {
+	if d == nil {
+		return
+	}
+	close(d.events)
+	select {
+	case <-d.done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,165 @@
+package notify_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/notify"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	e := notify.NewEvent("example.com", "192.0.2.1", 443, notify.StateDown, notify.StateUp)
+	e.Source = "tcp ping"
+	tmpl, err := notify.ParseTemplate(`{"alert": {{json .Message}}, "state": "{{.State}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		webhook  notify.Webhook
+		key      string
+		expected string
+	}{
+		{webhook: notify.Webhook{Format: notify.FormatGeneric}, key: "message", expected: "example.com port 443 is down (was up)"},
+		{webhook: notify.Webhook{Format: notify.FormatGeneric}, key: "source", expected: "tcp ping"},
+		{webhook: notify.Webhook{Format: notify.FormatSlack}, key: "text", expected: "[iptool tcp ping] example.com port 443 is down (was up)"},
+		{webhook: notify.Webhook{Format: notify.FormatTeams}, key: "themeColor", expected: "E01E5A"},
+		{webhook: notify.Webhook{Format: notify.FormatTeams}, key: "@type", expected: "MessageCard"},
+		{webhook: notify.Webhook{Template: tmpl}, key: "alert", expected: "example.com port 443 is down (was up)"},
+		{webhook: notify.Webhook{Template: tmpl}, key: "state", expected: "down"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		payload, err := tc.webhook.Payload(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			t.Fatalf("invalid JSON payload %s: %v", payload, err)
+		}
+		if fields[tc.key] != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.key, tc.expected, fields[tc.key])
+		}
+	}
+
+	// An unknown format is an error
+	w := notify.Webhook{Format: "pager"}
+	if _, err := w.Payload(e); err == nil {
+		t.Error("expected an error for an invalid format")
+	}
+	if _, err := notify.ParseTemplate("{{.Message"); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	// The server fails (some of) the requests depending on the path
+	var mutex sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		count := requests[r.URL.Path]
+		mutex.Unlock()
+		switch {
+		case r.URL.Path == "/unavailable" && count <= 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/limited" && count == 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	// Setup test cases
+	testCases := []struct {
+		path     string
+		retries  int
+		requests int
+		fail     bool
+	}{
+		{path: "/ok", retries: 3, requests: 1},
+		{path: "/unavailable", retries: 3, requests: 3},
+		{path: "/limited", retries: 1, requests: 2},
+		{path: "/forbidden", retries: 3, requests: 1, fail: true},
+		{path: "/down", retries: 2, requests: 3, fail: true},
+	}
+
+	// Run test cases
+	e := notify.NewEvent("example.com", "", 0, notify.StateDown, notify.StateUp)
+	for _, tc := range testCases {
+		w := notify.Webhook{URL: server.URL + tc.path, Retries: tc.retries, Backoff: time.Millisecond}
+		err := w.Send(context.Background(), e)
+		if (err != nil) != tc.fail {
+			t.Errorf("%s: unexpected error: %v", tc.path, err)
+		}
+		mutex.Lock()
+		if requests[tc.path] != tc.requests {
+			t.Errorf("%s: expected %d request(s), got %d", tc.path, tc.requests, requests[tc.path])
+		}
+		mutex.Unlock()
+	}
+}
+
+func TestWebhookLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// The name of the webhook is not resolved with --no-dns, nor retried
+	w := notify.Webhook{URL: "https://hooks.invalid/iptool", Retries: 3, Backoff: time.Hour}
+	e := notify.NewEvent("example.com", "", 0, notify.StateDown, notify.StateUp)
+	if err := w.Send(context.Background(), e); !errors.Is(err, ip.ErrLookupsDisabled) {
+		t.Errorf("expected %v, got %v", ip.ErrLookupsDisabled, err)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var mutex sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var fields map[string]string
+		json.Unmarshal(body, &fields)
+		mutex.Lock()
+		messages = append(messages, fields["text"])
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	// The events are sent in order, and all are sent before Close returns
+	var errs bytes.Buffer
+	d := notify.NewDispatcher(&notify.Webhook{URL: server.URL, Format: notify.FormatSlack}, &errs)
+	d.Notify(notify.NewEvent("a", "", 0, notify.StateDown, ""))
+	d.Notify(notify.NewEvent("b", "", 0, notify.StateDown, ""))
+	d.Notify(notify.NewEvent("a", "", 0, notify.StateUp, notify.StateDown))
+	d.Close(5 * time.Second)
+
+	expected := "[iptool] a is down|[iptool] b is down|[iptool] a is up again (was down)"
+	if got := strings.Join(messages, "|"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if errs.Len() > 0 {
+		t.Errorf("unexpected errors: %s", errs.String())
+	}
+
+	// A nil dispatcher discards the events
+	var nilDispatcher *notify.Dispatcher
+	nilDispatcher.Notify(notify.NewEvent("a", "", 0, notify.StateDown, ""))
+	nilDispatcher.Close(time.Second)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package packet
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Checksum is a function that computes the internet checksum (RFC 1071) of
+// the given data: the one's complement of the one's complement sum of the
+// 16-bit words, where the data is padded with a zero byte if its length is
+// odd. The slices are summed as if they were one (e.g. a pseudo-header and
+// a TCP segment). The checksum of data that contains a valid checksum is 0.
+func Checksum(data ...[]byte) uint16 {
+	var sum uint32
+	var odd bool
+	var last byte
+	for _, b := range data {
+		for _, c := range b {
+			if odd {
+				sum += uint32(last)<<8 | uint32(c)
+			} else {
+				last = c
+			}
+			odd = !odd
+		}
+	}
+	// Pad the last byte with a zero byte
+	if odd {
+		sum += uint32(last) << 8
+	}
+
+	// Fold the carries back into the lower 16 bits
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// PseudoHeader is a function that returns the IPv4 pseudo-header that is
+// included in the TCP and UDP checksums: the source and destination
+// addresses, a zero byte, the protocol and the length of the segment
+func PseudoHeader(src, dst netip.Addr, protocol uint8, length int) []byte {
+	b := make([]byte, 12)
+	s, d := src.As4(), dst.As4()
+	copy(b[0:4], s[:])
+	copy(b[4:8], d[:])
+	b[9] = protocol
+	binary.BigEndian.PutUint16(b[10:12], uint16(length))
+	return b
+}
@@ -0,0 +1,305 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Header lengths in bytes (without options)
+const (
+	IPv4HeaderLength = 20
+	TCPHeaderLength  = 20
+	UDPHeaderLength  = 8
+)
+
+// IP protocol numbers of the transport headers
+const (
+	ProtocolICMP = 1
+	ProtocolTCP  = 6
+	ProtocolUDP  = 17
+)
+
+// protocolNames maps the protocol numbers to their names
+var protocolNames = map[uint8]string{
+	ProtocolICMP: "icmp",
+	ProtocolTCP:  "tcp",
+	ProtocolUDP:  "udp",
+}
+
+// TCP flags in the order they appear in the header (low bit first)
+var tcpFlags = []string{"FIN", "SYN", "RST", "PSH", "ACK", "URG", "ECE", "CWR"}
+
+// IPv4 represents the fields of an IPv4 header that can be set, the
+// version, header length, total length and checksum are calculated
+type IPv4 struct {
+	TOS            uint8
+	ID             uint16
+	DontFragment   bool
+	MoreFragments  bool
+	FragmentOffset uint16
+	TTL            uint8
+	Protocol       uint8
+	Src            netip.Addr
+	Dst            netip.Addr
+}
+
+// TCP represents the fields of a TCP header, the data offset and the
+// checksum are calculated
+type TCP struct {
+	SrcPort uint16
+	DstPort uint16
+	Seq     uint32
+	Ack     uint32
+	Flags   uint8
+	Window  uint16
+	Urgent  uint16
+}
+
+// UDP represents the fields of a UDP header, the length and the checksum
+// are calculated
+type UDP struct {
+	SrcPort uint16
+	DstPort uint16
+}
+
+// Field describes a field of a header in the byte layout of a packet.
+// Offset and Length are in bytes, fields shorter than a byte (e.g. the
+// flags) share the bytes they are stored in.
+type Field struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	Value  string `json:"value"`
+}
+
+// ParseProtocol is a function that parses an IP protocol given by name
+// (tcp, udp or icmp) or number (0-255)
+func ParseProtocol(s string) (uint8, error) {
+	for number, name := range protocolNames {
+		if strings.EqualFold(s, name) {
+			return number, nil
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol: %s (must be tcp, udp, icmp or a number between 0 and 255)", s)
+	}
+	return uint8(n), nil
+}
+
+// ProtocolName is a function that returns the name of an IP protocol, or
+// its number if the name is not known
+func ProtocolName(protocol uint8) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+// ParseTCPFlags is a function that parses a comma separated list of TCP
+// flags (e.g. SYN,ACK) into the flags byte of a TCP header
+func ParseTCPFlags(s string) (uint8, error) {
+	var flags uint8
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		found := false
+		for bit, name := range tcpFlags {
+			if strings.EqualFold(field, name) {
+				flags |= 1 << bit
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid TCP flag: %s (must be one of %s)", field, strings.Join(tcpFlags, ", "))
+		}
+	}
+	return flags, nil
+}
+
+// TCPFlagNames is a function that returns the names of the flags set in
+// the flags byte of a TCP header, separated by commas
+func TCPFlagNames(flags uint8) string {
+	var names []string
+	for bit := len(tcpFlags) - 1; bit >= 0; bit-- {
+		if flags&(1<<bit) != 0 {
+			names = append(names, tcpFlags[bit])
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Marshal is a function that returns the IPv4 header of a packet with the
+// given payload length (the length of the transport header and data), with
+// the header checksum calculated
+func (h IPv4) Marshal(payloadLength int) ([]byte, error) {
+	if !h.Src.Is4() || !h.Dst.Is4() {
+		return nil, fmt.Errorf("invalid addresses: %s -> %s (must be IPv4 addresses)", h.Src, h.Dst)
+	}
+	if total := IPv4HeaderLength + payloadLength; total > 0xffff {
+		return nil, fmt.Errorf("packet too large: %d bytes (must be at most 65535 bytes)", total)
+	}
+	if h.FragmentOffset > 0x1fff {
+		return nil, fmt.Errorf("invalid fragment offset: %d (must be between 0 and 8191)", h.FragmentOffset)
+	}
+
+	b := make([]byte, IPv4HeaderLength)
+	b[0] = 4<<4 | IPv4HeaderLength/4
+	b[1] = h.TOS
+	binary.BigEndian.PutUint16(b[2:4], uint16(IPv4HeaderLength+payloadLength))
+	binary.BigEndian.PutUint16(b[4:6], h.ID)
+
+	// The flags are the upper 3 bits of the fragment offset field
+	fragment := h.FragmentOffset
+	if h.DontFragment {
+		fragment |= 0x4000
+	}
+	if h.MoreFragments {
+		fragment |= 0x2000
+	}
+	binary.BigEndian.PutUint16(b[6:8], fragment)
+	b[8] = h.TTL
+	b[9] = h.Protocol
+	src, dst := h.Src.As4(), h.Dst.As4()
+	copy(b[12:16], src[:])
+	copy(b[16:20], dst[:])
+
+	// Calculate the checksum with the checksum field set to zero
+	binary.BigEndian.PutUint16(b[10:12], Checksum(b))
+	return b, nil
+}
+
+// Marshal is a function that returns the TCP header of a segment with the
+// given payload, with the checksum calculated over the pseudo-header of
+// the source and destination addresses, the header and the payload
+func (h TCP) Marshal(src, dst netip.Addr, payload []byte) []byte {
+	b := make([]byte, TCPHeaderLength)
+	binary.BigEndian.PutUint16(b[0:2], h.SrcPort)
+	binary.BigEndian.PutUint16(b[2:4], h.DstPort)
+	binary.BigEndian.PutUint32(b[4:8], h.Seq)
+	binary.BigEndian.PutUint32(b[8:12], h.Ack)
+	b[12] = TCPHeaderLength / 4 << 4
+	b[13] = h.Flags
+	binary.BigEndian.PutUint16(b[14:16], h.Window)
+	binary.BigEndian.PutUint16(b[18:20], h.Urgent)
+
+	pseudo := PseudoHeader(src, dst, ProtocolTCP, len(b)+len(payload))
+	binary.BigEndian.PutUint16(b[16:18], Checksum(pseudo, b, payload))
+	return b
+}
+
+// Marshal is a function that returns the UDP header of a datagram with the
+// given payload, with the checksum calculated over the pseudo-header of
+// the source and destination addresses, the header and the payload
+func (h UDP) Marshal(src, dst netip.Addr, payload []byte) []byte {
+	b := make([]byte, UDPHeaderLength)
+	binary.BigEndian.PutUint16(b[0:2], h.SrcPort)
+	binary.BigEndian.PutUint16(b[2:4], h.DstPort)
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(b)+len(payload)))
+
+	// A calculated checksum of zero is sent as all ones (RFC 768)
+	pseudo := PseudoHeader(src, dst, ProtocolUDP, len(b)+len(payload))
+	checksum := Checksum(pseudo, b, payload)
+	if checksum == 0 {
+		checksum = 0xffff
+	}
+	binary.BigEndian.PutUint16(b[6:8], checksum)
+	return b
+}
+
+// IPv4Fields is a function that returns the byte layout of an IPv4 header
+func IPv4Fields(b []byte) ([]Field, error) {
+	if len(b) < IPv4HeaderLength {
+		return nil, fmt.Errorf("IPv4 header too short: %d bytes (must be at least %d bytes)", len(b), IPv4HeaderLength)
+	}
+	fragment := binary.BigEndian.Uint16(b[6:8])
+	var flags []string
+	if fragment&0x4000 != 0 {
+		flags = append(flags, "DF")
+	}
+	if fragment&0x2000 != 0 {
+		flags = append(flags, "MF")
+	}
+	if len(flags) == 0 {
+		flags = append(flags, "none")
+	}
+	src, _ := netip.AddrFromSlice(b[12:16])
+	dst, _ := netip.AddrFromSlice(b[16:20])
+
+	return []Field{
+		{Name: "Version", Offset: 0, Length: 1, Value: strconv.Itoa(int(b[0] >> 4))},
+		{Name: "IHL", Offset: 0, Length: 1, Value: fmt.Sprintf("%d (%d bytes)", b[0]&0x0f, int(b[0]&0x0f)*4)},
+		{Name: "TOS", Offset: 1, Length: 1, Value: fmt.Sprintf("0x%02x", b[1])},
+		{Name: "Total Length", Offset: 2, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[2:4])))},
+		{Name: "Identification", Offset: 4, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[4:6])))},
+		{Name: "Flags", Offset: 6, Length: 2, Value: strings.Join(flags, ",")},
+		{Name: "Fragment Offset", Offset: 6, Length: 2, Value: strconv.Itoa(int(fragment & 0x1fff))},
+		{Name: "TTL", Offset: 8, Length: 1, Value: strconv.Itoa(int(b[8]))},
+		{Name: "Protocol", Offset: 9, Length: 1, Value: fmt.Sprintf("%d (%s)", b[9], ProtocolName(b[9]))},
+		{Name: "Header Checksum", Offset: 10, Length: 2, Value: fmt.Sprintf("0x%04x", binary.BigEndian.Uint16(b[10:12]))},
+		{Name: "Source Address", Offset: 12, Length: 4, Value: src.String()},
+		{Name: "Destination Address", Offset: 16, Length: 4, Value: dst.String()},
+	}, nil
+}
+
+// TCPFields is a function that returns the byte layout of a TCP header,
+// the offsets are relative to the start of the header
+func TCPFields(b []byte) ([]Field, error) {
+	if len(b) < TCPHeaderLength {
+		return nil, fmt.Errorf("TCP header too short: %d bytes (must be at least %d bytes)", len(b), TCPHeaderLength)
+	}
+	return []Field{
+		{Name: "Source Port", Offset: 0, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[0:2])))},
+		{Name: "Destination Port", Offset: 2, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[2:4])))},
+		{Name: "Sequence Number", Offset: 4, Length: 4, Value: strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[4:8])), 10)},
+		{Name: "Acknowledgment Number", Offset: 8, Length: 4, Value: strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[8:12])), 10)},
+		{Name: "Data Offset", Offset: 12, Length: 1, Value: fmt.Sprintf("%d (%d bytes)", b[12]>>4, int(b[12]>>4)*4)},
+		{Name: "Flags", Offset: 13, Length: 1, Value: TCPFlagNames(b[13])},
+		{Name: "Window Size", Offset: 14, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[14:16])))},
+		{Name: "Checksum", Offset: 16, Length: 2, Value: fmt.Sprintf("0x%04x", binary.BigEndian.Uint16(b[16:18]))},
+		{Name: "Urgent Pointer", Offset: 18, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[18:20])))},
+	}, nil
+}
+
+// UDPFields is a function that returns the byte layout of a UDP header,
+// the offsets are relative to the start of the header
+func UDPFields(b []byte) ([]Field, error) {
+	if len(b) < UDPHeaderLength {
+		return nil, fmt.Errorf("UDP header too short: %d bytes (must be at least %d bytes)", len(b), UDPHeaderLength)
+	}
+	return []Field{
+		{Name: "Source Port", Offset: 0, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[0:2])))},
+		{Name: "Destination Port", Offset: 2, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[2:4])))},
+		{Name: "Length", Offset: 4, Length: 2, Value: strconv.Itoa(int(binary.BigEndian.Uint16(b[4:6])))},
+		{Name: "Checksum", Offset: 6, Length: 2, Value: fmt.Sprintf("0x%04x", binary.BigEndian.Uint16(b[6:8]))},
+	}, nil
+}
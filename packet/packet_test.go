@@ -0,0 +1,177 @@
+package packet_test
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/packet"
+)
+
+func TestChecksum(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		data     []string
+		expected uint16
+	}{
+		// The example of RFC 1071
+		{data: []string{"0001f203f4f5f6f7"}, expected: 0x220d},
+		// Odd lengths are padded with a zero byte, also across slices
+		{data: []string{"0001f203f4f5f6"}, expected: 0x2304},
+		{data: []string{"0001f2", "03f4f5f6f7"}, expected: 0x220d},
+		// A header with a valid checksum sums to zero
+		{data: []string{"45000073000040004011b861c0a80001c0a800c7"}, expected: 0x0000},
+		{data: []string{""}, expected: 0xffff},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.data[0], func(t *testing.T) {
+			var data [][]byte
+			for _, s := range tc.data {
+				b, err := hex.DecodeString(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data = append(data, b)
+			}
+			if got := packet.Checksum(data...); got != tc.expected {
+				t.Errorf("expected 0x%04x, got 0x%04x", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIPv4Marshal(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		header        packet.IPv4
+		payloadLength int
+		expected      string
+		expectErr     bool
+	}{
+		{
+			name: "udp",
+			header: packet.IPv4{DontFragment: true, TTL: 64, Protocol: packet.ProtocolUDP,
+				Src: netip.MustParseAddr("192.168.0.1"), Dst: netip.MustParseAddr("192.168.0.199")},
+			payloadLength: 95,
+			expected:      "45000073000040004011b861c0a80001c0a800c7",
+		},
+		{
+			name:      "ipv6",
+			header:    packet.IPv4{Src: netip.MustParseAddr("2001:db8::1"), Dst: netip.MustParseAddr("10.0.0.1")},
+			expectErr: true,
+		},
+		{
+			name:          "too large",
+			header:        packet.IPv4{Src: netip.MustParseAddr("10.0.0.1"), Dst: netip.MustParseAddr("10.0.0.2")},
+			payloadLength: 65516,
+			expectErr:     true,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := tc.header.Marshal(tc.payloadLength)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %x", b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := hex.EncodeToString(b); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTransportChecksums(t *testing.T) {
+	src, dst := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	payload := []byte("hello")
+
+	// The checksum over the pseudo-header and the segment must be zero
+	tcp := packet.TCP{SrcPort: 12345, DstPort: 80, Seq: 1, Flags: 0x02, Window: 65535}.Marshal(src, dst, payload)
+	if sum := packet.Checksum(packet.PseudoHeader(src, dst, packet.ProtocolTCP, len(tcp)+len(payload)), tcp, payload); sum != 0 {
+		t.Errorf("invalid TCP checksum: 0x%04x", sum)
+	}
+	udp := packet.UDP{SrcPort: 12345, DstPort: 53}.Marshal(src, dst, payload)
+	if sum := packet.Checksum(packet.PseudoHeader(src, dst, packet.ProtocolUDP, len(udp)+len(payload)), udp, payload); sum != 0 {
+		t.Errorf("invalid UDP checksum: 0x%04x", sum)
+	}
+	if got := hex.EncodeToString(udp[4:6]); got != "000d" {
+		t.Errorf("expected UDP length 000d, got %s", got)
+	}
+}
+
+func TestParseTCPFlags(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  uint8
+		names     string
+		expectErr bool
+	}{
+		{input: "SYN", expected: 0x02, names: "SYN"},
+		{input: "syn,ack", expected: 0x12, names: "ACK,SYN"},
+		{input: "FIN, PSH,ACK", expected: 0x19, names: "ACK,PSH,FIN"},
+		{input: "", expected: 0x00, names: "none"},
+		{input: "SYN,FOO", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := packet.ParseTCPFlags(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got 0x%02x", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected 0x%02x, got 0x%02x", tc.expected, got)
+			}
+			if names := packet.TCPFlagNames(got); names != tc.names {
+				t.Errorf("expected %s, got %s", tc.names, names)
+			}
+		})
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  uint8
+		expectErr bool
+	}{
+		{input: "tcp", expected: 6},
+		{input: "UDP", expected: 17},
+		{input: "icmp", expected: 1},
+		{input: "47", expected: 47},
+		{input: "256", expectErr: true},
+		{input: "sctp", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := packet.ParseProtocol(tc.input)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+)
+
+// IP protocol numbers of the transport protocols
+const (
+	ProtocolICMP   = 1
+	ProtocolTCP    = 6
+	ProtocolUDP    = 17
+	ProtocolICMPv6 = 58
+)
+
+// Ethernet types of the network protocols
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86dd
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88a8
+	etherTypeQinQ2 = 0x9100
+)
+
+// protocolNames maps the protocol numbers to their names
+var protocolNames = map[uint8]string{
+	ProtocolICMP:   "icmp",
+	ProtocolTCP:    "tcp",
+	ProtocolUDP:    "udp",
+	ProtocolICMPv6: "icmpv6",
+	2:              "igmp",
+	47:             "gre",
+	50:             "esp",
+	51:             "ah",
+	89:             "ospf",
+	112:            "vrrp",
+	132:            "sctp",
+}
+
+// Flow represents the addresses, protocol and ports of an IP packet
+type Flow struct {
+	Src      netip.Addr
+	Dst      netip.Addr
+	Protocol uint8
+	SrcPort  uint16
+	DstPort  uint16
+}
+
+// HasPorts is a function that returns true if the protocol of the flow
+// has ports (TCP, UDP or SCTP)
+func (f Flow) HasPorts() bool {
+	return f.Protocol == ProtocolTCP || f.Protocol == ProtocolUDP || f.Protocol == 132
+}
+
+// ProtocolName is a function that returns the name of an IP protocol, or
+// its number if the name is not known
+func ProtocolName(protocol uint8) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+// Decode is a function that decodes the IP addresses, the protocol and the
+// ports of a captured packet. The second return value is false if the
+// packet is not an IPv4 or IPv6 packet or is truncated before the addresses.
+func Decode(linkType int, data []byte) (Flow, bool) {
+	// Find the network layer protocol and header of the link type
+	var etherType uint16
+	switch linkType {
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return Flow{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:14]), data[14:]
+
+		// Skip the VLAN tags
+		for (etherType == etherTypeVLAN || etherType == etherTypeQinQ || etherType == etherTypeQinQ2) && len(data) >= 4 {
+			etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+		}
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return Flow{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:16]), data[16:]
+	case LinkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return Flow{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[0:2]), data[20:]
+	case LinkTypeNull:
+		// The address family is in the byte order of the capturing host
+		if len(data) < 4 {
+			return Flow{}, false
+		}
+		family := binary.LittleEndian.Uint32(data[0:4])
+		if family > 0xffff {
+			family = binary.BigEndian.Uint32(data[0:4])
+		}
+		switch family {
+		case 2:
+			etherType = etherTypeIPv4
+		case 10, 24, 28, 30:
+			etherType = etherTypeIPv6
+		}
+		data = data[4:]
+	case LinkTypeRaw:
+		if len(data) > 0 && data[0]>>4 == 6 {
+			etherType = etherTypeIPv6
+		} else {
+			etherType = etherTypeIPv4
+		}
+	case LinkTypeIPv4:
+		etherType = etherTypeIPv4
+	case LinkTypeIPv6:
+		etherType = etherTypeIPv6
+	}
+
+	switch etherType {
+	case etherTypeIPv4:
+		return decodeIPv4(data)
+	case etherTypeIPv6:
+		return decodeIPv6(data)
+	}
+	return Flow{}, false
+}
+
+// decodeIPv4 is a function that decodes an IPv4 packet
+func decodeIPv4(data []byte) (Flow, bool) {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return Flow{}, false
+	}
+	src, _ := netip.AddrFromSlice(data[12:16])
+	dst, _ := netip.AddrFromSlice(data[16:20])
+	flow := Flow{Src: src, Dst: dst, Protocol: data[9]}
+
+	// Only the first fragment carries the transport header
+	headerLength := int(data[0]&0x0f) * 4
+	if binary.BigEndian.Uint16(data[6:8])&0x1fff == 0 && headerLength >= 20 && len(data) >= headerLength {
+		decodePorts(&flow, data[headerLength:])
+	}
+	return flow, true
+}
+
+// decodeIPv6 is a function that decodes an IPv6 packet, skipping the
+// extension headers to find the transport protocol
+func decodeIPv6(data []byte) (Flow, bool) {
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return Flow{}, false
+	}
+	src, _ := netip.AddrFromSlice(data[8:24])
+	dst, _ := netip.AddrFromSlice(data[24:40])
+	flow := Flow{Src: src, Dst: dst, Protocol: data[6]}
+
+	data = data[40:]
+	for {
+		switch flow.Protocol {
+		case 0, 43, 60:
+			// Hop-by-hop, routing and destination options headers
+			if len(data) < 8 {
+				return flow, true
+			}
+			length := (int(data[1]) + 1) * 8
+			if len(data) < length {
+				return flow, true
+			}
+			flow.Protocol, data = data[0], data[length:]
+		case 44:
+			// Only the first fragment carries the transport header
+			if len(data) < 8 {
+				return flow, true
+			}
+			flow.Protocol = data[0]
+			if binary.BigEndian.Uint16(data[2:4])&0xfff8 != 0 {
+				return flow, true
+			}
+			data = data[8:]
+		default:
+			decodePorts(&flow, data)
+			return flow, true
+		}
+	}
+}
+
+// decodePorts is a function that sets the ports of a flow from the
+// transport header, if the protocol has ports and the header is captured
+func decodePorts(flow *Flow, data []byte) {
+	if flow.HasPorts() && len(data) >= 4 {
+		flow.SrcPort = binary.BigEndian.Uint16(data[0:2])
+		flow.DstPort = binary.BigEndian.Uint16(data[2:4])
+	}
+}
@@ -0,0 +1,232 @@
+package pcap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/packet"
+	"github.com/bitcanon/iptool/pcap"
+)
+
+// ipv4Packet is a helper that builds an IPv4 packet with a TCP or UDP header
+func ipv4Packet(t *testing.T, src, dst string, protocol uint8, srcPort, dstPort uint16, payload int) []byte {
+	s, d := netip.MustParseAddr(src), netip.MustParseAddr(dst)
+	data := make([]byte, payload)
+	var transport []byte
+	switch protocol {
+	case packet.ProtocolTCP:
+		transport = packet.TCP{SrcPort: srcPort, DstPort: dstPort}.Marshal(s, d, data)
+	case packet.ProtocolUDP:
+		transport = packet.UDP{SrcPort: srcPort, DstPort: dstPort}.Marshal(s, d, data)
+	}
+	header, err := packet.IPv4{TTL: 64, Protocol: protocol, Src: s, Dst: d}.Marshal(len(transport) + len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append(header, transport...), data...)
+}
+
+// ethernetFrame is a helper that wraps a packet in an Ethernet frame with
+// the given Ethernet type and an optional VLAN tag
+func ethernetFrame(etherType uint16, vlan bool, data []byte) []byte {
+	frame := make([]byte, 12)
+	if vlan {
+		frame = binary.BigEndian.AppendUint16(frame, 0x8100)
+		frame = binary.BigEndian.AppendUint16(frame, 100)
+	}
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	return append(frame, data...)
+}
+
+// pcapFile is a helper that builds a classic pcap file in the given byte order
+func pcapFile(order binary.AppendByteOrder, linkType uint32, packets ...[]byte) []byte {
+	var b []byte
+	b = order.AppendUint32(b, 0xa1b2c3d4)
+	b = order.AppendUint16(b, 2)
+	b = order.AppendUint16(b, 4)
+	b = order.AppendUint32(b, 0)
+	b = order.AppendUint32(b, 0)
+	b = order.AppendUint32(b, 65535)
+	b = order.AppendUint32(b, linkType)
+	for i, p := range packets {
+		b = order.AppendUint32(b, uint32(1700000000+i))
+		b = order.AppendUint32(b, 500000)
+		b = order.AppendUint32(b, uint32(len(p)))
+		b = order.AppendUint32(b, uint32(len(p)))
+		b = append(b, p...)
+	}
+	return b
+}
+
+// pcapngFile is a helper that builds a little-endian pcapng file with one
+// interface with nanosecond timestamps
+func pcapngFile(linkType uint16, packets ...[]byte) []byte {
+	le := binary.LittleEndian
+	block := func(b []byte, blockType uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b = le.AppendUint32(b, blockType)
+		b = le.AppendUint32(b, uint32(len(body)+12))
+		b = append(b, body...)
+		return le.AppendUint32(b, uint32(len(body)+12))
+	}
+
+	// Section header, interface with if_tsresol=9 and the packets
+	var shb, idb []byte
+	shb = le.AppendUint32(shb, 0x1a2b3c4d)
+	shb = le.AppendUint16(shb, 1)
+	shb = le.AppendUint16(shb, 0)
+	shb = le.AppendUint64(shb, 0xffffffffffffffff)
+	b := block(nil, 0x0a0d0d0a, shb)
+	idb = le.AppendUint16(idb, linkType)
+	idb = le.AppendUint16(idb, 0)
+	idb = le.AppendUint32(idb, 0)
+	idb = append(idb, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0)
+	b = block(b, 1, idb)
+	for _, p := range packets {
+		var epb []byte
+		timestamp := uint64(1700000000_250000000)
+		epb = le.AppendUint32(epb, 0)
+		epb = le.AppendUint32(epb, uint32(timestamp>>32))
+		epb = le.AppendUint32(epb, uint32(timestamp))
+		epb = le.AppendUint32(epb, uint32(len(p)))
+		epb = le.AppendUint32(epb, uint32(len(p)))
+		b = block(b, 6, append(epb, p...))
+	}
+	return b
+}
+
+func TestReader(t *testing.T) {
+	tcp := ipv4Packet(t, "10.0.0.1", "10.0.0.2", packet.ProtocolTCP, 49152, 443, 10)
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		file     []byte
+		linkType int
+		time     time.Time
+	}{
+		{name: "pcap little-endian", file: pcapFile(binary.LittleEndian, 1, ethernetFrame(0x0800, false, tcp)), linkType: 1, time: time.Unix(1700000000, 500000000)},
+		{name: "pcap big-endian", file: pcapFile(binary.BigEndian, 101, tcp), linkType: 101, time: time.Unix(1700000000, 500000000)},
+		{name: "pcapng", file: pcapngFile(1, ethernetFrame(0x0800, false, tcp)), linkType: 1, time: time.Unix(1700000000, 250000000)},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := pcap.NewReader(bytes.NewReader(tc.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p, err := reader.Next()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.LinkType != tc.linkType || !p.Time.Equal(tc.time) {
+				t.Errorf("expected link type %d at %v, got %d at %v", tc.linkType, tc.time, p.LinkType, p.Time)
+			}
+			flow, ok := pcap.Decode(p.LinkType, p.Data)
+			if !ok || flow.Src.String() != "10.0.0.1" || flow.DstPort != 443 {
+				t.Errorf("unexpected flow: %+v", flow)
+			}
+			if _, err := reader.Next(); err != io.EOF {
+				t.Errorf("expected io.EOF, got %v", err)
+			}
+		})
+	}
+
+	// Other files are rejected
+	if _, err := pcap.NewReader(bytes.NewReader([]byte("not a capture file"))); err != pcap.ErrFormat {
+		t.Errorf("expected ErrFormat, got %v", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	udp := ipv4Packet(t, "192.168.1.10", "8.8.8.8", packet.ProtocolUDP, 53000, 53, 20)
+
+	// An IPv6 packet with a hop-by-hop options header before ICMPv6
+	ipv6 := make([]byte, 48)
+	ipv6[0], ipv6[6] = 0x60, 0
+	copy(ipv6[8:24], netip.MustParseAddr("fe80::1").AsSlice())
+	copy(ipv6[24:40], netip.MustParseAddr("ff02::16").AsSlice())
+	ipv6[40] = 58
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		linkType int
+		data     []byte
+		expected string
+		ok       bool
+	}{
+		{name: "ethernet", linkType: 1, data: ethernetFrame(0x0800, false, udp), expected: "192.168.1.10:53000 -> 8.8.8.8:53 udp", ok: true},
+		{name: "vlan", linkType: 1, data: ethernetFrame(0x0800, true, udp), expected: "192.168.1.10:53000 -> 8.8.8.8:53 udp", ok: true},
+		{name: "raw", linkType: 101, data: udp, expected: "192.168.1.10:53000 -> 8.8.8.8:53 udp", ok: true},
+		{name: "null", linkType: 0, data: append([]byte{2, 0, 0, 0}, udp...), expected: "192.168.1.10:53000 -> 8.8.8.8:53 udp", ok: true},
+		{name: "ipv6 extension header", linkType: 229, data: ipv6, expected: "[fe80::1]:0 -> [ff02::16]:0 icmpv6", ok: true},
+		{name: "arp", linkType: 1, data: ethernetFrame(0x0806, false, make([]byte, 28))},
+		{name: "truncated", linkType: 1, data: ethernetFrame(0x0800, false, udp[:10])},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flow, ok := pcap.Decode(tc.linkType, tc.data)
+			if ok != tc.ok {
+				t.Fatalf("expected %v, got %v", tc.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			got := netip.AddrPortFrom(flow.Src, flow.SrcPort).String() + " -> " +
+				netip.AddrPortFrom(flow.Dst, flow.DstPort).String() + " " + pcap.ProtocolName(flow.Protocol)
+			if got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	file := pcapFile(binary.LittleEndian, 101,
+		ipv4Packet(t, "10.0.0.1", "10.0.1.1", packet.ProtocolTCP, 49152, 443, 100),
+		ipv4Packet(t, "10.0.1.1", "10.0.0.1", packet.ProtocolTCP, 443, 49152, 1000),
+		ipv4Packet(t, "10.0.0.1", "10.0.0.2", packet.ProtocolUDP, 40000, 53, 20),
+	)
+	group := func(addr netip.Addr) netip.Prefix {
+		return netip.PrefixFrom(addr, 24).Masked()
+	}
+
+	summary, err := pcap.Summarize(bytes.NewReader(file), group)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Packets != 3 || summary.Bytes != 140+1040+48 {
+		t.Errorf("expected 3 packets and 1228 bytes, got %d and %d", summary.Packets, summary.Bytes)
+	}
+	if d := summary.Last.Sub(summary.First); d != 2*time.Second {
+		t.Errorf("expected a duration of 2s, got %v", d)
+	}
+
+	// The talkers, ports, conversations and subnets are ranked by bytes
+	if top := pcap.Top(summary.Talkers, 1); top[0].Key.String() != "10.0.0.1" || top[0].Packets != 3 {
+		t.Errorf("unexpected top talker: %+v", top)
+	}
+	if top := pcap.Top(summary.Ports, 0); len(top) != 2 || top[0].Key.String() != "443/tcp" || top[1].Key.String() != "53/udp" {
+		t.Errorf("unexpected ports: %+v", top)
+	}
+	if top := pcap.Top(summary.Conversations, 0); len(top) != 2 || top[0].Key.String() != "10.0.0.1:49152 <-> 10.0.1.1:443 (tcp)" || top[0].Packets != 2 {
+		t.Errorf("unexpected conversations: %+v", top)
+	}
+	if top := pcap.Top(summary.Subnets, 0); len(top) != 2 || top[0].Key.String() != "10.0.0.0/24" || top[0].Packets != 3 || top[1].Packets != 2 {
+		t.Errorf("unexpected subnets: %+v", top)
+	}
+	if c := summary.Protocols["udp"]; c == nil || c.Packets != 1 {
+		t.Errorf("unexpected protocols: %+v", summary.Protocols)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Link types of the captured packets (see https://www.tcpdump.org/linktypes.html)
+const (
+	LinkTypeNull      = 0
+	LinkTypeEthernet  = 1
+	LinkTypeRaw       = 101
+	LinkTypeLinuxSLL  = 113
+	LinkTypeIPv4      = 228
+	LinkTypeIPv6      = 229
+	LinkTypeLinuxSLL2 = 276
+)
+
+// Magic numbers of the capture file formats
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	magicNanoseconds  = 0xa1b23c4d
+	magicPcapNG       = 0x0a0d0d0a
+	magicByteOrder    = 0x1a2b3c4d
+)
+
+// Block types of the pcapng format
+const (
+	blockInterface      = 0x00000001
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+)
+
+// maxBlockLength limits the length of a packet or block, so that a corrupt
+// file does not cause huge allocations
+const maxBlockLength = 16 << 20
+
+// ErrFormat is returned when the file is not a pcap or pcapng capture
+var ErrFormat = errors.New("not a pcap or pcapng capture file")
+
+// Packet represents a packet in a capture file
+type Packet struct {
+	Time     time.Time
+	LinkType int
+	Data     []byte
+	Length   int
+}
+
+// ngInterface represents an interface described in a pcapng file
+type ngInterface struct {
+	linkType   int
+	resolution float64
+}
+
+// Reader reads the packets of a capture file in the classic pcap format
+// (microsecond or nanosecond timestamps, both byte orders) or the pcapng
+// format written by tcpdump, Wireshark and dumpcap
+type Reader struct {
+	r          *bufio.Reader
+	order      binary.ByteOrder
+	ng         bool
+	linkType   int
+	nano       bool
+	interfaces []ngInterface
+}
+
+// NewReader is a function that returns a reader for the capture file read
+// from r, the format is detected from the file header
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReaderSize(r, 1<<16)}
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.r, header[:4]); err != nil {
+		return nil, ErrFormat
+	}
+
+	// A pcapng file starts with a section header block
+	if binary.LittleEndian.Uint32(header) == magicPcapNG {
+		reader.ng = true
+		if err := reader.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	}
+
+	// A classic pcap file has a magic number in the byte order of the writer
+	switch {
+	case binary.LittleEndian.Uint32(header) == magicMicroseconds:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == magicMicroseconds:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == magicNanoseconds:
+		reader.order, reader.nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == magicNanoseconds:
+		reader.order, reader.nano = binary.BigEndian, true
+	default:
+		return nil, ErrFormat
+	}
+	if _, err := io.ReadFull(reader.r, header[4:]); err != nil {
+		return nil, fmt.Errorf("truncated pcap file header: %w", err)
+	}
+
+	// The upper bits of the link type carry the FCS length
+	reader.linkType = int(reader.order.Uint32(header[20:24]) & 0xffff)
+	return reader, nil
+}
+
+// Next is a function that returns the next packet of the capture, or
+// io.EOF at the end of the file
+func (r *Reader) Next() (Packet, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Packet{}, fmt.Errorf("truncated packet header: %w", err)
+		}
+		return Packet{}, err
+	}
+	seconds, fraction := r.order.Uint32(header[0:4]), r.order.Uint32(header[4:8])
+	captured, length := r.order.Uint32(header[8:12]), r.order.Uint32(header[12:16])
+	if captured > maxBlockLength {
+		return Packet{}, fmt.Errorf("invalid packet length: %d bytes", captured)
+	}
+
+	data := make([]byte, captured)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Packet{}, fmt.Errorf("truncated packet: %w", io.ErrUnexpectedEOF)
+	}
+	if !r.nano {
+		fraction *= 1000
+	}
+	return Packet{
+		Time:     time.Unix(int64(seconds), int64(fraction)),
+		LinkType: r.linkType,
+		Data:     data,
+		Length:   int(length),
+	}, nil
+}
+
+// readSectionHeader is a function that reads the rest of a pcapng section
+// header block (after the block type) and sets the byte order of the section
+func (r *Reader) readSectionHeader() error {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return fmt.Errorf("truncated pcapng section header: %w", err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header[4:8]) == magicByteOrder:
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[4:8]) == magicByteOrder:
+		r.order = binary.BigEndian
+	default:
+		return ErrFormat
+	}
+
+	// Skip the rest of the block, the interfaces of a new section start over
+	length := r.order.Uint32(header[0:4])
+	if length < 12 || length > maxBlockLength {
+		return fmt.Errorf("invalid pcapng block length: %d bytes", length)
+	}
+	r.interfaces = nil
+	_, err := r.r.Discard(int(length) - 12)
+	return err
+}
+
+// nextBlock is a function that reads the pcapng blocks until the next
+// packet block, the interface blocks are remembered and the other blocks
+// are skipped
+func (r *Reader) nextBlock() (Packet, error) {
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r.r, header[:4]); err != nil {
+			return Packet{}, err
+		}
+		if binary.LittleEndian.Uint32(header) == magicPcapNG {
+			if err := r.readSectionHeader(); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		if _, err := io.ReadFull(r.r, header[4:]); err != nil {
+			return Packet{}, fmt.Errorf("truncated pcapng block: %w", io.ErrUnexpectedEOF)
+		}
+		blockType, length := r.order.Uint32(header[0:4]), r.order.Uint32(header[4:8])
+		if length < 12 || length%4 != 0 || length > maxBlockLength {
+			return Packet{}, fmt.Errorf("invalid pcapng block length: %d bytes", length)
+		}
+
+		// Read the body of the block and the trailing block length
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r.r, body); err != nil {
+			return Packet{}, fmt.Errorf("truncated pcapng block: %w", io.ErrUnexpectedEOF)
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case blockInterface:
+			if len(body) < 8 {
+				return Packet{}, fmt.Errorf("invalid pcapng interface block")
+			}
+			r.interfaces = append(r.interfaces, ngInterface{
+				linkType:   int(r.order.Uint16(body[0:2])),
+				resolution: r.timestampResolution(body[8:]),
+			})
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return Packet{}, fmt.Errorf("invalid pcapng packet block")
+			}
+			id := int(r.order.Uint32(body[0:4]))
+			if id >= len(r.interfaces) {
+				return Packet{}, fmt.Errorf("pcapng packet block of unknown interface %d", id)
+			}
+			timestamp := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+			captured, length := int(r.order.Uint32(body[12:16])), int(r.order.Uint32(body[16:20]))
+			if captured > len(body)-20 {
+				return Packet{}, fmt.Errorf("invalid pcapng packet length: %d bytes", captured)
+			}
+			return Packet{
+				Time:     r.timestamp(timestamp, r.interfaces[id].resolution),
+				LinkType: r.interfaces[id].linkType,
+				Data:     body[20 : 20+captured],
+				Length:   length,
+			}, nil
+		case blockSimplePacket:
+			if len(body) < 4 || len(r.interfaces) == 0 {
+				return Packet{}, fmt.Errorf("invalid pcapng simple packet block")
+			}
+			length := int(r.order.Uint32(body[0:4]))
+			data := body[4:]
+			if length < len(data) {
+				data = data[:length]
+			}
+			return Packet{LinkType: r.interfaces[0].linkType, Data: data, Length: length}, nil
+		}
+	}
+}
+
+// timestampResolution is a function that returns the resolution of the
+// timestamps of an interface in seconds, from the if_tsresol option of the
+// interface block (microseconds by default)
+func (r *Reader) timestampResolution(options []byte) float64 {
+	for len(options) >= 4 {
+		code, length := r.order.Uint16(options[0:2]), int(r.order.Uint16(options[2:4]))
+		if code == 0 || len(options) < 4+length {
+			break
+		}
+		if code == 9 && length >= 1 {
+			// The high bit selects a power of two instead of a power of ten
+			value := options[4]
+			if value&0x80 != 0 {
+				return math.Pow(2, -float64(value&0x7f))
+			}
+			return math.Pow(10, -float64(value))
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+	return 1e-6
+}
+
+// timestamp is a function that converts a pcapng timestamp in units of the
+// given resolution to a time
+func (r *Reader) timestamp(units uint64, resolution float64) time.Time {
+	perSecond := uint64(math.Round(1 / resolution))
+	if perSecond == 0 {
+		return time.Unix(0, 0)
+	}
+	seconds, fraction := units/perSecond, units%perSecond
+	return time.Unix(int64(seconds), int64(float64(fraction)*resolution*1e9))
+}
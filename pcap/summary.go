@@ -0,0 +1,208 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pcap
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// Counter counts the packets and bytes of a talker, protocol, port,
+// conversation or subnet
+type Counter struct {
+	Packets int   `json:"packets"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// add is a function that counts a packet of the given length
+func (c *Counter) add(length int) {
+	c.Packets++
+	c.Bytes += int64(length)
+}
+
+// Port represents a port of a transport protocol (e.g. 443/tcp)
+type Port struct {
+	Number   uint16
+	Protocol uint8
+}
+
+// String is a function that returns the port in the format 443/tcp
+func (p Port) String() string {
+	return fmt.Sprintf("%d/%s", p.Number, ProtocolName(p.Protocol))
+}
+
+// Conversation represents the traffic between two endpoints in both
+// directions. The endpoints are ordered, A is the lower address.
+type Conversation struct {
+	A        netip.Addr
+	B        netip.Addr
+	Protocol uint8
+	PortA    uint16
+	PortB    uint16
+}
+
+// String is a function that returns the conversation in the format
+// 10.0.0.1:49152 <-> 10.0.0.2:443 (tcp)
+func (c Conversation) String() string {
+	if c.PortA == 0 && c.PortB == 0 {
+		return fmt.Sprintf("%s <-> %s (%s)", c.A, c.B, ProtocolName(c.Protocol))
+	}
+	return fmt.Sprintf("%s <-> %s (%s)",
+		netip.AddrPortFrom(c.A, c.PortA), netip.AddrPortFrom(c.B, c.PortB), ProtocolName(c.Protocol))
+}
+
+// Summary holds the statistics of the packets of a capture
+type Summary struct {
+	Packets       int
+	Bytes         int64
+	NonIP         int
+	First         time.Time
+	Last          time.Time
+	Talkers       map[netip.Addr]*Counter
+	Protocols     map[string]*Counter
+	Ports         map[Port]*Counter
+	Conversations map[Conversation]*Counter
+	Subnets       map[netip.Prefix]*Counter
+
+	// group returns the subnet of an address
+	group func(netip.Addr) netip.Prefix
+}
+
+// NewSummary is a function that returns an empty summary, the traffic of
+// the addresses is grouped into the subnets returned by group
+func NewSummary(group func(netip.Addr) netip.Prefix) *Summary {
+	return &Summary{
+		Talkers:       make(map[netip.Addr]*Counter),
+		Protocols:     make(map[string]*Counter),
+		Ports:         make(map[Port]*Counter),
+		Conversations: make(map[Conversation]*Counter),
+		Subnets:       make(map[netip.Prefix]*Counter),
+		group:         group,
+	}
+}
+
+// count is a function that counts a packet in the counter of a key
+func count[K comparable](m map[K]*Counter, key K, length int) {
+	c, ok := m[key]
+	if !ok {
+		c = &Counter{}
+		m[key] = c
+	}
+	c.add(length)
+}
+
+// Add is a function that adds a packet to the summary. The original length
+// of the packet is counted, also when the capture is truncated (snaplen).
+func (s *Summary) Add(p Packet) {
+	s.Packets++
+	s.Bytes += int64(p.Length)
+	if !p.Time.IsZero() {
+		if s.First.IsZero() || p.Time.Before(s.First) {
+			s.First = p.Time
+		}
+		if p.Time.After(s.Last) {
+			s.Last = p.Time
+		}
+	}
+
+	flow, ok := Decode(p.LinkType, p.Data)
+	if !ok {
+		s.NonIP++
+		count(s.Protocols, "non-ip", p.Length)
+		return
+	}
+	count(s.Protocols, ProtocolName(flow.Protocol), p.Length)
+
+	// Both endpoints are talkers, a packet is counted once per subnet
+	count(s.Talkers, flow.Src, p.Length)
+	count(s.Talkers, flow.Dst, p.Length)
+	if s.group != nil {
+		src, dst := s.group(flow.Src), s.group(flow.Dst)
+		count(s.Subnets, src, p.Length)
+		if dst != src {
+			count(s.Subnets, dst, p.Length)
+		}
+	}
+
+	// The service port is the lower port, clients use ephemeral ports
+	if flow.HasPorts() {
+		count(s.Ports, Port{Number: min(flow.SrcPort, flow.DstPort), Protocol: flow.Protocol}, p.Length)
+	}
+
+	// Order the endpoints of the conversation
+	c := Conversation{A: flow.Src, B: flow.Dst, Protocol: flow.Protocol, PortA: flow.SrcPort, PortB: flow.DstPort}
+	if c.B.Less(c.A) || (c.A == c.B && c.PortB < c.PortA) {
+		c.A, c.B, c.PortA, c.PortB = c.B, c.A, c.PortB, c.PortA
+	}
+	count(s.Conversations, c, p.Length)
+}
+
+// Summarize is a function that reads all packets of a capture file and
+// returns their summary
+func Summarize(r io.Reader, group func(netip.Addr) netip.Prefix) (*Summary, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	summary := NewSummary(group)
+	for {
+		p, err := reader.Next()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Add(p)
+	}
+}
+
+// Ranked represents a key of a summary with its counter
+type Ranked[K comparable] struct {
+	Key K
+	Counter
+}
+
+// Top is a function that returns the n keys of a summary with the most
+// bytes (then packets) in descending order, or all keys if n is 0
+func Top[K comparable](m map[K]*Counter, n int) []Ranked[K] {
+	list := make([]Ranked[K], 0, len(m))
+	for key, c := range m {
+		list = append(list, Ranked[K]{Key: key, Counter: *c})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		if list[i].Packets != list[j].Packets {
+			return list[i].Packets > list[j].Packets
+		}
+		return fmt.Sprint(list[i].Key) < fmt.Sprint(list[j].Key)
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,268 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package plan
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity levels of the findings reported by Check
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Plan represents an address plan, a list of parent blocks that are
+// divided into child allocations
+type Plan struct {
+	Blocks []Block `yaml:"blocks"`
+}
+
+// Block represents a parent block in the address plan
+type Block struct {
+	Prefix      string       `yaml:"prefix"`
+	Name        string       `yaml:"name"`
+	Allocations []Allocation `yaml:"allocations"`
+}
+
+// Allocation represents a child allocation inside a parent block
+type Allocation struct {
+	Prefix string `yaml:"prefix"`
+	Name   string `yaml:"name"`
+}
+
+// Finding represents a problem found in the address plan
+type Finding struct {
+	Severity string
+	Message  string
+}
+
+// String is a function that returns the finding as a human readable string
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+}
+
+// Load is a function that reads an address plan from a YAML file
+func Load(filename string) (*Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse is a function that parses an address plan in YAML format. An example
+// of the format:
+//
+//	blocks:
+//	  - prefix: 10.0.0.0/16
+//	    name: datacenter
+//	    allocations:
+//	      - prefix: 10.0.0.0/24
+//	        name: servers
+func Parse(data []byte) (*Plan, error) {
+	var p Plan
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid address plan: %w", err)
+	}
+	return &p, nil
+}
+
+// entry is a parsed prefix together with a description of where it is
+// found in the address plan
+type entry struct {
+	prefix netip.Prefix
+	label  string
+}
+
+// Check is a function that validates the address plan p and returns the list
+// of findings. Duplicates, overlaps, invalid prefixes and allocations outside
+// of their parent block are reported as errors, while unallocated space
+// (gaps) in a parent block is reported as a warning.
+func Check(p *Plan) []Finding {
+	var findings []Finding
+	errorf := func(format string, a ...any) {
+		findings = append(findings, Finding{Severity: SeverityError, Message: fmt.Sprintf(format, a...)})
+	}
+
+	// Parse and validate the parent blocks
+	var blocks []entry
+	for i, b := range p.Blocks {
+		label := describe(b.Prefix, b.Name)
+		prefix, err := parsePrefix(b.Prefix)
+		if err != nil {
+			errorf("block #%d %s: %v", i+1, label, err)
+			continue
+		}
+		blocks = append(blocks, entry{prefix: prefix, label: "block " + label})
+
+		// Parse and validate the allocations of the block
+		var allocations []entry
+		for j, a := range b.Allocations {
+			label := describe(a.Prefix, a.Name)
+			child, err := parsePrefix(a.Prefix)
+			if err != nil {
+				errorf("allocation #%d %s in block %s: %v", j+1, label, describe(b.Prefix, b.Name), err)
+				continue
+			}
+
+			// The allocation must be inside its parent block
+			if !contains(prefix, child) {
+				errorf("allocation %s is outside of its parent block %s", label, describe(b.Prefix, b.Name))
+				continue
+			}
+			allocations = append(allocations, entry{prefix: child, label: "allocation " + label})
+		}
+
+		findings = append(findings, checkOverlaps(allocations)...)
+		findings = append(findings, checkGaps(prefix, describe(b.Prefix, b.Name), allocations)...)
+	}
+
+	// Parent blocks must not overlap each other
+	findings = append(findings, checkOverlaps(blocks)...)
+
+	return findings
+}
+
+// HasErrors is a function that checks if any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOverlaps is a function that reports duplicates and overlapping
+// prefixes in a list of entries
+func checkOverlaps(entries []entry) []Finding {
+	var findings []Finding
+
+	sortEntries(entries)
+	for i := 0; i < len(entries); i++ {
+		for j := i + 1; j < len(entries); j++ {
+			a, b := entries[i], entries[j]
+
+			// The entries are sorted, no later entry can overlap when this one does not
+			if !a.prefix.Overlaps(b.prefix) {
+				break
+			}
+
+			if a.prefix == b.prefix {
+				findings = append(findings, Finding{Severity: SeverityError, Message: fmt.Sprintf("duplicate prefix: %s and %s", a.label, b.label)})
+			} else {
+				findings = append(findings, Finding{Severity: SeverityError, Message: fmt.Sprintf("overlapping prefixes: %s and %s", a.label, b.label)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// checkGaps is a function that reports the address ranges in the parent
+// block that are not covered by any allocation
+func checkGaps(parent netip.Prefix, label string, allocations []entry) []Finding {
+	var findings []Finding
+
+	// Blocks without allocations are not considered to have gaps
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	sortEntries(allocations)
+
+	// Walk the allocations in order and report the space between them
+	next := parent.Addr()
+	for _, a := range allocations {
+		if next.IsValid() && next.Less(a.prefix.Addr()) {
+			findings = append(findings, gap(label, next, a.prefix.Addr().Prev()))
+		}
+
+		// Skip ahead to the address after the allocation (invalid at the end of the address space)
+		if last := lastAddr(a.prefix); next.IsValid() && !last.Less(next) {
+			next = last.Next()
+		}
+	}
+	if last := lastAddr(parent); next.IsValid() && !last.Less(next) {
+		findings = append(findings, gap(label, next, last))
+	}
+
+	return findings
+}
+
+// gap is a function that returns a warning about unallocated space in a block
+func gap(label string, first, last netip.Addr) Finding {
+	return Finding{Severity: SeverityWarning, Message: fmt.Sprintf("unallocated space in block %s: %s-%s", label, first, last)}
+}
+
+// parsePrefix is a function that parses a prefix in CIDR notation and makes
+// sure that the prefix is a network address (no host bits set)
+func parsePrefix(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix: %s", s)
+	}
+	if prefix.Masked() != prefix {
+		return netip.Prefix{}, fmt.Errorf("host bits set in prefix %s (did you mean %s?)", s, prefix.Masked())
+	}
+	return prefix, nil
+}
+
+// contains is a function that checks if the child prefix is inside the parent prefix
+func contains(parent, child netip.Prefix) bool {
+	return parent.Bits() <= child.Bits() && parent.Contains(child.Addr())
+}
+
+// lastAddr is a function that returns the last address in a prefix
+func lastAddr(p netip.Prefix) netip.Addr {
+	addr := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(addr)*8; i++ {
+		addr[i/8] |= 0x80 >> (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+// sortEntries is a function that sorts the entries by address and prefix length
+func sortEntries(entries []entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].prefix, entries[j].prefix
+		if a.Addr() != b.Addr() {
+			return a.Addr().Less(b.Addr())
+		}
+		return a.Bits() < b.Bits()
+	})
+}
+
+// describe is a function that returns the prefix with its name (if any)
+func describe(prefix, name string) string {
+	if name == "" {
+		return prefix
+	}
+	return fmt.Sprintf("%s (%s)", prefix, name)
+}
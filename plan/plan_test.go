@@ -0,0 +1,141 @@
+package plan_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/plan"
+)
+
+func TestCheck(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name             string
+		input            string
+		expectedErrors   []string
+		expectedWarnings []string
+	}{
+		{
+			name: "Valid",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/24
+    allocations:
+      - prefix: 10.0.0.0/25
+      - prefix: 10.0.0.128/25
+  - prefix: 2001:db8::/48
+    allocations:
+      - prefix: 2001:db8::/49
+      - prefix: 2001:db8:0:8000::/49`,
+		},
+		{
+			name: "Duplicate",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/24
+    allocations:
+      - {prefix: 10.0.0.0/24, name: a}
+      - {prefix: 10.0.0.0/24, name: b}`,
+			expectedErrors: []string{"duplicate prefix: allocation 10.0.0.0/24 (a) and allocation 10.0.0.0/24 (b)"},
+		},
+		{
+			name: "Overlap",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/24
+    allocations:
+      - prefix: 10.0.0.0/25
+      - prefix: 10.0.0.64/26
+      - prefix: 10.0.0.128/25`,
+			expectedErrors: []string{"overlapping prefixes: allocation 10.0.0.0/25 and allocation 10.0.0.64/26"},
+		},
+		{
+			name: "OutsideParent",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/25
+    allocations:
+      - prefix: 10.0.0.0/24`,
+			expectedErrors: []string{"allocation 10.0.0.0/24 is outside of its parent block 10.0.0.0/25"},
+		},
+		{
+			name: "Gaps",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/24
+    name: lab
+    allocations:
+      - prefix: 10.0.0.64/26
+      - prefix: 10.0.0.192/27`,
+			expectedWarnings: []string{
+				"unallocated space in block 10.0.0.0/24 (lab): 10.0.0.0-10.0.0.63",
+				"unallocated space in block 10.0.0.0/24 (lab): 10.0.0.128-10.0.0.191",
+				"unallocated space in block 10.0.0.0/24 (lab): 10.0.0.224-10.0.0.255",
+			},
+		},
+		{
+			name: "EndOfAddressSpace",
+			input: `
+blocks:
+  - prefix: 0.0.0.0/0
+    allocations:
+      - prefix: 128.0.0.0/1`,
+			expectedWarnings: []string{"unallocated space in block 0.0.0.0/0: 0.0.0.0-127.255.255.255"},
+		},
+		{
+			name: "OverlappingBlocks",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/16
+  - prefix: 10.0.128.0/17`,
+			expectedErrors: []string{"overlapping prefixes: block 10.0.0.0/16 and block 10.0.128.0/17"},
+		},
+		{
+			name: "InvalidPrefix",
+			input: `
+blocks:
+  - prefix: 10.0.0.0/33
+  - prefix: 10.0.1.1/24`,
+			expectedErrors: []string{
+				"block #1 10.0.0.0/33: invalid prefix: 10.0.0.0/33",
+				"block #2 10.0.1.1/24: host bits set in prefix 10.0.1.1/24 (did you mean 10.0.1.0/24?)",
+			},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := plan.Parse([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var errs, warnings []string
+			findings := plan.Check(p)
+			for _, f := range findings {
+				if f.Severity == plan.SeverityError {
+					errs = append(errs, f.Message)
+				} else {
+					warnings = append(warnings, f.Message)
+				}
+			}
+
+			if strings.Join(errs, "\n") != strings.Join(tc.expectedErrors, "\n") {
+				t.Errorf("expected errors %q, got %q", tc.expectedErrors, errs)
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tc.expectedWarnings, "\n") {
+				t.Errorf("expected warnings %q, got %q", tc.expectedWarnings, warnings)
+			}
+			if plan.HasErrors(findings) != (len(tc.expectedErrors) > 0) {
+				t.Errorf("expected HasErrors %t", len(tc.expectedErrors) > 0)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := plan.Parse([]byte("blocks: [")); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/privsep"
+)
+
+// Prefix is the prefix of the names of plugin executables, the plugin for
+// "iptool foo" is the executable iptool-foo (iptool-foo.exe on Windows)
+const Prefix = "iptool-"
+
+// APIVersion is the version of the plugin contract, see docs/plugins.md
+const APIVersion = 1
+
+// InfoFlag is the flag iptool uses to ask a plugin for its metadata
+const InfoFlag = "--iptool-plugin-info"
+
+// ContextEnv is the environment variable holding the plugin context (JSON)
+const ContextEnv = "IPTOOL_PLUGIN_CONTEXT"
+
+// infoTimeout is the time a plugin is given to print its metadata
+const infoTimeout = 2 * time.Second
+
+// ErrNotFound is returned when there is no plugin with the given name
+var ErrNotFound = errors.New("plugin not found")
+
+// Plugin is a plugin executable found in the PATH
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Info is the metadata a plugin prints in JSON format when it is run with
+// the --iptool-plugin-info flag
+type Info struct {
+	Name       string `json:"name"`
+	Short      string `json:"short"`
+	Version    string `json:"version,omitempty"`
+	APIVersion int    `json:"api_version"`
+}
+
+// Context is the information passed to a plugin in JSON format in the
+// IPTOOL_PLUGIN_CONTEXT environment variable
+type Context struct {
+	APIVersion int    `json:"api_version"`
+	Version    string `json:"iptool_version"`
+	Executable string `json:"iptool_executable"`
+	ConfigFile string `json:"config_file,omitempty"`
+	Plugin     string `json:"plugin"`
+}
+
+// ValidName is a function that checks if a name can be the name of a
+// plugin. Names starting with a dash (flags), containing path separators
+// and names reserved for executables shipped with iptool are not valid.
+func ValidName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	return Prefix+name != privsep.HelperName
+}
+
+// Find is a function that returns the plugin with the given name
+func Find(name string) (Plugin, error) {
+	if !ValidName(name) {
+		return Plugin{}, ErrNotFound
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, ErrNotFound
+	}
+	return Plugin{Name: name, Path: path}, nil
+}
+
+// List is a function that returns the plugins found in the PATH, sorted by
+// name. Only the first plugin with a name is returned, as for commands.
+func List() []Plugin {
+	seen := make(map[string]bool)
+	var plugins []Plugin
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), Prefix)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				if name, ok = strings.CutSuffix(name, ".exe"); !ok {
+					continue
+				}
+			}
+			if seen[name] || !ValidName(name) || !isExecutable(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+// Info is a function that runs the plugin with the --iptool-plugin-info
+// flag and returns the metadata printed by the plugin
+func (p Plugin) Info() (Info, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, p.Path, InfoFlag).Output()
+	if err != nil {
+		return Info{}, fmt.Errorf("%s %s: %w", p.Path, InfoFlag, err)
+	}
+	var info Info
+	if err := json.Unmarshal(output, &info); err != nil {
+		return Info{}, fmt.Errorf("invalid plugin info from %s: %w", p.Path, err)
+	}
+	return info, nil
+}
+
+// Command is a function that returns the command that runs the plugin with
+// the given arguments, standard streams and context
+func (p Plugin) Command(args []string, c Context) (*exec.Cmd, error) {
+	c.APIVersion = APIVersion
+	c.Plugin = p.Name
+	contextJSON, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(p.Path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), ContextEnv+"="+string(contextJSON))
+	return cmd, nil
+}
+
+// isExecutable is a function that checks if a file is an executable file
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
@@ -0,0 +1,142 @@
+package plugin_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/plugin"
+)
+
+// writePlugin is a helper that writes a shell script plugin to the directory
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), mode); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// setupPlugins is a helper that creates two PATH directories with plugins
+func setupPlugins(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins are not supported on Windows")
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "iptool-hello", `[ "$1" = "--iptool-plugin-info" ] && echo '{"name":"hello","short":"Say hello","version":"1.0.0","api_version":1}' && exit 0
+echo "$IPTOOL_PLUGIN_CONTEXT"
+`, 0755)
+	writePlugin(t, second, "iptool-hello", "echo shadowed\n", 0755)
+	writePlugin(t, second, "iptool-ipam", "echo '{'\n", 0755)
+	writePlugin(t, second, "iptool-helper", "exit 0\n", 0755)
+	writePlugin(t, second, "iptool-notexec", "exit 0\n", 0644)
+	writePlugin(t, second, "other-tool", "exit 0\n", 0755)
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	return first, second
+}
+
+func TestValidName(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{name: "ipam", expected: true},
+		{name: "foo-bar", expected: true},
+		{name: "", expected: false},
+		{name: "--help", expected: false},
+		{name: "../foo", expected: false},
+		{name: `foo\bar`, expected: false},
+		{name: "helper", expected: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := plugin.ValidName(tc.name); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	first, second := setupPlugins(t)
+
+	expected := []plugin.Plugin{
+		{Name: "hello", Path: filepath.Join(first, "iptool-hello")},
+		{Name: "ipam", Path: filepath.Join(second, "iptool-ipam")},
+	}
+	if got := plugin.List(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	first, _ := setupPlugins(t)
+
+	p, err := plugin.Find("hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Path != filepath.Join(first, "iptool-hello") {
+		t.Errorf("expected the first plugin in the PATH, got %s", p.Path)
+	}
+
+	for _, name := range []string{"missing", "helper", "notexec"} {
+		if _, err := plugin.Find(name); err != plugin.ErrNotFound {
+			t.Errorf("expected ErrNotFound for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	setupPlugins(t)
+
+	p, _ := plugin.Find("hello")
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := plugin.Info{Name: "hello", Short: "Say hello", Version: "1.0.0", APIVersion: 1}
+	if info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	// Invalid JSON is reported as an error
+	p, _ = plugin.Find("ipam")
+	if _, err := p.Info(); err == nil {
+		t.Errorf("expected error for invalid plugin info, got nil")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	setupPlugins(t)
+
+	p, _ := plugin.Find("hello")
+	cmd, err := p.Command(nil, plugin.Context{Version: "1.2.0", ConfigFile: "/etc/iptool.yaml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd.Stdout = nil
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The plugin receives the context in JSON format
+	var c plugin.Context
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(output))), &c); err != nil {
+		t.Fatalf("invalid context %q: %v", output, err)
+	}
+	expected := plugin.Context{APIVersion: plugin.APIVersion, Version: "1.2.0", ConfigFile: "/etc/iptool.yaml", Plugin: "hello"}
+	if c != expected {
+		t.Errorf("expected %+v, got %+v", expected, c)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package port
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// servicesCSV is the embedded database of well-known and registered ports
+//
+//go:embed services.csv
+var servicesCSV string
+
+// Protocols of the services in the database
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolSCTP = "sctp"
+)
+
+// Service represents a service name assigned to a port and protocol
+type Service struct {
+	Port        int      `json:"port"`
+	Protocol    string   `json:"protocol"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description"`
+}
+
+// services holds the parsed database, parsed on first use
+var (
+	services     []Service
+	servicesOnce sync.Once
+)
+
+// Services is a function that returns all services in the embedded
+// database, sorted by port and protocol
+func Services() []Service {
+	servicesOnce.Do(func() {
+		services = parseServices(servicesCSV)
+	})
+	return services
+}
+
+// parseServices is a function that parses the services database. Lines
+// starting with # are comments, invalid lines are skipped.
+func parseServices(data string) []Service {
+	var list []Service
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ",", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		port, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		list = append(list, Service{
+			Port:        port,
+			Protocol:    fields[1],
+			Name:        fields[2],
+			Aliases:     strings.Fields(fields[3]),
+			Description: fields[4],
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Port != list[j].Port {
+			return list[i].Port < list[j].Port
+		}
+		return list[i].Protocol < list[j].Protocol
+	})
+	return list
+}
+
+// matchProtocol is a function that checks if the service uses the protocol,
+// an empty protocol matches all services
+func (s Service) matchProtocol(protocol string) bool {
+	return protocol == "" || strings.EqualFold(s.Protocol, protocol)
+}
+
+// HasName is a function that checks if the name or one of the aliases of
+// the service is name (case insensitive)
+func (s Service) HasName(name string) bool {
+	if strings.EqualFold(s.Name, name) {
+		return true
+	}
+	for _, alias := range s.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePortRange is a function that parses a port (443) or a range of
+// ports (8000-8100) and returns the first and last port
+func ParsePortRange(s string) (int, int, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		last = first
+	}
+	from, err1 := strconv.Atoi(first)
+	to, err2 := strconv.Atoi(last)
+	if err1 != nil || err2 != nil || from < 0 || to > 65535 || from > to {
+		return 0, 0, fmt.Errorf("invalid port or port range: %s (ports must be between 0 and 65535)", s)
+	}
+	return from, to, nil
+}
+
+// IsNumeric is a function that checks if the query is a port or a range
+// of ports rather than a service name
+func IsNumeric(query string) bool {
+	return strings.TrimLeft(strings.TrimSpace(query), "0123456789-") == "" && strings.TrimSpace(query) != ""
+}
+
+// Lookup is a function that looks up a port (443), a range of ports
+// (8000-8100) or a service name or alias (https), optionally for a single
+// protocol only
+func Lookup(query, protocol string) ([]Service, error) {
+	var result []Service
+	if IsNumeric(query) {
+		from, to, err := ParsePortRange(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range Services() {
+			if s.Port >= from && s.Port <= to && s.matchProtocol(protocol) {
+				result = append(result, s)
+			}
+		}
+		return result, nil
+	}
+
+	for _, s := range Services() {
+		if s.HasName(query) && s.matchProtocol(protocol) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// Search is a function that returns the services with a name, alias or
+// description containing the term (case insensitive), optionally for a
+// single protocol only
+func Search(term, protocol string) []Service {
+	term = strings.ToLower(term)
+	var result []Service
+	for _, s := range Services() {
+		if !s.matchProtocol(protocol) {
+			continue
+		}
+		text := strings.ToLower(s.Name + " " + strings.Join(s.Aliases, " ") + " " + s.Description)
+		if strings.Contains(text, term) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
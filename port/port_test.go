@@ -0,0 +1,100 @@
+package port_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/port"
+)
+
+// serviceKeys is a helper that converts a list of services to port/protocol name strings
+func serviceKeys(services []port.Service) []string {
+	var keys []string
+	for _, s := range services {
+		keys = append(keys, fmt.Sprintf("%d/%s %s", s.Port, s.Protocol, s.Name))
+	}
+	return keys
+}
+
+func TestServices(t *testing.T) {
+	services := port.Services()
+	if len(services) < 100 {
+		t.Fatalf("expected at least 100 services, got %d", len(services))
+	}
+
+	// Every entry must be valid and the list sorted
+	for i, s := range services {
+		if s.Port < 0 || s.Port > 65535 || s.Name == "" || s.Description == "" {
+			t.Errorf("invalid service: %+v", s)
+		}
+		if s.Protocol != port.ProtocolTCP && s.Protocol != port.ProtocolUDP && s.Protocol != port.ProtocolSCTP {
+			t.Errorf("invalid protocol: %+v", s)
+		}
+		if i > 0 && services[i-1].Port > s.Port {
+			t.Errorf("services not sorted: %d before %d", services[i-1].Port, s.Port)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		query     string
+		protocol  string
+		expected  []string
+		expectErr bool
+	}{
+		{query: "443", expected: []string{"443/tcp https", "443/udp https"}},
+		{query: "443", protocol: "tcp", expected: []string{"443/tcp https"}},
+		{query: "https", protocol: "tcp", expected: []string{"443/tcp https"}},
+		{query: "RDP", protocol: "tcp", expected: []string{"3389/tcp ms-wbt-server"}},
+		{query: "dns", protocol: "udp", expected: []string{"53/udp domain"}},
+		{query: "5060-5061", protocol: "tcp", expected: []string{"5060/tcp sip", "5061/tcp sips"}},
+		{query: "1", protocol: "udp", expected: nil},
+		{query: "no-such-service", expected: nil},
+		{query: "70000", expectErr: true},
+		{query: "10-5", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.query+"/"+tc.protocol, func(t *testing.T) {
+			services, err := port.Lookup(tc.query, tc.protocol)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", services)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := serviceKeys(services); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		term     string
+		protocol string
+		expected []string
+	}{
+		{term: "radius", protocol: "udp", expected: []string{"1645/udp radius-old", "1646/udp radacct-old", "1812/udp radius", "1813/udp radius-acct"}},
+		{term: "POSTGRES", expected: []string{"5432/tcp postgresql"}},
+		{term: "nothing like this", expected: nil},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.term, func(t *testing.T) {
+			if got := serviceKeys(port.Search(tc.term, tc.protocol)); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package privsep
+
+import (
+	"os"
+	"syscall"
+)
+
+// DropPrivileges drops the privileges of the helper for good: the
+// supplementary groups are cleared and the user and group IDs are switched
+// back to the real IDs of the calling user (when it runs setuid/setgid), and
+// the capabilities granted to the executable (e.g. with setcap) are cleared.
+func DropPrivileges() error {
+	// Only root can change the supplementary groups, which a setuid root
+	// helper would otherwise keep
+	if os.Geteuid() == 0 {
+		if err := syscall.Setgroups([]int{}); err != nil {
+			return err
+		}
+	}
+	if gid := os.Getgid(); gid != os.Getegid() {
+		if err := syscall.Setgid(gid); err != nil {
+			return err
+		}
+	}
+	if uid := os.Getuid(); uid != os.Geteuid() {
+		if err := syscall.Setuid(uid); err != nil {
+			return err
+		}
+	}
+	return dropCapabilities()
+}
@@ -0,0 +1,218 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package privsep implements the narrow interface between iptool and the
+// privileged helper process (iptool-helper). Operations that require raw
+// sockets are performed by the helper, which can be installed setuid root or
+// with the CAP_NET_RAW capability, so that the main binary can stay
+// unprivileged. The helper reads a single JSON request on stdin, performs the
+// operation and writes a single JSON response on stdout.
+package privsep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HelperName is the name of the helper executable
+const HelperName = "iptool-helper"
+
+// Operations supported by the helper
+const (
+	// OpEchoAllNodes sends an ICMPv6 echo request to the all-nodes multicast
+	// address on an interface and returns the addresses that replied
+	OpEchoAllNodes = "icmp6-echo-all-nodes"
+
+	// OpCaptureDiscovery captures the LLDP and CDP frames received on an
+	// interface and returns them
+	OpCaptureDiscovery = "capture-discovery"
+)
+
+// maxTimeout is the longest timeout a request may ask for
+const maxTimeout = 60 * time.Second
+
+// maxCount is the largest number of neighbors a capture may wait for
+const maxCount = 1000
+
+var ErrHelperNotFound = errors.New(HelperName + " not found next to the executable or in PATH")
+
+// Request is a request sent to the helper
+type Request struct {
+	Op        string `json:"op"`
+	Interface string `json:"interface,omitempty"`
+	Source    string `json:"source,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+	Count     int    `json:"count,omitempty"`
+}
+
+// Response is the response returned by the helper
+type Response struct {
+	Addresses []string `json:"addresses,omitempty"`
+	Frames    [][]byte `json:"frames,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Timeout returns the timeout of the request as a duration
+func (r Request) Timeout() time.Duration {
+	return time.Duration(r.TimeoutMs) * time.Millisecond
+}
+
+// SourceAddress returns the source address of the request, or nil if the
+// request has no source address. Only IPv6 addresses are accepted.
+func (r Request) SourceAddress() (*net.IPAddr, error) {
+	if r.Source == "" {
+		return nil, nil
+	}
+	address, zone, _ := strings.Cut(r.Source, "%")
+	addr := net.ParseIP(address)
+	if addr == nil || addr.To4() != nil {
+		return nil, fmt.Errorf("invalid source address: %q", r.Source)
+	}
+	return &net.IPAddr{IP: addr, Zone: zone}, nil
+}
+
+// Validate checks that the request is well-formed before the helper acts on it.
+// The helper runs with elevated privileges, so every field is checked strictly.
+func (r Request) Validate() error {
+	switch r.Op {
+	case OpEchoAllNodes:
+		if _, err := net.InterfaceByName(r.Interface); err != nil {
+			return fmt.Errorf("invalid interface: %q", r.Interface)
+		}
+		if r.Source != "" {
+			if _, err := r.SourceAddress(); err != nil {
+				return err
+			}
+		}
+		if r.Timeout() <= 0 || r.Timeout() > maxTimeout {
+			return fmt.Errorf("invalid timeout: %d ms (must be between 1 and %d)", r.TimeoutMs, maxTimeout.Milliseconds())
+		}
+	case OpCaptureDiscovery:
+		if _, err := net.InterfaceByName(r.Interface); err != nil {
+			return fmt.Errorf("invalid interface: %q", r.Interface)
+		}
+		if r.Timeout() <= 0 || r.Timeout() > maxTimeout {
+			return fmt.Errorf("invalid timeout: %d ms (must be between 1 and %d)", r.TimeoutMs, maxTimeout.Milliseconds())
+		}
+		if r.Count < 0 || r.Count > maxCount {
+			return fmt.Errorf("invalid count: %d (must be between 0 and %d)", r.Count, maxCount)
+		}
+	default:
+		return fmt.Errorf("unsupported operation: %q", r.Op)
+	}
+	return nil
+}
+
+// FindHelper returns the path to the helper executable. The directory of the
+// running executable is searched first, then the directories in PATH.
+func FindHelper() (string, error) {
+	if exe, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(exe), HelperName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	if path, err := exec.LookPath(HelperName); err == nil {
+		return path, nil
+	}
+	return "", ErrHelperNotFound
+}
+
+// Call sends the request to the helper process and returns its response
+func Call(req Request) (*Response, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	path, err := FindHelper()
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode the request
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the helper, allowing some extra time on top of the request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), req.Timeout()+5*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	helper := exec.CommandContext(ctx, path)
+	helper.Stdin = bytes.NewReader(input)
+	helper.Stdout = &stdout
+	helper.Stderr = &stderr
+	if err := helper.Run(); err != nil && stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s failed: %w %s", HelperName, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	// Decode the response
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", HelperName, err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return &resp, nil
+}
+
+// Handler performs a single validated operation in the helper process
+type Handler func(req Request) (*Response, error)
+
+// Serve reads a single request from r, dispatches it to the handler of the
+// operation and writes the response to w
+func Serve(r io.Reader, w io.Writer, handlers map[string]Handler) error {
+	var req Request
+	resp := &Response{}
+
+	// Limit the size of the request, a valid request is tiny
+	err := json.NewDecoder(io.LimitReader(r, 4096)).Decode(&req)
+	if err == nil {
+		err = req.Validate()
+	}
+	if err == nil {
+		handler, ok := handlers[req.Op]
+		if !ok {
+			err = fmt.Errorf("unsupported operation: %q", req.Op)
+		} else {
+			resp, err = handler(req)
+		}
+	}
+	if err != nil {
+		resp = &Response{Error: err.Error()}
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,69 @@
+package privsep_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/privsep"
+)
+
+// TestServe tests that the helper only dispatches valid requests
+func TestServe(t *testing.T) {
+	// A handler that returns a fixed address
+	handlers := map[string]privsep.Handler{
+		privsep.OpEchoAllNodes: func(req privsep.Request) (*privsep.Response, error) {
+			return &privsep.Response{Addresses: []string{"fe80::1"}}, nil
+		},
+	}
+
+	// Use the first interface of the machine in the requests
+	interfaces, err := net.Interfaces()
+	if err != nil || len(interfaces) == 0 {
+		t.Skip("no network interfaces available")
+	}
+	iface := interfaces[0].Name
+
+	// Setup test cases
+	testCases := []struct {
+		name          string
+		input         string
+		expectedError string
+		expectedAddr  string
+	}{
+		{name: "ValidRequest", input: fmt.Sprintf(`{"op":"icmp6-echo-all-nodes","interface":%q,"timeout_ms":100}`, iface), expectedAddr: "fe80::1"},
+		{name: "UnknownOperation", input: fmt.Sprintf(`{"op":"shell","interface":%q,"timeout_ms":100}`, iface), expectedError: "unsupported operation"},
+		{name: "UnknownInterface", input: `{"op":"icmp6-echo-all-nodes","interface":"nope0","timeout_ms":100}`, expectedError: "invalid interface"},
+		{name: "TimeoutTooLong", input: fmt.Sprintf(`{"op":"icmp6-echo-all-nodes","interface":%q,"timeout_ms":3600000}`, iface), expectedError: "invalid timeout"},
+		{name: "ValidSource", input: fmt.Sprintf(`{"op":"icmp6-echo-all-nodes","interface":%q,"source":"fe80::2%%%s","timeout_ms":100}`, iface, iface), expectedAddr: "fe80::1"},
+		{name: "IPv4Source", input: fmt.Sprintf(`{"op":"icmp6-echo-all-nodes","interface":%q,"source":"192.0.2.1","timeout_ms":100}`, iface), expectedError: "invalid source address"},
+		{name: "InvalidSource", input: fmt.Sprintf(`{"op":"icmp6-echo-all-nodes","interface":%q,"source":"eth0; reboot","timeout_ms":100}`, iface), expectedError: "invalid source address"},
+		{name: "CaptureCountTooLarge", input: fmt.Sprintf(`{"op":"capture-discovery","interface":%q,"timeout_ms":100,"count":100000}`, iface), expectedError: "invalid count"},
+		{name: "CaptureTimeoutTooLong", input: fmt.Sprintf(`{"op":"capture-discovery","interface":%q,"timeout_ms":3600000}`, iface), expectedError: "invalid timeout"},
+		{name: "MalformedJSON", input: `{"op":`, expectedError: "unexpected EOF"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := privsep.Serve(strings.NewReader(tc.input), &out, handlers); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp privsep.Response
+			if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if !strings.Contains(resp.Error, tc.expectedError) || (tc.expectedError == "" && resp.Error != "") {
+				t.Errorf("expected error containing %q, got %q", tc.expectedError, resp.Error)
+			}
+			if tc.expectedAddr != "" && (len(resp.Addresses) != 1 || resp.Addresses[0] != tc.expectedAddr) {
+				t.Errorf("expected address %q, got %v", tc.expectedAddr, resp.Addresses)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// dnsProber measures the time it takes to resolve a name using the system
+// resolver. The result is never cached, every probe sends a query.
+type dnsProber struct {
+	name string
+}
+
+func init() {
+	Register("dns", func(address string) (Prober, error) {
+		return &dnsProber{name: address}, nil
+	})
+}
+
+// Probe is a function that resolves the name and returns the time it took
+func (p *dnsProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	if ip.LookupsDisabled() {
+		return 0, ip.ErrLookupsDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Start the timer
+	start := time.Now()
+
+	if _, err := net.DefaultResolver.LookupHost(ctx, p.name); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// String is a function that returns the target of the prober
+func (p *dnsProber) String() string {
+	return "dns://" + p.name
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// httpProber measures the time it takes to receive the response headers
+// of a HEAD request. Any response (even an error status) counts as a reply.
+type httpProber struct {
+	url string
+}
+
+func init() {
+	Register("http", func(address string) (Prober, error) {
+		return newHTTPProber("http://" + address)
+	})
+	Register("https", func(address string) (Prober, error) {
+		return newHTTPProber("https://" + address)
+	})
+}
+
+// newHTTPProber is a function that returns an HTTP prober for a URL
+func newHTTPProber(rawURL string) (Prober, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %s", rawURL)
+	}
+	return &httpProber{url: u.String()}, nil
+}
+
+// Probe is a function that sends a HEAD request to the target and returns
+// the time it took to receive the response
+func (p *httpProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil && ip.LookupsDisabled() {
+		return 0, ip.ErrLookupsDisabled
+	}
+
+	// Do not reuse connections, every probe should include the handshake
+	req.Close = true
+
+	// Start the timer
+	start := time.Now()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return rtt, nil
+}
+
+// String is a function that returns the target of the prober
+func (p *httpProber) String() string {
+	return p.url
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/packet"
+)
+
+// ICMP message types of echo requests and replies
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+var ErrICMPPermission = errors.New("permission denied: ICMP probes require unprivileged ping sockets (see net.ipv4.ping_group_range on Linux) or raw socket privileges (run as root)")
+
+// icmpProber measures the time it takes to receive the reply to an ICMP
+// (or ICMPv6) echo request, like ping
+type icmpProber struct {
+	host string
+}
+
+func init() {
+	Register("icmp", newICMPProber)
+}
+
+// newICMPProber is a function that returns an ICMP prober for a host, which
+// is an address or a name without a port
+func newICMPProber(address string) (Prober, error) {
+	host := strings.Trim(address, "[]")
+	if _, err := netip.ParseAddr(host); err != nil && strings.ContainsAny(host, ":/") {
+		return nil, fmt.Errorf("invalid ICMP target: %s (must be an address or a host name without a port)", address)
+	}
+	return &icmpProber{host: host}, nil
+}
+
+// Probe is a function that sends an echo request to the target and returns
+// the time it took to receive the echo reply
+func (p *icmpProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Resolve the host, names are resolved before every probe
+	addr, err := netip.ParseAddr(p.host)
+	if err != nil {
+		if ip.LookupsDisabled() {
+			return 0, ip.ErrLookupsDisabled
+		}
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", p.host)
+		if err != nil {
+			return 0, err
+		}
+		addr = addrs[0]
+	}
+	addr = addr.Unmap()
+
+	conn, datagram, err := listenICMP(addr.Is4())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	// Use random identifiers to tell the replies to concurrent probes apart
+	// on raw sockets (the kernel sets the identifier of datagram sockets)
+	id, seq := uint16(rand.Intn(1<<16)), uint16(rand.Intn(1<<16))
+	requestType, replyType := byte(icmpEchoRequest), byte(icmpEchoReply)
+	if !addr.Is4() {
+		requestType, replyType = icmpv6EchoRequest, icmpv6EchoReply
+	}
+	msg := marshalEcho(requestType, id, seq)
+
+	var dst net.Addr = &net.IPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	if datagram {
+		dst = &net.UDPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	}
+
+	// Start the timer
+	start := time.Now()
+
+	if _, err := conn.WriteTo(msg, dst); err != nil {
+		return 0, err
+	}
+
+	// Wait for the reply to our request, ignoring other ICMP messages
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, fmt.Errorf("no echo reply from %s: %w", addr, ctx.Err())
+			}
+			return 0, err
+		}
+		reply := buf[:n]
+
+		// Some systems (e.g. macOS) include the IPv4 header in the messages
+		if addr.Is4() && len(reply) >= 20 && reply[0]>>4 == 4 {
+			reply = reply[min(int(reply[0]&0x0f)*4, len(reply)):]
+		}
+		if len(reply) < 8 || reply[0] != replyType || binary.BigEndian.Uint16(reply[6:8]) != seq {
+			continue
+		}
+		if !datagram && binary.BigEndian.Uint16(reply[4:6]) != id {
+			continue
+		}
+		return time.Since(start), nil
+	}
+}
+
+// String is a function that returns the target of the prober
+func (p *icmpProber) String() string {
+	if strings.Contains(p.host, ":") {
+		return "icmp://[" + p.host + "]"
+	}
+	return "icmp://" + p.host
+}
+
+// listenICMP is a function that opens an ICMP socket for IPv4 or IPv6. An
+// unprivileged datagram (ping) socket is used where the system allows it,
+// otherwise a raw socket, which requires raw socket privileges. The
+// datagram flag reports which kind of socket was opened.
+func listenICMP(v4 bool) (conn net.PacketConn, datagram bool, err error) {
+	if conn, err := listenDatagramICMP(v4); err == nil {
+		return conn, true, nil
+	}
+
+	network, address := "ip4:icmp", "0.0.0.0"
+	if !v4 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+	conn, err = net.ListenPacket(network, address)
+	if errors.Is(err, os.ErrPermission) {
+		return nil, false, ErrICMPPermission
+	}
+	return conn, false, err
+}
+
+// marshalEcho is a function that returns an echo request message. The
+// checksum of ICMPv6 messages is left as zero since it is calculated by the
+// kernel, the checksum of ICMP (IPv4) messages is calculated here.
+func marshalEcho(msgType byte, id, seq uint16) []byte {
+	msg := make([]byte, 16)
+	msg[0] = msgType
+	binary.BigEndian.PutUint16(msg[4:6], id)
+	binary.BigEndian.PutUint16(msg[6:8], seq)
+	copy(msg[8:], "iptool\x00\x00")
+	if msgType == icmpEchoRequest {
+		binary.BigEndian.PutUint16(msg[2:4], packet.Checksum(msg))
+	}
+	return msg
+}
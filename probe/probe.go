@@ -0,0 +1,152 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultScheme is the type of probe used for targets without a scheme
+const DefaultScheme = "tcp"
+
+// Prober sends probes to a single target
+type Prober interface {
+	// Probe sends a single probe and returns the response time, or an
+	// error if the target did not respond within the timeout
+	Probe(ctx context.Context, timeout time.Duration) (time.Duration, error)
+
+	// String returns the target of the prober, e.g. tcp://10.0.0.1:443
+	String() string
+}
+
+// Factory is a function that returns a prober for an address, the target
+// without the scheme (e.g. 10.0.0.1:443 for tcp://10.0.0.1:443)
+type Factory func(address string) (Prober, error)
+
+// factories is the registry of the available types of probes, by scheme
+var factories = map[string]Factory{}
+
+// Register is a function that makes a type of probe available by its scheme
+func Register(scheme string, factory Factory) {
+	factories[scheme] = factory
+}
+
+// Schemes is a function that returns the schemes of the available probes
+func Schemes() []string {
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// New is a function that returns a prober for a target given as
+// scheme://address, e.g. tcp://10.0.0.1:443 or http://example.com. Targets
+// without a scheme are probed using the default scheme (tcp).
+func New(target string) (Prober, error) {
+	scheme, address, found := strings.Cut(target, "://")
+	if !found {
+		scheme, address = DefaultScheme, target
+	}
+
+	factory, ok := factories[strings.ToLower(scheme)]
+	if !ok {
+		return nil, fmt.Errorf("unknown probe type: %s (must be one of %s)", scheme, strings.Join(Schemes(), ", "))
+	}
+	if address == "" {
+		return nil, fmt.Errorf("missing address in target: %s", target)
+	}
+	return factory(address)
+}
+
+// SplitHostPort is a function that splits an address into a host and a
+// port, using the default port when the address has no port. IPv6
+// addresses with a port must be enclosed in brackets ([2001:db8::1]:443).
+func SplitHostPort(address string, defaultPort int) (string, int, error) {
+	// A bare IPv6 address (or any other address without a port)
+	bracketed := strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]")
+	if _, err := netip.ParseAddr(address); err == nil || bracketed || !strings.Contains(address, ":") {
+		return strings.Trim(address, "[]"), defaultPort, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address: %s", address)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port: %s", portStr)
+	}
+	return host, port, nil
+}
+
+// Group is a named group of targets
+type Group struct {
+	Name    string
+	Targets []string
+}
+
+// LoadGroups is a function that loads the groups of targets from a YAML
+// file, in the order they are defined in the file:
+//
+//	groups:
+//	  core: [10.0.0.1:22, 10.0.0.2:22]
+//	  web:
+//	    - https://www.example.com
+func LoadGroups(filename string) ([]Group, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the groups as a node to keep the order of the groups
+	var doc struct {
+		Groups yaml.Node `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if doc.Groups.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: no groups of targets defined", filename)
+	}
+
+	var groups []Group
+	for i := 0; i+1 < len(doc.Groups.Content); i += 2 {
+		group := Group{Name: doc.Groups.Content[i].Value}
+		if err := doc.Groups.Content[i+1].Decode(&group.Targets); err != nil {
+			return nil, fmt.Errorf("%s: group %s: %w", filename, group.Name, err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
@@ -0,0 +1,221 @@
+package probe_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/probe"
+)
+
+func TestNew(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		target    string
+		expected  string
+		expectErr bool
+	}{
+		{name: "DefaultScheme", target: "10.0.0.1", expected: "tcp://10.0.0.1:443"},
+		{name: "DefaultSchemeWithPort", target: "10.0.0.1:22", expected: "tcp://10.0.0.1:22"},
+		{name: "TCP", target: "tcp://example.com:80", expected: "tcp://example.com:80"},
+		{name: "TCPUppercase", target: "TCP://example.com", expected: "tcp://example.com:443"},
+		{name: "IPv6", target: "2001:db8::1", expected: "tcp://[2001:db8::1]:443"},
+		{name: "IPv6Bracketed", target: "[2001:db8::1]", expected: "tcp://[2001:db8::1]:443"},
+		{name: "IPv6WithPort", target: "tcp://[2001:db8::1]:22", expected: "tcp://[2001:db8::1]:22"},
+		{name: "HTTP", target: "http://example.com/health", expected: "http://example.com/health"},
+		{name: "HTTPS", target: "https://example.com", expected: "https://example.com"},
+		{name: "DNS", target: "dns://www.example.com", expected: "dns://www.example.com"},
+		{name: "ICMP", target: "icmp://10.0.0.1", expected: "icmp://10.0.0.1"},
+		{name: "ICMPv6", target: "icmp://2001:db8::1", expected: "icmp://[2001:db8::1]"},
+		{name: "ICMPv6Bracketed", target: "icmp://[2001:db8::1]", expected: "icmp://[2001:db8::1]"},
+		{name: "ICMPWithPort", target: "icmp://10.0.0.1:80", expectErr: true},
+		{name: "UnknownScheme", target: "gopher://example.com", expectErr: true},
+		{name: "MissingAddress", target: "tcp://", expectErr: true},
+		{name: "InvalidPort", target: "10.0.0.1:99999", expectErr: true},
+		{name: "InvalidPortName", target: "10.0.0.1:ssh", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prober, err := probe.New(tc.target)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prober.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, prober.String())
+			}
+		})
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	// Start a listener on a random port of the loopback interface
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	address := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	prober, err := probe.New(address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The probe succeeds while the listener is open
+	if _, err := prober.Probe(context.Background(), time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The probe fails when the port is closed
+	listener.Close()
+	if _, err := prober.Probe(context.Background(), time.Second); err == nil {
+		t.Errorf("expected error for closed port, got nil")
+	}
+}
+
+func TestProbeLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	// Names are not resolved with --no-dns, whatever the probe type
+	for _, target := range []string{"tcp://host.invalid:22", "http://host.invalid", "https://host.invalid:8443", "icmp://host.invalid"} {
+		prober, err := probe.New(target)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := prober.Probe(context.Background(), time.Second); !errors.Is(err, ip.ErrLookupsDisabled) {
+			t.Errorf("%s: expected %v, got %v", target, ip.ErrLookupsDisabled, err)
+		}
+	}
+}
+
+func TestICMPProbe(t *testing.T) {
+	prober, err := probe.New("icmp://127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The loopback interface answers echo requests, if the test may send them
+	_, err = prober.Probe(context.Background(), time.Second)
+	if errors.Is(err, probe.ErrICMPPermission) {
+		t.Skip("ICMP sockets are not permitted")
+	}
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadGroups(t *testing.T) {
+	dir := t.TempDir()
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		content   string
+		expected  []probe.Group
+		expectErr bool
+	}{
+		{
+			name:    "FileOrder",
+			content: "groups:\n  web: [https://www.example.com]\n  core:\n    - 10.0.0.1:22\n    - 10.0.0.2:22\n",
+			expected: []probe.Group{
+				{Name: "web", Targets: []string{"https://www.example.com"}},
+				{Name: "core", Targets: []string{"10.0.0.1:22", "10.0.0.2:22"}},
+			},
+		},
+		{name: "NoGroups", content: "targets: [10.0.0.1]\n", expectErr: true},
+		{name: "InvalidGroup", content: "groups:\n  core:\n    host: 10.0.0.1\n", expectErr: true},
+		{name: "InvalidYAML", content: "groups: [\n", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tc.name+".yaml")
+			if err := os.WriteFile(filename, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			groups, err := probe.LoadGroups(filename)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(groups, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, groups)
+			}
+		})
+	}
+}
+
+// fakeProber is a prober that fails the probes listed in fail
+type fakeProber struct {
+	fail  map[int]bool
+	count int
+}
+
+func (p *fakeProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	p.count++
+	if p.fail[p.count] {
+		return 0, errors.New("timeout")
+	}
+	return time.Duration(p.count) * time.Millisecond, nil
+}
+
+func (p *fakeProber) String() string {
+	return "fake://target"
+}
+
+func TestRun(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		fail     map[int]bool
+		count    int
+		expected probe.Result
+	}{
+		{name: "AllReplies", count: 3, expected: probe.Result{Target: "fake://target", Sent: 3, Received: 3, MinMs: 1, AvgMs: 2, MaxMs: 3}},
+		{name: "SomeLost", count: 4, fail: map[int]bool{2: true, 4: true}, expected: probe.Result{Target: "fake://target", Sent: 4, Received: 2, Loss: 50, MinMs: 1, AvgMs: 2, MaxMs: 3, Error: "timeout"}},
+		{name: "AllLost", count: 2, fail: map[int]bool{1: true, 2: true}, expected: probe.Result{Target: "fake://target", Sent: 2, Received: 0, Loss: 100, Error: "timeout"}},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := probe.Run(context.Background(), &fakeProber{fail: tc.fail}, tc.count, 0, time.Second)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, result)
+			}
+			if result.OK() != (tc.expected.Received > 0) {
+				t.Errorf("expected OK() = %v", tc.expected.Received > 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bitcanon/iptool/stats"
+)
+
+// Result holds the outcome of a series of probes sent to a target
+type Result struct {
+	Target   string  `json:"target"`
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	Loss     float64 `json:"loss"`
+	MinMs    float64 `json:"min_ms,omitempty"`
+	AvgMs    float64 `json:"avg_ms,omitempty"`
+	MaxMs    float64 `json:"max_ms,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// OK is a function that reports whether the target responded to any probe
+func (r Result) OK() bool {
+	return r.Received > 0
+}
+
+// Run is a function that sends count probes to the target, waiting the
+// interval between the probes, and returns the combined result. The last
+// error (if any) is reported in the result.
+func Run(ctx context.Context, prober Prober, count int, interval, timeout time.Duration) Result {
+	result := Result{Target: prober.String()}
+	var s stats.Stats
+
+	for i := 0; i < count; i++ {
+		// Wait between the probes
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return summarize(result, &s)
+			case <-time.After(interval):
+			}
+		}
+
+		rtt, err := prober.Probe(ctx, timeout)
+		result.Sent++
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Received++
+		s.Add(rtt)
+	}
+
+	return summarize(result, &s)
+}
+
+// RunAll is a function that runs the probes of all targets concurrently, see
+// Run, and returns the results in the same order as the probers
+func RunAll(ctx context.Context, probers []Prober, count int, interval, timeout time.Duration) []Result {
+	results := make([]Result, len(probers))
+
+	var wg sync.WaitGroup
+	for i, prober := range probers {
+		wg.Add(1)
+		go func(i int, prober Prober) {
+			defer wg.Done()
+			results[i] = Run(ctx, prober, count, interval, timeout)
+		}(i, prober)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// summarize is a function that fills in the loss and response times of a result
+func summarize(result Result, s *stats.Stats) Result {
+	if result.Sent > 0 {
+		result.Loss = float64(result.Sent-result.Received) / float64(result.Sent) * 100
+	}
+	if s.Count() > 0 {
+		result.MinMs = milliseconds(s.Min())
+		result.AvgMs = milliseconds(s.Mean())
+		result.MaxMs = milliseconds(s.Max())
+	}
+	return result
+}
+
+// milliseconds is a function that converts a duration to milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package probe
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// tcpDefaultPort is the port used by TCP probes when the target has no port
+const tcpDefaultPort = 443
+
+// tcpProber measures the time it takes to complete the TCP 3-way handshake
+type tcpProber struct {
+	host string
+	port int
+}
+
+func init() {
+	Register("tcp", newTCPProber)
+}
+
+// newTCPProber is a function that returns a TCP prober for host[:port]
+func newTCPProber(address string) (Prober, error) {
+	host, port, err := SplitHostPort(address, tcpDefaultPort)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpProber{host: host, port: port}, nil
+}
+
+// Probe is a function that connects to the target and returns the time it
+// took to establish the connection
+func (p *tcpProber) Probe(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Names are resolved by the dialer, unless name resolution is disabled
+	if _, err := netip.ParseAddr(p.host); err != nil && ip.LookupsDisabled() {
+		return 0, ip.ErrLookupsDisabled
+	}
+
+	// Start the timer
+	start := time.Now()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.address())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return time.Since(start), nil
+}
+
+// address is a function that returns the host and port of the target
+func (p *tcpProber) address() string {
+	return net.JoinHostPort(p.host, strconv.Itoa(p.port))
+}
+
+// String is a function that returns the target of the prober
+func (p *tcpProber) String() string {
+	return "tcp://" + p.address()
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package progress implements the progress bar printed on standard error by
+// the commands that run long jobs (e.g. sweep, tcp scan, enrich and large
+// subnet splits), so that the data written to standard output stays clean
+// for piping.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Interval is the time between two redraws of the progress bar
+const Interval = 200 * time.Millisecond
+
+// width is the number of characters of the bar itself
+const width = 30
+
+// Bar is a progress bar that is redrawn on a terminal at every Interval. The
+// steps are counted with atomic operations, so Add may be called from many
+// goroutines at once. A nil bar does nothing, so that the callers do not
+// have to check whether progress reporting is enabled.
+type Bar struct {
+	w       io.Writer
+	label   string
+	start   time.Time
+	total   atomic.Int64
+	done    atomic.Int64
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	last    int // Length of the last line drawn, to clear it
+}
+
+// New is a function that returns a progress bar for a job of total steps
+// (0 if the number of steps is not known in advance) and starts drawing it
+// to w. Call Finish to stop drawing and erase the bar.
+func New(w io.Writer, label string, total int64) *Bar {
+	b := &Bar{
+		w:       w,
+		label:   label,
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	b.total.Store(total)
+	go b.run()
+	return b
+}
+
+// Add is a method that adds n completed steps to the progress
+func (b *Bar) Add(n int64) {
+	if b == nil {
+		return
+	}
+	b.done.Add(n)
+}
+
+// SetTotal is a method that changes the number of steps of the job, e.g.
+// when the number of steps is only known after the job has started
+func (b *Bar) SetTotal(total int64) {
+	if b == nil {
+		return
+	}
+	b.total.Store(total)
+}
+
+// Finish is a method that stops drawing the progress bar and erases it. It
+// is safe to call Finish more than once.
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	b.once.Do(func() {
+		close(b.stop)
+		<-b.stopped
+	})
+}
+
+// run is a method that redraws the progress bar at every Interval until the
+// bar is finished
+func (b *Bar) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.draw(Format(b.label, b.done.Load(), b.total.Load(), time.Since(b.start)))
+		case <-b.stop:
+			// Erase the bar, so that the next output starts on a clean line
+			if b.last > 0 {
+				b.draw("")
+			}
+			return
+		}
+	}
+}
+
+// draw is a method that overwrites the current line with the given line
+func (b *Bar) draw(line string) {
+	padding := max(b.last-len(line), 0)
+	fmt.Fprintf(b.w, "\r%s%s\r", line, strings.Repeat(" ", padding))
+	b.last = len(line)
+}
+
+// Format is a function that returns the line of a progress bar with done of
+// total steps completed after elapsed time, with the rate and the estimated
+// time left. If total is 0, only the number of steps and the rate are shown.
+func Format(label string, done, total int64, elapsed time.Duration) string {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+	}
+
+	// The number of steps is not known, show the steps completed so far
+	if total <= 0 {
+		return fmt.Sprintf("%s %d done, %.1f/s, %s elapsed", label, done, rate, elapsed.Round(time.Second))
+	}
+
+	done = min(done, total)
+	filled := int(done * width / total)
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+
+	// Estimate the time left from the average rate so far
+	eta := "--"
+	if rate > 0 {
+		eta = (time.Duration(float64(total-done) / rate * float64(time.Second))).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s [%s] %d/%d %3d%% %.1f/s ETA %s", label, bar, done, total, done*100/total, rate, eta)
+}
@@ -0,0 +1,91 @@
+package progress_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/progress"
+)
+
+func TestFormat(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		done     int64
+		total    int64
+		elapsed  time.Duration
+		expected string
+	}{
+		{name: "Start", done: 0, total: 100, elapsed: 0, expected: "scan [>                             ] 0/100   0% 0.0/s ETA --"},
+		{name: "Half", done: 50, total: 100, elapsed: 10 * time.Second, expected: "scan [===============>              ] 50/100  50% 5.0/s ETA 10s"},
+		{name: "Done", done: 100, total: 100, elapsed: 20 * time.Second, expected: "scan [==============================] 100/100 100% 5.0/s ETA 0s"},
+		{name: "Overshoot", done: 120, total: 100, elapsed: 20 * time.Second, expected: "scan [==============================] 100/100 100% 6.0/s ETA 0s"},
+		{name: "UnknownTotal", done: 42, total: 0, elapsed: 4 * time.Second, expected: "scan 42 done, 10.5/s, 4s elapsed"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := progress.Format("scan", tc.done, tc.total, tc.elapsed); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// syncBuffer is a buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestBar(t *testing.T) {
+	var out syncBuffer
+	bar := progress.New(&out, "test", 1000)
+
+	// Add the steps from many goroutines at once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bar.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(progress.Interval + 50*time.Millisecond)
+	bar.Finish()
+	bar.Finish()
+
+	output := out.String()
+	if !strings.Contains(output, "1000/1000 100%") {
+		t.Errorf("expected the bar to show all steps completed, got %q", output)
+	}
+	if !strings.HasSuffix(output, "\r") {
+		t.Errorf("expected the bar to be erased, got %q", output)
+	}
+}
+
+func TestNilBar(t *testing.T) {
+	var bar *progress.Bar
+	bar.Add(1)
+	bar.SetTotal(10)
+	bar.Finish()
+}
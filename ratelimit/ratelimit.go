@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package ratelimit implements the rate limiting and concurrency control
+// shared by the commands that send many requests (e.g. dns bench, enrich and
+// sweep), so that large jobs do not trip intrusion detection systems or
+// overload links.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter. The bucket holds up to burst
+// tokens and is refilled at the rate, every request takes one token.
+// A nil limiter does not limit the rate.
+type Limiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	burst    int
+	tokens   float64
+	last     time.Time
+}
+
+// New is a function that returns a limiter allowing rate requests per
+// second, with bursts of up to burst requests (at least one)
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		burst:    max(burst, 1),
+		tokens:   float64(max(burst, 1)),
+		last:     time.Now(),
+	}
+}
+
+// Rate is a method that returns the number of requests per second
+func (l *Limiter) Rate() float64 {
+	if l == nil {
+		return 0
+	}
+	return float64(time.Second) / float64(l.interval)
+}
+
+// reserve takes a token from the bucket and returns how long the caller
+// has to wait before the token may be used
+func (l *Limiter) reserve() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Refill the bucket with the tokens earned since the last request
+	now := time.Now()
+	l.tokens = min(l.tokens+float64(now.Sub(l.last))/float64(l.interval), float64(l.burst))
+	l.last = now
+
+	// Take a token, the bucket may go negative (waiting requests)
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// Wait is a method that blocks until a request is allowed, or returns the
+// error of the context if it is done first
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	delay := l.reserve()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ParseRate is a function that parses a rate given as a number of requests
+// per second, minute or hour, e.g. 100/s, 600/m or 50 (per second). An empty
+// rate or a rate of zero means unlimited and returns a nil limiter. The
+// limiter has a burst of one, so that the requests are spaced evenly.
+func ParseRate(s string) (*Limiter, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	// Split the rate into the number and the unit
+	number, unit, _ := strings.Cut(s, "/")
+	per := time.Second
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "s", "sec", "second":
+	case "m", "min", "minute":
+		per = time.Minute
+	case "h", "hour":
+		per = time.Hour
+	default:
+		return nil, fmt.Errorf("invalid rate: %s (expected e.g. 100/s, 600/m or 3600/h)", s)
+	}
+
+	count, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || count < 0 || math.IsNaN(count) || math.IsInf(count, 0) {
+		return nil, fmt.Errorf("invalid rate: %s (expected e.g. 100/s, 600/m or 3600/h)", s)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	// The interval between requests must fit in a duration and be at least
+	// a nanosecond, otherwise the limiter would not limit anything
+	rate := count / per.Seconds()
+	if interval := float64(time.Second) / rate; interval < 1 || interval >= math.MaxInt64 {
+		return nil, fmt.Errorf("invalid rate: %s (out of range)", s)
+	}
+	return New(rate, 1), nil
+}
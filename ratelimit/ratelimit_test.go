@@ -0,0 +1,132 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ratelimit"
+)
+
+func TestParseRate(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input       string
+		expected    float64
+		expectError bool
+	}{
+		{input: "", expected: 0},
+		{input: "0", expected: 0},
+		{input: "100", expected: 100},
+		{input: "100/s", expected: 100},
+		{input: "600/m", expected: 10},
+		{input: "7200/h", expected: 2},
+		{input: "0.5/s", expected: 0.5},
+		{input: "100/d", expectError: true},
+		{input: "fast", expectError: true},
+		{input: "-1/s", expectError: true},
+		{input: "NaN", expectError: true},
+		{input: "Inf/s", expectError: true},
+		{input: "-Inf", expectError: true},
+		{input: "1e-12/h", expectError: true},
+		{input: "1e10/s", expectError: true},
+		{input: "1e-9/s", expected: 1e-9},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			limiter, err := ratelimit.ParseRate(tc.input)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rate := limiter.Rate(); rate < tc.expected*0.999 || rate > tc.expected*1.001 {
+				t.Errorf("expected %v requests per second, got %v", tc.expected, rate)
+			}
+		})
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	// 11 requests at 100/s with a burst of one take about 100 ms
+	limiter := ratelimit.New(100, 1)
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("expected about 100ms, got %v", elapsed)
+	}
+
+	// A nil limiter does not wait
+	var unlimited *ratelimit.Limiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name        string
+		n           int
+		concurrency int
+		cancelAfter int
+		expected    int
+	}{
+		{name: "All", n: 20, concurrency: 4, expected: 20},
+		{name: "Sequential", n: 5, concurrency: 0, expected: 5},
+		{name: "Drained", n: 100, concurrency: 2, cancelAfter: 6, expected: 6},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var inFlight, maxInFlight, calls, finished atomic.Int32
+			started := ratelimit.Run(ctx, tc.n, tc.concurrency, nil, func(jobCtx context.Context, i int) {
+				current := inFlight.Add(1)
+				for {
+					peak := maxInFlight.Load()
+					if current <= peak || maxInFlight.CompareAndSwap(peak, current) {
+						break
+					}
+				}
+
+				// Cancel the run, the calls in flight must still finish
+				if tc.cancelAfter > 0 && int(calls.Add(1)) == tc.cancelAfter {
+					cancel()
+				}
+				time.Sleep(5 * time.Millisecond)
+				if jobCtx.Err() == nil {
+					finished.Add(1)
+				}
+				inFlight.Add(-1)
+			})
+
+			if started != int(finished.Load()) {
+				t.Errorf("expected all %d started calls to finish, %d finished", started, finished.Load())
+			}
+			if tc.cancelAfter == 0 && started != tc.expected {
+				t.Errorf("expected %d calls, got %d", tc.expected, started)
+			}
+			if tc.cancelAfter > 0 && (started < tc.expected || started > tc.expected+max(tc.concurrency, 1)) {
+				t.Errorf("expected about %d calls after cancel, got %d", tc.expected, started)
+			}
+			if limit := int32(max(tc.concurrency, 1)); maxInFlight.Load() > limit {
+				t.Errorf("expected at most %d calls in flight, got %d", limit, maxInFlight.Load())
+			}
+		})
+	}
+}
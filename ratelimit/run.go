@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// Run is a function that calls fn for every index from 0 to n-1, with at
+// most concurrency calls in flight (at least one) and the calls started at
+// the rate of the limiter. No calls are started once the context is done,
+// but the calls in flight are allowed to finish (draining). The context
+// passed to fn is not canceled with ctx. Run returns the number of calls
+// that were started.
+func Run(ctx context.Context, n, concurrency int, limiter *Limiter, fn func(ctx context.Context, i int)) int {
+	drain := context.WithoutCancel(ctx)
+	slots := make(chan struct{}, max(concurrency, 1))
+
+	var wg sync.WaitGroup
+	started := 0
+	for i := 0; i < n; i++ {
+		// Wait for a free slot and for the rate limiter
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || limiter.Wait(ctx) != nil {
+			break
+		}
+
+		started++
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			fn(drain, i)
+		}(i)
+	}
+	wg.Wait()
+
+	return started
+}
+
+// InterruptContext is a function that returns a context that is canceled
+// when the user presses Ctrl-C, so that a job can stop starting new requests
+// and let the requests in flight finish. A message is printed to stderr, and
+// pressing Ctrl-C a second time exits immediately. Call stop to release the
+// signal handler.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupt := make(chan os.Signal, 2)
+	signal.Notify(interrupt, os.Interrupt)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupt:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Interrupted, waiting for the requests in flight (press Ctrl-C again to quit)")
+		cancel()
+
+		select {
+		case <-interrupt:
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(interrupt)
+			close(done)
+			cancel()
+		})
+	}
+	return ctx, stop
+}
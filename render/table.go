@@ -0,0 +1,405 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Gap is the number of spaces between the columns of a table in the
+// default (wide) layout, the narrow layout uses a single space
+const Gap = 2
+
+// Ellipsis marks the end of a truncated cell
+const Ellipsis = "…"
+
+// Align is the alignment of the cells in a column
+type Align int
+
+const (
+	// AlignLeft pads the cells on the right
+	AlignLeft Align = iota
+	// AlignRight pads the cells on the left (e.g. numbers)
+	AlignRight
+)
+
+// Format is the format a table is written in
+type Format string
+
+const (
+	// FormatTable writes an aligned text table (the default)
+	FormatTable Format = "table"
+	// FormatCSV writes a CSV record per row, with the titles as header
+	FormatCSV Format = "csv"
+	// FormatJSON writes a JSON object per row and line, with the titles
+	// as keys (see Key)
+	FormatJSON Format = "json"
+)
+
+// Formats is the list of the formats a table can be written in
+var Formats = []string{string(FormatTable), string(FormatCSV), string(FormatJSON)}
+
+// Column describes a column of a table
+type Column struct {
+	// Title is printed in the header of the column
+	Title string
+	// Width is the minimum width of the column, the column is never
+	// narrower than the title or the cells passed to Table.Fit
+	Width int
+	// Align is the alignment of the cells in the column
+	Align Align
+	// Truncate allows the column to be shortened when the table does not
+	// fit within Options.MaxWidth, the cells are cut off with an ellipsis
+	Truncate bool
+	// Name is the name the column is selected by with Options.Columns, the
+	// JSON key of the title (see Key) if empty
+	Name string
+	// Hidden columns are only written when selected with Options.Columns
+	Hidden bool
+}
+
+// key returns the name the column is selected by
+func (c Column) key() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return Key(c.Title)
+}
+
+// Options controls how a table is rendered
+type Options struct {
+	// NoHeader omits the header and the dashed line below it
+	NoHeader bool
+	// Narrow separates the columns with a single space instead of Gap
+	Narrow bool
+	// MaxWidth is the width the table is fitted to (e.g. the width of the
+	// terminal), a table that is wider is narrowed and its truncatable
+	// columns are shortened. Zero means that the width is unlimited.
+	MaxWidth int
+	// Format is the format the table is written in, the layout options
+	// only apply to the table format (the empty format)
+	Format Format
+	// Columns selects the columns to write by name, in the given order.
+	// Empty means all columns except the hidden ones.
+	Columns []string
+}
+
+// Table is a text table that is written row by row, so that very large
+// tables (e.g. subnet splits) can be streamed. The column widths are fixed
+// when the first row is written, use Fit to grow them before that.
+type Table struct {
+	out     io.Writer
+	opts    Options
+	columns []Column
+	widths  []int
+	started bool
+	csv     *csv.Writer // The CSV writer of the csv format
+	index   []int       // The index of the cells of the selected columns
+	err     error       // The error of the column selection
+}
+
+// NewTable is a function that returns a table with the columns that
+// writes to out
+func NewTable(out io.Writer, opts Options, columns ...Column) *Table {
+	t := &Table{out: out, opts: opts}
+	t.index, t.err = SelectColumns(columns, opts.Columns)
+	for _, i := range t.index {
+		t.columns = append(t.columns, columns[i])
+		t.widths = append(t.widths, max(columns[i].Width, Len(columns[i].Title)))
+	}
+	if opts.Format == FormatCSV {
+		t.csv = csv.NewWriter(out)
+	}
+	return t
+}
+
+// SelectColumns is a function that returns the indexes of the columns
+// selected by name, in the given order, or the indexes of all columns except
+// the hidden ones if no names are given
+func SelectColumns(columns []Column, names []string) ([]int, error) {
+	var index []int
+	if len(names) == 0 {
+		for i, column := range columns {
+			if !column.Hidden {
+				index = append(index, i)
+			}
+		}
+		return index, nil
+	}
+
+	// Look up the columns by name, case insensitive
+	for _, name := range names {
+		found := -1
+		for i, column := range columns {
+			if strings.EqualFold(strings.TrimSpace(name), column.key()) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, fmt.Errorf("invalid column: %s (valid columns: %s)", name, strings.Join(ColumnNames(columns), ", "))
+		}
+		index = append(index, found)
+	}
+	return index, nil
+}
+
+// ColumnNames is a function that returns the names the columns are selected
+// by with Options.Columns
+func ColumnNames(columns []Column) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.key()
+	}
+	return names
+}
+
+// Err is a method that returns the error of the column selection, e.g. an
+// unknown column name. The error is also returned by Header and Row.
+func (t *Table) Err() error {
+	return t.err
+}
+
+// selected returns the cells of the selected columns of a row, in the
+// order of the selected columns
+func (t *Table) selected(cells []string) []string {
+	row := make([]string, len(t.index))
+	for i, index := range t.index {
+		if index < len(cells) {
+			row[i] = cells[index]
+		}
+	}
+	return row
+}
+
+// Fit is a method that grows the column widths to fit the cells of a row,
+// it has no effect once the first row has been written
+func (t *Table) Fit(cells ...string) {
+	if t.started {
+		return
+	}
+	for i, cell := range t.selected(cells) {
+		t.widths[i] = max(t.widths[i], Len(cell))
+	}
+}
+
+// Width is a method that returns the total width of the table, including
+// the space between the columns
+func (t *Table) Width() int {
+	width := 0
+	for _, w := range t.widths {
+		width += w
+	}
+	if len(t.widths) > 1 {
+		width += t.gap() * (len(t.widths) - 1)
+	}
+	return width
+}
+
+// gap returns the number of spaces between the columns
+func (t *Table) gap() int {
+	if t.opts.Narrow {
+		return 1
+	}
+	return Gap
+}
+
+// fit narrows the table to Options.MaxWidth, first by reducing the space
+// between the columns and then by shortening the truncatable columns,
+// the widest one first, but never below the width of the title
+func (t *Table) fit() {
+	if t.opts.MaxWidth <= 0 || t.Width() <= t.opts.MaxWidth {
+		return
+	}
+	t.opts.Narrow = true
+
+	for t.Width() > t.opts.MaxWidth {
+		widest := -1
+		for i, column := range t.columns {
+			minimum := max(Len(column.Title), Len(Ellipsis)+1)
+			if column.Truncate && t.widths[i] > minimum && (widest < 0 || t.widths[i] > t.widths[widest]) {
+				widest = i
+			}
+		}
+
+		// Nothing left to shorten, the lines will wrap
+		if widest < 0 {
+			return
+		}
+		t.widths[widest]--
+	}
+}
+
+// start fixes the column widths and writes the header, unless disabled
+func (t *Table) start() error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.started {
+		return nil
+	}
+	t.fit()
+	t.started = true
+
+	// The JSON objects have no header
+	if t.opts.NoHeader || t.opts.Format == FormatJSON {
+		return nil
+	}
+	titles := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		titles[i] = column.Title
+	}
+	if t.csv != nil {
+		return t.writeCSV(titles)
+	}
+	if err := t.write(titles); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(t.out, strings.Repeat("-", t.Width()))
+	return err
+}
+
+// Header is a method that writes the header of the table (if not already
+// written), which is otherwise written together with the first row
+func (t *Table) Header() error {
+	return t.start()
+}
+
+// Row is a method that writes a row to the table, missing cells are empty
+// and cells that do not fit in their column are truncated. The cells are
+// given for all columns, the cells of the columns not selected are dropped.
+func (t *Table) Row(cells ...string) error {
+	if err := t.start(); err != nil {
+		return err
+	}
+	cells = t.selected(cells)
+	switch {
+	case t.csv != nil:
+		return t.writeCSV(cells)
+	case t.opts.Format == FormatJSON:
+		return t.writeJSON(cells)
+	}
+	return t.write(cells)
+}
+
+// writeCSV writes the cells of a line as a CSV record, the record is
+// flushed right away so that the rows are streamed
+func (t *Table) writeCSV(cells []string) error {
+	record := make([]string, len(t.columns))
+	copy(record, cells)
+	t.csv.Write(record)
+	t.csv.Flush()
+	return t.csv.Error()
+}
+
+// writeJSON writes the cells of a line as a JSON object keyed by the titles
+// of the columns, in the order of the columns. The cells of right aligned
+// columns are written as numbers if they are numbers.
+func (t *Table) writeJSON(cells []string) error {
+	var line strings.Builder
+	line.WriteString("{")
+	for i, column := range t.columns {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		if i > 0 {
+			line.WriteString(",")
+		}
+		key, _ := json.Marshal(Key(column.Title))
+		line.Write(key)
+		line.WriteString(":")
+		if _, err := strconv.ParseFloat(cell, 64); err == nil && column.Align == AlignRight && json.Valid([]byte(cell)) {
+			line.WriteString(cell)
+		} else {
+			value, _ := json.Marshal(cell)
+			line.Write(value)
+		}
+	}
+	line.WriteString("}")
+	_, err := fmt.Fprintln(t.out, line.String())
+	return err
+}
+
+// Key is a function that returns the JSON key of a column title, in lower
+// case with the words joined by underscores (e.g. "Last Seen" is last_seen)
+func Key(title string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+// write pads and writes the cells of a line, without trailing spaces
+func (t *Table) write(cells []string) error {
+	var line strings.Builder
+	for i, width := range t.widths {
+		cell := ""
+		if i < len(cells) {
+			cell = Truncate(cells[i], width)
+		}
+		if i > 0 {
+			line.WriteString(strings.Repeat(" ", t.gap()))
+		}
+		padding := strings.Repeat(" ", width-Len(cell))
+		if t.columns[i].Align == AlignRight {
+			line.WriteString(padding + cell)
+		} else {
+			line.WriteString(cell + padding)
+		}
+	}
+	_, err := fmt.Fprintln(t.out, strings.TrimRight(line.String(), " "))
+	return err
+}
+
+// Len is a function that returns the width of a string in characters
+func Len(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// Truncate is a function that shortens a string to the width, replacing
+// the end of the string with an ellipsis if it is cut off
+func Truncate(s string, width int) string {
+	if Len(s) <= width {
+		return s
+	}
+	if width <= Len(Ellipsis) {
+		return string([]rune(s)[:max(width, 0)])
+	}
+	return string([]rune(s)[:width-Len(Ellipsis)]) + Ellipsis
+}
@@ -0,0 +1,232 @@
+package render_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/bitcanon/iptool/render"
+)
+
+// TestTable tests the layout of the tables
+func TestTable(t *testing.T) {
+	columns := []render.Column{
+		{Title: "Name", Truncate: true},
+		{Title: "Prefix", Width: 13},
+		{Title: "Hosts", Align: render.AlignRight},
+	}
+	rows := [][]string{
+		{"management", "10.0.0.0/26", "62"},
+		{"voice", "10.0.0.64/26", "62"},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		opts     render.Options
+		expected string
+	}{
+		{
+			name: "Wide",
+			opts: render.Options{},
+			expected: "" +
+				"Name        Prefix         Hosts\n" +
+				"--------------------------------\n" +
+				"management  10.0.0.0/26       62\n" +
+				"voice       10.0.0.64/26      62\n",
+		},
+		{
+			name: "NoHeader",
+			opts: render.Options{NoHeader: true},
+			expected: "" +
+				"management  10.0.0.0/26       62\n" +
+				"voice       10.0.0.64/26      62\n",
+		},
+		{
+			name: "Narrow",
+			opts: render.Options{Narrow: true},
+			expected: "" +
+				"Name       Prefix        Hosts\n" +
+				"------------------------------\n" +
+				"management 10.0.0.0/26      62\n" +
+				"voice      10.0.0.64/26     62\n",
+		},
+		{
+			name: "FitsMaxWidth",
+			opts: render.Options{MaxWidth: 32},
+			expected: "" +
+				"Name        Prefix         Hosts\n" +
+				"--------------------------------\n" +
+				"management  10.0.0.0/26       62\n" +
+				"voice       10.0.0.64/26      62\n",
+		},
+		{
+			name: "Truncated",
+			opts: render.Options{MaxWidth: 26},
+			expected: "" +
+				"Name   Prefix        Hosts\n" +
+				"--------------------------\n" +
+				"manag… 10.0.0.0/26      62\n" +
+				"voice  10.0.0.64/26     62\n",
+		},
+		{
+			name: "CSV",
+			opts: render.Options{Format: render.FormatCSV, MaxWidth: 10},
+			expected: "" +
+				"Name,Prefix,Hosts\n" +
+				"management,10.0.0.0/26,62\n" +
+				"voice,10.0.0.64/26,62\n",
+		},
+		{
+			name: "CSVNoHeader",
+			opts: render.Options{Format: render.FormatCSV, NoHeader: true},
+			expected: "" +
+				"management,10.0.0.0/26,62\n" +
+				"voice,10.0.0.64/26,62\n",
+		},
+		{
+			name: "JSON",
+			opts: render.Options{Format: render.FormatJSON, MaxWidth: 10},
+			expected: "" +
+				`{"name":"management","prefix":"10.0.0.0/26","hosts":62}` + "\n" +
+				`{"name":"voice","prefix":"10.0.0.64/26","hosts":62}` + "\n",
+		},
+		{
+			name: "Columns",
+			opts: render.Options{Columns: []string{"hosts", "Name"}},
+			expected: "" +
+				"Hosts  Name\n" +
+				"-----------------\n" +
+				"   62  management\n" +
+				"   62  voice\n",
+		},
+		{
+			name: "ColumnsJSON",
+			opts: render.Options{Format: render.FormatJSON, Columns: []string{"prefix"}},
+			expected: "" +
+				`{"prefix":"10.0.0.0/26"}` + "\n" +
+				`{"prefix":"10.0.0.64/26"}` + "\n",
+		},
+		{
+			name: "TruncatedToTitle",
+			opts: render.Options{MaxWidth: 10},
+			expected: "" +
+				"Name Prefix        Hosts\n" +
+				"------------------------\n" +
+				"man… 10.0.0.0/26      62\n" +
+				"voi… 10.0.0.64/26     62\n",
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			table := render.NewTable(&out, tc.opts, columns...)
+			for _, row := range rows {
+				table.Fit(row...)
+			}
+			for _, row := range rows {
+				if err := table.Row(row...); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if out.String() != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+// TestSelectColumns tests the selection of the columns by name
+func TestSelectColumns(t *testing.T) {
+	columns := []render.Column{
+		{Title: "CIDR"},
+		{Title: "Subnet Mask", Name: "mask"},
+		{Title: "Hex Mask", Name: "hex", Hidden: true},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		names     []string
+		expected  []int
+		expectErr bool
+	}{
+		{name: "Default", names: nil, expected: []int{0, 1}},
+		{name: "Reordered", names: []string{"mask", "cidr"}, expected: []int{1, 0}},
+		{name: "Hidden", names: []string{"cidr", "HEX"}, expected: []int{0, 2}},
+		{name: "Repeated", names: []string{"cidr", "cidr"}, expected: []int{0, 0}},
+		{name: "Unknown", names: []string{"cidr", "subnet_mask"}, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			index, err := render.SelectColumns(columns, tc.names)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", index)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(index) != fmt.Sprint(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, index)
+			}
+		})
+	}
+}
+
+// TestKey tests the JSON keys of the column titles
+func TestKey(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		title    string
+		expected string
+	}{
+		{title: "Prefix", expected: "prefix"},
+		{title: "Last Seen", expected: "last_seen"},
+		{title: "RTT (ms)", expected: "rtt_ms"},
+		{title: " Hosts/Subnet ", expected: "hosts_subnet"},
+		{title: "IPv6", expected: "ipv6"},
+		{title: "#", expected: ""},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			if got := render.Key(tc.title); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestTruncate tests the truncation of strings
+func TestTruncate(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{"voice", 10, "voice"},
+		{"voice", 5, "voice"},
+		{"voice", 4, "voi…"},
+		{"voice", 1, "v"},
+		{"voice", 0, ""},
+		{"åäö-net", 4, "åäö…"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := render.Truncate(tc.input, tc.width); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
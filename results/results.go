@@ -0,0 +1,222 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package results implements the opt-in local store of measurements, where
+// the results of commands like tcp ping, probe, sweep and inspect are
+// recorded with timestamps, so that the history of a target can be shown.
+// The store is a JSON Lines file that is only ever appended to.
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/stats"
+)
+
+// Kinds of recorded results, the command that measured them
+const (
+	KindTCPPing = "tcp-ping"
+	KindProbe   = "probe"
+	KindSweep   = "sweep"
+	KindInspect = "inspect"
+	KindMonitor = "monitor"
+)
+
+// Kinds is the list of all kinds of results
+var Kinds = []string{KindTCPPing, KindProbe, KindSweep, KindInspect, KindMonitor}
+
+// Warnings receives the warnings about the lines of the store that cannot
+// be read
+var Warnings io.Writer = os.Stderr
+
+// Result is a single recorded measurement of a target
+type Result struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Target  string    `json:"target"`
+	Address string    `json:"address,omitempty"`
+	Port    int       `json:"port,omitempty"`
+	Success bool      `json:"success"`
+	RTTMs   float64   `json:"rtt_ms,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// RTT is a method that returns the round-trip time of the result
+func (r Result) RTT() time.Duration {
+	return time.Duration(r.RTTMs * float64(time.Millisecond))
+}
+
+// Matches is a method that reports whether the result is a measurement of
+// the target, given as the name or the address that was measured
+func (r Result) Matches(target string) bool {
+	return strings.EqualFold(r.Target, target) || (r.Address != "" && r.Address == target)
+}
+
+// DefaultPath is a function that returns the path of the results store in
+// the configuration directory of the user (e.g. ~/.config/iptool/results.jsonl
+// on Linux)
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iptool", "results.jsonl"), nil
+}
+
+// Append is a function that appends the results to the store, setting the
+// time of the results that have none
+func Append(path string, results ...Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Write all results in one write, so that concurrent writers do not interleave lines
+	now := time.Now()
+	var data []byte
+	for _, r := range results {
+		if r.Time.IsZero() {
+			r.Time = now
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Read is a function that reads the results in the store that are accepted
+// by the filter (all results if the filter is nil). A missing store has no
+// results.
+func Read(path string, filter func(Result) bool) ([]Result, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []Result
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		// Skip the lines that cannot be decoded (e.g. a line cut short by
+		// a crash while appending), so that the rest stays readable
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			fmt.Fprintf(Warnings, "Warning: %s:%d: skipping invalid result: %v\n", path, line, err)
+			continue
+		}
+		if filter == nil || filter(r) {
+			results = append(results, r)
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Period is the summary of the results of a period of time (e.g. a day)
+type Period struct {
+	Start    time.Time
+	Count    int
+	Failures int
+	Stats    stats.Stats
+}
+
+// FailureRate is a method that returns the percentage of failed results
+func (p *Period) FailureRate() float64 {
+	if p.Count == 0 {
+		return 0
+	}
+	return 100 * float64(p.Failures) / float64(p.Count)
+}
+
+// Trend is a function that summarizes the results per period of the given
+// length (e.g. 24 hours), in the time zone of the location, oldest period
+// first. Periods without results are left out.
+func Trend(results []Result, period time.Duration, loc *time.Location) []*Period {
+	var periods []*Period
+	index := make(map[time.Time]*Period)
+	for _, r := range results {
+		start := truncate(r.Time.In(loc), period)
+		p, ok := index[start]
+		if !ok {
+			p = &Period{Start: start}
+			index[start] = p
+			periods = append(periods, p)
+		}
+		p.Count++
+		if !r.Success {
+			p.Failures++
+			continue
+		}
+		if r.RTTMs > 0 {
+			p.Stats.Add(r.RTT())
+		}
+	}
+
+	// The results are appended in time order, but sort in case the clock was changed
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods
+}
+
+// truncate is a function that returns the start of the period of the time,
+// periods of a day or longer start at midnight in the time zone of t
+func truncate(t time.Time, period time.Duration) time.Time {
+	if period < 24*time.Hour {
+		return t.Truncate(period)
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period == 7*24*time.Hour {
+		// Weeks start on Monday
+		offset := (int(midnight.Weekday()) + 6) % 7
+		return midnight.AddDate(0, 0, -offset)
+	}
+	return midnight
+}
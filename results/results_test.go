@@ -0,0 +1,161 @@
+package results_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/results"
+)
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iptool", "results.jsonl")
+
+	// A missing store has no results
+	list, err := results.Read(path, nil)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("expected no results, got %v (%v)", list, err)
+	}
+
+	// Append results in two writes
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := results.Append(path,
+		results.Result{Time: start, Kind: results.KindTCPPing, Target: "web01", Address: "10.0.0.1", Port: 443, Success: true, RTTMs: 1.5},
+		results.Result{Time: start.Add(time.Second), Kind: results.KindTCPPing, Target: "web02", Address: "10.0.0.2", Port: 443},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := results.Append(path, results.Result{Kind: results.KindInspect, Target: "10.0.0.1", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		target   string
+		expected int
+	}{
+		{target: "web01", expected: 2},
+		{target: "WEB02", expected: 1},
+		{target: "10.0.0.1", expected: 2},
+		{target: "10.0.0.3", expected: 0},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			list, err := results.Read(path, func(r results.Result) bool {
+				return r.Matches(tc.target) || (tc.target == "web01" && r.Target == "10.0.0.1")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != tc.expected {
+				t.Errorf("expected %d results, got %d", tc.expected, len(list))
+			}
+		})
+	}
+
+	// Results without a time get the time of the write
+	list, _ = results.Read(path, nil)
+	if len(list) != 3 || list[2].Time.IsZero() || list[0].RTT() != 1500*time.Microsecond {
+		t.Errorf("unexpected results: %+v", list)
+	}
+
+	// The store is only readable by the user
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestReadInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	data := `{"time":"2024-03-01T12:00:00Z","kind":"probe","target":"web01","success":true}
+{"time":"2024-03-01T12:01:00Z","kind":"pro
+{"time":"2024-03-01T12:02:00Z","kind":"probe","target":"web01","success":false}
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	results.Warnings = &warnings
+	defer func() { results.Warnings = os.Stderr }()
+
+	// The truncated line is skipped with a warning, the others are read
+	list, err := results.Read(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || !list[0].Success || list[1].Success {
+		t.Errorf("unexpected results: %+v", list)
+	}
+	if !strings.Contains(warnings.String(), "results.jsonl:2: skipping invalid result") {
+		t.Errorf("expected a warning about line 2, got %q", warnings.String())
+	}
+}
+
+func TestTrend(t *testing.T) {
+	day := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC) // a Wednesday
+	list := []results.Result{
+		{Time: day.Add(1 * time.Hour), Success: true, RTTMs: 10},
+		{Time: day.Add(2 * time.Hour), Success: true, RTTMs: 20},
+		{Time: day.Add(26 * time.Hour), Success: false},
+		{Time: day.Add(27 * time.Hour), Success: true, RTTMs: 40},
+		{Time: day.Add(-time.Hour), Success: true, RTTMs: 5},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		period   time.Duration
+		starts   []time.Time
+		counts   []int
+		failures []int
+	}{
+		{
+			name:     "Day",
+			period:   24 * time.Hour,
+			starts:   []time.Time{day.AddDate(0, 0, -1), day, day.AddDate(0, 0, 1)},
+			counts:   []int{1, 2, 2},
+			failures: []int{0, 0, 1},
+		},
+		{
+			name:     "Week",
+			period:   7 * 24 * time.Hour,
+			starts:   []time.Time{day.AddDate(0, 0, -2)},
+			counts:   []int{5},
+			failures: []int{1},
+		},
+		{
+			name:     "Hour",
+			period:   time.Hour,
+			starts:   []time.Time{day.Add(-time.Hour), day.Add(time.Hour), day.Add(2 * time.Hour), day.Add(26 * time.Hour), day.Add(27 * time.Hour)},
+			counts:   []int{1, 1, 1, 1, 1},
+			failures: []int{0, 0, 0, 1, 0},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			periods := results.Trend(list, tc.period, time.UTC)
+			if len(periods) != len(tc.starts) {
+				t.Fatalf("expected %d periods, got %d", len(tc.starts), len(periods))
+			}
+			for i, p := range periods {
+				if !p.Start.Equal(tc.starts[i]) || p.Count != tc.counts[i] || p.Failures != tc.failures[i] {
+					t.Errorf("period %d: expected %v %d/%d, got %v %d/%d", i, tc.starts[i], tc.counts[i], tc.failures[i], p.Start, p.Count, p.Failures)
+				}
+			}
+		})
+	}
+
+	// The response times of the successful results are summarized
+	periods := results.Trend(list, 24*time.Hour, time.UTC)
+	if mean := periods[1].Stats.Mean(); mean != 15*time.Millisecond {
+		t.Errorf("expected a mean of 15ms, got %v", mean)
+	}
+}
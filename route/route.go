@@ -0,0 +1,428 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package route
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// Route flags of the Linux kernel (see linux/route.h)
+const (
+	flagUp     = 0x0001
+	flagReject = 0x0200
+	flagLocal  = 0x80000000
+)
+
+// Route represents an entry in a routing table. The gateway is not valid
+// for routes to directly connected networks.
+type Route struct {
+	Prefix    netip.Prefix `json:"prefix"`
+	Gateway   netip.Addr   `json:"gateway,omitempty"`
+	Interface string       `json:"interface,omitempty"`
+	Metric    int          `json:"metric"`
+}
+
+// String is a function that returns the route in the format of the Linux
+// ip route command, e.g. "10.0.0.0/8 via 10.1.1.1 dev eth0 metric 100"
+func (r Route) String() string {
+	var b strings.Builder
+	b.WriteString(r.Prefix.String())
+	if r.Gateway.IsValid() {
+		b.WriteString(" via " + r.Gateway.String())
+	}
+	if r.Interface != "" {
+		b.WriteString(" dev " + r.Interface)
+	}
+	if r.Metric != 0 {
+		b.WriteString(" metric " + strconv.Itoa(r.Metric))
+	}
+	return b.String()
+}
+
+// Sort is a function that sorts routes by prefix (IPv4 before IPv6, then
+// by address and prefix length) and metric
+func Sort(routes []Route) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		if c := ip.ComparePrefixes(routes[i].Prefix, routes[j].Prefix); c != 0 {
+			return c < 0
+		}
+		return routes[i].Metric < routes[j].Metric
+	})
+}
+
+// Table is a routing table indexed for longest-prefix matching
+type Table struct {
+	trie *ip.Trie[Route]
+}
+
+// Result is the result of a longest-prefix match in a routing table
+type Result struct {
+	// Route is the route a packet to the destination would take
+	Route Route `json:"route"`
+
+	// Ties are the other routes to the same prefix, sorted by metric
+	Ties []Route `json:"ties,omitempty"`
+
+	// Covering are the routes to the less specific prefixes that also
+	// contain the destination, from the most to the least specific prefix
+	Covering []Route `json:"covering,omitempty"`
+}
+
+// NewTable is a function that returns a routing table of the routes
+func NewTable(routes []Route) *Table {
+	t := &Table{trie: ip.NewTrie[Route]()}
+	for _, r := range routes {
+		t.trie.Insert(r.Prefix, r)
+	}
+	return t
+}
+
+// Len is a function that returns the number of prefixes in the table
+func (t *Table) Len() int {
+	return t.trie.Len()
+}
+
+// Lookup is a function that returns the route a packet to the destination
+// would take: of the routes with the longest prefix that contains the
+// address, the route with the lowest metric. The other routes to the same
+// prefix are the ties, and the routes to less specific prefixes that also
+// contain the address are the covering routes. The second return value is
+// false if no route matches.
+func (t *Table) Lookup(addr netip.Addr) (Result, bool) {
+	matches := t.trie.Matches(addr)
+	if len(matches) == 0 {
+		return Result{}, false
+	}
+
+	// Sort the routes of every prefix by metric (stable, so the order of
+	// the table decides between routes with the same metric)
+	byMetric := func(routes []Route) []Route {
+		sorted := append([]Route{}, routes...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Metric < sorted[j].Metric })
+		return sorted
+	}
+
+	best := byMetric(matches[0].Values)
+	result := Result{Route: best[0], Ties: best[1:]}
+	for _, m := range matches[1:] {
+		result.Covering = append(result.Covering, byMetric(m.Values)...)
+	}
+	return result, true
+}
+
+// Match is a function that returns the route a packet to the destination
+// would take, see Table.Lookup. The second return value is false if no
+// route matches.
+func Match(routes []Route, addr netip.Addr) (Route, bool) {
+	result, ok := NewTable(routes).Lookup(addr)
+	return result.Route, ok
+}
+
+// ParseTable is a function that parses a routing table file with one route
+// per line. Every line starts with a prefix (or "default") and optionally
+// continues in the format of the Linux ip route command ("via <gateway>",
+// "dev <interface>" and "metric <n>") or with the gateway and the interface
+// as positional fields. Empty lines and everything after a # are ignored.
+//
+//	10.0.0.0/8 via 10.1.1.1 dev eth0 metric 100
+//	192.168.0.0/16 192.168.1.1 eth1
+//	default 10.1.1.254
+func ParseTable(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		route, err := parseTableLine(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		routes = append(routes, route...)
+	}
+	return routes, scanner.Err()
+}
+
+// routeTypes are the route types that may start a line of the output of
+// the Linux ip route command (e.g. "local 10.0.0.1 dev eth0 ...")
+var routeTypes = map[string]bool{
+	"unicast": true, "local": true, "broadcast": true, "multicast": true, "anycast": true,
+	"unreachable": true, "blackhole": true, "prohibit": true, "throw": true, "nat": true,
+}
+
+// routeFlags are the keywords of the Linux ip route command without a value
+var routeFlags = map[string]bool{
+	"onlink": true, "linkdown": true, "dead": true, "pervasive": true, "offload": true, "notify": true,
+}
+
+// parseTableLine is a function that parses the fields of a line of a
+// routing table file. A default route is returned for both families if the
+// family cannot be told from the gateway.
+func parseTableLine(fields []string) ([]Route, error) {
+	if routeTypes[fields[0]] && len(fields) > 1 {
+		fields = fields[1:]
+	}
+
+	// The line is in the format of ip route if it has any keywords
+	keywords := false
+	for _, field := range fields[1:] {
+		if field == "via" || field == "dev" || field == "metric" {
+			keywords = true
+		}
+	}
+
+	var route Route
+	var positional []string
+	for i := 1; i < len(fields); i++ {
+		if !keywords {
+			positional = append(positional, fields[i])
+			continue
+		}
+
+		// Every keyword except the flags is followed by its value
+		keyword := fields[i]
+		if routeFlags[keyword] || i+1 >= len(fields) {
+			continue
+		}
+		i++
+		switch keyword {
+		case "via":
+			// The family may be given before the gateway (via inet6 fe80::1)
+			if (fields[i] == "inet" || fields[i] == "inet6") && i+1 < len(fields) {
+				i++
+			}
+			positional = append(positional, fields[i])
+		case "dev":
+			route.Interface = fields[i]
+		case "metric":
+			metric, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid metric: %s", fields[i])
+			}
+			route.Metric = metric
+		}
+	}
+
+	// The positional fields are the gateway and the interface
+	if len(positional) > 0 {
+		gateway, err := netip.ParseAddr(positional[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway: %s", positional[0])
+		}
+		route.Gateway = gateway.Unmap()
+	}
+	if len(positional) > 1 && !keywords {
+		route.Interface = positional[1]
+	}
+
+	// The family of a default route is the family of its gateway
+	if fields[0] == "default" {
+		switch {
+		case route.Gateway.Is4():
+			route.Prefix = netip.MustParsePrefix("0.0.0.0/0")
+		case route.Gateway.Is6():
+			route.Prefix = netip.MustParsePrefix("::/0")
+		default:
+			route6 := route
+			route.Prefix, route6.Prefix = netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")
+			return []Route{route, route6}, nil
+		}
+		return []Route{route}, nil
+	}
+
+	prefix, err := ip.ParsePrefix(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	route.Prefix = prefix
+	return []Route{route}, nil
+}
+
+// parseLinuxRoutes is a function that parses the IPv4 routing table of the
+// Linux kernel in /proc/net/route, where the addresses are hexadecimal in
+// host byte order (little-endian on the supported architectures)
+func parseLinuxRoutes(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		dst, err1 := parseHexIPv4(fields[1])
+		gateway, err2 := parseHexIPv4(fields[2])
+		flags, err3 := strconv.ParseUint(fields[3], 16, 32)
+		metric, err4 := strconv.Atoi(fields[6])
+		mask, err5 := parseHexIPv4(fields[7])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			return nil, fmt.Errorf("invalid route: %s", scanner.Text())
+		}
+		if flags&flagUp == 0 || flags&flagReject != 0 {
+			continue
+		}
+
+		bits, _ := net.IPMask(mask.AsSlice()).Size()
+		route := Route{Prefix: netip.PrefixFrom(dst, bits).Masked(), Interface: fields[0], Metric: metric}
+		if !gateway.IsUnspecified() {
+			route.Gateway = gateway
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseHexIPv4 is a function that parses an IPv4 address in hexadecimal
+// little-endian notation, as in /proc/net/route
+func parseHexIPv4(s string) (netip.Addr, error) {
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(n))
+	return netip.AddrFrom4(b), nil
+}
+
+// parseLinuxIPv6Routes is a function that parses the IPv6 routing table of
+// the Linux kernel in /proc/net/ipv6_route
+func parseLinuxIPv6Routes(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Destination, prefix length, source, source prefix length, next
+		// hop, metric, reference count, use count, flags and interface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		dst, err1 := hex.DecodeString(fields[0])
+		bits, err2 := strconv.ParseUint(fields[1], 16, 8)
+		gateway, err3 := hex.DecodeString(fields[4])
+		metric, err4 := strconv.ParseUint(fields[5], 16, 32)
+		flags, err5 := strconv.ParseUint(fields[8], 16, 32)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || len(dst) != 16 || len(gateway) != 16 || bits > 128 {
+			return nil, fmt.Errorf("invalid route: %s", scanner.Text())
+		}
+		// Skip the routes to the local addresses (the local table)
+		if flags&flagUp == 0 || flags&flagReject != 0 || flags&flagLocal != 0 {
+			continue
+		}
+
+		route := Route{
+			Prefix:    netip.PrefixFrom(netip.AddrFrom16([16]byte(dst)), int(bits)).Masked(),
+			Interface: fields[9],
+			Metric:    int(metric),
+		}
+		if gw := netip.AddrFrom16([16]byte(gateway)); !gw.IsUnspecified() {
+			route.Gateway = gw
+		}
+		routes = append(routes, route)
+	}
+	return routes, scanner.Err()
+}
+
+// parseBSDRoutes is a function that parses the output of the BSD/macOS
+// command "netstat -rn", e.g. "default 192.168.1.1 UGScg en0" or
+// "192.168.1 link#4 UCS en0". Destinations without a prefix length are
+// host routes, except for the shortened classful networks of IPv4.
+func parseBSDRoutes(output string) []Route {
+	var routes []Route
+	netif := -1
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Find the interface column in the header of every table
+		if fields[0] == "Destination" {
+			netif = -1
+			for i, field := range fields {
+				if field == "Netif" {
+					netif = i
+				}
+			}
+			continue
+		}
+		if netif < 0 || len(fields) <= netif {
+			continue
+		}
+
+		route := Route{Interface: fields[netif]}
+		if gateway, err := netip.ParseAddr(fields[1]); err == nil {
+			route.Gateway = gateway
+		}
+		prefix, ok := parseBSDDestination(fields[0], route.Gateway)
+		if !ok {
+			continue
+		}
+		route.Prefix = prefix
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// parseBSDDestination is a function that parses a destination of the
+// output of netstat -rn, the family of a default route is the family of
+// the gateway
+func parseBSDDestination(s string, gateway netip.Addr) (netip.Prefix, bool) {
+	if s == "default" {
+		if gateway.Is6() {
+			return netip.MustParsePrefix("::/0"), true
+		}
+		return netip.MustParsePrefix("0.0.0.0/0"), true
+	}
+
+	// Strip the zone of link-local destinations (fe80::%lo0/64)
+	address, bits, hasBits := strings.Cut(s, "/")
+	address, _, _ = strings.Cut(address, "%")
+
+	// Shortened IPv4 networks (e.g. 127 or 192.168.1) are classful
+	if !hasBits && !strings.Contains(address, ":") {
+		octets := strings.Split(address, ".")
+		bits = strconv.Itoa(8 * len(octets))
+		for len(octets) < 4 {
+			octets = append(octets, "0")
+		}
+		address, hasBits = strings.Join(octets, "."), true
+	}
+	if hasBits {
+		address += "/" + bits
+	}
+	prefix, err := ip.ParsePrefix(address)
+	return prefix, err == nil
+}
@@ -0,0 +1,151 @@
+package route_test
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/route"
+)
+
+// testTable is a routing table in the formats accepted by ParseTable
+const testTable = `# Routes of the lab network
+default via 10.0.0.254 dev eth0 metric 100
+10.0.0.0/8 via 10.0.0.1 dev eth0 metric 10
+10.1.0.0/16 10.0.0.2 eth1
+10.1.0.0/16 via 10.0.0.3 dev eth2 metric 5
+10.1.2.3 dev eth3          # host route
+2001:db8::/32 via fe80::1 dev eth0
+local 192.0.2.2 dev eth0 table local proto kernel scope host src 192.0.2.2
+fd00::/64 dev eth0 proto kernel metric 256 linkdown pref medium
+10.9.0.0/16 via inet6 fe80::2 dev eth1 onlink
+default
+`
+
+func TestParseTable(t *testing.T) {
+	routes, err := route.ParseTable(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, r := range routes {
+		got = append(got, r.String())
+	}
+	expected := []string{
+		"0.0.0.0/0 via 10.0.0.254 dev eth0 metric 100",
+		"10.0.0.0/8 via 10.0.0.1 dev eth0 metric 10",
+		"10.1.0.0/16 via 10.0.0.2 dev eth1",
+		"10.1.0.0/16 via 10.0.0.3 dev eth2 metric 5",
+		"10.1.2.3/32 dev eth3",
+		"2001:db8::/32 via fe80::1 dev eth0",
+		"192.0.2.2/32 dev eth0",
+		"fd00::/64 dev eth0 metric 256",
+		"10.9.0.0/16 via fe80::2 dev eth1",
+		"0.0.0.0/0",
+		"::/0",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// Invalid lines are reported with their line number
+	for _, table := range []string{"10.0.0.0/33", "10.0.0.0/8 via nowhere", "10.0.0.0/8 metric x"} {
+		if _, err := route.ParseTable(strings.NewReader("\n" + table)); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("%s: expected an error on line 2, got %v", table, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	routes, err := route.ParseTable(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "10.1.2.3", expected: "10.1.2.3/32 dev eth3"},
+		{addr: "10.1.2.4", expected: "10.1.0.0/16 via 10.0.0.2 dev eth1"},
+		{addr: "10.2.0.1", expected: "10.0.0.0/8 via 10.0.0.1 dev eth0 metric 10"},
+		{addr: "192.0.2.1", expected: "0.0.0.0/0"},
+		{addr: "2001:db8::1", expected: "2001:db8::/32 via fe80::1 dev eth0"},
+		{addr: "::ffff:10.2.0.1", expected: "10.0.0.0/8 via 10.0.0.1 dev eth0 metric 10"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			r, ok := route.Match(routes, netip.MustParseAddr(tc.addr))
+			if !ok {
+				t.Fatalf("expected a route")
+			}
+			if r.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, r)
+			}
+		})
+	}
+
+	// No route matches without a default route
+	if r, ok := route.Match(routes[1:2], netip.MustParseAddr("192.0.2.1")); ok {
+		t.Errorf("expected no route, got %s", r)
+	}
+}
+
+func TestSort(t *testing.T) {
+	routes, err := route.ParseTable(strings.NewReader("2001:db8::/32\n10.1.0.0/16 metric 5\n10.0.0.0/8\n10.1.0.0/16 metric 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	route.Sort(routes)
+
+	var got []string
+	for _, r := range routes {
+		got = append(got, r.String())
+	}
+	expected := "10.0.0.0/8,10.1.0.0/16 metric 1,10.1.0.0/16 metric 5,2001:db8::/32"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, ","))
+	}
+}
+
+func TestTableLookup(t *testing.T) {
+	routes, err := route.ParseTable(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table := route.NewTable(routes)
+	if table.Len() != 9 {
+		t.Errorf("expected 9 prefixes, got %d", table.Len())
+	}
+
+	// The ties are sorted by metric, the covering routes by prefix length
+	result, ok := table.Lookup(netip.MustParseAddr("10.1.9.9"))
+	if !ok {
+		t.Fatal("expected a route")
+	}
+	toStrings := func(routes []route.Route) string {
+		var list []string
+		for _, r := range routes {
+			list = append(list, r.String())
+		}
+		return strings.Join(list, ", ")
+	}
+	if got := result.Route.String(); got != "10.1.0.0/16 via 10.0.0.2 dev eth1" {
+		t.Errorf("unexpected route: %s", got)
+	}
+	if got := toStrings(result.Ties); got != "10.1.0.0/16 via 10.0.0.3 dev eth2 metric 5" {
+		t.Errorf("unexpected ties: %s", got)
+	}
+	if got := toStrings(result.Covering); got != "10.0.0.0/8 via 10.0.0.1 dev eth0 metric 10, 0.0.0.0/0, 0.0.0.0/0 via 10.0.0.254 dev eth0 metric 100" {
+		t.Errorf("unexpected covering routes: %s", got)
+	}
+
+	// No route matches an address outside the table
+	if _, ok := route.NewTable(routes[1:2]).Lookup(netip.MustParseAddr("192.0.2.1")); ok {
+		t.Error("expected no route")
+	}
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package route
+
+import (
+	"io"
+	"os"
+)
+
+// ReadTable is a function that returns the routes in the IPv4 and IPv6
+// routing tables of the operating system
+func ReadTable() ([]Route, error) {
+	var routes []Route
+	for _, table := range []struct {
+		path  string
+		parse func(io.Reader) ([]Route, error)
+	}{
+		{"/proc/net/route", parseLinuxRoutes},
+		{"/proc/net/ipv6_route", parseLinuxIPv6Routes},
+	} {
+		f, err := os.Open(table.path)
+		if os.IsNotExist(err) {
+			// IPv6 may be disabled in the kernel
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list, err := table.parse(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, list...)
+	}
+	Sort(routes)
+	return routes, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The kinds of changes between two results
+const (
+	ChangeAppeared    = "appeared"
+	ChangeDisappeared = "disappeared"
+	ChangeChanged     = "changed"
+)
+
+// Change is a difference between two results, of a host or of a port of a
+// host (if Port is set)
+type Change struct {
+	Kind    string `json:"kind"`
+	Address string `json:"address"`
+	Port    string `json:"port,omitempty"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+// String is a function that returns a description of the change
+func (c Change) String() string {
+	subject := "host " + c.Address
+	if c.Port != "" {
+		subject = fmt.Sprintf("port %s on %s", c.Port, c.Address)
+	}
+	switch c.Kind {
+	case ChangeAppeared:
+		return fmt.Sprintf("%s appeared (%s)", subject, c.New)
+	case ChangeDisappeared:
+		return fmt.Sprintf("%s disappeared (was %s)", subject, c.Old)
+	default:
+		return fmt.Sprintf("%s changed: %s -> %s", subject, c.Old, c.New)
+	}
+}
+
+// Diff is a function that compares two results and returns the hosts and
+// ports that appeared, disappeared or changed, ordered by address. A host
+// changes when its state or MAC address changes, a port when its state
+// changes. Hosts that are down count as absent, and the ports of hosts that
+// appeared or disappeared are part of the description of the host.
+func Diff(old, new []Host) []Change {
+	index := func(hosts []Host) map[string]Host {
+		m := make(map[string]Host)
+		for _, h := range Merge(hosts) {
+			if isUp(h.State) {
+				m[h.Address] = h
+			}
+		}
+		return m
+	}
+	o, n := index(old), index(new)
+
+	var changes []Change
+	for _, h := range Merge(old, new) {
+		oh, inOld := o[h.Address]
+		nh, inNew := n[h.Address]
+		switch {
+		case !inOld && !inNew:
+			continue
+		case !inOld:
+			changes = append(changes, Change{Kind: ChangeAppeared, Address: h.Address, New: hostSummary(nh)})
+		case !inNew:
+			changes = append(changes, Change{Kind: ChangeDisappeared, Address: h.Address, Old: hostSummary(oh)})
+		default:
+			if oh.State != nh.State || oh.MAC != nh.MAC {
+				changes = append(changes, Change{Kind: ChangeChanged, Address: h.Address, Old: hostSummary(oh), New: hostSummary(nh)})
+			}
+			changes = append(changes, diffPorts(h.Address, oh.Ports, nh.Ports)...)
+		}
+	}
+	return changes
+}
+
+// hostSummary is a function that returns the state, MAC address and open
+// ports of a host for the description of a change
+func hostSummary(h Host) string {
+	summary := []string{h.State}
+	if h.MAC != "" {
+		summary = append(summary, h.MAC)
+	}
+	for _, p := range h.Ports {
+		if p.State == "open" {
+			summary = append(summary, p.String())
+		}
+	}
+	return strings.Join(summary, ", ")
+}
+
+// diffPorts is a function that compares the ports of a host in two results
+func diffPorts(address string, old, new []Port) []Change {
+	var changes []Change
+	for _, p := range mergePorts(old, new) {
+		i, j := indexPort(old, p), indexPort(new, p)
+		switch {
+		case i < 0:
+			changes = append(changes, Change{Kind: ChangeAppeared, Address: address, Port: p.String(), New: new[j].State})
+		case j < 0:
+			changes = append(changes, Change{Kind: ChangeDisappeared, Address: address, Port: p.String(), Old: old[i].State})
+		case old[i].State != new[j].State:
+			changes = append(changes, Change{Kind: ChangeChanged, Address: address, Port: p.String(), Old: old[i].State, New: new[j].State})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,60 @@
+package scan_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/scan"
+)
+
+func TestDiff(t *testing.T) {
+	old := []scan.Host{
+		{Address: "10.0.0.1", State: "up", MAC: "00:11:22:33:44:55", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "open"}, {Port: 80, Protocol: "tcp", State: "open"}}},
+		{Address: "10.0.0.2", State: "up"},
+		{Address: "10.0.0.3", State: "down"},
+		{Address: "10.0.0.4", State: "REACHABLE"},
+	}
+	new := []scan.Host{
+		{Address: "10.0.0.1", State: "up", MAC: "00:11:22:33:44:55", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "filtered"}, {Port: 443, Protocol: "tcp", State: "open"}}},
+		{Address: "10.0.0.2", State: "down"},
+		{Address: "10.0.0.4", State: "STALE"},
+		{Address: "10.0.0.5", State: "up", Ports: []scan.Port{{Port: 53, Protocol: "udp", State: "open"}}},
+	}
+	expected := []scan.Change{
+		{Kind: scan.ChangeChanged, Address: "10.0.0.1", Port: "22/tcp", Old: "open", New: "filtered"},
+		{Kind: scan.ChangeDisappeared, Address: "10.0.0.1", Port: "80/tcp", Old: "open"},
+		{Kind: scan.ChangeAppeared, Address: "10.0.0.1", Port: "443/tcp", New: "open"},
+		{Kind: scan.ChangeDisappeared, Address: "10.0.0.2", Old: "up"},
+		{Kind: scan.ChangeChanged, Address: "10.0.0.4", Old: "REACHABLE", New: "STALE"},
+		{Kind: scan.ChangeAppeared, Address: "10.0.0.5", New: "up, 53/udp"},
+	}
+
+	changes := scan.Diff(old, new)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+	if changes := scan.Diff(old, old); changes != nil {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		change   scan.Change
+		expected string
+	}{
+		{change: scan.Change{Kind: scan.ChangeAppeared, Address: "10.0.0.5", New: "up"}, expected: "host 10.0.0.5 appeared (up)"},
+		{change: scan.Change{Kind: scan.ChangeDisappeared, Address: "10.0.0.1", Port: "80/tcp", Old: "open"}, expected: "port 80/tcp on 10.0.0.1 disappeared (was open)"},
+		{change: scan.Change{Kind: scan.ChangeChanged, Address: "10.0.0.1", Port: "22/tcp", Old: "open", New: "filtered"}, expected: "port 22/tcp on 10.0.0.1 changed: open -> filtered"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			if result := tc.change.String(); result != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
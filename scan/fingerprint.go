@@ -0,0 +1,233 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Fingerprint is the service found on an open port by FingerprintPort
+type Fingerprint struct {
+	Service string
+	Version string
+}
+
+// IdentifyBanner is a function that identifies a service from the first
+// bytes it sent: a greeting sent by the server on connect (SSH, FTP, SMTP,
+// POP3, IMAP, VNC, MySQL), or its response to an HTTP request (HTTP, Redis,
+// memcached). It returns false if the banner is not recognized.
+func IdentifyBanner(banner []byte) (Fingerprint, bool) {
+	// The MySQL greeting is a binary packet: a 3 byte length, a sequence
+	// number of 0, protocol version 10 and the server version
+	if len(banner) > 5 && banner[3] == 0 && banner[4] == 10 {
+		if end := bytes.IndexByte(banner[5:], 0); end > 0 {
+			return Fingerprint{Service: "mysql", Version: cleanVersion(string(banner[5 : 5+end]))}, true
+		}
+	}
+
+	line, _, _ := strings.Cut(string(banner), "\n")
+	line = strings.TrimRight(line, "\r")
+	upper := strings.ToUpper(line)
+
+	switch {
+	case strings.HasPrefix(line, "SSH-"):
+		// SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1
+		parts := strings.SplitN(line, "-", 3)
+		version := ""
+		if len(parts) == 3 {
+			version = parts[2]
+		}
+		return Fingerprint{Service: "ssh", Version: cleanVersion(version)}, true
+	case strings.HasPrefix(line, "HTTP/"):
+		return Fingerprint{Service: "http", Version: cleanVersion(httpHeader(banner, "Server"))}, true
+	case strings.HasPrefix(line, "RFB "):
+		return Fingerprint{Service: "vnc", Version: cleanVersion(strings.TrimPrefix(line, "RFB "))}, true
+	case strings.HasPrefix(line, "+OK"):
+		return Fingerprint{Service: "pop3", Version: cleanVersion(strings.TrimPrefix(line, "+OK"))}, true
+	case strings.HasPrefix(line, "* OK"):
+		return Fingerprint{Service: "imap", Version: cleanVersion(strings.TrimPrefix(line, "* OK"))}, true
+	case strings.HasPrefix(line, "220"):
+		version := cleanVersion(strings.TrimLeft(line[3:], "- "))
+		switch {
+		case strings.Contains(upper, "FTP"):
+			return Fingerprint{Service: "ftp", Version: version}, true
+		case strings.Contains(upper, "SMTP") || strings.Contains(upper, "MAIL"):
+			return Fingerprint{Service: "smtp", Version: version}, true
+		}
+	case strings.HasPrefix(line, "-ERR") || strings.HasPrefix(line, "-NOAUTH") || strings.HasPrefix(line, "-DENIED"):
+		return Fingerprint{Service: "redis"}, true
+	case line == "ERROR":
+		return Fingerprint{Service: "memcached"}, true
+	case strings.HasPrefix(line, "AMQP"):
+		return Fingerprint{Service: "amqp"}, true
+	}
+	return Fingerprint{}, false
+}
+
+// isTLSRecord is a function that reports whether data starts with a TLS
+// record (e.g. the alert a TLS server sends in response to plain text)
+func isTLSRecord(data []byte) bool {
+	return len(data) >= 3 && data[0] >= 0x14 && data[0] <= 0x17 && data[1] == 3
+}
+
+// isPlainHTTPError is a function that reports whether an HTTP response is
+// the error of an HTTPS server to a plain HTTP request
+func isPlainHTTPError(response []byte) bool {
+	return bytes.HasPrefix(response, []byte("HTTP/")) && bytes.Contains(response, []byte(" 400 ")) &&
+		bytes.Contains(bytes.ToUpper(response), []byte("HTTPS"))
+}
+
+// httpHeader is a function that returns the value of a header of an HTTP
+// response, or an empty string if the header is not found
+func httpHeader(response []byte, name string) string {
+	for _, line := range strings.Split(string(response), "\n") {
+		key, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// cleanVersion is a function that returns a version string with only
+// printable characters, at most 60 characters long
+func cleanVersion(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if len(s) > 60 {
+		s = s[:60]
+	}
+	return s
+}
+
+// tlsVersions are the names of the TLS versions
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsServices are the names of the services that are wrapped in TLS
+var tlsServices = map[string]string{
+	"http": "https",
+	"imap": "imaps",
+	"pop3": "pop3s",
+	"smtp": "smtps",
+	"ftp":  "ftps",
+}
+
+// FingerprintPort is a function that connects to an open port and
+// identifies the service on it: it first waits for a greeting, then sends an
+// HTTP request, and finally tries a TLS handshake and does the same over
+// TLS. Every step waits at most the timeout. The fingerprint is empty if the
+// service was not recognized.
+func FingerprintPort(ctx context.Context, address string, timeout time.Duration) Fingerprint {
+	host, _, _ := net.SplitHostPort(address)
+
+	// Plain text: a greeting or the response to an HTTP request
+	response, err := exchange(ctx, address, timeout, nil, host)
+	if err == nil {
+		fp, ok := IdentifyBanner(response)
+		switch {
+		case isPlainHTTPError(response):
+			// An HTTPS server that answered the plain HTTP request with an error
+		case ok:
+			return fp
+		case len(response) > 0 && !isTLSRecord(response):
+			// An unknown service, its greeting is the best guess of its version
+			return Fingerprint{Version: cleanVersion(strings.SplitN(string(response), "\n", 2)[0])}
+		}
+	}
+
+	// TLS: the same over a TLS connection
+	var state tls.ConnectionState
+	response, err = exchange(ctx, address, timeout, &state, host)
+	if err != nil {
+		return Fingerprint{}
+	}
+	fp, ok := IdentifyBanner(response)
+	if name, wrapped := tlsServices[fp.Service]; wrapped {
+		fp.Service = name
+	} else if ok {
+		fp.Service = "ssl/" + fp.Service
+	} else {
+		fp.Service = "tls"
+	}
+	if version, ok := tlsVersions[state.Version]; ok {
+		fp.Version = strings.TrimSpace(fp.Version + " (" + version + ")")
+	}
+	return fp
+}
+
+// exchange is a function that connects to the address (with TLS if state is
+// not nil, the connection state is stored in it) and returns the greeting
+// of the server, or its response to an HTTP request if it sends no greeting
+func exchange(ctx context.Context, address string, timeout time.Duration, state *tls.ConnectionState, host string) ([]byte, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if state != nil {
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host})
+		conn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		*state = tlsConn.ConnectionState()
+		conn = tlsConn
+	}
+
+	// Wait for a greeting
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	if n, err := conn.Read(buf); n > 0 {
+		return buf[:n], nil
+	} else if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		return nil, err
+	}
+
+	// No greeting, send an HTTP request
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\nUser-Agent: iptool\r\n\r\n", host); err != nil {
+		return nil, err
+	}
+	n, err := conn.Read(buf)
+	if n == 0 {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,100 @@
+package scan_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/scan"
+)
+
+func TestIdentifyBanner(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		banner   string
+		expected scan.Fingerprint
+		ok       bool
+	}{
+		{name: "SSH", banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.1\r\n", expected: scan.Fingerprint{Service: "ssh", Version: "OpenSSH_8.9p1 Ubuntu-3ubuntu0.1"}, ok: true},
+		{name: "HTTP", banner: "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nServer: nginx/1.24.0\r\n\r\n", expected: scan.Fingerprint{Service: "http", Version: "nginx/1.24.0"}, ok: true},
+		{name: "HTTPNoServer", banner: "HTTP/1.0 404 Not Found\r\n\r\n", expected: scan.Fingerprint{Service: "http"}, ok: true},
+		{name: "FTP", banner: "220 (vsFTPd 3.0.5)\r\n", expected: scan.Fingerprint{Service: "ftp", Version: "(vsFTPd 3.0.5)"}, ok: true},
+		{name: "SMTP", banner: "220 mail.example.com ESMTP Postfix (Ubuntu)\r\n", expected: scan.Fingerprint{Service: "smtp", Version: "mail.example.com ESMTP Postfix (Ubuntu)"}, ok: true},
+		{name: "POP3", banner: "+OK Dovecot ready.\r\n", expected: scan.Fingerprint{Service: "pop3", Version: "Dovecot ready."}, ok: true},
+		{name: "IMAP", banner: "* OK [CAPABILITY IMAP4rev1] Dovecot ready.\r\n", expected: scan.Fingerprint{Service: "imap", Version: "[CAPABILITY IMAP4rev1] Dovecot ready."}, ok: true},
+		{name: "VNC", banner: "RFB 003.008\n", expected: scan.Fingerprint{Service: "vnc", Version: "003.008"}, ok: true},
+		{name: "MySQL", banner: "J\x00\x00\x00\x0a8.0.35\x00\x08\x00\x00\x00", expected: scan.Fingerprint{Service: "mysql", Version: "8.0.35"}, ok: true},
+		{name: "Redis", banner: "-ERR unknown command 'HEAD'\r\n", expected: scan.Fingerprint{Service: "redis"}, ok: true},
+		{name: "Memcached", banner: "ERROR\r\n", expected: scan.Fingerprint{Service: "memcached"}, ok: true},
+		{name: "Unknown220", banner: "220 Welcome\r\n", ok: false},
+		{name: "Unknown", banner: "hello\r\n", ok: false},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := scan.IdentifyBanner([]byte(tc.banner))
+			if ok != tc.ok {
+				t.Fatalf("expected ok %v, got %v", tc.ok, ok)
+			}
+			if ok && got != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFingerprintPort(t *testing.T) {
+	// A server that sends a greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			conn.Close()
+		}
+	}()
+
+	// HTTP servers with and without TLS
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test/1.0")
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	// Setup test cases
+	testCases := []struct {
+		name    string
+		address string
+		service string
+		version string
+	}{
+		{name: "Greeting", address: listener.Addr().String(), service: "ssh", version: "OpenSSH_9.6"},
+		{name: "HTTP", address: strings.TrimPrefix(plain.URL, "http://"), service: "http", version: "test/1.0"},
+		{name: "HTTPS", address: strings.TrimPrefix(secure.URL, "https://"), service: "https", version: "test/1.0 (TLS 1.3)"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := scan.FingerprintPort(context.Background(), tc.address, 200*time.Millisecond)
+			if got.Service != tc.service || got.Version != tc.version {
+				t.Errorf("expected %s %q, got %s %q", tc.service, tc.version, got.Service, got.Version)
+			}
+		})
+	}
+}
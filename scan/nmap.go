@@ -0,0 +1,324 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package scan
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmapRun is the root element of Nmap XML output
+type nmapRun struct {
+	XMLName          xml.Name      `xml:"nmaprun"`
+	Scanner          string        `xml:"scanner,attr"`
+	Args             string        `xml:"args,attr,omitempty"`
+	Start            int64         `xml:"start,attr,omitempty"`
+	StartStr         string        `xml:"startstr,attr,omitempty"`
+	Version          string        `xml:"version,attr,omitempty"`
+	XMLOutputVersion string        `xml:"xmloutputversion,attr"`
+	Hosts            []nmapHost    `xml:"host"`
+	RunStats         *nmapRunStats `xml:"runstats,omitempty"`
+}
+
+// nmapHost is a host element of Nmap XML output
+type nmapHost struct {
+	Status    *nmapStatus    `xml:"status"`
+	Addresses []nmapAddress  `xml:"address"`
+	Hostnames []nmapHostname `xml:"hostnames>hostname"`
+	Ports     []nmapPort     `xml:"ports>port"`
+}
+
+type nmapStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr,omitempty"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapStatus   `xml:"state"`
+	Service  *nmapService `xml:"service"`
+}
+
+type nmapService struct {
+	Name    string `xml:"name,attr"`
+	Version string `xml:"version,attr,omitempty"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64  `xml:"time,attr"`
+	TimeStr string `xml:"timestr,attr"`
+	Exit    string `xml:"exit,attr"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// Run describes the sweep or scan that found the hosts, it is written to the
+// header of the Nmap formats
+type Run struct {
+	Scanner string
+	Version string
+	Args    string
+	Start   time.Time
+	End     time.Time
+}
+
+// nmapTimeLayout is the layout of the human readable times in Nmap output
+const nmapTimeLayout = "Mon Jan 2 15:04:05 2006"
+
+// isUp is a function that reports whether the state of a host means that it
+// is up (anything but down, e.g. an NDP neighbor state)
+func isUp(state string) bool {
+	return !strings.EqualFold(state, "down")
+}
+
+// addrType is a function that returns the Nmap address type of an address
+func addrType(address string) string {
+	if addr, err := netip.ParseAddr(address); err == nil && addr.Is6() {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// WriteXML is a function that writes hosts in the Nmap XML format, which
+// can be read by the tools that process Nmap results
+func WriteXML(w io.Writer, run Run, hosts []Host) error {
+	doc := nmapRun{
+		Scanner:          run.Scanner,
+		Args:             run.Args,
+		Start:            run.Start.Unix(),
+		StartStr:         run.Start.Format(nmapTimeLayout),
+		Version:          run.Version,
+		XMLOutputVersion: "1.05",
+		RunStats: &nmapRunStats{
+			Finished: nmapFinished{Time: run.End.Unix(), TimeStr: run.End.Format(nmapTimeLayout), Exit: "success"},
+		},
+	}
+
+	for _, h := range hosts {
+		state := "up"
+		if !isUp(h.State) {
+			state = "down"
+			doc.RunStats.Hosts.Down++
+		} else {
+			doc.RunStats.Hosts.Up++
+		}
+
+		host := nmapHost{
+			Status:    &nmapStatus{State: state, Reason: h.Source},
+			Addresses: []nmapAddress{{Addr: h.Address, AddrType: addrType(h.Address)}},
+		}
+		if h.MAC != "" {
+			host.Addresses = append(host.Addresses, nmapAddress{Addr: strings.ToUpper(h.MAC), AddrType: "mac"})
+		}
+		if h.Hostname != "" {
+			host.Hostnames = []nmapHostname{{Name: h.Hostname, Type: "PTR"}}
+		}
+		for _, p := range h.Ports {
+			port := nmapPort{Protocol: p.Protocol, PortID: p.Port, State: nmapStatus{State: p.State}}
+			if p.Service != "" || p.Version != "" {
+				port.Service = &nmapService{Name: p.Service, Version: p.Version}
+			}
+			host.Ports = append(host.Ports, port)
+		}
+		doc.Hosts = append(doc.Hosts, host)
+	}
+	doc.RunStats.Hosts.Total = len(hosts)
+
+	io.WriteString(w, xml.Header+"<!DOCTYPE nmaprun>\n")
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadXML is a function that reads hosts from Nmap or masscan XML output.
+// masscan does not write the status of the hosts, they are up.
+func ReadXML(r io.Reader) ([]Host, error) {
+	var doc nmapRun
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid Nmap XML: %w", err)
+	}
+
+	var hosts []Host
+	for _, nh := range doc.Hosts {
+		h := Host{State: "up", Source: doc.Scanner}
+		if nh.Status != nil {
+			h.State = nh.Status.State
+		}
+		for _, a := range nh.Addresses {
+			if a.AddrType == "mac" {
+				h.MAC = strings.ToLower(a.Addr)
+			} else if h.Address == "" {
+				h.Address = a.Addr
+			}
+		}
+		if h.Address == "" {
+			continue
+		}
+		if len(nh.Hostnames) > 0 {
+			h.Hostname = nh.Hostnames[0].Name
+		}
+		for _, np := range nh.Ports {
+			p := Port{Port: np.PortID, Protocol: np.Protocol, State: np.State.State}
+			if np.Service != nil {
+				p.Service = np.Service.Name
+				p.Version = np.Service.Version
+			}
+			h.Ports = append(h.Ports, p)
+		}
+		hosts = append(hosts, h)
+	}
+	return Merge(hosts), nil
+}
+
+// WriteGreppable is a function that writes hosts in the Nmap greppable
+// format (-oG), one line per host with its status and one line with its
+// ports if it has any
+func WriteGreppable(w io.Writer, run Run, hosts []Host) error {
+	fmt.Fprintf(w, "# %s %s scan initiated %s as: %s\n", run.Scanner, run.Version, run.Start.Format(nmapTimeLayout), run.Args)
+	up := 0
+	for _, h := range hosts {
+		state := "Up"
+		if !isUp(h.State) {
+			state = "Down"
+		} else {
+			up++
+		}
+		host := fmt.Sprintf("Host: %s (%s)", h.Address, h.Hostname)
+		fmt.Fprintf(w, "%s\tStatus: %s\n", host, state)
+
+		if len(h.Ports) > 0 {
+			ports := make([]string, len(h.Ports))
+			for i, p := range h.Ports {
+				ports[i] = fmt.Sprintf("%d/%s/%s//%s//%s/", p.Port, p.State, p.Protocol, p.Service, greppableField(p.Version))
+			}
+			fmt.Fprintf(w, "%s\tPorts: %s\n", host, strings.Join(ports, ", "))
+		}
+	}
+	_, err := fmt.Fprintf(w, "# %s done at %s -- %d IP addresses (%d hosts up) scanned in %.2f seconds\n",
+		run.Scanner, run.End.Format(nmapTimeLayout), len(hosts), up, run.End.Sub(run.Start).Seconds())
+	return err
+}
+
+// ReadGreppable is a function that reads hosts from Nmap or masscan
+// greppable output. Comments and fields other than Host, Status and Ports
+// are ignored.
+func ReadGreppable(r io.Reader) ([]Host, error) {
+	var hosts []Host
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		h := Host{State: "up"}
+		for _, field := range strings.Split(text, "\t") {
+			key, value, _ := strings.Cut(strings.TrimSpace(field), ": ")
+			switch key {
+			case "Host":
+				address, name, _ := strings.Cut(value, " ")
+				h.Address = address
+				h.Hostname = strings.Trim(name, "()")
+			case "Status":
+				h.State = strings.ToLower(value)
+			case "Ports":
+				for _, entry := range strings.Split(value, ",") {
+					p, err := parseGreppablePort(strings.TrimSpace(entry))
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %w", line, err)
+					}
+					h.Ports = append(h.Ports, p)
+				}
+			}
+		}
+		if _, err := netip.ParseAddr(h.Address); err != nil {
+			return nil, fmt.Errorf("line %d: not Nmap greppable output: %s", line, text)
+		}
+		hosts = append(hosts, h)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return Merge(hosts), nil
+}
+
+// parseGreppablePort is a function that parses a port of greppable output
+// (port/state/protocol/owner/service/rpc info/version/)
+func parseGreppablePort(s string) (Port, error) {
+	fields := strings.Split(s, "/")
+	if len(fields) < 3 {
+		return Port{}, fmt.Errorf("invalid port: %s", s)
+	}
+	number, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Port{}, fmt.Errorf("invalid port: %s", s)
+	}
+	p := Port{Port: number, State: fields[1], Protocol: fields[2]}
+	if len(fields) > 4 {
+		p.Service = fields[4]
+	}
+	if len(fields) > 6 {
+		p.Version = fields[6]
+	}
+	return p, nil
+}
+
+// greppableField is a function that replaces the separators of the
+// greppable format in a field, like Nmap does
+func greppableField(s string) string {
+	return strings.NewReplacer("/", "|", ",", " ").Replace(s)
+}
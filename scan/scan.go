@@ -0,0 +1,223 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package scan holds the results of host discovery (sweeps) and port scans,
+// and reads and writes them in the formats of other scanners (Nmap XML and
+// greppable output, which masscan writes as well), so that results can be
+// merged with existing scanning pipelines.
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+
+	"github.com/bitcanon/iptool/envelope"
+)
+
+// Host is a host found by a sweep or a scan
+type Host struct {
+	Address   string `json:"address"`
+	Hostname  string `json:"hostname,omitempty"`
+	MAC       string `json:"mac,omitempty"`
+	State     string `json:"state,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	Ports     []Port `json:"ports,omitempty"`
+}
+
+// Port is a port of a host found by a scan
+type Port struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	State    string `json:"state"`
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// String is a function that returns the port in the format port/protocol
+func (p Port) String() string {
+	return fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+}
+
+// Merge is a function that merges lists of hosts into one list sorted by
+// address, with the ports of every host sorted by protocol and port. The ports of a host found in several lists are merged, and the
+// fields of a later list take precedence over those of an earlier list.
+func Merge(lists ...[]Host) []Host {
+	index := make(map[string]int)
+	var merged []Host
+	for _, hosts := range lists {
+		for _, h := range hosts {
+			i, ok := index[h.Address]
+			if !ok {
+				h.Ports = mergePorts(nil, h.Ports)
+				index[h.Address] = len(merged)
+				merged = append(merged, h)
+				continue
+			}
+
+			// Merge the fields that are set and the ports
+			m := &merged[i]
+			for _, f := range []struct{ dst, src *string }{
+				{&m.Hostname, &h.Hostname}, {&m.MAC, &h.MAC}, {&m.State, &h.State}, {&m.Source, &h.Source}, {&m.Interface, &h.Interface},
+			} {
+				if *f.src != "" {
+					*f.dst = *f.src
+				}
+			}
+			m.Ports = mergePorts(m.Ports, h.Ports)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return compareAddresses(merged[i].Address, merged[j].Address) < 0
+	})
+	return merged
+}
+
+// mergePorts is a function that merges two lists of ports into one list
+// sorted by protocol and port, the ports of b take precedence
+func mergePorts(a, b []Port) []Port {
+	var merged []Port
+	for _, p := range append(append([]Port{}, a...), b...) {
+		i := indexPort(merged, p)
+		if i < 0 {
+			merged = append(merged, p)
+		} else {
+			merged[i] = p
+		}
+	}
+	sortPorts(merged)
+	return merged
+}
+
+// indexPort is a function that returns the index of the port with the same
+// number and protocol as p, or -1 if there is none
+func indexPort(ports []Port, p Port) int {
+	for i, q := range ports {
+		if q.Port == p.Port && q.Protocol == p.Protocol {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortPorts is a function that sorts ports by protocol and port number
+func sortPorts(ports []Port) {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].Port < ports[j].Port
+	})
+}
+
+// compareAddresses is a function that compares two addresses numerically,
+// addresses that do not parse are sorted as strings after the others
+func compareAddresses(a, b string) int {
+	aa, errA := netip.ParseAddr(a)
+	ab, errB := netip.ParseAddr(b)
+	switch {
+	case errA == nil && errB == nil:
+		if aa.Is4() != ab.Is4() {
+			if aa.Is4() {
+				return -1
+			}
+			return 1
+		}
+		return aa.Compare(ab)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Read is a function that reads hosts in any of the supported formats: the
+// JSON records written with --format json, Nmap (or masscan) XML or Nmap
+// (or masscan) greppable output. The format is detected from the content.
+func Read(r io.Reader) ([]Host, error) {
+	buffered := bufio.NewReader(r)
+	if envelope.IsJSON(buffered) {
+		return ReadJSON(buffered)
+	}
+	head, _ := buffered.Peek(512)
+	if bytes.HasPrefix(bytes.TrimSpace(head), []byte("<")) {
+		return ReadXML(buffered)
+	}
+	return ReadGreppable(buffered)
+}
+
+// ReadFile is a function that reads hosts from a file, see Read
+func ReadFile(name string) ([]Host, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hosts, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return hosts, nil
+}
+
+// ReadJSON is a function that reads hosts from the JSON records written
+// with --format json. The target of the record is the address of the host.
+func ReadJSON(r io.Reader) ([]Host, error) {
+	var hosts []Host
+	err := envelope.Read(r, func(record envelope.Record) error {
+		var h Host
+		if len(record.Data) > 0 {
+			if err := json.Unmarshal(record.Data, &h); err != nil {
+				return err
+			}
+		}
+		h.Address = record.Target
+		hosts = append(hosts, h)
+		return nil
+	})
+	return hosts, err
+}
+
+// WriteJSON is a function that writes hosts as JSON records
+func WriteJSON(w io.Writer, hosts []Host) error {
+	writer := envelope.NewWriter(w)
+	for _, h := range hosts {
+		if err := writer.Write(envelope.KindHost, h.Address, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
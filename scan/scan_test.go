@@ -0,0 +1,172 @@
+package scan_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/scan"
+)
+
+// nmapXML is the output of nmap -oX for a host with two open ports
+const nmapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap" args="nmap -oX - 10.0.0.0/24" start="1700000000" version="7.94" xmloutputversion="1.05">
+<host starttime="1700000000" endtime="1700000001"><status state="up" reason="arp-response" reason_ttl="0"/>
+<address addr="10.0.0.10" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac" vendor="Example"/>
+<hostnames><hostname name="web01.example.com" type="PTR"/></hostnames>
+<ports><extraports state="closed" count="998"/>
+<port protocol="tcp" portid="443"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="https" method="table" conf="3"/></port>
+<port protocol="tcp" portid="22"><state state="open" reason="syn-ack" reason_ttl="64"/><service name="ssh" method="table" conf="3"/></port>
+</ports>
+</host>
+<host><status state="down" reason="no-response"/><address addr="10.0.0.2" addrtype="ipv4"/></host>
+<runstats><finished time="1700000010" exit="success"/><hosts up="1" down="1" total="2"/></runstats>
+</nmaprun>
+`
+
+// masscanXML is the output of masscan -oX, without host status
+const masscanXML = `<?xml version="1.0"?>
+<nmaprun scanner="masscan" start="1700000000" version="1.0-BETA" xmloutputversion="1.03">
+<host endtime="1700000001"><address addr="10.0.0.10" addrtype="ipv4"/><ports><port protocol="tcp" portid="80"><state state="open" reason="syn-ack" reason_ttl="64"/></port></ports></host>
+</nmaprun>
+`
+
+// greppable is the output of nmap -oG and masscan -oG
+const greppable = `# Nmap 7.94 scan initiated Tue Nov 14 22:13:20 2023 as: nmap -oG - 10.0.0.0/24
+Host: 10.0.0.10 (web01.example.com)	Status: Up
+Host: 10.0.0.10 (web01.example.com)	Ports: 22/open/tcp//ssh///, 443/open/tcp//https///	Ignored State: closed (998)
+Timestamp: 1700000000	Host: 10.0.0.11 ()	Ports: 53/open/udp////
+# Nmap done at Tue Nov 14 22:13:22 2023 -- 256 IP addresses (2 hosts up) scanned in 2.10 seconds
+`
+
+func TestRead(t *testing.T) {
+	web := scan.Host{
+		Address:  "10.0.0.10",
+		Hostname: "web01.example.com",
+		MAC:      "00:11:22:33:44:55",
+		State:    "up",
+		Source:   "nmap",
+		Ports: []scan.Port{
+			{Port: 22, Protocol: "tcp", State: "open", Service: "ssh"},
+			{Port: 443, Protocol: "tcp", State: "open", Service: "https"},
+		},
+	}
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    string
+		expected []scan.Host
+	}{
+		{
+			name:     "Nmap XML",
+			input:    nmapXML,
+			expected: []scan.Host{{Address: "10.0.0.2", State: "down", Source: "nmap"}, web},
+		},
+		{
+			name:     "Masscan XML",
+			input:    masscanXML,
+			expected: []scan.Host{{Address: "10.0.0.10", State: "up", Source: "masscan", Ports: []scan.Port{{Port: 80, Protocol: "tcp", State: "open"}}}},
+		},
+		{
+			name:  "Greppable",
+			input: greppable,
+			expected: []scan.Host{
+				{Address: "10.0.0.10", Hostname: "web01.example.com", State: "up", Ports: web.Ports},
+				{Address: "10.0.0.11", State: "up", Ports: []scan.Port{{Port: 53, Protocol: "udp", State: "open"}}},
+			},
+		},
+		{
+			name:     "JSON",
+			input:    `{"schema":"iptool/v1","kind":"host","target":"fe80::1","data":{"address":"fe80::1","mac":"00:11:22:33:44:55","state":"REACHABLE","source":"echo"}}`,
+			expected: []scan.Host{{Address: "fe80::1", MAC: "00:11:22:33:44:55", State: "REACHABLE", Source: "echo"}},
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts, err := scan.Read(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(hosts, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, hosts)
+			}
+		})
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for _, input := range []string{"not a scan result\n", "<nmaprun><host>", "Host: 10.0.0.1 ()\tPorts: x/open/tcp"} {
+		if _, err := scan.Read(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	hosts := []scan.Host{
+		{Address: "10.0.0.10", Hostname: "web01", MAC: "00:11:22:33:44:55", State: "up", Source: "echo", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "open", Service: "ssh"}}},
+		{Address: "fe80::1", State: "down", Source: "cache"},
+	}
+	run := scan.Run{Scanner: "iptool", Version: "1.0.0", Args: "iptool sweep", Start: time.Unix(1700000000, 0), End: time.Unix(1700000002, 0)}
+
+	// Setup test cases
+	testCases := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+	}{
+		{name: "XML", write: func(b *bytes.Buffer) error { return scan.WriteXML(b, run, hosts) }},
+		{name: "Greppable", write: func(b *bytes.Buffer) error { return scan.WriteGreppable(b, run, hosts) }},
+		{name: "JSON", write: func(b *bytes.Buffer) error { return scan.WriteJSON(b, hosts) }},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			result, err := scan.Read(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != len(hosts) {
+				t.Fatalf("expected %d hosts, got %d", len(hosts), len(result))
+			}
+			for i, h := range result {
+				if h.Address != hosts[i].Address || !reflect.DeepEqual(h.Ports, hosts[i].Ports) {
+					t.Errorf("expected %+v, got %+v", hosts[i], h)
+				}
+				if (h.State == "down") != (hosts[i].State == "down") {
+					t.Errorf("expected state %s, got %s", hosts[i].State, h.State)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := []scan.Host{
+		{Address: "10.0.0.10", MAC: "00:11:22:33:44:55", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "open"}}},
+		{Address: "2001:db8::1"},
+	}
+	b := []scan.Host{
+		{Address: "10.0.0.10", State: "up", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "closed"}, {Port: 80, Protocol: "tcp", State: "open"}}},
+		{Address: "10.0.0.9"},
+	}
+	expected := []scan.Host{
+		{Address: "10.0.0.9"},
+		{Address: "10.0.0.10", MAC: "00:11:22:33:44:55", State: "up", Ports: []scan.Port{{Port: 22, Protocol: "tcp", State: "closed"}, {Port: 80, Protocol: "tcp", State: "open"}}},
+		{Address: "2001:db8::1"},
+	}
+	if result := scan.Merge(a, b); !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
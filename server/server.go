@@ -0,0 +1,368 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package server exposes the address calculations of iptool as a small
+// HTTP/JSON API, so that other tools and web interfaces can use them
+// without running the command line tool.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// DefaultSplitLimit is the number of subnets returned by the split endpoint
+// when no limit is given, and MaxSplitLimit is the largest limit accepted
+const (
+	DefaultSplitLimit = 1024
+	MaxSplitLimit     = 65536
+)
+
+// maxBodySize is the largest request body accepted by the POST endpoints
+const maxBodySize = 1 << 20
+
+// Options are the options of the API server
+type Options struct {
+	// Version is reported by the health endpoint
+	Version string
+
+	// CORSOrigin is the value of the Access-Control-Allow-Origin header,
+	// which allows web interfaces on other origins to use the API
+	CORSOrigin string
+}
+
+// errorJSON is the response body of failed requests
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// New is a function that returns the HTTP handler of the API. The
+// endpoints are:
+//
+//	GET  /healthz                  status and version
+//	GET  /v1/inspect?address=      details of an address and its network
+//	GET  /v1/classify?address=     type of one or more addresses
+//	GET  /v1/subnet/split?prefix=  subnets of a prefix (bits or networks)
+//	GET  /v1/subnet/summarize?prefix=
+//	POST /v1/subnet/summarize      {"prefixes": [...]}
+func New(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": opts.Version})
+	})
+	mux.HandleFunc("/v1/inspect", handleInspect)
+	mux.HandleFunc("/v1/classify", handleClassify)
+	mux.HandleFunc("/v1/subnet/split", handleSplit)
+	mux.HandleFunc("/v1/subnet/summarize", handleSummarize)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.CORSOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", opts.CORSOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		}
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet, http.MethodHead, http.MethodPost:
+			mux.ServeHTTP(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+		}
+	})
+}
+
+// writeJSON is a function that writes a value as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeError is a function that writes an error as the JSON response body
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorJSON{Error: err.Error()})
+}
+
+// queryValues is a function that returns the values of a query parameter,
+// given as repeated parameters and/or as comma separated lists
+func queryValues(r *http.Request, key string) []string {
+	var values []string
+	for _, v := range r.URL.Query()[key] {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				values = append(values, field)
+			}
+		}
+	}
+	return values
+}
+
+// queryInt is a function that returns the integer value of a query
+// parameter, or the default value if the parameter is not given
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", key, v)
+	}
+	return n, nil
+}
+
+// InspectResult is the response body of the inspect endpoint. The fields that
+// do not apply to the address family are omitted.
+type InspectResult struct {
+	Address      string `json:"address"`
+	Expanded     string `json:"expanded,omitempty"`
+	Type         string `json:"type"`
+	Prefix       string `json:"prefix"`
+	Network      string `json:"network"`
+	PrefixLength int    `json:"prefix_length"`
+	Netmask      string `json:"netmask,omitempty"`
+	Wildcard     string `json:"wildcard,omitempty"`
+	Broadcast    string `json:"broadcast,omitempty"`
+	FirstHost    string `json:"first_host,omitempty"`
+	LastHost     string `json:"last_host,omitempty"`
+	LastAddress  string `json:"last_address,omitempty"`
+	UsableHosts  uint32 `json:"usable_hosts,omitempty"`
+	Size         string `json:"size"`
+}
+
+// handleInspect returns the details of an address and its network, the
+// same details as the inspect command
+func handleInspect(w http.ResponseWriter, r *http.Request) {
+	address := strings.TrimSpace(r.URL.Query().Get("address"))
+	if address == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing address"))
+		return
+	}
+	result, err := Inspect(address)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Inspect is a function that returns the details of an IPv4 or IPv6
+// address, in any format accepted by the inspect command
+func Inspect(s string) (*InspectResult, error) {
+	// If there is a colon in the input string, assume it is an IPv6 address
+	if strings.Contains(s, ":") {
+		ipv6, err := ip.ParseIPv6(s)
+		if err != nil {
+			return nil, err
+		}
+		return &InspectResult{
+			Address:      ipv6.Address(),
+			Expanded:     ipv6.Expanded(),
+			Type:         ipv6.Type(),
+			Prefix:       fmt.Sprintf("%s/%d", ipv6.Network(), ipv6.PrefixLength()),
+			Network:      ipv6.Network(),
+			PrefixLength: ipv6.PrefixLength(),
+			LastAddress:  ipv6.LastAddress(),
+			Size:         ipv6.NetworkSize().String(),
+		}, nil
+	}
+
+	ipv4, err := ip.ParseIPv4(s)
+	if err != nil {
+		return nil, err
+	}
+	return &InspectResult{
+		Address:      ipv4.Address(),
+		Type:         ip.Classify(netip.MustParseAddr(ipv4.Address())),
+		Prefix:       fmt.Sprintf("%s/%d", ipv4.Network(), ipv4.PrefixLength()),
+		Network:      ipv4.Network(),
+		PrefixLength: ipv4.PrefixLength(),
+		Netmask:      ipv4.Netmask(),
+		Wildcard:     ipv4.Wildcard(),
+		Broadcast:    ipv4.Broadcast(),
+		FirstHost:    ipv4.FirstHost(),
+		LastHost:     ipv4.LastHost(),
+		UsableHosts:  ipv4.UsableHosts(),
+		Size:         fmt.Sprint(ipv4.NetworkSize()),
+	}, nil
+}
+
+// classifyJSON is an element of the response body of the classify endpoint
+type classifyJSON struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+}
+
+// handleClassify returns the type of one or more addresses
+func handleClassify(w http.ResponseWriter, r *http.Request) {
+	addresses := queryValues(r, "address")
+	if len(addresses) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("missing address"))
+		return
+	}
+
+	results := []classifyJSON{}
+	for _, s := range addresses {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid IP address: %s", s))
+			return
+		}
+		results = append(results, classifyJSON{Address: s, Type: ip.Classify(addr)})
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// subnetJSON is a subnet in the response body of the split endpoint
+type subnetJSON struct {
+	Prefix    string `json:"prefix"`
+	Network   string `json:"network"`
+	First     string `json:"first"`
+	Last      string `json:"last"`
+	Broadcast string `json:"broadcast"`
+	Hosts     uint32 `json:"hosts"`
+}
+
+// splitJSON is the response body of the split endpoint
+type splitJSON struct {
+	Prefix  string       `json:"prefix"`
+	Bits    int          `json:"bits"`
+	Total   uint64       `json:"total"`
+	Offset  uint64       `json:"offset"`
+	Subnets []subnetJSON `json:"subnets"`
+}
+
+// handleSplit returns the subnets of an IPv4 prefix, split by size (bits)
+// or count (networks). Large splits are returned in pages with the offset
+// and limit parameters.
+func handleSplit(w http.ResponseWriter, r *http.Request) {
+	network, err := ip.ParseIPv4(r.URL.Query().Get("prefix"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if network.IP.To4() == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid IPv4 prefix: %s", r.URL.Query().Get("prefix")))
+		return
+	}
+
+	// Parse the numeric parameters
+	params := map[string]int{"bits": 0, "networks": 0, "offset": 0, "limit": DefaultSplitLimit}
+	for key, def := range params {
+		if params[key], err = queryInt(r, key, def); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	bits, networks := params["bits"], params["networks"]
+	if (bits == 0) == (networks == 0) {
+		writeError(w, http.StatusBadRequest, errors.New("either bits or networks must be given"))
+		return
+	}
+	if params["limit"] == 0 || params["limit"] > MaxSplitLimit {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %d (must be between 1 and %d)", params["limit"], MaxSplitLimit))
+		return
+	}
+
+	// The number of networks is rounded up to the closest power of two
+	if networks > 0 {
+		bits = network.PrefixLength()
+		for bits < 32 && 1<<(bits-network.PrefixLength()) < networks {
+			bits++
+		}
+	}
+
+	total, err := network.SubnetCount(bits)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result := splitJSON{
+		Prefix:  fmt.Sprintf("%s/%d", network.Network(), network.PrefixLength()),
+		Bits:    bits,
+		Total:   total,
+		Offset:  uint64(params["offset"]),
+		Subnets: []subnetJSON{},
+	}
+	network.SplitFunc(bits, result.Offset, func(index uint64, subnet *ip.IPv4) bool {
+		result.Subnets = append(result.Subnets, subnetJSON{
+			Prefix:    subnet.String(),
+			Network:   subnet.Network(),
+			First:     subnet.FirstHost(),
+			Last:      subnet.LastHost(),
+			Broadcast: subnet.Broadcast(),
+			Hosts:     subnet.UsableHosts(),
+		})
+		return len(result.Subnets) < params["limit"]
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+// prefixesJSON is the request and response body of the summarize endpoint
+type prefixesJSON struct {
+	Prefixes []string `json:"prefixes"`
+}
+
+// handleSummarize returns the smallest list of prefixes covering the given
+// prefixes, which are given as query parameters or as a JSON request body
+func handleSummarize(w http.ResponseWriter, r *http.Request) {
+	values := queryValues(r, "prefix")
+	if r.Method == http.MethodPost {
+		var body prefixesJSON
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		values = append(values, body.Prefixes...)
+	}
+	if len(values) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("missing prefix"))
+		return
+	}
+
+	var prefixes []netip.Prefix
+	for _, s := range values {
+		prefix, err := ip.ParsePrefix(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	result := prefixesJSON{Prefixes: []string{}}
+	for _, prefix := range ip.Summarize(prefixes) {
+		result.Prefixes = append(result.Prefixes, prefix.String())
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,78 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitcanon/iptool/server"
+)
+
+func TestServer(t *testing.T) {
+	handler := server.New(server.Options{Version: "v1.2.3", CORSOrigin: "*"})
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		status   int
+		contains []string
+	}{
+		{name: "Health", method: "GET", target: "/healthz", status: 200, contains: []string{`"version": "v1.2.3"`}},
+		{name: "Inspect IPv4", method: "GET", target: "/v1/inspect?address=10.0.0.1/22", status: 200, contains: []string{`"prefix": "10.0.0.0/22"`, `"broadcast": "10.0.3.255"`, `"usable_hosts": 1022`, `"type": "Private"`}},
+		{name: "Inspect IPv6", method: "GET", target: "/v1/inspect?address=2001:db8::1/64", status: 200, contains: []string{`"prefix": "2001:db8::/64"`, `"type": "Documentation"`, `"size": "18446744073709551616"`}},
+		{name: "Inspect invalid", method: "GET", target: "/v1/inspect?address=10.0.0.256", status: 400, contains: []string{`"error"`}},
+		{name: "Inspect missing", method: "GET", target: "/v1/inspect", status: 400, contains: []string{`"error": "missing address"`}},
+		{name: "Classify", method: "GET", target: "/v1/classify?address=8.8.8.8,100.64.0.1&address=fe80::1", status: 200, contains: []string{`"Global unicast"`, `"Shared address space (CGNAT)"`, `"Link-local unicast"`}},
+		{name: "Split bits", method: "GET", target: "/v1/subnet/split?prefix=10.0.0.0/24&bits=26", status: 200, contains: []string{`"total": 4`, `"prefix": "10.0.0.192/26"`}},
+		{name: "Split networks", method: "GET", target: "/v1/subnet/split?prefix=10.0.0.0/24&networks=3&offset=3&limit=1", status: 200, contains: []string{`"bits": 26`, `"offset": 3`, `"prefix": "10.0.0.192/26"`}},
+		{name: "Split IPv6", method: "GET", target: "/v1/subnet/split?prefix=2001:db8::/24&bits=26", status: 400, contains: []string{`"error"`}},
+		{name: "Split both", method: "GET", target: "/v1/subnet/split?prefix=10.0.0.0/24&networks=3&bits=26", status: 400, contains: []string{`"error"`}},
+		{name: "Summarize query", method: "GET", target: "/v1/subnet/summarize?prefix=10.0.0.0/25,10.0.0.128/25", status: 200, contains: []string{`"10.0.0.0/24"`}},
+		{name: "Summarize body", method: "POST", target: "/v1/subnet/summarize", body: `{"prefixes": ["10.0.2.0/24", "10.0.3.0/24", "10.0.3.0/25"]}`, status: 200, contains: []string{`"10.0.2.0/23"`}},
+		{name: "Summarize invalid body", method: "POST", target: "/v1/subnet/summarize", body: `{`, status: 400, contains: []string{`"invalid request body`}},
+		{name: "Method", method: "DELETE", target: "/v1/inspect?address=10.0.0.1", status: 405},
+		{name: "Not found", method: "GET", target: "/v1/unknown", status: 404},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, rec.Code, rec.Body)
+			}
+			if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+				t.Errorf("expected CORS header to be set")
+			}
+			body := rec.Body.String()
+			if rec.Header().Get("Content-Type") == "application/json" && !json.Valid([]byte(body)) {
+				t.Errorf("expected valid JSON, got %s", body)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected body to contain %s, got %s", s, body)
+				}
+			}
+		})
+	}
+}
+
+func TestServerPreflight(t *testing.T) {
+	handler := server.New(server.Options{})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/v1/inspect", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected no CORS header without an origin")
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package ssh collects the host keys of SSH servers, without authenticating,
+// by running the SSH transport protocol (RFC 4253) up to the key exchange,
+// in which the server presents its host key. It is a small keyscan and not
+// an SSH client: the session is closed as soon as the host key is received.
+package ssh
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrMalformedKey is returned when a host key cannot be parsed
+var ErrMalformedKey = errors.New("malformed host key")
+
+// HostKey is a host key presented by an SSH server. Blob is the public key
+// in the SSH wire format, as found (base64 encoded) in known_hosts files.
+type HostKey struct {
+	Type string
+	Bits int
+	Blob []byte
+}
+
+// ParseHostKey is a function that parses a public key in the SSH wire
+// format and determines its type and size in bits
+func ParseHostKey(blob []byte) (HostKey, error) {
+	r := reader{data: blob}
+	keyType := string(r.string())
+	key := HostKey{Type: keyType, Blob: blob}
+
+	switch {
+	case keyType == "ssh-rsa":
+		// The exponent followed by the modulus
+		r.string()
+		key.Bits = new(big.Int).SetBytes(r.string()).BitLen()
+	case keyType == "ssh-dss":
+		// The prime p followed by q, g and y
+		key.Bits = new(big.Int).SetBytes(r.string()).BitLen()
+		r.string()
+		r.string()
+		r.string()
+	case strings.HasPrefix(keyType, "ecdsa-sha2-"):
+		// The curve name followed by the public point
+		switch string(r.string()) {
+		case "nistp256":
+			key.Bits = 256
+		case "nistp384":
+			key.Bits = 384
+		case "nistp521":
+			key.Bits = 521
+		}
+		r.string()
+	case keyType == "ssh-ed25519":
+		if len(r.string()) != 32 {
+			return HostKey{}, ErrMalformedKey
+		}
+		key.Bits = 256
+	}
+	if r.err != nil || keyType == "" {
+		return HostKey{}, ErrMalformedKey
+	}
+	return key, nil
+}
+
+// SHA256 is a function that returns the SHA256 fingerprint of the key, in
+// the format of OpenSSH (SHA256: and the unpadded base64 encoded hash)
+func (k HostKey) SHA256() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// MD5 is a function that returns the MD5 fingerprint of the key, in the
+// format of OpenSSH (MD5: and the hash in hexadecimal separated by colons)
+func (k HostKey) MD5() string {
+	sum := md5.Sum(k.Blob)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return "MD5:" + strings.Join(hex, ":")
+}
+
+// Base64 is a function that returns the key encoded in base64, as written
+// after the key type in known_hosts and authorized_keys files
+func (k HostKey) Base64() string {
+	return base64.StdEncoding.EncodeToString(k.Blob)
+}
+
+// KnownHostsLine is a function that returns the known_hosts line for the
+// key of a host, the host is written as [host]:port if the port is not 22
+func (k HostKey) KnownHostsLine(host string, port int) string {
+	if port != 22 {
+		host = fmt.Sprintf("[%s]:%d", host, port)
+	}
+	return host + " " + k.Type + " " + k.Base64()
+}
+
+// reader reads the data types of the SSH wire format (RFC 4251) from a
+// message, the first error is kept and the values read after it are empty
+type reader struct {
+	data []byte
+	err  error
+}
+
+// byte is a function that reads a byte
+func (r *reader) byte() byte {
+	if r.err != nil || len(r.data) < 1 {
+		r.err = ErrMalformedKey
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+// string is a function that reads a string (a uint32 length and the bytes)
+func (r *reader) string() []byte {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = ErrMalformedKey
+		return nil
+	}
+	n := binary.BigEndian.Uint32(r.data)
+	if uint64(n) > uint64(len(r.data)-4) {
+		r.err = ErrMalformedKey
+		return nil
+	}
+	s := r.data[4 : 4+n]
+	r.data = r.data[4+n:]
+	return s
+}
+
+// nameList is a function that reads a name-list (a string of names
+// separated by commas)
+func (r *reader) nameList() []string {
+	s := r.string()
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(string(s), ",")
+}
+
+// appendString is a function that appends a string in the SSH wire format
+func appendString(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// appendNameList is a function that appends a name-list in the SSH wire format
+func appendNameList(b []byte, names []string) []byte {
+	return appendString(b, []byte(strings.Join(names, ",")))
+}
@@ -0,0 +1,368 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+)
+
+// The messages of the SSH transport protocol used by the keyscan
+const (
+	msgDisconnect   = 1
+	msgIgnore       = 2
+	msgDebug        = 4
+	msgKexInit      = 20
+	msgKexECDHInit  = 30
+	msgKexECDHReply = 31
+)
+
+// ClientVersion is the version string sent to the servers
+const ClientVersion = "SSH-2.0-iptool"
+
+// maxPacketLength is the largest packet accepted from a server (RFC 4253
+// requires support for packets of 35000 bytes)
+const maxPacketLength = 35000
+
+// KeyTypes maps the key types that can be collected to the host key
+// algorithms that are offered to the server to get a key of the type. Every
+// ECDSA curve is a key of its own, so ecdsa collects up to three keys.
+var KeyTypes = map[string][][]string{
+	"ed25519": {{"ssh-ed25519"}},
+	"ecdsa":   {{"ecdsa-sha2-nistp256"}, {"ecdsa-sha2-nistp384"}, {"ecdsa-sha2-nistp521"}},
+	"rsa":     {{"rsa-sha2-512", "rsa-sha2-256", "ssh-rsa"}},
+	"dsa":     {{"ssh-dss"}},
+}
+
+// DefaultKeyTypes are the key types collected unless other types are given
+var DefaultKeyTypes = []string{"ed25519", "ecdsa", "rsa", "dsa"}
+
+// kexCurves maps the supported key exchange algorithms to their curves, in
+// the order of preference. The classic Diffie-Hellman groups are not
+// supported, every current SSH server supports an ECDH key exchange.
+var kexCurves = []struct {
+	name  string
+	curve ecdh.Curve
+}{
+	{"curve25519-sha256", ecdh.X25519()},
+	{"curve25519-sha256@libssh.org", ecdh.X25519()},
+	{"ecdh-sha2-nistp256", ecdh.P256()},
+	{"ecdh-sha2-nistp384", ecdh.P384()},
+	{"ecdh-sha2-nistp521", ecdh.P521()},
+}
+
+// The ciphers, MACs and compression offered to the server. They are never
+// used, but the negotiation fails unless the server supports one of each.
+var (
+	offeredCiphers     = []string{"chacha20-poly1305@openssh.com", "aes128-gcm@openssh.com", "aes256-gcm@openssh.com", "aes128-ctr", "aes192-ctr", "aes256-ctr", "aes128-cbc", "aes256-cbc", "3des-cbc"}
+	offeredMACs        = []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com", "hmac-sha2-256", "hmac-sha2-512", "hmac-sha1"}
+	offeredCompression = []string{"none", "zlib@openssh.com", "zlib"}
+)
+
+// Result is the result of a keyscan of an SSH server: the version string
+// of the server, the host key algorithms it offers and its host keys
+type Result struct {
+	Version    string
+	Algorithms []string
+	Keys       []HostKey
+}
+
+// Scan is a function that collects the host keys of the given types (see
+// KeyTypes) from the SSH server at address (host:port). The first
+// connection reads the host key algorithms the server offers, and every
+// key is collected with a connection of its own. The server does not have
+// every key type, the keys it does not have are silently skipped. Every
+// connection must be completed within the timeout.
+func Scan(ctx context.Context, address string, types []string, timeout time.Duration) (*Result, error) {
+	for _, t := range types {
+		if _, ok := KeyTypes[t]; !ok {
+			return nil, fmt.Errorf("invalid key type: %s (must be one of %s)", t, strings.Join(DefaultKeyTypes, ", "))
+		}
+	}
+
+	// Read the version and the algorithms of the server
+	s, err := dial(ctx, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Version: s.version, Algorithms: s.kexInit.hostKeyAlgorithms}
+	s.close()
+
+	// Collect a key for every key type the server offers an algorithm for
+	for _, t := range types {
+		for _, algorithms := range KeyTypes[t] {
+			offered := slices.DeleteFunc(slices.Clone(algorithms), func(a string) bool {
+				return !slices.Contains(result.Algorithms, a)
+			})
+			if len(offered) == 0 {
+				continue
+			}
+			key, err := fetchHostKey(ctx, address, offered, timeout)
+			if err != nil {
+				return result, err
+			}
+			result.Keys = append(result.Keys, key)
+		}
+	}
+	return result, nil
+}
+
+// fetchHostKey is a function that connects to an SSH server, offers the
+// host key algorithms and runs the key exchange up to the reply of the
+// server, which holds the host key
+func fetchHostKey(ctx context.Context, address string, algorithms []string, timeout time.Duration) (HostKey, error) {
+	s, err := dial(ctx, address, timeout)
+	if err != nil {
+		return HostKey{}, err
+	}
+	defer s.close()
+
+	// Choose the first key exchange algorithm the server supports
+	kex := -1
+	for i, k := range kexCurves {
+		if slices.Contains(s.kexInit.kexAlgorithms, k.name) {
+			kex = i
+			break
+		}
+	}
+	if kex < 0 {
+		return HostKey{}, fmt.Errorf("%s: no supported key exchange algorithm (the server offers %s)", address, strings.Join(s.kexInit.kexAlgorithms, ", "))
+	}
+
+	// Send the key exchange init with the algorithms and the ephemeral key
+	var kexNames []string
+	for _, k := range kexCurves {
+		kexNames = append(kexNames, k.name)
+	}
+	if err := s.writePacket(clientKexInit(kexNames, algorithms)); err != nil {
+		return HostKey{}, err
+	}
+	private, err := kexCurves[kex].curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return HostKey{}, err
+	}
+	if err := s.writePacket(appendString([]byte{msgKexECDHInit}, private.PublicKey().Bytes())); err != nil {
+		return HostKey{}, err
+	}
+
+	// The reply holds the host key, the ephemeral key of the server and the
+	// signature of the exchange hash. The signature is not verified: the key
+	// is only recorded, no session is established with it.
+	payload, err := s.readMessage()
+	if err != nil {
+		return HostKey{}, err
+	}
+	if payload[0] != msgKexECDHReply {
+		return HostKey{}, fmt.Errorf("%s: unexpected message %d in the key exchange", address, payload[0])
+	}
+	r := reader{data: payload[1:]}
+	blob := r.string()
+	if r.err != nil {
+		return HostKey{}, fmt.Errorf("%s: %w", address, r.err)
+	}
+	key, err := ParseHostKey(blob)
+	if err != nil {
+		return HostKey{}, fmt.Errorf("%s: %w", address, err)
+	}
+	return key, nil
+}
+
+// kexInit holds the name-lists of a key exchange init message used by the
+// keyscan
+type kexInit struct {
+	kexAlgorithms     []string
+	hostKeyAlgorithms []string
+}
+
+// clientKexInit is a function that returns the key exchange init message
+// of the client, offering the key exchange and host key algorithms
+func clientKexInit(kexAlgorithms, hostKeyAlgorithms []string) []byte {
+	msg := []byte{msgKexInit}
+	cookie := make([]byte, 16)
+	rand.Read(cookie)
+	msg = append(msg, cookie...)
+	msg = appendNameList(msg, kexAlgorithms)
+	msg = appendNameList(msg, hostKeyAlgorithms)
+	for _, list := range [][]string{offeredCiphers, offeredCiphers, offeredMACs, offeredMACs, offeredCompression, offeredCompression, nil, nil} {
+		msg = appendNameList(msg, list)
+	}
+
+	// No guessed key exchange packet follows, and the reserved field
+	return append(msg, 0, 0, 0, 0, 0)
+}
+
+// session is a connection to an SSH server after the version exchange and
+// the key exchange init of the server
+type session struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	address string
+	version string
+	kexInit kexInit
+}
+
+// dial is a function that connects to an SSH server, exchanges the
+// versions and reads the key exchange init of the server
+func dial(ctx context.Context, address string, timeout time.Duration) (*session, error) {
+	// Names are resolved by the dialer, unless name resolution is disabled
+	if host, _, err := net.SplitHostPort(address); err == nil && ip.LookupsDisabled() {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return nil, fmt.Errorf("cannot resolve %s: %w", host, ip.ErrLookupsDisabled)
+		}
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	s := &session{conn: conn, r: bufio.NewReader(conn), address: address}
+
+	if _, err := io.WriteString(conn, ClientVersion+"\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The server may send other lines before its version (RFC 4253 4.2)
+	for i := 0; ; i++ {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%s: connection closed before the SSH version was received", address)
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			s.version = line
+			break
+		}
+		if i >= 20 || len(line) > 255 {
+			conn.Close()
+			return nil, fmt.Errorf("%s: not an SSH server", address)
+		}
+	}
+	if !strings.HasPrefix(s.version, "SSH-2.0-") && !strings.HasPrefix(s.version, "SSH-1.99-") {
+		conn.Close()
+		return nil, fmt.Errorf("%s: unsupported SSH protocol version: %s", address, s.version)
+	}
+
+	// The server starts the key exchange by sending its algorithms
+	payload, err := s.readMessage()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if payload[0] != msgKexInit {
+		conn.Close()
+		return nil, fmt.Errorf("%s: unexpected message %d instead of the key exchange init", address, payload[0])
+	}
+	r := reader{data: payload[1:]}
+	for i := 0; i < 16; i++ {
+		r.byte()
+	}
+	s.kexInit.kexAlgorithms = r.nameList()
+	s.kexInit.hostKeyAlgorithms = r.nameList()
+	if r.err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: malformed key exchange init", address)
+	}
+	return s, nil
+}
+
+// close is a function that closes the connection to the server
+func (s *session) close() {
+	s.conn.Close()
+}
+
+// readMessage is a function that reads the next message from the server,
+// skipping ignore and debug messages. A disconnect message is an error.
+func (s *session) readMessage() ([]byte, error) {
+	for {
+		payload, err := s.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch payload[0] {
+		case msgIgnore, msgDebug:
+			continue
+		case msgDisconnect:
+			r := reader{data: payload[1:]}
+			r.string()
+			reason := r.string()
+			if len(reason) == 0 {
+				return nil, fmt.Errorf("%s: disconnected by the server", s.address)
+			}
+			return nil, fmt.Errorf("%s: disconnected by the server: %s", s.address, reason)
+		}
+		return payload, nil
+	}
+}
+
+// readPacket is a function that reads an unencrypted binary packet (RFC
+// 4253 6) from the server and returns its payload
+func (s *session) readPacket() ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(s.r, header); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.address, err)
+	}
+	length := binary.BigEndian.Uint32(header)
+	padding := uint32(header[4])
+	if length > maxPacketLength || length < padding+2 {
+		return nil, fmt.Errorf("%s: invalid packet length %d", s.address, length)
+	}
+	packet := make([]byte, length-1)
+	if _, err := io.ReadFull(s.r, packet); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.address, err)
+	}
+	return packet[:length-1-padding], nil
+}
+
+// writePacket is a function that writes a payload as an unencrypted binary
+// packet, padded to a multiple of 8 bytes with at least 4 bytes of padding
+func (s *session) writePacket(payload []byte) error {
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+	packet := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)+padding))
+	packet = append(packet, byte(padding))
+	packet = append(packet, payload...)
+	packet = append(packet, make([]byte, padding)...)
+	_, err := s.conn.Write(packet)
+	return err
+}
@@ -0,0 +1,223 @@
+package ssh_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/ip"
+	"github.com/bitcanon/iptool/ssh"
+)
+
+// Public keys generated with ssh-keygen, and their fingerprints as printed
+// by ssh-keygen -l (-E md5)
+const (
+	testEd25519 = "AAAAC3NzaC1lZDI1NTE5AAAAIIGGeNDi4mbTXjlH7cVCQoK/0Ya+qQui9piC5wmymYBM"
+	testECDSA   = "AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBGFRPR1fQ8P3RF8c4nRUwESQAc6dcu6atay5XiNhxukqLQg8e7kCVtikXmnWIPK9TELUIzN03A/Ukea8Lr4myq0="
+)
+
+func TestParseHostKey(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		key       string
+		keyType   string
+		bits      int
+		sha256    string
+		md5       string
+		expectErr bool
+	}{
+		{key: testEd25519, keyType: "ssh-ed25519", bits: 256, sha256: "SHA256:fyUCkBVlioaMFME7Nk41omoaHLTnnp4Wf/HBJFwJBmk", md5: "MD5:d5:ae:bf:0e:da:f6:93:e5:55:00:94:5e:ac:c9:5a:ee"},
+		{key: testECDSA, keyType: "ecdsa-sha2-nistp256", bits: 256, sha256: "SHA256:2pR/7+t54TrlABU9fnOjTeY6d/0FmPCU/0XOpA6ydhQ", md5: "MD5:17:09:5a:51:76:79:a7:34:57:10:17:66:73:08:36:cd"},
+		{key: testEd25519[:40], expectErr: true},
+		{key: "", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		blob, _ := base64.StdEncoding.DecodeString(tc.key)
+		key, err := ssh.ParseHostKey(blob)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("expected an error for %q", tc.key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Type != tc.keyType || key.Bits != tc.bits || key.SHA256() != tc.sha256 || key.MD5() != tc.md5 {
+			t.Errorf("expected %s %d %s %s, got %s %d %s %s", tc.keyType, tc.bits, tc.sha256, tc.md5, key.Type, key.Bits, key.SHA256(), key.MD5())
+		}
+		if line := key.KnownHostsLine("192.0.2.1", 2222); line != "[192.0.2.1]:2222 "+tc.keyType+" "+tc.key {
+			t.Errorf("unexpected known_hosts line: %s", line)
+		}
+	}
+}
+
+// appendString appends a string in the SSH wire format
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// readString reads a string in the SSH wire format
+func readString(b []byte) (string, []byte) {
+	n := binary.BigEndian.Uint32(b)
+	return string(b[4 : 4+n]), b[4+n:]
+}
+
+// writePacket writes an unencrypted binary packet with 4 bytes of padding
+func writePacket(w io.Writer, payload []byte) {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+5))
+	packet = append(packet, 4)
+	packet = append(packet, payload...)
+	w.Write(append(packet, 0, 0, 0, 0))
+}
+
+// readPacket reads an unencrypted binary packet and returns its payload
+func readPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	packet := make([]byte, binary.BigEndian.Uint32(header)-1)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return nil, err
+	}
+	return packet[:len(packet)-int(header[4])], nil
+}
+
+// startSSHServer starts an SSH server for the tests that offers the
+// ssh-ed25519 and ecdsa-sha2-nistp256 host keys, and answers the key
+// exchange with the key of the host key algorithm chosen by the client
+func startSSHServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	keys := map[string]string{"ssh-ed25519": testEd25519, "ecdsa-sha2-nistp256": testECDSA}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				conn.Write([]byte("Welcome\r\nSSH-2.0-Test_1.0\r\n"))
+
+				// Send the key exchange init with the algorithms of the server
+				kexInit := append([]byte{20}, make([]byte, 16)...)
+				kexInit = appendString(kexInit, "sntrup761x25519-sha512@openssh.com,curve25519-sha256")
+				kexInit = appendString(kexInit, "ssh-ed25519,ecdsa-sha2-nistp256")
+				for _, list := range []string{"aes128-ctr", "aes128-ctr", "hmac-sha2-256", "hmac-sha2-256", "none", "none", "", ""} {
+					kexInit = appendString(kexInit, list)
+				}
+				writePacket(conn, append(kexInit, 0, 0, 0, 0, 0))
+				if _, err := r.ReadString('\n'); err != nil {
+					return
+				}
+
+				// Choose the first host key algorithm of the client
+				payload, err := readPacket(r)
+				if err != nil || payload[0] != 20 {
+					return
+				}
+				_, rest := readString(payload[17:])
+				algorithms, _ := readString(rest)
+				algorithm, _, _ := strings.Cut(algorithms, ",")
+
+				// Answer the ECDH init with the host key
+				if payload, err = readPacket(r); err != nil || payload[0] != 30 {
+					return
+				}
+				blob, _ := base64.StdEncoding.DecodeString(keys[algorithm])
+				reply := appendString([]byte{31}, string(blob))
+				reply = appendString(reply, string(make([]byte, 32)))
+				writePacket(conn, appendString(reply, "signature"))
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	address := startSSHServer(t)
+
+	// Setup test cases
+	testCases := []struct {
+		types     []string
+		expected  []string
+		expectErr bool
+	}{
+		{types: ssh.DefaultKeyTypes, expected: []string{"ssh-ed25519", "ecdsa-sha2-nistp256"}},
+		{types: []string{"ecdsa"}, expected: []string{"ecdsa-sha2-nistp256"}},
+		{types: []string{"rsa"}},
+		{types: []string{"foo"}, expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.types, ","), func(t *testing.T) {
+			result, err := ssh.Scan(context.Background(), address, tc.types, time.Second)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Version != "SSH-2.0-Test_1.0" {
+				t.Errorf("unexpected version: %s", result.Version)
+			}
+			var types []string
+			for _, key := range result.Keys {
+				types = append(types, key.Type)
+			}
+			if !slices.Equal(types, tc.expected) {
+				t.Errorf("expected keys %v, got %v", tc.expected, types)
+			}
+		})
+	}
+}
+
+func TestScanNotSSH(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	if _, err := ssh.Scan(context.Background(), listener.Addr().String(), ssh.DefaultKeyTypes, time.Second); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestScanLookupsDisabled(t *testing.T) {
+	ip.DisableLookups(true)
+	defer ip.DisableLookups(false)
+
+	if _, err := ssh.Scan(context.Background(), "host.invalid:22", ssh.DefaultKeyTypes, time.Second); !errors.Is(err, ip.ErrLookupsDisabled) {
+		t.Errorf("expected %v, got %v", ip.ErrLookupsDisabled, err)
+	}
+}
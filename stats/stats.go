@@ -0,0 +1,132 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats collects response time samples of a probe (e.g. a TCP ping) and
+// calculates summary statistics. The mean and the standard deviation are
+// calculated incrementally using Welford's algorithm, which is numerically
+// stable. All samples are kept for the percentiles.
+type Stats struct {
+	count   int
+	mean    float64
+	m2      float64
+	min     time.Duration
+	max     time.Duration
+	samples []time.Duration
+	sorted  bool
+
+	// Jitter is the mean absolute difference between consecutive samples
+	last      time.Duration
+	jitterSum float64
+}
+
+// Add is a function that adds a sample to the statistics
+func (s *Stats) Add(d time.Duration) {
+	s.count++
+
+	// Update min/max
+	if s.count == 1 || d < s.min {
+		s.min = d
+	}
+	if s.count == 1 || d > s.max {
+		s.max = d
+	}
+
+	// Update the mean and the sum of squared differences (Welford's algorithm)
+	delta := float64(d) - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (float64(d) - s.mean)
+
+	// Update the jitter
+	if s.count > 1 {
+		s.jitterSum += math.Abs(float64(d - s.last))
+	}
+	s.last = d
+
+	s.samples = append(s.samples, d)
+	s.sorted = false
+}
+
+// Count is a function that returns the number of samples
+func (s *Stats) Count() int {
+	return s.count
+}
+
+// Min is a function that returns the smallest sample
+func (s *Stats) Min() time.Duration {
+	return s.min
+}
+
+// Max is a function that returns the largest sample
+func (s *Stats) Max() time.Duration {
+	return s.max
+}
+
+// Mean is a function that returns the mean of the samples
+func (s *Stats) Mean() time.Duration {
+	return time.Duration(s.mean)
+}
+
+// StdDev is a function that returns the (population) standard deviation of
+// the samples, the same measure as the mdev reported by ping
+func (s *Stats) StdDev() time.Duration {
+	if s.count < 2 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(s.m2 / float64(s.count)))
+}
+
+// Jitter is a function that returns the mean absolute difference between
+// consecutive samples
+func (s *Stats) Jitter() time.Duration {
+	if s.count < 2 {
+		return 0
+	}
+	return time.Duration(s.jitterSum / float64(s.count-1))
+}
+
+// Percentile is a function that returns the p-th percentile (0-100) of the
+// samples, using the nearest-rank method
+func (s *Stats) Percentile(p float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	if !s.sorted {
+		sort.Slice(s.samples, func(i, j int) bool { return s.samples[i] < s.samples[j] })
+		s.sorted = true
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(s.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > s.count {
+		rank = s.count
+	}
+	return s.samples[rank-1]
+}
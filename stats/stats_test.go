@@ -0,0 +1,114 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/stats"
+)
+
+func TestStats(t *testing.T) {
+	ms := time.Millisecond
+
+	// Setup test cases
+	testCases := []struct {
+		name           string
+		samples        []time.Duration
+		expectedMin    time.Duration
+		expectedMax    time.Duration
+		expectedMean   time.Duration
+		expectedStdDev time.Duration
+		expectedJitter time.Duration
+		expectedP50    time.Duration
+		expectedP90    time.Duration
+		expectedP99    time.Duration
+	}{
+		{name: "Empty"},
+		{
+			name:         "Single",
+			samples:      []time.Duration{5 * ms},
+			expectedMin:  5 * ms,
+			expectedMax:  5 * ms,
+			expectedMean: 5 * ms,
+			expectedP50:  5 * ms,
+			expectedP90:  5 * ms,
+			expectedP99:  5 * ms,
+		},
+		{
+			name:           "Constant",
+			samples:        []time.Duration{3 * ms, 3 * ms, 3 * ms},
+			expectedMin:    3 * ms,
+			expectedMax:    3 * ms,
+			expectedMean:   3 * ms,
+			expectedStdDev: 0,
+			expectedJitter: 0,
+			expectedP50:    3 * ms,
+			expectedP90:    3 * ms,
+			expectedP99:    3 * ms,
+		},
+		{
+			// Population standard deviation of 2, 4, 4, 4, 5, 5, 7, 9 is exactly 2
+			name:           "Unordered",
+			samples:        []time.Duration{9 * ms, 2 * ms, 4 * ms, 4 * ms, 5 * ms, 4 * ms, 7 * ms, 5 * ms},
+			expectedMin:    2 * ms,
+			expectedMax:    9 * ms,
+			expectedMean:   5 * ms,
+			expectedStdDev: 2 * ms,
+			expectedJitter: 16 * ms / 7,
+			expectedP50:    4 * ms,
+			expectedP90:    9 * ms,
+			expectedP99:    9 * ms,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var s stats.Stats
+			for _, sample := range tc.samples {
+				s.Add(sample)
+			}
+
+			if s.Count() != len(tc.samples) {
+				t.Errorf("expected count %d, got %d", len(tc.samples), s.Count())
+			}
+			checks := []struct {
+				what     string
+				expected time.Duration
+				got      time.Duration
+			}{
+				{"min", tc.expectedMin, s.Min()},
+				{"max", tc.expectedMax, s.Max()},
+				{"mean", tc.expectedMean, s.Mean()},
+				{"stddev", tc.expectedStdDev, s.StdDev()},
+				{"jitter", tc.expectedJitter, s.Jitter()},
+				{"p50", tc.expectedP50, s.Percentile(50)},
+				{"p90", tc.expectedP90, s.Percentile(90)},
+				{"p99", tc.expectedP99, s.Percentile(99)},
+			}
+			for _, c := range checks {
+				if c.got != c.expected {
+					t.Errorf("expected %s %s, got %s", c.what, c.expected, c.got)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsAddAfterPercentile(t *testing.T) {
+	var s stats.Stats
+	s.Add(10 * time.Millisecond)
+	s.Add(20 * time.Millisecond)
+	if p := s.Percentile(100); p != 20*time.Millisecond {
+		t.Fatalf("expected p100 20ms, got %s", p)
+	}
+
+	// Samples added after a percentile has been calculated must be included
+	s.Add(5 * time.Millisecond)
+	if p := s.Percentile(0); p != 5*time.Millisecond {
+		t.Errorf("expected p0 5ms, got %s", p)
+	}
+	if j := s.Jitter(); j != 12500*time.Microsecond {
+		t.Errorf("expected jitter 12.5ms, got %s", j)
+	}
+}
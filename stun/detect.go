@@ -0,0 +1,288 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package stun
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// Type is the NAT type in the classic STUN classification (RFC 3489)
+type Type string
+
+const (
+	// TypeBlocked means that no STUN server answered, UDP is blocked
+	TypeBlocked Type = "UDP blocked"
+	// TypeOpen means that there is no NAT and no firewall
+	TypeOpen Type = "Open internet"
+	// TypeSymmetricFirewall means that there is no NAT, but a firewall
+	// only lets in the responses of the hosts that were sent to
+	TypeSymmetricFirewall Type = "Symmetric UDP firewall"
+	// TypeFullCone means that any host can send to the mapped address
+	TypeFullCone Type = "Full cone"
+	// TypeRestrictedCone means that the hosts that were sent to can send to
+	// the mapped address, from any port
+	TypeRestrictedCone Type = "Restricted cone"
+	// TypePortRestrictedCone means that only the address and port that
+	// were sent to can send to the mapped address
+	TypePortRestrictedCone Type = "Port restricted cone"
+	// TypeSymmetric means that every destination gets its own mapping,
+	// which breaks peer-to-peer connections (e.g. WebRTC without TURN)
+	TypeSymmetric Type = "Symmetric"
+	// TypeCone means that the mapping is the same for every destination,
+	// but the filtering could not be tested (the server does not support
+	// CHANGE-REQUEST)
+	TypeCone Type = "Cone (filtering unknown)"
+	// TypeUnknown means that the server does not support the tests
+	TypeUnknown Type = "Unknown"
+)
+
+// The behaviors of the mapping and filtering of a NAT (RFC 4787)
+const (
+	BehaviorEndpointIndependent = "endpoint-independent"
+	BehaviorAddressDependent    = "address-dependent"
+	BehaviorAddressPortDep      = "address-and-port-dependent"
+	BehaviorUnknown             = "unknown"
+)
+
+// Result is the outcome of the NAT type detection
+type Result struct {
+	Type      Type           `json:"type"`
+	Local     netip.AddrPort `json:"local"`
+	Mapped    netip.AddrPort `json:"mapped"`
+	Mapping   string         `json:"mapping"`
+	Filtering string         `json:"filtering"`
+	Server    netip.AddrPort `json:"server"`
+	Other     netip.AddrPort `json:"other"`
+}
+
+// Request is a function that sends a binding request to the server and
+// returns the response. The request is retransmitted with a doubling
+// interval (starting at 100 milliseconds) until a response arrives or the
+// timeout expires. The origin of the response is the address and port it
+// came from, which tells whether the server honored the change flags.
+func Request(conn net.PacketConn, server netip.AddrPort, change byte, timeout time.Duration) (Response, error) {
+	id := NewTransactionID()
+	msg := NewBindingRequest(id, change)
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 1500)
+
+	for interval := 100 * time.Millisecond; time.Now().Before(deadline); interval *= 2 {
+		if _, err := conn.WriteTo(msg, net.UDPAddrFromAddrPort(server)); err != nil {
+			return Response{}, err
+		}
+
+		// Wait for the response until the next retransmission
+		conn.SetReadDeadline(minTime(deadline, time.Now().Add(interval)))
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return Response{}, err
+			}
+			resp, err := ParseResponse(buf[:n], id)
+			if errors.Is(err, ErrNotSTUN) {
+				continue
+			}
+			if err != nil {
+				return resp, err
+			}
+			if addr, ok := from.(*net.UDPAddr); ok {
+				origin := addr.AddrPort()
+				resp.Origin = netip.AddrPortFrom(origin.Addr().Unmap(), origin.Port())
+			}
+			return resp, nil
+		}
+	}
+	return Response{}, errTimeout
+}
+
+// errTimeout is returned when the server does not respond in time
+var errTimeout = errors.New("no response from the STUN server")
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Detect is a function that classifies the NAT between the local address of
+// the connection and the STUN servers, with the tests of RFC 3489 and RFC
+// 5780:
+//
+//  1. A binding request to the first server returns the mapped address. No
+//     response means that UDP is blocked, a mapped address equal to the
+//     local address means that there is no NAT.
+//  2. A binding request to the other address of the server (or the next
+//     server if the server has none) returns a second mapped address. A
+//     different mapping for another destination is a symmetric NAT.
+//  3. Binding requests asking the server to respond from its other address
+//     and port (CHANGE-REQUEST) test the filtering of the NAT: full cone,
+//     restricted cone or port restricted cone.
+//
+// The local address is the address used to reach the first server if the
+// connection is bound to the unspecified address.
+func Detect(conn net.PacketConn, servers []netip.AddrPort, timeout time.Duration) (Result, error) {
+	if len(servers) == 0 {
+		return Result{}, errors.New("no STUN servers given")
+	}
+	result := Result{Server: servers[0], Mapping: BehaviorUnknown, Filtering: BehaviorUnknown}
+	result.Local = localAddress(conn, servers[0])
+
+	// Test I: the mapped address as seen by the first server
+	first, err := Request(conn, servers[0], 0, timeout)
+	if errors.Is(err, errTimeout) {
+		result.Type = TypeBlocked
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Mapped, result.Other = first.Mapped, first.Other
+
+	// changed reports whether a response to a CHANGE-REQUEST really came
+	// from another address or port, servers without support answer from
+	// the same address and port
+	changed := func(resp Response, change byte) bool {
+		origin := resp.Origin
+		return (change&ChangeIP == 0 || origin.Addr() != servers[0].Addr()) &&
+			(change&ChangePort == 0 || origin.Port() != servers[0].Port())
+	}
+
+	// testFiltering sends a CHANGE-REQUEST and reports whether a response
+	// from the other address and/or port came through, and whether the test
+	// is supported by the server
+	testFiltering := func(change byte) (passed, supported bool) {
+		resp, err := Request(conn, servers[0], change, timeout)
+		if err != nil {
+			return false, first.Other.IsValid()
+		}
+		if !changed(resp, change) {
+			return false, false
+		}
+		return true, true
+	}
+
+	// No NAT: the filtering tells an open host from a firewall
+	if first.Mapped == result.Local {
+		result.Mapping = BehaviorEndpointIndependent
+		passed, supported := testFiltering(ChangeIP | ChangePort)
+		switch {
+		case passed:
+			result.Type, result.Filtering = TypeOpen, BehaviorEndpointIndependent
+		case supported:
+			result.Type, result.Filtering = TypeSymmetricFirewall, BehaviorAddressPortDep
+		default:
+			result.Type = TypeUnknown
+		}
+		return result, nil
+	}
+
+	// Test II: the mapped address as seen by another destination
+	var alternate netip.AddrPort
+	switch {
+	case first.Other.IsValid():
+		alternate = netip.AddrPortFrom(first.Other.Addr(), servers[0].Port())
+	case len(servers) > 1:
+		alternate = servers[1]
+	}
+	if alternate.IsValid() {
+		if second, err := Request(conn, alternate, 0, timeout); err == nil {
+			if second.Mapped != first.Mapped {
+				result.Type, result.Mapping = TypeSymmetric, BehaviorAddressDependent
+				return result, nil
+			}
+			result.Mapping = BehaviorEndpointIndependent
+		}
+	}
+
+	// Test III: the filtering of responses from other addresses and ports
+	passed, supported := testFiltering(ChangeIP | ChangePort)
+	switch {
+	case !supported && result.Mapping == BehaviorEndpointIndependent:
+		result.Type = TypeCone
+	case !supported:
+		result.Type = TypeUnknown
+	case passed:
+		result.Type, result.Filtering = TypeFullCone, BehaviorEndpointIndependent
+	default:
+		if passed, _ := testFiltering(ChangePort); passed {
+			result.Type, result.Filtering = TypeRestrictedCone, BehaviorAddressDependent
+		} else {
+			result.Type, result.Filtering = TypePortRestrictedCone, BehaviorAddressPortDep
+		}
+	}
+	return result, nil
+}
+
+// localAddress returns the local address and port of the connection, with
+// the address used to reach the server if it is bound to the unspecified
+// address (connecting a UDP socket sends no packets)
+func localAddress(conn net.PacketConn, server netip.AddrPort) netip.AddrPort {
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort()
+	addr := local.Addr().Unmap()
+	if addr.IsUnspecified() {
+		if c, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(server)); err == nil {
+			addr = c.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+			c.Close()
+		}
+	}
+	return netip.AddrPortFrom(addr, local.Port())
+}
+
+// Lifetime is a function that measures how long the NAT keeps an idle
+// mapping: binding requests are sent to the server after idle periods that
+// double from start up to max, until the mapped address changes. It returns
+// the longest idle period the mapping survived and the idle period after
+// which it changed (zero if it survived the longest period). A NAT that
+// assigns the same port to a new mapping cannot be told apart from one that
+// kept the mapping.
+func Lifetime(ctx context.Context, conn net.PacketConn, server, mapped netip.AddrPort, start, max, timeout time.Duration, progress func(idle time.Duration)) (survived, expired time.Duration, err error) {
+	for idle := start; idle <= max; idle *= 2 {
+		if progress != nil {
+			progress(idle)
+		}
+		select {
+		case <-ctx.Done():
+			return survived, 0, ctx.Err()
+		case <-time.After(idle):
+		}
+
+		resp, err := Request(conn, server, 0, timeout)
+		if err != nil {
+			return survived, 0, err
+		}
+		if resp.Mapped != mapped {
+			return survived, idle, nil
+		}
+		survived = idle
+	}
+	return survived, 0, nil
+}
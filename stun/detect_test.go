@@ -0,0 +1,217 @@
+package stun_test
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/stun"
+)
+
+// fakeServer is a STUN server for the tests with two addresses and two
+// ports (127.0.0.1 and 127.0.0.2) that simulates the NAT of the client
+type fakeServer struct {
+	conns [2][2]net.PacketConn // By address and port
+	// mapped returns the mapped address of a client for a request received
+	// on the address and port of the server (the NAT mapping)
+	mapped func(client netip.AddrPort, addr, port int) netip.AddrPort
+	// filter reports whether a response with the change flags passes the
+	// NAT (the NAT filtering), or whether the server supports them at all
+	filter func(change byte) bool
+	// other is false if the server does not report its other address
+	other bool
+}
+
+// start opens the sockets of the server and answers the requests
+func (s *fakeServer) start(t *testing.T) netip.AddrPort {
+	port := 0
+	for a, addr := range []string{"127.0.0.1", "127.0.0.2"} {
+		for p := range s.conns[a] {
+			// The ports of the first address are reused on the second address
+			listenPort := 0
+			if a == 1 {
+				listenPort = s.conns[0][p].LocalAddr().(*net.UDPAddr).Port
+			}
+			conn, err := net.ListenPacket("udp4", net.JoinHostPort(addr, strconv.Itoa(listenPort)))
+			if err != nil {
+				t.Skipf("cannot listen on %s: %v", addr, err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			s.conns[a][p] = conn
+			if a == 0 && p == 0 {
+				port = conn.LocalAddr().(*net.UDPAddr).Port
+			}
+		}
+	}
+
+	for a := range s.conns {
+		for p := range s.conns[a] {
+			go s.serve(a, p)
+		}
+	}
+	return netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), uint16(port))
+}
+
+// serve answers the requests received on a socket
+func (s *fakeServer) serve(a, p int) {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := s.conns[a][p].ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		id, change, err := stun.ParseRequest(buf[:n])
+		if err != nil || !s.filter(change) {
+			continue
+		}
+
+		// Respond from the other address and/or port if asked to
+		ra, rp := a, p
+		if change&stun.ChangeIP != 0 {
+			ra ^= 1
+		}
+		if change&stun.ChangePort != 0 {
+			rp ^= 1
+		}
+		var other netip.AddrPort
+		if s.other {
+			other = s.conns[a^1][p^1].LocalAddr().(*net.UDPAddr).AddrPort()
+		}
+		client := from.(*net.UDPAddr).AddrPort()
+		resp := stun.NewBindingResponse(id, s.mapped(client, a, p), other, netip.AddrPort{})
+		s.conns[ra][rp].WriteTo(resp, from)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	// The NAT mappings: none, one per client and one per destination
+	noNAT := func(client netip.AddrPort, addr, port int) netip.AddrPort { return client }
+	coneNAT := func(client netip.AddrPort, addr, port int) netip.AddrPort {
+		return netip.MustParseAddrPort("203.0.113.5:40000")
+	}
+	symmetricNAT := func(client netip.AddrPort, addr, port int) netip.AddrPort {
+		return netip.AddrPortFrom(netip.MustParseAddr("203.0.113.5"), uint16(40000+addr*10+port))
+	}
+
+	// The filters: everything, only the same address and nothing changed
+	all := func(change byte) bool { return true }
+	sameAddr := func(change byte) bool { return change&stun.ChangeIP == 0 }
+	none := func(change byte) bool { return change == 0 }
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		server   fakeServer
+		expected stun.Type
+	}{
+		{name: "Open", server: fakeServer{mapped: noNAT, filter: all, other: true}, expected: stun.TypeOpen},
+		{name: "SymmetricFirewall", server: fakeServer{mapped: noNAT, filter: none, other: true}, expected: stun.TypeSymmetricFirewall},
+		{name: "FullCone", server: fakeServer{mapped: coneNAT, filter: all, other: true}, expected: stun.TypeFullCone},
+		{name: "RestrictedCone", server: fakeServer{mapped: coneNAT, filter: sameAddr, other: true}, expected: stun.TypeRestrictedCone},
+		{name: "PortRestrictedCone", server: fakeServer{mapped: coneNAT, filter: none, other: true}, expected: stun.TypePortRestrictedCone},
+		{name: "Symmetric", server: fakeServer{mapped: symmetricNAT, filter: all, other: true}, expected: stun.TypeSymmetric},
+		{name: "NoOtherAddress", server: fakeServer{mapped: coneNAT, filter: none, other: false}, expected: stun.TypeUnknown},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := tc.server.start(t)
+			conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			result, err := stun.Detect(conn, []netip.AddrPort{server}, 300*time.Millisecond)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tc.expected {
+				t.Errorf("expected %s, got %s (%+v)", tc.expected, result.Type, result)
+			}
+		})
+	}
+}
+
+func TestDetectBlocked(t *testing.T) {
+	// A server that never answers
+	silent, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	server := silent.LocalAddr().(*net.UDPAddr).AddrPort()
+	result, err := stun.Detect(conn, []netip.AddrPort{server}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != stun.TypeBlocked {
+		t.Errorf("expected %s, got %s", stun.TypeBlocked, result.Type)
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	// A NAT that drops the mapping after the given number of requests (never if negative)
+	newServer := func(requests int) fakeServer {
+		count := 0
+		var mu sync.Mutex
+		return fakeServer{
+			mapped: func(client netip.AddrPort, addr, port int) netip.AddrPort {
+				mu.Lock()
+				defer mu.Unlock()
+				count++
+				if requests >= 0 && count > requests {
+					return netip.MustParseAddrPort("203.0.113.5:40001")
+				}
+				return netip.MustParseAddrPort("203.0.113.5:40000")
+			},
+			filter: func(change byte) bool { return true },
+		}
+	}
+	mapped := netip.MustParseAddrPort("203.0.113.5:40000")
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		requests int
+		survived time.Duration
+		expired  time.Duration
+	}{
+		{name: "Kept", requests: -1, survived: 40 * time.Millisecond},
+		{name: "Expired", requests: 2, survived: 20 * time.Millisecond, expired: 40 * time.Millisecond},
+		{name: "ExpiredAtOnce", requests: 0, survived: 0, expired: 10 * time.Millisecond},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newServer(tc.requests)
+			addr := server.start(t)
+			conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			survived, expired, err := stun.Lifetime(context.Background(), conn, addr, mapped, 10*time.Millisecond, 40*time.Millisecond, 300*time.Millisecond, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if survived != tc.survived || expired != tc.expired {
+				t.Errorf("expected %s and %s, got %s and %s", tc.survived, tc.expired, survived, expired)
+			}
+		})
+	}
+}
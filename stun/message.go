@@ -0,0 +1,271 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package stun
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// MagicCookie is the fixed value in the header of every STUN message
+// (RFC 5389), it tells STUN apart from other protocols on the same port
+const MagicCookie = 0x2112A442
+
+// The message types of a binding transaction
+const (
+	typeBindingRequest  = 0x0001
+	typeBindingResponse = 0x0101
+	typeBindingError    = 0x0111
+)
+
+// The attributes used by the NAT behavior discovery (RFC 5389, RFC 5780 and
+// the classic STUN attributes of RFC 3489, which older servers still send)
+const (
+	attrMappedAddress    = 0x0001
+	attrSourceAddress    = 0x0004
+	attrChangedAddress   = 0x0005
+	attrChangeRequest    = 0x0003
+	attrErrorCode        = 0x0009
+	attrXORMappedAddress = 0x0020
+	attrResponseOrigin   = 0x802b
+	attrOtherAddress     = 0x802c
+)
+
+// The flags of the CHANGE-REQUEST attribute, they ask the server to send
+// the response from its other address and/or port
+const (
+	ChangeIP   = 0x04
+	ChangePort = 0x02
+)
+
+// headerSize is the size of the STUN message header
+const headerSize = 20
+
+// ErrNotSTUN is returned for packets that are not STUN messages
+var ErrNotSTUN = errors.New("not a STUN message")
+
+// TransactionID identifies a request and its response
+type TransactionID [12]byte
+
+// NewTransactionID is a function that returns a random transaction ID
+func NewTransactionID() TransactionID {
+	var id TransactionID
+	rand.Read(id[:])
+	return id
+}
+
+// Response is a decoded binding response
+type Response struct {
+	// Mapped is the address the request came from as seen by the server,
+	// the public address and port of a NAT
+	Mapped netip.AddrPort
+	// Other is the alternate address and port of the server, the invalid
+	// address if the server does not support the NAT behavior tests
+	Other netip.AddrPort
+	// Origin is the address and port the server sent the response from,
+	// as reported by the server
+	Origin netip.AddrPort
+}
+
+// NewBindingRequest is a function that returns a binding request with the
+// transaction ID, with a CHANGE-REQUEST attribute if any flags are given
+func NewBindingRequest(id TransactionID, change byte) []byte {
+	length := 0
+	if change != 0 {
+		length = 8
+	}
+	msg := make([]byte, headerSize+length)
+	binary.BigEndian.PutUint16(msg[0:], typeBindingRequest)
+	binary.BigEndian.PutUint16(msg[2:], uint16(length))
+	binary.BigEndian.PutUint32(msg[4:], MagicCookie)
+	copy(msg[8:], id[:])
+	if change != 0 {
+		binary.BigEndian.PutUint16(msg[20:], attrChangeRequest)
+		binary.BigEndian.PutUint16(msg[22:], 4)
+		msg[27] = change
+	}
+	return msg
+}
+
+// ParseResponse is a function that decodes a binding response to the
+// request with the transaction ID. Error responses are returned as errors,
+// ErrNotSTUN is returned for other packets and responses to other requests.
+func ParseResponse(msg []byte, id TransactionID) (Response, error) {
+	var resp Response
+	if len(msg) < headerSize || binary.BigEndian.Uint32(msg[4:]) != MagicCookie || TransactionID(msg[8:20]) != id {
+		return resp, ErrNotSTUN
+	}
+	msgType := binary.BigEndian.Uint16(msg[0:])
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	if msgType != typeBindingResponse && msgType != typeBindingError {
+		return resp, ErrNotSTUN
+	}
+	if headerSize+length > len(msg) {
+		return resp, fmt.Errorf("truncated STUN message")
+	}
+
+	// Walk the attributes, their values are padded to 4 bytes
+	var mapped netip.AddrPort
+	attrs := msg[headerSize : headerSize+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:])
+		attrLength := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+attrLength > len(attrs) {
+			return resp, fmt.Errorf("truncated STUN attribute 0x%04x", attrType)
+		}
+		value := attrs[4 : 4+attrLength]
+
+		switch attrType {
+		case attrXORMappedAddress:
+			resp.Mapped = xorAddress(parseAddress(value), id)
+		case attrMappedAddress:
+			mapped = parseAddress(value)
+		case attrOtherAddress, attrChangedAddress:
+			resp.Other = parseAddress(value)
+		case attrResponseOrigin, attrSourceAddress:
+			resp.Origin = parseAddress(value)
+		case attrErrorCode:
+			if len(value) >= 4 {
+				return resp, fmt.Errorf("STUN error %d: %s", int(value[2]&0x07)*100+int(value[3]), value[4:])
+			}
+		}
+		attrs = attrs[min(len(attrs), 4+(attrLength+3)&^3):]
+	}
+	if msgType == typeBindingError {
+		return resp, fmt.Errorf("STUN error response")
+	}
+
+	// Prefer the XOR-MAPPED-ADDRESS, which NATs that rewrite addresses in
+	// the payload do not recognize
+	if !resp.Mapped.IsValid() {
+		resp.Mapped = mapped
+	}
+	if !resp.Mapped.IsValid() {
+		return resp, fmt.Errorf("STUN response without a mapped address")
+	}
+	return resp, nil
+}
+
+// parseAddress decodes an address attribute: a reserved byte, the family
+// (1 for IPv4, 2 for IPv6), the port and the address
+func parseAddress(value []byte) netip.AddrPort {
+	if len(value) < 8 {
+		return netip.AddrPort{}
+	}
+	port := binary.BigEndian.Uint16(value[2:])
+	switch {
+	case value[1] == 0x01:
+		return netip.AddrPortFrom(netip.AddrFrom4([4]byte(value[4:8])), port)
+	case value[1] == 0x02 && len(value) >= 20:
+		return netip.AddrPortFrom(netip.AddrFrom16([16]byte(value[4:20])), port)
+	}
+	return netip.AddrPort{}
+}
+
+// xorAddress decodes the address and port of a XOR-MAPPED-ADDRESS, which
+// are XORed with the magic cookie (and the transaction ID for IPv6)
+func xorAddress(addr netip.AddrPort, id TransactionID) netip.AddrPort {
+	if !addr.IsValid() {
+		return addr
+	}
+	var key [16]byte
+	binary.BigEndian.PutUint32(key[0:], MagicCookie)
+	copy(key[4:], id[:])
+
+	b := addr.Addr().AsSlice()
+	for i := range b {
+		b[i] ^= key[i]
+	}
+	ip, _ := netip.AddrFromSlice(b)
+	return netip.AddrPortFrom(ip, addr.Port()^uint16(MagicCookie>>16))
+}
+
+// encodeAddress encodes an address attribute value, XORed for a
+// XOR-MAPPED-ADDRESS when the transaction ID is given
+func encodeAddress(addr netip.AddrPort, id *TransactionID) []byte {
+	if id != nil {
+		addr = xorAddress(addr, *id)
+	}
+	ip := addr.Addr().Unmap()
+	family := byte(0x01)
+	if ip.Is6() {
+		family = 0x02
+	}
+	value := []byte{0, family, 0, 0}
+	binary.BigEndian.PutUint16(value[2:], addr.Port())
+	return append(value, ip.AsSlice()...)
+}
+
+// NewBindingResponse is a function that returns a binding response to the
+// request with the transaction ID, with the mapped address (as a
+// XOR-MAPPED-ADDRESS), and the other address and origin of the server if
+// they are valid. It is used by tests and simple servers.
+func NewBindingResponse(id TransactionID, mapped, other, origin netip.AddrPort) []byte {
+	msg := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(msg[0:], typeBindingResponse)
+	binary.BigEndian.PutUint32(msg[4:], MagicCookie)
+	copy(msg[8:], id[:])
+
+	appendAttr := func(attrType uint16, value []byte) {
+		attr := make([]byte, 4, 4+len(value))
+		binary.BigEndian.PutUint16(attr[0:], attrType)
+		binary.BigEndian.PutUint16(attr[2:], uint16(len(value)))
+		msg = append(msg, append(attr, value...)...)
+	}
+	appendAttr(attrXORMappedAddress, encodeAddress(mapped, &id))
+	if other.IsValid() {
+		appendAttr(attrOtherAddress, encodeAddress(other, nil))
+	}
+	if origin.IsValid() {
+		appendAttr(attrResponseOrigin, encodeAddress(origin, nil))
+	}
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)-headerSize))
+	return msg
+}
+
+// ParseRequest is a function that decodes a binding request and returns its
+// transaction ID and the flags of its CHANGE-REQUEST attribute (if any)
+func ParseRequest(msg []byte) (TransactionID, byte, error) {
+	var id TransactionID
+	if len(msg) < headerSize || binary.BigEndian.Uint16(msg[0:]) != typeBindingRequest || binary.BigEndian.Uint32(msg[4:]) != MagicCookie {
+		return id, 0, ErrNotSTUN
+	}
+	id = TransactionID(msg[8:20])
+
+	var change byte
+	attrs := msg[headerSize:min(len(msg), headerSize+int(binary.BigEndian.Uint16(msg[2:])))]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:])
+		attrLength := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+attrLength > len(attrs) {
+			break
+		}
+		if attrType == attrChangeRequest && attrLength == 4 {
+			change = attrs[7] & (ChangeIP | ChangePort)
+		}
+		attrs = attrs[min(len(attrs), 4+(attrLength+3)&^3):]
+	}
+	return id, change, nil
+}
@@ -0,0 +1,109 @@
+package stun_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/bitcanon/iptool/stun"
+)
+
+// TestBindingRequest tests the encoding and decoding of binding requests
+func TestBindingRequest(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name   string
+		change byte
+	}{
+		{name: "Plain", change: 0},
+		{name: "ChangeIP", change: stun.ChangeIP},
+		{name: "ChangeIPAndPort", change: stun.ChangeIP | stun.ChangePort},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id := stun.NewTransactionID()
+			gotID, change, err := stun.ParseRequest(stun.NewBindingRequest(id, tc.change))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotID != id || change != tc.change {
+				t.Errorf("expected %x %d, got %x %d", id, tc.change, gotID, change)
+			}
+		})
+	}
+}
+
+// TestParseResponse tests the decoding of binding responses
+func TestParseResponse(t *testing.T) {
+	id := stun.NewTransactionID()
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		msg       []byte
+		expected  stun.Response
+		expectErr error
+	}{
+		{
+			name: "IPv4",
+			msg: stun.NewBindingResponse(id, netip.MustParseAddrPort("203.0.113.5:40000"),
+				netip.MustParseAddrPort("198.51.100.2:3479"), netip.MustParseAddrPort("198.51.100.1:3478")),
+			expected: stun.Response{
+				Mapped: netip.MustParseAddrPort("203.0.113.5:40000"),
+				Other:  netip.MustParseAddrPort("198.51.100.2:3479"),
+				Origin: netip.MustParseAddrPort("198.51.100.1:3478"),
+			},
+		},
+		{
+			name:     "IPv6",
+			msg:      stun.NewBindingResponse(id, netip.MustParseAddrPort("[2001:db8::5]:50000"), netip.AddrPort{}, netip.AddrPort{}),
+			expected: stun.Response{Mapped: netip.MustParseAddrPort("[2001:db8::5]:50000")},
+		},
+		{
+			// RFC 5769, section 2.2: a response with an IPv4 XOR-MAPPED-ADDRESS
+			// of 192.0.2.1:32853 (the other attributes are left out)
+			name: "RFC5769",
+			msg: []byte{
+				0x01, 0x01, 0x00, 0x0c, 0x21, 0x12, 0xa4, 0x42,
+				0xb7, 0xe7, 0xa7, 0x01, 0xbc, 0x34, 0xd6, 0x86, 0xfa, 0x87, 0xdf, 0xae,
+				0x00, 0x20, 0x00, 0x08, 0x00, 0x01, 0xa1, 0x47, 0xe1, 0x12, 0xa6, 0x43,
+			},
+			expected: stun.Response{Mapped: netip.MustParseAddrPort("192.0.2.1:32853")},
+		},
+		{
+			name:      "OtherTransaction",
+			msg:       stun.NewBindingResponse(stun.NewTransactionID(), netip.MustParseAddrPort("203.0.113.5:40000"), netip.AddrPort{}, netip.AddrPort{}),
+			expectErr: stun.ErrNotSTUN,
+		},
+		{
+			name:      "NotSTUN",
+			msg:       []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+			expectErr: stun.ErrNotSTUN,
+		},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			txid := id
+			if tc.name == "RFC5769" {
+				copy(txid[:], tc.msg[8:20])
+			}
+			resp, err := stun.ParseResponse(tc.msg, txid)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("expected error %v, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, resp)
+			}
+		})
+	}
+}
@@ -1,17 +1,32 @@
 package tcp
 
 import (
+	"context"
 	"net"
+	"net/netip"
 	"strconv"
 	"time"
 )
 
-func PingTCP(host string, port int, timeoutMs time.Duration) (time.Duration, error) {
+// FallbackDelay is the default time to wait for a connection attempt before
+// the next address is tried, the connection attempt delay of RFC 8305
+const FallbackDelay = 250 * time.Millisecond
+
+// PingTCP is a function that connects to the host on the port and returns
+// the time it took to establish the connection (the round-trip time of the
+// SYN and SYN/ACK). The connection is made from the source address if it is
+// not nil, e.g. to test the path of a specific interface.
+func PingTCP(host string, port int, source *net.IPAddr, timeoutMs time.Duration) (time.Duration, error) {
+	dialer := net.Dialer{Timeout: timeoutMs}
+	if source != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: source.IP, Zone: source.Zone}
+	}
+
 	// Start the timer
 	start := time.Now()
 
 	// Connect to the host on the specified port and timeout
-	conn, err := net.DialTimeout("tcp", host+":"+strconv.Itoa(port), timeoutMs)
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
 	if err != nil {
 		return 0, err
 	}
@@ -22,3 +37,89 @@ func PingTCP(host string, port int, timeoutMs time.Duration) (time.Duration, err
 
 	return rtt, nil
 }
+
+// PingHappyEyeballs is a function that connects to the host on the port like
+// PingTCP, but tries the addresses of the host like a happy eyeballs client
+// (RFC 8305): the connection attempts are started one after the other, in
+// order, with the fallback delay in between (or as soon as an attempt fails)
+// and the first connection established wins. It returns the address that
+// was connected to and the time from the first attempt until the connection
+// was established, which includes the fallback delays, as users of
+// dual-stack hosts experience it. The error of the first attempt is returned
+// if no connection could be established within the timeout.
+func PingHappyEyeballs(addrs []netip.Addr, port int, source *net.IPAddr, timeout, fallbackDelay time.Duration) (netip.Addr, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	if source != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: source.IP, Zone: source.Zone}
+	}
+
+	// The attempts report their result on the channel
+	type attempt struct {
+		addr netip.Addr
+		conn net.Conn
+		err  error
+	}
+	results := make(chan attempt, len(addrs))
+	dial := func(addr netip.Addr) {
+		conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, uint16(port)).String())
+		results <- attempt{addr: addr, conn: conn, err: err}
+	}
+
+	// Start the timer
+	start := time.Now()
+
+	var firstErr error
+	next, pending := 0, 0
+	for next < len(addrs) || pending > 0 {
+		// Start the next attempt if none is in flight
+		if pending == 0 {
+			go dial(addrs[next])
+			next++
+			pending++
+		}
+
+		// Wait for an attempt to finish, or for the fallback delay
+		var fallback <-chan time.Time
+		var timer *time.Timer
+		if next < len(addrs) {
+			timer = time.NewTimer(fallbackDelay)
+			fallback = timer.C
+		}
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				rtt := time.Since(start)
+				r.conn.Close()
+
+				// Cancel the other attempts and close their connections
+				cancel()
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				if timer != nil {
+					timer.Stop()
+				}
+				return r.addr, rtt, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		case <-fallback:
+			go dial(addrs[next])
+			next++
+			pending++
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return netip.Addr{}, 0, firstErr
+}
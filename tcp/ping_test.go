@@ -0,0 +1,70 @@
+package tcp_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/tcp"
+)
+
+func TestPingHappyEyeballs(t *testing.T) {
+	// Listen on a local port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	open := netip.MustParseAddr("127.0.0.1")
+	refused := netip.MustParseAddr("127.0.0.2")
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		addrs     []netip.Addr
+		expected  netip.Addr
+		expectErr bool
+	}{
+		{name: "Single", addrs: []netip.Addr{open}, expected: open},
+		{name: "FallbackOnError", addrs: []netip.Addr{refused, open}, expected: open},
+		{name: "FirstWins", addrs: []netip.Addr{open, refused}, expected: open},
+		{name: "AllFail", addrs: []netip.Addr{refused}, expectErr: true},
+	}
+
+	// Run test cases, the attempt to 127.0.0.2 is refused since nobody
+	// listens on the port there (the port is only in use on 127.0.0.1)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, rtt, err := tcp.PingHappyEyeballs(tc.addrs, port, nil, 2*time.Second, time.Second)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, connected to %s", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if addr != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, addr)
+			}
+
+			// A failed attempt starts the next one without waiting for the fallback delay
+			if rtt >= time.Second {
+				t.Errorf("expected the connection within the fallback delay, took %s", rtt)
+			}
+		})
+	}
+}
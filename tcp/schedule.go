@@ -0,0 +1,97 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package tcp
+
+import (
+	"math/rand"
+	"time"
+)
+
+// MinAdaptiveDelay is the shortest delay between probes in adaptive mode
+const MinAdaptiveDelay = 10 * time.Millisecond
+
+// Scheduler calculates the delay before the next probe of a ping loop.
+// The delay is the fixed Delay, or in adaptive mode the smoothed round-trip
+// time of the previous probes (like ping -A), so that low-latency links are
+// probed faster. A random Jitter is added to every delay to avoid
+// phase-locking with periodic events in the network.
+type Scheduler struct {
+	Delay    time.Duration
+	Jitter   time.Duration
+	Adaptive bool
+
+	// Rand is the source of the jitter, the global source is used if nil
+	Rand *rand.Rand
+
+	// Smoothed round-trip time, zero until the first response
+	srtt time.Duration
+	// The last probe timed out
+	lost bool
+}
+
+// Observe updates the smoothed round-trip time with the result of a probe,
+// ok is false if the probe timed out
+func (s *Scheduler) Observe(rtt time.Duration, ok bool) {
+	s.lost = !ok
+	if !ok {
+		return
+	}
+
+	// Smooth the round-trip time like the TCP retransmission timer (RFC 6298)
+	if s.srtt == 0 {
+		s.srtt = rtt
+	} else {
+		s.srtt += (rtt - s.srtt) / 8
+	}
+}
+
+// Next returns the delay before the next probe
+func (s *Scheduler) Next() time.Duration {
+	delay := s.Delay
+
+	// In adaptive mode, wait one round-trip time but never longer than the
+	// fixed delay, and back off to the fixed delay after a timeout
+	if s.Adaptive && s.srtt > 0 && !s.lost {
+		delay = s.srtt
+		if delay < MinAdaptiveDelay {
+			delay = MinAdaptiveDelay
+		}
+		if delay > s.Delay {
+			delay = s.Delay
+		}
+	}
+
+	// Add a random jitter between -Jitter and +Jitter
+	if s.Jitter > 0 {
+		var n int64
+		if s.Rand != nil {
+			n = s.Rand.Int63n(2*int64(s.Jitter) + 1)
+		} else {
+			n = rand.Int63n(2*int64(s.Jitter) + 1)
+		}
+		delay += time.Duration(n) - s.Jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
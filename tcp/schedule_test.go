@@ -0,0 +1,58 @@
+package tcp_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/tcp"
+)
+
+func TestSchedulerNext(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		adaptive bool
+		rtts     []time.Duration // zero is a timeout
+		expected time.Duration
+	}{
+		{name: "Fixed", adaptive: false, rtts: []time.Duration{20 * time.Millisecond}, expected: time.Second},
+		{name: "AdaptiveNoResponse", adaptive: true, rtts: nil, expected: time.Second},
+		{name: "AdaptiveFirstResponse", adaptive: true, rtts: []time.Duration{20 * time.Millisecond}, expected: 20 * time.Millisecond},
+		{name: "AdaptiveSmoothed", adaptive: true, rtts: []time.Duration{20 * time.Millisecond, 100 * time.Millisecond}, expected: 30 * time.Millisecond},
+		{name: "AdaptiveMinimum", adaptive: true, rtts: []time.Duration{time.Millisecond}, expected: tcp.MinAdaptiveDelay},
+		{name: "AdaptiveMaximum", adaptive: true, rtts: []time.Duration{3 * time.Second}, expected: time.Second},
+		{name: "AdaptiveTimeout", adaptive: true, rtts: []time.Duration{20 * time.Millisecond, 0}, expected: time.Second},
+		{name: "AdaptiveRecovered", adaptive: true, rtts: []time.Duration{20 * time.Millisecond, 0, 20 * time.Millisecond}, expected: 20 * time.Millisecond},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tcp.Scheduler{Delay: time.Second, Adaptive: tc.adaptive}
+			for _, rtt := range tc.rtts {
+				s.Observe(rtt, rtt > 0)
+			}
+			if got := s.Next(); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	s := tcp.Scheduler{Delay: 50 * time.Millisecond, Jitter: 100 * time.Millisecond, Rand: rand.New(rand.NewSource(1))}
+
+	// The delay varies within the jitter and is never negative
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 1000; i++ {
+		delay := s.Next()
+		if delay < 0 || delay > 150*time.Millisecond {
+			t.Fatalf("delay %s outside of 0s-150ms", delay)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 100 {
+		t.Errorf("expected varying delays, got %d different delays", len(seen))
+	}
+}
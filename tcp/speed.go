@@ -0,0 +1,150 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package tcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// speedBufferSize is the size of the buffer written to (or read from) the connection
+const speedBufferSize = 128 * 1024
+
+// SpeedInterval holds the statistics of a single reporting interval
+// (or of the whole test) of a throughput test
+type SpeedInterval struct {
+	Start       time.Duration
+	End         time.Duration
+	Bytes       int64
+	Retransmits int
+}
+
+// Mbps returns the throughput of the interval in megabits per second
+func (s SpeedInterval) Mbps() float64 {
+	seconds := (s.End - s.Start).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) * 8 / seconds / 1e6
+}
+
+// SpeedSend writes data to the connection for the specified duration and
+// calls the report function at the end of every interval. The statistics
+// of the whole test are returned when the duration has passed.
+// The number of retransmits is -1 if it is not available on the platform.
+func SpeedSend(conn net.Conn, duration, interval time.Duration, report func(SpeedInterval)) (SpeedInterval, error) {
+	buf := make([]byte, speedBufferSize)
+	return speedLoop(conn, duration, interval, report, func() (int, error) {
+		return conn.Write(buf)
+	})
+}
+
+// SpeedReceive reads data from the connection until the sender closes the
+// connection and calls the report function at the end of every interval.
+// The statistics of the whole test are returned when the connection is closed.
+func SpeedReceive(conn net.Conn, interval time.Duration, report func(SpeedInterval)) (SpeedInterval, error) {
+	buf := make([]byte, speedBufferSize)
+	total, err := speedLoop(conn, 0, interval, report, func() (int, error) {
+		return conn.Read(buf)
+	})
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return total, err
+}
+
+// speedLoop calls the transfer function until the duration has passed (or
+// forever if the duration is zero) and keeps track of the transferred bytes
+func speedLoop(conn net.Conn, duration, interval time.Duration, report func(SpeedInterval), transfer func() (int, error)) (SpeedInterval, error) {
+	start := time.Now()
+	current := SpeedInterval{Start: 0, End: interval}
+	total := SpeedInterval{}
+
+	// Retransmits are counted relative to the start of the test
+	baseRetransmits := Retransmits(conn)
+	lastRetransmits := baseRetransmits
+
+	// finishInterval reports the current interval and starts the next one
+	finishInterval := func(end time.Duration) {
+		retransmits := Retransmits(conn)
+		current.End = end
+		current.Retransmits = retransmits - lastRetransmits
+		if retransmits < 0 {
+			current.Retransmits = -1
+		}
+		lastRetransmits = retransmits
+		if report != nil && current.End > current.Start {
+			report(current)
+		}
+		current = SpeedInterval{Start: end, End: end + interval}
+	}
+
+	// Stop writing when the duration has passed
+	if duration > 0 {
+		conn.SetWriteDeadline(start.Add(duration))
+	}
+
+	var err error
+	for {
+		n, transferErr := transfer()
+		elapsed := time.Since(start)
+
+		current.Bytes += int64(n)
+		total.Bytes += int64(n)
+
+		// Report every interval that has passed
+		for elapsed >= current.End {
+			finishInterval(current.End)
+		}
+
+		if transferErr != nil {
+			var netErr net.Error
+			if !(errors.As(transferErr, &netErr) && netErr.Timeout()) {
+				err = transferErr
+			}
+			break
+		}
+		if duration > 0 && elapsed >= duration {
+			break
+		}
+	}
+
+	// Report the last (partial) interval
+	elapsed := time.Since(start)
+	if duration > 0 && elapsed > duration {
+		elapsed = duration
+	}
+	if current.Bytes > 0 || elapsed > current.Start {
+		finishInterval(elapsed)
+	}
+
+	// Calculate the statistics of the whole test
+	total.End = elapsed
+	total.Retransmits = lastRetransmits - baseRetransmits
+	if lastRetransmits < 0 {
+		total.Retransmits = -1
+	}
+
+	return total, err
+}
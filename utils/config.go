@@ -26,7 +26,9 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -181,3 +183,64 @@ func PrintConfigInfo() {
 	// Print the environment variables that start with the specified prefix
 	PrintVariables(os.Stdout, Environment)
 }
+
+// ParseConfigValue parses the string value of a configuration variable as
+// the type of the flag it is bound to (e.g. int, bool, duration or
+// stringSlice), so that it is written to the config file with the right type.
+// Lists are separated by commas, durations are kept as strings (e.g. 100ms).
+func ParseConfigValue(kind, s string) (any, error) {
+	switch kind {
+	case "bool":
+		value, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value: %s (must be true or false)", s)
+		}
+		return value, nil
+	case "int", "int8", "int16", "int32", "int64":
+		value, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer value: %s", s)
+		}
+		return value, nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		value, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer value: %s", s)
+		}
+		return value, nil
+	case "float32", "float64":
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number value: %s", s)
+		}
+		return value, nil
+	case "duration":
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid duration value: %s (e.g. 500ms, 10s or 1m)", s)
+		}
+		return s, nil
+	case "stringSlice", "stringArray":
+		value := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				value = append(value, item)
+			}
+		}
+		return value, nil
+	case "intSlice":
+		value := []int{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer value: %s", item)
+			}
+			value = append(value, n)
+		}
+		return value, nil
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,52 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/utils"
+)
+
+func TestParseConfigValue(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		kind      string
+		input     string
+		expected  any
+		expectErr bool
+	}{
+		{kind: "string", input: "eth0", expected: "eth0"},
+		{kind: "bool", input: "true", expected: true},
+		{kind: "bool", input: "yes", expectErr: true},
+		{kind: "int", input: "2000", expected: int64(2000)},
+		{kind: "int", input: "-1", expected: int64(-1)},
+		{kind: "int", input: "2s", expectErr: true},
+		{kind: "uint", input: "-1", expectErr: true},
+		{kind: "float64", input: "0.5", expected: 0.5},
+		{kind: "duration", input: "100ms", expected: "100ms"},
+		{kind: "duration", input: "100", expectErr: true},
+		{kind: "stringSlice", input: "mgmt, voice,,data", expected: []string{"mgmt", "voice", "data"}},
+		{kind: "stringSlice", input: "", expected: []string{}},
+		{kind: "intSlice", input: "26,28,30", expected: []int{26, 28, 30}},
+		{kind: "intSlice", input: "26,x", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.kind+"/"+tc.input, func(t *testing.T) {
+			got, err := utils.ParseConfigValue(tc.kind, tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %#v, got %#v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"math"
+	"strings"
+)
+
+// sparkTicks are the characters used to draw a sparkline, from low to high
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline returns a sparkline of the values, one character per value,
+// scaled between zero and the largest value. Missing values (NaN) are drawn
+// with the gap character.
+func Sparkline(values []float64, gap rune) string {
+	// Find the largest value to scale the sparkline
+	highest := 0.0
+	for _, v := range values {
+		if !math.IsNaN(v) && v > highest {
+			highest = v
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(gap)
+		case highest <= 0:
+			sb.WriteRune(sparkTicks[0])
+		default:
+			index := int(math.Round(v / highest * float64(len(sparkTicks)-1)))
+			index = min(max(index, 0), len(sparkTicks)-1)
+			sb.WriteRune(sparkTicks[index])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package utils_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bitcanon/iptool/utils"
+)
+
+func TestSparkline(t *testing.T) {
+	nan := math.NaN()
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		values   []float64
+		expected string
+	}{
+		{name: "Empty", values: nil, expected: ""},
+		{name: "Zeros", values: []float64{0, 0, 0}, expected: "▁▁▁"},
+		{name: "Rising", values: []float64{0, 1, 2, 3, 4, 5, 6, 7}, expected: "▁▂▃▄▅▆▇█"},
+		{name: "Scaled", values: []float64{10, 70, 35}, expected: "▂█▅"},
+		{name: "Gaps", values: []float64{nan, 1, nan, 2}, expected: "x▅x█"},
+		{name: "OnlyGaps", values: []float64{nan, nan}, expected: "xx"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := utils.Sparkline(tc.values, 'x'); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// maxExpandedTargets is the maximum number of targets a single pattern may expand to
+const maxExpandedTargets = 65536
+
+// ExpandPattern expands brace patterns in a target string and returns the list
+// of resulting targets. Two kinds of brace expressions are supported:
+//   - Numeric ranges: "web{01..20}.example.com" (leading zeros set the width)
+//   - Lists: "{core,edge}-sw1"
+//
+// Several brace expressions may be combined, e.g. "10.0.{1..4}.{1,254}".
+// A string without braces is returned as is.
+func ExpandPattern(pattern string) ([]string, error) {
+	// Find the first brace expression
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		if strings.Contains(pattern, "}") {
+			return nil, fmt.Errorf("unbalanced braces in target: %s", pattern)
+		}
+		return []string{pattern}, nil
+	}
+	end := strings.Index(pattern[start:], "}")
+	if end < 0 {
+		return nil, fmt.Errorf("unbalanced braces in target: %s", pattern)
+	}
+	end += start
+
+	// Expand the brace expression into its alternatives
+	alternatives, err := expandBraces(pattern[start+1 : end])
+	if err != nil {
+		return nil, fmt.Errorf("%w in target: %s", err, pattern)
+	}
+
+	// Expand the rest of the pattern recursively
+	suffixes, err := ExpandPattern(pattern[end+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the expansion stays within reasonable limits
+	if len(alternatives)*len(suffixes) > maxExpandedTargets {
+		return nil, fmt.Errorf("target pattern expands to more than %d targets: %s", maxExpandedTargets, pattern)
+	}
+
+	// Combine the prefix, the alternatives and the suffixes
+	prefix := pattern[:start]
+	targets := make([]string, 0, len(alternatives)*len(suffixes))
+	for _, alternative := range alternatives {
+		for _, suffix := range suffixes {
+			targets = append(targets, prefix+alternative+suffix)
+		}
+	}
+
+	return targets, nil
+}
+
+// expandBraces expands the contents of a single brace expression, either
+// a numeric range ("1..4", "01..20") or a comma separated list ("a,b,c").
+func expandBraces(expr string) ([]string, error) {
+	// Comma separated list
+	if !strings.Contains(expr, "..") {
+		if !strings.Contains(expr, ",") {
+			return nil, fmt.Errorf("invalid brace expression {%s}", expr)
+		}
+		return strings.Split(expr, ","), nil
+	}
+
+	// Numeric range
+	bounds := strings.SplitN(expr, "..", 2)
+	first, err1 := strconv.Atoi(bounds[0])
+	last, err2 := strconv.Atoi(bounds[1])
+	if err1 != nil || err2 != nil || first < 0 || last < 0 {
+		return nil, fmt.Errorf("invalid numeric range {%s}", expr)
+	}
+
+	// Zero-pad the numbers if either bound has a leading zero
+	width := 0
+	if (len(bounds[0]) > 1 && bounds[0][0] == '0') || (len(bounds[1]) > 1 && bounds[1][0] == '0') {
+		width = max(len(bounds[0]), len(bounds[1]))
+	}
+
+	// Count up or down depending on the order of the bounds
+	step := 1
+	if last < first {
+		step = -1
+	}
+	if (last-first)*step+1 > maxExpandedTargets {
+		return nil, fmt.Errorf("numeric range {%s} is too large", expr)
+	}
+
+	var values []string
+	for i := first; ; i += step {
+		values = append(values, fmt.Sprintf("%0*d", width, i))
+		if i == last {
+			break
+		}
+	}
+
+	return values, nil
+}
+
+// ExpandTargets expands every target of the list and returns the combined
+// list of targets, in order. A target starting with "@" refers to a named
+// group of targets defined in the configuration file, for example:
+//
+//	groups:
+//	  dns-servers: [1.1.1.1, 8.8.8.8]
+//
+// Brace patterns are expanded both in targets and in group members.
+func ExpandTargets(targets []string) ([]string, error) {
+	return expandTargets(targets, 0)
+}
+
+// maxGroupDepth is the maximum nesting depth of groups referring to groups
+const maxGroupDepth = 8
+
+// expandTargets expands the targets and keeps track of the group nesting depth
+func expandTargets(targets []string, depth int) ([]string, error) {
+	var expanded []string
+	for _, target := range targets {
+		// Expand group references (@name)
+		if strings.HasPrefix(target, "@") {
+			if depth >= maxGroupDepth {
+				return nil, fmt.Errorf("target groups nested too deeply: %s", target)
+			}
+			members, err := GetTargetGroup(strings.TrimPrefix(target, "@"))
+			if err != nil {
+				return nil, err
+			}
+			t, err := expandTargets(members, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, t...)
+			continue
+		}
+
+		t, err := ExpandPattern(target)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, t...)
+	}
+	return expanded, nil
+}
+
+// GetTargetGroup returns the members of the named target group
+// defined in the "groups" section of the configuration file
+func GetTargetGroup(name string) ([]string, error) {
+	key := "groups." + strings.ToLower(name)
+	if name == "" || !viper.IsSet(key) {
+		return nil, fmt.Errorf("unknown target group: @%s", name)
+	}
+
+	members := viper.GetStringSlice(key)
+	if len(members) == 0 {
+		return nil, fmt.Errorf("target group is empty: @%s", name)
+	}
+	return members, nil
+}
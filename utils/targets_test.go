@@ -0,0 +1,99 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/viper"
+)
+
+// TestExpandPattern tests the ExpandPattern function
+// using numeric ranges, lists and combinations of both
+func TestExpandPattern(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "NoPattern", input: "example.com", expected: []string{"example.com"}},
+		{name: "NumericRange", input: "10.0.{1..4}.1", expected: []string{"10.0.1.1", "10.0.2.1", "10.0.3.1", "10.0.4.1"}},
+		{name: "ZeroPadded", input: "web{08..11}.example.com", expected: []string{"web08.example.com", "web09.example.com", "web10.example.com", "web11.example.com"}},
+		{name: "Descending", input: "h{3..1}", expected: []string{"h3", "h2", "h1"}},
+		{name: "List", input: "{core,edge}-sw1", expected: []string{"core-sw1", "edge-sw1"}},
+		{name: "Combined", input: "10.0.{1..2}.{1,254}", expected: []string{"10.0.1.1", "10.0.1.254", "10.0.2.1", "10.0.2.254"}},
+		{name: "Unbalanced", input: "web{01..20.example.com", expectErr: true},
+		{name: "UnbalancedClose", input: "web01}.example.com", expectErr: true},
+		{name: "InvalidRange", input: "web{a..c}", expectErr: true},
+		{name: "SingleItem", input: "web{01}", expectErr: true},
+		{name: "TooLarge", input: "{0..9999}.{0..9999}", expectErr: true},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Expand the pattern
+			result, err := utils.ExpandPattern(testCase.input)
+			if testCase.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got: %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// Check if the result matches the expected value
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %v, got: %v", testCase.expected, result)
+			}
+		})
+	}
+}
+
+// TestExpandTargets tests the ExpandTargets function
+// using target groups defined in the configuration
+func TestExpandTargets(t *testing.T) {
+	// Define the target groups
+	viper.Set("groups.dns-servers", []string{"1.1.1.1", "8.8.8.8"})
+	viper.Set("groups.web", []string{"web{1..2}", "@dns-servers"})
+	viper.Set("groups.loop", []string{"@loop"})
+	defer viper.Reset()
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		input     []string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "PlainTargets", input: []string{"a", "b"}, expected: []string{"a", "b"}},
+		{name: "Group", input: []string{"@dns-servers"}, expected: []string{"1.1.1.1", "8.8.8.8"}},
+		{name: "GroupWithPatternsAndNesting", input: []string{"@web"}, expected: []string{"web1", "web2", "1.1.1.1", "8.8.8.8"}},
+		{name: "Mixed", input: []string{"9.9.9.9", "@dns-servers"}, expected: []string{"9.9.9.9", "1.1.1.1", "8.8.8.8"}},
+		{name: "UnknownGroup", input: []string{"@nope"}, expectErr: true},
+		{name: "RecursiveGroup", input: []string{"@loop"}, expectErr: true},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := utils.ExpandTargets(testCase.input)
+			if testCase.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got: %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("expected: %v, got: %v", testCase.expected, result)
+			}
+		})
+	}
+}
@@ -22,21 +22,128 @@ THE SOFTWARE.
 package utils
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Named timestamp formats accepted by the time-format configuration key
+const (
+	TimeFormatDefault = "default"
+	TimeFormatRFC3339 = "rfc3339"
+	TimeFormatEpoch   = "epoch"
+	TimeFormatEpochMs = "epoch-ms"
+	TimeFormatUnix    = "unix"    // Alias of epoch
+	TimeFormatUnixMs  = "unix-ms" // Alias of epoch-ms
 )
 
-// GetTime returns the current time as a string
+// defaultTimeLayout is the layout of the default timestamp format, with
+// the fractional seconds padded to a fixed width of 7 digits
+const defaultTimeLayout = "2006-01-02 15:04:05.0000000"
+
+// GetTimestamp returns the current time as a string, formatted according
+// to the time-format and time-zone configuration keys
 func GetTimestamp() string {
-	// Get the current time as a string
-	currentTime := time.Now().Format("2006-01-02 15:04:05.9999999")
+	return FormatTimestamp(time.Now())
+}
+
+// FormatTimestamp formats the time t according to the time-format and
+// time-zone configuration keys. The time format is one of the named formats
+// (default, rfc3339, epoch or epoch-ms) or a custom Go time layout, and the
+// time zone is either local (default) or utc.
+func FormatTimestamp(t time.Time) string {
+	return FormatTimestampAs(t, viper.GetString("time-format"))
+}
+
+// FormatTimestampAs formats the time t like FormatTimestamp, but using the
+// time format given instead of the time-format configuration key, so that a
+// command can override the format of its own timestamps
+func FormatTimestampAs(t time.Time, format string) string {
+	if strings.EqualFold(viper.GetString("time-zone"), "utc") {
+		t = t.UTC()
+	}
+
+	switch strings.ToLower(format) {
+	case "", TimeFormatDefault:
+		return t.Format(defaultTimeLayout)
+	case TimeFormatRFC3339:
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	case TimeFormatEpoch, TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeFormatEpochMs, TimeFormatUnixMs:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return t.Format(format)
+	}
+}
 
-	// Add zeros to the end of the string if it is shorter than 27 characters
-	currentTimeLen := len(currentTime)
-	if currentTimeLen < 27 {
-		currentTime += strings.Repeat("0", 27-currentTimeLen)
+// ValidateTimeConfig checks that the time-zone configuration key is valid
+// and that a custom time-format layout contains at least one time element
+func ValidateTimeConfig() error {
+	switch strings.ToLower(viper.GetString("time-zone")) {
+	case "", "local", "utc":
+	default:
+		return fmt.Errorf("invalid time zone: %s (must be local or utc)", viper.GetString("time-zone"))
 	}
 
-	// Return the current time
-	return currentTime
+	return ValidateTimeFormat(viper.GetString("time-format"))
+}
+
+// ValidateTimeFormat checks that a time format is one of the named formats
+// or a custom layout containing at least one time element
+func ValidateTimeFormat(format string) error {
+	// A layout without any time elements is formatted as itself, which is most likely a typo
+	switch strings.ToLower(format) {
+	case "", TimeFormatDefault, TimeFormatRFC3339, TimeFormatEpoch, TimeFormatEpochMs, TimeFormatUnix, TimeFormatUnixMs:
+		return nil
+	}
+	reference := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if reference.Format(format) == format {
+		return fmt.Errorf("invalid time format: %s (must be default, rfc3339, epoch, epoch-ms, unix, unix-ms or a Go time layout)", format)
+	}
+	return nil
+}
+
+// ParseDuration parses a duration like time.ParseDuration, but also accepts
+// days (d) and weeks (w) as units, e.g. "30d", "2w" or "1d12h". Days are
+// always 24 hours long.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration: empty")
+	}
+	var total time.Duration
+
+	// Consume the leading day and week components, the rest is a Go duration
+	for {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) || (s[i] != 'd' && s[i] != 'w') {
+			break
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		unit := 24 * time.Hour
+		if s[i] == 'w' {
+			unit *= 7
+		}
+		total += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+	if s == "" {
+		return total, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s (e.g. 30d, 2w or 12h)", s)
+	}
+	return total + d, nil
 }
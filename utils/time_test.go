@@ -0,0 +1,144 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitcanon/iptool/utils"
+	"github.com/spf13/viper"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	// 2024-03-05 07:08:09.0123456 UTC
+	timestamp := time.Date(2024, 3, 5, 7, 8, 9, 12345600, time.UTC)
+
+	// Setup test cases
+	testCases := []struct {
+		name      string
+		format    string
+		expected  string
+		expectErr bool
+	}{
+		{name: "Empty", format: "", expected: "2024-03-05 07:08:09.0123456"},
+		{name: "Default", format: "default", expected: "2024-03-05 07:08:09.0123456"},
+		{name: "RFC3339", format: "rfc3339", expected: "2024-03-05T07:08:09.012Z"},
+		{name: "Epoch", format: "epoch", expected: "1709622489"},
+		{name: "EpochMs", format: "EPOCH-MS", expected: "1709622489012"},
+		{name: "Unix", format: "unix", expected: "1709622489"},
+		{name: "UnixMs", format: "unix-ms", expected: "1709622489012"},
+		{name: "CustomLayout", format: "02/01/2006 15:04", expected: "05/03/2024 07:08"},
+		{name: "InvalidLayout", format: "timestamp", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("time-format", tc.format)
+			viper.Set("time-zone", "utc")
+			defer viper.Reset()
+
+			err := utils.ValidateTimeConfig()
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := utils.FormatTimestamp(timestamp.In(time.FixedZone("test", 3600))); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatTimestampAs(t *testing.T) {
+	// 2024-03-05 07:08:09.0123456 in a time zone one hour east of UTC
+	timestamp := time.Date(2024, 3, 5, 8, 8, 9, 12345600, time.FixedZone("test", 3600))
+
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		format   string
+		zone     string
+		expected string
+	}{
+		{name: "RFC3339Local", format: "rfc3339", zone: "local", expected: "2024-03-05T08:08:09.012+01:00"},
+		{name: "RFC3339UTC", format: "rfc3339", zone: "utc", expected: "2024-03-05T07:08:09.012Z"},
+		{name: "Unix", format: "unix", zone: "local", expected: "1709622489"},
+		{name: "UnixMs", format: "UNIX-MS", zone: "utc", expected: "1709622489012"},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("time-format", "default")
+			viper.Set("time-zone", tc.zone)
+			defer viper.Reset()
+
+			if err := utils.ValidateTimeFormat(tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := utils.FormatTimestampAs(timestamp, tc.format); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateTimeZone(t *testing.T) {
+	defer viper.Reset()
+
+	for _, zone := range []string{"", "local", "UTC"} {
+		viper.Set("time-zone", zone)
+		if err := utils.ValidateTimeConfig(); err != nil {
+			t.Errorf("unexpected error for time zone %q: %v", zone, err)
+		}
+	}
+
+	viper.Set("time-zone", "mars")
+	if err := utils.ValidateTimeConfig(); err == nil {
+		t.Errorf("expected error for time zone mars, got nil")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{input: "30d", expected: 30 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "1w2d", expected: 9 * 24 * time.Hour},
+		{input: "1d12h", expected: 36 * time.Hour},
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "0", expected: 0},
+		{input: "", expectErr: true},
+		{input: "d", expectErr: true},
+		{input: "30days", expectErr: true},
+		{input: "1h2d", expectErr: true},
+	}
+
+	// Run test cases
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := utils.ParseDuration(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, d)
+			}
+		})
+	}
+}
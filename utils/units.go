@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatBytes returns a human readable representation of a number of bytes
+// using binary prefixes (e.g. 1536 bytes is returned as "1.50 KiB")
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	// Find the largest prefix that keeps the value above 1
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.2f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseBytes parses a human readable number of bytes with an optional binary
+// or decimal prefix (e.g. "512", "10k", "10KB", "10KiB" or "1.5G"). The
+// prefixes are binary, so both "1KB" and "1KiB" are 1024 bytes.
+func ParseBytes(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+
+	// Strip the unit, e.g. the "KIB" of "10KIB"
+	value = strings.TrimSuffix(value, "B")
+	value = strings.TrimSuffix(value, "I")
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", value[n-1]); exp >= 0 {
+			multiplier = int64(1) << (10 * (exp + 1))
+			value = strings.TrimSpace(value[:n-1])
+		}
+	}
+
+	// Parse the number, which may have a fraction (e.g. 1.5G)
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 || number*float64(multiplier) > float64(1<<62) {
+		return 0, fmt.Errorf("invalid size: %s (e.g. 512, 10K, 100MB or 1GiB)", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
@@ -0,0 +1,76 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/bitcanon/iptool/utils"
+)
+
+// TestFormatBytes tests the FormatBytes function using various input values
+func TestFormatBytes(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    int64
+		expected string
+	}{
+		{name: "Zero", input: 0, expected: "0 B"},
+		{name: "Bytes", input: 1023, expected: "1023 B"},
+		{name: "KiB", input: 1536, expected: "1.50 KiB"},
+		{name: "MiB", input: 10 * 1024 * 1024, expected: "10.00 MiB"},
+		{name: "GiB", input: 3 * 1024 * 1024 * 1024 / 2, expected: "1.50 GiB"},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := utils.FormatBytes(testCase.input)
+			if result != testCase.expected {
+				t.Errorf("expected: %s, got: %s", testCase.expected, result)
+			}
+		})
+	}
+}
+
+// TestParseBytes tests the ParseBytes function using various input values
+func TestParseBytes(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{name: "Zero", input: "0", expected: 0},
+		{name: "Bytes", input: "512", expected: 512},
+		{name: "BytesUnit", input: "512B", expected: 512},
+		{name: "K", input: "10k", expected: 10 * 1024},
+		{name: "KB", input: "10KB", expected: 10 * 1024},
+		{name: "KiB", input: "10KiB", expected: 10 * 1024},
+		{name: "MB", input: "100 MB", expected: 100 * 1024 * 1024},
+		{name: "Fraction", input: "1.5G", expected: 3 * 1024 * 1024 * 1024 / 2},
+		{name: "Empty", input: "", wantErr: true},
+		{name: "Negative", input: "-1K", wantErr: true},
+		{name: "UnknownUnit", input: "10X", wantErr: true},
+		{name: "TooLarge", input: "100E", wantErr: true},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := utils.ParseBytes(testCase.input)
+			if testCase.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %d", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != testCase.expected {
+				t.Errorf("expected: %d, got: %d", testCase.expected, result)
+			}
+		})
+	}
+}
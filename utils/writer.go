@@ -0,0 +1,303 @@
+/*
+Copyright © 2024 Mikael Schultz <mikael@conf-t.se>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// OutputOptions controls how an OutputWriter writes the output
+type OutputOptions struct {
+	// Append to the output file instead of overwriting it
+	Append bool
+
+	// Stream flushes every write to the output right away, for commands
+	// that print their results as they come in (e.g. tcp ping)
+	Stream bool
+
+	// Tee writes the header and everything written to the output file to
+	// this writer as well (e.g. standard output), it is ignored when there
+	// is no file
+	Tee io.Writer
+
+	// MaxSize rotates the output file when it would grow beyond this many
+	// bytes, 0 means that the file is never rotated
+	MaxSize int64
+
+	// Header is written at the start of the output and of every rotated
+	// file, e.g. the header line of a CSV file. It is not written when
+	// appending to a file that is not empty.
+	Header string
+}
+
+// OutputWriter is a buffered writer for the output of a command, written to
+// standard output or to a file. Files with a .gz extension are compressed
+// with gzip. Writes block while the buffer is written to the output, so a
+// slow disk or pipe slows the command down instead of using more memory.
+type OutputWriter struct {
+	mu       sync.Mutex
+	filename string
+	opts     OutputOptions
+	file     *os.File        // The file written to, os.Stdout if there is none
+	counter  *countingWriter // Counts the bytes written to the file
+	gzip     *gzip.Writer    // The compressor, nil if the file is not compressed
+	buffer   *bufio.Writer
+	written  int64 // Bytes written to the current file, excluding the header
+	rotated  int   // Number of the last rotated file
+	closed   bool
+}
+
+// countingWriter is a writer that counts the bytes written to it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write is a function that writes to the underlying writer and counts the
+// bytes written
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewOutputWriter is a function that returns a writer for the output of a
+// command. If the filename is empty, the output is written to standard output.
+func NewOutputWriter(filename string, opts OutputOptions) (*OutputWriter, error) {
+	w := &OutputWriter{filename: filename, opts: opts}
+
+	// Write to standard output if no file is given
+	if filename == "" {
+		w.file = os.Stdout
+		w.buffer = bufio.NewWriter(os.Stdout)
+		_, err := w.buffer.WriteString(opts.Header)
+		return w, err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	if err := w.open(flags); err != nil {
+		return nil, err
+	}
+
+	// The tee writer is a new output, so it gets the header once
+	if opts.Tee != nil {
+		if _, err := io.WriteString(opts.Tee, opts.Header); err != nil {
+			w.closeFile()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// open is a function that opens the output file with the flags given, and
+// writes the header if the file is empty
+func (w *OutputWriter) open(flags int) error {
+	file, err := os.OpenFile(w.filename, flags, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	// Compress the output if the file has a .gz extension, appending to a
+	// compressed file adds a new gzip member, which gzip reads as one stream
+	w.file = file
+	w.counter = &countingWriter{w: file, n: info.Size()}
+	w.gzip = nil
+	var dst io.Writer = w.counter
+	if strings.EqualFold(filepath.Ext(w.filename), ".gz") {
+		w.gzip = gzip.NewWriter(w.counter)
+		dst = w.gzip
+	}
+	w.buffer = bufio.NewWriter(dst)
+	w.written = 0
+
+	// Only write the header at the start of the file
+	if info.Size() == 0 {
+		if _, err := w.buffer.WriteString(w.opts.Header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write is a function that writes to the output, rotating the output file
+// first if the data would make it grow beyond the maximum size
+func (w *OutputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errors.New("write to closed output")
+	}
+
+	// Rotate the file when it is full, a file gets at least one write so
+	// that writes larger than the maximum size do not rotate forever
+	if w.opts.MaxSize > 0 && w.counter != nil && w.written > 0 && w.size()+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.buffer.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	// Copy the output to the tee writer (e.g. standard output)
+	if w.opts.Tee != nil && w.counter != nil {
+		if _, err := w.opts.Tee.Write(p); err != nil {
+			return n, err
+		}
+	}
+
+	// Make the output visible right away in streaming mode
+	if w.opts.Stream {
+		return n, w.flush()
+	}
+	return n, nil
+}
+
+// size is a function that returns the size of the current file, including
+// the buffered data. The size of a compressed file is only known once the
+// data is compressed, so it grows slightly beyond the maximum size.
+func (w *OutputWriter) size() int64 {
+	if w.gzip != nil {
+		return w.counter.n
+	}
+	return w.counter.n + int64(w.buffer.Buffered())
+}
+
+// rotate is a function that closes the current file, renames it to the next
+// free numbered name (e.g. results.1.csv.gz) and starts a new file
+func (w *OutputWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	for {
+		w.rotated++
+		name := RotatedName(w.filename, w.rotated)
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			if err := os.Rename(w.filename, name); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return w.open(os.O_CREATE | os.O_WRONLY | os.O_TRUNC)
+}
+
+// RotatedName is a function that returns the name of a rotated output file,
+// with the number inserted before the extension (e.g. results.1.csv.gz)
+func RotatedName(filename string, n int) string {
+	dir, base := filepath.Split(filename)
+	ext := ""
+	if strings.EqualFold(filepath.Ext(base), ".gz") {
+		ext = base[len(base)-3:]
+		base = base[:len(base)-3]
+	}
+	ext = filepath.Ext(base) + ext
+	base = base[:len(base)-len(filepath.Ext(base))]
+	return fmt.Sprintf("%s%s.%d%s", dir, base, n, ext)
+}
+
+// flush is a function that writes the buffered data (and the data held by
+// the compressor) to the output
+func (w *OutputWriter) flush() error {
+	if err := w.buffer.Flush(); err != nil {
+		return err
+	}
+	if w.gzip != nil {
+		return w.gzip.Flush()
+	}
+	return nil
+}
+
+// closeFile is a function that flushes the output and closes the file
+func (w *OutputWriter) closeFile() error {
+	err := w.buffer.Flush()
+	if w.gzip != nil {
+		if gzipErr := w.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Flush is a function that writes the buffered data to the output
+func (w *OutputWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	return w.flush()
+}
+
+// Close is a function that flushes the output and closes the output file.
+// Standard output is flushed but not closed. Closing the writer more than
+// once has no effect.
+func (w *OutputWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.counter == nil {
+		return w.buffer.Flush()
+	}
+	return w.closeFile()
+}
+
+// IsFile is a function that reports whether the output is written to a file
+func (w *OutputWriter) IsFile() bool {
+	return w.filename != ""
+}
+
+// File is a function that returns the file written to, os.Stdout if the
+// output is written to standard output
+func (w *OutputWriter) File() *os.File {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file
+}
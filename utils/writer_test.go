@@ -0,0 +1,195 @@
+package utils_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitcanon/iptool/utils"
+)
+
+// TestRotatedName tests the RotatedName function using various file names
+func TestRotatedName(t *testing.T) {
+	// Setup test cases
+	testCases := []struct {
+		name     string
+		filename string
+		n        int
+		expected string
+	}{
+		{name: "NoExtension", filename: "results", n: 1, expected: "results.1"},
+		{name: "Extension", filename: "results.csv", n: 2, expected: "results.2.csv"},
+		{name: "Gzip", filename: "results.csv.gz", n: 1, expected: "results.1.csv.gz"},
+		{name: "GzipOnly", filename: "results.GZ", n: 3, expected: "results.3.GZ"},
+		{name: "Directory", filename: filepath.Join("out", "results.csv"), n: 1, expected: filepath.Join("out", "results.1.csv")},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := utils.RotatedName(testCase.filename, testCase.n); result != testCase.expected {
+				t.Errorf("expected: %s, got: %s", testCase.expected, result)
+			}
+		})
+	}
+}
+
+// readOutput is a function that reads an output file, decompressing it if
+// it has a .gz extension
+func readOutput(t *testing.T, filename string) string {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Ext(filename) == ".gz" {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return string(data)
+}
+
+// TestOutputWriter tests writing, appending, rotating and compressing files
+func TestOutputWriter(t *testing.T) {
+	// Setup test cases, every case writes the lines to a new directory
+	testCases := []struct {
+		name     string
+		filename string
+		opts     utils.OutputOptions
+		existing string            // Content of the file before writing
+		lines    []string          // Written to the output
+		expected map[string]string // Content of the files after closing
+	}{
+		{
+			name:     "Plain",
+			filename: "out.txt",
+			lines:    []string{"a\n", "b\n"},
+			expected: map[string]string{"out.txt": "a\nb\n"},
+		},
+		{
+			name:     "Overwrite",
+			filename: "out.txt",
+			existing: "old\n",
+			lines:    []string{"a\n"},
+			expected: map[string]string{"out.txt": "a\n"},
+		},
+		{
+			name:     "Header",
+			filename: "out.csv",
+			opts:     utils.OutputOptions{Header: "h\n"},
+			lines:    []string{"a\n"},
+			expected: map[string]string{"out.csv": "h\na\n"},
+		},
+		{
+			name:     "Append",
+			filename: "out.csv",
+			opts:     utils.OutputOptions{Append: true, Header: "h\n"},
+			existing: "h\nold\n",
+			lines:    []string{"a\n"},
+			expected: map[string]string{"out.csv": "h\nold\na\n"},
+		},
+		{
+			name:     "AppendEmpty",
+			filename: "out.csv",
+			opts:     utils.OutputOptions{Append: true, Header: "h\n"},
+			lines:    []string{"a\n"},
+			expected: map[string]string{"out.csv": "h\na\n"},
+		},
+		{
+			name:     "Rotate",
+			filename: "out.csv",
+			opts:     utils.OutputOptions{Header: "h\n", MaxSize: 6},
+			lines:    []string{"a\n", "b\n", "c\n", "d\n", "e\n"},
+			expected: map[string]string{"out.1.csv": "h\na\nb\n", "out.2.csv": "h\nc\nd\n", "out.csv": "h\ne\n"},
+		},
+		{
+			name:     "RotateLargeWrite",
+			filename: "out.txt",
+			opts:     utils.OutputOptions{MaxSize: 2},
+			lines:    []string{"abcd\n", "efgh\n"},
+			expected: map[string]string{"out.1.txt": "abcd\n", "out.txt": "efgh\n"},
+		},
+		{
+			name:     "Gzip",
+			filename: "out.csv.gz",
+			opts:     utils.OutputOptions{Header: "h\n", Stream: true},
+			lines:    []string{"a\n", "b\n"},
+			expected: map[string]string{"out.csv.gz": "h\na\nb\n"},
+		},
+	}
+
+	// Loop through test cases
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, testCase.filename)
+			if testCase.existing != "" {
+				if err := os.WriteFile(filename, []byte(testCase.existing), 0644); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			w, err := utils.NewOutputWriter(filename, testCase.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, line := range testCase.lines {
+				if _, err := io.WriteString(w, line); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Check the files written, and that there are no others
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != len(testCase.expected) {
+				t.Errorf("expected %d files, got %d", len(testCase.expected), len(entries))
+			}
+			for name, expected := range testCase.expected {
+				if result := readOutput(t, filepath.Join(dir, name)); result != expected {
+					t.Errorf("%s: expected: %q, got: %q", name, expected, result)
+				}
+			}
+		})
+	}
+}
+
+// TestOutputWriterTee tests that the output is copied to the tee writer
+func TestOutputWriterTee(t *testing.T) {
+	var tee bytes.Buffer
+	filename := filepath.Join(t.TempDir(), "out.txt")
+	w, err := utils.NewOutputWriter(filename, utils.OutputOptions{Tee: &tee, Header: "h\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.WriteString(w, "a\n")
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The header and the data are copied
+	if tee.String() != "h\na\n" {
+		t.Errorf("expected: %q, got: %q", "h\na\n", tee.String())
+	}
+	if result := readOutput(t, filename); result != "h\na\n" {
+		t.Errorf("expected: %q, got: %q", "h\na\n", result)
+	}
+
+	// Writing after closing fails, closing again does not
+	if _, err := io.WriteString(w, "b\n"); err == nil {
+		t.Errorf("expected an error writing to a closed writer")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}